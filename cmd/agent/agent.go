@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"no-lights-monitor/internal/ping"
+	"no-lights-monitor/internal/workpool"
+)
+
+// tcpTimeout bounds one TCP connect attempt.
+const tcpTimeout = 5 * time.Second
+
+// agent runs probe rounds against the API's /api/probe endpoints.
+type agent struct {
+	apiURL      string
+	token       string
+	concurrency int
+	tcpPorts    []int // tried when ICMP gets no answer (e.g. ICMP filtered upstream)
+
+	client http.Client
+}
+
+type target struct {
+	MonitorID int64  `json:"monitor_id"`
+	Target    string `json:"target"`
+}
+
+type result struct {
+	MonitorID int64     `json:"monitor_id"`
+	OK        bool      `json:"ok"`
+	At        time.Time `json:"at"`
+}
+
+// Run probes all targets every interval until ctx is cancelled.
+func (a *agent) Run(ctx context.Context, interval time.Duration) {
+	a.client.Timeout = 30 * time.Second
+	pool := workpool.New("agent", a.concurrency)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := a.round(ctx, pool); err != nil {
+			log.Printf("[agent] round failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// round fetches the targets, checks them concurrently and reports the results.
+func (a *agent) round(ctx context.Context, pool *workpool.Pool) error {
+	targets, err := a.fetchTargets(ctx)
+	if err != nil {
+		return fmt.Errorf("fetch targets: %w", err)
+	}
+
+	var (
+		mu      sync.Mutex
+		results = make([]result, 0, len(targets))
+		wg      sync.WaitGroup
+	)
+	for _, t := range targets {
+		wg.Add(1)
+		pool.Go(func() {
+			defer wg.Done()
+			r := result{MonitorID: t.MonitorID, OK: a.check(t.Target), At: time.Now()}
+			mu.Lock()
+			results = append(results, r)
+			mu.Unlock()
+		})
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err := a.report(ctx, results); err != nil {
+		return fmt.Errorf("report results: %w", err)
+	}
+	down := 0
+	for _, r := range results {
+		if !r.OK {
+			down++
+		}
+	}
+	log.Printf("[agent] checked %d targets, %d unreachable", len(results), down)
+	return nil
+}
+
+// check pings the target and, if ICMP gets no answer, tries the configured TCP ports.
+func (a *agent) check(host string) bool {
+	if ping.PingHost(host) {
+		return true
+	}
+	for _, port := range a.tcpPorts {
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), tcpTimeout)
+		if err == nil {
+			conn.Close()
+			return true
+		}
+	}
+	return false
+}
+
+func (a *agent) fetchTargets(ctx context.Context) ([]target, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.apiURL+"/api/probe/targets", nil)
+	if err != nil {
+		return nil, err
+	}
+	var body struct {
+		Targets []target `json:"targets"`
+	}
+	if err := a.do(req, &body); err != nil {
+		return nil, err
+	}
+	return body.Targets, nil
+}
+
+func (a *agent) report(ctx context.Context, results []result) error {
+	payload, err := json.Marshal(map[string]any{"results": results})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.apiURL+"/api/probe/results", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return a.do(req, nil)
+}
+
+// do sends an authenticated request and decodes the JSON response into out (if non-nil).
+func (a *agent) do(req *http.Request, out any) error {
+	req.Header.Set("Authorization", "Bearer "+a.token)
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: status %d", req.Method, req.URL.Path, resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,86 @@
+// Command agent is a remote probe for ping monitors. Run it on a VPS in
+// another network: it pulls the ping targets from the API, checks them and
+// reports the results, which the worker combines with its own pings by quorum.
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"no-lights-monitor/internal/safego"
+)
+
+const (
+	// DefaultIntervalSec matches the worker's ping round.
+	DefaultIntervalSec = 60
+	// DefaultConcurrency is the max number of checks in flight.
+	DefaultConcurrency = 32
+)
+
+func main() {
+	_ = godotenv.Load()
+
+	apiURL := strings.TrimRight(getEnv("AGENT_API_URL", ""), "/")
+	token := getEnv("AGENT_TOKEN", "")
+	if apiURL == "" || token == "" {
+		log.Fatal("AGENT_API_URL and AGENT_TOKEN are required")
+	}
+
+	a := &agent{
+		apiURL:      apiURL,
+		token:       token,
+		concurrency: getEnvInt("AGENT_CONCURRENCY", DefaultConcurrency),
+		tcpPorts:    parsePorts(getEnv("AGENT_TCP_PORTS", "")),
+	}
+	interval := time.Duration(getEnvInt("AGENT_INTERVAL", DefaultIntervalSec)) * time.Second
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	safego.Go("shutdown", func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		<-quit
+		log.Println("shutting down...")
+		cancel()
+	})
+
+	log.Printf("probe agent started (api: %s, interval: %s, tcp ports: %v)", apiURL, interval, a.tcpPorts)
+	a.Run(ctx, interval)
+}
+
+// parsePorts reads a comma-separated list of TCP ports, skipping invalid ones.
+func parsePorts(s string) []int {
+	var ports []int
+	for _, p := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil || n < 1 || n > 65535 {
+			continue
+		}
+		ports = append(ports, n)
+	}
+	return ports
+}
+
+func getEnv(key, fallback string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return fallback
+}
+
+func getEnvInt(key string, fallback int) int {
+	if val := os.Getenv(key); val != "" {
+		if n, err := strconv.Atoi(val); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
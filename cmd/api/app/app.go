@@ -0,0 +1,330 @@
+// Package app is the API service: the public map, ping endpoints, the
+// settings page backend and the admin panel.
+package app
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+	"github.com/gofiber/fiber/v2/middleware/logger"
+
+	"no-lights-monitor/cmd/api/handlers"
+	"no-lights-monitor/internal/bootstrap"
+	"no-lights-monitor/internal/config"
+	"no-lights-monitor/internal/contentfilter"
+	"no-lights-monitor/internal/geocode"
+	"no-lights-monitor/internal/health"
+	"no-lights-monitor/internal/metrics"
+	"no-lights-monitor/internal/mq"
+	"no-lights-monitor/internal/outage"
+	"no-lights-monitor/internal/ping"
+	"no-lights-monitor/internal/publicurl"
+	"no-lights-monitor/internal/safego"
+)
+
+// Run starts the API service and blocks until ctx is cancelled.
+func Run(ctx context.Context, cfg *config.Config) {
+	// Pre-render HTML pages that need config values injected (values are static after startup).
+	type webVars struct{ BotUsername, ChatUsername string }
+	webCfg := webVars{cfg.TelegramBotUsername, cfg.TelegramChatUsername}
+	renderOnce := func(file string) []byte {
+		var buf bytes.Buffer
+		template.Must(template.ParseFiles(file)).Execute(&buf, webCfg)
+		return buf.Bytes()
+	}
+	indexHTML := renderOnce("./web/index.html")
+	notFoundHTML := renderOnce("./web/404.html")
+	serveHTML := func(body []byte, status int) fiber.Handler {
+		return func(c *fiber.Ctx) error {
+			c.Set("Content-Type", "text/html; charset=utf-8")
+			c.Set("Cache-Control", "no-cache, must-revalidate")
+			return c.Status(status).Send(body)
+		}
+	}
+
+	// --- Database ---
+	db := bootstrap.Database(ctx, cfg)
+	defer db.Close()
+
+	// Warn when a hot listing lost its index (see database.CheckQueryPlans).
+	if plans, err := db.CheckQueryPlans(ctx); err != nil {
+		log.Printf("query plan check: %v", err)
+	} else {
+		for _, p := range plans {
+			if p.SeqScans {
+				log.Printf("WARNING: %s scans the monitors table:\n%s", p.Name, p.Plan)
+			}
+		}
+	}
+
+	// --- Redis ---
+	redisCache := bootstrap.Redis(cfg)
+	defer redisCache.Close()
+
+	// --- Health + metrics server on :8081 (not exposed through ingress) ---
+	health.ServeAsync(func() error {
+		if err := db.Pool.Ping(context.Background()); err != nil {
+			return err
+		}
+		return redisCache.Client.Ping(context.Background()).Err()
+	})
+
+	// --- RabbitMQ ---
+	mqPub := bootstrap.Publisher(cfg)
+	defer mqPub.Close()
+	commands, err := mq.NewCommandBus(mqPub)
+	if err != nil {
+		log.Fatalf("rabbitmq command bus: %v", err)
+	}
+	defer commands.Close()
+	log.Println("rabbitmq connected")
+
+	// --- Fiber HTTP Server ---
+	// The proxy header is only believed from the proxies in TRUSTED_PROXIES
+	// (loopback by default): anyone else could forge it and slip past the
+	// per-IP limits.
+	trustedProxies := cfg.TrustedProxies
+	if len(trustedProxies) == 0 {
+		trustedProxies = []string{"127.0.0.1", "::1"}
+	}
+	app := fiber.New(fiber.Config{
+		DisableStartupMessage:   true,
+		BodyLimit:               64 * 1024, // 64KB — settings JSON has no business being larger
+		ProxyHeader:             cfg.ProxyHeader,
+		EnableTrustedProxyCheck: cfg.ProxyHeader != "",
+		TrustedProxies:          trustedProxies,
+		EnableIPValidation:      true,
+	})
+
+	app.Use(logger.New(logger.Config{
+		Format: "${time} ${status} ${method} ${path} ${latency}\n",
+	}))
+	app.Use(cors.New())
+
+	// Record latency for /api/* routes only (avoids cardinality from static file paths).
+	app.Use(func(c *fiber.Ctx) error {
+		if len(c.Path()) < 5 || c.Path()[:5] != "/api/" {
+			return c.Next()
+		}
+		start := time.Now()
+		err := c.Next()
+		route := c.Route().Path
+		if route == "" {
+			route = "unknown"
+		}
+		metrics.APIRequestDuration.WithLabelValues(route, strconv.Itoa(c.Response().StatusCode())).Observe(time.Since(start).Seconds())
+		return err
+	})
+
+	// Health checks (before all other routes so they're never shadowed)
+	app.Get("/healthz", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	app.Get("/readyz", func(c *fiber.Ctx) error {
+		if err := db.Pool.Ping(context.Background()); err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "db"})
+		}
+		if err := redisCache.Client.Ping(context.Background()).Err(); err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "redis"})
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	// API routes
+	h := &handlers.Handlers{DB: db, Cache: redisCache, Hosts: publicurl.New(cfg.BaseURL, cfg.LegacyBaseURLs), OutageServiceURL: cfg.OutageServiceURL, OutageClient: outage.NewClient(cfg.OutageServiceURL, cfg.InternalAuthSecret, cfg.OutagePolicy()), InternalSecret: cfg.InternalAuthSecret, DtekServiceURL: cfg.DtekServiceURL, MQPublisher: mqPub, Commands: commands, SandboxChannelID: cfg.SandboxChannelID, BotToken: cfg.BotToken, PingHost: ping.PingHost, OfflineThreshold: time.Duration(cfg.OfflineThreshold) * time.Second, ProbeAgents: handlers.ParseProbeAgents(cfg.ProbeAgentTokens), MapJitterSecret: cfg.MapJitterSecret, Filter: contentfilter.New(db), Geocoder: geocode.NewClient(redisCache), Sandbox: cfg.Sandbox, SMSAvailable: cfg.SMSProvider != "", SMSMonthlyQuota: cfg.SMSMonthlyQuota}
+	// Drop the /api/monitors cache as soon as the worker announces a status
+	// change, and push the change to the live stream clients.
+	safego.Go("monitor_cache_invalidation", func() {
+		redisCache.SubscribeMonitorsChanged(ctx, func() {
+			h.InvalidateMonitorCache()
+			h.NotifyStream()
+		})
+	})
+	app.Use(h.LegacyHostRedirect)
+	api := app.Group("/api")
+	api.Get("/ping/:token", h.PingAPI)
+	api.Get("/ping-ip", h.PingByIP)
+	api.Get("/monitors", h.GetMonitors)
+	api.Get("/monitors/changes", h.GetMonitorChanges)
+	api.Get("/stream", h.GetStream)
+	api.Get("/announcements", h.GetAnnouncements)
+	api.Get("/heatmap/:z/:x/:y", h.GetHeatmapTile) // y carries the ".json" suffix
+
+	// Public mass-outage feed; a year-long range is a heavier query than the map.
+	api.Get("/incidents", limiter.New(limiter.Config{
+		Max:        handlers.IncidentsRateLimit,
+		Expiration: time.Minute,
+		LimitReached: func(c *fiber.Ctx) error {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "too many requests"})
+		},
+	}), h.GetIncidents)
+
+	// Channel rights check for web onboarding (asks the bot via the command bus).
+	api.Get("/channels/check", limiter.New(limiter.Config{
+		Max:        handlers.ChannelCheckRateLimit,
+		Expiration: time.Minute,
+		LimitReached: func(c *fiber.Ctx) error {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "too many requests"})
+		},
+	}), h.CheckChannelRights)
+
+	// Web onboarding: Telegram sign-in, then monitor creation like the bot's /create.
+	web := api.Group("/web", limiter.New(limiter.Config{
+		Max:        handlers.WebRateLimit,
+		Expiration: time.Minute,
+		LimitReached: func(c *fiber.Ctx) error {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "too many requests"})
+		},
+	}))
+	web.Post("/auth", h.WebAuth)
+	web.Get("/geocode", h.WebSessionGuard, h.WebGeocode)
+	web.Post("/monitors", h.WebSessionGuard, h.WebCreateMonitor)
+	web.Post("/push", h.WebSessionGuard, h.WebRegisterPushDevice)
+	web.Delete("/push", h.WebSessionGuard, h.WebUnregisterPushDevice)
+
+	// REST API for power users, keyed by the bot's /apikey. Bad keys count
+	// towards the same lockout as bad settings tokens.
+	v1 := api.Group("/v1", limiter.New(limiter.Config{
+		Max:        handlers.APIRateLimit,
+		Expiration: time.Minute,
+		LimitReached: func(c *fiber.Ctx) error {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "too many requests"})
+		},
+	}), h.SettingsGuard, h.APIKeyAuth)
+	v1.Get("/monitors", h.APIListMonitors)
+	v1.Post("/monitors", h.APICreateMonitor)
+	v1.Put("/monitors/:id", h.APIUpdateMonitor)
+	v1.Delete("/monitors/:id", h.APIDeleteMonitor)
+
+	// Remote probe agents: fetch ping targets, report reachability votes.
+	probe := api.Group("/probe", h.ProbeAuth)
+	probe.Get("/targets", h.GetProbeTargets)
+	probe.Post("/results", h.PostProbeResults)
+
+	// Address lookups for the settings page (cached Nominatim proxy).
+	geocodeLimit := limiter.New(limiter.Config{
+		Max:        handlers.GeocodeRateLimit,
+		Expiration: time.Minute,
+		LimitReached: func(c *fiber.Ctx) error {
+			log.Printf("[geocode] rate limit hit by %s", c.IP())
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "too many requests"})
+		},
+	})
+	api.Get("/geocode", geocodeLimit, h.Geocode)
+	api.Get("/geocode/suggest", geocodeLimit, h.GeocodeSuggest)
+
+	// Proxy outage API from the outage service (for settings page)
+	api.Get("/outage/*", h.ProxyOutage)
+
+	// Proxy DTEK scraper (address autocomplete for settings page)
+	api.Get("/dtek/*", h.ProxyDtek)
+
+	// Settings API (accessed by settings_token)
+	api.Use("/settings", limiter.New(limiter.Config{
+		Max:        handlers.SettingsRateLimit,
+		Expiration: time.Minute,
+		LimitReached: func(c *fiber.Ctx) error {
+			log.Printf("[settings] rate limit hit by %s on %s", c.IP(), c.Path())
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "too many requests"})
+		},
+	}), h.SettingsGuard)
+	api.Get("/settings/:token", h.GetSettings)
+	api.Put("/settings/:token", h.UpdateSettings)
+	api.Post("/settings/:token/stop", h.StopMonitor)
+	api.Post("/settings/:token/resume", h.ResumeMonitor)
+	api.Get("/settings/:token/preview", h.PreviewNotifications)
+	api.Get("/settings/:token/ping-url", h.GetPingURL)
+	api.Post("/settings/:token/ping-url/regenerate", h.RegeneratePingURL)
+	api.Post("/settings/:token/ping-ip", h.BindPingIP)
+	api.Delete("/settings/:token/ping-ip", h.UnbindPingIP)
+	api.Get("/settings/:token/devices", h.GetDevices)
+	api.Post("/settings/:token/devices", h.CreateDevice)
+	api.Delete("/settings/:token/devices/:id", h.DeleteDevice)
+	api.Post("/settings/:token/changes/:id/revert", h.RevertChange)
+	api.Get("/settings/:token/corrections", h.GetCorrections)
+	api.Post("/settings/:token/corrections", h.AddCorrection)
+	api.Delete("/settings/:token/corrections/:id", h.DeleteCorrection)
+	api.Post("/settings/:token/test", h.SendTestMessage)
+	api.Post("/settings/:token/push", h.RegisterPushDevice)
+	api.Delete("/settings/:token/push", h.UnregisterPushDevice)
+	api.Delete("/settings/:token", h.DeleteMonitorWeb)
+
+	// Admin routes (protected by HTTP Basic Auth)
+	if cfg.AdminLogin != "" && cfg.AdminPassword != "" {
+		admin := app.Group("/admin", handlers.BasicAuth(cfg.AdminLogin, cfg.AdminPassword))
+		admin.Get("/", h.AdminPage)
+		admin.Get("/api/settings", h.AdminGetSettings)
+		admin.Put("/api/settings", h.AdminSetSettings)
+		admin.Get("/api/users", h.AdminGetUsers)
+		admin.Get("/api/users/banned", h.AdminGetBannedUsers)
+		admin.Post("/api/users/:telegram_id/ban", h.AdminBanUser)
+		admin.Delete("/api/users/:telegram_id/ban", h.AdminUnbanUser)
+		admin.Get("/api/monitors", h.AdminGetMonitors)
+		admin.Get("/api/monitors/deleted", h.AdminGetDeletedMonitors)
+		admin.Get("/api/jobs", h.AdminGetJobs)
+		admin.Get("/api/query-plans", h.AdminGetQueryPlans)
+		admin.Get("/api/monitors/:id/history", h.GetHistory)
+		admin.Post("/api/broadcast", h.AdminBroadcast)
+		admin.Post("/api/monitors/:id/test-drive", h.AdminTestDrive)
+		admin.Post("/api/backfill", h.AdminBackfill)
+		admin.Post("/api/recompute", h.AdminRecompute)
+		admin.Get("/api/banned-words", h.AdminGetBannedWords)
+		admin.Put("/api/banned-words", h.AdminSetBannedWords)
+		admin.Put("/api/monitors/:id", h.AdminUpdateMonitor)
+		admin.Get("/api/announcements", h.AdminGetAnnouncements)
+		admin.Post("/api/announcements", h.AdminCreateAnnouncement)
+		admin.Delete("/api/announcements/:id", h.AdminDeleteAnnouncement)
+		admin.Get("/api/sponsor-messages", h.AdminGetSponsorMessages)
+		admin.Post("/api/sponsor-messages", h.AdminCreateSponsorMessage)
+		admin.Delete("/api/sponsor-messages/:id", h.AdminDeleteSponsorMessage)
+	}
+
+	// Per-monitor Prometheus gauges for self-hosters (opt-in, API key protected).
+	if cfg.MonitorMetricsKey != "" {
+		app.Get("/metrics/monitors", h.MonitorMetrics(cfg.MonitorMetricsKey))
+	}
+
+	// Settings page (serve settings.html for any /settings/* path).
+	app.Get("/settings/:token", func(c *fiber.Ctx) error {
+		c.Set("Cache-Control", "no-cache, must-revalidate")
+		return c.SendFile("./web/settings.html")
+	})
+
+	// Index page: pre-rendered with config values injected.
+	app.Get("/", serveHTML(indexHTML, fiber.StatusOK))
+	app.Get("/index.html", serveHTML(indexHTML, fiber.StatusOK))
+
+	// HTML and JS files: bypass static handler so Cache-Control is guaranteed.
+	noCache := func(c *fiber.Ctx) error {
+		c.Set("Cache-Control", "no-cache, must-revalidate")
+		return c.SendFile("./web" + c.Path())
+	}
+	app.Get("/*.html", noCache)
+	app.Get("/js/*.js", noCache)
+
+	// Everything else (CSS, images, fonts…) served normally with default caching.
+	app.Static("/", "./web")
+
+	// 404 handler: pre-rendered with config values injected.
+	app.Use(serveHTML(notFoundHTML, fiber.StatusNotFound))
+
+	// --- Graceful shutdown ---
+	safego.Go("api_shutdown", func() {
+		<-ctx.Done()
+		log.Println("shutting down API service...")
+		h.CloseStreams()
+		_ = app.Shutdown()
+	})
+
+	log.Printf("API service starting on :%s", cfg.Port)
+	if err := app.Listen(":" + cfg.Port); err != nil {
+		log.Fatalf("server: %v", err)
+	}
+}
@@ -4,11 +4,23 @@ import (
 	"context"
 	"crypto/subtle"
 	"encoding/base64"
+	"log"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 
+	"no-lights-monitor/internal/backfill"
 	"no-lights-monitor/internal/mq"
+	"no-lights-monitor/internal/recompute"
+)
+
+const (
+	// DefaultBackfillDays is how far back AdminBackfill looks by default.
+	DefaultBackfillDays = 7
+	// MaxBackfillDays matches how long the API keeps heartbeat gaps in Redis.
+	MaxBackfillDays = 30
 )
 
 // AdminGetSettings returns global app settings.
@@ -102,6 +114,29 @@ func (h *Handlers) AdminGetDeletedMonitors(c *fiber.Ctx) error {
 	return c.JSON(monitors)
 }
 
+// AdminGetJobs returns scheduler bookkeeping for all periodic jobs.
+func (h *Handlers) AdminGetJobs(c *fiber.Ctx) error {
+	jobs, err := h.DB.GetScheduledJobs(context.Background())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to load jobs"})
+	}
+	if jobs == nil {
+		return c.JSON([]struct{}{})
+	}
+	return c.JSON(jobs)
+}
+
+// AdminGetQueryPlans EXPLAINs the hot monitor listings and flags any that
+// would scan the monitors table instead of using their partial index.
+func (h *Handlers) AdminGetQueryPlans(c *fiber.Ctx) error {
+	plans, err := h.DB.CheckQueryPlans(context.Background())
+	if err != nil {
+		log.Printf("[admin] check query plans: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to explain queries"})
+	}
+	return c.JSON(plans)
+}
+
 // AdminBroadcast sends a text message to all active monitors' Telegram channels.
 func (h *Handlers) AdminBroadcast(c *fiber.Ctx) error {
 	var req struct {
@@ -120,6 +155,9 @@ func (h *Handlers) AdminBroadcast(c *fiber.Ctx) error {
 	seen := make(map[int64]struct{})
 	var count int
 	for _, m := range monitors {
+		if m.IsCanary {
+			continue
+		}
 		if _, ok := seen[m.ChannelID]; ok {
 			continue
 		}
@@ -135,3 +173,105 @@ func (h *Handlers) AdminBroadcast(c *fiber.Ctx) error {
 
 	return c.JSON(fiber.Map{"channels": count})
 }
+
+// AdminTestDrive replays a monitor's notification cycle (offline → online, graph,
+// outage photo) with its exact configuration into a sandbox channel, so user
+// complaints can be reproduced without touching the real channel.
+// The channel defaults to SANDBOX_CHANNEL_ID and can be overridden per request.
+func (h *Handlers) AdminTestDrive(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid monitor id"})
+	}
+	var req struct {
+		ChannelID int64 `json:"channel_id"`
+	}
+	_ = c.BodyParser(&req)
+	channelID := req.ChannelID
+	if channelID == 0 {
+		channelID = h.SandboxChannelID
+	}
+	if channelID == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "no sandbox channel configured"})
+	}
+
+	ctx := context.Background()
+	m, err := h.DB.GetMonitorByID(ctx, id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "monitor not found"})
+	}
+	if channelID == m.ChannelID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "sandbox channel must differ from the monitor's channel"})
+	}
+
+	if err := h.MQPublisher.Publish(ctx, mq.RoutingTestDrive, mq.TestDriveMsg{
+		MonitorID: m.ID,
+		ChannelID: channelID,
+	}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to publish"})
+	}
+	return c.JSON(fiber.Map{"monitor_id": m.ID, "channel_id": channelID})
+}
+
+// AdminBackfill infers outages the heartbeat checker missed (e.g. while the
+// worker was down) from heartbeat gaps logged by the API, and inserts them as
+// status events so weekly graphs stop showing false "online" stretches.
+// Body: {"days": 7, "monitor_id": 0, "dry_run": true}.
+func (h *Handlers) AdminBackfill(c *fiber.Ctx) error {
+	var req struct {
+		Days      int   `json:"days"`
+		MonitorID int64 `json:"monitor_id"`
+		DryRun    bool  `json:"dry_run"`
+	}
+	_ = c.BodyParser(&req)
+	if req.Days <= 0 {
+		req.Days = DefaultBackfillDays
+	}
+	if req.Days > MaxBackfillDays {
+		req.Days = MaxBackfillDays
+	}
+
+	report, err := backfill.Run(context.Background(), h.DB, h.Cache, backfill.Options{
+		Since:            time.Now().AddDate(0, 0, -req.Days),
+		MonitorID:        req.MonitorID,
+		DefaultThreshold: h.OfflineThreshold,
+		DryRun:           req.DryRun,
+	})
+	if err != nil {
+		log.Printf("[admin] backfill: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error(), "report": report})
+	}
+	return c.JSON(report)
+}
+
+// AdminRecompute force-regenerates channel graphs or outage photos for a set of
+// monitors, e.g. after a rendering bug fix. Body: {"kind": "graph"|"photo",
+// "monitor_ids": [...], "region": "...", "group": "...", "dry_run": bool};
+// empty filters match every monitor. The worker processes the queued requests
+// at a throttled pace.
+func (h *Handlers) AdminRecompute(c *fiber.Ctx) error {
+	var req struct {
+		Kind       mq.RecomputeKind `json:"kind"`
+		MonitorIDs []int64          `json:"monitor_ids"`
+		Region     string           `json:"region"`
+		Group      string           `json:"group"`
+		DryRun     bool             `json:"dry_run"`
+	}
+	if err := c.BodyParser(&req); err != nil || !recompute.ValidKind(req.Kind) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "kind must be graph or photo"})
+	}
+
+	report, err := recompute.Enqueue(context.Background(), h.DB, h.MQPublisher, recompute.Options{
+		Kind:       req.Kind,
+		MonitorIDs: req.MonitorIDs,
+		Region:     req.Region,
+		Group:      req.Group,
+		DryRun:     req.DryRun,
+	})
+	if err != nil {
+		log.Printf("[admin] recompute %s: %v", req.Kind, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error(), "report": report})
+	}
+	log.Printf("[admin] recompute %s: %d monitors (dry_run=%v)", req.Kind, len(report.MonitorIDs), req.DryRun)
+	return c.JSON(report)
+}
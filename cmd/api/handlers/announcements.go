@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"no-lights-monitor/internal/models"
+)
+
+const (
+	// announcementsTTL is how long active announcements are cached in process;
+	// the map page asks on every load.
+	announcementsTTL = 30 * time.Second
+
+	maxAnnouncementLen = 500
+)
+
+// announcementLevels are the banner styles the pages know.
+var announcementLevels = []string{"info", "warning"}
+
+// announcementPages are the pages that show banners.
+var announcementPages = []string{"map", "settings"}
+
+// activeAnnouncements returns the announcements shown on page, from a short
+// in-process cache. Errors are logged and yield no announcements: a banner is
+// never worth failing the page for.
+func (h *Handlers) activeAnnouncements(ctx context.Context, page string) []fiber.Map {
+	h.announcementsMu.Lock()
+	if h.announcementsAt.IsZero() || time.Since(h.announcementsAt) > announcementsTTL {
+		list, err := h.DB.GetActiveAnnouncements(ctx, time.Now())
+		if err != nil {
+			log.Printf("[announcements] load: %v", err)
+		} else {
+			h.announcements, h.announcementsAt = list, time.Now()
+		}
+	}
+	list := h.announcements
+	h.announcementsMu.Unlock()
+
+	now := time.Now()
+	out := []fiber.Map{}
+	for _, a := range list {
+		if a.EndsAt != nil && !now.Before(*a.EndsAt) {
+			continue
+		}
+		if a.Pages != "" && !slices.Contains(strings.Split(a.Pages, ","), page) {
+			continue
+		}
+		out = append(out, fiber.Map{
+			"id":       a.ID,
+			"message":  a.Message,
+			"link_url": a.LinkURL,
+			"level":    a.Level,
+		})
+	}
+	return out
+}
+
+// invalidateAnnouncements drops the cache after an admin change.
+func (h *Handlers) invalidateAnnouncements() {
+	h.announcementsMu.Lock()
+	h.announcementsAt = time.Time{}
+	h.announcementsMu.Unlock()
+}
+
+// GetAnnouncements handles GET /api/announcements?page=map: the active
+// operator notices for a page (all pages when page is omitted).
+func (h *Handlers) GetAnnouncements(c *fiber.Ctx) error {
+	page := c.Query("page")
+	if page != "" && !slices.Contains(announcementPages, page) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "page must be map or settings"})
+	}
+	c.Set("Cache-Control", "public, max-age=60")
+	return c.JSON(h.activeAnnouncements(context.Background(), page))
+}
+
+// AdminGetAnnouncements returns every announcement, including scheduled and expired ones.
+func (h *Handlers) AdminGetAnnouncements(c *fiber.Ctx) error {
+	list, err := h.DB.GetAnnouncements(context.Background())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to load announcements"})
+	}
+	if list == nil {
+		list = []models.Announcement{}
+	}
+	return c.JSON(list)
+}
+
+// AdminCreateAnnouncement adds an announcement. Body: {"message": "...",
+// "link_url": "https://...", "level": "info|warning", "pages": ["map"],
+// "starts_at": RFC3339, "ends_at": RFC3339}; all but message are optional.
+func (h *Handlers) AdminCreateAnnouncement(c *fiber.Ctx) error {
+	var req struct {
+		Message  string     `json:"message"`
+		LinkURL  string     `json:"link_url"`
+		Level    string     `json:"level"`
+		Pages    []string   `json:"pages"`
+		StartsAt *time.Time `json:"starts_at"`
+		EndsAt   *time.Time `json:"ends_at"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+
+	a := models.Announcement{
+		Message:  strings.TrimSpace(req.Message),
+		LinkURL:  strings.TrimSpace(req.LinkURL),
+		Level:    req.Level,
+		StartsAt: time.Now(),
+		EndsAt:   req.EndsAt,
+	}
+	if a.Message == "" || len([]rune(a.Message)) > maxAnnouncementLen {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "message must be 1-" + strconv.Itoa(maxAnnouncementLen) + " characters"})
+	}
+	if a.LinkURL != "" {
+		if u, err := url.Parse(a.LinkURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "link_url must be an http(s) URL"})
+		}
+	}
+	if a.Level == "" {
+		a.Level = "info"
+	}
+	if !slices.Contains(announcementLevels, a.Level) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "level must be info or warning"})
+	}
+	for _, p := range req.Pages {
+		if !slices.Contains(announcementPages, p) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "unknown page " + p})
+		}
+	}
+	a.Pages = strings.Join(req.Pages, ",")
+	if req.StartsAt != nil {
+		a.StartsAt = *req.StartsAt
+	}
+	if a.EndsAt != nil && !a.EndsAt.After(a.StartsAt) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ends_at must be after starts_at"})
+	}
+
+	created, err := h.DB.CreateAnnouncement(context.Background(), a)
+	if err != nil {
+		log.Printf("[admin] create announcement: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create announcement"})
+	}
+	h.invalidateAnnouncements()
+	log.Printf("[admin] announcement %d created", created.ID)
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+// AdminDeleteAnnouncement removes an announcement.
+func (h *Handlers) AdminDeleteAnnouncement(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+	ok, err := h.DB.DeleteAnnouncement(context.Background(), id)
+	if err != nil {
+		log.Printf("[admin] delete announcement %d: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to delete announcement"})
+	}
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "announcement not found"})
+	}
+	h.invalidateAnnouncements()
+	log.Printf("[admin] announcement %d deleted", id)
+	return c.JSON(fiber.Map{"status": "ok"})
+}
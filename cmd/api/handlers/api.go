@@ -3,45 +3,105 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"log"
+	"net/url"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 
 	"no-lights-monitor/internal/cache"
+	"no-lights-monitor/internal/contentfilter"
 	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/geocode"
+	"no-lights-monitor/internal/geoprivacy"
 	"no-lights-monitor/internal/metrics"
 	"no-lights-monitor/internal/models"
+	"no-lights-monitor/internal/mq"
+	"no-lights-monitor/internal/outage"
+	"no-lights-monitor/internal/publicurl"
+	"no-lights-monitor/internal/safego"
 )
 
 type Handlers struct {
 	DB    *database.DB
 	Cache *cache.Cache // For API service (stateless ping)
 
-	OutageServiceURL string // URL of the outage data service (for proxying)
-	DtekServiceURL   string // URL of the DTEK scraper service (for proxying)
+	Hosts            *publicurl.Hosts // Public base URLs, used to build ping URLs
+	OutageServiceURL string           // URL of the outage data service (for proxying)
+	DtekServiceURL   string           // URL of the DTEK scraper service (for proxying)
+	OutageClient     *outage.Client   // schedule lookups for notification previews
+	InternalSecret   string           // signs requests proxied to the outage service
 	MQPublisher      mqPublisher
+	Commands         commandBus // Telegram actions performed by the bot on the API's behalf
+	SandboxChannelID int64      // default channel for admin test-drives
+	BotToken         string     // verifies Telegram Login Widget and Mini App signatures
+	PingHost         func(string) bool
+	OfflineThreshold time.Duration         // default offline threshold, for logging heartbeat gaps
+	ProbeAgents      map[string]string     // remote probe agent name → bearer token
+	MapJitterSecret  string                // keys the coordinate offsets of fuzzed monitors
+	Filter           *contentfilter.Filter // banned words and limits for names and addresses
+	Geocoder         *geocode.Client       // address lookups through the shared cache and throttle
+	Sandbox          bool                  // accept synthetic ping tokens (SANDBOX=1)
+	SMSAvailable     bool                  // an SMS gateway is configured (SMS_PROVIDER)
+	SMSMonthlyQuota  int                   // texts per monitor per month, shown on the settings page
 
-	// In-memory response cache for /api/monitors.
+	// In-process copy of the /api/monitors response (shared copy lives in Redis).
 	monitorCache   []byte
 	monitorCacheAt time.Time
 	monitorCacheMu sync.RWMutex
+
+	// Active announcements, cached briefly (see activeAnnouncements).
+	announcements   []models.Announcement
+	announcementsAt time.Time
+	announcementsMu sync.Mutex
+
+	// Open /api/stream connections (see stream.go).
+	stream streamHub
+
+	// Last read of the worker's surge flag (see surgeActive).
+	surgeOn        bool
+	surgeCheckedAt time.Time
+	surgeMu        sync.Mutex
 }
 
 type mqPublisher interface {
 	Publish(ctx context.Context, routingKey string, msg any) error
 }
 
+type commandBus interface {
+	Call(ctx context.Context, cmd mq.BotCommand) (*mq.CommandResult, error)
+	Send(ctx context.Context, cmd mq.BotCommand) error
+}
+
 const (
 	// MonitorCacheTTL is how long to cache the monitor list response.
 	MonitorCacheTTL = 15 * time.Second
 	// MonitorCacheMaxAgeSec is the Cache-Control max-age header value.
 	MonitorCacheMaxAgeSec = 15
+	// SurgeMonitorCacheTTL replaces MonitorCacheTTL (and max-age) during an
+	// outage wave, when the list would otherwise be re-rendered every second.
+	SurgeMonitorCacheTTL = 60 * time.Second
+	// surgeRecheck is how often the Redis surge flag is re-read.
+	surgeRecheck = 10 * time.Second
 	// DefaultHistoryLookback is the default time range for history queries.
 	DefaultHistoryLookback = 24 * time.Hour
 	// MaxHistoryRange is the maximum allowed time range for history queries.
 	MaxHistoryRange = 30 * 24 * time.Hour
+	// monitorsResponse names the /api/monitors rendering in the shared response cache.
+	monitorsResponse = "monitors"
+	// MaxMonitorIDs caps the ?ids= filter of /api/monitors.
+	MaxMonitorIDs = 100
+	// MaxChangesLookback is the oldest ?since= accepted by /api/monitors/changes;
+	// clients further behind should reload the full list.
+	MaxChangesLookback = time.Hour
+	// HeartbeatEventInterval rate-limits the heartbeat firehose of a monitor.
+	HeartbeatEventInterval = time.Minute
+	// SyntheticTokenPrefix starts ping tokens that sandbox mode accepts without a
+	// monitor, for exercising the ping path against a copy of production data.
+	SyntheticTokenPrefix = "synthetic-"
 )
 
 // PingAPI handles GET /api/ping/:token -- for API service (stateless, DB + Redis only).
@@ -55,12 +115,41 @@ func (h *Handlers) PingAPI(c *fiber.Ctx) error {
 
 	ctx := context.Background()
 
-	// Validate token by looking up monitor in database.
+	if h.Sandbox && strings.HasPrefix(token, SyntheticTokenPrefix) {
+		metrics.PingTotal.WithLabelValues("synthetic").Inc()
+		return c.JSON(fiber.Map{"status": "ok"})
+	}
+
+	// Validate token by looking up monitor in database, then among the extra
+	// devices of monitors.
 	monitor, err := h.DB.GetMonitorByToken(ctx, token)
-	if err != nil {
+	if err == nil {
+		return h.acceptPing(c, monitor, nil)
+	}
+	monitor, device, err := h.DB.GetMonitorByDeviceToken(ctx, token)
+	if err != nil || monitor.MonitorType != "heartbeat" {
 		metrics.PingTotal.WithLabelValues("not_found").Inc()
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "unknown token"})
 	}
+	return h.acceptPing(c, monitor, device)
+}
+
+// PingByIP handles GET /api/ping-ip -- for relays that can only open a fixed URL.
+// The monitor is found by the caller's IP, which its owner bound explicitly via
+// the settings API. Anyone behind the same public IP can mark it online.
+func (h *Handlers) PingByIP(c *fiber.Ctx) error {
+	monitor, err := h.DB.GetMonitorByPingIP(context.Background(), c.IP())
+	if err != nil {
+		metrics.PingTotal.WithLabelValues("not_found").Inc()
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no monitor bound to this IP"})
+	}
+	return h.acceptPing(c, monitor, nil)
+}
+
+// acceptPing records a heartbeat for an identified monitor. device is the
+// extra device that pinged, or nil for the monitor's own token.
+func (h *Handlers) acceptPing(c *fiber.Ctx, monitor *models.Monitor, device *models.MonitorDevice) error {
+	ctx := context.Background()
 
 	// Skip if monitoring is paused.
 	if !monitor.IsActive {
@@ -68,37 +157,143 @@ func (h *Handlers) PingAPI(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{"status": "paused"})
 	}
 
-	// Write heartbeat timestamp to Redis.
+	// Shadow-ban: answer like a normal ping but record nothing.
+	if banned, err := h.DB.IsUserBanned(ctx, monitor.UserID); err == nil && banned {
+		metrics.PingTotal.WithLabelValues("banned").Inc()
+		return c.JSON(fiber.Map{"status": "ok"})
+	}
+
 	now := time.Now()
-	if err := h.Cache.SetHeartbeat(ctx, monitor.ID, now); err != nil {
+	h.emitHeartbeatEvent(monitor, now)
+
+	// An extra device only refreshes its own heartbeat: the worker takes the
+	// newest of all, and cadence and gap tracking stay with the main device.
+	if device != nil {
+		if err := h.Cache.SetDeviceHeartbeat(ctx, monitor.ID, device.ID, now); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "cache error"})
+		}
+		safego.Go("heartbeat_db_update", func() {
+			_ = h.DB.UpdateMonitorDevicePing(context.Background(), device.ID, now)
+			_ = h.DB.UpdateMonitorHeartbeat(context.Background(), monitor.ID, now)
+		})
+		metrics.PingTotal.WithLabelValues("ok").Inc()
+		return c.JSON(fiber.Map{"status": "ok"})
+	}
+
+	// Write heartbeat timestamp to Redis.
+	prev, err := h.Cache.SwapHeartbeat(ctx, monitor.ID, now)
+	if err != nil {
 		// Log error but don't fail the request - Redis is not critical for accepting pings.
 		// The Worker will handle status changes based on what's in Redis.
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "cache error"})
 	}
+	// Track the device's cadence for stale-device hints.
+	if !prev.IsZero() {
+		gap := now.Sub(prev)
+		_ = h.Cache.RecordPingInterval(ctx, monitor.ID, gap)
+		// Log outage-length gaps so outages missed by the checker can be backfilled.
+		threshold := h.OfflineThreshold
+		if monitor.OfflineThresholdSec > 0 {
+			threshold = time.Duration(monitor.OfflineThresholdSec) * time.Second
+		}
+		if threshold > 0 && gap > threshold {
+			_ = h.Cache.RecordHeartbeatGap(ctx, monitor.ID, prev, now)
+		}
+	}
 
 	// Update last_heartbeat_at in database (async, non-blocking).
 	// This is used for display in Telegram bot /info command.
-	go func() {
+	safego.Go("heartbeat_db_update", func() {
 		if err := h.DB.UpdateMonitorHeartbeat(context.Background(), monitor.ID, now); err != nil {
 			// Don't fail the request if DB update fails - heartbeat is already in Redis.
 			// Just log for debugging.
 		}
-	}()
+	})
 
 	metrics.PingTotal.WithLabelValues("ok").Inc()
 	return c.JSON(fiber.Map{"status": "ok"})
 }
 
+// emitHeartbeatEvent queues the ping for the monitor's heartbeat firehose when
+// the owner enabled it, at most once per HeartbeatEventInterval.
+func (h *Handlers) emitHeartbeatEvent(monitor *models.Monitor, at time.Time) {
+	if !monitor.HeartbeatWebhookEnabled || monitor.WebhookURL == "" {
+		return
+	}
+	safego.Go("heartbeat_event", func() {
+		ctx := context.Background()
+		ok, err := h.Cache.TakeHeartbeatEvent(ctx, monitor.ID, HeartbeatEventInterval)
+		if err != nil || !ok {
+			return
+		}
+		if err := h.Cache.AddHeartbeatEvent(ctx, monitor.ID, at); err != nil {
+			log.Printf("[api] heartbeat event of monitor %d: %v", monitor.ID, err)
+		}
+	})
+}
+
+// InvalidateMonitorCache drops the cached /api/monitors response so the next
+// request reloads it. Called for every Redis "monitors changed" announcement;
+// ignored during a surge, when the cached list simply expires.
+func (h *Handlers) InvalidateMonitorCache() {
+	if h.surgeActive() {
+		return
+	}
+	h.monitorCacheMu.Lock()
+	h.monitorCache = nil
+	h.monitorCacheMu.Unlock()
+}
+
+// announceMonitorsChanged invalidates the /api/monitors cache of every API replica.
+func (h *Handlers) announceMonitorsChanged(ctx context.Context, monitorID int64) {
+	if err := h.Cache.PublishMonitorsChanged(ctx, monitorID); err != nil {
+		log.Printf("[api] announce monitor list change: %v", err)
+		h.InvalidateMonitorCache()
+	}
+}
+
+// surgeActive reports whether the worker flagged an outage wave. The flag is
+// read from Redis at most every surgeRecheck.
+func (h *Handlers) surgeActive() bool {
+	h.surgeMu.Lock()
+	defer h.surgeMu.Unlock()
+	if time.Since(h.surgeCheckedAt) >= surgeRecheck {
+		on, err := h.Cache.SurgeActive(context.Background())
+		if err == nil {
+			h.surgeOn = on
+		}
+		h.surgeCheckedAt = time.Now()
+	}
+	return h.surgeOn
+}
+
+// monitorCacheTTL returns how long /api/monitors responses are cached.
+func (h *Handlers) monitorCacheTTL() time.Duration {
+	if h.surgeActive() {
+		return SurgeMonitorCacheTTL
+	}
+	return MonitorCacheTTL
+}
+
 // GetMonitors returns all monitors with status. Response is cached server-side
-// for 15 seconds so thousands of map visitors don't hit the DB.
+// for 15 seconds (a minute during a surge) so thousands of map visitors don't
+// hit the DB: in process, and in Redis so only one API replica renders it.
+// Status changes announced by the worker invalidate both right away.
+// With ?ids=1,2,3 only those public monitors are returned (for embeds).
 func (h *Handlers) GetMonitors(c *fiber.Ctx) error {
+	if c.Query("ids") != "" {
+		return h.getMonitorsByIDs(c)
+	}
+	ttl := h.monitorCacheTTL()
+	maxAge := "public, max-age=" + strconv.Itoa(int(ttl/time.Second))
+
 	// Try serving from cache.
 	h.monitorCacheMu.RLock()
-	if h.monitorCache != nil && time.Since(h.monitorCacheAt) < MonitorCacheTTL {
+	if h.monitorCache != nil && time.Since(h.monitorCacheAt) < ttl {
 		data := h.monitorCache
 		h.monitorCacheMu.RUnlock()
 		c.Set("Content-Type", "application/json")
-		c.Set("Cache-Control", "public, max-age="+strconv.Itoa(MonitorCacheMaxAgeSec))
+		c.Set("Cache-Control", maxAge)
 		return c.Send(data)
 	}
 	h.monitorCacheMu.RUnlock()
@@ -108,13 +303,25 @@ func (h *Handlers) GetMonitors(c *fiber.Ctx) error {
 	defer h.monitorCacheMu.Unlock()
 
 	// Double-check after acquiring write lock.
-	if h.monitorCache != nil && time.Since(h.monitorCacheAt) < MonitorCacheTTL {
+	if h.monitorCache != nil && time.Since(h.monitorCacheAt) < ttl {
 		c.Set("Content-Type", "application/json")
-		c.Set("Cache-Control", "public, max-age="+strconv.Itoa(MonitorCacheMaxAgeSec))
+		c.Set("Cache-Control", maxAge)
 		return c.Send(h.monitorCache)
 	}
 
+	// Another replica may have rendered the list already.
 	ctx := context.Background()
+	version, verErr := h.Cache.ResponseVersion(ctx)
+	if verErr == nil {
+		if data, err := h.Cache.GetResponse(ctx, monitorsResponse, version); err == nil && data != nil {
+			h.monitorCache = data
+			h.monitorCacheAt = time.Now()
+			c.Set("Content-Type", "application/json")
+			c.Set("Cache-Control", maxAge)
+			return c.Send(data)
+		}
+	}
+
 	monitors, err := h.DB.GetPublicMonitors(ctx)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to load monitors"})
@@ -122,16 +329,7 @@ func (h *Handlers) GetMonitors(c *fiber.Ctx) error {
 
 	result := make([]fiber.Map, 0, len(monitors))
 	for _, m := range monitors {
-		result = append(result, fiber.Map{
-			"id":           m.ID,
-			"name":         m.Name,
-			"address":      m.Address,
-			"lat":          m.Latitude,
-			"lng":          m.Longitude,
-			"is_online":    m.IsOnline,
-			"status_since": m.LastStatusChangeAt.UTC().Format(time.RFC3339),
-			"channel_name": m.ChannelName,
-		})
+		result = append(result, h.publicMonitor(m))
 	}
 
 	data, err := json.Marshal(result)
@@ -139,18 +337,137 @@ func (h *Handlers) GetMonitors(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "marshal error"})
 	}
 
-	// Store in cache.
+	// Store in both caches; Redis only under the version read before loading.
 	h.monitorCache = data
 	h.monitorCacheAt = time.Now()
+	if verErr == nil {
+		if err := h.Cache.SetResponse(ctx, monitorsResponse, version, data, ttl); err != nil {
+			log.Printf("[api] store shared monitor list: %v", err)
+		}
+	}
 
 	c.Set("Content-Type", "application/json")
-	c.Set("Cache-Control", "public, max-age="+strconv.Itoa(MonitorCacheMaxAgeSec))
+	c.Set("Cache-Control", maxAge)
 	return c.Send(data)
 }
 
+// getMonitorsByIDs serves /api/monitors?ids=... Private, paused and unknown IDs
+// are left out, so the response may be shorter than the list.
+func (h *Handlers) getMonitorsByIDs(c *fiber.Ctx) error {
+	var ids []int64
+	seen := make(map[int64]bool)
+	for _, s := range strings.Split(c.Query("ids"), ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || id <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid monitor id: " + s})
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ids is empty"})
+	}
+	if len(ids) > MaxMonitorIDs {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "too many ids, max " + strconv.Itoa(MaxMonitorIDs)})
+	}
+
+	monitors, err := h.DB.GetPublicMonitorsByIDs(context.Background(), ids)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to load monitors"})
+	}
+	result := make([]fiber.Map, 0, len(monitors))
+	for _, m := range monitors {
+		result = append(result, h.publicMonitor(m))
+	}
+
+	c.Set("Cache-Control", "public, max-age="+strconv.Itoa(MonitorCacheMaxAgeSec))
+	return c.JSON(result)
+}
+
+// GetMonitorChanges handles GET /api/monitors/changes?since=<RFC3339>: the
+// public monitors whose status changed after since, and in "removed" the IDs
+// of those that left the map. The response's "now" is the since of the next
+// poll, so map clients can refresh cheaply between full loads.
+func (h *Handlers) GetMonitorChanges(c *fiber.Ctx) error {
+	since, err := time.Parse(time.RFC3339, c.Query("since"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "since must be an RFC3339 timestamp"})
+	}
+	now := time.Now()
+	if now.Sub(since) > MaxChangesLookback {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "since is too old, reload /api/monitors"})
+	}
+
+	monitors, removed, err := h.monitorChangesSince(context.Background(), since)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to load monitors"})
+	}
+	return c.JSON(fiber.Map{
+		"now":      now.UTC().Format(time.RFC3339),
+		"monitors": monitors,
+		"removed":  removed,
+	})
+}
+
+// monitorChangesSince loads the public view of the monitors whose status
+// changed at or after since, and the IDs of the monitors hidden from the map
+// since then.
+func (h *Handlers) monitorChangesSince(ctx context.Context, since time.Time) ([]fiber.Map, []int64, error) {
+	monitors, err := h.DB.GetPublicMonitorsChangedSince(ctx, since)
+	if err != nil {
+		return nil, nil, err
+	}
+	removed, err := h.DB.GetMonitorsHiddenSince(ctx, since)
+	if err != nil {
+		return nil, nil, err
+	}
+	result := make([]fiber.Map, 0, len(monitors))
+	for _, m := range monitors {
+		result = append(result, h.publicMonitor(m))
+	}
+	return result, removed, nil
+}
+
+// publicMonitor is the map/embed view of a monitor: no tokens or owner data,
+// and only what the owner's map privacy options allow.
+func (h *Handlers) publicMonitor(m *models.Monitor) fiber.Map {
+	name, address, channel := m.Name, m.Address, m.ChannelName
+	lat, lng := m.Latitude, m.Longitude
+	if m.MapFuzzLocation {
+		// A street address would undo the rounding.
+		lat, lng = geoprivacy.Fuzz(lat, lng, m.ID, h.MapJitterSecret)
+		address = ""
+	}
+	if m.MapHideName {
+		name, address = "", ""
+	}
+	if m.MapHideChannel {
+		channel = ""
+	}
+	return fiber.Map{
+		"id":           m.ID,
+		"name":         name,
+		"address":      address,
+		"lat":          lat,
+		"lng":          lng,
+		"is_online":    m.IsOnline,
+		"status_since": m.LastStatusChangeAt.UTC().Format(time.RFC3339),
+		"channel_name": channel,
+	}
+}
+
 // GetHistory returns status change events for a monitor.
 // Query params: ?from=2026-02-09T00:00:00Z&to=2026-02-10T00:00:00Z
-// Defaults to the last 24 hours if not provided.
+// Defaults to the last 24 hours if not provided. Owner corrections are applied
+// unless ?raw=true is given. The anchor is the last event before from (null if
+// there is none), so clients know the state at the start of the range;
+// initial_online is its is_online, or null when the state is unknown.
 func (h *Handlers) GetHistory(c *fiber.Ctx) error {
 	monitorID, err := c.ParamsInt("id")
 	if err != nil || monitorID <= 0 {
@@ -178,7 +495,18 @@ func (h *Handlers) GetHistory(c *fiber.Ctx) error {
 	}
 
 	ctx := context.Background()
-	events, err := h.DB.GetStatusHistory(ctx, int64(monitorID), from, to)
+	var (
+		anchor *models.StatusEvent
+		events []*models.StatusEvent
+	)
+	if c.QueryBool("raw") {
+		events, err = h.DB.GetStatusHistory(ctx, int64(monitorID), from, to)
+		if err == nil {
+			anchor, err = h.DB.GetLastEventBefore(ctx, int64(monitorID), from)
+		}
+	} else {
+		anchor, events, err = h.DB.GetCorrectedStatusHistory(ctx, int64(monitorID), from, to)
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to load history"})
 	}
@@ -186,12 +514,68 @@ func (h *Handlers) GetHistory(c *fiber.Ctx) error {
 	if events == nil {
 		events = make([]*models.StatusEvent, 0)
 	}
+	var initialOnline *bool
+	if anchor != nil {
+		initialOnline = &anchor.IsOnline
+	}
 
 	return c.JSON(fiber.Map{
-		"monitor_id": monitorID,
-		"from":       from.Format(time.RFC3339),
-		"to":         to.Format(time.RFC3339),
-		"events":     events,
+		"monitor_id":     monitorID,
+		"from":           from.Format(time.RFC3339),
+		"to":             to.Format(time.RFC3339),
+		"anchor":         anchor,
+		"initial_online": initialOnline,
+		"events":         events,
 	})
 }
 
+// legacyPaths maps pages of the old path scheme to their current paths, so
+// links in old channel descriptions and bookmarks keep working. The query
+// string is carried over.
+var legacyPaths = map[string]string{
+	"/map":     "/map.html",
+	"/heatmap": "/heatmap.html",
+}
+
+// legacyPage returns the current path (and query) of an old-scheme page, or ""
+// if path isn't one. The settings page used to take its token as a query
+// parameter: /settings.html?token=T is now /settings/T (other parameters,
+// such as pwd, are kept).
+func legacyPage(c *fiber.Ctx) string {
+	path := strings.TrimSuffix(c.Path(), "/")
+	query, _ := url.ParseQuery(string(c.Request().URI().QueryString()))
+
+	to, ok := legacyPaths[path]
+	if path == "/settings.html" || path == "/settings" {
+		token := query.Get("token")
+		if token == "" {
+			return "/"
+		}
+		query.Del("token")
+		to, ok = "/settings/"+url.PathEscape(token), true
+	}
+	if !ok {
+		return ""
+	}
+	if len(query) > 0 {
+		to += "?" + query.Encode()
+	}
+	return to
+}
+
+// LegacyHostRedirect sends browsers on a legacy hostname, or on a page of the
+// old path scheme, to the current page on the canonical host. /api/* (device
+// pings included) is served on every host so devices configured with the old
+// URL keep working without a firmware change.
+func (h *Handlers) LegacyHostRedirect(c *fiber.Ctx) error {
+	if strings.HasPrefix(c.Path(), "/api/") {
+		return c.Next()
+	}
+	if to := legacyPage(c); to != "" {
+		return c.Redirect(h.Hosts.Canonical()+to, fiber.StatusMovedPermanently)
+	}
+	if !h.Hosts.IsLegacyHost(c.Hostname()) {
+		return c.Next()
+	}
+	return c.Redirect(h.Hosts.Canonical()+c.OriginalURL(), fiber.StatusMovedPermanently)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/models"
+)
+
+// REST API for power users: /api/v1/monitors with an API key issued by the
+// bot's /apikey command. Creating, updating and deleting monitors goes through
+// the same code as the web onboarding and the settings page.
+
+// APIRateLimit is the max number of /api/v1 requests per IP per minute.
+const APIRateLimit = 60
+
+// apiUserKey is the fiber.Locals key holding the *models.User of the API key.
+const apiUserKey = "api_user"
+
+// APIKeyAuth checks the "Authorization: Bearer <key>" header against the
+// stored API keys. Banned users are refused.
+func (h *Handlers) APIKeyAuth(c *fiber.Ctx) error {
+	key, ok := strings.CutPrefix(c.Get("Authorization"), "Bearer ")
+	if !ok || key == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "api key required"})
+	}
+	user, err := h.DB.GetUserByAPIKey(context.Background(), key)
+	if err != nil {
+		log.Printf("[api] key lookup: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "key lookup failed"})
+	}
+	if user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid api key"})
+	}
+	if user.BannedAt != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "account is blocked"})
+	}
+	c.Locals(apiUserKey, user)
+	return c.Next()
+}
+
+// APIListMonitors handles GET /api/v1/monitors: the monitors of the key's user,
+// including those of the primary account when it is linked to one.
+func (h *Handlers) APIListMonitors(c *fiber.Ctx) error {
+	user := c.Locals(apiUserKey).(*models.User)
+	monitors, err := h.DB.GetMonitorsByTelegramID(context.Background(), user.TelegramID)
+	if err != nil {
+		log.Printf("[api] get monitors of user %d: %v", user.TelegramID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to get monitors"})
+	}
+	out := make([]fiber.Map, 0, len(monitors))
+	for _, m := range monitors {
+		out = append(out, fiber.Map{
+			"id":           m.ID,
+			"name":         m.Name,
+			"type":         m.MonitorType,
+			"address":      m.Address,
+			"channel":      m.ChannelName,
+			"is_active":    m.IsActive,
+			"is_online":    m.IsOnline,
+			"is_public":    m.IsPublic,
+			"settings_url": h.Hosts.Canonical() + "/settings/" + m.SettingsToken,
+		})
+	}
+	return c.JSON(out)
+}
+
+// APICreateMonitor handles POST /api/v1/monitors. The body and response are
+// those of POST /api/web/monitors.
+func (h *Handlers) APICreateMonitor(c *fiber.Ctx) error {
+	return h.createMonitor(c, c.Locals(apiUserKey).(*models.User), database.ChangeSourceAPI)
+}
+
+// APIUpdateMonitor handles PUT /api/v1/monitors/:id. The body is that of
+// PUT /api/settings/:token; fields left out stay unchanged.
+func (h *Handlers) APIUpdateMonitor(c *fiber.Ctx) error {
+	m, err := h.apiMonitor(c)
+	if m == nil {
+		return err
+	}
+	var req settingsUpdateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	return h.applySettings(c, m, &req, database.ChangeSourceAPI)
+}
+
+// APIDeleteMonitor handles DELETE /api/v1/monitors/:id.
+func (h *Handlers) APIDeleteMonitor(c *fiber.Ctx) error {
+	m, err := h.apiMonitor(c)
+	if m == nil {
+		return err
+	}
+	if err := h.deleteMonitor(context.Background(), m, database.ChangeSourceAPI); err != nil {
+		log.Printf("[api] delete monitor %d: %v", m.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to delete monitor"})
+	}
+	return c.JSON(fiber.Map{"status": "ok"})
+}
+
+// apiMonitor loads the monitor named by the :id parameter if the key's user
+// may manage it. Otherwise it answers the request and returns a nil monitor.
+func (h *Handlers) apiMonitor(c *fiber.Ctx) (*models.Monitor, error) {
+	user := c.Locals(apiUserKey).(*models.User)
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid monitor id"})
+	}
+	ctx := context.Background()
+	ownerID, err := h.DB.OwnerUserID(ctx, user)
+	if err != nil {
+		log.Printf("[api] resolve owner of user %d: %v", user.TelegramID, err)
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to get monitor"})
+	}
+	m, err := h.DB.GetMonitorByID(ctx, id)
+	if err != nil || m.UserID != ownerID {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "monitor not found"})
+	}
+	return m, nil
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"no-lights-monitor/internal/database"
+)
+
+// MaxBanReasonRunes caps the ban reason stored with a user.
+const MaxBanReasonRunes = 500
+
+// AdminGetBannedUsers returns every banned user with the reason.
+func (h *Handlers) AdminGetBannedUsers(c *fiber.Ctx) error {
+	users, err := h.DB.GetBannedUsers(context.Background())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to load banned users"})
+	}
+	if users == nil {
+		return c.JSON([]struct{}{})
+	}
+	return c.JSON(users)
+}
+
+// AdminBanUser shadow-bans a Telegram user. Body: {"reason": "..."}.
+// The bot politely refuses their commands, their monitors disappear from the
+// public map, their pings are accepted but ignored and the worker stops
+// checking their monitors. The reason is written to the audit log of each of
+// their monitors.
+func (h *Handlers) AdminBanUser(c *fiber.Ctx) error {
+	telegramID, err := strconv.ParseInt(c.Params("telegram_id"), 10, 64)
+	if err != nil || telegramID == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid telegram id"})
+	}
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	_ = c.BodyParser(&req)
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "reason is required"})
+	}
+	if len([]rune(reason)) > MaxBanReasonRunes {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "reason is too long"})
+	}
+
+	ctx := context.Background()
+	monitorIDs, err := h.DB.BanUser(ctx, telegramID, reason)
+	if err != nil {
+		log.Printf("[admin] ban user %d: %v", telegramID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to ban user"})
+	}
+	for _, id := range monitorIDs {
+		h.recordAdminChange(ctx, id, database.ChangeFieldOwnerBanned, "", reason)
+	}
+	h.announceMonitorsChanged(ctx, 0)
+	log.Printf("[admin] user %d banned (%d monitors): %s", telegramID, len(monitorIDs), reason)
+	return c.JSON(fiber.Map{"telegram_id": telegramID, "monitors": len(monitorIDs)})
+}
+
+// AdminUnbanUser lifts a ban. The user's monitors return to the public map
+// right away and resume going online with the next ping.
+func (h *Handlers) AdminUnbanUser(c *fiber.Ctx) error {
+	telegramID, err := strconv.ParseInt(c.Params("telegram_id"), 10, 64)
+	if err != nil || telegramID == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid telegram id"})
+	}
+
+	ctx := context.Background()
+	monitorIDs, ok, err := h.DB.UnbanUser(ctx, telegramID)
+	if err != nil {
+		log.Printf("[admin] unban user %d: %v", telegramID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to unban user"})
+	}
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user is not banned"})
+	}
+	for _, id := range monitorIDs {
+		h.recordAdminChange(ctx, id, database.ChangeFieldOwnerBanned, "banned", "")
+	}
+	h.announceMonitorsChanged(ctx, 0)
+	log.Printf("[admin] user %d unbanned", telegramID)
+	return c.JSON(fiber.Map{"telegram_id": telegramID, "monitors": len(monitorIDs)})
+}
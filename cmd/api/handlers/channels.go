@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"no-lights-monitor/internal/mq"
+)
+
+// ChannelCheckRateLimit is the max number of channel checks per IP per minute.
+// Each check costs the bot two Telegram API calls.
+const ChannelCheckRateLimit = 10
+
+// channelUsernameRe matches a public Telegram channel username (without @).
+var channelUsernameRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{3,31}$`)
+
+// CheckChannelRights handles GET /api/channels/check?channel=@name. It asks the
+// bot, over the command bus, whether it is an admin allowed to post in the
+// channel, so a web onboarding flow can guide the user before creating a monitor.
+// Only public channels can be checked this way; private ones are linked in the bot.
+func (h *Handlers) CheckChannelRights(c *fiber.Ctx) error {
+	name := strings.TrimPrefix(strings.TrimSpace(c.Query("channel")), "@")
+	if !channelUsernameRe.MatchString(name) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "channel must be a public @username"})
+	}
+
+	res, err := h.Commands.Call(context.Background(), mq.BotCommand{Type: mq.CommandCheckChannelRights, Channel: "@" + name})
+	if err != nil {
+		log.Printf("[channels] check @%s: %v", name, err)
+		return c.Status(fiber.StatusGatewayTimeout).JSON(fiber.Map{"error": "bot did not respond, try again later"})
+	}
+
+	return c.JSON(fiber.Map{
+		"ok":       res.OK,
+		"error":    res.Error, // not_found | not_admin | no_post_right | check_failed
+		"channel":  res.ChannelUsername,
+		"title":    res.ChannelTitle,
+		"can_post": res.OK,
+	})
+}
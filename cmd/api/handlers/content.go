@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"no-lights-monitor/internal/contentfilter"
+	"no-lights-monitor/internal/database"
+)
+
+// MaxBannedWords caps the admin's banned word list.
+const MaxBannedWords = 1000
+
+// contentProblem returns a user-facing reason a name or address is rejected,
+// or "" if the content filter accepts it.
+func (h *Handlers) contentProblem(ctx context.Context, field contentfilter.Field, text string) string {
+	if h.Filter == nil {
+		return ""
+	}
+	err := h.Filter.Check(ctx, field, text)
+	what := "name"
+	if field == contentfilter.Address {
+		what = "address"
+	}
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, contentfilter.ErrTooLong):
+		return what + " is too long"
+	case errors.Is(err, contentfilter.ErrTooManyEmoji):
+		return fmt.Sprintf("%s has more than %d emoji", what, contentfilter.MaxEmoji)
+	default:
+		return what + " contains a banned word"
+	}
+}
+
+// AdminGetBannedWords returns the content filter's banned words.
+func (h *Handlers) AdminGetBannedWords(c *fiber.Ctx) error {
+	words, err := h.DB.GetBannedWords(context.Background())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to load banned words"})
+	}
+	if words == nil {
+		words = []string{}
+	}
+	return c.JSON(fiber.Map{"words": words})
+}
+
+// AdminSetBannedWords replaces the banned word list. Body: {"words": ["...", ...]}.
+// Words are matched case-insensitively inside names and addresses, ignoring
+// spaces and punctuation, so a stem also bans its inflected forms.
+func (h *Handlers) AdminSetBannedWords(c *fiber.Ctx) error {
+	var req struct {
+		Words []string `json:"words"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+	words := make([]string, 0, len(req.Words))
+	for _, w := range req.Words {
+		if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+			words = append(words, w)
+		}
+	}
+	if len(words) > MaxBannedWords {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "too many words"})
+	}
+
+	if err := h.DB.SetBannedWords(context.Background(), words); err != nil {
+		log.Printf("[admin] set banned words: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to save banned words"})
+	}
+	if h.Filter != nil {
+		h.Filter.Invalidate()
+	}
+	log.Printf("[admin] banned word list replaced (%d words)", len(words))
+	return c.JSON(fiber.Map{"words": words})
+}
+
+// AdminUpdateMonitor lets an admin fix a monitor's name or address, e.g. one
+// that was created before a word was banned. Body: {"name": "...", "address": "..."};
+// omitted fields are left unchanged. Coordinates are kept.
+func (h *Handlers) AdminUpdateMonitor(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid monitor id"})
+	}
+	var req struct {
+		Name    *string `json:"name"`
+		Address *string `json:"address"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+
+	ctx := context.Background()
+	m, err := h.DB.GetMonitorByID(ctx, id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "monitor not found"})
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if len([]rune(name)) < 2 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "name is too short"})
+		}
+		if msg := h.contentProblem(ctx, contentfilter.Name, name); msg != "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+		}
+		if name != m.Name {
+			if err := h.DB.UpdateMonitorName(ctx, m.ID, name); err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update name"})
+			}
+			h.recordAdminChange(ctx, m.ID, "name", m.Name, name)
+		}
+	}
+	if req.Address != nil {
+		address := strings.TrimSpace(*req.Address)
+		if len([]rune(address)) < 3 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "address is too short"})
+		}
+		if msg := h.contentProblem(ctx, contentfilter.Address, address); msg != "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+		}
+		if address != m.Address {
+			if err := h.DB.UpdateMonitorAddress(ctx, m.ID, address, m.Latitude, m.Longitude); err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update address"})
+			}
+			h.recordAdminChange(ctx, m.ID, "address", m.Address, address)
+		}
+	}
+	return c.JSON(fiber.Map{"status": "ok"})
+}
+
+// recordAdminChange stores an audit entry for a field changed from the admin panel.
+func (h *Handlers) recordAdminChange(ctx context.Context, monitorID int64, field, oldVal, newVal string) {
+	if err := h.DB.RecordMonitorChange(ctx, monitorID, database.ChangeSourceAdmin, field, oldVal, newVal); err != nil {
+		log.Printf("[admin] record %s change for monitor %d: %v", field, monitorID, err)
+	}
+}
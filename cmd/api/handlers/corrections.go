@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"no-lights-monitor/internal/models"
+	"no-lights-monitor/internal/mq"
+)
+
+const (
+	recentCorrectionsLimit = 50
+	maxCorrectionNote      = 200
+
+	// maxCorrectionSpan caps a single correction; longer periods need several.
+	maxCorrectionSpan = 31 * 24 * time.Hour
+	// maxCorrectionAge is how far back an owner may correct the status.
+	maxCorrectionAge = 90 * 24 * time.Hour
+)
+
+// GetCorrections lists the owner's status corrections, newest first.
+func (h *Handlers) GetCorrections(c *fiber.Ctx) error {
+	m, ok := h.settingsMonitor(c)
+	if !ok {
+		return nil
+	}
+	list, err := h.DB.GetRecentStatusCorrections(context.Background(), m.ID, recentCorrectionsLimit)
+	if err != nil {
+		log.Printf("[settings] get corrections for monitor %d: %v", m.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to load corrections"})
+	}
+	if list == nil {
+		list = []*models.StatusCorrection{}
+	}
+	return c.JSON(list)
+}
+
+// AddCorrection marks a period as "actually had power" (is_online=true) or
+// "actually had no power" (false). Raw status events are kept; the correction
+// is overlaid when graphs and history are built.
+func (h *Handlers) AddCorrection(c *fiber.Ctx) error {
+	m, ok := h.settingsMonitor(c)
+	if !ok {
+		return nil
+	}
+
+	var req struct {
+		IsOnline *bool     `json:"is_online"`
+		Start    time.Time `json:"start"`
+		End      time.Time `json:"end"`
+		Note     string    `json:"note"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.IsOnline == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "is_online, start and end are required"})
+	}
+	now := time.Now()
+	switch {
+	case req.Start.IsZero() || req.End.IsZero() || !req.Start.Before(req.End):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "start must be before end"})
+	case req.End.After(now):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "cannot correct the future"})
+	case req.End.Sub(req.Start) > maxCorrectionSpan:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "period is too long"})
+	case now.Sub(req.Start) > maxCorrectionAge:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "period is too old"})
+	}
+	note := strings.TrimSpace(req.Note)
+	if len([]rune(note)) > maxCorrectionNote {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "note is too long"})
+	}
+
+	ctx := context.Background()
+	corr, err := h.DB.AddStatusCorrection(ctx, m.ID, *req.IsOnline, req.Start, req.End, note)
+	if err != nil {
+		log.Printf("[settings] add correction for monitor %d: %v", m.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to save correction"})
+	}
+	log.Printf("[settings] monitor %d: status corrected to online=%v for %s – %s", m.ID, corr.IsOnline, corr.StartAt.Format(time.RFC3339), corr.EndAt.Format(time.RFC3339))
+	h.refreshGraph(ctx, m)
+
+	return c.Status(fiber.StatusCreated).JSON(corr)
+}
+
+// DeleteCorrection removes a status correction, restoring the raw history.
+func (h *Handlers) DeleteCorrection(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	m, ok := h.settingsMonitor(c)
+	if !ok {
+		return nil
+	}
+
+	ctx := context.Background()
+	deleted, err := h.DB.DeleteStatusCorrection(ctx, m.ID, id)
+	if err != nil {
+		log.Printf("[settings] delete correction %d for monitor %d: %v", id, m.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to delete correction"})
+	}
+	if !deleted {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "correction not found"})
+	}
+	h.refreshGraph(ctx, m)
+
+	return c.JSON(fiber.Map{"status": "ok"})
+}
+
+// settingsMonitor resolves the monitor of a settings request and checks its
+// password. On failure the error response has already been written.
+func (h *Handlers) settingsMonitor(c *fiber.Ctx) (*models.Monitor, bool) {
+	token := c.Params("token")
+	if token == "" {
+		_ = c.SendStatus(fiber.StatusBadRequest)
+		return nil, false
+	}
+	m, err := h.DB.GetMonitorBySettingsToken(context.Background(), token)
+	if err != nil {
+		_ = c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "monitor not found"})
+		return nil, false
+	}
+	if !checkSettingsPassword(c, m.SettingsPassword) {
+		_ = c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid password"})
+		return nil, false
+	}
+	return m, true
+}
+
+// refreshGraph asks the worker to redraw the channel's weekly graph.
+func (h *Handlers) refreshGraph(ctx context.Context, m *models.Monitor) {
+	if m.ChannelID == 0 || !m.GraphEnabled {
+		return
+	}
+	if err := h.MQPublisher.Publish(ctx, mq.RoutingGraphRequest, mq.GraphRequestMsg{MonitorID: m.ID, ChannelID: m.ChannelID}); err != nil {
+		log.Printf("[settings] graph refresh for monitor %d: %v", m.ID, err)
+	}
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"no-lights-monitor/internal/models"
+)
+
+// Extra heartbeat devices. A heartbeat monitor can have several devices on
+// different circuits or uplinks, each pinging its own URL; the monitor only
+// goes offline when all of them (and its own ping URL) fall silent.
+
+const (
+	// maxMonitorDevices bounds the extra devices of one monitor.
+	maxMonitorDevices = 5
+	// maxDeviceNameLen bounds the label an owner gives a device.
+	maxDeviceNameLen = 40
+)
+
+// deviceJSON renders a device for the settings page, with its ping URL.
+func (h *Handlers) deviceJSON(d models.MonitorDevice) fiber.Map {
+	return fiber.Map{
+		"id":           d.ID,
+		"name":         d.Name,
+		"ping_url":     h.Hosts.PingURL(h.Hosts.Canonical(), d.Token),
+		"created_at":   d.CreatedAt,
+		"last_ping_at": d.LastPingAt,
+	}
+}
+
+// settingsHeartbeatMonitor resolves and authorizes the monitor of a settings
+// request for the device routes. On failure it has already answered and
+// returns nil.
+func (h *Handlers) settingsHeartbeatMonitor(c *fiber.Ctx) (*models.Monitor, error) {
+	token := c.Params("token")
+	if token == "" {
+		return nil, c.SendStatus(fiber.StatusBadRequest)
+	}
+	m, err := h.DB.GetMonitorBySettingsToken(context.Background(), token)
+	if err != nil {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "monitor not found"})
+	}
+	if !checkSettingsPassword(c, m.SettingsPassword) {
+		return nil, c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid password"})
+	}
+	if m.MonitorType != "heartbeat" {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "devices are only available for heartbeat monitors"})
+	}
+	return m, nil
+}
+
+// GetDevices handles GET /api/settings/:token/devices.
+func (h *Handlers) GetDevices(c *fiber.Ctx) error {
+	m, err := h.settingsHeartbeatMonitor(c)
+	if m == nil {
+		return err
+	}
+	devices, err := h.DB.GetMonitorDevices(context.Background(), m.ID)
+	if err != nil {
+		log.Printf("[devices] list for monitor %d: %v", m.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to load devices"})
+	}
+	out := make([]fiber.Map, 0, len(devices))
+	for _, d := range devices {
+		out = append(out, h.deviceJSON(d))
+	}
+	return c.JSON(fiber.Map{"devices": out, "max_devices": maxMonitorDevices})
+}
+
+// CreateDevice handles POST /api/settings/:token/devices: adds a device with
+// its own ping URL. Body: {"name": "..."} (optional).
+func (h *Handlers) CreateDevice(c *fiber.Ctx) error {
+	m, err := h.settingsHeartbeatMonitor(c)
+	if m == nil {
+		return err
+	}
+	var req struct {
+		Name string `json:"name"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid JSON"})
+		}
+	}
+	name := strings.TrimSpace(req.Name)
+	if len([]rune(name)) > maxDeviceNameLen {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "name must be at most " + strconv.Itoa(maxDeviceNameLen) + " characters"})
+	}
+
+	d, err := h.DB.CreateMonitorDevice(context.Background(), m.ID, name, maxMonitorDevices)
+	if err != nil {
+		log.Printf("[devices] create for monitor %d: %v", m.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to add device"})
+	}
+	if d == nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "too many devices for this monitor"})
+	}
+	log.Printf("[devices] device %d added to monitor %d", d.ID, m.ID)
+	return c.Status(fiber.StatusCreated).JSON(h.deviceJSON(*d))
+}
+
+// DeleteDevice handles DELETE /api/settings/:token/devices/:id. The device's
+// ping URL stops working immediately.
+func (h *Handlers) DeleteDevice(c *fiber.Ctx) error {
+	m, err := h.settingsHeartbeatMonitor(c)
+	if m == nil {
+		return err
+	}
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	ctx := context.Background()
+	ok, err := h.DB.DeleteMonitorDevice(ctx, m.ID, id)
+	if err != nil {
+		log.Printf("[devices] delete %d of monitor %d: %v", id, m.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to remove device"})
+	}
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "device not found"})
+	}
+	if err := h.Cache.DeleteDeviceHeartbeat(ctx, m.ID, id); err != nil {
+		log.Printf("[devices] forget heartbeat of device %d: %v", id, err)
+	}
+	log.Printf("[devices] device %d removed from monitor %d", id, m.ID)
+	return c.JSON(fiber.Map{"status": "ok"})
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"no-lights-monitor/internal/geocode"
+)
+
+const (
+	// GeocodeRateLimit is the max number of /api/geocode requests per IP per minute.
+	GeocodeRateLimit = 20
+
+	// geocodeTimeout bounds one geocoding request of a page, the wait for a
+	// Nominatim slot included.
+	geocodeTimeout = 15 * time.Second
+)
+
+// geocodeFailed answers a failed geocoding request: 503 when Nominatim's
+// queue is full or the request ran out of time waiting, 502 otherwise.
+func geocodeFailed(c *fiber.Ctx, err error) error {
+	if errors.Is(err, geocode.ErrBusy) || errors.Is(err, context.DeadlineExceeded) {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "geocoding is busy, try again later"})
+	}
+	return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "geocoding failed"})
+}
+
+// Geocode handles GET /api/geocode?q=... for the settings page, so the browser
+// never calls Nominatim directly. Results are cached and rate limited per IP.
+func (h *Handlers) Geocode(c *fiber.Ctx) error {
+	q := strings.TrimSpace(c.Query("q"))
+	if len(q) < 3 || len(q) > maxAddressLen {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "address must be 3-300 characters"})
+	}
+	ctx, cancel := context.WithTimeout(c.UserContext(), geocodeTimeout)
+	defer cancel()
+	result, err := h.Geocoder.Lookup(ctx, q)
+	if err != nil {
+		log.Printf("[geocode] %s q=%q: %v", c.IP(), q, err)
+		return geocodeFailed(c, err)
+	}
+	log.Printf("[geocode] %s q=%q found=%v", c.IP(), q, result != nil)
+	if result == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "address not found"})
+	}
+	return c.JSON(fiber.Map{
+		"address":   result.DisplayName,
+		"latitude":  result.Latitude,
+		"longitude": result.Longitude,
+	})
+}
+
+// GeocodeSuggest handles GET /api/geocode/suggest?q=... for the settings page
+// address dropdown. It shares the cache and rate limit with Geocode.
+func (h *Handlers) GeocodeSuggest(c *fiber.Ctx) error {
+	q := strings.TrimSpace(c.Query("q"))
+	if len(q) < 3 || len(q) > maxAddressLen {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "address must be 3-300 characters"})
+	}
+	ctx, cancel := context.WithTimeout(c.UserContext(), geocodeTimeout)
+	defer cancel()
+	results, err := h.Geocoder.Candidates(ctx, q)
+	if err != nil {
+		log.Printf("[geocode] suggest %s q=%q: %v", c.IP(), q, err)
+		return geocodeFailed(c, err)
+	}
+
+	suggestions := make([]fiber.Map, 0, len(results))
+	for _, r := range results {
+		suggestions = append(suggestions, fiber.Map{
+			"address":   r.DisplayName,
+			"latitude":  r.Latitude,
+			"longitude": r.Longitude,
+		})
+	}
+	return c.JSON(fiber.Map{"suggestions": suggestions})
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// SettingsRateLimit is the max number of settings API requests per IP per minute.
+	SettingsRateLimit = 30
+	// SettingsMaxFailures is how many bad tokens/passwords an IP may send within
+	// SettingsFailureWindow before it gets locked out.
+	SettingsMaxFailures   = 10
+	SettingsFailureWindow = 15 * time.Minute
+	// SettingsLockout is how long a locked-out IP is refused.
+	SettingsLockout = 30 * time.Minute
+)
+
+// SettingsGuard refuses locked-out IPs and counts failed settings lookups
+// (unknown token or wrong password) towards a temporary lockout.
+func (h *Handlers) SettingsGuard(c *fiber.Ctx) error {
+	ctx := context.Background()
+	ip := c.IP()
+
+	if h.Cache.IsSettingsLocked(ctx, ip) {
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "too many failed attempts, try again later"})
+	}
+
+	err := c.Next()
+
+	status := c.Response().StatusCode()
+	if status != fiber.StatusNotFound && status != fiber.StatusUnauthorized {
+		return err
+	}
+
+	n, cerr := h.Cache.RecordSettingsFailure(ctx, ip, SettingsFailureWindow)
+	if cerr != nil {
+		log.Printf("[settings] record failure for %s: %v", ip, cerr)
+		return err
+	}
+	if n >= SettingsMaxFailures {
+		log.Printf("[settings] suspicious access: %s made %d failed attempts (last %s %s), locking for %s",
+			ip, n, c.Method(), c.Path(), SettingsLockout)
+		if cerr := h.Cache.LockSettings(ctx, ip, SettingsLockout); cerr != nil {
+			log.Printf("[settings] lock %s: %v", ip, cerr)
+		}
+	}
+	return err
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"no-lights-monitor/internal/tiles"
+)
+
+// heatmapMaxAgeSec matches the worker's rebuild interval.
+const heatmapMaxAgeSec = 60
+
+// GetHeatmapTile handles GET /api/heatmap/:z/:x/:y.json: the pre-aggregated
+// heat layer cells of one slippy map tile, built by the worker every minute.
+// Tiles without monitors (or before the first build) have no cells.
+func (h *Handlers) GetHeatmapTile(c *fiber.Ctx) error {
+	z, errZ := strconv.Atoi(c.Params("z"))
+	x, errX := strconv.Atoi(c.Params("x"))
+	y, errY := strconv.Atoi(strings.TrimSuffix(c.Params("y"), ".json"))
+	if errZ != nil || errX != nil || errY != nil || !tiles.Valid(z, x, y) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid tile, zoom must be " + strconv.Itoa(tiles.MinZoom) + "-" + strconv.Itoa(tiles.MaxZoom),
+		})
+	}
+
+	data, err := h.Cache.GetHeatmapTile(context.Background(), tiles.Key(z, x, y))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to load tile"})
+	}
+	if data == nil {
+		data, _ = json.Marshal(tiles.Tile{Z: z, X: x, Y: y, Cells: []tiles.Cell{}})
+	}
+	c.Set("Content-Type", "application/json")
+	c.Set("Cache-Control", "public, max-age="+strconv.Itoa(heatmapMaxAgeSec))
+	return c.Send(data)
+}
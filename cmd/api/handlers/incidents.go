@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"no-lights-monitor/internal/geoprivacy"
+)
+
+const (
+	// DefaultIncidentsLookback is the time range of /api/incidents without ?from.
+	DefaultIncidentsLookback = 30 * 24 * time.Hour
+	// MaxIncidentsRange is the widest ?from..?to range of /api/incidents.
+	MaxIncidentsRange = 366 * 24 * time.Hour
+	// IncidentsRateLimit is the max /api/incidents requests per IP per minute.
+	IncidentsRateLimit = 30
+	// MaxIncidents caps the number of incidents in one response.
+	MaxIncidents = 1000
+	// incidentAreaMinMonitors is how many public monitors a group needs before
+	// its area is published.
+	incidentAreaMinMonitors = 3
+	// incidentsMaxAgeSec is the Cache-Control max-age of /api/incidents.
+	incidentsMaxAgeSec = 60
+)
+
+// GetIncidents handles GET /api/incidents: the mass outages detected by the
+// incident subsystem, for media and researchers. Query params (RFC3339):
+// ?from= defaults to 30 days ago, ?to= to now; ongoing incidents have a null
+// ended_at. The area is the bounding box of the group's current public
+// monitors, widened to the map privacy grid, and null for small groups.
+func (h *Handlers) GetIncidents(c *fiber.Ctx) error {
+	now := time.Now()
+	to := now
+	if s := c.Query("to"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "to must be an RFC3339 timestamp"})
+		}
+		to = t
+	}
+	from := to.Add(-DefaultIncidentsLookback)
+	if s := c.Query("from"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "from must be an RFC3339 timestamp"})
+		}
+		from = t
+	}
+	if !from.Before(to) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "from must be before to"})
+	}
+	if to.Sub(from) > MaxIncidentsRange {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "time range too large, max " + strconv.Itoa(int(MaxIncidentsRange.Hours()/24)) + " days"})
+	}
+
+	ctx := context.Background()
+	incidents, err := h.DB.GetIncidents(ctx, from, to, MaxIncidents)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to load incidents"})
+	}
+	bounds, err := h.DB.GetOutageGroupBounds(ctx, incidentAreaMinMonitors)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to load incident areas"})
+	}
+	areas := make(map[string]fiber.Map, len(bounds))
+	for _, b := range bounds {
+		minLat, minLng, maxLat, maxLng := geoprivacy.Box(b.MinLat, b.MinLng, b.MaxLat, b.MaxLng)
+		areas[b.Region+"/"+b.OutageGroup] = fiber.Map{
+			"bbox": []float64{minLng, minLat, maxLng, maxLat}, // GeoJSON order
+		}
+	}
+
+	result := make([]fiber.Map, 0, len(incidents))
+	for _, inc := range incidents {
+		var endedAt any
+		if inc.EndedAt != nil {
+			endedAt = inc.EndedAt.UTC().Format(time.RFC3339)
+		}
+		var area any
+		if a, ok := areas[inc.Region+"/"+inc.OutageGroup]; ok {
+			area = a
+		}
+		result = append(result, fiber.Map{
+			"id":           inc.ID,
+			"region":       inc.Region,
+			"outage_group": inc.OutageGroup,
+			"started_at":   inc.StartedAt.UTC().Format(time.RFC3339),
+			"ended_at":     endedAt,
+			"affected":     inc.Affected,
+			"total":        inc.Total,
+			"peak":         inc.Peak,
+			"area":         area,
+		})
+	}
+
+	c.Set("Cache-Control", "public, max-age="+strconv.Itoa(incidentsMaxAgeSec))
+	return c.JSON(fiber.Map{
+		"from":      from.UTC().Format(time.RFC3339),
+		"to":        to.UTC().Format(time.RFC3339),
+		"incidents": result,
+	})
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"no-lights-monitor/internal/metrics"
+)
+
+// MonitorMetrics returns the handler of /metrics/monitors: per-monitor uptime
+// gauges in the Prometheus text format, for self-hosters who want Grafana
+// alerts of their own. Scrapers authenticate with "Authorization: Bearer <key>"
+// (or ?key= for tools that can't set headers).
+func (h *Handlers) MonitorMetrics(key string) fiber.Handler {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(metrics.NewMonitorCollector(h.DB.GetAllMonitors))
+	serve := adaptor.HTTPHandler(promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+
+	return func(c *fiber.Ctx) error {
+		got := c.Query("key")
+		if auth := c.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			got = strings.TrimPrefix(auth, "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(got), []byte(key)) != 1 {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid api key"})
+		}
+		return serve(c)
+	}
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"no-lights-monitor/internal/cache"
+)
+
+// Remote probe agents ping the targets of ping monitors from other networks.
+// Their votes are combined with the worker's own ping by quorum, so a routing
+// problem at the main server doesn't flip every ping monitor offline.
+
+const (
+	// maxProbeResults caps one POST /api/probe/results batch.
+	maxProbeResults = 5000
+	// maxProbeSkew rejects results timestamped too far from the server clock.
+	maxProbeSkew = 5 * time.Minute
+)
+
+// probeAgentKey is the fiber.Locals key holding the authenticated agent name.
+const probeAgentKey = "probe_agent"
+
+// ParseProbeAgents turns "name:token" pairs (PROBE_AGENT_TOKENS) into a name → token map.
+// Malformed pairs are skipped with a log line.
+func ParseProbeAgents(pairs []string) map[string]string {
+	agents := make(map[string]string, len(pairs))
+	for _, p := range pairs {
+		name, token, ok := strings.Cut(p, ":")
+		name, token = strings.TrimSpace(name), strings.TrimSpace(token)
+		if !ok || name == "" || token == "" {
+			log.Printf("[probe] ignoring malformed agent entry %q", p)
+			continue
+		}
+		agents[name] = token
+	}
+	return agents
+}
+
+// ProbeAuth requires "Authorization: Bearer <token>" of a configured probe agent.
+func (h *Handlers) ProbeAuth(c *fiber.Ctx) error {
+	token, ok := strings.CutPrefix(c.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+	for name, want := range h.ProbeAgents {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1 {
+			c.Locals(probeAgentKey, name)
+			return c.Next()
+		}
+	}
+	return c.SendStatus(fiber.StatusUnauthorized)
+}
+
+// GetProbeTargets handles GET /api/probe/targets: the active ping monitors an agent should ping.
+func (h *Handlers) GetProbeTargets(c *fiber.Ctx) error {
+	monitors, err := h.DB.GetAllMonitors(context.Background())
+	if err != nil {
+		log.Printf("[probe] get monitors: %v", err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	type target struct {
+		MonitorID int64  `json:"monitor_id"`
+		Target    string `json:"target"`
+	}
+	targets := []target{}
+	for _, m := range monitors {
+		if m.MonitorType == "ping" && m.IsActive && m.PingTarget != "" {
+			targets = append(targets, target{MonitorID: m.ID, Target: m.PingTarget})
+		}
+	}
+	return c.JSON(fiber.Map{"targets": targets})
+}
+
+// PostProbeResults handles POST /api/probe/results with
+// {"results": [{"monitor_id": 1, "ok": true, "at": "2024-01-01T00:00:00Z"}]}.
+// Each result becomes the agent's vote for that monitor.
+func (h *Handlers) PostProbeResults(c *fiber.Ctx) error {
+	agent := c.Locals(probeAgentKey).(string)
+
+	var req struct {
+		Results []struct {
+			MonitorID int64     `json:"monitor_id"`
+			OK        bool      `json:"ok"`
+			At        time.Time `json:"at"`
+		} `json:"results"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+	if len(req.Results) > maxProbeResults {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": "too many results"})
+	}
+
+	ctx := context.Background()
+	monitors, err := h.DB.GetAllMonitors(ctx)
+	if err != nil {
+		log.Printf("[probe] get monitors: %v", err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+	pingMonitors := make(map[int64]bool)
+	for _, m := range monitors {
+		if m.MonitorType == "ping" && m.IsActive {
+			pingMonitors[m.ID] = true
+		}
+	}
+
+	now := time.Now()
+	accepted := 0
+	for _, r := range req.Results {
+		if !pingMonitors[r.MonitorID] {
+			continue
+		}
+		at := r.At
+		if at.IsZero() {
+			at = now
+		}
+		if d := now.Sub(at); d > maxProbeSkew || d < -maxProbeSkew {
+			continue
+		}
+		if err := h.Cache.RecordProbe(ctx, r.MonitorID, cache.AgentVantage(agent), r.OK, at); err != nil {
+			log.Printf("[probe] record vote of %s for monitor %d: %v", agent, r.MonitorID, err)
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		accepted++
+	}
+	return c.JSON(fiber.Map{"accepted": accepted})
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"no-lights-monitor/internal/cache"
+	"no-lights-monitor/internal/push"
+)
+
+// Companion app device registration. The app subscribes its APNs or FCM token
+// either to one monitor (with the monitor's settings token, like the settings
+// page) or to all monitors of a Telegram user (with a /api/web session). The
+// worker pushes every published status change to the subscribed devices.
+
+// pushDeviceRequest is the JSON body of the device registration routes.
+type pushDeviceRequest struct {
+	Platform string `json:"platform"` // push.PlatformAPNs or push.PlatformFCM
+	Token    string `json:"token"`
+}
+
+// parsePushDevice reads and validates the device from the request body,
+// returning an error message for the client when it is invalid.
+func parsePushDevice(c *fiber.Ctx) (pushDeviceRequest, string) {
+	var req pushDeviceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return req, "invalid JSON"
+	}
+	req.Platform = strings.ToLower(strings.TrimSpace(req.Platform))
+	req.Token = strings.TrimSpace(req.Token)
+	if req.Platform != push.PlatformAPNs && req.Platform != push.PlatformFCM {
+		return req, "platform must be apns or fcm"
+	}
+	if !push.ValidToken(req.Platform, req.Token) {
+		return req, "invalid device token"
+	}
+	return req, ""
+}
+
+// RegisterPushDevice handles POST /api/settings/:token/push: subscribes a
+// device to the monitor's status changes.
+func (h *Handlers) RegisterPushDevice(c *fiber.Ctx) error {
+	token := c.Params("token")
+	if token == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	ctx := context.Background()
+	m, err := h.DB.GetMonitorBySettingsToken(ctx, token)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "monitor not found"})
+	}
+
+	if !checkSettingsPassword(c, m.SettingsPassword) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid password"})
+	}
+
+	req, msg := parsePushDevice(c)
+	if msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+	ok, err := h.DB.RegisterPushDevice(ctx, m.ID, req.Platform, req.Token, push.MaxDevicesPerMonitor)
+	if err != nil {
+		log.Printf("[push] register device for monitor %d: %v", m.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to register device"})
+	}
+	if !ok {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "too many devices for this monitor"})
+	}
+	return c.JSON(fiber.Map{"status": "ok", "monitor_ids": []int64{m.ID}})
+}
+
+// UnregisterPushDevice handles DELETE /api/settings/:token/push.
+func (h *Handlers) UnregisterPushDevice(c *fiber.Ctx) error {
+	token := c.Params("token")
+	if token == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	ctx := context.Background()
+	m, err := h.DB.GetMonitorBySettingsToken(ctx, token)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "monitor not found"})
+	}
+
+	if !checkSettingsPassword(c, m.SettingsPassword) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid password"})
+	}
+
+	req, msg := parsePushDevice(c)
+	if msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+	if err := h.DB.DeletePushDevice(ctx, m.ID, req.Platform, req.Token); err != nil {
+		log.Printf("[push] unregister device for monitor %d: %v", m.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to unregister device"})
+	}
+	return c.JSON(fiber.Map{"status": "ok"})
+}
+
+// WebRegisterPushDevice handles POST /api/web/push: subscribes a device to
+// all monitors of the signed-in Telegram user. The app calls it again after
+// the user creates a monitor.
+func (h *Handlers) WebRegisterPushDevice(c *fiber.Ctx) error {
+	s := c.Locals(webSessionKey).(*cache.WebSession)
+
+	req, msg := parsePushDevice(c)
+	if msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+
+	ctx := context.Background()
+	monitors, err := h.DB.GetMonitorsByTelegramID(ctx, s.TelegramID)
+	if err != nil {
+		log.Printf("[push] monitors of %d: %v", s.TelegramID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to register device"})
+	}
+	ids := make([]int64, 0, len(monitors))
+	for _, m := range monitors {
+		ok, err := h.DB.RegisterPushDevice(ctx, m.ID, req.Platform, req.Token, push.MaxDevicesPerMonitor)
+		if err != nil {
+			log.Printf("[push] register device for monitor %d: %v", m.ID, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to register device"})
+		}
+		if ok {
+			ids = append(ids, m.ID)
+		}
+	}
+	return c.JSON(fiber.Map{"status": "ok", "monitor_ids": ids})
+}
+
+// WebUnregisterPushDevice handles DELETE /api/web/push: unsubscribes a device
+// from all monitors of the signed-in Telegram user.
+func (h *Handlers) WebUnregisterPushDevice(c *fiber.Ctx) error {
+	s := c.Locals(webSessionKey).(*cache.WebSession)
+
+	req, msg := parsePushDevice(c)
+	if msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+
+	ctx := context.Background()
+	monitors, err := h.DB.GetMonitorsByTelegramID(ctx, s.TelegramID)
+	if err != nil {
+		log.Printf("[push] monitors of %d: %v", s.TelegramID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to unregister device"})
+	}
+	for _, m := range monitors {
+		if err := h.DB.DeletePushDevice(ctx, m.ID, req.Platform, req.Token); err != nil {
+			log.Printf("[push] unregister device for monitor %d: %v", m.ID, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to unregister device"})
+		}
+	}
+	return c.JSON(fiber.Map{"status": "ok"})
+}
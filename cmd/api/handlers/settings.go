@@ -2,15 +2,28 @@ package handlers
 
 import (
 	"context"
+	"crypto/subtle"
 	"fmt"
 	"io"
+	"log"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 
+	"no-lights-monitor/internal/contentfilter"
 	"no-lights-monitor/internal/database"
-	"no-lights-monitor/internal/geocode"
+	"no-lights-monitor/internal/models"
+	"no-lights-monitor/internal/mq"
+	"no-lights-monitor/internal/notify"
+	"no-lights-monitor/internal/outage"
+	"no-lights-monitor/internal/regionhint"
+	"no-lights-monitor/internal/sms"
+	"no-lights-monitor/internal/svcauth"
+	"no-lights-monitor/internal/webhook"
 )
 
 var proxyHTTPClient = &http.Client{Timeout: 10 * time.Second}
@@ -25,7 +38,14 @@ func (h *Handlers) ProxyOutage(c *fiber.Ctx) error {
 	path := c.Params("*")
 	url := fmt.Sprintf("%s/api/outage/%s", h.OutageServiceURL, path)
 
-	resp, err := proxyHTTPClient.Get(url)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid path"})
+	}
+	if err := svcauth.Sign(req, h.InternalSecret); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to sign request"})
+	}
+	resp, err := proxyHTTPClient.Do(req)
 	if err != nil {
 		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "outage service unavailable"})
 	}
@@ -66,9 +86,9 @@ func (h *Handlers) ProxyDtek(c *fiber.Ctx) error {
 }
 
 // checkSettingsPassword validates the X-Settings-Password header against the stored password.
-// Returns false and writes a 401 response if invalid; returns true if valid.
+// The comparison is constant-time so the password can't be guessed byte by byte.
 func checkSettingsPassword(c *fiber.Ctx, storedPassword string) bool {
-	return c.Get("X-Settings-Password") == storedPassword
+	return subtle.ConstantTimeCompare([]byte(c.Get("X-Settings-Password")), []byte(storedPassword)) == 1
 }
 
 // GetSettings returns the full monitor configuration for the settings page.
@@ -90,6 +110,43 @@ func (h *Handlers) GetSettings(c *fiber.Ctx) error {
 
 	dur := time.Since(m.LastStatusChangeAt)
 
+	// Median gap between recent heartbeats (0 until the device has pinged twice).
+	observedSec := 0
+	if m.MonitorType == "heartbeat" {
+		if iv, n, err := h.Cache.ObservedPingInterval(ctx, m.ID); err == nil && n > 0 {
+			observedSec = int(iv.Seconds())
+		}
+	}
+
+	changes, err := h.DB.GetMonitorChanges(ctx, m.ID, recentChangesLimit)
+	if err != nil {
+		log.Printf("[settings] get changes for monitor %d: %v", m.ID, err)
+	}
+	smsSent := 0
+	if h.SMSAvailable {
+		if smsSent, err = h.DB.GetSMSUsage(ctx, m.ID, sms.Month(time.Now())); err != nil {
+			log.Printf("[settings] get sms usage for monitor %d: %v", m.ID, err)
+		}
+	}
+	smsPhones := sms.ParsePhones(m.SMSPhones)
+	if smsPhones == nil {
+		smsPhones = []string{}
+	}
+
+	recent := make([]fiber.Map, 0, len(changes))
+	for _, ch := range changes {
+		recent = append(recent, fiber.Map{
+			"id":         ch.ID,
+			"source":     ch.Source,
+			"field":      ch.Field,
+			"old_value":  ch.OldValue,
+			"new_value":  ch.NewValue,
+			"created_at": ch.CreatedAt,
+			"reverted":   ch.RevertedAt != nil,
+			"revertable": ch.RevertedAt == nil && database.IsRevertableField(ch.Field),
+		})
+	}
+
 	return c.JSON(fiber.Map{
 		"id":              m.ID,
 		"name":            m.Name,
@@ -103,12 +160,37 @@ func (h *Handlers) GetSettings(c *fiber.Ctx) error {
 		"outage_region":   m.OutageRegion,
 		"outage_group":    m.OutageGroup,
 		"notify_outage":        m.NotifyOutage,
+		"notify_style":         m.NotifyStyle,
+		"notify_styles":        notify.Styles(),
+		"planned_outage_mode":  m.PlannedOutageMode,
 		"outage_photo_enabled": m.OutagePhotoEnabled,
 		"skip_outage_photo_if_no_outages": m.SkipOutagePhotoIfNoOutages,
+		"outage_photo_mode":     m.OutagePhotoMode,
+		"outage_photo_daily_at": m.OutagePhotoDailyAt,
+		"outage_summary_enabled": m.OutageSummaryEnabled,
+		"outage_summary_at":      m.OutageSummaryAt,
+		"outage_prealert_enabled": m.OutagePreAlertEnabled,
+		"outage_forecast_enabled": m.OutageForecastEnabled,
 		"graph_enabled":        m.GraphEnabled,
+		"latency_graph_enabled": m.LatencyGraphEnabled,
+		"daily_graph_enabled":  m.DailyGraphEnabled,
+		"monthly_graph_enabled": m.MonthlyGraphEnabled,
+		"channel_stats_enabled": m.ChannelStatsEnabled,
+		"sponsor_enabled":      m.SponsorEnabled,
+		"webhook_url":          m.WebhookURL,
+		"webhook_secret":       m.WebhookSecret,
+		"heartbeat_webhook_enabled": m.HeartbeatWebhookEnabled,
+		"power_return_url":     m.PowerReturnURL,
+		"power_return_secret":  m.PowerReturnSecret,
 		"channel_name":         m.ChannelName,
+		"sms_available":        h.SMSAvailable,
+		"sms_enabled":          m.SMSEnabled,
+		"sms_phones":           smsPhones,
+		"sms_quota":            h.SMSMonthlyQuota,
+		"sms_sent_this_month":  smsSent,
 		"monitor_type":    m.MonitorType,
 		"ping_target":     m.PingTarget,
+		"ping_ip":         m.PingIP,
 		"status_duration": database.FormatDuration(dur),
 		"dtek_enabled":          m.DtekEnabled,
 		"dtek_region":           m.DtekRegion,
@@ -116,10 +198,16 @@ func (h *Handlers) GetSettings(c *fiber.Ctx) error {
 		"dtek_street":           m.DtekStreet,
 		"dtek_house":            m.DtekHouse,
 		"offline_threshold_sec": m.OfflineThresholdSec,
+		"online_confirm_sec":    m.OnlineConfirmSec,
+		"observed_ping_interval_sec": observedSec,
+		"recent_changes":        recent,
+		"announcements":         h.activeAnnouncements(ctx, "settings"),
 	})
 }
 
 const (
+	recentChangesLimit = 20
+
 	maxNameLen         = 100
 	maxAddressLen      = 300
 	maxOutageRegionLen = 50
@@ -139,15 +227,36 @@ type settingsUpdateRequest struct {
 	OutageRegion  *string  `json:"outage_region"`
 	OutageGroup   *string  `json:"outage_group"`
 	NotifyOutage                  *bool `json:"notify_outage"`
+	NotifyStyle                   *string `json:"notify_style"` // one of notify.Styles()
+	PlannedOutageMode             *string `json:"planned_outage_mode"` // "" | silent | skip
 	OutagePhotoEnabled            *bool `json:"outage_photo_enabled"`
 	SkipOutagePhotoIfNoOutages    *bool `json:"skip_outage_photo_if_no_outages"`
+	OutagePhotoMode               *string `json:"outage_photo_mode"`     // change | daily | outage_start
+	OutagePhotoDailyAt            *string `json:"outage_photo_daily_at"` // HH:MM, used by the daily mode
+	OutageSummaryEnabled          *bool   `json:"outage_summary_enabled"`
+	OutageSummaryAt               *string `json:"outage_summary_at"` // HH:MM Kyiv time of the daily text summary
+	OutagePreAlertEnabled         *bool   `json:"outage_prealert_enabled"` // heads-up 15 min before scheduled outages
+	OutageForecastEnabled         *bool   `json:"outage_forecast_enabled"` // high-risk evening advisory in the daily summary
 	GraphEnabled       *bool `json:"graph_enabled"`
+	LatencyGraphEnabled *bool `json:"latency_graph_enabled"` // ping monitors: weekly RTT/packet loss graph
+	DailyGraphEnabled   *bool `json:"daily_graph_enabled"`   // today's uptime graph, next to the weekly one
+	MonthlyGraphEnabled *bool `json:"monthly_graph_enabled"` // the month's uptime graph, next to the weekly one
+	ChannelStatsEnabled *bool `json:"channel_stats_enabled"` // weekly subscriber stats DM to the owner
+	SponsorEnabled      *bool `json:"sponsor_enabled"`       // occasional support note under the weekly graph
+	WebhookURL          *string `json:"webhook_url"`           // status changes are POSTed here; "" removes the webhook
+	WebhookRotateSecret bool    `json:"webhook_rotate_secret"` // issue a new signing secret
+	HeartbeatWebhookEnabled *bool `json:"heartbeat_webhook_enabled"` // also POST ping arrivals, at most one per minute
+	PowerReturnURL          *string `json:"power_return_url"`           // called only on offline → online; "" removes the trigger
+	PowerReturnRotateSecret bool    `json:"power_return_rotate_secret"` // issue a new shared secret
+	SMSEnabled          *bool     `json:"sms_enabled"`
+	SMSPhones           *[]string `json:"sms_phones"` // up to sms.MaxPhones Ukrainian mobile numbers
 	DtekEnabled         *bool   `json:"dtek_enabled"`
 	DtekRegion          *string `json:"dtek_region"`
 	DtekCity            *string `json:"dtek_city"`
 	DtekStreet          *string `json:"dtek_street"`
 	DtekHouse           *string `json:"dtek_house"`
 	OfflineThresholdSec *int    `json:"offline_threshold_sec"` // only 150 or 300 accepted
+	OnlineConfirmSec    *int    `json:"online_confirm_sec"`    // 0, 120, 300 or 600
 }
 
 // UpdateSettings updates editable fields of a monitor.
@@ -171,89 +280,332 @@ func (h *Handlers) UpdateSettings(c *fiber.Ctx) error {
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
 	}
+	return h.applySettings(c, m, &req, database.ChangeSourceWeb)
+}
+
+// applySettings saves the fields set in req, recording each change with
+// source, and answers the request. Shared by the settings page and the API.
+func (h *Handlers) applySettings(c *fiber.Ctx, m *models.Monitor, req *settingsUpdateRequest, source string) error {
+	ctx := context.Background()
+
+	// Names and addresses end up in channels and on the public map.
+	if req.Name != nil && *req.Name != m.Name {
+		if msg := h.contentProblem(ctx, contentfilter.Name, *req.Name); msg != "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+		}
+	}
+	if req.Address != nil && *req.Address != m.Address {
+		if msg := h.contentProblem(ctx, contentfilter.Address, *req.Address); msg != "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+		}
+	}
+
+	// Changed fields are collected and saved together, so a request with one
+	// invalid field changes nothing.
+	var upd database.MonitorSettings
+	var listChanged bool                   // the public monitor list needs an announcement
+	var graphRequests []mq.GraphRequestMsg // newly enabled graphs, posted right away
+	var moved *models.Monitor              // the monitor at its new coordinates
 
 	// Update name.
 	if req.Name != nil && *req.Name != m.Name && len(*req.Name) >= 2 && len(*req.Name) <= maxNameLen {
-		if err := h.DB.UpdateMonitorName(ctx, m.ID, *req.Name); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update name"})
-		}
+		upd.Set("name", *req.Name)
+		upd.Record("name", m.Name, *req.Name)
 	}
 
-	// Update address — either with provided coordinates or geocode.
+	// Update address — either with provided coordinates or geocode. A move may
+	// put the monitor into another outage group; the owner is offered a hint.
 	if req.Address != nil && len(*req.Address) >= 3 && len(*req.Address) <= maxAddressLen {
 		lat, lng := m.Latitude, m.Longitude
 		if req.Latitude != nil && req.Longitude != nil {
 			lat, lng = *req.Latitude, *req.Longitude
 		} else {
 			// Geocode the address.
-			result, err := geocode.Search(ctx, *req.Address)
+			result, err := h.Geocoder.Lookup(ctx, *req.Address)
 			if err == nil && result != nil {
 				lat, lng = result.Latitude, result.Longitude
 				req.Address = &result.DisplayName
 			}
 		}
-		if err := h.DB.UpdateMonitorAddress(ctx, m.ID, *req.Address, lat, lng); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update address"})
+		if *req.Address != m.Address || lat != m.Latitude || lng != m.Longitude {
+			upd.Set("address", *req.Address)
+			upd.Set("latitude", lat)
+			upd.Set("longitude", lng)
+			if *req.Address != m.Address {
+				upd.Record("address", m.Address, *req.Address)
+			} else {
+				upd.Record("coordinates", fmt.Sprintf("%.5f,%.5f", m.Latitude, m.Longitude), fmt.Sprintf("%.5f,%.5f", lat, lng))
+			}
+			listChanged = true
+			moved = new(models.Monitor)
+			*moved = *m
+			moved.Latitude, moved.Longitude = lat, lng
 		}
 	}
 
 	// Update map visibility.
 	if req.IsPublic != nil && *req.IsPublic != m.IsPublic {
-		if err := h.DB.SetMonitorPublic(ctx, m.ID, *req.IsPublic); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update map visibility"})
-		}
+		upd.Set("is_public", *req.IsPublic)
+		upd.Record("is_public", m.IsPublic, *req.IsPublic)
+		listChanged = true
 	}
 
 	// Update notify address.
 	if req.NotifyAddress != nil && *req.NotifyAddress != m.NotifyAddress {
-		if err := h.DB.SetMonitorNotifyAddress(ctx, m.ID, *req.NotifyAddress); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update notify_address"})
-		}
+		upd.Set("notify_address", *req.NotifyAddress)
+		upd.Record("notify_address", m.NotifyAddress, *req.NotifyAddress)
 	}
 
 	// Update outage group.
 	if req.OutageRegion != nil && req.OutageGroup != nil &&
 		len(*req.OutageRegion) <= maxOutageRegionLen && len(*req.OutageGroup) <= maxOutageGroupLen {
 		if *req.OutageRegion != m.OutageRegion || *req.OutageGroup != m.OutageGroup {
-			if err := h.DB.SetMonitorOutageGroup(ctx, m.ID, *req.OutageRegion, *req.OutageGroup); err != nil {
-				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update outage group"})
-			}
+			upd.Set("outage_region", *req.OutageRegion)
+			upd.Set("outage_group", *req.OutageGroup)
+			upd.Record("outage_group", m.OutageRegion+"/"+m.OutageGroup, *req.OutageRegion+"/"+*req.OutageGroup)
 		}
 	}
 
 	// Update notify outage.
 	if req.NotifyOutage != nil && *req.NotifyOutage != m.NotifyOutage {
-		if err := h.DB.SetMonitorNotifyOutage(ctx, m.ID, *req.NotifyOutage); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update notify_outage"})
+		upd.Set("notify_outage", *req.NotifyOutage)
+		upd.Record("notify_outage", m.NotifyOutage, *req.NotifyOutage)
+	}
+
+	// Update notification style.
+	if req.NotifyStyle != nil && *req.NotifyStyle != m.NotifyStyle {
+		if !notify.ValidStyle(*req.NotifyStyle) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "unknown notify_style"})
 		}
+		upd.Set("notify_style", *req.NotifyStyle)
+		upd.Record("notify_style", m.NotifyStyle, *req.NotifyStyle)
+	}
+
+	// Update delivery of schedule-predicted status changes.
+	if req.PlannedOutageMode != nil && *req.PlannedOutageMode != m.PlannedOutageMode {
+		if !outage.ValidPlannedMode(*req.PlannedOutageMode) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "planned_outage_mode must be empty, silent or skip"})
+		}
+		upd.Set("planned_outage_mode", *req.PlannedOutageMode)
+		upd.Record("planned_outage_mode", m.PlannedOutageMode, *req.PlannedOutageMode)
+	}
+
+	// Update outage pre-alerts.
+	if req.OutagePreAlertEnabled != nil && *req.OutagePreAlertEnabled != m.OutagePreAlertEnabled {
+		upd.Set("outage_prealert_enabled", *req.OutagePreAlertEnabled)
+		upd.Record("outage_prealert_enabled", m.OutagePreAlertEnabled, *req.OutagePreAlertEnabled)
+	}
+
+	// Update the outage forecast advisory of the daily summary.
+	if req.OutageForecastEnabled != nil && *req.OutageForecastEnabled != m.OutageForecastEnabled {
+		upd.Set("outage_forecast_enabled", *req.OutageForecastEnabled)
+		upd.Record("outage_forecast_enabled", m.OutageForecastEnabled, *req.OutageForecastEnabled)
 	}
 
 	// Update skip outage photo if no outages.
 	if req.SkipOutagePhotoIfNoOutages != nil && *req.SkipOutagePhotoIfNoOutages != m.SkipOutagePhotoIfNoOutages {
-		if err := h.DB.SetMonitorSkipOutagePhotoIfNoOutages(ctx, m.ID, *req.SkipOutagePhotoIfNoOutages); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update skip_outage_photo_if_no_outages"})
-		}
+		upd.Set("skip_outage_photo_if_no_outages", *req.SkipOutagePhotoIfNoOutages)
+		upd.Record("skip_outage_photo_if_no_outages", m.SkipOutagePhotoIfNoOutages, *req.SkipOutagePhotoIfNoOutages)
 	}
 
 	// Update outage photo enabled.
 	if req.OutagePhotoEnabled != nil && *req.OutagePhotoEnabled != m.OutagePhotoEnabled {
-		if err := h.DB.SetMonitorOutagePhotoEnabled(ctx, m.ID, *req.OutagePhotoEnabled); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update outage_photo_enabled"})
+		upd.Set("outage_photo_enabled", *req.OutagePhotoEnabled)
+		upd.Record("outage_photo_enabled", m.OutagePhotoEnabled, *req.OutagePhotoEnabled)
+	}
+
+	// Update outage photo delivery schedule.
+	if req.OutagePhotoMode != nil || req.OutagePhotoDailyAt != nil {
+		mode, dailyAt := m.OutagePhotoMode, m.OutagePhotoDailyAt
+		if req.OutagePhotoMode != nil {
+			mode = *req.OutagePhotoMode
+		}
+		if req.OutagePhotoDailyAt != nil {
+			dailyAt = *req.OutagePhotoDailyAt
+		}
+		if !outage.ValidPhotoMode(mode) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "outage_photo_mode must be change, daily or outage_start"})
+		}
+		if _, _, err := outage.ParseDailyAt(dailyAt); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "outage_photo_daily_at must be HH:MM"})
+		}
+		if mode != m.OutagePhotoMode || dailyAt != m.OutagePhotoDailyAt {
+			upd.Set("outage_photo_mode", mode)
+			upd.Set("outage_photo_daily_at", dailyAt)
+			upd.Record("outage_photo_schedule", m.OutagePhotoMode+" "+m.OutagePhotoDailyAt, mode+" "+dailyAt)
+		}
+	}
+
+	// Update daily outage summary message.
+	if req.OutageSummaryEnabled != nil || req.OutageSummaryAt != nil {
+		enabled, at := m.OutageSummaryEnabled, m.OutageSummaryAt
+		if req.OutageSummaryEnabled != nil {
+			enabled = *req.OutageSummaryEnabled
+		}
+		if req.OutageSummaryAt != nil {
+			at = *req.OutageSummaryAt
+		}
+		if _, _, err := outage.ParseDailyAt(at); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "outage_summary_at must be HH:MM"})
+		}
+		if enabled != m.OutageSummaryEnabled || at != m.OutageSummaryAt {
+			upd.Set("outage_summary_enabled", enabled)
+			upd.Set("outage_summary_at", at)
+			upd.Record("outage_summary", fmt.Sprintf("%t %s", m.OutageSummaryEnabled, m.OutageSummaryAt), fmt.Sprintf("%t %s", enabled, at))
 		}
 	}
 
 	// Update graph enabled.
 	if req.GraphEnabled != nil && *req.GraphEnabled != m.GraphEnabled {
-		if err := h.DB.SetMonitorGraphEnabled(ctx, m.ID, *req.GraphEnabled); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update graph_enabled"})
+		upd.Set("graph_enabled", *req.GraphEnabled)
+		upd.Record("graph_enabled", m.GraphEnabled, *req.GraphEnabled)
+	}
+
+	// Update the ping latency graph; a newly enabled one is posted right away.
+	if req.LatencyGraphEnabled != nil && *req.LatencyGraphEnabled != m.LatencyGraphEnabled {
+		if m.MonitorType != "ping" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "latency graph is only available for ping monitors"})
+		}
+		upd.Set("latency_graph_enabled", *req.LatencyGraphEnabled)
+		upd.Record("latency_graph_enabled", m.LatencyGraphEnabled, *req.LatencyGraphEnabled)
+		if *req.LatencyGraphEnabled && m.ChannelID != 0 {
+			graphRequests = append(graphRequests, mq.GraphRequestMsg{Kind: mq.GraphLatency, MonitorID: m.ID, ChannelID: m.ChannelID})
+		}
+	}
+
+	// Update the daily uptime graph; a newly enabled one is posted right away.
+	if req.DailyGraphEnabled != nil && *req.DailyGraphEnabled != m.DailyGraphEnabled {
+		upd.Set("daily_graph_enabled", *req.DailyGraphEnabled)
+		upd.Record("daily_graph_enabled", m.DailyGraphEnabled, *req.DailyGraphEnabled)
+		if *req.DailyGraphEnabled && m.ChannelID != 0 {
+			graphRequests = append(graphRequests, mq.GraphRequestMsg{Period: mq.GraphDay, MonitorID: m.ID, ChannelID: m.ChannelID})
+		}
+	}
+
+	// Update the monthly uptime graph; a newly enabled one is posted right away.
+	if req.MonthlyGraphEnabled != nil && *req.MonthlyGraphEnabled != m.MonthlyGraphEnabled {
+		upd.Set("monthly_graph_enabled", *req.MonthlyGraphEnabled)
+		upd.Record("monthly_graph_enabled", m.MonthlyGraphEnabled, *req.MonthlyGraphEnabled)
+		if *req.MonthlyGraphEnabled && m.ChannelID != 0 {
+			graphRequests = append(graphRequests, mq.GraphRequestMsg{Period: mq.GraphMonth, MonitorID: m.ID, ChannelID: m.ChannelID})
+		}
+	}
+
+	// Update weekly channel stats.
+	if req.ChannelStatsEnabled != nil && *req.ChannelStatsEnabled != m.ChannelStatsEnabled {
+		upd.Set("channel_stats_enabled", *req.ChannelStatsEnabled)
+		upd.Record("channel_stats_enabled", m.ChannelStatsEnabled, *req.ChannelStatsEnabled)
+	}
+
+	// Update sponsor message opt-in.
+	if req.SponsorEnabled != nil && *req.SponsorEnabled != m.SponsorEnabled {
+		upd.Set("sponsor_enabled", *req.SponsorEnabled)
+		upd.Record("sponsor_enabled", m.SponsorEnabled, *req.SponsorEnabled)
+	}
+
+	// Update SMS notifications. Phones are normalized before the toggle so that
+	// enabling and setting numbers in one request works.
+	if req.SMSPhones != nil {
+		if len(*req.SMSPhones) > sms.MaxPhones {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("at most %d phone numbers", sms.MaxPhones)})
+		}
+		phones := make([]string, 0, len(*req.SMSPhones))
+		for _, p := range *req.SMSPhones {
+			if strings.TrimSpace(p) == "" {
+				continue
+			}
+			phone, ok := sms.NormalizePhone(p)
+			if !ok {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid phone number: " + p})
+			}
+			phones = append(phones, phone)
+		}
+		if joined := strings.Join(phones, ","); joined != m.SMSPhones {
+			upd.Set("sms_phones", joined)
+			upd.Record("sms_phones", m.SMSPhones, joined)
+		}
+	}
+	if req.SMSEnabled != nil && *req.SMSEnabled != m.SMSEnabled {
+		if *req.SMSEnabled && !h.SMSAvailable {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "SMS notifications are not available"})
+		}
+		upd.Set("sms_enabled", *req.SMSEnabled)
+		upd.Record("sms_enabled", m.SMSEnabled, *req.SMSEnabled)
+	}
+
+	// Update the webhook. A secret is issued with the first URL and kept
+	// across URL changes unless a rotation is asked for.
+	if req.WebhookURL != nil || req.WebhookRotateSecret {
+		url, secret := m.WebhookURL, m.WebhookSecret
+		if req.WebhookURL != nil {
+			url = strings.TrimSpace(*req.WebhookURL)
+			if url != "" {
+				var err error
+				if url, err = webhook.ValidateURL(url); err != nil {
+					return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "webhook_url: " + err.Error()})
+				}
+			}
+		}
+		switch {
+		case url == "":
+			secret = ""
+		case secret == "" || req.WebhookRotateSecret:
+			secret = webhook.NewSecret()
+		}
+		if url != m.WebhookURL || secret != m.WebhookSecret {
+			upd.Set("webhook_url", url)
+			upd.Set("webhook_secret", secret)
+		}
+		if url != m.WebhookURL {
+			upd.Record("webhook_url", m.WebhookURL, url)
+		}
+		if secret != m.WebhookSecret {
+			// Like a regenerated ping token, recorded without the values.
+			upd.Record("webhook_secret", "", "")
+		}
+	}
+
+	// Update the power return trigger; its secret is handled like the webhook's.
+	if req.PowerReturnURL != nil || req.PowerReturnRotateSecret {
+		url, secret := m.PowerReturnURL, m.PowerReturnSecret
+		if req.PowerReturnURL != nil {
+			url = strings.TrimSpace(*req.PowerReturnURL)
+			if url != "" {
+				var err error
+				if url, err = webhook.ValidateURL(url); err != nil {
+					return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "power_return_url: " + err.Error()})
+				}
+			}
+		}
+		switch {
+		case url == "":
+			secret = ""
+		case secret == "" || req.PowerReturnRotateSecret:
+			secret = webhook.NewSecret()
+		}
+		if url != m.PowerReturnURL || secret != m.PowerReturnSecret {
+			upd.Set("power_return_url", url)
+			upd.Set("power_return_secret", secret)
+		}
+		if url != m.PowerReturnURL {
+			upd.Record("power_return_url", m.PowerReturnURL, url)
+		}
+		if secret != m.PowerReturnSecret {
+			// Like a regenerated ping token, recorded without the values.
+			upd.Record("power_return_secret", "", "")
 		}
 	}
 
+	// Update the heartbeat firehose; it has no effect without a webhook URL.
+	if req.HeartbeatWebhookEnabled != nil && *req.HeartbeatWebhookEnabled != m.HeartbeatWebhookEnabled {
+		upd.Set("heartbeat_webhook_enabled", *req.HeartbeatWebhookEnabled)
+		upd.Record("heartbeat_webhook_enabled", m.HeartbeatWebhookEnabled, *req.HeartbeatWebhookEnabled)
+	}
+
 	// Update DTEK enabled toggle.
 	if req.DtekEnabled != nil && *req.DtekEnabled != m.DtekEnabled {
-		if err := h.DB.SetMonitorDtekEnabled(ctx, m.ID, *req.DtekEnabled); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update dtek_enabled"})
-		}
+		upd.Set("dtek_enabled", *req.DtekEnabled)
+		upd.Record("dtek_enabled", m.DtekEnabled, *req.DtekEnabled)
 	}
 
 	// Update offline threshold (only 150 or 300 are valid).
@@ -263,12 +615,21 @@ func (h *Handlers) UpdateSettings(c *fiber.Ctx) error {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "offline_threshold_sec must be 150 or 300"})
 		}
 		if sec != m.OfflineThresholdSec {
-			if err := h.DB.SetMonitorThreshold(ctx, m.ID, sec); err != nil {
-				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update offline threshold"})
-			}
+			upd.Set("offline_threshold_sec", sec)
+			upd.Record("offline_threshold_sec", m.OfflineThresholdSec, sec)
 		}
 	}
 
+	// Update online confirmation delay.
+	if req.OnlineConfirmSec != nil && *req.OnlineConfirmSec != m.OnlineConfirmSec {
+		sec := *req.OnlineConfirmSec
+		if !database.ValidOnlineConfirmSec(sec) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "online_confirm_sec must be 0, 120, 300 or 600"})
+		}
+		upd.Set("online_confirm_sec", sec)
+		upd.Record("online_confirm_sec", m.OnlineConfirmSec, sec)
+	}
+
 	// Update DTEK address config (region + city + street + house sent together).
 	if req.DtekRegion != nil && req.DtekStreet != nil && req.DtekHouse != nil {
 		region := *req.DtekRegion
@@ -281,11 +642,40 @@ func (h *Handlers) UpdateSettings(c *fiber.Ctx) error {
 		if len(region) > 10 || len(city) > maxDtekFieldLen || len(street) > maxDtekFieldLen || len(house) > maxDtekHouseLen {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "dtek field too long"})
 		}
-		if err := h.DB.SetMonitorDtekConfig(ctx, m.ID, m.DtekEnabled, region, city, street, house); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update dtek config"})
+		if region != m.DtekRegion || city != m.DtekCity || street != m.DtekStreet || house != m.DtekHouse {
+			upd.Set("dtek_region", region)
+			upd.Set("dtek_city", city)
+			upd.Set("dtek_street", street)
+			upd.Set("dtek_house", house)
+			oldDtek := strings.Join([]string{m.DtekRegion, m.DtekCity, m.DtekStreet, m.DtekHouse}, ", ")
+			newDtek := strings.Join([]string{region, city, street, house}, ", ")
+			upd.Record("dtek_address", oldDtek, newDtek)
+		}
+	}
+
+	if err := h.DB.UpdateMonitorSettings(ctx, m.ID, source, &upd); err != nil {
+		log.Printf("[settings] update monitor %d: %v", m.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update settings"})
+	}
+	if listChanged {
+		h.announceMonitorsChanged(ctx, m.ID)
+	}
+	for _, msg := range graphRequests {
+		if err := h.MQPublisher.Publish(ctx, mq.RoutingGraphRequest, msg); err != nil {
+			log.Printf("[settings] graph request for monitor %d: %v", m.ID, err)
+		}
+	}
+	var outageHint *regionhint.Hint
+	if moved != nil {
+		var err error
+		if outageHint, err = regionhint.Check(ctx, h.DB, moved); err != nil {
+			log.Printf("[settings] region hint for monitor %d: %v", m.ID, err)
 		}
 	}
 
+	if outageHint != nil {
+		return c.JSON(fiber.Map{"status": "ok", "outage_hint": outageHint})
+	}
 	return c.JSON(fiber.Map{"status": "ok"})
 }
 
@@ -313,10 +703,46 @@ func (h *Handlers) StopMonitor(c *fiber.Ctx) error {
 	if err := h.DB.SetMonitorActive(ctx, m.ID, false); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to stop monitor"})
 	}
+	h.recordChange(ctx, m.ID, "is_active", true, false)
+	h.announceMonitorsChanged(ctx, m.ID)
 
 	return c.JSON(fiber.Map{"status": "ok"})
 }
 
+// SendTestMessage asks the bot, over the command bus, to post a test message to
+// the linked channel like its /test command, and reports whether it got through.
+// A channel access error also pauses the monitor and DMs the owner as usual.
+func (h *Handlers) SendTestMessage(c *fiber.Ctx) error {
+	token := c.Params("token")
+	if token == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	ctx := context.Background()
+	m, err := h.DB.GetMonitorBySettingsToken(ctx, token)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "monitor not found"})
+	}
+
+	if !checkSettingsPassword(c, m.SettingsPassword) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid password"})
+	}
+
+	if m.ChannelID == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "monitor has no channel"})
+	}
+
+	res, err := h.Commands.Call(ctx, mq.BotCommand{Type: mq.CommandSendTest, MonitorID: m.ID})
+	if err != nil {
+		log.Printf("[settings] test message for monitor %d: %v", m.ID, err)
+		return c.Status(fiber.StatusGatewayTimeout).JSON(fiber.Map{"error": "bot did not respond, try again later"})
+	}
+	if !res.OK {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"status": "failed", "error": res.Error})
+	}
+	return c.JSON(fiber.Map{"status": "sent"})
+}
+
 // ResumeMonitor resumes monitoring via settings page.
 func (h *Handlers) ResumeMonitor(c *fiber.Ctx) error {
 	token := c.Params("token")
@@ -341,6 +767,8 @@ func (h *Handlers) ResumeMonitor(c *fiber.Ctx) error {
 	if err := h.DB.SetMonitorActive(ctx, m.ID, true); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to resume monitor"})
 	}
+	h.recordChange(ctx, m.ID, "is_active", false, true)
+	h.announceMonitorsChanged(ctx, m.ID)
 
 	return c.JSON(fiber.Map{"status": "ok"})
 }
@@ -362,9 +790,295 @@ func (h *Handlers) DeleteMonitorWeb(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid password"})
 	}
 
-	if err := h.DB.DeleteMonitor(ctx, m.ID); err != nil {
+	if err := h.deleteMonitor(ctx, m, database.ChangeSourceWeb); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to delete monitor"})
 	}
+	return c.JSON(fiber.Map{"status": "ok"})
+}
+
+// deleteMonitor deletes m, recording the deletion with source, and queues
+// the cleanup of its channel posts.
+func (h *Handlers) deleteMonitor(ctx context.Context, m *models.Monitor, source string) error {
+	if err := h.DB.DeleteMonitor(ctx, m.ID); err != nil {
+		return err
+	}
+	// m still carries the channel message IDs the deletion cleared.
+	if m.ChannelID != 0 {
+		if err := h.MQPublisher.Publish(ctx, mq.RoutingMonitorCleanup, mq.NewMonitorCleanupMsg(m)); err != nil {
+			log.Printf("[%s] monitor %d: queue channel cleanup: %v", source, m.ID, err)
+		}
+	}
+	h.recordSourceChange(ctx, source, m.ID, database.ChangeFieldDeleted, m.Name, "")
+	h.announceMonitorsChanged(ctx, m.ID)
+	return nil
+}
+
+// PreviewNotifications returns the online and offline channel posts exactly as
+// the bot would render them right now, including the schedule line. Query
+// parameters (address, notify_address, outage_region, outage_group,
+// notify_outage, notify_style) override the saved values so unsaved edits can be previewed.
+func (h *Handlers) PreviewNotifications(c *fiber.Ctx) error {
+	token := c.Params("token")
+	if token == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	ctx := context.Background()
+	m, err := h.DB.GetMonitorBySettingsToken(ctx, token)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "monitor not found"})
+	}
+
+	if !checkSettingsPassword(c, m.SettingsPassword) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid password"})
+	}
+
+	address := m.Address
+	if v, ok := c.Queries()["address"]; ok {
+		address = strings.TrimSpace(v)
+	}
+	region := c.Query("outage_region", m.OutageRegion)
+	group := c.Query("outage_group", m.OutageGroup)
+	notifyAddress := c.QueryBool("notify_address", m.NotifyAddress)
+	notifyOutage := c.QueryBool("notify_outage", m.NotifyOutage)
+	style := c.Query("notify_style", m.NotifyStyle)
+	if !notify.ValidStyle(style) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "unknown notify_style"})
+	}
+
+	now := time.Now()
+	// Sample duration: how long the monitor has been in its current state.
+	dur := now.Sub(m.LastStatusChangeAt)
+	render := func(isOnline bool) string {
+		return notify.StatusText(h.OutageClient, models.StatusChange{
+			Address:       address,
+			NotifyAddress: notifyAddress,
+			IsOnline:      isOnline,
+			Duration:      dur,
+			When:          now,
+			OutageRegion:  region,
+			OutageGroup:   group,
+			NotifyOutage:  notifyOutage,
+			NotifyStyle:   style,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"parse_mode": "HTML",
+		"online":     render(true),
+		"offline":    render(false),
+	})
+}
+
+// GetPingURL reveals the heartbeat ping URL of a monitor via settings page.
+func (h *Handlers) GetPingURL(c *fiber.Ctx) error {
+	token := c.Params("token")
+	if token == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	ctx := context.Background()
+	m, err := h.DB.GetMonitorBySettingsToken(ctx, token)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "monitor not found"})
+	}
+
+	if !checkSettingsPassword(c, m.SettingsPassword) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid password"})
+	}
+
+	if m.MonitorType != "heartbeat" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ping URL is only available for heartbeat monitors"})
+	}
+
+	return c.JSON(fiber.Map{"ping_url": h.Hosts.PingURL(m.PingBaseURL, m.Token)})
+}
+
+// RegeneratePingURL issues a new heartbeat token via settings page.
+// The previous ping URL is invalidated, so the device must be reconfigured.
+func (h *Handlers) RegeneratePingURL(c *fiber.Ctx) error {
+	token := c.Params("token")
+	if token == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	ctx := context.Background()
+	m, err := h.DB.GetMonitorBySettingsToken(ctx, token)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "monitor not found"})
+	}
+
+	if !checkSettingsPassword(c, m.SettingsPassword) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid password"})
+	}
+
+	if m.MonitorType != "heartbeat" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ping URL is only available for heartbeat monitors"})
+	}
+
+	newToken, err := h.DB.RegenerateMonitorToken(ctx, m.ID, h.Hosts.Canonical())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to regenerate ping URL"})
+	}
+	h.recordChange(ctx, m.ID, database.ChangeFieldToken, "", "")
+
+	return c.JSON(fiber.Map{"status": "ok", "ping_url": h.Hosts.PingURL(h.Hosts.Canonical(), newToken)})
+}
+
+// pingIPWarnings are returned until the owner confirms binding a monitor to an IP.
+var pingIPWarnings = []string{
+	"anyone sending requests from this public IP (neighbours behind the same NAT, CGNAT, office or mobile network) will mark the monitor online",
+	"if your provider changes the IP, pings stop matching and the monitor goes offline until you bind the new one",
+	"only one monitor can be bound to an IP; prefer the token ping URL whenever the device supports it",
+}
+
+// pingIPRequest is the JSON body for binding a monitor to a source IP.
+type pingIPRequest struct {
+	IP      string `json:"ip"`      // optional; must be the IP this request comes from
+	Confirm bool   `json:"confirm"` // must be true; the first call returns warnings
+}
+
+// BindPingIP binds a heartbeat monitor to the IP the request comes from (as
+// resolved through the trusted proxy) so GET /api/ping-ip counts as its
+// heartbeat. Nobody can claim another household's address: other IPs are
+// refused, as is an IP already bound to another monitor. Opt-in: without
+// "confirm": true it only returns the warnings and the IP that would be bound.
+func (h *Handlers) BindPingIP(c *fiber.Ctx) error {
+	token := c.Params("token")
+	if token == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	ctx := context.Background()
+	m, err := h.DB.GetMonitorBySettingsToken(ctx, token)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "monitor not found"})
+	}
+
+	if !checkSettingsPassword(c, m.SettingsPassword) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid password"})
+	}
+
+	if m.MonitorType != "heartbeat" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "IP binding is only available for heartbeat monitors"})
+	}
+
+	var req pingIPRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid JSON"})
+	}
+	ip := net.ParseIP(c.IP())
+	if req.IP = strings.TrimSpace(req.IP); req.IP != "" && !net.ParseIP(req.IP).Equal(ip) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "only the IP this request comes from can be bound"})
+	}
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "a public IP address is required"})
+	}
+	ipStr := ip.String()
+
+	if !req.Confirm {
+		return c.Status(fiber.StatusPreconditionRequired).JSON(fiber.Map{
+			"error":    "confirmation required",
+			"ip":       ipStr,
+			"warnings": pingIPWarnings,
+		})
+	}
+
+	ok, err := h.DB.SetMonitorPingIP(ctx, m.ID, ipStr)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to bind IP"})
+	}
+	if !ok {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "this IP is already bound to another monitor"})
+	}
+	h.recordChange(ctx, m.ID, "ping_ip", m.PingIP, ipStr)
+	log.Printf("[settings] monitor %d bound to IP %s", m.ID, ipStr)
+
+	return c.JSON(fiber.Map{"status": "ok", "ping_ip": ipStr, "ping_url": h.Hosts.Canonical() + "/api/ping-ip"})
+}
+
+// UnbindPingIP disables IP-based pings for a monitor.
+func (h *Handlers) UnbindPingIP(c *fiber.Ctx) error {
+	token := c.Params("token")
+	if token == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	ctx := context.Background()
+	m, err := h.DB.GetMonitorBySettingsToken(ctx, token)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "monitor not found"})
+	}
+
+	if !checkSettingsPassword(c, m.SettingsPassword) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid password"})
+	}
+
+	if m.PingIP == "" {
+		return c.JSON(fiber.Map{"status": "ok"})
+	}
+	if _, err := h.DB.SetMonitorPingIP(ctx, m.ID, ""); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to unbind IP"})
+	}
+	h.recordChange(ctx, m.ID, "ping_ip", m.PingIP, "")
 
 	return c.JSON(fiber.Map{"status": "ok"})
 }
+
+// RevertChange restores the old value of an audited settings change via settings page.
+// Refuses if the field was changed again since, so a stale revert can't clobber newer edits.
+func (h *Handlers) RevertChange(c *fiber.Ctx) error {
+	token := c.Params("token")
+	changeID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if token == "" || err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	ctx := context.Background()
+	m, err := h.DB.GetMonitorBySettingsToken(ctx, token)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "monitor not found"})
+	}
+
+	if !checkSettingsPassword(c, m.SettingsPassword) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid password"})
+	}
+
+	ch, err := h.DB.GetMonitorChange(ctx, m.ID, changeID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "change not found"})
+	}
+	if ch.RevertedAt != nil {
+		return c.JSON(fiber.Map{"status": "already_reverted"})
+	}
+	if !database.IsRevertableField(ch.Field) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "this change cannot be reverted"})
+	}
+	if cur, _ := database.MonitorFieldValue(m, ch.Field); cur != ch.NewValue {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "field was changed again since"})
+	}
+
+	if err := h.DB.ApplyMonitorField(ctx, m.ID, ch.Field, ch.OldValue); err != nil {
+		log.Printf("[settings] revert change %d for monitor %d: %v", ch.ID, m.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to revert change"})
+	}
+	if err := h.DB.MarkMonitorChangeReverted(ctx, ch.ID); err != nil {
+		log.Printf("[settings] mark change %d reverted: %v", ch.ID, err)
+	}
+	h.recordChange(ctx, m.ID, ch.Field, ch.NewValue, ch.OldValue)
+
+	return c.JSON(fiber.Map{"status": "ok"})
+}
+
+// recordChange stores an audit entry for a settings field changed via the web page.
+// Failures are only logged — the change itself has already been applied.
+func (h *Handlers) recordChange(ctx context.Context, monitorID int64, field string, oldVal, newVal any) {
+	h.recordSourceChange(ctx, database.ChangeSourceWeb, monitorID, field, oldVal, newVal)
+}
+
+// recordSourceChange is recordChange for a change made through source.
+func (h *Handlers) recordSourceChange(ctx context.Context, source string, monitorID int64, field string, oldVal, newVal any) {
+	if err := h.DB.RecordMonitorChange(ctx, monitorID, source, field, fmt.Sprint(oldVal), fmt.Sprint(newVal)); err != nil {
+		log.Printf("[settings] record %s change for monitor %d: %v", field, monitorID, err)
+	}
+}
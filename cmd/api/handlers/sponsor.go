@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"no-lights-monitor/internal/models"
+)
+
+const (
+	// maxSponsorLen keeps the sponsor footer well inside Telegram's
+	// 1024-character photo caption limit.
+	maxSponsorLen = 300
+	// maxSponsorEveryWeeks is the rarest schedule accepted (about once a year).
+	maxSponsorEveryWeeks = 52
+)
+
+// AdminGetSponsorMessages returns every sponsor message, including scheduled and expired ones.
+func (h *Handlers) AdminGetSponsorMessages(c *fiber.Ctx) error {
+	list, err := h.DB.GetSponsorMessages(context.Background())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to load sponsor messages"})
+	}
+	if list == nil {
+		list = []models.SponsorMessage{}
+	}
+	return c.JSON(list)
+}
+
+// AdminCreateSponsorMessage schedules a sponsor message for the weekly graph
+// captions. Body: {"message": "...", "link_url": "https://...",
+// "every_weeks": 4, "starts_at": RFC3339, "ends_at": RFC3339}; all but
+// message are optional. The newest active message wins.
+func (h *Handlers) AdminCreateSponsorMessage(c *fiber.Ctx) error {
+	var req struct {
+		Message    string     `json:"message"`
+		LinkURL    string     `json:"link_url"`
+		EveryWeeks int        `json:"every_weeks"`
+		StartsAt   *time.Time `json:"starts_at"`
+		EndsAt     *time.Time `json:"ends_at"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+
+	s := models.SponsorMessage{
+		Message:    strings.TrimSpace(req.Message),
+		LinkURL:    strings.TrimSpace(req.LinkURL),
+		EveryWeeks: req.EveryWeeks,
+		StartsAt:   time.Now(),
+		EndsAt:     req.EndsAt,
+	}
+	if s.Message == "" || len([]rune(s.Message)) > maxSponsorLen {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "message must be 1-" + strconv.Itoa(maxSponsorLen) + " characters"})
+	}
+	if s.LinkURL != "" {
+		if u, err := url.Parse(s.LinkURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || len(s.LinkURL) > maxSponsorLen {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "link_url must be an http(s) URL"})
+		}
+	}
+	if s.EveryWeeks == 0 {
+		s.EveryWeeks = 4
+	}
+	if s.EveryWeeks < 1 || s.EveryWeeks > maxSponsorEveryWeeks {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "every_weeks must be 1-" + strconv.Itoa(maxSponsorEveryWeeks)})
+	}
+	if req.StartsAt != nil {
+		s.StartsAt = *req.StartsAt
+	}
+	if s.EndsAt != nil && !s.EndsAt.After(s.StartsAt) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ends_at must be after starts_at"})
+	}
+
+	created, err := h.DB.CreateSponsorMessage(context.Background(), s)
+	if err != nil {
+		log.Printf("[admin] create sponsor message: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create sponsor message"})
+	}
+	log.Printf("[admin] sponsor message %d created (every %d weeks)", created.ID, created.EveryWeeks)
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+// AdminDeleteSponsorMessage removes a sponsor message. Captions already
+// posted keep it until their graph is next re-rendered.
+func (h *Handlers) AdminDeleteSponsorMessage(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+	ok, err := h.DB.DeleteSponsorMessage(context.Background(), id)
+	if err != nil {
+		log.Printf("[admin] delete sponsor message %d: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to delete sponsor message"})
+	}
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "sponsor message not found"})
+	}
+	log.Printf("[admin] sponsor message %d deleted", id)
+	return c.JSON(fiber.Map{"status": "ok"})
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"no-lights-monitor/internal/metrics"
+)
+
+const (
+	// MaxStreamClients caps the open /api/stream connections of one replica.
+	MaxStreamClients = 5000
+	// MaxStreamClientsPerIP caps the open streams of one client address, so a
+	// single host can't take the whole MaxStreamClients budget.
+	MaxStreamClientsPerIP = 10
+	// StreamKeepalive is how often an idle stream gets a comment line, so
+	// proxies don't time the connection out.
+	StreamKeepalive = 25 * time.Second
+	// streamCoalesce batches the status changes announced within it into one
+	// database query and one event.
+	streamCoalesce = 2 * time.Second
+	// surgeStreamCoalesce replaces streamCoalesce during an outage wave.
+	surgeStreamCoalesce = 15 * time.Second
+	// streamOverlap re-reads changes slightly older than the last query to
+	// absorb clock skew between the worker, the database and this replica.
+	streamOverlap = 5 * time.Second
+	// streamRetryMs is the reconnect delay suggested to EventSource clients.
+	streamRetryMs = 5000
+	// streamBuffer is how many events a client may fall behind before it is
+	// disconnected; EventSource reconnects and the map reloads the list.
+	streamBuffer = 8
+)
+
+// streamHub fans the public status changes out to the /api/stream clients of
+// this replica. Every replica hears the worker's "monitors changed"
+// announcements over Redis and loads the changed monitors itself.
+type streamHub struct {
+	mu        sync.Mutex
+	clients   map[chan []byte]string // client -> its IP
+	perIP     map[string]int
+	since     time.Time // start of the last successful changes query
+	scheduled bool      // a push is pending
+	closed    bool
+}
+
+// subscribe registers a client connecting from ip. It returns false when the
+// hub or ip's share of it is full, or the hub is shutting down.
+func (s *streamHub) subscribe(ip string) (chan []byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed || len(s.clients) >= MaxStreamClients || s.perIP[ip] >= MaxStreamClientsPerIP {
+		return nil, false
+	}
+	if s.clients == nil {
+		s.clients = make(map[chan []byte]string)
+		s.perIP = make(map[string]int)
+	}
+	if len(s.clients) == 0 {
+		// Nothing was pushed while nobody listened; clients load the list first.
+		s.since = time.Now()
+	}
+	ch := make(chan []byte, streamBuffer)
+	s.clients[ch] = ip
+	s.perIP[ip]++
+	metrics.StreamClients.Inc()
+	return ch, true
+}
+
+// unsubscribe removes a client unless it was already dropped.
+func (s *streamHub) unsubscribe(ch chan []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drop(ch)
+}
+
+// drop closes and forgets a client. Must be called with s.mu held.
+func (s *streamHub) drop(ch chan []byte) {
+	ip, ok := s.clients[ch]
+	if !ok {
+		return
+	}
+	delete(s.clients, ch)
+	if s.perIP[ip]--; s.perIP[ip] <= 0 {
+		delete(s.perIP, ip)
+	}
+	close(ch)
+	metrics.StreamClients.Dec()
+}
+
+// broadcast queues frame for every client, disconnecting those that fell behind.
+func (s *streamHub) broadcast(frame []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.clients {
+		select {
+		case ch <- frame:
+		default:
+			s.drop(ch)
+		}
+	}
+}
+
+// NotifyStream schedules a push of the latest status changes to the stream
+// clients. Called for every Redis "monitors changed" announcement; the
+// announcements of one coalescing window share a single query.
+func (h *Handlers) NotifyStream() {
+	s := &h.stream
+	s.mu.Lock()
+	if s.closed || len(s.clients) == 0 || s.scheduled {
+		s.mu.Unlock()
+		return
+	}
+	s.scheduled = true
+	s.mu.Unlock()
+
+	delay := streamCoalesce
+	if h.surgeActive() {
+		delay = surgeStreamCoalesce
+	}
+	time.AfterFunc(delay, h.pushStreamChanges)
+}
+
+// pushStreamChanges sends the public monitors whose status changed, and the
+// IDs of those hidden, since the previous successful push as one "monitors"
+// event. After a failed query the next push covers the missed changes too.
+func (h *Handlers) pushStreamChanges() {
+	s := &h.stream
+	s.mu.Lock()
+	s.scheduled = false
+	since := s.since
+	s.mu.Unlock()
+
+	start := time.Now()
+	monitors, removed, err := h.monitorChangesSince(context.Background(), since.Add(-streamOverlap))
+	if err != nil {
+		log.Printf("[stream] load changed monitors: %v", err)
+		return
+	}
+	s.mu.Lock()
+	if s.since.Equal(since) {
+		s.since = start
+	}
+	s.mu.Unlock()
+	if len(monitors) == 0 && len(removed) == 0 {
+		return
+	}
+	data, err := json.Marshal(fiber.Map{
+		"now":      start.UTC().Format(time.RFC3339),
+		"monitors": monitors,
+		"removed":  removed,
+	})
+	if err != nil {
+		log.Printf("[stream] encode changes: %v", err)
+		return
+	}
+	s.broadcast([]byte(fmt.Sprintf("event: monitors\ndata: %s\n\n", data)))
+}
+
+// CloseStreams ends every open stream and refuses new ones, so a graceful
+// shutdown isn't held up by long-lived connections.
+func (h *Handlers) CloseStreams() {
+	s := &h.stream
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for ch := range s.clients {
+		s.drop(ch)
+	}
+}
+
+// GetStream handles GET /api/stream: a Server-Sent Events stream of public
+// monitor status changes. Each "monitors" event carries the same body as
+// /api/monitors/changes; clients load /api/monitors first and apply events on top.
+func (h *Handlers) GetStream(c *fiber.Ctx) error {
+	ch, ok := h.stream.subscribe(c.IP())
+	if !ok {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "too many stream clients"})
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set("X-Accel-Buffering", "no") // nginx: don't buffer the stream
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer h.stream.unsubscribe(ch)
+		fmt.Fprintf(w, "retry: %d\n\n", streamRetryMs)
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		keepalive := time.NewTicker(StreamKeepalive)
+		defer keepalive.Stop()
+		for {
+			select {
+			case frame, ok := <-ch:
+				if !ok {
+					return
+				}
+				_, _ = w.Write(frame)
+			case <-keepalive.C:
+				_, _ = w.WriteString(": keepalive\n\n")
+			}
+			// A failed flush means the client went away.
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+	return nil
+}
@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"no-lights-monitor/internal/cache"
+	"no-lights-monitor/internal/contentfilter"
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/models"
+	"no-lights-monitor/internal/mq"
+	"no-lights-monitor/internal/ping"
+	"no-lights-monitor/internal/tgauth"
+)
+
+// Web onboarding: a Telegram user signs in with the Login Widget or from the
+// Mini App, then creates a monitor the same way the bot's /create conversation
+// does — type, ping target, address, channel — and gets its ping and settings URLs.
+
+const (
+	// WebAuthMaxAge rejects Telegram auth data signed longer ago than this.
+	WebAuthMaxAge = 24 * time.Hour
+	// WebSessionTTL is how long a web session stays valid after sign-in.
+	WebSessionTTL = 12 * time.Hour
+	// WebRateLimit is the max number of /api/web requests per IP per minute.
+	WebRateLimit = 30
+)
+
+// webSessionKey is the fiber.Locals key holding the *cache.WebSession.
+const webSessionKey = "web_session"
+
+// WebAuth handles POST /api/web/auth. The body is either {"init_data": "..."}
+// from a Mini App or {"login": {...}} with the Login Widget callback fields.
+// On success it returns a bearer session token for the other /api/web routes.
+func (h *Handlers) WebAuth(c *fiber.Ctx) error {
+	var req struct {
+		InitData string         `json:"init_data"`
+		Login    map[string]any `json:"login"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+	if h.BotToken == "" {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "telegram auth not configured"})
+	}
+
+	var (
+		u   *tgauth.User
+		err error
+	)
+	switch {
+	case req.InitData != "":
+		u, err = tgauth.VerifyWebApp(req.InitData, h.BotToken, WebAuthMaxAge)
+	case len(req.Login) > 0:
+		u, err = tgauth.VerifyLogin(loginFields(req.Login), h.BotToken, WebAuthMaxAge)
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "init_data or login is required"})
+	}
+	if err != nil {
+		log.Printf("[web] auth rejected from %s: %v", c.IP(), err)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid telegram auth"})
+	}
+
+	ctx := context.Background()
+	user, err := h.DB.UpsertUser(ctx, u.ID, u.Username, u.FirstName)
+	if err != nil {
+		log.Printf("[web] upsert user %d: %v", u.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to sign in"})
+	}
+	if user.BannedAt != nil {
+		log.Printf("[web] banned user %d refused sign-in", u.ID)
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "account is blocked"})
+	}
+	session, err := h.Cache.CreateWebSession(ctx, cache.WebSession{TelegramID: u.ID, Username: u.Username, FirstName: u.FirstName}, WebSessionTTL)
+	if err != nil {
+		log.Printf("[web] create session for %d: %v", u.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to sign in"})
+	}
+
+	log.Printf("[web] user %d (@%s) signed in", u.ID, u.Username)
+	return c.JSON(fiber.Map{
+		"session":    session,
+		"expires_in": int(WebSessionTTL.Seconds()),
+		"user":       u,
+	})
+}
+
+// loginFields converts Login Widget JSON values to the strings that were signed.
+// Numbers (id, auth_date) arrive as float64 and must be printed without exponent.
+func loginFields(in map[string]any) map[string]string {
+	out := make(map[string]string, len(in))
+	for k, v := range in {
+		switch v := v.(type) {
+		case string:
+			out[k] = v
+		case float64:
+			out[k] = strconv.FormatFloat(v, 'f', -1, 64)
+		case nil:
+		default:
+			out[k] = fmt.Sprint(v)
+		}
+	}
+	return out
+}
+
+// WebSessionGuard requires a valid "Authorization: Bearer <session>" header of
+// a user who isn't banned; a ban ends the sessions opened before it.
+func (h *Handlers) WebSessionGuard(c *fiber.Ctx) error {
+	token, ok := strings.CutPrefix(c.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "sign in required"})
+	}
+	s, err := h.Cache.GetWebSession(context.Background(), token)
+	if err != nil {
+		log.Printf("[web] session lookup: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "session lookup failed"})
+	}
+	if s == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "session expired"})
+	}
+	banned, err := h.DB.IsTelegramUserBanned(context.Background(), s.TelegramID)
+	if err != nil {
+		log.Printf("[web] ban check for %d: %v", s.TelegramID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "session lookup failed"})
+	}
+	if banned {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "account is blocked"})
+	}
+	c.Locals(webSessionKey, s)
+	return c.Next()
+}
+
+// WebGeocode handles GET /api/web/geocode?q=... for the address step.
+func (h *Handlers) WebGeocode(c *fiber.Ctx) error {
+	q := strings.TrimSpace(c.Query("q"))
+	if len(q) < 3 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "address is too short"})
+	}
+	geoCtx, cancel := context.WithTimeout(c.UserContext(), geocodeTimeout)
+	defer cancel()
+	result, err := h.Geocoder.Lookup(geoCtx, q)
+	if err != nil {
+		log.Printf("[web] geocode %q: %v", q, err)
+		return geocodeFailed(c, err)
+	}
+	if result == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "address not found"})
+	}
+	return c.JSON(fiber.Map{
+		"address":   result.DisplayName,
+		"latitude":  result.Latitude,
+		"longitude": result.Longitude,
+	})
+}
+
+// WebCreateMonitor handles POST /api/web/monitors. Only public channels can be
+// linked from the web; private ones still go through the bot's verification code.
+func (h *Handlers) WebCreateMonitor(c *fiber.Ctx) error {
+	s := c.Locals(webSessionKey).(*cache.WebSession)
+	user, err := h.DB.UpsertUser(context.Background(), s.TelegramID, s.Username, s.FirstName)
+	if err != nil {
+		log.Printf("[web] upsert user %d: %v", s.TelegramID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create monitor"})
+	}
+	return h.createMonitor(c, user, database.ChangeSourceWeb)
+}
+
+// createMonitor creates a monitor of user from the request body the way the
+// bot's /create does and answers with its ping and settings URLs. source
+// (web or api) is recorded with the creation and prefixes the log lines.
+func (h *Handlers) createMonitor(c *fiber.Ctx, user *models.User, source string) error {
+	var req struct {
+		Type       string  `json:"type"`
+		PingTarget string  `json:"ping_target"`
+		Name       string  `json:"name"`
+		Address    string  `json:"address"`
+		Latitude   float64 `json:"latitude"`
+		Longitude  float64 `json:"longitude"`
+		Channel    string  `json:"channel"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+
+	req.PingTarget = strings.TrimSpace(req.PingTarget)
+	req.Name = strings.TrimSpace(req.Name)
+	req.Address = strings.TrimSpace(req.Address)
+	switch req.Type {
+	case "heartbeat":
+		req.PingTarget = ""
+	case "ping":
+		if msg := h.checkPingTarget(req.PingTarget); msg != "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+		}
+	case "http":
+		target, msg := checkHTTPTarget(req.PingTarget)
+		if msg != "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+		}
+		req.PingTarget = target
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "type must be heartbeat, ping or http"})
+	}
+	if len(req.Name) < 3 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "name is too short"})
+	}
+	if req.Address == "" {
+		req.Address = req.Name
+	}
+	ctx := context.Background()
+	if msg := h.contentProblem(ctx, contentfilter.Name, req.Name); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+	if msg := h.contentProblem(ctx, contentfilter.Address, req.Address); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+	if req.Latitude < -90 || req.Latitude > 90 || req.Longitude < -180 || req.Longitude > 180 ||
+		(req.Latitude == 0 && req.Longitude == 0) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid coordinates"})
+	}
+	channel := strings.TrimPrefix(strings.TrimSpace(req.Channel), "@")
+	if !channelUsernameRe.MatchString(channel) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "channel must be a public @username"})
+	}
+
+	res, err := h.Commands.Call(ctx, mq.BotCommand{Type: mq.CommandCheckChannelRights, Channel: "@" + channel})
+	if err != nil {
+		log.Printf("[%s] check channel @%s: %v", source, channel, err)
+		return c.Status(fiber.StatusGatewayTimeout).JSON(fiber.Map{"error": "bot did not respond, try again later"})
+	}
+	if !res.OK {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "channel check failed", "reason": res.Error})
+	}
+
+	ownerID, err := h.DB.OwnerUserID(ctx, user)
+	if err != nil {
+		log.Printf("[%s] resolve owner of user %d: %v", source, user.TelegramID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create monitor"})
+	}
+	m, err := h.DB.CreateMonitor(ctx, ownerID, req.Name, req.Address, req.Latitude, req.Longitude, res.ChannelID, res.ChannelUsername, req.Type, req.PingTarget, h.Hosts.Canonical())
+	if err != nil {
+		log.Printf("[%s] create monitor for user %d: %v", source, user.TelegramID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create monitor"})
+	}
+	log.Printf("[%s] monitor created: id=%d type=%s name=%q user=%d (@%s)", source, m.ID, m.MonitorType, m.Name, user.TelegramID, user.Username)
+	h.recordSourceChange(ctx, source, m.ID, database.ChangeFieldCreated, "", m.Name)
+
+	// Initial weekly graph in the channel, as after /create.
+	if err := h.MQPublisher.Publish(ctx, mq.RoutingGraphRequest, mq.GraphRequestMsg{MonitorID: m.ID, ChannelID: m.ChannelID}); err != nil {
+		log.Printf("[%s] initial graph for monitor %d: %v", source, m.ID, err)
+	}
+
+	resp := fiber.Map{
+		"id":                m.ID,
+		"name":              m.Name,
+		"type":              m.MonitorType,
+		"channel":           m.ChannelName,
+		"settings_url":      h.Hosts.Canonical() + "/settings/" + m.SettingsToken,
+		"settings_password": m.SettingsPassword,
+	}
+	if m.MonitorType == "heartbeat" {
+		resp["ping_url"] = h.Hosts.PingURL(m.PingBaseURL, m.Token)
+	} else {
+		resp["ping_target"] = m.PingTarget
+	}
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+// checkPingTarget validates an ICMP target like the bot's ping step and returns
+// a user-facing problem, or "" if the host resolves publicly and answers.
+func (h *Handlers) checkPingTarget(target string) string {
+	if len(target) < 3 {
+		return "ping target is too short"
+	}
+	ips, err := net.LookupHost(target)
+	if err != nil || len(ips) == 0 {
+		return "host not found"
+	}
+	if ping.AnyPrivate(ips) {
+		return "private addresses cannot be monitored"
+	}
+	if h.PingHost != nil && !h.PingHost(target) {
+		return "host does not answer ping"
+	}
+	return ""
+}
+
+// checkHTTPTarget validates a URL like the bot's http step and returns it
+// normalized, or a user-facing problem if it is private or doesn't answer 2xx.
+func checkHTTPTarget(target string) (string, string) {
+	u, err := ping.ValidateURL(target)
+	if err != nil {
+		return "", err.Error()
+	}
+	if !ping.CheckURL(context.Background(), u) {
+		return "", "URL does not answer with 2xx"
+	}
+	return u, ""
+}
@@ -1,201 +1,18 @@
 package main
 
 import (
-	"bytes"
-	"context"
-	"html/template"
-	"log"
-	"os"
-	"os/signal"
-	"strconv"
-	"syscall"
 	"time"
 
-	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
-	"github.com/joho/godotenv"
-
-	"no-lights-monitor/cmd/api/handlers"
-	"no-lights-monitor/internal/cache"
-	"no-lights-monitor/internal/config"
-	"no-lights-monitor/internal/database"
-	"no-lights-monitor/internal/health"
-	"no-lights-monitor/internal/metrics"
-	"no-lights-monitor/internal/mq"
+	"no-lights-monitor/cmd/api/app"
+	"no-lights-monitor/internal/bootstrap"
+	"no-lights-monitor/internal/errsink"
 )
 
 func main() {
-	// Load .env if present.
-	_ = godotenv.Load()
-
-	cfg := config.Load()
-
-	// Pre-render HTML pages that need config values injected (values are static after startup).
-	type webVars struct{ BotUsername, ChatUsername string }
-	webCfg := webVars{cfg.TelegramBotUsername, cfg.TelegramChatUsername}
-	renderOnce := func(file string) []byte {
-		var buf bytes.Buffer
-		template.Must(template.ParseFiles(file)).Execute(&buf, webCfg)
-		return buf.Bytes()
-	}
-	indexHTML := renderOnce("./web/index.html")
-	notFoundHTML := renderOnce("./web/404.html")
-	serveHTML := func(body []byte, status int) fiber.Handler {
-		return func(c *fiber.Ctx) error {
-			c.Set("Content-Type", "text/html; charset=utf-8")
-			c.Set("Cache-Control", "no-cache, must-revalidate")
-			return c.Status(status).Send(body)
-		}
-	}
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	// --- Database ---
-	db, err := database.New(ctx, cfg.DatabaseURL)
-	if err != nil {
-		log.Fatalf("database: %v", err)
-	}
-	defer db.Close()
-
-	if err := db.Migrate(ctx); err != nil {
-		log.Fatalf("migrate: %v", err)
-	}
-	log.Println("database connected and migrated")
-
-	// --- Redis ---
-	redisCache, err := cache.New(cfg.RedisURL)
-	if err != nil {
-		log.Fatalf("redis: %v", err)
-	}
-	defer redisCache.Close()
-	log.Println("redis connected")
-
-	// --- Health + metrics server on :8081 (not exposed through ingress) ---
-	health.ServeAsync(func() error {
-		if err := db.Pool.Ping(context.Background()); err != nil {
-			return err
-		}
-		return redisCache.Client.Ping(context.Background()).Err()
-	})
-
-	// --- RabbitMQ ---
-	mqPub, err := mq.NewPublisher(cfg.RabbitMQURL)
-	if err != nil {
-		log.Fatalf("rabbitmq: %v", err)
-	}
-	defer mqPub.Close()
-	log.Println("rabbitmq connected")
-
-	// --- Fiber HTTP Server ---
-	app := fiber.New(fiber.Config{
-		DisableStartupMessage: true,
-		BodyLimit:             64 * 1024, // 64KB — settings JSON has no business being larger
-	})
-
-	app.Use(logger.New(logger.Config{
-		Format: "${time} ${status} ${method} ${path} ${latency}\n",
-	}))
-	app.Use(cors.New())
-
-	// Record latency for /api/* routes only (avoids cardinality from static file paths).
-	app.Use(func(c *fiber.Ctx) error {
-		if len(c.Path()) < 5 || c.Path()[:5] != "/api/" {
-			return c.Next()
-		}
-		start := time.Now()
-		err := c.Next()
-		route := c.Route().Path
-		if route == "" {
-			route = "unknown"
-		}
-		metrics.APIRequestDuration.WithLabelValues(route, strconv.Itoa(c.Response().StatusCode())).Observe(time.Since(start).Seconds())
-		return err
-	})
-
-	// Health checks (before all other routes so they're never shadowed)
-	app.Get("/healthz", func(c *fiber.Ctx) error {
-		return c.SendStatus(fiber.StatusOK)
-	})
-	app.Get("/readyz", func(c *fiber.Ctx) error {
-		if err := db.Pool.Ping(context.Background()); err != nil {
-			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "db"})
-		}
-		if err := redisCache.Client.Ping(context.Background()).Err(); err != nil {
-			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "redis"})
-		}
-		return c.SendStatus(fiber.StatusOK)
-	})
-
-	// API routes
-	h := &handlers.Handlers{DB: db, Cache: redisCache, OutageServiceURL: cfg.OutageServiceURL, DtekServiceURL: cfg.DtekServiceURL, MQPublisher: mqPub}
-	api := app.Group("/api")
-	api.Get("/ping/:token", h.PingAPI)
-	api.Get("/monitors", h.GetMonitors)
-
-	// Proxy outage API from the outage service (for settings page)
-	api.Get("/outage/*", h.ProxyOutage)
-
-	// Proxy DTEK scraper (address autocomplete for settings page)
-	api.Get("/dtek/*", h.ProxyDtek)
-
-	// Settings API (accessed by settings_token)
-	api.Get("/settings/:token", h.GetSettings)
-	api.Put("/settings/:token", h.UpdateSettings)
-	api.Post("/settings/:token/stop", h.StopMonitor)
-	api.Post("/settings/:token/resume", h.ResumeMonitor)
-	api.Delete("/settings/:token", h.DeleteMonitorWeb)
-
-	// Admin routes (protected by HTTP Basic Auth)
-	if cfg.AdminLogin != "" && cfg.AdminPassword != "" {
-		admin := app.Group("/admin", handlers.BasicAuth(cfg.AdminLogin, cfg.AdminPassword))
-		admin.Get("/", h.AdminPage)
-		admin.Get("/api/settings", h.AdminGetSettings)
-		admin.Put("/api/settings", h.AdminSetSettings)
-		admin.Get("/api/users", h.AdminGetUsers)
-		admin.Get("/api/monitors", h.AdminGetMonitors)
-		admin.Get("/api/monitors/deleted", h.AdminGetDeletedMonitors)
-		admin.Get("/api/monitors/:id/history", h.GetHistory)
-		admin.Post("/api/broadcast", h.AdminBroadcast)
-	}
-
-	// Settings page (serve settings.html for any /settings/* path).
-	app.Get("/settings/:token", func(c *fiber.Ctx) error {
-		c.Set("Cache-Control", "no-cache, must-revalidate")
-		return c.SendFile("./web/settings.html")
-	})
-
-	// Index page: pre-rendered with config values injected.
-	app.Get("/", serveHTML(indexHTML, fiber.StatusOK))
-	app.Get("/index.html", serveHTML(indexHTML, fiber.StatusOK))
-
-	// HTML and JS files: bypass static handler so Cache-Control is guaranteed.
-	noCache := func(c *fiber.Ctx) error {
-		c.Set("Cache-Control", "no-cache, must-revalidate")
-		return c.SendFile("./web" + c.Path())
-	}
-	app.Get("/*.html", noCache)
-	app.Get("/js/*.js", noCache)
-
-	// Everything else (CSS, images, fonts…) served normally with default caching.
-	app.Static("/", "./web")
-
-	// 404 handler: pre-rendered with config values injected.
-	app.Use(serveHTML(notFoundHTML, fiber.StatusNotFound))
-
-	// --- Graceful shutdown ---
-	go func() {
-		quit := make(chan os.Signal, 1)
-		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-		<-quit
-		log.Println("shutting down...")
-		cancel()
-		_ = app.Shutdown()
-	}()
+	cfg := bootstrap.Init("api")
+	defer errsink.Flush(2 * time.Second)
 
-	log.Printf("API service starting on :%s", cfg.Port)
-	if err := app.Listen(":" + cfg.Port); err != nil {
-		log.Fatalf("server: %v", err)
-	}
+	ctx, stop := bootstrap.SignalContext()
+	defer stop()
+	app.Run(ctx, cfg)
 }
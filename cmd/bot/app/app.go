@@ -0,0 +1,104 @@
+// Package app is the bot service: it runs the Telegram bot and delivers the
+// notifications other services publish to RabbitMQ.
+package app
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"no-lights-monitor/cmd/bot/bot"
+	"no-lights-monitor/cmd/bot/channeldesc"
+	"no-lights-monitor/cmd/bot/channelstats"
+	"no-lights-monitor/internal/bootstrap"
+	"no-lights-monitor/internal/config"
+	"no-lights-monitor/internal/geocode"
+	"no-lights-monitor/internal/health"
+	"no-lights-monitor/internal/mq"
+	"no-lights-monitor/internal/outage"
+	"no-lights-monitor/internal/ping"
+	"no-lights-monitor/internal/publicurl"
+	"no-lights-monitor/internal/safego"
+	"no-lights-monitor/internal/scheduler"
+)
+
+// Run starts the bot service and blocks until ctx is cancelled.
+func Run(ctx context.Context, cfg *config.Config) {
+	if cfg.BotToken == "" {
+		log.Fatal("BOT_TOKEN is required. Get one from @BotFather on Telegram.")
+	}
+
+	// --- Database ---
+	db := bootstrap.Database(ctx, cfg)
+	defer db.Close()
+
+	// --- Redis (geocoding cache and throttle shared with the API) ---
+	redisCache := bootstrap.Redis(cfg)
+	defer redisCache.Close()
+
+	// --- RabbitMQ ---
+	mqPublisher := bootstrap.Publisher(cfg)
+	defer mqPublisher.Close()
+
+	mqConsumer := bootstrap.Consumer(cfg)
+	defer mqConsumer.Close()
+	log.Println("rabbitmq connected")
+
+	// --- Health server ---
+	health.ServeAsync(func() error {
+		return db.Pool.Ping(ctx)
+	})
+
+	// --- Telegram Bot ---
+	hosts := publicurl.New(cfg.BaseURL, cfg.LegacyBaseURLs)
+	var tgClient *http.Client
+	if cfg.Sandbox {
+		tgClient = bot.SandboxClient(cfg.SandboxDebugChatID)
+	}
+	tgClient = bot.FloodClient(ctx, tgClient)
+	tgBot, err := bot.New(cfg.BotToken, db, ping.PingHost, hosts, cfg.TelegramChatUsername, tgClient)
+	if err != nil {
+		log.Fatalf("bot: %v", err)
+	}
+
+	// --- Outage Client ---
+	outageClient := outage.NewClient(cfg.OutageServiceURL, cfg.InternalAuthSecret, cfg.OutagePolicy())
+	tgBot.SetOutageClient(outageClient)
+	tgBot.SetGeocoder(geocode.NewClient(redisCache))
+
+	// --- Graph Requester (publishes to MQ for worker to generate) ---
+	graphRequester := mq.NewGraphRequester(mqPublisher)
+	tgBot.SetGraphUpdater(graphRequester)
+	tgBot.SetChannelCleaner(mq.NewCleanupRequester(mqPublisher))
+
+	// --- Start bot polling ---
+	safego.Go("telebot", tgBot.Start)
+	defer tgBot.Stop()
+	log.Println("telegram bot started")
+
+	// --- Start RabbitMQ listener ---
+	listener := newListener(tgBot.TeleBot(), db, outageClient, mqConsumer, cfg.CanaryChannelID)
+	safego.Go("listener", func() { listener.start(ctx) })
+	log.Println("rabbitmq listener started")
+
+	// --- Channel jobs: description checker (daily at 14:00 Kyiv), stats (Mondays at 11:00) ---
+	kyiv, err := time.LoadLocation("Europe/Kyiv")
+	if err != nil {
+		log.Fatalf("load Europe/Kyiv timezone: %v", err)
+	}
+	descChecker := channeldesc.NewChecker(tgBot.TeleBot(), db, hosts)
+	sched := scheduler.New(db, kyiv)
+	if err := sched.Register(scheduler.Job{Name: "channel_description", Spec: "0 14 * * *", Run: descChecker.Run}); err != nil {
+		log.Fatalf("scheduler: %v", err)
+	}
+	statsCollector := channelstats.NewCollector(tgBot.TeleBot(), db)
+	if err := sched.Register(scheduler.Job{Name: "channel_stats", Spec: "0 11 * * 1", Run: statsCollector.Run}); err != nil {
+		log.Fatalf("scheduler: %v", err)
+	}
+	sched.Start(ctx)
+	log.Println("channel description checker and stats collector scheduled")
+
+	<-ctx.Done()
+	log.Println("shutting down bot service...")
+}
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	tele "gopkg.in/telebot.v3"
+
+	"no-lights-monitor/cmd/bot/bot"
+	"no-lights-monitor/internal/errsink"
+	"no-lights-monitor/internal/metrics"
+	"no-lights-monitor/internal/mq"
+)
+
+// ── Command bus handler ──────────────────────────────────────────────
+
+// handleCommand runs a command sent by another service and replies if the
+// sender is waiting for the result.
+func (l *listener) handleCommand(ctx context.Context, d amqp.Delivery) {
+	var cmd mq.BotCommand
+	var res mq.CommandResult
+	if err := json.Unmarshal(d.Body, &cmd); err != nil {
+		log.Printf("[listener] bad bot_command message: %v", err)
+		errsink.Capture(err, errsink.Fields{"queue": "bot_command"})
+		res = mq.CommandResult{Error: mq.CommandErrBadRequest}
+	} else {
+		metrics.BotMessagesProcessed.WithLabelValues("bot_command").Inc()
+		switch cmd.Type {
+		case mq.CommandSendTest:
+			res = l.commandSendTest(ctx, cmd)
+		case mq.CommandCheckChannelRights:
+			res = l.commandCheckChannelRights(cmd)
+		case mq.CommandPostNotice:
+			res = l.commandPostNotice(ctx, cmd)
+		default:
+			res = mq.CommandResult{Error: mq.CommandErrUnknown}
+		}
+		log.Printf("[listener] bot_command %s monitor=%d: ok=%v %s", cmd.Type, cmd.MonitorID, res.OK, res.Error)
+	}
+	if err := l.consumer.Reply(ctx, d, res); err != nil {
+		log.Printf("[listener] bot_command reply failed: %v", err)
+	}
+}
+
+// commandSendTest posts the /test message to the monitor's channel.
+func (l *listener) commandSendTest(ctx context.Context, cmd mq.BotCommand) mq.CommandResult {
+	m, err := l.db.GetMonitorByID(ctx, cmd.MonitorID)
+	if err != nil {
+		return mq.CommandResult{Error: mq.CommandErrNotFound}
+	}
+	if m.ChannelID == 0 {
+		return mq.CommandResult{Error: mq.CommandErrNoChannel}
+	}
+	if err := bot.SendTestMessage(l.bot, m); err != nil {
+		metrics.BotNotificationErrors.WithLabelValues("bot_command").Inc()
+		l.handleChannelError(ctx, m.ID, m.Name, err)
+		return mq.CommandResult{Error: mq.CommandErrSendFailed, Detail: err.Error(), ChannelID: m.ChannelID}
+	}
+	return mq.CommandResult{OK: true, ChannelID: m.ChannelID, ChannelUsername: m.ChannelName}
+}
+
+// commandCheckChannelRights checks the bot can post in a public @channel.
+func (l *listener) commandCheckChannelRights(cmd mq.BotCommand) mq.CommandResult {
+	name := strings.TrimSpace(cmd.Channel)
+	if name == "" {
+		return mq.CommandResult{Error: mq.CommandErrBadRequest}
+	}
+	if !strings.HasPrefix(name, "@") {
+		name = "@" + name
+	}
+	chat, err := l.bot.ChatByUsername(name)
+	if err != nil || chat.Type != tele.ChatChannel {
+		return mq.CommandResult{Error: mq.CommandErrNotFound}
+	}
+	res := mq.CommandResult{ChannelID: chat.ID, ChannelTitle: chat.Title, ChannelUsername: chat.Username}
+	if problem := bot.ChannelRightsProblem(l.bot, chat); problem != "" {
+		res.Error = problem
+		return res
+	}
+	res.OK = true
+	return res
+}
+
+// commandPostNotice posts an HTML notice to the monitor's channel.
+func (l *listener) commandPostNotice(ctx context.Context, cmd mq.BotCommand) mq.CommandResult {
+	if strings.TrimSpace(cmd.Text) == "" {
+		return mq.CommandResult{Error: mq.CommandErrBadRequest}
+	}
+	m, err := l.db.GetMonitorByID(ctx, cmd.MonitorID)
+	if err != nil {
+		return mq.CommandResult{Error: mq.CommandErrNotFound}
+	}
+	if m.ChannelID == 0 {
+		return mq.CommandResult{Error: mq.CommandErrNoChannel}
+	}
+	if _, err := l.bot.Send(&tele.Chat{ID: m.ChannelID}, cmd.Text, &tele.SendOptions{ParseMode: tele.ModeHTML}); err != nil {
+		metrics.BotNotificationErrors.WithLabelValues("bot_command").Inc()
+		l.handleChannelError(ctx, m.ID, m.Name, err)
+		return mq.CommandResult{Error: mq.CommandErrSendFailed, Detail: err.Error(), ChannelID: m.ChannelID}
+	}
+	return mq.CommandResult{OK: true, ChannelID: m.ChannelID, ChannelUsername: m.ChannelName}
+}
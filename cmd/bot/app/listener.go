@@ -1,4 +1,4 @@
-package main
+package app
 
 import (
 	"bytes"
@@ -11,113 +11,165 @@ import (
 	"strings"
 	"time"
 
+	amqp "github.com/rabbitmq/amqp091-go"
 	tele "gopkg.in/telebot.v3"
 
 	"no-lights-monitor/cmd/bot/bot"
 	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/errsink"
 	"no-lights-monitor/internal/metrics"
 	"no-lights-monitor/internal/models"
 	"no-lights-monitor/internal/mq"
 	"no-lights-monitor/internal/outage"
+	"no-lights-monitor/internal/safego"
 )
 
 // listener consumes messages from RabbitMQ and handles them
 // by sending Telegram messages, editing photos, etc.
 type listener struct {
-	bot          *tele.Bot
-	db           *database.DB
-	consumer     *mq.Consumer
-	notifier     *bot.TelegramNotifier
+	bot      *tele.Bot
+	db       *database.DB
+	consumer *mq.Consumer
+	notifier *bot.TelegramNotifier
+
+	canaryChannelID int64 // status messages to this channel come from the canary monitor
 }
 
-func newListener(b *tele.Bot, db *database.DB, oc *outage.Client, consumer *mq.Consumer) *listener {
+func newListener(b *tele.Bot, db *database.DB, oc *outage.Client, consumer *mq.Consumer, canaryChannelID int64) *listener {
 	return &listener{
-		bot:      b,
-		db:       db,
-		consumer: consumer,
-		notifier: bot.NewNotifier(b, db, oc),
+		bot:             b,
+		db:              db,
+		consumer:        consumer,
+		notifier:        bot.NewNotifier(b, db, oc),
+		canaryChannelID: canaryChannelID,
 	}
 }
 
-func (l *listener) start(ctx context.Context) {
-	statusCh, err := l.consumer.Consume(mq.QueueStatusChange)
-	if err != nil {
-		log.Fatalf("[listener] failed to consume %s: %v", mq.QueueStatusChange, err)
-	}
-	graphCh, err := l.consumer.Consume(mq.QueueGraphReady)
-	if err != nil {
-		log.Fatalf("[listener] failed to consume %s: %v", mq.QueueGraphReady, err)
-	}
-	photoCh, err := l.consumer.Consume(mq.QueueOutagePhoto)
-	if err != nil {
-		log.Fatalf("[listener] failed to consume %s: %v", mq.QueueOutagePhoto, err)
-	}
-	dtekCh, err := l.consumer.Consume(mq.QueueDtekOutage)
-	if err != nil {
-		log.Fatalf("[listener] failed to consume %s: %v", mq.QueueDtekOutage, err)
+// Per-message processing budgets. When a handler's budget runs out its
+// context is cancelled, so a hung Telegram upload gives up and the rest of
+// its queue moves on.
+const (
+	textTimeout  = 30 * time.Second
+	photoTimeout = 90 * time.Second
+)
+
+// listenerPrefetch is how many unacked deliveries RabbitMQ hands each queue's
+// consumer; it must cover the largest queueHandler.workers.
+const listenerPrefetch = 4
+
+// queueHandler describes how the listener processes one queue.
+type queueHandler struct {
+	queue   string
+	name    string        // label in logs, metrics and panic reports
+	workers int           // deliveries processed at once
+	timeout time.Duration // per-message budget
+	handle  func(ctx context.Context, d amqp.Delivery)
+}
+
+// handlers lists the consumed queues. Every queue runs in its own goroutine, so
+// a slow photo upload no longer delays status notifications. Status changes
+// keep a single worker: two messages of one monitor must arrive in order.
+// Telegram sends are paced by the bot's HTTP transport (see bot.FloodClient).
+func (l *listener) handlers() []queueHandler {
+	body := func(fn func(payload []byte)) func(context.Context, amqp.Delivery) {
+		return func(_ context.Context, d amqp.Delivery) { fn(d.Body) }
+	}
+	bodyCtx := func(fn func(ctx context.Context, payload []byte)) func(context.Context, amqp.Delivery) {
+		return func(ctx context.Context, d amqp.Delivery) { fn(ctx, d.Body) }
+	}
+	return []queueHandler{
+		{mq.QueueStatusChange, "status_change", 1, textTimeout, body(l.handleStatusChange)},
+		{mq.QueueGraphReady, "graph_ready", 4, photoTimeout, bodyCtx(l.handleGraphReady)},
+		{mq.QueueOutagePhoto, "outage_photo", 4, photoTimeout, bodyCtx(l.handleOutagePhoto)},
+		{mq.QueueDtekOutage, "dtek_outage", 1, textTimeout, bodyCtx(l.handleDtekOutage)},
+		{mq.QueueInactivePause, "inactive_pause", 2, textTimeout, body(l.handleInactivePause)},
+		{mq.QueueBroadcast, "broadcast", 2, textTimeout, body(l.handleBroadcast)},
+		{mq.QueueOutageSummary, "outage_summary", 2, textTimeout, func(ctx context.Context, d amqp.Delivery) {
+			l.handleOutageText(ctx, "outage_summary", d.Body)
+		}},
+		{mq.QueueOutagePreAlert, "outage_prealert", 2, textTimeout, func(ctx context.Context, d amqp.Delivery) {
+			l.handleOutageText(ctx, "outage_prealert", d.Body)
+		}},
+		{mq.QueueIntervalHint, "interval_hint", 1, textTimeout, body(l.handleIntervalHint)},
+		{mq.QueueBotCommand, "bot_command", 4, textTimeout, l.handleCommand},
+		{mq.QueueMonitorCleanup, "monitor_cleanup", 1, textTimeout, body(l.handleMonitorCleanup)},
+		{mq.QueueOwnerDigest, "owner_digest", 1, textTimeout, body(l.handleOwnerDigest)},
 	}
-	inactiveCh, err := l.consumer.Consume(mq.QueueInactivePause)
-	if err != nil {
-		log.Fatalf("[listener] failed to consume %s: %v", mq.QueueInactivePause, err)
+}
+
+func (l *listener) start(ctx context.Context) {
+	if err := l.consumer.SetPrefetch(listenerPrefetch); err != nil {
+		log.Fatalf("[listener] %v", err)
 	}
-	broadcastCh, err := l.consumer.Consume(mq.QueueBroadcast)
-	if err != nil {
-		log.Fatalf("[listener] failed to consume %s: %v", mq.QueueBroadcast, err)
+
+	var names []string
+	for _, h := range l.handlers() {
+		deliveries, err := l.consumer.Consume(h.queue)
+		if err != nil {
+			log.Fatalf("[listener] failed to consume %s: %v", h.queue, err)
+		}
+		safego.Go("listener:"+h.name, func() { l.serve(ctx, h, deliveries) })
+		names = append(names, h.name)
 	}
+	log.Printf("[listener] consuming from %s", strings.Join(names, ", "))
 
-	log.Println("[listener] consuming from status_change, graph_ready, outage_photo, dtek_outage, inactive_pause, broadcast")
+	<-ctx.Done()
+	log.Println("[listener] stopped")
+}
 
+// serve processes one queue's deliveries with at most h.workers in flight.
+func (l *listener) serve(ctx context.Context, h queueHandler, deliveries <-chan amqp.Delivery) {
+	sem := make(chan struct{}, h.workers)
 	for {
 		select {
 		case <-ctx.Done():
-			log.Println("[listener] stopped")
 			return
-		case d, ok := <-statusCh:
-			if !ok {
-				return
-			}
-			l.handleStatusChange(d.Body)
-			d.Ack(false)
-		case d, ok := <-graphCh:
-			if !ok {
-				return
-			}
-			l.handleGraphReady(ctx, d.Body)
-			d.Ack(false)
-		case d, ok := <-photoCh:
-			if !ok {
-				return
-			}
-			l.handleOutagePhoto(ctx, d.Body)
-			d.Ack(false)
-		case d, ok := <-dtekCh:
-			if !ok {
-				return
-			}
-			l.handleDtekOutage(ctx, d.Body)
-			d.Ack(false)
-		case d, ok := <-inactiveCh:
-			if !ok {
-				return
-			}
-			l.handleInactivePause(d.Body)
-			d.Ack(false)
-		case d, ok := <-broadcastCh:
+		case sem <- struct{}{}:
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case d, ok := <-deliveries:
 			if !ok {
 				return
 			}
-			l.handleBroadcast(d.Body)
-			d.Ack(false)
+			safego.Go("listener:"+h.name, func() {
+				defer func() { <-sem }()
+				l.process(ctx, h, d)
+			})
 		}
 	}
 }
 
+// process runs the handler for d with a context that expires after h.timeout
+// and acks d once the handler has returned, so a worker slot is never freed
+// while its handler is still running.
+func (l *listener) process(ctx context.Context, h queueHandler, d amqp.Delivery) {
+	msgCtx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	done := make(chan struct{})
+	safego.Go("listener:"+h.name, func() {
+		defer close(done)
+		h.handle(msgCtx, d)
+	})
+	select {
+	case <-done:
+	case <-msgCtx.Done():
+		metrics.BotMessageTimeouts.WithLabelValues(h.name).Inc()
+		log.Printf("[listener] %s: message not handled within %s, waiting for the handler to give up", h.name, h.timeout)
+		<-done
+	}
+	d.Ack(false)
+}
+
 // ── Broadcast handler ────────────────────────────────────────────────
 
 func (l *listener) handleBroadcast(payload []byte) {
 	var msg mq.BroadcastMsg
 	if err := json.Unmarshal(payload, &msg); err != nil {
 		log.Printf("[listener] bad broadcast message: %v", err)
+		errsink.Capture(err, errsink.Fields{"queue": "broadcast"})
 		return
 	}
 	if msg.ChannelID == 0 {
@@ -127,16 +179,45 @@ func (l *listener) handleBroadcast(payload []byte) {
 	chat := &tele.Chat{ID: msg.ChannelID}
 	if _, err := l.bot.Send(chat, msg.Text, &tele.SendOptions{ParseMode: tele.ModeHTML}); err != nil {
 		metrics.BotNotificationErrors.WithLabelValues("broadcast").Inc()
+		errsink.Capture(err, errsink.Fields{"component": "telegram", "channel_id": msg.ChannelID})
 		log.Printf("[listener] broadcast to channel %d failed: %v", msg.ChannelID, err)
 	}
 }
 
+// ── Outage summary / pre-alert handler ───────────────────────────────
+
+// handleOutageText posts a schedule text (daily summary or pre-alert) to the
+// monitor's channel; kind is the queue name used in logs and metrics.
+func (l *listener) handleOutageText(ctx context.Context, kind string, payload []byte) {
+	var msg mq.OutageSummaryMsg
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		log.Printf("[listener] bad %s message: %v", kind, err)
+		errsink.Capture(err, errsink.Fields{"queue": kind})
+		return
+	}
+	metrics.BotMessagesProcessed.WithLabelValues(kind).Inc()
+	if msg.ChannelID == 0 {
+		return
+	}
+	chat := &tele.Chat{ID: msg.ChannelID}
+	opts := &tele.SendOptions{ParseMode: tele.ModeHTML, DisableNotification: bot.IsQuietHour()}
+	if _, err := l.bot.Send(chat, msg.Text, opts); err != nil {
+		metrics.BotNotificationErrors.WithLabelValues(kind).Inc()
+		if !l.handleChannelError(ctx, msg.MonitorID, msg.MonitorName, err) {
+			log.Printf("[listener] %s monitor %d: failed to send: %v", kind, msg.MonitorID, err)
+		}
+		return
+	}
+	log.Printf("[listener] %s monitor %d: sent", kind, msg.MonitorID)
+}
+
 // ── DTEK outage handler ──────────────────────────────────────────────
 
 func (l *listener) handleDtekOutage(ctx context.Context, payload []byte) {
 	var msg mq.DtekOutageMsg
 	if err := json.Unmarshal(payload, &msg); err != nil {
 		log.Printf("[listener] bad dtek_outage message: %v", err)
+		errsink.Capture(err, errsink.Fields{"queue": "dtek_outage"})
 		return
 	}
 	metrics.BotMessagesProcessed.WithLabelValues("dtek_outage").Inc()
@@ -201,27 +282,85 @@ func (l *listener) handleInactivePause(payload []byte) {
 	var msg mq.InactivePauseMsg
 	if err := json.Unmarshal(payload, &msg); err != nil {
 		log.Printf("[listener] bad inactive_pause message: %v", err)
+		errsink.Capture(err, errsink.Fields{"queue": "inactive_pause"})
 		return
 	}
 	metrics.BotMessagesProcessed.WithLabelValues("inactive_pause").Inc()
 	l.notifier.NotifyInactivePause(msg.MonitorID, msg.ChannelID, msg.OwnerTelegramID, msg.MonitorName)
 }
 
+// ── Monitor cleanup handler ──────────────────────────────────────────
+
+func (l *listener) handleMonitorCleanup(payload []byte) {
+	var msg mq.MonitorCleanupMsg
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		log.Printf("[listener] bad monitor_cleanup message: %v", err)
+		errsink.Capture(err, errsink.Fields{"queue": "monitor_cleanup"})
+		return
+	}
+	metrics.BotMessagesProcessed.WithLabelValues("monitor_cleanup").Inc()
+	l.notifier.CleanupDeletedMonitor(msg.MonitorID, msg.ChannelID, msg.MonitorName, msg.GraphMsgID, msg.LatencyGraphMsgID, msg.DailyGraphMsgID, msg.MonthlyGraphMsgID, msg.OutagePhotoMsgID, msg.DtekMsgID)
+}
+
+// ── Owner digest handler ─────────────────────────────────────────────
+
+func (l *listener) handleOwnerDigest(payload []byte) {
+	var msg mq.OwnerDigestMsg
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		log.Printf("[listener] bad owner_digest message: %v", err)
+		errsink.Capture(err, errsink.Fields{"queue": "owner_digest"})
+		return
+	}
+	if msg.OwnerTelegramID == 0 {
+		return
+	}
+	metrics.BotMessagesProcessed.WithLabelValues("owner_digest").Inc()
+	opts := &tele.SendOptions{ParseMode: tele.ModeHTML, DisableWebPagePreview: true}
+	if _, err := l.bot.Send(&tele.Chat{ID: msg.OwnerTelegramID}, msg.Text, opts); err != nil {
+		metrics.BotNotificationErrors.WithLabelValues("owner_digest").Inc()
+		log.Printf("[listener] owner digest to user %d failed: %v", msg.OwnerTelegramID, err)
+	}
+}
+
+// ── Interval hint handler ────────────────────────────────────────────
+
+func (l *listener) handleIntervalHint(payload []byte) {
+	var msg mq.IntervalHintMsg
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		log.Printf("[listener] bad interval_hint message: %v", err)
+		errsink.Capture(err, errsink.Fields{"queue": "interval_hint"})
+		return
+	}
+	metrics.BotMessagesProcessed.WithLabelValues("interval_hint").Inc()
+	l.notifier.NotifyIntervalHint(msg.MonitorID, msg.OwnerTelegramID, msg.MonitorName, msg.IntervalSec, msg.ThresholdSec, msg.SuggestedThresholdSec)
+}
+
 // ── Status change handler ────────────────────────────────────────────
 
 func (l *listener) handleStatusChange(payload []byte) {
 	var msg mq.StatusChangeMsg
 	if err := json.Unmarshal(payload, &msg); err != nil {
 		log.Printf("[listener] bad status_change message: %v", err)
+		errsink.Capture(err, errsink.Fields{"queue": "status_change"})
 		return
 	}
 	metrics.BotMessagesProcessed.WithLabelValues("status_change").Inc()
-	duration := time.Duration(msg.DurationSec * float64(time.Second))
-	l.notifier.NotifyStatusChange(
-		msg.MonitorID, msg.ChannelID, msg.Name, msg.Address,
-		msg.NotifyAddress, msg.IsOnline, duration, msg.When,
-		msg.OutageRegion, msg.OutageGroup, msg.NotifyOutage,
-	)
+	if msg.Sandbox {
+		l.notifier.SendSandboxStatus(msg.StatusChange())
+		return
+	}
+	delivered := l.notifier.NotifyStatusChange(msg.StatusChange())
+	if delivered {
+		if err := l.db.RecordChannelPost(context.Background(), msg.MonitorID); err != nil {
+			log.Printf("[listener] monitor %d: failed to record channel post: %v", msg.MonitorID, err)
+		}
+	}
+	if delivered && l.canaryChannelID != 0 && msg.ChannelID == l.canaryChannelID {
+		// Close the canary loop: the worker checks this against the expected phase.
+		if err := l.db.RecordCanaryDelivery(context.Background(), msg.MonitorID, msg.IsOnline, time.Now()); err != nil {
+			log.Printf("[listener] canary monitor %d: failed to record delivery: %v", msg.MonitorID, err)
+		}
+	}
 }
 
 // ── Graph ready handler ──────────────────────────────────────────────
@@ -230,9 +369,14 @@ func (l *listener) handleGraphReady(ctx context.Context, payload []byte) {
 	var msg mq.GraphReadyMsg
 	if err := json.Unmarshal(payload, &msg); err != nil {
 		log.Printf("[listener] bad graph_ready message: %v", err)
+		errsink.Capture(err, errsink.Fields{"queue": "graph_ready"})
 		return
 	}
 	metrics.BotMessagesProcessed.WithLabelValues("graph").Inc()
+	if msg.Sandbox {
+		l.sendSandboxPhoto(msg.MonitorID, msg.ChannelID, msg.ImagePNG, "graph.png", msg.Caption)
+		return
+	}
 
 	chat := &tele.Chat{ID: msg.ChannelID}
 	silent := &tele.SendOptions{DisableNotification: bot.IsQuietHour()}
@@ -248,9 +392,19 @@ func (l *listener) handleGraphReady(ctx context.Context, payload []byte) {
 			l.handleChannelError(ctx, msg.MonitorID, msg.MonitorName, err)
 			return
 		}
-		if err := l.db.UpdateGraphMessage(ctx, msg.MonitorID, sent.ID, msg.WeekStart); err != nil {
+		save := l.db.UpdateGraphMessage
+		switch {
+		case msg.Kind == mq.GraphLatency:
+			save = l.db.UpdateLatencyGraphMessage
+		case msg.Period == mq.GraphDay:
+			save = l.db.UpdateDailyGraphMessage
+		case msg.Period == mq.GraphMonth:
+			save = l.db.UpdateMonthlyGraphMessage
+		}
+		if err := save(ctx, msg.MonitorID, sent.ID, msg.WeekStart); err != nil {
 			log.Printf("[listener] graph monitor %d: failed to save message id: %v", msg.MonitorID, err)
 		}
+		l.saveGraphHash(ctx, msg)
 		log.Printf("[listener] graph monitor %d: sent new (msg %d)", msg.MonitorID, sent.ID)
 	} else {
 		editPhoto := &tele.Photo{
@@ -261,6 +415,7 @@ func (l *listener) handleGraphReady(ctx context.Context, payload []byte) {
 		_, err := l.bot.EditMedia(editMsg, editPhoto)
 		if err != nil {
 			if strings.Contains(err.Error(), "message is not modified") {
+				l.saveGraphHash(ctx, msg)
 				return
 			}
 			metrics.BotNotificationErrors.WithLabelValues("graph").Inc()
@@ -270,20 +425,37 @@ func (l *listener) handleGraphReady(ctx context.Context, payload []byte) {
 			// Edit failed (e.g. Telegram API timeout) — skip, will retry on next hourly run.
 			log.Printf("[listener] graph monitor %d: edit failed (%v), will retry next run", msg.MonitorID, err)
 		} else {
+			l.saveGraphHash(ctx, msg)
 			log.Printf("[listener] graph monitor %d: updated (msg %d)", msg.MonitorID, msg.OldMsgID)
 		}
 	}
 }
 
+// saveGraphHash remembers which event set the channel graph now reflects,
+// so the worker can skip re-rendering until it changes.
+func (l *listener) saveGraphHash(ctx context.Context, msg mq.GraphReadyMsg) {
+	if msg.EventsHash == "" {
+		return
+	}
+	if err := l.db.SetGraphEventsHash(ctx, msg.MonitorID, msg.EventsHash); err != nil {
+		log.Printf("[listener] graph monitor %d: failed to save events hash: %v", msg.MonitorID, err)
+	}
+}
+
 // ── Outage photo handler ─────────────────────────────────────────────
 
 func (l *listener) handleOutagePhoto(ctx context.Context, payload []byte) {
 	var msg mq.OutagePhotoMsg
 	if err := json.Unmarshal(payload, &msg); err != nil {
 		log.Printf("[listener] bad outage_photo message: %v", err)
+		errsink.Capture(err, errsink.Fields{"queue": "outage_photo"})
 		return
 	}
 	metrics.BotMessagesProcessed.WithLabelValues("outage_photo").Inc()
+	if msg.Sandbox {
+		l.sendSandboxPhoto(msg.MonitorID, msg.ChannelID, msg.ImageData, msg.Filename, msg.Caption)
+		return
+	}
 
 	switch msg.Action {
 	case mq.OutagePhotoDelete:
@@ -369,12 +541,28 @@ func (l *listener) sendPhoto(ctx context.Context, msg mq.OutagePhotoMsg) {
 
 // ── Helpers ──────────────────────────────────────────────────────────
 
+// sendSandboxPhoto posts an admin test-drive image. Nothing is stored and
+// delivery errors never affect the monitor the image was rendered for.
+func (l *listener) sendSandboxPhoto(monitorID, channelID int64, data []byte, filename, caption string) {
+	photo := &tele.Photo{
+		File:    tele.FromReader(namedReader(data, filename)),
+		Caption: caption,
+	}
+	if _, err := l.bot.Send(&tele.Chat{ID: channelID}, photo); err != nil {
+		log.Printf("[listener] sandbox photo for monitor %d to channel %d failed: %v", monitorID, channelID, err)
+		return
+	}
+	log.Printf("[listener] sandbox photo for monitor %d sent to channel %d", monitorID, channelID)
+}
+
 // handleChannelError delegates to bot.NotifyChannelError.
 // Returns true if the error was a channel error and was handled.
 func (l *listener) handleChannelError(ctx context.Context, monitorID int64, monitorName string, err error) bool {
+	errsink.Capture(err, errsink.Fields{"component": "telegram", "monitor_id": monitorID})
 	ownerID, dbErr := l.db.GetOwnerTelegramIDByMonitorID(ctx, monitorID)
 	if dbErr != nil {
 		log.Printf("[listener] failed to get owner for monitor %d: %v", monitorID, dbErr)
+		errsink.Capture(dbErr, errsink.Fields{"component": "listener", "monitor_id": monitorID})
 		return false
 	}
 	monitor := &models.Monitor{ID: monitorID, Name: monitorName}
@@ -0,0 +1,196 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/models"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+// Account linking: a second Telegram account (e.g. a work phone) runs /link to
+// get a one-time code, the primary account sends /link <code> and approves.
+// The second account then sees and manages the primary account's monitors.
+
+// LinkCodeTTL is how long a link code can be redeemed.
+const LinkCodeTTL = 15 * time.Minute
+
+// linkCodePrefix marks link codes so they aren't mistaken for channel codes.
+const linkCodePrefix = "LINK-"
+
+// handleLink handles /link (secondary: get a code) and /link <code> (primary: approve).
+func (b *Bot) handleLink(c tele.Context) error {
+	ctx := context.Background()
+	if code := strings.ToUpper(strings.TrimSpace(c.Message().Payload)); code != "" {
+		return b.redeemLinkCode(ctx, c, code)
+	}
+
+	sender := c.Sender()
+	primary, err := b.db.GetPrimaryAccount(ctx, sender.ID)
+	if err != nil {
+		log.Printf("[bot] link: get primary of %d: %v", sender.ID, err)
+		return c.Send(msgError)
+	}
+	if primary != nil {
+		return c.Send(fmt.Sprintf(msgLinkAlreadyLinked, html.EscapeString(userLabel(primary))), htmlOpts)
+	}
+
+	code := linkCodePrefix + randomCode(6)
+	if err := b.db.CreateLinkCode(ctx, code, sender.ID, sender.Username, sender.FirstName, LinkCodeTTL); err != nil {
+		log.Printf("[bot] link: create code for %d: %v", sender.ID, err)
+		return c.Send(msgError)
+	}
+	return c.Send(fmt.Sprintf(msgLinkCode, code, int(LinkCodeTTL.Minutes())), htmlOpts)
+}
+
+// redeemLinkCode asks the primary account to confirm a link code.
+func (b *Bot) redeemLinkCode(ctx context.Context, c tele.Context, code string) error {
+	lc, err := b.db.GetLinkCode(ctx, code)
+	if err != nil {
+		log.Printf("[bot] link: get code: %v", err)
+		return c.Send(msgError)
+	}
+	if lc == nil {
+		return c.Send(msgLinkCodeInvalid)
+	}
+	if lc.TelegramID == c.Sender().ID {
+		return c.Send(msgLinkCodeOwn)
+	}
+	markup := &tele.ReplyMarkup{InlineKeyboard: [][]tele.InlineButton{{
+		{Text: msgLinkBtnApprove, Data: "link_ok:" + lc.Code},
+		{Text: msgLinkBtnDecline, Data: "link_no:" + lc.Code},
+	}}}
+	label := linkCodeLabel(lc)
+	return c.Send(fmt.Sprintf(msgLinkConfirm, html.EscapeString(label)), tele.ModeHTML, markup)
+}
+
+// handleUnlink handles /unlink: a secondary account drops its own link, a
+// primary account gets buttons to remove its linked accounts.
+func (b *Bot) handleUnlink(c tele.Context) error {
+	ctx := context.Background()
+	sender := c.Sender()
+
+	removed, err := b.db.UnlinkAccount(ctx, 0, sender.ID)
+	if err != nil {
+		log.Printf("[bot] unlink %d: %v", sender.ID, err)
+		return c.Send(msgError)
+	}
+	if removed {
+		log.Printf("[bot] account %d unlinked itself", sender.ID)
+		return c.Send(msgUnlinkDone)
+	}
+
+	linked, err := b.db.GetLinkedAccounts(ctx, sender.ID)
+	if err != nil {
+		log.Printf("[bot] unlink: get linked accounts of %d: %v", sender.ID, err)
+		return c.Send(msgError)
+	}
+	if len(linked) == 0 {
+		return c.Send(msgUnlinkNone)
+	}
+	rows := make([][]tele.InlineButton, 0, len(linked))
+	for _, u := range linked {
+		rows = append(rows, []tele.InlineButton{
+			{Text: fmt.Sprintf(msgLinkBtnRemove, userLabel(u)), Data: fmt.Sprintf("link_rm:%d", u.TelegramID)},
+		})
+	}
+	return c.Send(msgUnlinkChoose, &tele.ReplyMarkup{InlineKeyboard: rows})
+}
+
+// handleLinkCallback handles the link_ok, link_no and link_rm buttons, which
+// carry a code or account instead of a monitor ID.
+func (b *Bot) handleLinkCallback(c tele.Context, action, arg string) error {
+	ctx := context.Background()
+	sender := c.Sender()
+
+	if action == "link_rm" {
+		secondaryID, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return c.Respond(&tele.CallbackResponse{Text: msgInvalidFormat})
+		}
+		removed, err := b.db.UnlinkAccount(ctx, sender.ID, secondaryID)
+		if err != nil {
+			log.Printf("[bot] unlink %d from %d: %v", secondaryID, sender.ID, err)
+			return c.Respond(&tele.CallbackResponse{Text: msgLinkActionError})
+		}
+		_ = c.Respond(&tele.CallbackResponse{})
+		if !removed {
+			return c.Edit(msgLinkRequestGone, &tele.ReplyMarkup{})
+		}
+		log.Printf("[bot] account %d unlinked from %d", secondaryID, sender.ID)
+		SendToUser(b.bot, secondaryID, msgUnlinkedDM)
+		return c.Edit(msgUnlinkDone, &tele.ReplyMarkup{})
+	}
+
+	lc, err := b.db.GetLinkCode(ctx, arg)
+	if err != nil {
+		log.Printf("[bot] link: get code: %v", err)
+		return c.Respond(&tele.CallbackResponse{Text: msgLinkActionError})
+	}
+	_ = c.Respond(&tele.CallbackResponse{})
+	if lc == nil {
+		return c.Edit(msgLinkRequestGone, &tele.ReplyMarkup{})
+	}
+	label := html.EscapeString(linkCodeLabel(lc))
+
+	if action == "link_no" {
+		if err := b.db.DeleteLinkCode(ctx, lc.Code); err != nil {
+			log.Printf("[bot] link: delete code: %v", err)
+		}
+		SendToUser(b.bot, lc.TelegramID, msgLinkDeclinedDM)
+		return c.Edit(fmt.Sprintf(msgLinkDeclined, label), tele.ModeHTML, &tele.ReplyMarkup{})
+	}
+
+	if _, err := b.db.UpsertUser(ctx, sender.ID, sender.Username, sender.FirstName); err != nil {
+		log.Printf("[bot] link: upsert user %d: %v", sender.ID, err)
+		return c.Edit(msgError, &tele.ReplyMarkup{})
+	}
+	err = b.db.LinkAccount(ctx, sender.ID, lc.TelegramID, lc.Username, lc.FirstName)
+	if errors.Is(err, database.ErrLinkNotAllowed) {
+		return c.Edit(msgLinkNotAllowed, &tele.ReplyMarkup{})
+	}
+	if err != nil {
+		log.Printf("[bot] link %d to %d: %v", lc.TelegramID, sender.ID, err)
+		return c.Edit(msgError, &tele.ReplyMarkup{})
+	}
+	if err := b.db.DeleteLinkCode(ctx, lc.Code); err != nil {
+		log.Printf("[bot] link: delete code: %v", err)
+	}
+	log.Printf("[bot] account %d linked to %d (@%s)", lc.TelegramID, sender.ID, sender.Username)
+	SendToUser(b.bot, lc.TelegramID, msgLinkApprovedDM)
+	return c.Edit(fmt.Sprintf(msgLinkApproved, label), tele.ModeHTML, &tele.ReplyMarkup{})
+}
+
+// randomCode returns n random characters from channelCodeAlphabet.
+func randomCode(n int) string {
+	buf := make([]byte, n)
+	_, _ = rand.Read(buf)
+	for i := range buf {
+		buf[i] = channelCodeAlphabet[int(buf[i])%len(channelCodeAlphabet)]
+	}
+	return string(buf)
+}
+
+// userLabel is how another account is shown in linking messages.
+func userLabel(u *models.User) string {
+	if u.Username != "" {
+		return "@" + u.Username
+	}
+	if u.FirstName != "" {
+		return u.FirstName
+	}
+	return strconv.FormatInt(u.TelegramID, 10)
+}
+
+func linkCodeLabel(lc *models.LinkCode) string {
+	return userLabel(&models.User{TelegramID: lc.TelegramID, Username: lc.Username, FirstName: lc.FirstName})
+}
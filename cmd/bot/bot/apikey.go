@@ -0,0 +1,43 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+// handleAPIKey handles /apikey (issue a new key for /api/v1, replacing the
+// old one) and /apikey revoke.
+func (b *Bot) handleAPIKey(c tele.Context) error {
+	ctx := context.Background()
+	sender := c.Sender()
+	user, err := b.db.UpsertUser(ctx, sender.ID, sender.Username, sender.FirstName)
+	if err != nil {
+		log.Printf("[bot] apikey: upsert user %d: %v", sender.ID, err)
+		return c.Send(msgError)
+	}
+
+	if strings.EqualFold(strings.TrimSpace(c.Message().Payload), "revoke") {
+		revoked, err := b.db.RevokeAPIKey(ctx, user.ID)
+		if err != nil {
+			log.Printf("[bot] apikey: revoke for %d: %v", sender.ID, err)
+			return c.Send(msgError)
+		}
+		if !revoked {
+			return c.Send(msgAPIKeyNone)
+		}
+		log.Printf("[bot] apikey: revoked for %d", sender.ID)
+		return c.Send(msgAPIKeyRevoked)
+	}
+
+	key, err := b.db.CreateAPIKey(ctx, user.ID)
+	if err != nil {
+		log.Printf("[bot] apikey: create for %d: %v", sender.ID, err)
+		return c.Send(msgError)
+	}
+	log.Printf("[bot] apikey: issued for %d", sender.ID)
+	return c.Send(fmt.Sprintf(msgAPIKey, key, b.baseURL), htmlOpts)
+}
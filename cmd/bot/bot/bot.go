@@ -4,11 +4,16 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/http"
 	"sync"
 	"time"
 
+	"no-lights-monitor/internal/contentfilter"
 	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/geocode"
+	"no-lights-monitor/internal/models"
 	"no-lights-monitor/internal/outage"
+	"no-lights-monitor/internal/publicurl"
 
 	tele "gopkg.in/telebot.v3"
 )
@@ -20,23 +25,28 @@ const (
 	stateIdle conversationState = iota
 	stateAwaitingType
 	stateAwaitingPingTarget
+	stateAwaitingHTTPTarget
 	stateAwaitingAddress
 	stateAwaitingManualAddress
 	stateAwaitingChannel
 	stateAwaitingEditName
 	stateAwaitingEditAddress
 	stateAwaitingEditManualAddress
+	stateAwaitingRelinkChannel
+	stateAwaitingEditWebhook
+	stateAwaitingEditMaintenance
 )
 
 type conversationData struct {
 	State         conversationState
-	MonitorType   string // "heartbeat" or "ping"
-	PingTarget    string // IP/hostname for ping monitors
+	MonitorType   string // "heartbeat", "ping" or "http"
+	PingTarget    string // IP/hostname for ping monitors, URL for http monitors
 	Name          string
 	Address       string
 	Latitude      float64
 	Longitude     float64
-	EditMonitorID int64 // ID of monitor being edited
+	EditMonitorID int64  // ID of monitor being edited
+	ChannelCode   string // one-time code to link a private channel by posting it there
 }
 
 // GraphUpdater is used to trigger a graph update for a newly created monitor.
@@ -44,17 +54,32 @@ type GraphUpdater interface {
 	UpdateSingle(ctx context.Context, monitorID, channelID int64) error
 }
 
+// ChannelCleaner removes a deleted monitor's messages from its channel.
+type ChannelCleaner interface {
+	CleanupChannel(ctx context.Context, m *models.Monitor) error
+}
+
 // Bot wraps the Telegram bot and registration conversation logic.
 type Bot struct {
 	bot           *tele.Bot
 	db            *database.DB
 	pingHost      func(string) bool
 	baseURL       string
+	hosts         *publicurl.Hosts
 	chatUsername  string
 	graphUpdater  GraphUpdater
+	cleaner       ChannelCleaner
 	outageClient  *outage.Client
+	geocoder      *geocode.Client
+	filter        *contentfilter.Filter
 	conversations map[int64]*conversationData
 	mu            sync.RWMutex
+
+	discussions   map[int64]discussionLink // discussion group ID -> linked channel
+	discussionsMu sync.Mutex
+
+	touches userTouches // last user upsert per sender (see route)
+	limiter userLimiter // per-sender token buckets (see route)
 }
 
 var htmlOpts = &tele.SendOptions{ParseMode: tele.ModeHTML}
@@ -67,6 +92,7 @@ var createTypeMenu = &tele.ReplyMarkup{
 	ReplyKeyboard: [][]tele.ReplyButton{
 		{{Text: msgCreateBtnHeartbeat}},
 		{{Text: msgCreateBtnPing}},
+		{{Text: msgCreateBtnHTTP}},
 	},
 }
 
@@ -85,10 +111,11 @@ var mainMenu = &tele.ReplyMarkup{
 }
 
 // New creates and configures the Telegram bot.
-func New(token string, db *database.DB, pingHost func(string) bool, baseURL, chatUsername string) (*Bot, error) {
+func New(token string, db *database.DB, pingHost func(string) bool, hosts *publicurl.Hosts, chatUsername string, client *http.Client) (*Bot, error) {
 	pref := tele.Settings{
 		Token:  token,
 		Poller: &tele.LongPoller{Timeout: 10 * time.Second},
+		Client: client,
 	}
 
 	b, err := tele.NewBot(pref)
@@ -100,9 +127,12 @@ func New(token string, db *database.DB, pingHost func(string) bool, baseURL, cha
 		bot:           b,
 		db:            db,
 		pingHost:      pingHost,
-		baseURL:       baseURL,
+		baseURL:       hosts.Canonical(),
+		hosts:         hosts,
 		chatUsername:  chatUsername,
+		filter:        contentfilter.New(db),
 		conversations: make(map[int64]*conversationData),
+		discussions:   make(map[int64]discussionLink),
 	}
 
 	bot.registerHandlers()
@@ -111,14 +141,26 @@ func New(token string, db *database.DB, pingHost func(string) bool, baseURL, cha
 		{Text: "create", Description: "Налаштувати новий монітор"},
 		{Text: "info", Description: "Детальна інформація та URL для пінгу"},
 		{Text: "edit", Description: "Змінити налаштування монітора"},
+		{Text: "relink", Description: "Перенести монітор в інший канал"},
 		{Text: "test", Description: "Відправити тестове повідомлення"},
 		{Text: "stop", Description: "Призупинити моніторинг"},
 		{Text: "resume", Description: "Відновити моніторинг"},
+		{Text: "mute", Description: "Тимчасово вимкнути сповіщення"},
 		{Text: "delete", Description: "Видалити монітор"},
+		{Text: "follow", Description: "Стежити за чужим монітором"},
+		{Text: "unfollow", Description: "Відписатися від монітора"},
+		{Text: "link", Description: "Доступ до моніторів з іншого акаунта"},
+		{Text: "unlink", Description: "Відв'язати інший акаунт"},
+		{Text: "apikey", Description: "Ключ для REST API"},
 		{Text: "help", Description: "Довідка про команди"},
 	}); err != nil {
 		log.Printf("[bot] failed to set commands: %v", err)
 	}
+	if err := b.SetCommands([]tele.Command{
+		{Text: "status", Description: "Чи є зараз світло"},
+	}, tele.CommandScope{Type: tele.CommandScopeAllGroupChats}); err != nil {
+		log.Printf("[bot] failed to set group commands: %v", err)
+	}
 
 	return bot, nil
 }
@@ -139,36 +181,101 @@ func (b *Bot) SetGraphUpdater(g GraphUpdater) {
 	b.graphUpdater = g
 }
 
+// SetChannelCleaner wires the channel cleanup requester used on deletion.
+func (b *Bot) SetChannelCleaner(c ChannelCleaner) {
+	b.cleaner = c
+}
+
 // SetOutageClient wires the outage service client.
 func (b *Bot) SetOutageClient(c *outage.Client) {
 	b.outageClient = c
 }
 
+// SetGeocoder wires the address lookups shared with the API (cache and
+// Nominatim throttle).
+func (b *Bot) SetGeocoder(g *geocode.Client) {
+	b.geocoder = g
+}
+
 // TeleBot returns the underlying telebot instance (used by the notifier).
 func (b *Bot) TeleBot() *tele.Bot {
 	return b.bot
 }
 
 func (b *Bot) registerHandlers() {
-	b.bot.Handle("/start", b.handleStart)
-	b.bot.Handle("/create", b.handleCreate)
-	b.bot.Handle("/info", b.handleInfo)
-	b.bot.Handle("/stop", b.handleStop)
-	b.bot.Handle("/resume", b.handleResume)
-	b.bot.Handle("/test", b.handleTest)
-	b.bot.Handle("/delete", b.handleDelete)
-	b.bot.Handle("/edit", b.handleEdit)
-	b.bot.Handle("/help", b.handleHelp)
-	b.bot.Handle("/cancel", b.handleCancel)
+	b.bot.Use(b.refuseBanned)
+	b.bot.Use(b.onlyGroupCommands)
+
+	commands := []struct {
+		name string
+		h    tele.HandlerFunc
+	}{
+		{"/start", b.handleStart},
+		{"/create", b.handleCreate},
+		{"/info", b.handleInfo},
+		{"/stop", b.handleStop},
+		{"/resume", b.handleResume},
+		{"/mute", b.handleMute},
+		{"/test", b.handleTest},
+		{"/delete", b.handleDelete},
+		{"/edit", b.handleEdit},
+		{"/relink", b.handleRelink},
+		{"/follow", b.handleFollow},
+		{"/unfollow", b.handleUnfollow},
+		{"/link", b.handleLink},
+		{"/unlink", b.handleUnlink},
+		{"/apikey", b.handleAPIKey},
+		{"/help", b.handleHelp},
+		{"/cancel", b.handleCancel},
+		// Comments in a channel's linked discussion group.
+		{"/status", b.handleStatus},
+	}
+	for _, cmd := range commands {
+		b.handle(cmd.name, cmd.name, cmd.h)
+	}
 
 	// Callback queries for inline buttons.
-	b.bot.Handle(tele.OnCallback, b.handleCallback)
+	b.handle(tele.OnCallback, "callback", b.handleCallback)
 
 	// Handle all text messages for conversation flow.
-	b.bot.Handle(tele.OnText, b.handleText)
+	b.handle(tele.OnText, "text", b.handleText)
 
 	// Handle location sharing.
-	b.bot.Handle(tele.OnLocation, b.handleLocation)
+	b.handle(tele.OnLocation, "location", b.handleLocation)
+
+	// Private channel linking: forwarded media posts and verification codes.
+	b.handle(tele.OnPhoto, "media", b.handleForwardedMedia)
+	b.handle(tele.OnVideo, "media", b.handleForwardedMedia)
+	b.handle(tele.OnDocument, "media", b.handleForwardedMedia)
+	b.handle(tele.OnAnimation, "media", b.handleForwardedMedia)
+	b.handle(tele.OnChannelPost, "channel_post", b.handleChannelPost)
+}
+
+// refuseBanned answers banned users with a polite refusal instead of running
+// their commands. Channel posts have no sender and always pass.
+func (b *Bot) refuseBanned(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		sender := c.Sender()
+		if sender == nil || c.Chat() == nil || c.Chat().Type != tele.ChatPrivate {
+			return next(c)
+		}
+		banned, err := b.db.IsTelegramUserBanned(context.Background(), sender.ID)
+		if err != nil {
+			log.Printf("[bot] ban check for user %d: %v", sender.ID, err)
+			return next(c)
+		}
+		if !banned {
+			return next(c)
+		}
+		log.Printf("[bot] refused update from banned user %d (@%s)", sender.ID, sender.Username)
+		b.mu.Lock()
+		delete(b.conversations, sender.ID)
+		b.mu.Unlock()
+		if c.Callback() != nil {
+			return c.Respond(&tele.CallbackResponse{Text: msgBanned, ShowAlert: true})
+		}
+		return c.Send(msgBanned, removeMenu)
+	}
 }
 
 // ── Text handler (router) ────────────────────────────────────────────
@@ -191,6 +298,8 @@ func (b *Bot) handleText(c tele.Context) error {
 		return b.onCreateType(c, conv)
 	case stateAwaitingPingTarget:
 		return b.onPingTarget(c, conv)
+	case stateAwaitingHTTPTarget:
+		return b.onHTTPTarget(c, conv)
 	case stateAwaitingAddress:
 		return b.onAddress(c, conv)
 	case stateAwaitingManualAddress:
@@ -203,6 +312,12 @@ func (b *Bot) handleText(c tele.Context) error {
 		return b.onEditAddress(c, conv)
 	case stateAwaitingEditManualAddress:
 		return b.onEditManualAddress(c, conv)
+	case stateAwaitingRelinkChannel:
+		return b.onRelinkChannel(c, conv)
+	case stateAwaitingEditWebhook:
+		return b.onEditWebhook(c, conv)
+	case stateAwaitingEditMaintenance:
+		return b.onEditMaintenance(c, conv)
 	}
 	return nil
 }
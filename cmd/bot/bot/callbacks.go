@@ -8,13 +8,14 @@ import (
 	"strconv"
 	"strings"
 
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/maintenance"
 	"no-lights-monitor/internal/models"
 
 	tele "gopkg.in/telebot.v3"
 )
 
 func (b *Bot) handleCallback(c tele.Context) error {
-	log.Printf("[bot] callback %q from user %d (@%s)", c.Callback().Data, c.Sender().ID, c.Sender().Username)
 	data := c.Callback().Data
 	parts := strings.Split(data, ":")
 	if len(parts) < 2 {
@@ -22,6 +23,12 @@ func (b *Bot) handleCallback(c tele.Context) error {
 	}
 
 	action := parts[0]
+	if strings.HasPrefix(action, "link_") {
+		return b.handleLinkCallback(c, action, parts[1])
+	}
+	if action == "info_refresh" {
+		return b.onCallbackInfoRefresh(c)
+	}
 
 	monitorID, err := strconv.ParseInt(parts[1], 10, 64)
 	if err != nil {
@@ -54,6 +61,8 @@ func (b *Bot) handleCallback(c tele.Context) error {
 		return b.onCallbackStop(ctx, c, targetMonitor)
 	case "resume":
 		return b.onCallbackResume(ctx, c, targetMonitor)
+	case "mute":
+		return b.onCallbackMute(ctx, c, parts, targetMonitor)
 	case "delete_confirm":
 		return b.onCallbackDelete(ctx, c, targetMonitor)
 	case "info":
@@ -64,6 +73,10 @@ func (b *Bot) handleCallback(c tele.Context) error {
 		return b.onCallbackEditName(c, targetMonitor)
 	case "edit_address":
 		return b.onCallbackEditAddress(c, targetMonitor)
+	case "edit_webhook":
+		return b.onCallbackEditWebhook(c, targetMonitor)
+	case "edit_maintenance":
+		return b.onCallbackEditMaintenance(ctx, c, targetMonitor)
 	case "edit_channel_refresh":
 		return b.onCallbackEditChannelRefresh(ctx, c, targetMonitor)
 	case "edit_notify_address":
@@ -80,14 +93,36 @@ func (b *Bot) handleCallback(c tele.Context) error {
 		return b.onCallbackEditOutagePhoto(ctx, c, targetMonitor)
 	case "edit_graph":
 		return b.onCallbackEditGraph(ctx, c, targetMonitor)
+	case "edit_graph_day":
+		return b.onCallbackEditDailyGraph(ctx, c, targetMonitor)
+	case "edit_graph_month":
+		return b.onCallbackEditMonthlyGraph(ctx, c, targetMonitor)
+	case "edit_channel_stats":
+		return b.onCallbackEditChannelStats(ctx, c, targetMonitor)
 	case "map_hide":
 		return b.onCallbackMapHide(ctx, c, targetMonitor)
 	case "map_show":
 		return b.onCallbackMapShow(ctx, c, targetMonitor)
+	case "map_privacy":
+		return b.onCallbackMapPrivacy(ctx, c, parts, targetMonitor)
 	case "threshold":
 		return b.onCallbackThreshold(ctx, c, parts, targetMonitor)
+	case "followers":
+		return b.onCallbackFollowers(ctx, c, targetMonitor)
+	case "follow_ok":
+		return b.onCallbackFollowApprove(ctx, c, parts, targetMonitor)
+	case "follow_no":
+		return b.onCallbackFollowDecline(ctx, c, parts, targetMonitor)
+	case "follow_rm":
+		return b.onCallbackFollowRevoke(ctx, c, parts, targetMonitor)
 	case "test":
 		return b.onCallbackTest(c, targetMonitor)
+	case "relink":
+		return b.onCallbackRelink(c, targetMonitor)
+	case "replace_device":
+		return b.onCallbackReplaceDevice(c, targetMonitor)
+	case "replace_device_ok":
+		return b.onCallbackReplaceDeviceConfirm(ctx, c, targetMonitor)
 	default:
 		return c.Respond(&tele.CallbackResponse{Text: msgUnknownAction})
 	}
@@ -98,6 +133,7 @@ func (b *Bot) onCallbackStop(ctx context.Context, c tele.Context, m *models.Moni
 		log.Printf("[bot] set monitor inactive error: %v", err)
 		return c.Respond(&tele.CallbackResponse{Text: msgStopError})
 	}
+	b.recordChange(ctx, m.ID, "is_active", true, false)
 	if m.ChannelID != 0 {
 		if _, err := b.bot.Send(&tele.Chat{ID: m.ChannelID}, msgChannelPaused, htmlOpts); err != nil {
 			log.Printf("[bot] failed to send pause notice to channel %d: %v", m.ChannelID, err)
@@ -115,13 +151,14 @@ func (b *Bot) onCallbackResume(ctx context.Context, c tele.Context, m *models.Mo
 		member, err := b.bot.ChatMemberOf(chat, me)
 		if err != nil || (member.Role != tele.Administrator && member.Role != tele.Creator) || !member.Rights.CanPostMessages {
 			_ = c.Respond(&tele.CallbackResponse{Text: msgResumeNoAccess})
-			return c.Edit(fmt.Sprintf(msgResumeNoAccessDetail, html.EscapeString(m.ChannelName)), tele.ModeHTML, &tele.ReplyMarkup{})
+			return c.Edit(fmt.Sprintf(msgResumeNoAccessDetail, channelLabel(m.ChannelName)), tele.ModeHTML, &tele.ReplyMarkup{})
 		}
 	}
 	if err := b.db.SetMonitorActive(ctx, m.ID, true); err != nil {
 		log.Printf("[bot] set monitor active error: %v", err)
 		return c.Respond(&tele.CallbackResponse{Text: msgResumeError})
 	}
+	b.recordChange(ctx, m.ID, "is_active", false, true)
 	if m.ChannelID != 0 {
 		if _, err := b.bot.Send(&tele.Chat{ID: m.ChannelID}, msgChannelResumed, htmlOpts); err != nil {
 			log.Printf("[bot] failed to send resume notice to channel %d: %v", m.ChannelID, err)
@@ -136,10 +173,47 @@ func (b *Bot) onCallbackDelete(ctx context.Context, c tele.Context, m *models.Mo
 		log.Printf("[bot] delete monitor error: %v", err)
 		return c.Respond(&tele.CallbackResponse{Text: msgDeleteError})
 	}
+	// m still carries the channel message IDs the deletion cleared.
+	if b.cleaner != nil {
+		if err := b.cleaner.CleanupChannel(ctx, m); err != nil {
+			log.Printf("[bot] monitor %d: queue channel cleanup: %v", m.ID, err)
+		}
+	}
+	b.recordChange(ctx, m.ID, database.ChangeFieldDeleted, m.Name, "")
 	_ = c.Respond(&tele.CallbackResponse{Text: msgDeleteOK})
 	return c.Edit(fmt.Sprintf(msgDeleteDone, msgDeleteOK, html.EscapeString(m.Name)), tele.ModeHTML, &tele.ReplyMarkup{})
 }
 
+func (b *Bot) onCallbackReplaceDevice(c tele.Context, m *models.Monitor) error {
+	if m.MonitorType != "heartbeat" {
+		return c.Respond(&tele.CallbackResponse{Text: msgReplaceDevicePing})
+	}
+	_ = c.Respond(&tele.CallbackResponse{})
+	keyboard := &tele.ReplyMarkup{InlineKeyboard: [][]tele.InlineButton{
+		{{Text: msgReplaceDeviceBtnOK, Data: fmt.Sprintf("replace_device_ok:%d", m.ID)}},
+		{{Text: msgReplaceDeviceBtnBack, Data: fmt.Sprintf("edit:%d", m.ID)}},
+	}}
+	return c.Edit(fmt.Sprintf(msgReplaceDeviceConfirm, html.EscapeString(m.Name)), tele.ModeHTML, keyboard)
+}
+
+// onCallbackReplaceDeviceConfirm issues a new heartbeat token for the same
+// monitor record, so a replaced device keeps the monitor's history, graphs
+// and channel wiring.
+func (b *Bot) onCallbackReplaceDeviceConfirm(ctx context.Context, c tele.Context, m *models.Monitor) error {
+	if m.MonitorType != "heartbeat" {
+		return c.Respond(&tele.CallbackResponse{Text: msgReplaceDevicePing})
+	}
+	token, err := b.db.RegenerateMonitorToken(ctx, m.ID, b.hosts.Canonical())
+	if err != nil {
+		log.Printf("[bot] replace device of monitor %d: %v", m.ID, err)
+		return c.Respond(&tele.CallbackResponse{Text: msgReplaceDeviceError})
+	}
+	b.recordChange(ctx, m.ID, database.ChangeFieldToken, "", "")
+	log.Printf("[bot] monitor %d: device replaced by user %d (@%s)", m.ID, c.Sender().ID, c.Sender().Username)
+	_ = c.Respond(&tele.CallbackResponse{})
+	return c.Edit(fmt.Sprintf(msgReplaceDeviceDone, html.EscapeString(m.Name), b.hosts.Canonical(), token), tele.ModeHTML, &tele.ReplyMarkup{})
+}
+
 func (b *Bot) onCallbackInfo(ctx context.Context, c tele.Context, m *models.Monitor) error {
 	_ = c.Respond(&tele.CallbackResponse{})
 
@@ -163,19 +237,27 @@ func (b *Bot) onCallbackInfo(ctx context.Context, c tele.Context, m *models.Moni
 	}
 
 	if m.ChannelID != 0 {
-		bld.WriteString(fmt.Sprintf(msgInfoDetailChannel, html.EscapeString(m.ChannelName)))
+		bld.WriteString(fmt.Sprintf(msgInfoDetailChannel, channelLabel(m.ChannelName)))
 	} else {
 		bld.WriteString("\n")
 	}
 
-	if m.MonitorType == "ping" {
+	switch m.MonitorType {
+	case "ping":
 		bld.WriteString(fmt.Sprintf(msgInfoDetailTypePing, msgInfoTypePing))
 		bld.WriteString(fmt.Sprintf(msgInfoDetailTarget, html.EscapeString(m.PingTarget)))
 		bld.WriteString(msgInfoPingHint)
-	} else {
+	case "http":
+		bld.WriteString(fmt.Sprintf(msgInfoDetailTypePing, msgInfoTypeHTTP))
+		bld.WriteString(fmt.Sprintf(msgInfoDetailTarget, html.EscapeString(m.PingTarget)))
+		bld.WriteString(msgInfoHTTPHint)
+	default:
 		bld.WriteString(fmt.Sprintf(msgInfoDetailTypeHB, msgInfoTypeHeartbeat))
 		bld.WriteString(msgInfoDetailURLLabel)
-		bld.WriteString(fmt.Sprintf(msgInfoDetailURL, b.baseURL, m.Token))
+		bld.WriteString(fmt.Sprintf(msgInfoDetailURL, b.hosts.For(m.PingBaseURL), m.Token))
+		if m.PingIP != "" {
+			bld.WriteString(fmt.Sprintf(msgInfoDetailPingIP, html.EscapeString(m.PingIP), b.hosts.Canonical()))
+		}
 		bld.WriteString(fmt.Sprintf(msgInfoHeartbeatHint, b.chatUsername))
 	}
 
@@ -202,6 +284,9 @@ func (b *Bot) renderEditMenu(c tele.Context, m *models.Monitor) error {
 		{{Text: addrBtnText, Data: fmt.Sprintf("edit_notify_address:%d", m.ID)}},
 		{{Text: mapBtnText, Data: fmt.Sprintf("%s:%d", mapBtnAction, m.ID)}},
 	}
+	if m.IsPublic {
+		rows = append(rows, mapPrivacyRows(m)...)
+	}
 	if m.ChannelID != 0 {
 		rows = append(rows, []tele.InlineButton{
 			{Text: msgEditBtnRefreshChannel, Data: fmt.Sprintf("edit_channel_refresh:%d", m.ID)},
@@ -214,6 +299,27 @@ func (b *Bot) renderEditMenu(c tele.Context, m *models.Monitor) error {
 		rows = append(rows, []tele.InlineButton{
 			{Text: graphBtnText, Data: fmt.Sprintf("edit_graph:%d", m.ID)},
 		})
+		// Daily and monthly graph toggles.
+		dayBtnText := msgEditBtnShowDailyGraph
+		if m.DailyGraphEnabled {
+			dayBtnText = msgEditBtnHideDailyGraph
+		}
+		monthBtnText := msgEditBtnShowMonthGraph
+		if m.MonthlyGraphEnabled {
+			monthBtnText = msgEditBtnHideMonthGraph
+		}
+		rows = append(rows, []tele.InlineButton{
+			{Text: dayBtnText, Data: fmt.Sprintf("edit_graph_day:%d", m.ID)},
+			{Text: monthBtnText, Data: fmt.Sprintf("edit_graph_month:%d", m.ID)},
+		})
+		// Weekly channel stats DM toggle.
+		statsBtnText := msgEditBtnShowStats
+		if m.ChannelStatsEnabled {
+			statsBtnText = msgEditBtnHideStats
+		}
+		rows = append(rows, []tele.InlineButton{
+			{Text: statsBtnText, Data: fmt.Sprintf("edit_channel_stats:%d", m.ID)},
+		})
 	}
 	// Offline threshold toggle.
 	nextThreshold := 300
@@ -251,6 +357,22 @@ func (b *Bot) renderEditMenu(c tele.Context, m *models.Monitor) error {
 			})
 		}
 	}
+	rows = append(rows, []tele.InlineButton{
+		{Text: msgEditBtnFollowers, Data: fmt.Sprintf("followers:%d", m.ID)},
+	})
+	rows = append(rows, []tele.InlineButton{
+		{Text: msgEditBtnWebhook, Data: fmt.Sprintf("edit_webhook:%d", m.ID)},
+	})
+	if m.ChannelID != 0 {
+		rows = append(rows, []tele.InlineButton{
+			{Text: msgEditBtnMaintenance, Data: fmt.Sprintf("edit_maintenance:%d", m.ID)},
+		})
+	}
+	if m.MonitorType == "heartbeat" {
+		rows = append(rows, []tele.InlineButton{
+			{Text: msgEditBtnReplaceDevice, Data: fmt.Sprintf("replace_device:%d", m.ID)},
+		})
+	}
 	keyboard := &tele.ReplyMarkup{InlineKeyboard: rows}
 	return c.Edit(fmt.Sprintf(msgEditChoose, html.EscapeString(m.Name), b.baseURL, m.SettingsToken, m.SettingsPassword), tele.ModeHTML, keyboard)
 }
@@ -272,11 +394,26 @@ func (b *Bot) onCallbackEditName(c tele.Context, m *models.Monitor) error {
 	return c.Send(fmt.Sprintf(msgEditNamePrompt, html.EscapeString(m.Name)), tele.ModeHTML, removeMenu)
 }
 
+func (b *Bot) onCallbackRelink(c tele.Context, m *models.Monitor) error {
+	_ = c.Respond(&tele.CallbackResponse{})
+	conv := &conversationData{
+		State:         stateAwaitingRelinkChannel,
+		EditMonitorID: m.ID,
+	}
+	b.mu.Lock()
+	b.conversations[c.Sender().ID] = conv
+	b.mu.Unlock()
+	prompt := fmt.Sprintf(msgRelinkPrompt, html.EscapeString(m.Name), channelLabel(m.ChannelName))
+	_ = c.Edit(prompt, tele.ModeHTML, &tele.ReplyMarkup{})
+	return c.Send(fmt.Sprintf(msgRelinkSendChannel, b.channelCode(conv)), tele.ModeHTML, backMenu)
+}
+
 func (b *Bot) onCallbackEditAddress(c tele.Context, m *models.Monitor) error {
 	_ = c.Respond(&tele.CallbackResponse{})
 	b.mu.Lock()
 	b.conversations[c.Sender().ID] = &conversationData{
 		State:         stateAwaitingEditAddress,
+		Address:       m.Address, // kept for the change audit
 		EditMonitorID: m.ID,
 	}
 	b.mu.Unlock()
@@ -284,6 +421,43 @@ func (b *Bot) onCallbackEditAddress(c tele.Context, m *models.Monitor) error {
 	return c.Send(fmt.Sprintf(msgEditAddressPrompt, html.EscapeString(m.Address)), tele.ModeHTML, removeMenu)
 }
 
+func (b *Bot) onCallbackEditWebhook(c tele.Context, m *models.Monitor) error {
+	_ = c.Respond(&tele.CallbackResponse{})
+	b.mu.Lock()
+	b.conversations[c.Sender().ID] = &conversationData{
+		State:         stateAwaitingEditWebhook,
+		EditMonitorID: m.ID,
+	}
+	b.mu.Unlock()
+	current := msgEditWebhookNone
+	if m.WebhookURL != "" {
+		current = "<code>" + html.EscapeString(m.WebhookURL) + "</code>"
+	}
+	_ = c.Edit(fmt.Sprintf(msgEditWebhookPrompt, current), tele.ModeHTML, &tele.ReplyMarkup{})
+	return c.Send(fmt.Sprintf(msgEditWebhookPrompt, current), tele.ModeHTML, removeMenu)
+}
+
+func (b *Bot) onCallbackEditMaintenance(ctx context.Context, c tele.Context, m *models.Monitor) error {
+	windows, err := b.db.GetMonitorMaintenanceWindows(ctx, m.ID)
+	if err != nil {
+		log.Printf("[bot] get maintenance windows for monitor %d: %v", m.ID, err)
+		return c.Respond(&tele.CallbackResponse{Text: msgError})
+	}
+	_ = c.Respond(&tele.CallbackResponse{})
+	b.mu.Lock()
+	b.conversations[c.Sender().ID] = &conversationData{
+		State:         stateAwaitingEditMaintenance,
+		EditMonitorID: m.ID,
+	}
+	b.mu.Unlock()
+	current := msgEditMaintenanceNone
+	if len(windows) > 0 {
+		current = "<b>" + maintenance.Format(windows) + "</b>"
+	}
+	_ = c.Edit(fmt.Sprintf(msgEditMaintenancePrompt, current), tele.ModeHTML, &tele.ReplyMarkup{})
+	return c.Send(fmt.Sprintf(msgEditMaintenancePrompt, current), tele.ModeHTML, removeMenu)
+}
+
 func (b *Bot) onCallbackEditChannelRefresh(ctx context.Context, c tele.Context, m *models.Monitor) error {
 	_ = c.Respond(&tele.CallbackResponse{})
 	chat, err := b.bot.ChatByID(m.ChannelID)
@@ -293,13 +467,13 @@ func (b *Bot) onCallbackEditChannelRefresh(ctx context.Context, c tele.Context,
 	}
 	newName := chat.Username
 	if newName == m.ChannelName {
-		return c.Edit(fmt.Sprintf(msgEditChannelRefreshNoChange, newName), tele.ModeHTML, &tele.ReplyMarkup{})
+		return c.Edit(fmt.Sprintf(msgEditChannelRefreshNoChange, channelLabel(newName)), tele.ModeHTML, &tele.ReplyMarkup{})
 	}
 	if err := b.db.UpdateMonitorChannelName(ctx, m.ID, newName); err != nil {
 		log.Printf("[bot] failed to update channel name for monitor %d: %v", m.ID, err)
 		return c.Edit(msgError, tele.ModeHTML, &tele.ReplyMarkup{})
 	}
-	return c.Edit(fmt.Sprintf(msgEditChannelRefreshDone, newName), tele.ModeHTML, &tele.ReplyMarkup{})
+	return c.Edit(fmt.Sprintf(msgEditChannelRefreshDone, channelLabel(newName)), tele.ModeHTML, &tele.ReplyMarkup{})
 }
 
 func (b *Bot) onCallbackEditNotifyAddress(ctx context.Context, c tele.Context, m *models.Monitor) error {
@@ -308,6 +482,7 @@ func (b *Bot) onCallbackEditNotifyAddress(ctx context.Context, c tele.Context, m
 		log.Printf("[bot] set notify_address error: %v", err)
 		return c.Respond(&tele.CallbackResponse{Text: msgNotifyAddressError})
 	}
+	b.recordChange(ctx, m.ID, "notify_address", m.NotifyAddress, newVal)
 	_ = c.Respond(&tele.CallbackResponse{})
 	m.NotifyAddress = newVal
 	return b.renderEditMenu(c, m)
@@ -374,9 +549,12 @@ func (b *Bot) onCallbackOutageGroup(ctx context.Context, c tele.Context, parts [
 		log.Printf("[bot] set outage group error: %v", err)
 		return c.Edit(msgError, tele.ModeHTML, &tele.ReplyMarkup{})
 	}
+	b.recordChange(ctx, m.ID, "outage_group", m.OutageRegion+"/"+m.OutageGroup, region+"/"+group)
 	// Auto-enable notify_outage when setting a group.
 	if err := b.db.SetMonitorNotifyOutage(ctx, m.ID, true); err != nil {
 		log.Printf("[bot] set notify_outage error: %v", err)
+	} else if !m.NotifyOutage {
+		b.recordChange(ctx, m.ID, "notify_outage", false, true)
 	}
 	return c.Edit(fmt.Sprintf(msgOutageGroupSet, html.EscapeString(group), html.EscapeString(region)), tele.ModeHTML, &tele.ReplyMarkup{})
 }
@@ -387,6 +565,7 @@ func (b *Bot) onCallbackEditNotifyOutage(ctx context.Context, c tele.Context, m
 		log.Printf("[bot] set notify_outage error: %v", err)
 		return c.Respond(&tele.CallbackResponse{Text: msgNotifyOutageError})
 	}
+	b.recordChange(ctx, m.ID, "notify_outage", m.NotifyOutage, newVal)
 	_ = c.Respond(&tele.CallbackResponse{})
 	m.NotifyOutage = newVal
 	return b.renderEditMenu(c, m)
@@ -398,17 +577,55 @@ func (b *Bot) onCallbackEditGraph(ctx context.Context, c tele.Context, m *models
 		log.Printf("[bot] set graph_enabled error: %v", err)
 		return c.Respond(&tele.CallbackResponse{Text: msgGraphToggleError})
 	}
+	b.recordChange(ctx, m.ID, "graph_enabled", m.GraphEnabled, newVal)
 	_ = c.Respond(&tele.CallbackResponse{})
 	m.GraphEnabled = newVal
 	return b.renderEditMenu(c, m)
 }
 
+func (b *Bot) onCallbackEditDailyGraph(ctx context.Context, c tele.Context, m *models.Monitor) error {
+	newVal := !m.DailyGraphEnabled
+	if err := b.db.SetMonitorDailyGraphEnabled(ctx, m.ID, newVal); err != nil {
+		log.Printf("[bot] set daily_graph_enabled error: %v", err)
+		return c.Respond(&tele.CallbackResponse{Text: msgGraphToggleError})
+	}
+	b.recordChange(ctx, m.ID, "daily_graph_enabled", m.DailyGraphEnabled, newVal)
+	_ = c.Respond(&tele.CallbackResponse{})
+	m.DailyGraphEnabled = newVal
+	return b.renderEditMenu(c, m)
+}
+
+func (b *Bot) onCallbackEditMonthlyGraph(ctx context.Context, c tele.Context, m *models.Monitor) error {
+	newVal := !m.MonthlyGraphEnabled
+	if err := b.db.SetMonitorMonthlyGraphEnabled(ctx, m.ID, newVal); err != nil {
+		log.Printf("[bot] set monthly_graph_enabled error: %v", err)
+		return c.Respond(&tele.CallbackResponse{Text: msgGraphToggleError})
+	}
+	b.recordChange(ctx, m.ID, "monthly_graph_enabled", m.MonthlyGraphEnabled, newVal)
+	_ = c.Respond(&tele.CallbackResponse{})
+	m.MonthlyGraphEnabled = newVal
+	return b.renderEditMenu(c, m)
+}
+
+func (b *Bot) onCallbackEditChannelStats(ctx context.Context, c tele.Context, m *models.Monitor) error {
+	newVal := !m.ChannelStatsEnabled
+	if err := b.db.SetMonitorChannelStats(ctx, m.ID, newVal); err != nil {
+		log.Printf("[bot] set channel_stats_enabled error: %v", err)
+		return c.Respond(&tele.CallbackResponse{Text: msgStatsToggleError})
+	}
+	b.recordChange(ctx, m.ID, "channel_stats_enabled", m.ChannelStatsEnabled, newVal)
+	_ = c.Respond(&tele.CallbackResponse{})
+	m.ChannelStatsEnabled = newVal
+	return b.renderEditMenu(c, m)
+}
+
 func (b *Bot) onCallbackEditOutagePhoto(ctx context.Context, c tele.Context, m *models.Monitor) error {
 	newVal := !m.OutagePhotoEnabled
 	if err := b.db.SetMonitorOutagePhotoEnabled(ctx, m.ID, newVal); err != nil {
 		log.Printf("[bot] set outage_photo_enabled error: %v", err)
 		return c.Respond(&tele.CallbackResponse{Text: msgOutagePhotoError})
 	}
+	b.recordChange(ctx, m.ID, "outage_photo_enabled", m.OutagePhotoEnabled, newVal)
 	_ = c.Respond(&tele.CallbackResponse{})
 	m.OutagePhotoEnabled = newVal
 	return b.renderEditMenu(c, m)
@@ -419,6 +636,7 @@ func (b *Bot) onCallbackMapHide(ctx context.Context, c tele.Context, m *models.M
 		log.Printf("[bot] set monitor public error: %v", err)
 		return c.Respond(&tele.CallbackResponse{Text: msgMapHideError})
 	}
+	b.recordChange(ctx, m.ID, "is_public", m.IsPublic, false)
 	_ = c.Respond(&tele.CallbackResponse{})
 	m.IsPublic = false
 	return b.renderEditMenu(c, m)
@@ -429,11 +647,72 @@ func (b *Bot) onCallbackMapShow(ctx context.Context, c tele.Context, m *models.M
 		log.Printf("[bot] set monitor public error: %v", err)
 		return c.Respond(&tele.CallbackResponse{Text: msgMapHideError})
 	}
+	b.recordChange(ctx, m.ID, "is_public", m.IsPublic, true)
 	_ = c.Respond(&tele.CallbackResponse{})
 	m.IsPublic = true
 	return b.renderEditMenu(c, m)
 }
 
+// mapPrivacyRows are the toggles for what the public map shows of a monitor.
+func mapPrivacyRows(m *models.Monitor) [][]tele.InlineButton {
+	fuzzText, nameText, channelText := msgMapBtnFuzzLocation, msgMapBtnHideName, msgMapBtnHideChannel
+	if m.MapFuzzLocation {
+		fuzzText = msgMapBtnExactLocation
+	}
+	if m.MapHideName {
+		nameText = msgMapBtnShowName
+	}
+	if m.MapHideChannel {
+		channelText = msgMapBtnShowChannel
+	}
+	rows := [][]tele.InlineButton{
+		{{Text: fuzzText, Data: fmt.Sprintf("map_privacy:%d:fuzz", m.ID)}},
+		{{Text: nameText, Data: fmt.Sprintf("map_privacy:%d:name", m.ID)}},
+	}
+	if m.ChannelName != "" {
+		rows = append(rows, []tele.InlineButton{{Text: channelText, Data: fmt.Sprintf("map_privacy:%d:channel", m.ID)}})
+	}
+	return rows
+}
+
+func (b *Bot) onCallbackMapPrivacy(ctx context.Context, c tele.Context, parts []string, m *models.Monitor) error {
+	if len(parts) < 3 {
+		return c.Respond(&tele.CallbackResponse{Text: msgInvalidFormat})
+	}
+	var (
+		field string
+		cur   *bool
+		set   func(context.Context, int64, bool) error
+	)
+	switch parts[2] {
+	case "fuzz":
+		field, cur, set = "map_fuzz_location", &m.MapFuzzLocation, b.db.SetMonitorMapFuzzLocation
+	case "name":
+		field, cur, set = "map_hide_name", &m.MapHideName, b.db.SetMonitorMapHideName
+	case "channel":
+		field, cur, set = "map_hide_channel", &m.MapHideChannel, b.db.SetMonitorMapHideChannel
+	default:
+		return c.Respond(&tele.CallbackResponse{Text: msgInvalidFormat})
+	}
+	newVal := !*cur
+	if err := set(ctx, m.ID, newVal); err != nil {
+		log.Printf("[bot] set %s error: %v", field, err)
+		return c.Respond(&tele.CallbackResponse{Text: msgMapHideError})
+	}
+	b.recordChange(ctx, m.ID, field, *cur, newVal)
+	_ = c.Respond(&tele.CallbackResponse{})
+	*cur = newVal
+	return b.renderEditMenu(c, m)
+}
+
+// thresholdLabel returns the human label for an offline threshold in seconds.
+func thresholdLabel(sec int) string {
+	if sec == 150 {
+		return msgThreshold150
+	}
+	return msgThreshold300
+}
+
 func (b *Bot) onCallbackThreshold(ctx context.Context, c tele.Context, parts []string, m *models.Monitor) error {
 	_ = c.Respond(&tele.CallbackResponse{})
 	if len(parts) < 3 {
@@ -446,31 +725,35 @@ func (b *Bot) onCallbackThreshold(ctx context.Context, c tele.Context, parts []s
 	if err := b.db.SetMonitorThreshold(ctx, m.ID, sec); err != nil {
 		return c.Edit(msgThresholdError, tele.ModeHTML, &tele.ReplyMarkup{})
 	}
-	label := msgThreshold300
-	if sec == 150 {
-		label = msgThreshold150
+	if sec != m.OfflineThresholdSec {
+		b.recordChange(ctx, m.ID, "offline_threshold_sec", m.OfflineThresholdSec, sec)
 	}
-	_ = c.Edit(fmt.Sprintf(msgThresholdSet, label), tele.ModeHTML, &tele.ReplyMarkup{})
+	_ = c.Edit(fmt.Sprintf(msgThresholdSet, thresholdLabel(sec)), tele.ModeHTML, &tele.ReplyMarkup{})
 	m.OfflineThresholdSec = sec
 	return b.renderEditMenu(c, m)
 }
 
-func (b *Bot) onCallbackTest(c tele.Context, m *models.Monitor) error {
-	if m.ChannelID == 0 {
-		return c.Respond(&tele.CallbackResponse{Text: msgTestNoChannel})
-	}
-
+// SendTestMessage posts the test notification to the monitor's channel.
+// Used by the /test flow and by test requests from the settings page.
+func SendTestMessage(tb *tele.Bot, m *models.Monitor) error {
 	testMsg := fmt.Sprintf(msgTestNotification,
 		html.EscapeString(m.Name),
 		html.EscapeString(m.Address),
 	)
+	_, err := tb.Send(&tele.Chat{ID: m.ChannelID}, testMsg, htmlOpts)
+	return err
+}
+
+func (b *Bot) onCallbackTest(c tele.Context, m *models.Monitor) error {
+	if m.ChannelID == 0 {
+		return c.Respond(&tele.CallbackResponse{Text: msgTestNoChannel})
+	}
 
-	chat := &tele.Chat{ID: m.ChannelID}
-	if _, err := b.bot.Send(chat, testMsg, htmlOpts); err != nil {
+	if err := SendTestMessage(b.bot, m); err != nil {
 		log.Printf("[bot] test notification error: %v", err)
 		return c.Respond(&tele.CallbackResponse{Text: msgTestSendError})
 	}
 
 	_ = c.Respond(&tele.CallbackResponse{Text: msgTestOK})
-	return c.Edit(fmt.Sprintf(msgTestSentTo, msgTestOK, html.EscapeString(m.ChannelName)), tele.ModeHTML, &tele.ReplyMarkup{})
+	return c.Edit(fmt.Sprintf(msgTestSentTo, msgTestOK, channelLabel(m.ChannelName)), tele.ModeHTML, &tele.ReplyMarkup{})
 }
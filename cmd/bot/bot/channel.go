@@ -0,0 +1,172 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"strings"
+
+	"no-lights-monitor/internal/mq"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+// Channels can be linked three ways: by public @username, by forwarding any
+// post from the channel, or by posting a one-time code in the channel. The last
+// two work for private channels, which have no username.
+
+// channelCodeAlphabet omits characters that are easy to confuse (0/O, 1/I).
+const channelCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// channelLabel renders a linked channel for bot messages (HTML-escaped).
+func channelLabel(username string) string {
+	if username == "" {
+		return msgChannelPrivate
+	}
+	return "@" + html.EscapeString(username)
+}
+
+// reply sends a message to the user's private chat with the bot.
+func (b *Bot) reply(user *tele.User, what interface{}, opts ...interface{}) error {
+	_, err := b.bot.Send(user, what, opts...)
+	return err
+}
+
+// channelCode returns the conversation's verification code, generating it on first use.
+func (b *Bot) channelCode(conv *conversationData) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if conv.ChannelCode == "" {
+		conv.ChannelCode = "NLM-" + randomCode(6)
+	}
+	return conv.ChannelCode
+}
+
+// resolveChannel finds the channel a user pointed at in the channel step: either
+// the source of a forwarded post or an @username. It checks that the bot can
+// post there. On failure it returns nil and the message to show the user.
+func (b *Bot) resolveChannel(msg *tele.Message) (*tele.Chat, string) {
+	if msg.IsForwarded() {
+		chat := forwardedChannel(msg)
+		if chat == nil {
+			return nil, msgChannelForwardNotChannel
+		}
+		return b.checkChannelRights(chat)
+	}
+
+	text := strings.TrimSpace(msg.Text)
+	if !strings.HasPrefix(text, "@") {
+		text = "@" + text
+	}
+	chat, err := b.bot.ChatByUsername(text)
+	if err != nil {
+		return nil, fmt.Sprintf(msgChannelNotFound, html.EscapeString(text))
+	}
+	return b.checkChannelRights(chat)
+}
+
+// forwardedChannel returns the channel a forwarded message originally came from.
+func forwardedChannel(msg *tele.Message) *tele.Chat {
+	if msg.Origin != nil && msg.Origin.Chat != nil && msg.Origin.Chat.Type == tele.ChatChannel {
+		return msg.Origin.Chat
+	}
+	if msg.OriginalChat != nil && msg.OriginalChat.Type == tele.ChatChannel {
+		return msg.OriginalChat
+	}
+	return nil
+}
+
+// checkChannelRights verifies the bot is an admin of chat with the right to post.
+func (b *Bot) checkChannelRights(chat *tele.Chat) (*tele.Chat, string) {
+	switch ChannelRightsProblem(b.bot, chat) {
+	case "":
+		return chat, ""
+	case mq.CommandErrNotAdmin:
+		return nil, msgChannelNotAdmin
+	case mq.CommandErrNoPostRight:
+		return nil, msgChannelNoPost
+	default:
+		return nil, msgChannelCheckError
+	}
+}
+
+// ChannelRightsProblem returns why the bot cannot post in chat as one of the
+// mq.CommandErr* reasons, or "" if it can.
+func ChannelRightsProblem(tb *tele.Bot, chat *tele.Chat) string {
+	member, err := tb.ChatMemberOf(chat, tb.Me)
+	if err != nil {
+		return mq.CommandErrCheckFailed
+	}
+	if member.Role != tele.Administrator && member.Role != tele.Creator {
+		return mq.CommandErrNotAdmin
+	}
+	if !member.Rights.CanPostMessages {
+		return mq.CommandErrNoPostRight
+	}
+	return ""
+}
+
+// linkChannel completes whichever channel step the conversation is in.
+func (b *Bot) linkChannel(user *tele.User, conv *conversationData, chat *tele.Chat) error {
+	if conv.State == stateAwaitingRelinkChannel {
+		return b.relinkWithChannel(user, conv, chat)
+	}
+	return b.createWithChannel(user, conv, chat)
+}
+
+// handleChannelPost links a channel when a pending verification code is posted in it.
+func (b *Bot) handleChannelPost(c tele.Context) error {
+	text := strings.ToUpper(strings.TrimSpace(c.Message().Text))
+	if !strings.HasPrefix(text, "NLM-") {
+		return nil
+	}
+
+	var userID int64
+	var conv *conversationData
+	b.mu.RLock()
+	for id, cv := range b.conversations {
+		if cv.ChannelCode == text && (cv.State == stateAwaitingChannel || cv.State == stateAwaitingRelinkChannel) {
+			userID, conv = id, cv
+			break
+		}
+	}
+	b.mu.RUnlock()
+	if conv == nil {
+		return nil
+	}
+
+	chat := c.Chat()
+	user := &tele.User{ID: userID}
+	log.Printf("[bot] verification code posted in channel %d for user %d", chat.ID, userID)
+
+	if _, problem := b.checkChannelRights(chat); problem != "" {
+		return b.reply(user, problem, htmlOpts)
+	}
+	// The code has served its purpose; keep the channel clean.
+	if err := b.bot.Delete(c.Message()); err != nil {
+		log.Printf("[bot] failed to delete verification post in channel %d: %v", chat.ID, err)
+	}
+	if sender, err := b.bot.ChatByID(userID); err == nil {
+		user.Username, user.FirstName = sender.Username, sender.FirstName
+	}
+	return b.linkChannel(user, conv, chat)
+}
+
+// handleForwardedMedia routes forwarded non-text channel posts (photos, videos,
+// files) to the channel step; text posts arrive through handleText.
+func (b *Bot) handleForwardedMedia(c tele.Context) error {
+	b.mu.RLock()
+	conv, exists := b.conversations[c.Sender().ID]
+	b.mu.RUnlock()
+
+	if !exists || !c.Message().IsForwarded() {
+		return nil
+	}
+	switch conv.State {
+	case stateAwaitingChannel:
+		return b.onChannel(c, conv)
+	case stateAwaitingRelinkChannel:
+		return b.onRelinkChannel(c, conv)
+	}
+	return nil
+}
@@ -2,11 +2,14 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"html"
 	"log"
 	"strings"
+	"time"
 
+	"no-lights-monitor/internal/database"
 	"no-lights-monitor/internal/models"
 
 	tele "gopkg.in/telebot.v3"
@@ -15,17 +18,18 @@ import (
 // ── Simple commands ──────────────────────────────────────────────────
 
 func (b *Bot) handleStart(c tele.Context) error {
-	log.Printf("[bot] /start from user %d (@%s)", c.Sender().ID, c.Sender().Username)
+	// Deep link from a monitor's followers menu: t.me/<bot>?start=follow_<token>.
+	if token, ok := strings.CutPrefix(c.Message().Payload, followStartPrefix); ok && token != "" {
+		return b.requestFollow(c, token)
+	}
 	return c.Send(fmt.Sprintf(msgStart, b.baseURL, b.chatUsername), tele.ModeHTML, mainMenu)
 }
 
 func (b *Bot) handleHelp(c tele.Context) error {
-	log.Printf("[bot] /help from user %d (@%s)", c.Sender().ID, c.Sender().Username)
 	return c.Send(fmt.Sprintf(msgHelp, b.baseURL, b.chatUsername), htmlOpts)
 }
 
 func (b *Bot) handleCancel(c tele.Context) error {
-	log.Printf("[bot] /cancel from user %d (@%s)", c.Sender().ID, c.Sender().Username)
 	b.mu.Lock()
 	delete(b.conversations, c.Sender().ID)
 	b.mu.Unlock()
@@ -35,7 +39,6 @@ func (b *Bot) handleCancel(c tele.Context) error {
 // ── /stop ────────────────────────────────────────────────────────────
 
 func (b *Bot) handleStop(c tele.Context) error {
-	log.Printf("[bot] /stop from user %d (@%s)", c.Sender().ID, c.Sender().Username)
 	ctx := context.Background()
 	monitors, err := b.db.GetMonitorsByTelegramID(ctx, c.Sender().ID)
 	if err != nil {
@@ -76,7 +79,6 @@ func (b *Bot) handleStop(c tele.Context) error {
 // ── /resume ──────────────────────────────────────────────────────────
 
 func (b *Bot) handleResume(c tele.Context) error {
-	log.Printf("[bot] /resume from user %d (@%s)", c.Sender().ID, c.Sender().Username)
 	ctx := context.Background()
 	monitors, err := b.db.GetMonitorsByTelegramID(ctx, c.Sender().ID)
 	if err != nil {
@@ -117,7 +119,6 @@ func (b *Bot) handleResume(c tele.Context) error {
 // ── /info ────────────────────────────────────────────────────────────
 
 func (b *Bot) handleInfo(c tele.Context) error {
-	log.Printf("[bot] /info from user %d (@%s)", c.Sender().ID, c.Sender().Username)
 	ctx := context.Background()
 	monitors, err := b.db.GetMonitorsByTelegramID(ctx, c.Sender().ID)
 	if err != nil {
@@ -129,36 +130,89 @@ func (b *Bot) handleInfo(c tele.Context) error {
 		return c.Send(msgNoMonitors)
 	}
 
+	text, keyboard := renderInfoList(monitors, time.Now())
+	return c.Send(text, tele.ModeHTML, keyboard)
+}
+
+// onCallbackInfoRefresh re-renders the /info list in place.
+func (b *Bot) onCallbackInfoRefresh(c tele.Context) error {
+	monitors, err := b.db.GetMonitorsByTelegramID(context.Background(), c.Sender().ID)
+	if err != nil {
+		log.Printf("[bot] get monitors error: %v", err)
+		return c.Respond(&tele.CallbackResponse{Text: msgFetchError})
+	}
+	if len(monitors) == 0 {
+		_ = c.Respond(&tele.CallbackResponse{})
+		return c.Edit(msgNoMonitors, &tele.ReplyMarkup{})
+	}
+
+	_ = c.Respond(&tele.CallbackResponse{Text: msgInfoRefreshed})
+	text, keyboard := renderInfoList(monitors, time.Now())
+	if err := c.Edit(text, tele.ModeHTML, keyboard); err != nil && !errors.Is(err, tele.ErrSameMessageContent) && !errors.Is(err, tele.ErrMessageNotModified) {
+		return err
+	}
+	return nil
+}
+
+// infoGroup is one status section of the /info list.
+type infoGroup struct {
+	title    string
+	monitors []*models.Monitor
+	since    bool // show how long the monitors have been in this state
+}
+
+// renderInfoList builds the /info list: monitors grouped by status, offline
+// first, each with the time since its last status change, plus a button per
+// monitor and one that refreshes the list in place.
+func renderInfoList(monitors []*models.Monitor, now time.Time) (string, *tele.ReplyMarkup) {
+	offline := infoGroup{title: msgInfoStatusOffline, since: true}
+	online := infoGroup{title: msgInfoStatusOnline, since: true}
+	paused := infoGroup{title: msgStatusPaused}
+	for _, m := range monitors {
+		switch {
+		case !m.IsActive:
+			paused.monitors = append(paused.monitors, m)
+		case m.IsOnline:
+			online.monitors = append(online.monitors, m)
+		default:
+			offline.monitors = append(offline.monitors, m)
+		}
+	}
+
 	var bld strings.Builder
 	bld.WriteString(msgInfoHeader)
-
-	rows := make([][]tele.InlineButton, 0, len(monitors))
-	for i, m := range monitors {
-		status := msgInfoStatusOffline
-		if m.IsOnline {
-			status = msgInfoStatusOnline
+	rows := make([][]tele.InlineButton, 0, len(monitors)+1)
+	n := 0
+	for _, g := range []infoGroup{offline, online, paused} {
+		if len(g.monitors) == 0 {
+			continue
 		}
-		if !m.IsActive {
-			status = msgStatusPaused
+		bld.WriteString(fmt.Sprintf(msgInfoGroup, g.title, len(g.monitors)))
+		for _, m := range g.monitors {
+			n++
+			if g.since {
+				bld.WriteString(fmt.Sprintf(msgInfoRowSince, n, html.EscapeString(m.Name), database.FormatDuration(now.Sub(m.LastStatusChangeAt))))
+			} else {
+				bld.WriteString(fmt.Sprintf(msgInfoRow, n, html.EscapeString(m.Name)))
+			}
+			rows = append(rows, []tele.InlineButton{
+				{
+					Text: fmt.Sprintf("%d. %s", n, m.Name),
+					Data: fmt.Sprintf("info:%d", m.ID),
+				},
+			})
 		}
-
-		bld.WriteString(fmt.Sprintf(msgInfoRow, i+1, html.EscapeString(m.Name), status))
-		rows = append(rows, []tele.InlineButton{
-			{
-				Text: fmt.Sprintf("%d. %s", i+1, m.Name),
-				Data: fmt.Sprintf("info:%d", m.ID),
-			},
-		})
 	}
+	kyiv, _ := time.LoadLocation("Europe/Kyiv")
+	bld.WriteString(fmt.Sprintf(msgInfoUpdatedAt, now.In(kyiv).Format("15:04:05")))
+	rows = append(rows, []tele.InlineButton{{Text: msgInfoBtnRefresh, Data: "info_refresh:0"}})
 
-	keyboard := &tele.ReplyMarkup{InlineKeyboard: rows}
-	return c.Send(bld.String(), tele.ModeHTML, keyboard)
+	return bld.String(), &tele.ReplyMarkup{InlineKeyboard: rows}
 }
 
 // ── /test ────────────────────────────────────────────────────────────
 
 func (b *Bot) handleTest(c tele.Context) error {
-	log.Printf("[bot] /test from user %d (@%s)", c.Sender().ID, c.Sender().Username)
 	ctx := context.Background()
 	monitors, err := b.db.GetMonitorsByTelegramID(ctx, c.Sender().ID)
 	if err != nil {
@@ -183,7 +237,7 @@ func (b *Bot) handleTest(c tele.Context) error {
 
 	rows := make([][]tele.InlineButton, 0, len(withChannels))
 	for i, m := range withChannels {
-		bld.WriteString(fmt.Sprintf(msgTestRow, i+1, html.EscapeString(m.Name), html.EscapeString(m.ChannelName)))
+		bld.WriteString(fmt.Sprintf(msgTestRow, i+1, html.EscapeString(m.Name), channelLabel(m.ChannelName)))
 		rows = append(rows, []tele.InlineButton{
 			{
 				Text: fmt.Sprintf("%d. %s", i+1, m.Name),
@@ -199,7 +253,6 @@ func (b *Bot) handleTest(c tele.Context) error {
 // ── /delete ──────────────────────────────────────────────────────────
 
 func (b *Bot) handleDelete(c tele.Context) error {
-	log.Printf("[bot] /delete from user %d (@%s)", c.Sender().ID, c.Sender().Username)
 	ctx := context.Background()
 	monitors, err := b.db.GetMonitorsByTelegramID(ctx, c.Sender().ID)
 	if err != nil {
@@ -229,10 +282,41 @@ func (b *Bot) handleDelete(c tele.Context) error {
 	return c.Send(bld.String(), tele.ModeHTML, keyboard)
 }
 
+// ── /relink ──────────────────────────────────────────────────────────
+
+func (b *Bot) handleRelink(c tele.Context) error {
+	ctx := context.Background()
+	monitors, err := b.db.GetMonitorsByTelegramID(ctx, c.Sender().ID)
+	if err != nil {
+		log.Printf("[bot] get monitors error: %v", err)
+		return c.Send(msgError)
+	}
+
+	if len(monitors) == 0 {
+		return c.Send(msgNoMonitors)
+	}
+
+	var bld strings.Builder
+	bld.WriteString(msgRelinkHeader)
+
+	rows := make([][]tele.InlineButton, 0, len(monitors))
+	for i, m := range monitors {
+		bld.WriteString(fmt.Sprintf("%d. %s — %s\n", i+1, html.EscapeString(m.Name), channelLabel(m.ChannelName)))
+		rows = append(rows, []tele.InlineButton{
+			{
+				Text: fmt.Sprintf("%d. %s", i+1, m.Name),
+				Data: fmt.Sprintf("relink:%d", m.ID),
+			},
+		})
+	}
+
+	keyboard := &tele.ReplyMarkup{InlineKeyboard: rows}
+	return c.Send(bld.String(), tele.ModeHTML, keyboard)
+}
+
 // ── /edit ────────────────────────────────────────────────────────────
 
 func (b *Bot) handleEdit(c tele.Context) error {
-	log.Printf("[bot] /edit from user %d (@%s)", c.Sender().ID, c.Sender().Username)
 	ctx := context.Background()
 	monitors, err := b.db.GetMonitorsByTelegramID(ctx, c.Sender().ID)
 	if err != nil {
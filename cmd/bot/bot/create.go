@@ -2,13 +2,17 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"html"
 	"log"
 	"net"
 	"strings"
 
-	"no-lights-monitor/internal/geocode"
+	"no-lights-monitor/internal/contentfilter"
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/ping"
+	"no-lights-monitor/internal/safego"
 
 	tele "gopkg.in/telebot.v3"
 )
@@ -16,7 +20,6 @@ import (
 // ── /create command ──────────────────────────────────────────────────
 
 func (b *Bot) handleCreate(c tele.Context) error {
-	log.Printf("[bot] /create from user %d (@%s)", c.Sender().ID, c.Sender().Username)
 	ctx := context.Background()
 	_, err := b.db.UpsertUser(ctx, c.Sender().ID, c.Sender().Username, c.Sender().FirstName)
 	if err != nil {
@@ -49,6 +52,8 @@ func (b *Bot) onCreateType(c tele.Context, conv *conversationData) error {
 		monitorType = "heartbeat"
 	case msgCreateBtnPing:
 		monitorType = "ping"
+	case msgCreateBtnHTTP:
+		monitorType = "http"
 	default:
 		return c.Send(msgCreateStep1, tele.ModeHTML, createTypeMenu)
 	}
@@ -64,6 +69,13 @@ func (b *Bot) onCreateType(c tele.Context, conv *conversationData) error {
 
 		return c.Send(msgPingTargetStep, tele.ModeHTML, backMenu)
 	}
+	if monitorType == "http" {
+		b.mu.Lock()
+		conv.State = stateAwaitingHTTPTarget
+		b.mu.Unlock()
+
+		return c.Send(msgHTTPTargetStep, tele.ModeHTML, backMenu)
+	}
 
 	// Heartbeat — go directly to address step.
 	b.mu.Lock()
@@ -87,9 +99,8 @@ func (b *Bot) onPingTarget(c tele.Context, conv *conversationData) error {
 		return c.Send(fmt.Sprintf(msgPingHostNotFound, html.EscapeString(target)), htmlOpts)
 	}
 
-	// Check for private IPs.
-	ip := net.ParseIP(ips[0])
-	if ip != nil && (ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast()) {
+	// Check for private IPs; every address counts, not just the first.
+	if ping.AnyPrivate(ips) {
 		return c.Send(msgPingTargetPrivate, htmlOpts)
 	}
 
@@ -109,6 +120,35 @@ func (b *Bot) onPingTarget(c tele.Context, conv *conversationData) error {
 	return c.Send(msgAddressStepPing, tele.ModeHTML, backMenu)
 }
 
+// ── Step 2 (http only): URL ──────────────────────────────────────────
+
+func (b *Bot) onHTTPTarget(c tele.Context, conv *conversationData) error {
+	target, err := ping.ValidateURL(c.Text())
+	switch {
+	case errors.Is(err, ping.ErrPrivateHost):
+		return c.Send(msgHTTPTargetPrivate, htmlOpts)
+	case errors.Is(err, ping.ErrHostUnknown):
+		return c.Send(fmt.Sprintf(msgPingHostNotFound, html.EscapeString(strings.TrimSpace(c.Text()))), htmlOpts)
+	case err != nil:
+		return c.Send(msgHTTPTargetInvalid, htmlOpts)
+	}
+
+	// Test the URL once so typos surface now rather than as an offline alert.
+	_ = c.Send(fmt.Sprintf(msgHTTPChecking, html.EscapeString(target)), htmlOpts)
+	if !ping.CheckURL(context.Background(), target) {
+		return c.Send(fmt.Sprintf(msgHTTPTargetUnreachable, html.EscapeString(target)), htmlOpts)
+	}
+
+	b.mu.Lock()
+	conv.PingTarget = target
+	conv.State = stateAwaitingAddress
+	b.mu.Unlock()
+
+	_ = c.Send(fmt.Sprintf(msgHTTPTargetOK, html.EscapeString(target)), htmlOpts)
+
+	return c.Send(msgAddressStepPing, tele.ModeHTML, backMenu)
+}
+
 // ── Step: Address ────────────────────────────────────────────────────
 
 func (b *Bot) onAddress(c tele.Context, conv *conversationData) error {
@@ -116,6 +156,10 @@ func (b *Bot) onAddress(c tele.Context, conv *conversationData) error {
 	if len(text) < 3 {
 		return c.Send(msgAddressTooShort, htmlOpts)
 	}
+	// The typed address also becomes the monitor's name.
+	if problem := b.contentProblem(contentfilter.Name, text); problem != "" {
+		return c.Send(problem, htmlOpts)
+	}
 
 	// Check if user typed raw coordinates (lat, lng).
 	if parts := strings.Split(text, ","); len(parts) == 2 {
@@ -134,7 +178,7 @@ func (b *Bot) onAddress(c tele.Context, conv *conversationData) error {
 	// Geocode the address.
 	_ = c.Send(msgSearchingAddress)
 
-	result, err := geocode.Search(context.Background(), text)
+	result, err := b.geocoder.Lookup(context.Background(), text)
 	if err != nil {
 		log.Printf("[bot] geocode error: %v", err)
 		return c.Send(msgGeocodeError)
@@ -163,6 +207,9 @@ func (b *Bot) onManualAddress(c tele.Context, conv *conversationData) error {
 	if len(text) < 3 {
 		return c.Send(msgManualAddressTooShort, htmlOpts)
 	}
+	if problem := b.contentProblem(contentfilter.Name, text); problem != "" {
+		return c.Send(problem, htmlOpts)
+	}
 
 	b.mu.Lock()
 	conv.Name = text
@@ -177,43 +224,34 @@ func (b *Bot) onManualAddress(c tele.Context, conv *conversationData) error {
 
 func (b *Bot) channelStepMessage(conv *conversationData) string {
 	step := "3/3"
-	if conv.MonitorType == "ping" {
+	if conv.MonitorType == "ping" || conv.MonitorType == "http" {
 		step = "4/4"
 	}
-	return fmt.Sprintf(msgChannelStep, conv.Latitude, conv.Longitude, step)
+	return fmt.Sprintf(msgChannelStep, conv.Latitude, conv.Longitude, step, b.channelCode(conv))
 }
 
 func (b *Bot) onChannel(c tele.Context, conv *conversationData) error {
-	text := strings.TrimSpace(c.Text())
-
-	if !strings.HasPrefix(text, "@") {
-		text = "@" + text
-	}
-
-	chat, err := b.bot.ChatByUsername(text)
-	if err != nil {
-		return c.Send(fmt.Sprintf(msgChannelNotFound, html.EscapeString(text)), htmlOpts)
-	}
-
-	me := b.bot.Me
-	member, err := b.bot.ChatMemberOf(chat, me)
-	if err != nil {
-		return c.Send(msgChannelCheckError)
-	}
-
-	if member.Role != tele.Administrator && member.Role != tele.Creator {
-		return c.Send(msgChannelNotAdmin)
-	}
-
-	if !member.Rights.CanPostMessages {
-		return c.Send(msgChannelNoPost)
+	chat, problem := b.resolveChannel(c.Message())
+	if chat == nil {
+		return c.Send(problem, htmlOpts)
 	}
+	return b.createWithChannel(c.Sender(), conv, chat)
+}
 
+// createWithChannel finishes the /create flow once the channel is known.
+// Replies go to the user directly because the channel may also be confirmed
+// by a verification code posted in the channel itself.
+func (b *Bot) createWithChannel(sender *tele.User, conv *conversationData, chat *tele.Chat) error {
 	ctx := context.Background()
-	user, err := b.db.UpsertUser(ctx, c.Sender().ID, c.Sender().Username, c.Sender().FirstName)
+	user, err := b.db.UpsertUser(ctx, sender.ID, sender.Username, sender.FirstName)
 	if err != nil {
 		log.Printf("[bot] upsert user error: %v", err)
-		return c.Send(msgErrorRetry)
+		return b.reply(sender, msgErrorRetry)
+	}
+	ownerID, err := b.db.OwnerUserID(ctx, user)
+	if err != nil {
+		log.Printf("[bot] resolve owner of user %d: %v", sender.ID, err)
+		return b.reply(sender, msgErrorRetry)
 	}
 
 	monitorType := conv.MonitorType
@@ -221,46 +259,55 @@ func (b *Bot) onChannel(c tele.Context, conv *conversationData) error {
 		monitorType = "heartbeat"
 	}
 
-	monitor, err := b.db.CreateMonitor(ctx, user.ID, conv.Name, conv.Address, conv.Latitude, conv.Longitude, chat.ID, chat.Username, monitorType, conv.PingTarget)
+	monitor, err := b.db.CreateMonitor(ctx, ownerID, conv.Name, conv.Address, conv.Latitude, conv.Longitude, chat.ID, chat.Username, monitorType, conv.PingTarget, b.hosts.Canonical())
 	if err != nil {
 		log.Printf("[bot] create monitor error: %v", err)
-		return c.Send(msgErrorRetry)
+		return b.reply(sender, msgErrorRetry)
 	}
 
-	log.Printf("[bot] monitor created: id=%d type=%s name=%q user=%d (@%s)", monitor.ID, monitorType, monitor.Name, c.Sender().ID, c.Sender().Username)
+	log.Printf("[bot] monitor created: id=%d type=%s name=%q user=%d (@%s)", monitor.ID, monitorType, monitor.Name, sender.ID, sender.Username)
+	b.recordChange(ctx, monitor.ID, database.ChangeFieldCreated, "", monitor.Name)
 
 	// Trigger initial weekly graph in the channel.
 	if b.graphUpdater != nil && monitor.ChannelID != 0 {
-		go func() {
+		safego.Go("initial_graph", func() {
 			if err := b.graphUpdater.UpdateSingle(context.Background(), monitor.ID, monitor.ChannelID); err != nil {
 				log.Printf("[bot] initial graph for monitor %d failed: %v", monitor.ID, err)
 			}
-		}()
+		})
 	}
 
 	b.mu.Lock()
-	delete(b.conversations, c.Sender().ID)
+	delete(b.conversations, sender.ID)
 	b.mu.Unlock()
 
 	var msg string
-	if monitorType == "ping" {
+	switch monitorType {
+	case "ping":
 		msg = fmt.Sprintf(msgCreateDonePing,
 			html.EscapeString(monitor.Name),
 			html.EscapeString(monitor.PingTarget),
 			conv.Latitude, conv.Longitude,
-			html.EscapeString(chat.Username),
+			channelLabel(chat.Username),
 			html.EscapeString(monitor.PingTarget),
 		)
-	} else {
-		pingURL := fmt.Sprintf("%s/api/ping/%s", b.baseURL, monitor.Token)
+	case "http":
+		msg = fmt.Sprintf(msgCreateDoneHTTP,
+			html.EscapeString(monitor.Name),
+			html.EscapeString(monitor.PingTarget),
+			conv.Latitude, conv.Longitude,
+			channelLabel(chat.Username),
+		)
+	default:
+		pingURL := b.hosts.PingURL(monitor.PingBaseURL, monitor.Token)
 		msg = fmt.Sprintf(msgCreateDoneHeartbeat,
 			html.EscapeString(monitor.Name),
 			conv.Latitude, conv.Longitude,
-			html.EscapeString(chat.Username),
+			channelLabel(chat.Username),
 			html.EscapeString(pingURL),
 			b.chatUsername,
 		)
 	}
 
-	return c.Send(msg, tele.ModeHTML, mainMenu)
+	return b.reply(sender, msg, tele.ModeHTML, mainMenu)
 }
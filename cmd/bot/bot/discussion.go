@@ -0,0 +1,145 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"strings"
+	"time"
+
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/models"
+	"no-lights-monitor/internal/outage"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+// A channel with comments has a linked discussion group where every post is
+// mirrored. Readers can ask the bot about the monitor there; everything else
+// the bot does stays in private chats.
+
+// groupCommands are the commands answered in group chats. Other group
+// messages are dropped before routing so readers' comments never reach the
+// private conversation flows.
+var groupCommands = map[string]bool{"/status": true}
+
+// discussionLinkTTL is how long a group's linked channel is remembered.
+const discussionLinkTTL = 10 * time.Minute
+
+// discussionLink caches the channel a discussion group belongs to (0 = none).
+type discussionLink struct {
+	channelID int64
+	checkedAt time.Time
+}
+
+// onlyGroupCommands lets group and supergroup updates through only when they
+// are one of groupCommands. Private chats and channel posts pass untouched.
+func (b *Bot) onlyGroupCommands(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		chat := c.Chat()
+		if chat == nil || (chat.Type != tele.ChatGroup && chat.Type != tele.ChatSuperGroup) {
+			return next(c)
+		}
+		if c.Message() == nil || !groupCommands[commandName(c.Message().Text)] {
+			return nil
+		}
+		return next(c)
+	}
+}
+
+// commandName returns the bot command a message starts with, without the
+// @botname suffix, or "".
+func commandName(text string) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return ""
+	}
+	name, _, _ := strings.Cut(fields[0], "@")
+	return name
+}
+
+// linkedChannel returns the channel whose discussion group chatID is, or 0.
+func (b *Bot) linkedChannel(chatID int64) (int64, error) {
+	b.discussionsMu.Lock()
+	link, ok := b.discussions[chatID]
+	b.discussionsMu.Unlock()
+	if ok && time.Since(link.checkedAt) < discussionLinkTTL {
+		return link.channelID, nil
+	}
+
+	chat, err := b.bot.ChatByID(chatID)
+	if err != nil {
+		return 0, err
+	}
+	b.discussionsMu.Lock()
+	b.discussions[chatID] = discussionLink{channelID: chat.LinkedChatID, checkedAt: time.Now()}
+	b.discussionsMu.Unlock()
+	return chat.LinkedChatID, nil
+}
+
+// handleStatus answers /status in a discussion group with the current state
+// of the linked channel's monitors and their next scheduled outage.
+func (b *Bot) handleStatus(c tele.Context) error {
+	chat := c.Chat()
+	if chat.Type == tele.ChatPrivate {
+		return c.Send(msgStatusPrivate)
+	}
+	log.Printf("[bot] /status in group %d", chat.ID)
+
+	channelID, err := b.linkedChannel(chat.ID)
+	if err != nil {
+		log.Printf("[bot] /status: get chat %d: %v", chat.ID, err)
+		return nil
+	}
+	if channelID == 0 {
+		return c.Reply(msgStatusNoChannel)
+	}
+
+	ctx := context.Background()
+	monitors, err := b.db.GetMonitorsByChannelID(ctx, channelID)
+	if err != nil {
+		log.Printf("[bot] /status: monitors of channel %d: %v", channelID, err)
+		return nil
+	}
+	if len(monitors) == 0 {
+		return c.Reply(msgStatusNoChannel)
+	}
+
+	now := time.Now()
+	var bld strings.Builder
+	for _, m := range monitors {
+		bld.WriteString(b.statusLine(m, now))
+	}
+	return c.Reply(bld.String(), htmlOpts)
+}
+
+// statusLine renders one monitor for /status, with its next scheduled outage
+// when the monitor has an outage group.
+func (b *Bot) statusLine(m *models.Monitor, now time.Time) string {
+	dur := database.FormatDuration(now.Sub(m.LastStatusChangeAt))
+	icon, state := "🔴", fmt.Sprintf(msgStatusOffline, dur)
+	if m.IsOnline {
+		icon, state = "🟢", fmt.Sprintf(msgStatusOnline, dur)
+	}
+	line := fmt.Sprintf(msgStatusLine, icon, html.EscapeString(m.Name), state)
+
+	if b.outageClient == nil || m.OutageRegion == "" || m.OutageGroup == "" {
+		return line
+	}
+	fact, err := b.outageClient.GetGroupFact(m.OutageRegion, m.OutageGroup)
+	if err != nil {
+		log.Printf("[bot] /status: outage fetch error for %s/%s: %v", m.OutageRegion, m.OutageGroup, err)
+		return line
+	}
+	start, end, ok := outage.NextOutageStart(fact, now, 24*time.Hour)
+	if !ok {
+		return line + msgStatusNoMorePlanned
+	}
+	kyiv, _ := time.LoadLocation("Europe/Kyiv")
+	endStr := end.In(kyiv).Format("15:04")
+	if endStr == "00:00" {
+		endStr = "24:00"
+	}
+	return line + fmt.Sprintf(msgStatusNextPlanned, start.In(kyiv).Format("15:04"), endStr)
+}
@@ -2,14 +2,20 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"html"
 	"log"
 	"strconv"
 	"strings"
 
-	"no-lights-monitor/internal/geocode"
+	"no-lights-monitor/internal/contentfilter"
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/maintenance"
 	"no-lights-monitor/internal/models"
+	"no-lights-monitor/internal/regionhint"
+	"no-lights-monitor/internal/safego"
+	"no-lights-monitor/internal/webhook"
 
 	tele "gopkg.in/telebot.v3"
 )
@@ -19,6 +25,9 @@ func (b *Bot) onEditName(c tele.Context, conv *conversationData) error {
 	if len(name) < 2 {
 		return c.Send(msgEditNameTooShort, htmlOpts)
 	}
+	if problem := b.contentProblem(contentfilter.Name, name); problem != "" {
+		return c.Send(problem, htmlOpts)
+	}
 
 	ctx := context.Background()
 
@@ -46,6 +55,9 @@ func (b *Bot) onEditName(c tele.Context, conv *conversationData) error {
 		log.Printf("[bot] update monitor name error: %v", err)
 		return c.Send(msgErrorRetry)
 	}
+	if name != target.Name {
+		b.recordChange(ctx, target.ID, "name", target.Name, name)
+	}
 
 	b.mu.Lock()
 	delete(b.conversations, c.Sender().ID)
@@ -59,6 +71,9 @@ func (b *Bot) onEditAddress(c tele.Context, conv *conversationData) error {
 	if len(text) < 3 {
 		return c.Send(msgAddressTooShort, htmlOpts)
 	}
+	if problem := b.contentProblem(contentfilter.Address, text); problem != "" {
+		return c.Send(problem, htmlOpts)
+	}
 
 	// Raw coordinates.
 	if parts := strings.Split(text, ","); len(parts) == 2 {
@@ -76,7 +91,7 @@ func (b *Bot) onEditAddress(c tele.Context, conv *conversationData) error {
 
 	_ = c.Send(msgSearchingAddress)
 
-	result, err := geocode.Search(context.Background(), text)
+	result, err := b.geocoder.Lookup(context.Background(), text)
 	if err != nil {
 		log.Printf("[bot] geocode error: %v", err)
 		return c.Send(msgGeocodeError)
@@ -90,12 +105,16 @@ func (b *Bot) onEditAddress(c tele.Context, conv *conversationData) error {
 		log.Printf("[bot] update monitor address error: %v", err)
 		return c.Send(msgErrorRetry)
 	}
+	b.recordChange(ctx, conv.EditMonitorID, "address", conv.Address, result.DisplayName)
 
 	b.mu.Lock()
 	delete(b.conversations, c.Sender().ID)
 	b.mu.Unlock()
 
-	return c.Send(fmt.Sprintf(msgEditAddressDone, html.EscapeString(result.DisplayName)), tele.ModeHTML, mainMenu)
+	if err := c.Send(fmt.Sprintf(msgEditAddressDone, html.EscapeString(result.DisplayName)), tele.ModeHTML, mainMenu); err != nil {
+		return err
+	}
+	return b.offerOutageGroupHint(ctx, c, conv.EditMonitorID)
 }
 
 func (b *Bot) onEditManualAddress(c tele.Context, conv *conversationData) error {
@@ -103,21 +122,269 @@ func (b *Bot) onEditManualAddress(c tele.Context, conv *conversationData) error
 	if len(text) < 3 {
 		return c.Send(msgManualAddressTooShort, htmlOpts)
 	}
+	if problem := b.contentProblem(contentfilter.Address, text); problem != "" {
+		return c.Send(problem, htmlOpts)
+	}
 
 	ctx := context.Background()
 	if err := b.db.UpdateMonitorAddress(ctx, conv.EditMonitorID, text, conv.Latitude, conv.Longitude); err != nil {
 		log.Printf("[bot] update monitor address error: %v", err)
 		return c.Send(msgErrorRetry)
 	}
+	b.recordChange(ctx, conv.EditMonitorID, "address", conv.Address, text)
+
+	b.mu.Lock()
+	delete(b.conversations, c.Sender().ID)
+	b.mu.Unlock()
+
+	if err := c.Send(fmt.Sprintf(msgEditAddressDone, html.EscapeString(text)), tele.ModeHTML, mainMenu); err != nil {
+		return err
+	}
+	return b.offerOutageGroupHint(ctx, c, conv.EditMonitorID)
+}
+
+// offerOutageGroupHint re-checks the outage group after an address change and,
+// if it no longer fits the new location, offers the suggested one.
+func (b *Bot) offerOutageGroupHint(ctx context.Context, c tele.Context, monitorID int64) error {
+	m, err := b.db.GetMonitorByID(ctx, monitorID)
+	if err != nil {
+		log.Printf("[bot] region hint: get monitor %d: %v", monitorID, err)
+		return nil
+	}
+	hint, err := regionhint.Check(ctx, b.db, m)
+	if err != nil {
+		log.Printf("[bot] region hint for monitor %d: %v", monitorID, err)
+		return nil
+	}
+	if hint == nil {
+		return nil
+	}
+
+	current := msgRegionHintNoGroup
+	if m.OutageGroup != "" {
+		current = fmt.Sprintf(msgRegionHintGroup, html.EscapeString(m.OutageGroup), html.EscapeString(m.OutageRegion))
+	}
+	suggested := html.EscapeString(hint.Region)
+	var rows [][]tele.InlineButton
+	if hint.Group != "" {
+		suggested = fmt.Sprintf(msgRegionHintGroup, html.EscapeString(hint.Group), html.EscapeString(hint.Region))
+		rows = append(rows, []tele.InlineButton{{
+			Text: fmt.Sprintf(msgRegionHintBtnApply, hint.Group),
+			Data: fmt.Sprintf("outage_g:%d:%s:%s", m.ID, hint.Region, hint.Group),
+		}})
+	}
+	rows = append(rows, []tele.InlineButton{{
+		Text: msgRegionHintBtnPick,
+		Data: fmt.Sprintf("outage_r:%d:%s", m.ID, hint.Region),
+	}})
+	return c.Send(fmt.Sprintf(msgRegionHint, current, suggested), tele.ModeHTML, &tele.ReplyMarkup{InlineKeyboard: rows})
+}
+
+// onRelinkChannel handles the channel reply of the /relink flow.
+func (b *Bot) onRelinkChannel(c tele.Context, conv *conversationData) error {
+	chat, problem := b.resolveChannel(c.Message())
+	if chat == nil {
+		return c.Send(problem, htmlOpts)
+	}
+	return b.relinkWithChannel(c.Sender(), conv, chat)
+}
+
+// relinkWithChannel finishes the /relink flow once the new channel is known.
+func (b *Bot) relinkWithChannel(sender *tele.User, conv *conversationData, chat *tele.Chat) error {
+	ctx := context.Background()
+
+	// Verify the monitor still belongs to this user.
+	monitors, err := b.db.GetMonitorsByTelegramID(ctx, sender.ID)
+	if err != nil {
+		log.Printf("[bot] get monitors error: %v", err)
+		return b.reply(sender, msgError)
+	}
+	var target *models.Monitor
+	for _, m := range monitors {
+		if m.ID == conv.EditMonitorID {
+			target = m
+			break
+		}
+	}
+
+	b.mu.Lock()
+	delete(b.conversations, sender.ID)
+	b.mu.Unlock()
+
+	if target == nil {
+		return b.reply(sender, msgMonitorNotFound, mainMenu)
+	}
+
+	if chat.ID == target.ChannelID {
+		return b.reply(sender, fmt.Sprintf(msgRelinkSameChannel, channelLabel(chat.Username)), tele.ModeHTML, mainMenu)
+	}
+
+	if err := b.db.RelinkMonitorChannel(ctx, target.ID, chat.ID, chat.Username); err != nil {
+		log.Printf("[bot] relink monitor %d error: %v", target.ID, err)
+		return b.reply(sender, msgErrorRetry, mainMenu)
+	}
+	b.recordChange(ctx, target.ID, "channel", strconv.FormatInt(target.ChannelID, 10), strconv.FormatInt(chat.ID, 10))
+	log.Printf("[bot] monitor %d relinked: channel %d -> %d (@%s)", target.ID, target.ChannelID, chat.ID, chat.Username)
+
+	// Post a fresh weekly graph in the new channel.
+	if b.graphUpdater != nil && target.GraphEnabled {
+		safego.Go("relink_graph", func() {
+			if err := b.graphUpdater.UpdateSingle(context.Background(), target.ID, chat.ID); err != nil {
+				log.Printf("[bot] graph after relink for monitor %d failed: %v", target.ID, err)
+			}
+		})
+	}
+
+	msg := fmt.Sprintf(msgRelinkDone, html.EscapeString(target.Name), channelLabel(chat.Username))
+	if !target.IsActive {
+		msg += msgRelinkResumeHint
+	}
+	return b.reply(sender, msg, tele.ModeHTML, mainMenu)
+}
+
+func (b *Bot) onEditWebhook(c tele.Context, conv *conversationData) error {
+	text := strings.TrimSpace(c.Text())
+	url := ""
+	if text != "-" {
+		var err error
+		if url, err = webhook.ValidateURL(text); err != nil {
+			return c.Send(fmt.Sprintf(msgEditWebhookInvalid, html.EscapeString(err.Error())), htmlOpts)
+		}
+	}
+
+	ctx := context.Background()
+
+	// Verify the monitor still belongs to this user.
+	monitors, err := b.db.GetMonitorsByTelegramID(ctx, c.Sender().ID)
+	if err != nil {
+		log.Printf("[bot] get monitors error: %v", err)
+		return c.Send(msgError)
+	}
+	var target *models.Monitor
+	for _, m := range monitors {
+		if m.ID == conv.EditMonitorID {
+			target = m
+			break
+		}
+	}
+
+	b.mu.Lock()
+	delete(b.conversations, c.Sender().ID)
+	b.mu.Unlock()
+
+	if target == nil {
+		return c.Send(msgMonitorNotFound)
+	}
+
+	// The secret survives URL changes so receivers needn't be reconfigured.
+	secret := target.WebhookSecret
+	if url == "" {
+		secret = ""
+	} else if secret == "" {
+		secret = webhook.NewSecret()
+	}
+	if err := b.db.SetMonitorWebhook(ctx, target.ID, url, secret); err != nil {
+		log.Printf("[bot] set webhook error: %v", err)
+		return c.Send(msgErrorRetry)
+	}
+	if url != target.WebhookURL {
+		b.recordChange(ctx, target.ID, "webhook_url", target.WebhookURL, url)
+	}
+	if secret != target.WebhookSecret {
+		b.recordChange(ctx, target.ID, "webhook_secret", "", "")
+	}
+
+	if url == "" {
+		return c.Send(msgEditWebhookRemoved, tele.ModeHTML, mainMenu)
+	}
+	return c.Send(fmt.Sprintf(msgEditWebhookDone, html.EscapeString(url), secret), tele.ModeHTML, mainMenu)
+}
+
+func (b *Bot) onEditMaintenance(c tele.Context, conv *conversationData) error {
+	text := strings.TrimSpace(c.Text())
+	var windows []models.MaintenanceWindow
+	if text != "-" {
+		var err error
+		if windows, err = maintenance.Parse(text); err != nil {
+			return c.Send(fmt.Sprintf(msgEditMaintenanceInvalid, html.EscapeString(err.Error())), htmlOpts)
+		}
+	}
+
+	ctx := context.Background()
+
+	// Verify the monitor still belongs to this user.
+	monitors, err := b.db.GetMonitorsByTelegramID(ctx, c.Sender().ID)
+	if err != nil {
+		log.Printf("[bot] get monitors error: %v", err)
+		return c.Send(msgError)
+	}
+	var target *models.Monitor
+	for _, m := range monitors {
+		if m.ID == conv.EditMonitorID {
+			target = m
+			break
+		}
+	}
 
 	b.mu.Lock()
 	delete(b.conversations, c.Sender().ID)
 	b.mu.Unlock()
 
-	return c.Send(fmt.Sprintf(msgEditAddressDone, html.EscapeString(text)), tele.ModeHTML, mainMenu)
+	if target == nil {
+		return c.Send(msgMonitorNotFound)
+	}
+
+	old, err := b.db.GetMonitorMaintenanceWindows(ctx, target.ID)
+	if err != nil {
+		log.Printf("[bot] get maintenance windows error: %v", err)
+		return c.Send(msgErrorRetry)
+	}
+	if err := b.db.SetMonitorMaintenanceWindows(ctx, target.ID, windows); err != nil {
+		log.Printf("[bot] set maintenance windows error: %v", err)
+		return c.Send(msgErrorRetry)
+	}
+	// The change record also tells the worker to reload the windows.
+	b.recordChange(ctx, target.ID, database.ChangeFieldMaintenance, maintenance.Format(old), maintenance.Format(windows))
+
+	if len(windows) == 0 {
+		return c.Send(msgEditMaintenanceRemoved, tele.ModeHTML, mainMenu)
+	}
+	return c.Send(fmt.Sprintf(msgEditMaintenanceDone, maintenance.Format(windows)), tele.ModeHTML, mainMenu)
 }
 
 // parseCoord parses a trimmed string as a float64 coordinate.
 func parseCoord(s string) (float64, error) {
 	return strconv.ParseFloat(strings.TrimSpace(s), 64)
 }
+
+// contentProblem returns the reply explaining why a name or address is not
+// allowed, or "" if the content filter accepts it.
+func (b *Bot) contentProblem(field contentfilter.Field, text string) string {
+	switch err := b.filter.Check(context.Background(), field, text); {
+	case err == nil:
+		return ""
+	case errors.Is(err, contentfilter.ErrTooLong):
+		limit := contentfilter.MaxNameRunes
+		if field == contentfilter.Address {
+			limit = contentfilter.MaxAddressRunes
+		}
+		return fmt.Sprintf(msgContentTooLong, limit)
+	case errors.Is(err, contentfilter.ErrTooManyEmoji):
+		return fmt.Sprintf(msgContentTooManyEmoji, contentfilter.MaxEmoji)
+	default:
+		return msgContentBanned
+	}
+}
+
+// recordChange stores an audit entry for a settings field changed via the bot.
+// Re-saving the same value (e.g. toggling to the state already stored) leaves
+// nothing to audit, as on the web page.
+func (b *Bot) recordChange(ctx context.Context, monitorID int64, field string, oldVal, newVal any) {
+	oldText, newText := fmt.Sprint(oldVal), fmt.Sprint(newVal)
+	if oldText == newText {
+		return
+	}
+	if err := b.db.RecordMonitorChange(ctx, monitorID, database.ChangeSourceBot, field, oldText, newText); err != nil {
+		log.Printf("[bot] record %s change for monitor %d: %v", field, monitorID, err)
+	}
+}
@@ -0,0 +1,231 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"no-lights-monitor/internal/metrics"
+)
+
+// Per-chat pacing and FloodWait handling. Telegram answers 429 with
+// parameters.retry_after when one chat gets messages too fast (channels: about
+// 20 a minute) or the bot as a whole exceeds about 30 a second. Every Telegram
+// call of the bot service goes through one HTTP client, so the handling lives
+// in its transport: each call takes a slot of the shared send gate, calls to
+// a chat are sent one at a time in arrival order and chatInterval apart, and
+// after a 429 the chat's queue sleeps for retry_after and retries, while calls
+// to every other chat go on as usual.
+
+const (
+	// floodMaxWait is the longest retry_after waited out; longer bans are
+	// returned to the caller as the error they are.
+	floodMaxWait = time.Minute
+	// floodMaxRetries bounds the retries of one call.
+	floodMaxRetries = 3
+	// floodAttemptTimeout bounds one HTTP attempt; the client itself has no
+	// timeout, since waiting in a chat's queue is expected.
+	floodAttemptTimeout = time.Minute
+	// floodPruneEvery is how often idle chat queues are forgotten.
+	floodPruneEvery = 10 * time.Minute
+)
+
+// FloodClient returns a client for the bot that paces its calls, queues them
+// per chat and waits out FloodWait answers until ctx is cancelled. next
+// supplies the underlying transport (nil for the default one, e.g.
+// SandboxClient in a sandbox).
+func FloodClient(ctx context.Context, next *http.Client) *http.Client {
+	rt := http.DefaultTransport
+	if next != nil && next.Transport != nil {
+		rt = next.Transport
+	}
+	return &http.Client{Transport: &floodTransport{
+		next:  rt,
+		gate:  newSendGate(ctx, sendRate),
+		chats: make(map[string]*chatQueue),
+	}}
+}
+
+type floodTransport struct {
+	next http.RoundTripper
+	gate *sendGate
+
+	mu        sync.Mutex
+	chats     map[string]*chatQueue
+	lastPrune time.Time
+}
+
+// chatQueue serializes the calls to one chat. Goroutines blocked sending on a
+// channel are woken in FIFO order, so lock also keeps the arrival order.
+type chatQueue struct {
+	lock  chan struct{}
+	until time.Time // no call before this (pacing or retry_after); guarded by floodTransport.mu
+	refs  int       // calls holding or waiting for lock; guarded by floodTransport.mu
+}
+
+func (t *floodTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	method := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+	if strings.HasPrefix(method, "get") || req.Body == nil {
+		return t.next.RoundTrip(req)
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	bulk := bulkMethods[method]
+	fields, err := sandboxFields(req.Header.Get("Content-Type"), body)
+	chatID := fields["chat_id"]
+	if err != nil || chatID == "" {
+		if err := t.gate.wait(req.Context(), bulk); err != nil {
+			return nil, err
+		}
+		return t.attempt(req, body)
+	}
+
+	q := t.acquire(chatID)
+	defer t.release(chatID, q)
+	select {
+	case q.lock <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	defer func() { <-q.lock }()
+
+	for retry := 0; ; retry++ {
+		if err := sleepUntil(req.Context(), t.until(q)); err != nil {
+			return nil, err
+		}
+		if err := t.gate.wait(req.Context(), bulk); err != nil {
+			return nil, err
+		}
+		resp, err := t.attempt(req, body)
+		t.hold(q, chatInterval(chatID))
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+			return resp, err
+		}
+		wait, data := retryAfter(resp)
+		if wait <= 0 || wait > floodMaxWait || retry >= floodMaxRetries {
+			metrics.BotFloodWaits.WithLabelValues("gave_up").Inc()
+			log.Printf("[flood] %s to chat %s: retry after %s, giving up", method, chatID, wait)
+			resp.Body = io.NopCloser(bytes.NewReader(data))
+			resp.ContentLength = int64(len(data))
+			return resp, nil
+		}
+		metrics.BotFloodWaits.WithLabelValues("retried").Inc()
+		log.Printf("[flood] %s to chat %s: retry after %s, holding the chat's queue", method, chatID, wait)
+		t.hold(q, wait)
+	}
+}
+
+// until returns the time q may send again.
+func (t *floodTransport) until(q *chatQueue) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return q.until
+}
+
+// hold keeps q from sending for wait.
+func (t *floodTransport) hold(q *chatQueue, wait time.Duration) {
+	t.mu.Lock()
+	q.until = time.Now().Add(wait)
+	t.mu.Unlock()
+}
+
+// attempt sends one copy of req with body within floodAttemptTimeout. The
+// timeout ends when the caller closes the response body.
+func (t *floodTransport) attempt(req *http.Request, body []byte) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), floodAttemptTimeout)
+	out := req.Clone(ctx)
+	out.Body = io.NopCloser(bytes.NewReader(body))
+	out.ContentLength = int64(len(body))
+	out.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+	resp, err := t.next.RoundTrip(out)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// acquire returns the queue of chatID, counting the caller in.
+func (t *floodTransport) acquire(chatID string) *chatQueue {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if now := time.Now(); now.Sub(t.lastPrune) >= floodPruneEvery {
+		for id, q := range t.chats {
+			if q.refs == 0 && !q.until.After(now) {
+				delete(t.chats, id)
+			}
+		}
+		t.lastPrune = now
+	}
+	q, ok := t.chats[chatID]
+	if !ok {
+		q = &chatQueue{lock: make(chan struct{}, 1)}
+		t.chats[chatID] = q
+	}
+	q.refs++
+	return q
+}
+
+// release counts the caller out and forgets the queue once it is idle.
+func (t *floodTransport) release(chatID string, q *chatQueue) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	q.refs--
+	if q.refs == 0 && !q.until.After(time.Now()) {
+		delete(t.chats, chatID)
+	}
+}
+
+// retryAfter reads the retry_after of a 429 answer. It returns the body too,
+// since the answer is handed to the caller when it isn't retried.
+func retryAfter(resp *http.Response) (time.Duration, []byte) {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	var answer struct {
+		Parameters struct {
+			RetryAfter int `json:"retry_after"`
+		} `json:"parameters"`
+	}
+	if json.Unmarshal(data, &answer) != nil {
+		return 0, data
+	}
+	return time.Duration(answer.Parameters.RetryAfter) * time.Second, data
+}
+
+// sleepUntil waits until t or until ctx is done.
+func sleepUntil(ctx context.Context, t time.Time) error {
+	d := time.Until(t)
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// cancelBody releases an attempt's context when the response is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
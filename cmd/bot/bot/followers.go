@@ -0,0 +1,275 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"no-lights-monitor/internal/models"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+// Followers: other Telegram users (neighbours sharing the building's sensor)
+// can ask to get a monitor's status changes by DM through the monitor's
+// secret invite link. The owner approves or declines each request and can
+// revoke approved followers later. Nothing about the monitor is shown to a
+// requester before approval, and a declined user can't ask again until
+// followDeclineCooldown is over.
+
+// MaxFollowersPerMonitor caps followers plus pending requests of one monitor.
+const MaxFollowersPerMonitor = 50
+
+// followStartPrefix is the /start payload of follow deep links: t.me/<bot>?start=follow_<token>.
+const followStartPrefix = "follow_"
+
+// followDeclineCooldown is how long a declined or revoked follower can't ask again.
+const followDeclineCooldown = 7 * 24 * time.Hour
+
+// ── Follower side ────────────────────────────────────────────────────
+
+// handleFollow handles /follow <invite token>.
+func (b *Bot) handleFollow(c tele.Context) error {
+	token := strings.TrimSpace(c.Message().Payload)
+	if token == "" {
+		return c.Send(msgFollowUsage, htmlOpts)
+	}
+	return b.requestFollow(c, token)
+}
+
+// handleUnfollow handles /unfollow <monitor id>.
+func (b *Bot) handleUnfollow(c tele.Context) error {
+	id, err := strconv.ParseInt(strings.TrimSpace(c.Message().Payload), 10, 64)
+	if err != nil || id <= 0 {
+		return c.Send(msgUnfollowUsage, htmlOpts)
+	}
+	removed, err := b.db.RemoveFollower(context.Background(), id, c.Sender().ID)
+	if err != nil {
+		log.Printf("[bot] remove follower %d of monitor %d: %v", c.Sender().ID, id, err)
+		return c.Send(msgError)
+	}
+	if !removed {
+		return c.Send(msgUnfollowNotFollowing)
+	}
+	return c.Send(msgUnfollowDone)
+}
+
+// requestFollow records a follow request for the monitor of an invite token
+// and asks the owner to approve it. The monitor's name is only told once the
+// request is approved.
+func (b *Bot) requestFollow(c tele.Context, token string) error {
+	ctx := context.Background()
+	sender := c.Sender()
+
+	monitorID, err := b.db.GetMonitorIDByFollowToken(ctx, token)
+	if err != nil {
+		return c.Send(msgFollowNotFound)
+	}
+	m, err := b.db.GetMonitorByID(ctx, monitorID)
+	if err != nil || m.IsCanary {
+		return c.Send(msgFollowNotFound)
+	}
+	ownerID, err := b.db.GetOwnerTelegramIDByMonitorID(ctx, m.ID)
+	if err != nil {
+		log.Printf("[bot] follow: get owner of monitor %d: %v", m.ID, err)
+		return c.Send(msgError)
+	}
+	if ownerID == sender.ID {
+		return c.Send(msgFollowOwnMonitor)
+	}
+
+	existing, err := b.db.GetFollower(ctx, m.ID, sender.ID)
+	if err != nil {
+		log.Printf("[bot] follow: get follower: %v", err)
+		return c.Send(msgError)
+	}
+	if existing == nil || existing.DeclinedAt != nil {
+		n, err := b.db.CountFollowers(ctx, m.ID)
+		if err != nil {
+			log.Printf("[bot] follow: count followers of monitor %d: %v", m.ID, err)
+			return c.Send(msgError)
+		}
+		if n >= MaxFollowersPerMonitor {
+			return c.Send(msgFollowLimit)
+		}
+	}
+
+	f, created, err := b.db.AddFollowRequest(ctx, m.ID, sender.ID, sender.Username, sender.FirstName, followDeclineCooldown)
+	if err != nil {
+		log.Printf("[bot] follow: add request: %v", err)
+		return c.Send(msgError)
+	}
+	switch {
+	case f.Approved:
+		return c.Send(fmt.Sprintf(msgFollowAlready, html.EscapeString(m.Name), m.ID), htmlOpts)
+	case !created:
+		// Pending, or declined within the cooldown: the same answer for
+		// both, and the owner isn't asked again.
+		return c.Send(msgFollowPending, htmlOpts)
+	}
+
+	log.Printf("[bot] follow request: user %d (@%s) → monitor %d", sender.ID, sender.Username, m.ID)
+	text := fmt.Sprintf(msgFollowOwnerRequest, html.EscapeString(followerLabel(f)), html.EscapeString(m.Name))
+	markup := &tele.ReplyMarkup{InlineKeyboard: [][]tele.InlineButton{{
+		{Text: msgFollowBtnApprove, Data: fmt.Sprintf("follow_ok:%d:%d", m.ID, sender.ID)},
+		{Text: msgFollowBtnDecline, Data: fmt.Sprintf("follow_no:%d:%d", m.ID, sender.ID)},
+	}}}
+	if _, err := b.bot.Send(&tele.Chat{ID: ownerID}, text, &tele.SendOptions{ParseMode: tele.ModeHTML, ReplyMarkup: markup}); err != nil {
+		log.Printf("[bot] follow: DM owner %d: %v", ownerID, err)
+	}
+	return c.Send(msgFollowRequested, htmlOpts)
+}
+
+// ── Owner side (callbacks) ───────────────────────────────────────────
+
+// followerTarget parses the follower's Telegram ID from "action:<monitor>:<telegram id>".
+func followerTarget(parts []string) (int64, bool) {
+	if len(parts) < 3 {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(parts[2], 10, 64)
+	return id, err == nil
+}
+
+func (b *Bot) onCallbackFollowApprove(ctx context.Context, c tele.Context, parts []string, m *models.Monitor) error {
+	followerID, ok := followerTarget(parts)
+	if !ok {
+		return c.Respond(&tele.CallbackResponse{Text: msgInvalidFormat})
+	}
+	approved, err := b.db.ApproveFollower(ctx, m.ID, followerID)
+	if err != nil {
+		log.Printf("[bot] approve follower %d of monitor %d: %v", followerID, m.ID, err)
+		return c.Respond(&tele.CallbackResponse{Text: msgFollowActionError})
+	}
+	_ = c.Respond(&tele.CallbackResponse{})
+	if !approved {
+		return c.Edit(msgFollowRequestGone, &tele.ReplyMarkup{})
+	}
+	log.Printf("[bot] monitor %d: follower %d approved", m.ID, followerID)
+	SendToUser(b.bot, followerID, fmt.Sprintf(msgFollowApprovedDM, html.EscapeString(m.Name), m.ID))
+	return c.Edit(fmt.Sprintf(msgFollowApprovedOwner, html.EscapeString(m.Name)), tele.ModeHTML, &tele.ReplyMarkup{})
+}
+
+func (b *Bot) onCallbackFollowDecline(ctx context.Context, c tele.Context, parts []string, m *models.Monitor) error {
+	followerID, ok := followerTarget(parts)
+	if !ok {
+		return c.Respond(&tele.CallbackResponse{Text: msgInvalidFormat})
+	}
+	declined, err := b.db.DeclineFollower(ctx, m.ID, followerID)
+	if err != nil {
+		log.Printf("[bot] decline follower %d of monitor %d: %v", followerID, m.ID, err)
+		return c.Respond(&tele.CallbackResponse{Text: msgFollowActionError})
+	}
+	_ = c.Respond(&tele.CallbackResponse{})
+	if !declined {
+		return c.Edit(msgFollowRequestGone, &tele.ReplyMarkup{})
+	}
+	SendToUser(b.bot, followerID, msgFollowDeclinedDM)
+	return c.Edit(fmt.Sprintf(msgFollowDeclinedOwner, html.EscapeString(m.Name)), tele.ModeHTML, &tele.ReplyMarkup{})
+}
+
+// onCallbackFollowRevoke removes a follower from the followers menu.
+func (b *Bot) onCallbackFollowRevoke(ctx context.Context, c tele.Context, parts []string, m *models.Monitor) error {
+	followerID, ok := followerTarget(parts)
+	if !ok {
+		return c.Respond(&tele.CallbackResponse{Text: msgInvalidFormat})
+	}
+	removed, err := b.db.DeclineFollower(ctx, m.ID, followerID)
+	if err != nil {
+		log.Printf("[bot] revoke follower %d of monitor %d: %v", followerID, m.ID, err)
+		return c.Respond(&tele.CallbackResponse{Text: msgFollowActionError})
+	}
+	_ = c.Respond(&tele.CallbackResponse{})
+	if removed {
+		log.Printf("[bot] monitor %d: follower %d revoked", m.ID, followerID)
+		SendToUser(b.bot, followerID, fmt.Sprintf(msgFollowRevokedDM, html.EscapeString(m.Name)))
+	}
+	return b.renderFollowers(ctx, c, m)
+}
+
+func (b *Bot) onCallbackFollowers(ctx context.Context, c tele.Context, m *models.Monitor) error {
+	_ = c.Respond(&tele.CallbackResponse{})
+	return b.renderFollowers(ctx, c, m)
+}
+
+// renderFollowers shows the followers menu: the invite link, approved
+// followers with revoke buttons and pending requests with approve/decline.
+func (b *Bot) renderFollowers(ctx context.Context, c tele.Context, m *models.Monitor) error {
+	followers, err := b.db.GetFollowers(ctx, m.ID)
+	if err != nil {
+		log.Printf("[bot] get followers of monitor %d: %v", m.ID, err)
+		return c.Edit(msgError, &tele.ReplyMarkup{})
+	}
+	token, err := b.db.GetFollowToken(ctx, m.ID)
+	if err != nil {
+		log.Printf("[bot] get follow token of monitor %d: %v", m.ID, err)
+		return c.Edit(msgError, &tele.ReplyMarkup{})
+	}
+
+	var bld strings.Builder
+	bld.WriteString(fmt.Sprintf(msgFollowersHeader, html.EscapeString(m.Name), b.bot.Me.Username, followStartPrefix, token))
+	if len(followers) == 0 {
+		bld.WriteString(msgFollowersEmpty)
+	}
+	var rows [][]tele.InlineButton
+	for _, f := range followers {
+		label := followerLabel(f)
+		if f.Approved {
+			rows = append(rows, []tele.InlineButton{
+				{Text: fmt.Sprintf(msgFollowBtnRevoke, label), Data: fmt.Sprintf("follow_rm:%d:%d", m.ID, f.TelegramID)},
+			})
+			continue
+		}
+		rows = append(rows, []tele.InlineButton{
+			{Text: fmt.Sprintf(msgFollowBtnApprovePending, label), Data: fmt.Sprintf("follow_ok:%d:%d", m.ID, f.TelegramID)},
+			{Text: msgFollowBtnDecline, Data: fmt.Sprintf("follow_no:%d:%d", m.ID, f.TelegramID)},
+		})
+	}
+	rows = append(rows, []tele.InlineButton{{Text: msgFollowBtnBack, Data: fmt.Sprintf("edit:%d", m.ID)}})
+	return c.Edit(bld.String(), tele.ModeHTML, &tele.ReplyMarkup{InlineKeyboard: rows})
+}
+
+// followerLabel is how a follower is shown to the owner.
+func followerLabel(f *models.Follower) string {
+	if f.Username != "" {
+		return "@" + f.Username
+	}
+	if f.FirstName != "" {
+		return f.FirstName
+	}
+	return strconv.FormatInt(f.TelegramID, 10)
+}
+
+// ── Delivery ─────────────────────────────────────────────────────────
+
+// notifyFollowers DMs a status message to the approved followers of a monitor.
+// Followers who blocked the bot are dropped.
+func (n *TelegramNotifier) notifyFollowers(ctx context.Context, monitorID int64, name, msg string, silent bool) {
+	ids, err := n.db.GetApprovedFollowerIDs(ctx, monitorID)
+	if err != nil {
+		log.Printf("[bot] get followers of monitor %d: %v", monitorID, err)
+		return
+	}
+	if len(ids) == 0 {
+		return
+	}
+	text := fmt.Sprintf(msgFollowerStatusPrefix, html.EscapeString(name)) + msg
+	opts := &tele.SendOptions{ParseMode: tele.ModeHTML, DisableNotification: silent}
+	for _, id := range ids {
+		_, err := n.bot.Send(&tele.Chat{ID: id}, text, opts)
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, tele.ErrBlockedByUser) || errors.Is(err, tele.ErrUserIsDeactivated) || errors.Is(err, tele.ErrChatNotFound) {
+			log.Printf("[bot] follower %d of monitor %d unreachable, removing: %v", id, monitorID, err)
+			_, _ = n.db.RemoveFollower(ctx, monitorID, id)
+			continue
+		}
+		log.Printf("[bot] DM follower %d of monitor %d: %v", id, monitorID, err)
+	}
+}
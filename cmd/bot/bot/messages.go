@@ -11,6 +11,7 @@ const msgStart = `<b>Вітаю в No-Lights Monitor!</b>
 /create - Налаштувати новий монітор
 /info - Детальна інформація та URL для пінгу
 /edit - Змінити налаштування монітора
+/relink - Перенести монітор в інший канал
 /test - Відправити тестове повідомлення
 /stop - Призупинити моніторинг
 /resume - Відновити моніторинг
@@ -33,17 +34,29 @@ const msgHelp = `<b>Як це працює:</b>
 <b>Команди:</b>
 /info — детальна інформація та URL для пінгу
 /edit — змінити налаштування монітора
+/relink — перенести монітор в інший канал
 /test — відправити тестове повідомлення в канал
 /stop — призупинити моніторинг (не буде сповіщень)
 /resume — відновити призупинений монітор
+/mute 2h — тимчасово вимкнути сповіщення в каналі (моніторинг триває)
 /delete — видалити монітор назавжди
+/follow КОД — отримувати сповіщення чужого монітора в особисті (код із посилання власника, потрібен його дозвіл)
+/unfollow ID — відписатися від монітора
+/link — отримати доступ до моніторів з іншого акаунта Telegram
+/unlink — відв'язати інший акаунт
+/apikey — ключ для керування моніторами через REST API
 /cancel — скасувати поточну операцію
 
+<b>Коментарі в каналі:</b>
+Якщо в каналу є група для обговорень, додайте мене туди — читачі зможуть написати /status у коментарях і дізнатися, чи є світло та коли наступне планове відключення.
+
 🌐 %s
 💬 Питання, ідеї? @%s`
 
 // ── Generic / errors ────────────────────────────────────────────────
 
+const msgSlowDown = "⏳ Забагато запитів. Зачекайте кілька секунд і спробуйте знову."
+
 const (
 	msgError           = "Щось пішло не так. Спробуйте пізніше."
 	msgErrorRetry      = "Щось пішло не так. Спробуйте ще раз."
@@ -53,6 +66,7 @@ const (
 	msgMonitorNotFound = "Монітор не знайдено"
 	msgFetchError      = "Помилка отримання даних"
 	msgUnknownAction   = "Невідома дія"
+	msgBanned          = "Вибачте, ваш обліковий запис заблоковано адміністратором, тому бот не може виконати цю дію."
 )
 
 // ── /status ─────────────────────────────────────────────────────────
@@ -90,7 +104,13 @@ const (
 
 // ── /info ───────────────────────────────────────────────────────────
 
-const msgInfoHeader = "<b>Детальна інформація про монітори</b>\n\n"
+const (
+	msgInfoHeader     = "<b>Детальна інформація про монітори</b>\n"
+	msgInfoGroup      = "\n<b>%s</b> (%d)\n"
+	msgInfoUpdatedAt  = "\n<i>Оновлено о %s</i>"
+	msgInfoBtnRefresh = "🔄 Оновити"
+	msgInfoRefreshed  = "Оновлено"
+)
 
 // ── Callbacks ───────────────────────────────────────────────────────
 
@@ -101,7 +121,7 @@ const (
 	msgResumeOK          = "✅ Моніторинг відновлено"
 	msgResumeError       = "Помилка відновлення моніторингу"
 	msgResumeNoAccess       = "❌ Бот не має доступу до каналу"
-	msgResumeNoAccessDetail = "❌ <b>Не вдалося відновити моніторинг</b>\n\nБот не є адміністратором каналу <b>@%s</b> або не має права публікувати повідомлення.\n\nДодайте бота як адміністратора з правом \"Публікація повідомлень\" і спробуйте ще раз."
+	msgResumeNoAccessDetail = "❌ <b>Не вдалося відновити моніторинг</b>\n\nБот не є адміністратором каналу <b>%s</b> або не має права публікувати повідомлення.\n\nДодайте бота як адміністратора з правом \"Публікація повідомлень\" і спробуйте ще раз."
 
 	msgDeleteOK    = "✅ Монітор видалено"
 	msgDeleteError = "Помилка видалення монітора"
@@ -120,12 +140,19 @@ const msgCreateStep1 = `Налаштуємо новий монітор!
 
 const msgCreateBtnHeartbeat = "📡 ESP або смартфон"
 const msgCreateBtnPing = "🌐 Пінг айпі роутера"
+const msgCreateBtnHTTP = "🔗 Сайт або сервер (HTTP)"
 
 const msgPingTargetStep = `<b>Крок 2/4:</b> Введіть IP-адресу або hostname для пінгу.
 Наприклад: <code>93.75.123.45</code> або <code>myrouter.ddns.net</code>
 
 ⚠️ Потрібна біла (публічна) IP-адреса. Сірі IP (за NAT провайдера) не працюватимуть.`
 
+const msgHTTPTargetStep = `<b>Крок 2/4:</b> Введіть URL сторінки, яку віддає пристрій у вашій локації.
+Наприклад: <code>https://myhome.ddns.net/status</code>
+
+Світло вважається наявним, поки сторінка відповідає кодом 2xx протягом 10 секунд.
+⚠️ Адреса має бути доступна з інтернету.`
+
 const msgAddressStepHeartbeat = `<b>Крок 2/3:</b> Введіть адресу вашої локації.
 Наприклад: <code>Київ, Хрещатик 1</code>
 
@@ -177,8 +204,10 @@ const (
 
 const (
 	msgInfoTypePing      = "Server Ping"
+	msgInfoTypeHTTP      = "HTTP(S)"
 	msgInfoTypeHeartbeat = "ESP Heartbeat"
 	msgInfoPingHint      = "<i>Сервер автоматично пінгує цю адресу кожні 5 хвилин.</i>"
+	msgInfoHTTPHint      = "<i>Сервер щохвилини відкриває цю сторінку; відповідь 2xx означає, що світло є.</i>"
 	msgInfoHeartbeatHint = "<i>Налаштуйте ваш пристрій відправляти GET-запити на цей URL кожні 5 хвилин.</i> \n💬 Інструкції з налаштування та допомога: @%s"
 )
 
@@ -203,13 +232,32 @@ const (
 	msgEditAddressDone  = "✅ Адресу оновлено: <b>%s</b>"
 )
 
+const (
+	msgEditWebhookPrompt  = "🔗 <b>Вебхук</b>\n\nПри кожній зміні статусу бот надсилатиме POST із JSON (monitor_id, name, status, duration_sec, timestamp) на вказаний URL. Запит підписано заголовком <code>X-NLM-Signature</code>.\n\nПоточний URL: %s\n\nНадішліть новий URL або «-», щоб вимкнути вебхук."
+	msgEditWebhookNone    = "<i>не задано</i>"
+	msgEditWebhookInvalid = "Невірний URL: %s. Надішліть публічну адресу http(s):// або «-»."
+	msgEditWebhookDone    = "✅ Вебхук збережено: <code>%s</code>\n\n🔑 Секрет для перевірки підпису: <code>%s</code>"
+	msgEditWebhookRemoved = "✅ Вебхук вимкнено."
+)
+
+const (
+	msgEditMaintenancePrompt  = "🛠 <b>Вікна обслуговування</b>\n\nЩодня в ці години (за Києвом) зміни статусу записуються в історію, але сповіщення не надсилаються — зручно для нічного перезавантаження роутера чи планових робіт.\n\nПоточні вікна: %s\n\nНадішліть вікна у форматі <code>02:00-03:00</code> (до 3 через кому) або «-», щоб прибрати всі."
+	msgEditMaintenanceNone    = "<i>немає</i>"
+	msgEditMaintenanceInvalid = "Не вдалося розібрати вікна (%s). Приклад: <code>02:00-03:00, 13:30-13:45</code>, або «-»."
+	msgEditMaintenanceDone    = "✅ Вікна обслуговування: <b>%s</b>"
+	msgEditMaintenanceRemoved = "✅ Вікна обслуговування прибрано."
+)
+
 // ── /info list row ───────────────────────────────────────────────────
 
-const msgInfoRow = "<b>%d.</b> %s - %s\n"
+const (
+	msgInfoRow      = "<b>%d.</b> %s\n"
+	msgInfoRowSince = "<b>%d.</b> %s — %s\n"
+)
 
 // ── /test list row ───────────────────────────────────────────────────
 
-const msgTestRow = "%d. %s (@%s)\n"
+const msgTestRow = "%d. %s (%s)\n"
 
 // ── Callbacks: stop / resume / delete ────────────────────────────────
 
@@ -228,12 +276,13 @@ const (
 	msgInfoDetailCoords   = "🌐 <b>Координати:</b> %.6f, %.6f\n\n"
 	msgInfoDetailStatus   = "<b>Статус:</b> %s\n"
 	msgInfoDetailLastPing = "<b>Останній пінг:</b> %s\n"
-	msgInfoDetailChannel  = "<b>Канал:</b> @%s\n\n"
+	msgInfoDetailChannel  = "<b>Канал:</b> %s\n\n"
 	msgInfoDetailTypePing = "<b>🌐 Тип:</b> %s\n"
 	msgInfoDetailTarget   = "<b>🎯 Ціль:</b> <code>%s</code>\n\n"
 	msgInfoDetailTypeHB   = "<b>📡 Тип:</b> %s\n"
 	msgInfoDetailURLLabel  = "<b>🔗 URL для пінгу:</b>\n"
 	msgInfoDetailURL       = "<code>%s/api/ping/%s</code>\n\n"
+	msgInfoDetailPingIP    = "<b>🌐 Пінг за IP</b> <code>%s</code>: <code>%s/api/ping-ip</code>\n<i>⚠️ Будь-хто з цієї IP-адреси може позначити світло як наявне.</i>\n\n"
 	msgInfoDetailSettings  = "⚙️ <b>Налаштування на вебсайті:</b>\n%s/settings/%s\n🔑 <b>Пароль:</b> <code>%s</code>\n\n"
 )
 
@@ -261,9 +310,48 @@ const (
 	msgEditBtnHideAddress     = "📍 Приховати адресу в сповіщеннях"
 	msgEditBtnShowGraph       = "📊 Публікувати графік аптайму в каналі"
 	msgEditBtnHideGraph       = "📊 Не публікувати графік аптайму"
+	msgEditBtnShowDailyGraph  = "📅 Додати графік за день"
+	msgEditBtnHideDailyGraph  = "📅 Прибрати графік за день"
+	msgEditBtnShowMonthGraph  = "🗓 Додати графік за місяць"
+	msgEditBtnHideMonthGraph  = "🗓 Прибрати графік за місяць"
+	msgEditBtnShowStats       = "📈 Щотижнева статистика каналу"
+	msgEditBtnHideStats       = "📈 Вимкнути статистику каналу"
 	msgMapBtnHide             = "🗺 Прибрати з карти"
 	msgMapBtnShow             = "🗺 Додати на карту"
+	msgMapBtnFuzzLocation     = "🎯 Округлювати координати на карті"
+	msgMapBtnExactLocation    = "🎯 Точні координати на карті"
+	msgMapBtnHideName         = "🏷 Приховати назву й адресу на карті"
+	msgMapBtnShowName         = "🏷 Показувати назву й адресу на карті"
+	msgMapBtnHideChannel      = "📢 Приховати канал на карті"
+	msgMapBtnShowChannel      = "📢 Показувати канал на карті"
 	msgEditBtnThreshold       = "⏱ Поріг офлайн: %s"
+	msgEditBtnFollowers       = "👥 Підписники"
+	msgEditBtnWebhook         = "🔗 Вебхук"
+	msgEditBtnMaintenance     = "🛠 Вікна обслуговування"
+	msgEditBtnReplaceDevice   = "🔁 Замінити пристрій"
+)
+
+// ── Discussion group ──────────────────────────────────────────────────
+
+const (
+	msgStatusPrivate       = "Команда /status працює в коментарях каналу, до якого підключено монітор. Свої монітори дивіться через /info."
+	msgStatusNoChannel     = "Ця група не прив'язана до каналу з монітором світла."
+	msgStatusLine          = "%s <b>%s</b> — %s\n"
+	msgStatusOnline        = "світло є вже %s"
+	msgStatusOffline       = "світла немає вже %s"
+	msgStatusNextPlanned   = "⏱ <i>Наступне планове: %s – %s</i>\n"
+	msgStatusNoMorePlanned = "⏱ <i>Більше планових відключень сьогодні немає</i>\n"
+)
+
+// ── Device replacement ────────────────────────────────────────────────
+
+const (
+	msgReplaceDeviceConfirm = "<b>🔁 Заміна пристрою для «%s»</b>\n\nБуде видано новий URL для пінгу. Старий URL одразу перестане працювати, тож налаштуйте новий пристрій після підтвердження.\n\nІсторія, графіки, канал і всі налаштування монітора збережуться."
+	msgReplaceDeviceBtnOK   = "✅ Видати новий URL"
+	msgReplaceDeviceBtnBack = "↩️ Назад"
+	msgReplaceDeviceDone    = "✅ <b>Новий URL для «%s»</b>\n\n" + msgInfoDetailURLLabel + msgInfoDetailURL + "<i>Вкажіть цей URL у налаштуваннях нового пристрою. Поки він не почне пінгувати, монітор показуватиме, що світла немає.</i>"
+	msgReplaceDeviceError   = "Помилка видачі нового URL."
+	msgReplaceDevicePing    = "Пінг-монітори не прив'язані до пристрою — заміна не потрібна."
 )
 
 const (
@@ -280,6 +368,7 @@ const (
 	msgGraphEnabled          = "✅ Графік аптайму буде публікуватися в каналі."
 	msgGraphDisabled         = "✅ Графік аптайму не буде публікуватися."
 	msgGraphToggleError      = "Помилка зміни налаштування."
+	msgStatsToggleError      = "Помилка зміни налаштування."
 )
 
 // ── Outage group ──────────────────────────────────────────────────────
@@ -295,8 +384,6 @@ const (
 	msgNotifyOutageEnabled  = "✅ Графік відключень буде показано в сповіщеннях."
 	msgNotifyOutageDisabled = "✅ Графік відключень приховано зі сповіщень."
 	msgNotifyOutageError    = "Помилка зміни налаштування."
-	msgOutageNextPlanned    = "\n⏱ <i>Наступне планове: %s</i>"
-	msgOutageExpected       = "\n⏱ <i>Очікуємо за ~%s, о %s</i>"
 
 	msgEditBtnShowOutagePhoto    = "🖼 Публікувати фото графіка в каналі"
 	msgEditBtnHideOutagePhoto    = "🖼 Не публікувати фото графіка"
@@ -305,9 +392,18 @@ const (
 	msgOutagePhotoError          = "Помилка зміни налаштування."
 )
 
+// Offered after an address change when the outage group no longer fits.
+const (
+	msgRegionHint         = "📍 <b>Перевірте групу відключень</b>\n\nЗа новою адресою група, схоже, інша.\n\nЗараз: %s\nЙмовірно: %s"
+	msgRegionHintGroup    = "<b>%s</b> (%s)"
+	msgRegionHintNoGroup  = "не встановлена"
+	msgRegionHintBtnApply = "✅ Встановити групу %s"
+	msgRegionHintBtnPick  = "⚡ Обрати групу"
+)
+
 const (
-	msgEditChannelRefreshDone     = "✅ Тег каналу оновлено: @%s"
-	msgEditChannelRefreshNoChange = "✅ Тег каналу вже актуальний: @%s"
+	msgEditChannelRefreshDone     = "✅ Тег каналу оновлено: %s"
+	msgEditChannelRefreshNoChange = "✅ Тег каналу вже актуальний: %s"
 	msgEditChannelRefreshError    = "Не вдалося отримати дані каналу. Спробуйте пізніше."
 )
 
@@ -315,7 +411,7 @@ const (
 
 const (
 	msgTestNotification = "🧪 <b>Тестове повідомлення</b>\n\nМонітор: <b>%s</b>\nАдреса: %s\n\nЯкщо ви бачите це повідомлення, то налаштування каналу працює коректно! ✅"
-	msgTestSentTo       = "%s відправлено в канал <b>@%s</b>"
+	msgTestSentTo       = "%s відправлено в канал <b>%s</b>"
 )
 
 // ── Ping target validation ────────────────────────────────────────────
@@ -327,6 +423,24 @@ const (
 	msgPingHostOK          = "✅ Хост доступний: <code>%s</code> → <code>%s</code>"
 )
 
+// ── HTTP target validation ────────────────────────────────────────────
+
+const (
+	msgHTTPTargetInvalid     = "Це не схоже на URL. Введіть адресу на кшталт <code>https://myhome.ddns.net/status</code>."
+	msgHTTPTargetPrivate     = "Ця адреса веде в приватну (локальну) мережу. Потрібна адреса, доступна з інтернету."
+	msgHTTPChecking          = "🔍 Перевіряю <code>%s</code>..."
+	msgHTTPTargetUnreachable = "❌ <code>%s</code> не відповів кодом 2xx за 10 секунд.\nПеревірте адресу і спробуйте ще раз."
+	msgHTTPTargetOK          = "✅ Сторінка відповідає: <code>%s</code>"
+)
+
+// ── Content filter (names and addresses) ─────────────────────────────
+
+const (
+	msgContentTooLong      = "Занадто довго — не більше %d символів. Спробуйте коротше."
+	msgContentTooManyEmoji = "Забагато емодзі — не більше %d. Спробуйте ще раз."
+	msgContentBanned       = "Цей текст містить недопустимі слова. Назва й адреса видні в каналах і на публічній карті — спробуйте інакше."
+)
+
 // ── Address / geocode ─────────────────────────────────────────────────
 
 const msgAddressFound = "Знайдено: <b>%s</b>"
@@ -339,9 +453,17 @@ const (
 
 <b>Крок %s:</b> Створіть Telegram-канал і додайте мене як адміністратора з правом "Публікація повідомлень".
 
-Потім надішліть мені @username каналу (напр., @my_power_channel).`
+Потім надішліть мені @username каналу (напр., @my_power_channel).
+
+<i>Приватний канал?</i> Перешліть мені будь-який пост з нього або опублікуйте в каналі код <code>%s</code>.`
 )
 
+// msgChannelPrivate labels a linked channel that has no public @username.
+const msgChannelPrivate = "🔒 приватний канал"
+
+// msgChannelForwardNotChannel is sent when a forwarded message did not come from a channel.
+const msgChannelForwardNotChannel = "Це повідомлення переслано не з каналу. Перешліть пост саме з каналу, до якого потрібно підключити монітор."
+
 // ── Create success ────────────────────────────────────────────────────
 
 const msgCreateDonePing = `<b>Монітор налаштовано!</b>
@@ -350,18 +472,30 @@ const msgCreateDonePing = `<b>Монітор налаштовано!</b>
 <b>Тип:</b> Server Ping
 <b>Ціль:</b> <code>%s</code>
 <b>Координати:</b> %.5f, %.5f
-<b>Канал:</b> @%s
+<b>Канал:</b> %s
 
 Сервер пінгуватиме <code>%s</code> кожні 5 хвилин.
 
 Коли пінги не проходять — я сповіщу канал, що світла немає. Коли відновляться — що світло повернулося.`
 
+const msgCreateDoneHTTP = `<b>Монітор налаштовано!</b>
+
+<b>Назва:</b> %s
+<b>Тип:</b> HTTP(S)
+<b>URL:</b> <code>%s</code>
+<b>Координати:</b> %.5f, %.5f
+<b>Канал:</b> %s
+
+Сервер щохвилини відкриватиме цю сторінку.
+
+Коли вона перестане відповідати кодом 2xx — я сповіщу канал, що світла немає. Коли відповідь повернеться — що світло з'явилося.`
+
 const msgCreateDoneHeartbeat = `<b>Монітор налаштовано!</b>
 
 <b>Назва:</b> %s
 <b>Тип:</b> ESP Heartbeat
 <b>Координати:</b> %.5f, %.5f
-<b>Канал:</b> @%s
+<b>Канал:</b> %s
 
 <b>Посилання для пінгу:</b>
 <code>%s</code>
@@ -372,20 +506,28 @@ const msgCreateDoneHeartbeat = `<b>Монітор налаштовано!</b>
 
 💬 Інструкції з налаштування та допомога: @%s`
 
-// ── Notifications ───────────────────────────────────────────────────
-
-const (
-	msgNotifyOnline      = "🟢 <b>%s Світло з'явилося</b> \n<i>(не було %s)</i>"
-	msgNotifyOffline     = "🔴 <b>%s Світла немає</b>\n<i>(воно було %s)</i>"
-	msgNotifyAddressLine = "\n📍 <i>%s</i>"
-)
-
 // ── Channel access errors ────────────────────────────────────────────
 
 // msgChannelError is sent to the monitor owner when the bot loses channel access.
 // %s = monitor name
 const msgChannelError = "⚠️ <b>Монітор призупинено</b>\n\nМонітор <b>%s</b> було призупинено, оскільки бот втратив доступ до каналу (канал видалено, бота видалено або відкликано права).\n\nПереконайтеся, що бот є адміністратором каналу з правом \"Публікація повідомлень\", та відновіть моніторинг через /resume."
 
+// msgChannelMigrated is sent to the monitor owner when Telegram reports that the
+// channel's group was migrated to a new chat ID and the monitor followed it.
+// %s = monitor name
+const msgChannelMigrated = "ℹ️ <b>Канал оновлено</b>\n\nTelegram змінив ідентифікатор каналу монітора <b>%s</b> (група перетворена на супергрупу). Я автоматично перейшов на новий канал, моніторинг продовжується."
+
+// ── /relink ─────────────────────────────────────────────────────────
+
+const (
+	msgRelinkHeader      = "<b>Оберіть монітор, який потрібно перенести в інший канал:</b>\n\n"
+	msgRelinkPrompt      = "Перенесення монітора <b>%s</b> (зараз: %s)."
+	msgRelinkSendChannel = "Додайте мене адміністратором нового каналу з правом \"Публікація повідомлень\" і надішліть його @username.\n\n<i>Приватний канал?</i> Перешліть мені будь-який пост з нього або опублікуйте в каналі код <code>%s</code>."
+	msgRelinkSameChannel = "Монітор вже публікує в %s."
+	msgRelinkDone        = "✅ Монітор <b>%s</b> тепер публікує в %s.\n\nІсторія та налаштування збережені."
+	msgRelinkResumeHint  = "\n\nМонітор зараз призупинено — відновіть його через /resume."
+)
+
 // msgChannelPaused is posted to the channel when the owner manually pauses monitoring.
 const msgChannelPaused = "⏸ <b>Моніторинг призупинено</b>\n\nВласник тимчасово призупинив оновлення статусу."
 
@@ -395,6 +537,69 @@ const msgChannelPausedBySystem = "⚠️ <b>Моніторинг призупи
 // msgChannelResumed is posted to the channel when the owner resumes monitoring.
 const msgChannelResumed = "▶️ <b>Моніторинг відновлено</b>\n\nВласник відновив оновлення статусу."
 
+// ── Followers ────────────────────────────────────────────────────────
+
+const (
+	msgFollowUsage             = "Вкажіть код запрошення: <code>/follow КОД</code>\n\nПосилання для підписки можна отримати у власника монітора."
+	msgUnfollowUsage           = "Вкажіть ID монітора: <code>/unfollow 123</code>"
+	msgUnfollowNotFollowing    = "Ви не стежите за цим монітором."
+	msgUnfollowDone            = "✅ Ви більше не отримуватимете сповіщень цього монітора."
+	msgFollowNotFound          = "Монітор не знайдено."
+	msgFollowOwnMonitor        = "Це ваш власний монітор — сповіщення вже приходять у ваш канал."
+	msgFollowLimit             = "У цього монітора вже забагато підписників."
+	msgFollowAlready           = "Ви вже стежите за <b>%s</b>. Відписатися: /unfollow %d"
+	msgFollowPending           = "Запит вже надіслано. Чекайте підтвердження власника монітора."
+	msgFollowRequested         = "📨 Запит надіслано власнику монітора. Я повідомлю, коли його підтвердять."
+	msgFollowOwnerRequest      = "👥 %s хоче отримувати сповіщення монітора <b>%s</b> в особисті повідомлення."
+	msgFollowApprovedDM        = "✅ Власник підтвердив запит. Тепер сповіщення <b>%s</b> приходитимуть сюди.\n\nВідписатися: /unfollow %d"
+	msgFollowApprovedOwner     = "✅ Підписку на <b>%s</b> підтверджено."
+	msgFollowDeclinedDM        = "Власник монітора відхилив запит на сповіщення."
+	msgFollowDeclinedOwner     = "Запит на <b>%s</b> відхилено."
+	msgFollowRevokedDM         = "Власник <b>%s</b> скасував вашу підписку на сповіщення."
+	msgFollowRequestGone       = "Цей запит вже неактуальний."
+	msgFollowActionError       = "Помилка зміни підписників."
+	msgFollowersHeader         = "👥 <b>Підписники %s</b>\n\nСусіди можуть отримувати сповіщення в особисті за посиланням:\nhttps://t.me/%s?start=%s%s\n\nДіліться ним лише з тими, кому довіряєте. Кожен запит потрібно підтвердити.\n"
+	msgFollowersEmpty          = "\nПоки що підписників немає."
+	msgFollowerStatusPrefix    = "🏠 <b>%s</b>\n"
+	msgFollowBtnApprove        = "✅ Підтвердити"
+	msgFollowBtnDecline        = "❌ Відхилити"
+	msgFollowBtnApprovePending = "✅ %s"
+	msgFollowBtnRevoke         = "❌ %s"
+	msgFollowBtnBack           = "⬅️ Назад"
+)
+
+// ── Linked accounts ──────────────────────────────────────────────────
+
+const (
+	msgLinkCode          = "🔗 Ваш код: <code>%s</code>\n\nНадішліть з основного акаунта команду <code>/link %[1]s</code> і підтвердіть запит. Код дійсний %d хв.\n\nПісля підтвердження цей акаунт бачитиме монітори основного й зможе ними керувати. Монітори, створені тут, перейдуть до основного акаунта."
+	msgLinkAlreadyLinked = "Цей акаунт уже пов'язано з %s. Відв'язати: /unlink"
+	msgLinkCodeInvalid   = "Код не знайдено або він застарів. Згенеруйте новий командою /link в іншому акаунті."
+	msgLinkCodeOwn       = "Цей код потрібно надіслати з іншого, основного акаунта."
+	msgLinkConfirm       = "🔗 %s отримає доступ до всіх ваших моніторів і зможе ними керувати. Підтвердити?"
+	msgLinkApproved      = "✅ %s тепер має доступ до ваших моніторів. Відв'язати: /unlink"
+	msgLinkDeclined      = "Запит %s відхилено."
+	msgLinkApprovedDM    = "✅ Акаунт пов'язано. Ваші монітори доступні через /info."
+	msgLinkDeclinedDM    = "Запит на доступ до моніторів відхилено."
+	msgLinkNotAllowed    = "Ці акаунти не можна пов'язати: пов'язаний акаунт не може бути основним, а основний не можна прив'язати до іншого."
+	msgLinkRequestGone   = "Цей запит вже неактуальний."
+	msgLinkActionError   = "Помилка зміни пов'язаних акаунтів."
+	msgUnlinkDone        = "✅ Акаунт відв'язано. Монітори, створені в ньому до прив'язки, повернуто йому."
+	msgUnlinkedDM        = "Власник відв'язав ваш акаунт — його монітори більше недоступні. Ваші власні монітори, створені до прив'язки, повернуто вам."
+	msgUnlinkNone        = "Пов'язаних акаунтів немає. Щоб додати, надішліть /link з іншого акаунта."
+	msgUnlinkChoose      = "Оберіть акаунт, який потрібно відв'язати:"
+	msgLinkBtnApprove    = "✅ Підтвердити"
+	msgLinkBtnDecline    = "❌ Відхилити"
+	msgLinkBtnRemove     = "❌ %s"
+)
+
+// ── /apikey ──────────────────────────────────────────────────────────
+
+const (
+	msgAPIKey        = "🔑 Ваш API-ключ:\n<code>%s</code>\n\nПередавайте його в заголовку <code>Authorization: Bearer ...</code> до <code>%s/api/v1/monitors</code>.\n\nКлюч показується лише один раз. Нова команда /apikey замінить його, <code>/apikey revoke</code> — відкличе."
+	msgAPIKeyRevoked = "✅ API-ключ відкликано."
+	msgAPIKeyNone    = "У вас немає API-ключа. Отримати: /apikey"
+)
+
 // ── DTEK unplanned outage notifications ─────────────────────────────
 
 // msgDtekOutage is sent when DTEK confirms an unplanned outage for the monitor's address.
@@ -410,5 +615,41 @@ const msgDtekOutageNoTime = "⚡ <b>Відключення підтвердже
 // %s = monitor name.
 const msgInactivePause = "⏸ <b>Монітор призупинено</b>\n\nМонітор <b>%s</b> було автоматично призупинено, оскільки з моменту створення не надійшло жодного сигналу.\n\nПереконайтеся, що пристрій налаштовано коректно, та відновіть моніторинг через /resume."
 
+// msgChannelMonitoringEnded is posted to the channel once its monitor is deleted.
+const msgChannelMonitoringEnded = "⏹ <b>Моніторинг завершено</b>\n\nВласник видалив монітор «%s». Сповіщення в цьому каналі більше не надходитимуть."
+
 // msgChannelInactivePause is posted to the channel when auto-paused due to no activity.
 const msgChannelInactivePause = "⏸ <b>Моніторинг призупинено автоматично</b>\n\nЖодного сигналу з моменту створення монітора. Власник отримав сповіщення."
+
+// ── Stale-device hints ───────────────────────────────────────────────
+
+// msgIntervalHint is sent to the owner when the device pings too rarely for the offline threshold.
+// %s = monitor name, %d = observed interval (sec), %d = threshold (sec).
+const msgIntervalHint = "⚠️ <b>Пристрій пінгує надто рідко</b>\n\nМонітор <b>%s</b> отримує сигнал приблизно раз на <b>%d с</b>, а поріг офлайну — <b>%d с</b>. Через це канал регулярно отримуватиме хибні сповіщення про зникнення світла.\n\n"
+
+// msgIntervalHintThreshold suggests a larger threshold. %s = threshold label.
+const msgIntervalHintThreshold = "Збільште поріг до <b>%s</b> кнопкою нижче або налаштуйте пристрій пінгувати частіше."
+
+// msgIntervalHintDevice asks to reconfigure the device. %d = max recommended interval (sec).
+const msgIntervalHintDevice = "Жоден поріг не підходить для такого інтервалу — налаштуйте пристрій надсилати запит щонайменше раз на <b>%d с</b>."
+
+// msgIntervalHintBtn is the button applying the suggested threshold. %s = threshold label.
+const msgIntervalHintBtn = "⏱ Встановити поріг %s"
+
+// ── /mute ────────────────────────────────────────────────────────────
+
+const (
+	msgMuteUsage     = "Вкажіть, на скільки вимкнути сповіщення, наприклад:\n<code>/mute 30m</code>, <code>/mute 2h</code>, <code>/mute 1d</code> (до 7 днів).\n\n<code>/mute off</code> — увімкнути сповіщення раніше.\n\n<i>Моніторинг не зупиняється: історія й графіки оновлюються як зазвичай.</i>"
+	msgMuteHeader    = "<b>Вимкнути сповіщення на %s</b>\n\nОберіть монітор:\n\n"
+	msgUnmuteHeader  = "<b>Увімкнути сповіщення</b>\n\nОберіть монітор:\n\n"
+	msgMuteNoneMuted = "Жоден монітор зараз не заглушено."
+	msgMuteDone      = "🔇 Сповіщення <b>%s</b> вимкнено до %s.\n\nСтатус і далі записується. Увімкнути раніше: /mute off"
+	msgUnmuteDone    = "🔔 Сповіщення <b>%s</b> знову увімкнено."
+	msgMuteError     = "Помилка зміни налаштування."
+)
+
+// msgChannelMuted is posted to the channel when the owner mutes the monitor.
+const msgChannelMuted = "🔇 <b>Сповіщення вимкнено до %s</b>\n\nВласник проводить роботи — зміни статусу тимчасово не публікуються."
+
+// msgChannelUnmuted is posted to the channel when the owner unmutes early.
+const msgChannelUnmuted = "🔔 Сповіщення про світло знову увімкнено."
@@ -0,0 +1,182 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"no-lights-monitor/internal/models"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+// Mute: the owner silences a monitor's status notifications for a while (e.g.
+// during electrical repairs) without pausing it, so history and graphs keep
+// filling in. The worker unmutes expired monitors and posts a note (see
+// cmd/worker/mute).
+
+// maxMute caps a single /mute; longer silences should use /stop.
+const maxMute = 7 * 24 * time.Hour
+
+// handleMute handles /mute <duration> and /mute off.
+func (b *Bot) handleMute(c tele.Context) error {
+	arg := strings.ToLower(strings.TrimSpace(c.Message().Payload))
+	var minutes int
+	if arg != "off" {
+		d, ok := parseMuteDuration(arg)
+		if !ok {
+			return c.Send(msgMuteUsage, htmlOpts)
+		}
+		minutes = int(d / time.Minute)
+	}
+
+	ctx := context.Background()
+	monitors, err := b.db.GetMonitorsByTelegramID(ctx, c.Sender().ID)
+	if err != nil {
+		log.Printf("[bot] get monitors error: %v", err)
+		return c.Send(msgError)
+	}
+	var withChannel []*models.Monitor
+	for _, m := range monitors {
+		if m.ChannelID != 0 && (minutes > 0 || m.MutedUntil != nil) {
+			withChannel = append(withChannel, m)
+		}
+	}
+	if len(withChannel) == 0 {
+		if minutes == 0 {
+			return c.Send(msgMuteNoneMuted)
+		}
+		return c.Send(msgNoTestChannels, htmlOpts)
+	}
+	if len(withChannel) == 1 {
+		text, err := b.applyMute(ctx, withChannel[0], minutes)
+		if err != nil {
+			return c.Send(msgMuteError)
+		}
+		return c.Send(text, htmlOpts)
+	}
+
+	var bld strings.Builder
+	if minutes == 0 {
+		bld.WriteString(msgUnmuteHeader)
+	} else {
+		bld.WriteString(fmt.Sprintf(msgMuteHeader, muteLabel(minutes)))
+	}
+	rows := make([][]tele.InlineButton, 0, len(withChannel))
+	for i, m := range withChannel {
+		bld.WriteString(fmt.Sprintf("%d. %s\n", i+1, html.EscapeString(m.Name)))
+		rows = append(rows, []tele.InlineButton{
+			{
+				Text: fmt.Sprintf("%d. %s", i+1, m.Name),
+				Data: fmt.Sprintf("mute:%d:%d", m.ID, minutes),
+			},
+		})
+	}
+	return c.Send(bld.String(), tele.ModeHTML, &tele.ReplyMarkup{InlineKeyboard: rows})
+}
+
+func (b *Bot) onCallbackMute(ctx context.Context, c tele.Context, parts []string, m *models.Monitor) error {
+	if len(parts) < 3 {
+		return c.Respond(&tele.CallbackResponse{Text: msgInvalidFormat})
+	}
+	minutes, err := strconv.Atoi(parts[2])
+	if err != nil || minutes < 0 || time.Duration(minutes)*time.Minute > maxMute {
+		return c.Respond(&tele.CallbackResponse{Text: msgInvalidFormat})
+	}
+	text, err := b.applyMute(ctx, m, minutes)
+	if err != nil {
+		return c.Respond(&tele.CallbackResponse{Text: msgMuteError})
+	}
+	_ = c.Respond(&tele.CallbackResponse{})
+	return c.Edit(text, tele.ModeHTML, &tele.ReplyMarkup{})
+}
+
+// applyMute mutes m for the given number of minutes (0 unmutes it), posts a
+// note to its channel and returns the owner's confirmation.
+func (b *Bot) applyMute(ctx context.Context, m *models.Monitor, minutes int) (string, error) {
+	var until *time.Time
+	if minutes > 0 {
+		t := time.Now().Add(time.Duration(minutes) * time.Minute).Truncate(time.Minute)
+		until = &t
+	}
+	if err := b.db.SetMonitorMutedUntil(ctx, m.ID, until); err != nil {
+		log.Printf("[bot] set muted_until for monitor %d: %v", m.ID, err)
+		return "", err
+	}
+	b.recordChange(ctx, m.ID, "muted_until", mutedValue(m.MutedUntil), mutedValue(until))
+
+	name := html.EscapeString(m.Name)
+	note, reply := msgChannelUnmuted, fmt.Sprintf(msgUnmuteDone, name)
+	if until != nil {
+		at := muteUntilLabel(*until)
+		note, reply = fmt.Sprintf(msgChannelMuted, at), fmt.Sprintf(msgMuteDone, name, at)
+	}
+	if _, err := b.bot.Send(&tele.Chat{ID: m.ChannelID}, note, htmlOpts); err != nil {
+		log.Printf("[bot] failed to send mute notice to channel %d: %v", m.ChannelID, err)
+	}
+	return reply, nil
+}
+
+// parseMuteDuration accepts "90" (minutes), "30m", "2h", "1h30m", "1d" and
+// their Ukrainian spellings ("30хв", "2год", "1д"), up to maxMute.
+func parseMuteDuration(s string) (time.Duration, bool) {
+	s = strings.NewReplacer(" ", "", "хв", "m", "год", "h", "дн", "d", "д", "d").Replace(s)
+	if s == "" {
+		return 0, false
+	}
+	var d time.Duration
+	if n, err := strconv.Atoi(s); err == nil {
+		d = time.Duration(n) * time.Minute
+	} else if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, false
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else if d, err = time.ParseDuration(s); err != nil {
+		return 0, false
+	}
+	if d < time.Minute || d > maxMute {
+		return 0, false
+	}
+	return d, true
+}
+
+// muteLabel renders a mute length for the picker header, e.g. "2 год 30 хв".
+func muteLabel(minutes int) string {
+	d, h, m := minutes/(24*60), minutes/60%24, minutes%60
+	var parts []string
+	if d > 0 {
+		parts = append(parts, fmt.Sprintf("%d д", d))
+	}
+	if h > 0 {
+		parts = append(parts, fmt.Sprintf("%d год", h))
+	}
+	if m > 0 {
+		parts = append(parts, fmt.Sprintf("%d хв", m))
+	}
+	return strings.Join(parts, " ")
+}
+
+// muteUntilLabel renders the end of a mute in Kyiv time, with the date when
+// it isn't today.
+func muteUntilLabel(t time.Time) string {
+	kyiv, _ := time.LoadLocation("Europe/Kyiv")
+	t = t.In(kyiv)
+	if t.Format("2006-01-02") == time.Now().In(kyiv).Format("2006-01-02") {
+		return t.Format("15:04")
+	}
+	return t.Format("02.01 15:04")
+}
+
+// mutedValue is the change-log form of muted_until ("" = not muted).
+func mutedValue(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
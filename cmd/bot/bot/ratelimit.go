@@ -0,0 +1,77 @@
+package bot
+
+import (
+	"sync"
+	"time"
+)
+
+// Per-user rate limiting: every sender gets a token bucket that refills at
+// userRate and holds up to userBurst updates, enough for a /create flow typed
+// at full speed but not for someone hammering /info. Updates over the limit
+// are dropped by route with a polite reply.
+
+const (
+	// userBurst is how many updates a user may send back to back.
+	userBurst = 8
+	// userRate is how many updates per second the bucket refills.
+	userRate = 0.5
+	// slowDownEvery is how often a limited user is told to slow down; other
+	// updates over the limit are dropped silently.
+	slowDownEvery = 10 * time.Second
+	// bucketIdle is how long an untouched bucket is kept; a full bucket
+	// after this long is the same as a new one.
+	bucketIdle = 10 * time.Minute
+)
+
+type userBucket struct {
+	tokens float64
+	last   time.Time // last refill
+	warned time.Time // last slow-down reply
+}
+
+// userLimiter holds the token buckets of recent senders.
+type userLimiter struct {
+	mu        sync.Mutex
+	buckets   map[int64]*userBucket
+	lastPrune time.Time
+}
+
+// allow takes a token from telegramID's bucket. When none is left it reports
+// whether the user should be told to slow down (at most every slowDownEvery).
+func (l *userLimiter) allow(telegramID int64, now time.Time) (ok, warn bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.buckets == nil {
+		l.buckets = make(map[int64]*userBucket)
+	}
+	if now.Sub(l.lastPrune) >= bucketIdle {
+		l.prune(now)
+	}
+
+	b, exists := l.buckets[telegramID]
+	if !exists {
+		b = &userBucket{tokens: userBurst, last: now}
+		l.buckets[telegramID] = b
+	}
+	b.tokens = min(userBurst, b.tokens+now.Sub(b.last).Seconds()*userRate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, false
+	}
+	if now.Sub(b.warned) < slowDownEvery {
+		return false, false
+	}
+	b.warned = now
+	return false, true
+}
+
+// prune forgets buckets idle for bucketIdle.
+func (l *userLimiter) prune(now time.Time) {
+	for id, b := range l.buckets {
+		if now.Sub(b.last) >= bucketIdle {
+			delete(l.buckets, id)
+		}
+	}
+	l.lastPrune = now
+}
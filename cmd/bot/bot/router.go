@@ -0,0 +1,157 @@
+package bot
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"no-lights-monitor/internal/metrics"
+	"no-lights-monitor/internal/safego"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+// Routing: every command, callback and update handler is registered through
+// handle, which wraps it in the same middleware chain — rate limiting,
+// logging, user upsert, language context, metrics and panic recovery — so
+// handlers only contain their own logic.
+
+// ctxLang is the tele.Context key holding the sender's language code.
+const ctxLang = "lang"
+
+// defaultLang is used when Telegram doesn't report the sender's language.
+const defaultLang = "uk"
+
+// userTouchEvery throttles the user upsert to once per user and period.
+const userTouchEvery = time.Hour
+
+// userTouches remembers when each sender's user record was last refreshed.
+type userTouches struct {
+	mu   sync.Mutex
+	seen map[int64]time.Time
+}
+
+// due reports whether telegramID's record should be refreshed now, and if so
+// marks it refreshed.
+func (t *userTouches) due(telegramID int64, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.seen == nil {
+		t.seen = make(map[int64]time.Time)
+	}
+	if last, ok := t.seen[telegramID]; ok && now.Sub(last) < userTouchEvery {
+		return false
+	}
+	t.seen[telegramID] = now
+	return true
+}
+
+// handle registers h for endpoint under the route name used in logs and
+// metrics. Keep names low-cardinality: one per command or update kind.
+func (b *Bot) handle(endpoint any, name string, h tele.HandlerFunc) {
+	b.bot.Handle(endpoint, b.route(name, h))
+}
+
+// route wraps h in the shared middleware chain.
+func (b *Bot) route(name string, h tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		start := time.Now()
+		if sender := c.Sender(); sender != nil {
+			if ok, warn := b.limiter.allow(sender.ID, start); !ok {
+				metrics.BotUpdates.WithLabelValues(name, "limited").Inc()
+				if warn {
+					log.Printf("[bot] %s: user %d (@%s) rate limited", name, sender.ID, sender.Username)
+					return slowDown(c)
+				}
+				return nil
+			}
+		}
+		c.Set(ctxLang, senderLang(c))
+		logUpdate(name, c)
+		b.touchUser(c)
+
+		var err error
+		result := "ok"
+		if panicErr := safego.Run("bot:"+name, func() { err = h(c) }); panicErr != nil {
+			result = "panic"
+			err = replyError(c)
+		} else if err != nil {
+			result = "error" // logged by telebot's OnError
+		}
+		metrics.BotUpdates.WithLabelValues(name, result).Inc()
+		metrics.BotUpdateDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// logUpdate writes the one log line every update gets.
+func logUpdate(name string, c tele.Context) {
+	sender := c.Sender()
+	if sender == nil {
+		return // channel posts
+	}
+	switch {
+	case c.Callback() != nil:
+		log.Printf("[bot] callback %q from user %d (@%s)", c.Callback().Data, sender.ID, sender.Username)
+	case name[0] == '/' && c.Message() != nil && c.Message().Payload != "":
+		log.Printf("[bot] %s %q from user %d (@%s, %s)", name, c.Message().Payload, sender.ID, sender.Username, lang(c))
+	case name[0] == '/':
+		log.Printf("[bot] %s from user %d (@%s, %s)", name, sender.ID, sender.Username, lang(c))
+	}
+}
+
+// touchUser keeps the sender's username and first name current. Only private
+// chats count: group members who type /status are not users of the bot.
+func (b *Bot) touchUser(c tele.Context) {
+	sender := c.Sender()
+	if sender == nil || c.Chat() == nil || c.Chat().Type != tele.ChatPrivate {
+		return
+	}
+	if !b.touches.due(sender.ID, time.Now()) {
+		return
+	}
+	if _, err := b.db.UpsertUser(context.Background(), sender.ID, sender.Username, sender.FirstName); err != nil {
+		log.Printf("[bot] upsert user %d: %v", sender.ID, err)
+	}
+}
+
+// slowDown asks a rate-limited user to wait. In groups the update is dropped
+// quietly so the bot doesn't add to the noise.
+func slowDown(c tele.Context) error {
+	if c.Callback() != nil {
+		return c.Respond(&tele.CallbackResponse{Text: msgSlowDown})
+	}
+	if c.Chat() == nil || c.Chat().Type != tele.ChatPrivate {
+		return nil
+	}
+	return c.Send(msgSlowDown)
+}
+
+// replyError tells the user something went wrong after a handler panicked.
+func replyError(c tele.Context) error {
+	if c.Callback() != nil {
+		return c.Respond(&tele.CallbackResponse{Text: msgError})
+	}
+	if c.Chat() == nil || c.Chat().Type != tele.ChatPrivate {
+		return nil
+	}
+	return c.Send(msgError)
+}
+
+// senderLang returns the sender's Telegram language code, or defaultLang.
+func senderLang(c tele.Context) string {
+	if s := c.Sender(); s != nil && s.LanguageCode != "" {
+		return s.LanguageCode
+	}
+	return defaultLang
+}
+
+// lang returns the language code route stored for this update. Messages are
+// Ukrainian only for now; this is where translated ones will pick theirs.
+func lang(c tele.Context) string {
+	if l, ok := c.Get(ctxLang).(string); ok {
+		return l
+	}
+	return defaultLang
+}
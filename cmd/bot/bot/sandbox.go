@@ -0,0 +1,202 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// SandboxClient returns an HTTP client for the bot that keeps Telegram API
+// writes from reaching real chats. Messages are redirected to debugChatID, or
+// only logged when it is 0; edits, deletes and chat changes are always only
+// logged. Reads (getUpdates, getChat...) pass through so the bot still works.
+func SandboxClient(debugChatID int64) *http.Client {
+	return &http.Client{
+		Timeout:   time.Minute,
+		Transport: &sandboxTransport{next: http.DefaultTransport, debugChatID: debugChatID},
+	}
+}
+
+type sandboxTransport struct {
+	next        http.RoundTripper
+	debugChatID int64
+	lastMsgID   atomic.Int64 // IDs handed out for suppressed messages
+}
+
+// passThrough lists the non-"get" API methods that are safe in a sandbox: they
+// only answer the user interacting with the bot or configure the bot itself.
+var passThrough = map[string]bool{
+	"answerCallbackQuery": true,
+	"answerInlineQuery":   true,
+	"setMyCommands":       true,
+	"deleteWebhook":       true,
+}
+
+func (t *sandboxTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	method := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+	if strings.HasPrefix(method, "get") || passThrough[method] {
+		return t.next.RoundTrip(req)
+	}
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+	fields, err := sandboxFields(req.Header.Get("Content-Type"), body)
+	if err != nil {
+		return nil, fmt.Errorf("sandbox: read %s: %w", method, err)
+	}
+
+	isSend := strings.HasPrefix(method, "send") || method == "copyMessage" || method == "forwardMessage"
+	if isSend && t.debugChatID != 0 {
+		debugChat := strconv.FormatInt(t.debugChatID, 10)
+		if body, err = replaceChatID(req.Header.Get("Content-Type"), body, debugChat); err != nil {
+			return nil, fmt.Errorf("sandbox: redirect %s: %w", method, err)
+		}
+		log.Printf("[sandbox] %s to chat %s redirected to %s", method, fields["chat_id"], debugChat)
+		out := req.Clone(req.Context())
+		out.Body = io.NopCloser(bytes.NewReader(body))
+		out.ContentLength = int64(len(body))
+		return t.next.RoundTrip(out)
+	}
+
+	log.Printf("[sandbox] %s to chat %s suppressed: %s", method, fields["chat_id"], sandboxPreview(fields))
+	return t.fakeResponse(req, method, isSend, fields["chat_id"])
+}
+
+// fakeResponse answers a suppressed call the way Telegram would, so callers
+// storing message IDs keep working.
+func (t *sandboxTransport) fakeResponse(req *http.Request, method string, isSend bool, chatID string) (*http.Response, error) {
+	var result any = true
+	if isSend || strings.HasPrefix(method, "edit") {
+		id, _ := strconv.ParseInt(chatID, 10, 64)
+		msg := map[string]any{
+			"message_id": t.lastMsgID.Add(1),
+			"date":       time.Now().Unix(),
+			"chat":       map[string]any{"id": id, "type": "private"},
+		}
+		result = msg
+		if method == "sendMediaGroup" {
+			result = []any{msg}
+		}
+	}
+	data, err := json.Marshal(map[string]any{"ok": true, "result": result})
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Status:        "200 OK",
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}, nil
+}
+
+// sandboxFields returns the plain fields of a JSON or multipart API call.
+func sandboxFields(contentType string, body []byte) (map[string]string, error) {
+	fields := map[string]string{}
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	if mediaType == "multipart/form-data" {
+		r := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+		for {
+			part, err := r.NextPart()
+			if err == io.EOF {
+				return fields, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			if part.FileName() == "" {
+				v, _ := io.ReadAll(part)
+				fields[part.FormName()] = string(v)
+			}
+		}
+	}
+	if len(body) == 0 {
+		return fields, nil
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, err
+	}
+	for k, v := range raw {
+		if s, ok := v.(string); ok {
+			fields[k] = s
+		} else {
+			fields[k] = fmt.Sprint(v)
+		}
+	}
+	return fields, nil
+}
+
+// replaceChatID rewrites the chat_id field of a JSON or multipart API call.
+func replaceChatID(contentType string, body []byte, chatID string) ([]byte, error) {
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	if mediaType != "multipart/form-data" {
+		var raw map[string]any
+		if err := json.Unmarshal(body, &raw); err != nil {
+			return nil, err
+		}
+		raw["chat_id"] = chatID
+		return json.Marshal(raw)
+	}
+
+	var out bytes.Buffer
+	w := multipart.NewWriter(&out)
+	if err := w.SetBoundary(params["boundary"]); err != nil {
+		return nil, err
+	}
+	r := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, err := r.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		pw, err := w.CreatePart(part.Header)
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "chat_id" && part.FileName() == "" {
+			_, err = io.WriteString(pw, chatID)
+		} else {
+			_, err = io.Copy(pw, part)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// sandboxPreview returns the start of a suppressed message for the log.
+func sandboxPreview(fields map[string]string) string {
+	text := fields["text"]
+	if text == "" {
+		text = fields["caption"]
+	}
+	if r := []rune(text); len(r) > 120 {
+		text = string(r[:120]) + "…"
+	}
+	return strings.ReplaceAll(text, "\n", " ")
+}
@@ -0,0 +1,95 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"no-lights-monitor/internal/metrics"
+	"no-lights-monitor/internal/safego"
+)
+
+// sendRate is how many calls per second may go to Telegram, a little under the
+// bot API's global limit of about 30/s, so Telegram never starts throttling
+// (and delaying) status notifications.
+const sendRate = 25
+
+// Per-chat pacing: Telegram allows about one message a second in a private
+// chat and 20 a minute in a group or channel.
+const (
+	privateChatInterval = time.Second
+	groupChatInterval   = 3 * time.Second
+)
+
+// bulkMethods are the media uploads of graphs and schedule photos. They only
+// get send slots no other call is waiting for, so during a mass outage
+// thousands of graph edits queue behind the status messages instead of in
+// front of them.
+var bulkMethods = map[string]bool{
+	"sendPhoto":        true,
+	"sendDocument":     true,
+	"sendMediaGroup":   true,
+	"editMessageMedia": true,
+}
+
+// chatInterval returns the gap kept between two calls to chatID. Groups and
+// channels have negative IDs (or an @username).
+func chatInterval(chatID string) time.Duration {
+	if strings.HasPrefix(chatID, "-") || strings.HasPrefix(chatID, "@") {
+		return groupChatInterval
+	}
+	return privateChatInterval
+}
+
+// sendGate paces every Telegram call of the bot service and hands each free
+// slot to a waiting priority call before a bulk one.
+type sendGate struct {
+	priority chan struct{}
+	bulk     chan struct{}
+}
+
+func newSendGate(ctx context.Context, perSecond int) *sendGate {
+	g := &sendGate{priority: make(chan struct{}), bulk: make(chan struct{})}
+	safego.Go("telegram:send_gate", func() { g.run(ctx, time.Second/time.Duration(perSecond)) })
+	return g
+}
+
+func (g *sendGate) run(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		// A waiting priority call always takes the slot first.
+		select {
+		case g.priority <- struct{}{}:
+			continue
+		default:
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case g.priority <- struct{}{}:
+		case g.bulk <- struct{}{}:
+		}
+	}
+}
+
+// wait blocks until the call may be sent or ctx is done.
+func (g *sendGate) wait(ctx context.Context, bulk bool) error {
+	slot, class := g.priority, "priority"
+	if bulk {
+		slot, class = g.bulk, "bulk"
+	}
+	start := time.Now()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-slot:
+		metrics.BotSendGateWait.WithLabelValues(class).Observe(time.Since(start).Seconds())
+		return nil
+	}
+}
@@ -2,52 +2,45 @@ package channeldesc
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"strings"
-	"time"
 
 	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/publicurl"
 
 	tele "gopkg.in/telebot.v3"
 )
 
 // Checker runs daily and ensures every active monitor's Telegram channel
-// description contains the service URL. If missing, it appends it.
-// Runs daily at 14:00 Kyiv time.
+// description contains the service URL. If missing, it appends the canonical
+// one; a legacy URL already in the description counts as present.
+// Scheduled daily at 14:00 Kyiv time.
 type Checker struct {
-	bot     *tele.Bot
-	db      *database.DB
-	baseURL string
+	bot   *tele.Bot
+	db    *database.DB
+	hosts *publicurl.Hosts
 }
 
-func NewChecker(bot *tele.Bot, db *database.DB, baseURL string) *Checker {
-	return &Checker{bot: bot, db: db, baseURL: baseURL}
+func NewChecker(bot *tele.Bot, db *database.DB, hosts *publicurl.Hosts) *Checker {
+	return &Checker{bot: bot, db: db, hosts: hosts}
 }
 
-// Start runs the checker loop, firing daily at 14:00 Kyiv time.
-func (c *Checker) Start(ctx context.Context) {
-	kyiv, _ := time.LoadLocation("Europe/Kyiv")
-	log.Println("[channeldesc] checker started, running initial check")
-	c.run(ctx)
-
-	for {
-		delay := timeUntilNext(14, 0, kyiv)
-		log.Printf("[channeldesc] next check in %s", delay.Round(time.Second))
-		select {
-		case <-ctx.Done():
-			log.Println("[channeldesc] checker stopped")
-			return
-		case <-time.After(delay):
-			c.run(ctx)
+// hasServiceURL reports whether desc mentions any of the service's base URLs.
+func (c *Checker) hasServiceURL(desc string) bool {
+	for _, u := range c.hosts.All() {
+		if strings.Contains(desc, u) {
+			return true
 		}
 	}
+	return false
 }
 
-func (c *Checker) run(ctx context.Context) {
+// Run checks every monitor's channel description once.
+func (c *Checker) Run(ctx context.Context) error {
 	monitors, err := c.db.GetMonitorsWithChannels(ctx)
 	if err != nil {
-		log.Printf("[channeldesc] failed to query monitors: %v", err)
-		return
+		return fmt.Errorf("query monitors: %w", err)
 	}
 	log.Printf("[channeldesc] checking %d monitors with channels", len(monitors))
 
@@ -58,7 +51,7 @@ func (c *Checker) run(ctx context.Context) {
 			continue
 		}
 
-		if strings.Contains(chat.Description, c.baseURL) {
+		if c.hasServiceURL(chat.Description) {
 			continue
 		}
 
@@ -66,7 +59,7 @@ func (c *Checker) run(ctx context.Context) {
 		if newDesc != "" {
 			newDesc += "\n"
 		}
-		newDesc += c.baseURL
+		newDesc += c.hosts.Canonical()
 
 		if err := c.bot.SetGroupDescription(chat, newDesc); err != nil {
 			log.Printf("[channeldesc] monitor %d: failed to update channel %d description: %v", m.ID, m.ChannelID, err)
@@ -74,13 +67,5 @@ func (c *Checker) run(ctx context.Context) {
 		}
 		log.Printf("[channeldesc] monitor %d: appended base URL to channel %d description", m.ID, m.ChannelID)
 	}
-}
-
-func timeUntilNext(hour, minute int, loc *time.Location) time.Duration {
-	now := time.Now().In(loc)
-	next := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, loc)
-	if !now.Before(next) {
-		next = next.Add(24 * time.Hour)
-	}
-	return next.Sub(time.Now())
+	return nil
 }
@@ -0,0 +1,119 @@
+// Package channelstats sends owners who opted in a weekly DM with their
+// channels' subscriber count change and how many notifications were posted.
+package channelstats
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"strings"
+	"time"
+
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/models"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+// period is the window the notification count covers; it matches the job's
+// weekly schedule.
+const period = 7 * 24 * time.Hour
+
+const (
+	msgStatsHeader      = "📈 <b>Статистика каналів за тиждень</b>\n\n"
+	msgStatsItem        = "• <b>%s</b>: %d підписників%s, сповіщень: %d\n"
+	msgStatsDelta       = " (%+d)"
+	msgStatsFirstReport = " (перший звіт)"
+	msgStatsFooter      = "\n<i>Вимкнути звіт можна в /edit або на сторінці налаштувань.</i>"
+)
+
+// Collector records each opted-in channel's subscriber count and sends the
+// owners a weekly summary. Scheduled on Mondays at 11:00 Kyiv time.
+type Collector struct {
+	bot *tele.Bot
+	db  *database.DB
+}
+
+func NewCollector(bot *tele.Bot, db *database.DB) *Collector {
+	return &Collector{bot: bot, db: db}
+}
+
+// Run collects stats for every opted-in monitor and DMs one summary per owner.
+func (c *Collector) Run(ctx context.Context) error {
+	monitors, err := c.db.GetChannelStatsMonitors(ctx)
+	if err != nil {
+		return fmt.Errorf("query monitors: %w", err)
+	}
+	since := time.Now().Add(-period)
+
+	// Monitors come ordered by owner; lines accumulate until the owner changes.
+	var (
+		ownerID int64
+		lines   []string
+		sent    int
+	)
+	flush := func() {
+		if ownerID == 0 || len(lines) == 0 {
+			return
+		}
+		text := msgStatsHeader + strings.Join(lines, "") + msgStatsFooter
+		if _, err := c.bot.Send(&tele.User{ID: ownerID}, text, tele.ModeHTML); err != nil {
+			log.Printf("[channelstats] owner %d: send error: %v", ownerID, err)
+			return
+		}
+		sent++
+	}
+	for _, m := range monitors {
+		owner, err := c.db.GetOwnerTelegramIDByMonitorID(ctx, m.ID)
+		if err != nil {
+			log.Printf("[channelstats] monitor %d: owner lookup error: %v", m.ID, err)
+			continue
+		}
+		if owner != ownerID {
+			flush()
+			ownerID, lines = owner, nil
+		}
+		line, err := c.collect(ctx, m, since)
+		if err != nil {
+			log.Printf("[channelstats] monitor %d: %v", m.ID, err)
+			continue
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	log.Printf("[channelstats] %d monitors checked, %d summaries sent", len(monitors), sent)
+
+	if _, err := c.db.DeleteChannelPostsBefore(ctx, since); err != nil {
+		log.Printf("[channelstats] prune post records: %v", err)
+	}
+	return nil
+}
+
+// collect stores the channel's current subscriber count and returns the
+// monitor's summary line.
+func (c *Collector) collect(ctx context.Context, m *models.Monitor, since time.Time) (string, error) {
+	count, err := c.bot.Len(&tele.Chat{ID: m.ChannelID})
+	if err != nil {
+		return "", fmt.Errorf("get subscriber count of channel %d: %w", m.ChannelID, err)
+	}
+	prev, ok, err := c.db.GetLastChannelSubscribers(ctx, m.ID)
+	if err != nil {
+		return "", fmt.Errorf("load previous count: %w", err)
+	}
+	if err := c.db.SaveChannelSubscribers(ctx, m.ID, count); err != nil {
+		return "", fmt.Errorf("save count: %w", err)
+	}
+	// Only messages that reached the channel count: muted, flapping and
+	// planned-outage changes were never posted.
+	posted, err := c.db.CountChannelPosts(ctx, m.ID, since)
+	if err != nil {
+		return "", fmt.Errorf("count notifications: %w", err)
+	}
+
+	delta := msgStatsFirstReport
+	if ok {
+		delta = fmt.Sprintf(msgStatsDelta, count-prev)
+	}
+	return fmt.Sprintf(msgStatsItem, html.EscapeString(m.Name), count, delta, posted), nil
+}
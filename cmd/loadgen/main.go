@@ -0,0 +1,163 @@
+// Command loadgen exercises a deployment with synthetic monitors, to check
+// offline thresholds and worker/API sizing before a real wave of blackouts:
+//
+//	loadgen setup -n 1000               create synthetic monitors
+//	loadgen run -api URL                ping them every -interval
+//	loadgen run -api URL -replay-from T -replay-to T -speed 10
+//	                                    ping them, going silent whenever the real
+//	                                    monitor each one mirrors was offline
+//	loadgen cleanup                     delete the synthetic monitors
+//
+// Synthetic monitors belong to a placeholder owner, have no channel and are
+// not public, so they never notify anyone or show up on the map. It reads
+// DATABASE_URL like the services do.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"time"
+
+	"no-lights-monitor/internal/bootstrap"
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/errsink"
+	"no-lights-monitor/internal/models"
+)
+
+const (
+	// loadgenTelegramID owns the synthetic monitors. Telegram user IDs are
+	// positive, so it never collides with a real account.
+	loadgenTelegramID = -4242
+	// namePrefix starts the name of every synthetic monitor.
+	namePrefix = "loadgen-"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cfg := bootstrap.Init("loadgen")
+	defer errsink.Flush(2 * time.Second)
+
+	ctx, stop := bootstrap.SignalContext()
+	defer stop()
+
+	args := os.Args[2:]
+	switch os.Args[1] {
+	case "setup":
+		fs := flag.NewFlagSet("setup", flag.ExitOnError)
+		n := fs.Int("n", 100, "number of synthetic monitors to create")
+		_ = fs.Parse(args)
+		db := bootstrap.Database(ctx, cfg)
+		defer db.Close()
+		if err := setup(ctx, db, *n, cfg.BaseURL); err != nil {
+			log.Fatalf("setup: %v", err)
+		}
+	case "run":
+		fs := flag.NewFlagSet("run", flag.ExitOnError)
+		opts := runOptions{}
+		fs.StringVar(&opts.apiURL, "api", cfg.BaseURL, "base URL of the API to ping")
+		fs.DurationVar(&opts.interval, "interval", 5*time.Minute, "ping interval of each monitor")
+		fs.DurationVar(&opts.duration, "duration", 0, "stop after this long (0 runs until interrupted)")
+		replayFrom := fs.String("replay-from", "", "start of a recorded outage wave to replay (RFC 3339)")
+		replayTo := fs.String("replay-to", "", "end of the recorded outage wave (RFC 3339)")
+		fs.Float64Var(&opts.speed, "speed", 1, "replay speed-up; outages shorter than the offline threshold go unnoticed")
+		_ = fs.Parse(args)
+		if opts.interval <= 0 {
+			log.Fatal("-interval must be positive")
+		}
+
+		db := bootstrap.Database(ctx, cfg)
+		defer db.Close()
+		monitors, err := syntheticMonitors(ctx, db)
+		if err != nil {
+			log.Fatalf("load monitors: %v", err)
+		}
+		if len(monitors) == 0 {
+			log.Fatal("no synthetic monitors, run loadgen setup first")
+		}
+		if *replayFrom != "" || *replayTo != "" {
+			if opts.wave, err = loadWave(ctx, db, *replayFrom, *replayTo); err != nil {
+				log.Fatalf("replay: %v", err)
+			}
+		}
+		run(ctx, monitors, opts)
+	case "cleanup":
+		db := bootstrap.Database(ctx, cfg)
+		defer db.Close()
+		if err := cleanup(ctx, db); err != nil {
+			log.Fatalf("cleanup: %v", err)
+		}
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: loadgen setup|run|cleanup [flags]")
+	os.Exit(2)
+}
+
+// setup creates n private synthetic heartbeat monitors scattered over Kyiv.
+func setup(ctx context.Context, db *database.DB, n int, baseURL string) error {
+	owner, err := db.UpsertUser(ctx, loadgenTelegramID, "loadgen", "Load test")
+	if err != nil {
+		return fmt.Errorf("owner: %w", err)
+	}
+	existing, err := syntheticMonitors(ctx, db)
+	if err != nil {
+		return err
+	}
+	// Monitors from older setups were created public.
+	for _, m := range existing {
+		if m.IsPublic {
+			if err := db.SetMonitorPublic(ctx, m.ID, false); err != nil {
+				return fmt.Errorf("hide monitor %d: %w", m.ID, err)
+			}
+		}
+	}
+	for i := range n {
+		name := fmt.Sprintf("%s%05d", namePrefix, len(existing)+i+1)
+		lat := 50.35 + rand.Float64()*0.2
+		lng := 30.35 + rand.Float64()*0.4
+		if _, err := db.CreateSyntheticMonitor(ctx, owner.ID, name, lat, lng, baseURL); err != nil {
+			return fmt.Errorf("create %s: %w", name, err)
+		}
+	}
+	log.Printf("created %d synthetic monitors (%d in total)", n, len(existing)+n)
+	return nil
+}
+
+// cleanup deletes every synthetic monitor.
+func cleanup(ctx context.Context, db *database.DB) error {
+	monitors, err := syntheticMonitors(ctx, db)
+	if err != nil {
+		return err
+	}
+	for _, m := range monitors {
+		if err := db.DeleteMonitor(ctx, m.ID); err != nil {
+			return fmt.Errorf("delete monitor %d: %w", m.ID, err)
+		}
+	}
+	log.Printf("deleted %d synthetic monitors", len(monitors))
+	return nil
+}
+
+// syntheticMonitors returns the monitors created by setup.
+func syntheticMonitors(ctx context.Context, db *database.DB) ([]*models.Monitor, error) {
+	monitors, err := db.GetMonitorsByTelegramID(ctx, loadgenTelegramID)
+	if err != nil {
+		return nil, err
+	}
+	out := monitors[:0]
+	for _, m := range monitors {
+		if m.ChannelID == 0 && len(m.Name) > len(namePrefix) && m.Name[:len(namePrefix)] == namePrefix {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/models"
+)
+
+// statsInterval is how often run logs its counters.
+const statsInterval = 10 * time.Second
+
+type runOptions struct {
+	apiURL   string
+	interval time.Duration
+	duration time.Duration
+	wave     *wave
+	speed    float64
+}
+
+// wave is a recorded outage wave: the offline windows of real monitors.
+type wave struct {
+	from, to time.Time
+	windows  [][]window // one entry per real monitor that went offline
+}
+
+type window struct{ start, end time.Time }
+
+// offline reports whether the i-th synthetic monitor is offline at t, by
+// mirroring one of the real monitors in the wave.
+func (w *wave) offline(i int, t time.Time) bool {
+	for _, win := range w.windows[i%len(w.windows)] {
+		if !t.Before(win.start) && t.Before(win.end) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadWave reads the offline windows recorded between from and to.
+func loadWave(ctx context.Context, db *database.DB, from, to string) (*wave, error) {
+	start, err := time.Parse(time.RFC3339, from)
+	if err != nil {
+		return nil, fmt.Errorf("replay-from: %w", err)
+	}
+	end, err := time.Parse(time.RFC3339, to)
+	if err != nil {
+		return nil, fmt.Errorf("replay-to: %w", err)
+	}
+	if !end.After(start) {
+		return nil, fmt.Errorf("replay-to must be after replay-from")
+	}
+	events, err := db.GetStatusEventsBetween(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	byMonitor := map[int64][]*models.StatusEvent{}
+	for _, e := range events {
+		byMonitor[e.MonitorID] = append(byMonitor[e.MonitorID], e)
+	}
+	ids := make([]int64, 0, len(byMonitor))
+	for id := range byMonitor {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	w := &wave{from: start, to: end}
+	for _, id := range ids {
+		var wins []window
+		var offSince *time.Time
+		for i, e := range byMonitor[id] {
+			switch {
+			case !e.IsOnline && offSince == nil:
+				t := e.Timestamp
+				offSince = &t
+			case e.IsOnline && offSince != nil:
+				wins = append(wins, window{*offSince, e.Timestamp})
+				offSince = nil
+			case e.IsOnline && i == 0:
+				// Already offline when the wave starts.
+				wins = append(wins, window{start, e.Timestamp})
+			}
+		}
+		if offSince != nil {
+			wins = append(wins, window{*offSince, end})
+		}
+		if len(wins) > 0 {
+			w.windows = append(w.windows, wins)
+		}
+	}
+	if len(w.windows) == 0 {
+		return nil, fmt.Errorf("no outages recorded between %s and %s", from, to)
+	}
+	log.Printf("replaying %d outage timelines from %s to %s", len(w.windows), from, to)
+	return w, nil
+}
+
+type counters struct {
+	sent, failed, silent atomic.Int64
+	latencyNs            atomic.Int64
+}
+
+// run pings every monitor once per interval, spread evenly over the interval,
+// until ctx is cancelled or the duration or replayed wave ends.
+func run(ctx context.Context, monitors []*models.Monitor, opts runOptions) {
+	if opts.duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.duration)
+		defer cancel()
+	}
+	if opts.wave != nil {
+		if opts.speed <= 0 {
+			opts.speed = 1
+		}
+		length := time.Duration(float64(opts.wave.to.Sub(opts.wave.from)) / opts.speed)
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, length)
+		defer cancel()
+		log.Printf("replay takes %s at %gx", length.Round(time.Second), opts.speed)
+	}
+
+	client := &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &http.Transport{MaxIdleConnsPerHost: 256},
+	}
+	base := strings.TrimRight(opts.apiURL, "/")
+	started := time.Now()
+	var c counters
+
+	var wg sync.WaitGroup
+	for i, m := range monitors {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Spread the first pings over the interval like real devices.
+			phase := time.Duration(rand.Int64N(int64(opts.interval)))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(phase):
+			}
+			ticker := time.NewTicker(opts.interval)
+			defer ticker.Stop()
+			for {
+				if opts.wave != nil && opts.wave.offline(i, opts.wave.from.Add(time.Duration(float64(time.Since(started))*opts.speed))) {
+					c.silent.Add(1)
+				} else {
+					ping(ctx, client, base+"/api/ping/"+m.Token, &c)
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+	}
+
+	log.Printf("pinging %d monitors every %s at %s", len(monitors), opts.interval, base)
+	ticker := time.NewTicker(statsInterval)
+	defer ticker.Stop()
+	done := make(chan struct{})
+	go func() { wg.Wait(); close(done) }()
+	for {
+		select {
+		case <-done:
+			logStats(&c)
+			return
+		case <-ticker.C:
+			logStats(&c)
+		}
+	}
+}
+
+func ping(ctx context.Context, client *http.Client, url string, c *counters) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		c.failed.Add(1)
+		return
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() == nil {
+			c.failed.Add(1)
+		}
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		c.failed.Add(1)
+		return
+	}
+	c.sent.Add(1)
+	c.latencyNs.Add(int64(time.Since(start)))
+}
+
+func logStats(c *counters) {
+	sent := c.sent.Load()
+	avg := time.Duration(0)
+	if sent > 0 {
+		avg = time.Duration(c.latencyNs.Load() / sent)
+	}
+	log.Printf("pings ok=%d failed=%d withheld=%d avg_latency=%s", sent, c.failed.Load(), c.silent.Load(), avg.Round(time.Millisecond))
+}
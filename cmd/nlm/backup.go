@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"no-lights-monitor/internal/backup"
+	"no-lights-monitor/internal/bootstrap"
+	"no-lights-monitor/internal/config"
+	"no-lights-monitor/internal/database"
+)
+
+// tools are the one-shot operator subcommands.
+var tools = map[string]func(ctx context.Context, cfg *config.Config, args []string) error{
+	"backup":    runBackup,
+	"backups":   listBackups,
+	"restore":   runRestore,
+	"recompute": runRecompute,
+}
+
+var errNoBackupStore = errors.New("BACKUP_S3_BUCKET is not set")
+
+// runBackup takes a backup right away, like the worker's daily job.
+func runBackup(ctx context.Context, cfg *config.Config, _ []string) error {
+	store := bootstrap.BackupStore(cfg)
+	if store == nil {
+		return errNoBackupStore
+	}
+	db := bootstrap.Database(ctx, cfg)
+	defer db.Close()
+	key, err := store.Save(ctx, db, time.Now().AddDate(0, 0, -cfg.BackupEventsDays))
+	if err != nil {
+		return err
+	}
+	fmt.Println(key)
+	return nil
+}
+
+// listBackups prints the stored backups, oldest first.
+func listBackups(ctx context.Context, cfg *config.Config, _ []string) error {
+	store := bootstrap.BackupStore(cfg)
+	if store == nil {
+		return errNoBackupStore
+	}
+	objects, err := store.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, o := range objects {
+		fmt.Printf("%s\t%s\t%d\n", o.Key, o.LastModified.Format(time.RFC3339), o.Size)
+	}
+	return nil
+}
+
+// runRestore restores a backup from a local file if one exists at the given
+// path, or else from the bucket object with that key.
+func runRestore(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: nlm restore <key|file>")
+	}
+	db := bootstrap.Database(ctx, cfg)
+	defer db.Close()
+
+	var counts map[string]int64
+	if f, err := os.Open(args[0]); err == nil {
+		defer f.Close()
+		if counts, err = backup.Restore(ctx, db, f); err != nil {
+			return err
+		}
+	} else {
+		store := bootstrap.BackupStore(cfg)
+		if store == nil {
+			return fmt.Errorf("%s is not a file and %w", args[0], errNoBackupStore)
+		}
+		if counts, err = store.Load(ctx, db, args[0]); err != nil {
+			return err
+		}
+	}
+	for _, t := range database.BackupTables {
+		fmt.Printf("%s: %d rows restored\n", t, counts[t])
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+// Command nlm runs any of the services from one binary:
+//
+//	nlm api | worker | bot | outage   run a single service
+//	nlm serve                         run all four in one process
+//	nlm backup                        take a backup now
+//	nlm backups                       list stored backups
+//	nlm restore <key|file>            restore a stored or downloaded backup
+//	nlm recompute graph|photo [...]   force-regenerate channel graphs or photos
+//
+// Each subcommand behaves like the matching cmd/<service> binary. serve suits
+// small self-hosted setups where one container is simpler than four; the
+// services still talk to each other over RabbitMQ and HTTP as usual.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	apiapp "no-lights-monitor/cmd/api/app"
+	botapp "no-lights-monitor/cmd/bot/app"
+	outageapp "no-lights-monitor/cmd/outage/app"
+	workerapp "no-lights-monitor/cmd/worker/app"
+	"no-lights-monitor/internal/bootstrap"
+	"no-lights-monitor/internal/config"
+	"no-lights-monitor/internal/errsink"
+)
+
+type runFunc func(ctx context.Context, cfg *config.Config)
+
+var services = map[string]runFunc{
+	"api":    apiapp.Run,
+	"worker": workerapp.Run,
+	"bot":    botapp.Run,
+	"outage": outageapp.Run,
+}
+
+// serveOrder lists the services serve runs.
+var serveOrder = []string{"outage", "api", "worker", "bot"}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	name := os.Args[1]
+	run, ok := services[name]
+	_, isTool := tools[name]
+	if !ok && !isTool && name != "serve" {
+		usage()
+	}
+
+	cfg := bootstrap.Init(name)
+	defer errsink.Flush(2 * time.Second)
+
+	ctx, stop := bootstrap.SignalContext()
+	defer stop()
+
+	if isTool {
+		if err := tools[name](ctx, cfg, os.Args[2:]); err != nil {
+			log.Fatalf("%s: %v", name, err)
+		}
+		return
+	}
+	if len(os.Args) != 2 {
+		usage()
+	}
+	if name != "serve" {
+		run(ctx, cfg)
+		return
+	}
+
+	// Migrate once up front: the services would otherwise race to create the schema.
+	bootstrap.Database(ctx, cfg).Close()
+
+	var wg sync.WaitGroup
+	for _, svc := range serveOrder {
+		wg.Add(1)
+		go func(run runFunc) {
+			defer wg.Done()
+			run(ctx, cfg)
+		}(services[svc])
+	}
+	log.Printf("serving %v in one process", serveOrder)
+	wg.Wait()
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: nlm serve|api|worker|bot|outage|backup|backups|restore|recompute")
+	os.Exit(2)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"no-lights-monitor/internal/bootstrap"
+	"no-lights-monitor/internal/config"
+	"no-lights-monitor/internal/mq"
+	"no-lights-monitor/internal/recompute"
+)
+
+const recomputeUsage = "usage: nlm recompute graph|photo [-region R] [-group G] [-dry-run] [monitor_id...]"
+
+// runRecompute queues a forced regeneration of graphs or outage photos for the
+// selected monitors, like the admin panel's recompute action. A running worker
+// picks the requests up at a throttled pace.
+func runRecompute(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) < 1 {
+		return errors.New(recomputeUsage)
+	}
+	kind := mq.RecomputeKind(args[0])
+	if !recompute.ValidKind(kind) {
+		return errors.New(recomputeUsage)
+	}
+
+	fs := flag.NewFlagSet("recompute", flag.ContinueOnError)
+	region := fs.String("region", "", "only monitors in this outage region")
+	group := fs.String("group", "", "only monitors in this outage group")
+	dryRun := fs.Bool("dry-run", false, "list the matching monitors without queueing")
+	if err := fs.Parse(args[1:]); err != nil {
+		return errors.New(recomputeUsage)
+	}
+	var ids []int64
+	for _, a := range fs.Args() {
+		id, err := strconv.ParseInt(a, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid monitor id %q", a)
+		}
+		ids = append(ids, id)
+	}
+
+	db := bootstrap.Database(ctx, cfg)
+	defer db.Close()
+	pub := bootstrap.Publisher(cfg)
+	defer pub.Close()
+
+	report, err := recompute.Enqueue(ctx, db, pub, recompute.Options{
+		Kind:       kind,
+		MonitorIDs: ids,
+		Region:     *region,
+		Group:      *group,
+		DryRun:     *dryRun,
+	})
+	if err != nil {
+		return err
+	}
+	for _, id := range report.MonitorIDs {
+		fmt.Println(id)
+	}
+	verb := "queued"
+	if report.DryRun {
+		verb = "matched"
+	}
+	fmt.Printf("%s %s recompute for %d monitors\n", verb, kind, len(report.MonitorIDs))
+	return nil
+}
@@ -0,0 +1,75 @@
+// Package app is the outage service: it mirrors the public outage schedule data
+// and serves it to the other services.
+package app
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/logger"
+
+	"no-lights-monitor/internal/config"
+	"no-lights-monitor/internal/safego"
+	"no-lights-monitor/internal/svcauth"
+)
+
+// Run starts the outage service and blocks until ctx is cancelled.
+func Run(ctx context.Context, cfg *config.Config) {
+	// --- Outage data fetcher ---
+	fetcher := newFetcher(cfg.OutageFetchInterval, time.Duration(cfg.OutageStaleAfterMin)*time.Minute, cfg.OutageForecastURL)
+	safego.Go("outage_fetcher", func() { fetcher.Start(ctx) })
+	log.Printf("outage fetcher started (interval: %ds, stale after: %dm)", cfg.OutageFetchInterval, cfg.OutageStaleAfterMin)
+
+	// --- Fiber HTTP Server ---
+	app := fiber.New(fiber.Config{
+		DisableStartupMessage: true,
+	})
+
+	app.Use(logger.New(logger.Config{
+		Format: "${time} ${status} ${method} ${path} ${latency}\n",
+	}))
+	app.Use(cors.New())
+
+	// Outage API routes. Only the other services call them; with
+	// INTERNAL_AUTH_SECRET set, unsigned requests are rejected.
+	api := app.Group("/api", requireSignature(cfg.InternalAuthSecret))
+	h := &handlers{fetcher: fetcher}
+	h.registerRoutes(api)
+
+	// --- Graceful shutdown ---
+	safego.Go("outage_shutdown", func() {
+		<-ctx.Done()
+		log.Println("shutting down outage service...")
+		_ = app.Shutdown()
+	})
+
+	port := getEnv("OUTAGE_PORT", "8090")
+	log.Printf("outage service starting on :%s", port)
+	if err := app.Listen(":" + port); err != nil {
+		log.Fatalf("server: %v", err)
+	}
+}
+
+// requireSignature verifies the svcauth signature of every request.
+func requireSignature(secret string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := svcauth.Verify(secret, c.Method(), string(c.Request().RequestURI()), c.Body(),
+			c.Get(svcauth.HeaderTimestamp), c.Get(svcauth.HeaderSignature))
+		if err != nil {
+			log.Printf("[auth] rejected %s %s from %s: %v", c.Method(), c.OriginalURL(), c.IP(), err)
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+		}
+		return c.Next()
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return fallback
+}
@@ -0,0 +1,276 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"no-lights-monitor/internal/outage"
+)
+
+const (
+	rawBaseURL   = "https://raw.githubusercontent.com/Baskerville42/outage-data-ua/main/data"
+	rawImagesURL = "https://raw.githubusercontent.com/Baskerville42/outage-data-ua/refs/heads/main/images"
+)
+
+var supportedRegions = []string{"kyiv", "kyiv-region", "odesa", "dnipro"}
+
+// historyDays is how many days of fact data are kept for schedule statistics.
+// The history lives in memory only and refills after a restart.
+const historyDays = 14
+
+// dayFact is one day of fact data: group ID -> hour (1-24) -> status.
+type dayFact map[string]map[string]string
+
+// Fetcher periodically fetches outage data from GitHub and stores it in memory.
+type Fetcher struct {
+	client     *http.Client
+	interval   time.Duration
+	staleAfter time.Duration // 0 = never stale
+
+	forecastURL string // outage forecast feed ("" = no forecasts)
+
+	mu        sync.RWMutex
+	data      map[string]*outage.RegionData // keyed by regionId
+	history   map[string]map[int64]dayFact  // regionId -> day (unix, Kyiv midnight) -> fact
+	forecasts []outage.Forecast             // latest forecast feed entries
+}
+
+func newFetcher(intervalSec int, staleAfter time.Duration, forecastURL string) *Fetcher {
+	return &Fetcher{
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		interval:    time.Duration(intervalSec) * time.Second,
+		staleAfter:  staleAfter,
+		forecastURL: forecastURL,
+		data:        make(map[string]*outage.RegionData),
+		history:     make(map[string]map[int64]dayFact),
+	}
+}
+
+// age returns how long ago the upstream last updated rd, or -1 when its
+// lastUpdated can't be parsed.
+func (f *Fetcher) age(rd *outage.RegionData, now time.Time) time.Duration {
+	t, ok := outage.ParseLastUpdated(rd.LastUpdated)
+	if !ok {
+		return -1
+	}
+	return now.Sub(t)
+}
+
+// isStale reports whether rd is older than staleAfter. Data with an
+// unparseable timestamp isn't flagged; the regions list reports its age as -1.
+func (f *Fetcher) isStale(rd *outage.RegionData, now time.Time) bool {
+	return f.staleAfter > 0 && f.age(rd, now) > f.staleAfter
+}
+
+// Start begins periodic fetching. It performs an initial fetch immediately,
+// then fetches every interval. Blocks until ctx is cancelled.
+func (f *Fetcher) Start(ctx context.Context) {
+	f.fetchAll()
+
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			f.fetchAll()
+		}
+	}
+}
+
+func (f *Fetcher) fetchAll() {
+	log.Printf("[outage] fetching data for %d regions...", len(supportedRegions))
+	for _, region := range supportedRegions {
+		if err := f.fetchRegion(region); err != nil {
+			log.Printf("[outage] failed to fetch %s: %v", region, err)
+		}
+	}
+	if f.forecastURL != "" {
+		if err := f.fetchForecasts(); err != nil {
+			log.Printf("[outage] failed to fetch forecasts: %v", err)
+		}
+	}
+}
+
+// fetchForecasts replaces the stored forecasts with the current feed. On
+// failure the previous forecasts are kept; they only match their own day.
+func (f *Fetcher) fetchForecasts() error {
+	resp, err := f.client.Get(f.forecastURL)
+	if err != nil {
+		return fmt.Errorf("GET forecasts: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET forecasts: status %d", resp.StatusCode)
+	}
+
+	var feed outage.ForecastFeed
+	if err := json.NewDecoder(resp.Body).Decode(&feed); err != nil {
+		return fmt.Errorf("decode forecasts: %w", err)
+	}
+
+	f.mu.Lock()
+	f.forecasts = feed.Forecasts
+	f.mu.Unlock()
+	log.Printf("[outage] updated forecasts (%d entries, updated: %s)", len(feed.Forecasts), feed.Updated)
+	return nil
+}
+
+func (f *Fetcher) fetchRegion(region string) error {
+	url := fmt.Sprintf("%s/%s.json", rawBaseURL, region)
+
+	resp, err := f.client.Get(url)
+	if err != nil {
+		return fmt.Errorf("GET %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", url, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read body: %w", err)
+	}
+
+	var rd outage.RegionData
+	if err := json.Unmarshal(body, &rd); err != nil {
+		return fmt.Errorf("unmarshal %s: %w", region, err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Skip if data hasn't changed.
+	if existing, ok := f.data[region]; ok && existing.LastUpdated == rd.LastUpdated {
+		log.Printf("[outage] %s unchanged (lastUpdated: %s, factUpdate: %s, today: %d)",
+			region, rd.LastUpdated, rd.Fact.Update, rd.Fact.Today)
+		return nil
+	}
+
+	f.data[region] = &rd
+	f.remember(region, &rd)
+	log.Printf("[outage] updated %s (lastUpdated: %s, factUpdate: %s, today: %d)",
+		region, rd.LastUpdated, rd.Fact.Update, rd.Fact.Today)
+	return nil
+}
+
+// remember merges the days of rd into the region's history, dropping days
+// older than historyDays. Later fetches of a day replace earlier ones, so a
+// past day keeps its final schedule. Must be called with f.mu held.
+func (f *Fetcher) remember(region string, rd *outage.RegionData) {
+	days := f.history[region]
+	if days == nil {
+		days = make(map[int64]dayFact)
+		f.history[region] = days
+	}
+	for key, groups := range rd.Fact.Data {
+		day, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			continue
+		}
+		days[day] = groups
+	}
+	cutoff := time.Now().AddDate(0, 0, -historyDays).Unix()
+	for day := range days {
+		if day < cutoff {
+			delete(days, day)
+		}
+	}
+}
+
+// getHistory returns the region's remembered days that start in [from, to).
+func (f *Fetcher) getHistory(region string, from, to time.Time) map[int64]dayFact {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	result := make(map[int64]dayFact)
+	for day, fact := range f.history[region] {
+		if day >= from.Unix() && day < to.Unix() {
+			result[day] = fact
+		}
+	}
+	return result
+}
+
+// getForecast returns today's forecast feed entry for region, or nil when
+// there is none (including when no feed is configured).
+func (f *Fetcher) getForecast(region string, now time.Time) *outage.Forecast {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return outage.PickForecast(f.forecasts, region, now)
+}
+
+func (f *Fetcher) getRegionData(region string) *outage.RegionData {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.data[region]
+}
+
+func (f *Fetcher) getAllRegions() []outage.RegionInfo {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	now := time.Now()
+	result := make([]outage.RegionInfo, 0, len(f.data))
+	for _, rd := range f.data {
+		ageSec := int64(-1)
+		if age := f.age(rd, now); age >= 0 {
+			ageSec = int64(age.Seconds())
+		}
+		result = append(result, outage.RegionInfo{
+			RegionID:    rd.RegionID,
+			LastUpdated: rd.LastUpdated,
+			AgeSec:      ageSec,
+			Stale:       f.isStale(rd, now),
+		})
+	}
+	return result
+}
+
+// getPhoto proxies a photo request to GitHub, forwarding the If-None-Match header.
+// Returns (nil, "", true, nil) when the image is unchanged (304 Not Modified).
+func (f *Fetcher) getPhoto(region, filename, ifNoneMatch string) (data []byte, etag string, notModified bool, err error) {
+	imageURL := fmt.Sprintf("%s/%s/%s", rawImagesURL, region, filename)
+
+	req, err := http.NewRequest(http.MethodGet, imageURL, nil)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("build request: %w", err)
+	}
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("GET %s: %w", imageURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, "", true, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", false, fmt.Errorf("GET %s: status %d", imageURL, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("read body: %w", err)
+	}
+
+	return body, resp.Header.Get("ETag"), false, nil
+}
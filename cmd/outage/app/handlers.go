@@ -1,9 +1,10 @@
-package main
+package app
 
 import (
 	"fmt"
 	"sort"
 	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 
@@ -19,6 +20,8 @@ func (h *handlers) registerRoutes(api fiber.Router) {
 	g.Get("/regions", h.getRegions)
 	g.Get("/:region/groups", h.getGroups)
 	g.Get("/:region", h.getRegionFact)
+	g.Get("/:region/scheduled", h.getScheduledHours)
+	g.Get("/:region/forecast", h.getForecast)
 	g.Get("/:region/:group/photo", h.getGroupPhoto)
 	g.Get("/:region/:group", h.getGroupFact)
 }
@@ -90,9 +93,63 @@ func (h *handlers) getRegionFact(c *fiber.Ctx) error {
 		LastUpdated: rd.LastUpdated,
 		FactUpdate:  rd.Fact.Update,
 		Groups:      dayData,
+		Stale:       h.fetcher.isStale(rd, time.Now()),
 	})
 }
 
+// maxScheduledDays caps the ?days window of getScheduledHours.
+const maxScheduledDays = historyDays
+
+// getScheduledHours sums the scheduled outage hours of every group over the
+// last ?days (default 7) complete Kyiv days the service has data for.
+func (h *handlers) getScheduledHours(c *fiber.Ctx) error {
+	region := c.Params("region")
+	days := c.QueryInt("days", 7)
+	if days < 1 || days > maxScheduledDays {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("days must be between 1 and %d", maxScheduledDays),
+		})
+	}
+	if h.fetcher.getRegionData(region) == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": fmt.Sprintf("region %q not found", region),
+		})
+	}
+
+	kyiv, _ := time.LoadLocation("Europe/Kyiv")
+	now := time.Now().In(kyiv)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, kyiv)
+	from := to.AddDate(0, 0, -days)
+	history := h.fetcher.getHistory(region, from, to)
+
+	result := outage.ScheduledHours{Region: region, Days: []string{}, Groups: map[string]float64{}}
+	dayKeys := make([]int64, 0, len(history))
+	for day := range history {
+		dayKeys = append(dayKeys, day)
+	}
+	sort.Slice(dayKeys, func(i, j int) bool { return dayKeys[i] < dayKeys[j] })
+	for _, day := range dayKeys {
+		result.Days = append(result.Days, time.Unix(day, 0).In(kyiv).Format("2006-01-02"))
+		for group, hours := range history[day] {
+			result.Groups[group] += outage.OffHours(hours)
+		}
+	}
+	return c.JSON(result)
+}
+
+// getForecast returns today's outage forecast for a region, falling back to
+// the country-wide one.
+func (h *handlers) getForecast(c *fiber.Ctx) error {
+	region := c.Params("region")
+	f := h.fetcher.getForecast(region, time.Now())
+	if f == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": fmt.Sprintf("no forecast for region %q today", region),
+		})
+	}
+	return c.JSON(f)
+}
+
 func (h *handlers) getGroupFact(c *fiber.Ctx) error {
 	region := c.Params("region")
 	group := c.Params("group")
@@ -126,6 +183,7 @@ func (h *handlers) getGroupFact(c *fiber.Ctx) error {
 		LastUpdated: rd.LastUpdated,
 		FactUpdate:  rd.Fact.Update,
 		Hours:       hours,
+		Stale:       h.fetcher.isStale(rd, time.Now()),
 	})
 }
 
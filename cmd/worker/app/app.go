@@ -0,0 +1,298 @@
+// Package app is the worker service: it tracks heartbeats and pings, detects
+// status changes and runs the periodic jobs.
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"no-lights-monitor/cmd/worker/archive"
+	"no-lights-monitor/cmd/worker/backups"
+	"no-lights-monitor/cmd/worker/canary"
+	"no-lights-monitor/cmd/worker/dtek"
+	"no-lights-monitor/cmd/worker/flapnotify"
+	"no-lights-monitor/cmd/worker/graph"
+	"no-lights-monitor/cmd/worker/groupstats"
+	"no-lights-monitor/cmd/worker/heartbeat"
+	"no-lights-monitor/cmd/worker/heatmap"
+	"no-lights-monitor/cmd/worker/inactivity"
+	"no-lights-monitor/cmd/worker/incident"
+	"no-lights-monitor/cmd/worker/intervalhint"
+	"no-lights-monitor/cmd/worker/mute"
+	"no-lights-monitor/cmd/worker/outagefreshness"
+	"no-lights-monitor/cmd/worker/outagephoto"
+	"no-lights-monitor/cmd/worker/outageprealert"
+	"no-lights-monitor/cmd/worker/outagesummary"
+	"no-lights-monitor/cmd/worker/ownerdigest"
+	"no-lights-monitor/cmd/worker/plannedoutage"
+	"no-lights-monitor/cmd/worker/pushnotify"
+	"no-lights-monitor/cmd/worker/recompute"
+	"no-lights-monitor/cmd/worker/smsnotify"
+	"no-lights-monitor/cmd/worker/surge"
+	"no-lights-monitor/cmd/worker/testdrive"
+	"no-lights-monitor/cmd/worker/webhooknotify"
+	"no-lights-monitor/internal/bootstrap"
+	"no-lights-monitor/internal/config"
+	"no-lights-monitor/internal/errsink"
+	"no-lights-monitor/internal/health"
+	"no-lights-monitor/internal/mq"
+	"no-lights-monitor/internal/outage"
+	"no-lights-monitor/internal/push"
+	"no-lights-monitor/internal/safego"
+	"no-lights-monitor/internal/scheduler"
+	"no-lights-monitor/internal/sms"
+)
+
+const (
+	// HeartbeatCheckIntervalSec is how often we check for stale heartbeats.
+	HeartbeatCheckIntervalSec = 15
+	// PingCheckIntervalSec is how often we ICMP-ping targets for ping monitors.
+	PingCheckIntervalSec = 60
+	// HTTPCheckIntervalSec is how often we GET the URLs of http monitors.
+	HTTPCheckIntervalSec = 60
+)
+
+// Run starts the worker service and blocks until ctx is cancelled.
+func Run(ctx context.Context, cfg *config.Config) {
+	// --- Database ---
+	db := bootstrap.Database(ctx, cfg)
+	defer db.Close()
+
+	// --- Redis ---
+	redisCache := bootstrap.Redis(cfg)
+	defer redisCache.Close()
+
+	// --- RabbitMQ ---
+	publisher := bootstrap.Publisher(cfg)
+	defer publisher.Close()
+
+	consumer := bootstrap.Consumer(cfg)
+	defer consumer.Close()
+	log.Println("rabbitmq connected")
+
+	// --- Canary monitor (synthetic end-to-end pipeline check) ---
+	var canaryDriver *canary.Driver
+	if cfg.CanaryChannelID != 0 {
+		canaryDriver = canary.NewDriver(db, redisCache, cfg.BaseURL, cfg.CanaryChannelID, time.Duration(cfg.CanaryPeriodMin)*time.Minute)
+		if err := canaryDriver.Setup(ctx); err != nil {
+			log.Fatalf("canary: %v", err)
+		}
+	}
+
+	// --- Health server ---
+	health.ServeAsync(func() error {
+		if err := db.Pool.Ping(ctx); err != nil {
+			return err
+		}
+		if canaryDriver != nil {
+			return canaryDriver.Check(ctx)
+		}
+		return nil
+	})
+
+	// --- Outage photo updater (used by the scheduler and the outage-start trigger) ---
+	outageClient := outage.NewClient(cfg.OutageServiceURL, cfg.InternalAuthSecret, cfg.OutagePolicy())
+	photoUpdater := outagephoto.NewUpdater(db, outagephoto.NewMQDelivery(publisher), outageClient)
+
+	// --- Heartbeat Service ---
+	// Optional SMS copies of published status changes.
+	var published heartbeat.Notifier = mq.NewStatusNotifier(publisher)
+	smsProvider, err := sms.NewProvider(cfg.SMSProvider, cfg.SMSToken, cfg.SMSSender)
+	if err != nil {
+		log.Fatalf("sms: %v", err)
+	}
+	if smsProvider != nil && cfg.Sandbox {
+		smsProvider = sms.Sandbox(smsProvider)
+	}
+	if smsProvider != nil {
+		smsNotifier := smsnotify.New(published, db, smsProvider, cfg.SMSMonthlyQuota, cfg.SMSMonthlyBudget)
+		safego.Go("sms", func() { smsNotifier.Run(ctx) })
+		published = smsNotifier
+		log.Printf("sms notifications via %s enabled", smsProvider.Name())
+	}
+	// Optional companion app pushes.
+	pushSenders, err := push.NewSenders(cfg.PushConfig())
+	if err != nil {
+		log.Fatalf("push: %v", err)
+	}
+	if cfg.Sandbox {
+		pushSenders = push.Sandbox(pushSenders)
+	}
+	if len(pushSenders) > 0 {
+		pushNotifier := pushnotify.New(published, db, pushSenders)
+		safego.Go("push", func() { pushNotifier.Run(ctx) })
+		published = pushNotifier
+		log.Printf("push notifications enabled for %d platforms", len(pushSenders))
+	}
+	// Owner webhooks.
+	webhookNotifier := webhooknotify.New(published, db)
+	webhookNotifier.SetSandbox(cfg.Sandbox)
+	safego.Go("webhook", func() { webhookNotifier.Run(ctx) })
+	powerReturn := webhooknotify.NewPowerReturn(db)
+	powerReturn.SetSandbox(cfg.Sandbox)
+	safego.Go("power_return", func() { powerReturn.Run(ctx) })
+	heartbeatRelay := webhooknotify.NewHeartbeatRelay(db, redisCache)
+	heartbeatRelay.SetSandbox(cfg.Sandbox)
+	safego.Go("webhook_heartbeat", func() { heartbeatRelay.Run(ctx) })
+	published = webhookNotifier
+	// Schedule-predicted changes are downgraded per monitor before publishing.
+	var notifier heartbeat.Notifier = photoUpdater.WrapNotifier(plannedoutage.NewFilter(published, outageClient))
+	// Outage waves: batch notifications while transitions spike.
+	if cfg.SurgeTransitions > 0 {
+		surgeMode := surge.New(notifier, redisCache, cfg.SurgeTransitions)
+		safego.Go("surge", func() { surgeMode.Run(ctx) })
+		notifier = surgeMode
+	}
+	hbService := heartbeat.NewService(db, redisCache, notifier, cfg.OfflineThreshold, heartbeat.Limits{
+		Ping:      cfg.PingConcurrency,
+		HTTP:      cfg.HTTPCheckConcurrency,
+		DBWrite:   cfg.DBWriteConcurrency,
+		MQPublish: cfg.MQPublishConcurrency,
+	})
+	hbService.SetPowerReturn(powerReturn)
+	// Votes from remote probe agents count for two ping rounds.
+	hbService.SetVantage(cfg.ProbeVantage, 2*PingCheckIntervalSec*time.Second)
+	// Flapping monitors get one "unstable power" notice instead of a message per change.
+	if cfg.FlapMaxChanges > 0 {
+		hbService.SetFlapDetection(cfg.FlapMaxChanges, time.Duration(cfg.FlapWindowMin)*time.Minute, flapnotify.New(publisher))
+	}
+
+	if err := hbService.LoadMonitors(ctx); err != nil {
+		log.Fatalf("load monitors: %v", err)
+	}
+
+	// --- Start heartbeat, ping and http checkers ---
+	safego.Go("heartbeat_checker", func() { hbService.StartHeartbeatChecker(ctx, HeartbeatCheckIntervalSec) })
+	safego.Go("settings_watch", func() { hbService.WatchSettings(ctx) })
+	safego.Go("ping_checker", func() { hbService.StartPingChecker(ctx, PingCheckIntervalSec) })
+	safego.Go("http_checker", func() { hbService.StartHTTPChecker(ctx, HTTPCheckIntervalSec) })
+
+	// --- Periodic jobs ---
+	kyiv, err := time.LoadLocation("Europe/Kyiv")
+	if err != nil {
+		log.Fatalf("load Europe/Kyiv timezone: %v", err)
+	}
+	sched := scheduler.New(db, kyiv)
+	sched.SetHeartbeats(redisCache)
+
+	// Uptime and ping latency graphs (hourly) + on-demand requests from the bot.
+	graphClient := graph.NewClient(cfg.GraphServiceURL, cfg.InternalAuthSecret, cfg.GraphPolicy())
+	graphUpdater := graph.NewUpdater(db, graphClient, publisher)
+	safego.Go("graph_requests", func() { graphUpdater.ListenRequests(ctx, consumer) })
+	mustRegister(sched, scheduler.Job{Name: "graph", Spec: "@hourly", Timeout: 55 * time.Minute, StartDelay: 30 * time.Second, Run: surge.Postpone(redisCache, "graph", graphUpdater.RunAll)})
+	mustRegister(sched, scheduler.Job{Name: "latency_prune", Spec: "40 4 * * *", Run: graphUpdater.PruneLatencySamples})
+
+	// Admin test-drive: replays a monitor's notifications into a sandbox channel.
+	testDrive := testdrive.NewRunner(db, publisher, graphUpdater, photoUpdater)
+	safego.Go("testdrive", func() { testDrive.Listen(ctx, consumer) })
+
+	// Admin batch recompute: force-regenerates graphs or photos, throttled.
+	recomputer := recompute.NewRunner(db, graphUpdater, photoUpdater)
+	safego.Go("recompute", func() { recomputer.Listen(ctx, consumer) })
+
+	// Outage schedule photos (hourly, offset from graphs).
+	mustRegister(sched, scheduler.Job{Name: "outage_photo", Spec: "10 * * * *", Timeout: 30 * time.Minute, StartDelay: 60 * time.Second, Run: surge.Postpone(redisCache, "outage_photo", photoUpdater.RunAll)})
+
+	// Daily outage schedule text summaries (sent once per day at each monitor's configured time).
+	summarySender := outagesummary.NewSender(db, publisher, outageClient)
+	mustRegister(sched, scheduler.Job{Name: "outage_summary", Spec: "*/5 * * * *", Run: summarySender.Run})
+
+	// Heads-up shortly before scheduled outage windows (opt-in per monitor).
+	preAlerter := outageprealert.NewAlerter(db, publisher, outageClient)
+	mustRegister(sched, scheduler.Job{Name: "outage_prealert", Spec: "@every 1m", Run: preAlerter.Run})
+
+	// Canary pings and pipeline verdict.
+	if canaryDriver != nil {
+		mustRegister(sched, scheduler.Job{Name: "canary", Spec: "@every 1m", Run: canaryDriver.Run})
+	}
+
+	// Stale-device hints: warn owners whose devices ping too rarely for their threshold.
+	hintChecker := intervalhint.NewChecker(db, redisCache, publisher, cfg.OfflineThreshold)
+	mustRegister(sched, scheduler.Job{Name: "interval_hints", Spec: "25 * * * *", Run: hintChecker.Run})
+
+	// Mass-outage incidents, posted anonymized to city aggregate channels.
+	if cities := incident.ParseCities(cfg.CityChannels); len(cities) > 0 {
+		detector := incident.NewDetector(db, redisCache, publisher, cities, cfg.IncidentMinMonitors, time.Duration(cfg.IncidentPostInterval)*time.Minute)
+		mustRegister(sched, scheduler.Job{Name: "incidents", Spec: "@every 1m", Run: detector.Run})
+
+		// Weekly measured vs scheduled outage hours per group (Mondays 10:00 Kyiv).
+		reporter := groupstats.NewReporter(db, publisher, outageClient, cities, cfg.IncidentMinMonitors)
+		mustRegister(sched, scheduler.Job{Name: "group_stats", Spec: "0 10 * * 1", Run: reporter.Run})
+	}
+
+	// Heat layer tiles for the public map (offline density per area).
+	heatBuilder := heatmap.NewBuilder(db, redisCache)
+	mustRegister(sched, scheduler.Job{Name: "heatmap", Spec: "@every 1m", Run: heatBuilder.Run})
+
+	// Owner mutes (/mute): unmute and tell the channel once the time is up.
+	muteExpirer := mute.NewExpirer(db, publisher)
+	mustRegister(sched, scheduler.Job{Name: "mute_expiry", Spec: "@every 1m", Run: muteExpirer.Run})
+
+	// Daily problem digest for owners of many monitors (10:00 Kyiv).
+	digester := ownerdigest.NewDigester(db, publisher, cfg.BaseURL)
+	mustRegister(sched, scheduler.Job{Name: "owner_digest", Spec: "0 10 * * *", Run: digester.Run})
+
+	// Inactivity checker (daily at 13:00 Kyiv).
+	inactivityChecker := inactivity.NewChecker(db, publisher)
+	mustRegister(sched, scheduler.Job{Name: "inactivity", Spec: "0 13 * * *", Run: inactivityChecker.Run})
+
+	// DTEK unplanned outage poller.
+	if cfg.DtekServiceURL != "" {
+		dtekPoller := dtek.NewPoller(db, publisher, cfg.DtekServiceURL)
+		spec := fmt.Sprintf("@every %ds", cfg.DtekPollInterval)
+		mustRegister(sched, scheduler.Job{Name: "dtek_poll", Spec: spec, Lease: time.Duration(cfg.DtekPollInterval) * time.Second, Run: dtekPoller.Run})
+	}
+
+	// Daily logical backup to an S3-compatible bucket.
+	if store := bootstrap.BackupStore(cfg); store != nil {
+		days := func(n int) time.Duration { return time.Duration(n) * 24 * time.Hour }
+		backupRunner := backups.NewRunner(db, store, days(cfg.BackupEventsDays), days(cfg.BackupRetentionDays))
+		mustRegister(sched, scheduler.Job{Name: "backup", Spec: "30 3 * * *", Run: backupRunner.Run})
+
+		// Long-deleted monitors: export their history, then purge them (04:30 Kyiv).
+		if cfg.ArchiveAfterDays > 0 {
+			archiver := archive.NewArchiver(db, store.Bucket, cfg.ArchivePrefix, days(cfg.ArchiveAfterDays))
+			mustRegister(sched, scheduler.Job{Name: "archive", Spec: "30 4 * * *", Run: archiver.Run})
+		}
+	}
+
+	// Upstream outage data freshness, reported to the ops channel.
+	freshness := outagefreshness.NewChecker(outageClient, publisher, cfg.CanaryChannelID)
+	mustRegister(sched, scheduler.Job{Name: "outage_freshness", Spec: "*/10 * * * *", Run: freshness.Run})
+
+	// Watchdog: alerts operators when a job stops completing its runs.
+	mustRegister(sched, sched.Watchdog("*/5 * * * *", cfg.WatchdogMissedRuns, stallAlert(publisher, cfg.CanaryChannelID, kyiv)))
+
+	sched.Start(ctx)
+	log.Println("scheduler started")
+
+	<-ctx.Done()
+	log.Println("shutting down worker...")
+}
+
+// stallAlert reports a stalled job to the error sink and, if configured, to
+// the ops channel the canary monitor posts to.
+func stallAlert(publisher *mq.Publisher, opsChannelID int64, loc *time.Location) scheduler.StallAlert {
+	return func(ctx context.Context, job string, lastOK time.Time) {
+		last := "never"
+		if !lastOK.IsZero() {
+			last = lastOK.In(loc).Format("02.01 15:04")
+		}
+		errsink.CaptureMessage("scheduled job stalled: "+job, errsink.Fields{"component": "scheduler", "job": job, "last_success": last})
+		if opsChannelID == 0 {
+			return
+		}
+		text := fmt.Sprintf("⚠️ <b>Watchdog</b>: job <code>%s</code> has not completed a run since %s", job, last)
+		if err := publisher.Publish(ctx, mq.RoutingBroadcast, mq.BroadcastMsg{ChannelID: opsChannelID, Text: text}); err != nil {
+			log.Printf("[watchdog] alert %s: %v", job, err)
+		}
+	}
+}
+
+func mustRegister(s *scheduler.Scheduler, job scheduler.Job) {
+	if err := s.Register(job); err != nil {
+		log.Fatalf("scheduler: %v", err)
+	}
+}
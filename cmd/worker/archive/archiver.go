@@ -0,0 +1,194 @@
+// Package archive exports the history of long-deleted monitors to the backup
+// bucket and then purges them from the database, so research data outlives
+// the retention of deleted monitors.
+//
+// An archive is one gzipped JSON document per monitor: coarse metadata (no
+// name, address, channel or tokens; coordinates rounded to ~1 km), daily
+// online/offline totals in Kyiv time and the list of outages.
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"no-lights-monitor/internal/backup"
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/models"
+)
+
+// Format identifies archive files.
+const Format = "nlm-archive/1"
+
+// batchSize caps the monitors archived per run.
+const batchSize = 50
+
+// Archive is the exported history of one monitor.
+type Archive struct {
+	Format    string    `json:"format"`
+	CreatedAt time.Time `json:"created_at"`
+	Monitor   Meta      `json:"monitor"`
+	Days      []Day     `json:"days"`
+	Outages   []Outage  `json:"outages"`
+}
+
+// Meta is what an archive keeps of the monitor itself.
+type Meta struct {
+	ID           int64     `json:"id"`
+	Type         string    `json:"type"`
+	Latitude     float64   `json:"latitude"`  // rounded to 0.01°
+	Longitude    float64   `json:"longitude"` // rounded to 0.01°
+	OutageRegion string    `json:"outage_region,omitempty"`
+	OutageGroup  string    `json:"outage_group,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	DeletedAt    time.Time `json:"deleted_at"`
+}
+
+// Day is the time a monitor spent online and offline on one Kyiv calendar day.
+type Day struct {
+	Date       string `json:"date"` // YYYY-MM-DD
+	OnlineSec  int64  `json:"online_sec"`
+	OfflineSec int64  `json:"offline_sec"`
+	Outages    int    `json:"outages"` // outages that started that day
+}
+
+// Outage is one offline period.
+type Outage struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Archiver archives and purges monitors deleted longer than after ago.
+type Archiver struct {
+	db     *database.DB
+	bucket *backup.Bucket
+	prefix string
+	after  time.Duration
+	kyiv   *time.Location
+}
+
+// NewArchiver creates an archiver writing to bucket under prefix.
+func NewArchiver(db *database.DB, bucket *backup.Bucket, prefix string, after time.Duration) *Archiver {
+	kyiv, _ := time.LoadLocation("Europe/Kyiv")
+	return &Archiver{db: db, bucket: bucket, prefix: prefix, after: after, kyiv: kyiv}
+}
+
+// Run archives a batch of due monitors. A monitor is only purged after its
+// archive was uploaded; failures leave it for the next run.
+func (a *Archiver) Run(ctx context.Context) error {
+	monitors, err := a.db.GetMonitorsToArchive(ctx, time.Now().Add(-a.after), batchSize)
+	if err != nil {
+		return fmt.Errorf("get monitors to archive: %w", err)
+	}
+	archived := 0
+	for _, m := range monitors {
+		if err := a.archive(ctx, m); err != nil {
+			log.Printf("[archive] monitor %d: %v", m.ID, err)
+			continue
+		}
+		archived++
+	}
+	if archived > 0 {
+		log.Printf("[archive] archived and purged %d monitors", archived)
+	}
+	if archived < len(monitors) {
+		return fmt.Errorf("%d of %d monitors failed", len(monitors)-archived, len(monitors))
+	}
+	return nil
+}
+
+func (a *Archiver) archive(ctx context.Context, m *models.Monitor) error {
+	_, events, err := a.db.GetCorrectedStatusHistory(ctx, m.ID, m.CreatedAt, *m.DeletedAt)
+	if err != nil {
+		return fmt.Errorf("load history: %w", err)
+	}
+	doc := a.build(m, events)
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(gz).Encode(doc); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	key := fmt.Sprintf("%smonitor-%d.json.gz", a.prefix, m.ID)
+	if err := a.bucket.Put(ctx, key, buf.Bytes()); err != nil {
+		return fmt.Errorf("upload %s: %w", key, err)
+	}
+	if err := a.db.PurgeMonitor(ctx, m.ID); err != nil {
+		return fmt.Errorf("purge: %w", err)
+	}
+	return nil
+}
+
+// build aggregates the events (ascending) between the monitor's creation and
+// deletion. Time before the first event is unknown and not counted.
+func (a *Archiver) build(m *models.Monitor, events []*models.StatusEvent) Archive {
+	doc := Archive{
+		Format:    Format,
+		CreatedAt: time.Now().UTC(),
+		Monitor: Meta{
+			ID:           m.ID,
+			Type:         m.MonitorType,
+			Latitude:     round2(m.Latitude),
+			Longitude:    round2(m.Longitude),
+			OutageRegion: m.OutageRegion,
+			OutageGroup:  m.OutageGroup,
+			CreatedAt:    m.CreatedAt,
+			DeletedAt:    *m.DeletedAt,
+		},
+		Days:    []Day{},
+		Outages: []Outage{},
+	}
+
+	end := *m.DeletedAt
+	byDate := map[string]int{} // date → index in doc.Days
+	day := func(t time.Time) *Day {
+		date := t.In(a.kyiv).Format("2006-01-02")
+		i, ok := byDate[date]
+		if !ok {
+			i = len(doc.Days)
+			byDate[date] = i
+			doc.Days = append(doc.Days, Day{Date: date})
+		}
+		return &doc.Days[i]
+	}
+
+	for i, e := range events {
+		to := end
+		if i+1 < len(events) {
+			to = events[i+1].Timestamp
+		}
+		if !e.IsOnline {
+			doc.Outages = append(doc.Outages, Outage{Start: e.Timestamp, End: to})
+			day(e.Timestamp).Outages++
+		}
+		// Split the interval at Kyiv midnights.
+		for from := e.Timestamp; from.Before(to); {
+			k := from.In(a.kyiv)
+			next := time.Date(k.Year(), k.Month(), k.Day()+1, 0, 0, 0, 0, a.kyiv)
+			if next.After(to) {
+				next = to
+			}
+			secs := int64(next.Sub(from).Seconds())
+			if e.IsOnline {
+				day(from).OnlineSec += secs
+			} else {
+				day(from).OfflineSec += secs
+			}
+			from = next
+		}
+	}
+	return doc
+}
+
+// round2 rounds a coordinate to 0.01° (about 1 km).
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
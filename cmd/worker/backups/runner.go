@@ -0,0 +1,41 @@
+package backups
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"no-lights-monitor/internal/backup"
+	"no-lights-monitor/internal/database"
+)
+
+// Runner takes the daily backup and prunes old ones.
+type Runner struct {
+	db        *database.DB
+	store     *backup.Store
+	events    time.Duration
+	retention time.Duration
+}
+
+// NewRunner creates a backup runner keeping events for the given period in each
+// backup and backups themselves for retention.
+func NewRunner(db *database.DB, store *backup.Store, events, retention time.Duration) *Runner {
+	return &Runner{db: db, store: store, events: events, retention: retention}
+}
+
+// Run saves a backup, then deletes the expired ones. Pruning only runs after a
+// successful save, so a broken backup never costs the older good ones.
+func (r *Runner) Run(ctx context.Context) error {
+	if _, err := r.store.Save(ctx, r.db, time.Now().Add(-r.events)); err != nil {
+		return fmt.Errorf("save backup: %w", err)
+	}
+	deleted, err := r.store.Prune(ctx, r.retention)
+	if err != nil {
+		return fmt.Errorf("prune backups: %w", err)
+	}
+	if deleted > 0 {
+		log.Printf("[backup] deleted %d expired backups", deleted)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package canary
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"no-lights-monitor/internal/cache"
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/metrics"
+	"no-lights-monitor/internal/models"
+)
+
+// settleTime is how long after a phase switch the whole pipeline gets to
+// deliver the matching status message: the canary's 150s offline threshold,
+// a few heartbeat check cycles and MQ + Telegram delivery.
+const settleTime = 6 * time.Minute
+
+// staleAfter is how old the last verdict may be before readiness fails.
+const staleAfter = 10 * time.Minute
+
+// Driver maintains a synthetic heartbeat monitor that posts to an ops channel.
+// It alternates between an online phase (pinging the public API every run) and
+// an offline phase (no pings), so every phase must produce one status message
+// through the full pipeline: API → Redis → heartbeat checker → RabbitMQ → bot →
+// Telegram. The bot records each delivery; Run compares it to the expected state.
+type Driver struct {
+	db        *database.DB
+	cache     *cache.Cache
+	baseURL   string
+	channelID int64
+	period    time.Duration
+	client    *http.Client
+
+	monitor *models.Monitor
+}
+
+func NewDriver(db *database.DB, c *cache.Cache, baseURL string, channelID int64, period time.Duration) *Driver {
+	return &Driver{
+		db:        db,
+		cache:     c,
+		baseURL:   baseURL,
+		channelID: channelID,
+		period:    period,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Setup creates the canary monitor if it does not exist yet.
+func (d *Driver) Setup(ctx context.Context) error {
+	m, err := d.db.EnsureCanaryMonitor(ctx, d.channelID)
+	if err != nil {
+		return fmt.Errorf("ensure canary monitor: %w", err)
+	}
+	d.monitor = m
+	log.Printf("[canary] monitor %d posting to channel %d, phase %s", m.ID, d.channelID, d.period)
+	return nil
+}
+
+// Run pings the API during online phases and checks that the pipeline caught up
+// with the current phase. Scheduled every minute.
+func (d *Driver) Run(ctx context.Context) error {
+	if d.cache.IsDevMode(ctx) {
+		// Heartbeat checks are suspended in dev mode; nothing to verify.
+		return d.verdict(ctx, "", "")
+	}
+
+	now := time.Now()
+	phaseStart := now.Truncate(d.period)
+	wantOnline := (now.Unix()/int64(d.period/time.Second))%2 == 0
+
+	if wantOnline {
+		if err := d.ping(ctx); err != nil {
+			return d.verdict(ctx, "ping", err.Error())
+		}
+	}
+
+	if now.Sub(phaseStart) < settleTime {
+		return nil
+	}
+
+	m, err := d.db.GetMonitorByID(ctx, d.monitor.ID)
+	if err != nil {
+		return fmt.Errorf("load canary: %w", err)
+	}
+	if m.IsOnline != wantOnline {
+		return d.verdict(ctx, "check", fmt.Sprintf("monitor is_online=%v, expected %v since %s", m.IsOnline, wantOnline, phaseStart.Format(time.RFC3339)))
+	}
+	if m.LastStatusChangeAt.Before(phaseStart) {
+		// Status did not flip this cycle (e.g. first phase after setup): no message to verify.
+		return d.verdict(ctx, "", "")
+	}
+
+	c, err := d.db.GetCanary(ctx, d.monitor.ID)
+	if err != nil {
+		return fmt.Errorf("load canary state: %w", err)
+	}
+	if c.DeliveredAt == nil || c.DeliveredAt.Before(m.LastStatusChangeAt.Add(-time.Minute)) || c.DeliveredOnline != wantOnline {
+		return d.verdict(ctx, "delivery", fmt.Sprintf("no Telegram delivery of is_online=%v since %s", wantOnline, m.LastStatusChangeAt.Format(time.RFC3339)))
+	}
+	return d.verdict(ctx, "", "")
+}
+
+// ping sends a heartbeat through the public API, like a real device would.
+func (d *Driver) ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/api/ping/%s", d.baseURL, d.monitor.Token), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("ping API: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ping API: status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// verdict stores and exports the check result. An empty stage means healthy.
+func (d *Driver) verdict(ctx context.Context, stage, problem string) error {
+	healthy := stage == ""
+	if healthy {
+		metrics.CanaryHealthy.Set(1)
+	} else {
+		metrics.CanaryHealthy.Set(0)
+		metrics.CanaryFailuresTotal.WithLabelValues(stage).Inc()
+		problem = stage + ": " + problem
+		log.Printf("[canary] UNHEALTHY %s", problem)
+	}
+	if err := d.db.SetCanaryHealth(ctx, d.monitor.ID, healthy, problem); err != nil {
+		return fmt.Errorf("save canary health: %w", err)
+	}
+	return nil
+}
+
+// Check reports the stored canary verdict for /readyz. It reads the database so
+// any worker replica reflects the verdict of whichever one ran the job.
+func (d *Driver) Check(ctx context.Context) error {
+	if d.monitor == nil {
+		return nil
+	}
+	c, err := d.db.GetCanary(ctx, d.monitor.ID)
+	if err != nil {
+		return fmt.Errorf("canary: %w", err)
+	}
+	if !c.Healthy {
+		return fmt.Errorf("canary: %s", c.Problem)
+	}
+	if c.CheckedAt != nil && time.Since(*c.CheckedAt) > staleAfter {
+		return fmt.Errorf("canary: not checked since %s", c.CheckedAt.Format(time.RFC3339))
+	}
+	return nil
+}
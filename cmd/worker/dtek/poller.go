@@ -39,35 +39,19 @@ func NewPoller(db *database.DB, publisher *mq.Publisher, serviceURL string) *Pol
 	}
 }
 
-// Start runs the polling loop. intervalSec controls how often it fires.
-func (p *Poller) Start(ctx context.Context, intervalSec int) {
-	ticker := time.NewTicker(time.Duration(intervalSec) * time.Second)
-	defer ticker.Stop()
-
-	// Run once immediately on start, then on each tick.
-	p.run(ctx)
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			p.run(ctx)
-		}
-	}
-}
-
-func (p *Poller) run(ctx context.Context) {
+// Run performs a single poll over all pending monitors. Scheduled every
+// DTEK_POLL_INTERVAL seconds by the worker.
+func (p *Poller) Run(ctx context.Context) error {
 	monitors, err := p.db.GetDtekPendingMonitors(ctx)
 	if err != nil {
-		log.Printf("[dtek] failed to query pending monitors: %v", err)
-		return
+		return fmt.Errorf("query pending monitors: %w", err)
 	}
 	for _, m := range monitors {
 		if err := p.check(ctx, m); err != nil {
 			log.Printf("[dtek] monitor %d check error: %v", m.ID, err)
 		}
 	}
+	return nil
 }
 
 type outageResponse struct {
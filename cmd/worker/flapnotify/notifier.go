@@ -0,0 +1,36 @@
+// Package flapnotify posts the "unstable power" notices of flapping monitors
+// (see heartbeat.FlapAlerter) to their channels.
+package flapnotify
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"time"
+
+	"no-lights-monitor/internal/models"
+	"no-lights-monitor/internal/mq"
+)
+
+const msgFlapping = "⚠️ <b>%s</b>: нестабільне живлення — %d перемикань за %d хв.\n" +
+	"Окремі сповіщення призупинено, повідомимо, коли стан стабілізується."
+
+// Notifier publishes flap notices as channel broadcasts.
+type Notifier struct {
+	publisher *mq.Publisher
+}
+
+func New(publisher *mq.Publisher) *Notifier {
+	return &Notifier{publisher: publisher}
+}
+
+func (n *Notifier) NotifyFlapping(sc models.StatusChange, changes int, window time.Duration) {
+	n.publish(sc, fmt.Sprintf(msgFlapping, html.EscapeString(sc.Name), changes, int(window.Minutes())))
+}
+
+func (n *Notifier) publish(sc models.StatusChange, text string) {
+	if err := n.publisher.Publish(context.Background(), mq.RoutingBroadcast, mq.BroadcastMsg{ChannelID: sc.ChannelID, Text: text}); err != nil {
+		log.Printf("[flap] monitor %d: publish notice: %v", sc.MonitorID, err)
+	}
+}
@@ -8,22 +8,23 @@ import (
 	"net/http"
 	"time"
 
+	"no-lights-monitor/internal/httpx"
 	"no-lights-monitor/internal/models"
+	"no-lights-monitor/internal/svcauth"
 )
 
 // Client talks to the external graph-generation service.
 type Client struct {
 	baseURL    string
-	httpClient *http.Client
+	httpClient *httpx.Client
 }
 
-// NewClient creates a new graph service client.
-func NewClient(baseURL string) *Client {
+// NewClient creates a new graph service client. Requests are signed with
+// secret (see svcauth) when it is not empty and retried according to policy.
+func NewClient(baseURL, secret string, policy httpx.Policy) *Client {
 	return &Client{
-		baseURL: baseURL,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		baseURL:    baseURL,
+		httpClient: &httpx.Client{Name: "graph", HTTP: svcauth.NewClient(secret, 0), Policy: policy},
 	}
 }
 
@@ -51,11 +52,68 @@ func (c *Client) GenerateWeekGraph(monitorID int64, weekStart time.Time, events
 		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
-	resp, err := c.httpClient.Post(
-		c.baseURL+"/generate-week-graph",
-		"application/json",
-		bytes.NewReader(body),
-	)
+	return c.post("/generate-week-graph", body)
+}
+
+// periodGraphRequest is the JSON body for POST /generate-period-graph.
+type periodGraphRequest struct {
+	MonitorID int64                `json:"monitor_id"`
+	Start     time.Time            `json:"start"`
+	Days      int                  `json:"days"`
+	Events    []models.StatusEvent `json:"events"`
+}
+
+// GeneratePeriodGraph renders days day rows of uptime from start, a Kyiv
+// midnight, and returns raw PNG bytes.
+func (c *Client) GeneratePeriodGraph(monitorID int64, start time.Time, days int, events []*models.StatusEvent) ([]byte, error) {
+	evts := make([]models.StatusEvent, len(events))
+	for i, e := range events {
+		evts[i] = *e
+	}
+	body, err := json.Marshal(periodGraphRequest{
+		MonitorID: monitorID,
+		Start:     start,
+		Days:      days,
+		Events:    evts,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+	return c.post("/generate-period-graph", body)
+}
+
+// latencyGraphRequest is the JSON body for POST /generate-latency-graph.
+type latencyGraphRequest struct {
+	MonitorID int64                 `json:"monitor_id"`
+	WeekStart time.Time             `json:"week_start"`
+	Points    []models.LatencyPoint `json:"points"`
+}
+
+// GenerateLatencyGraph renders a week of hourly ping latency and packet loss
+// and returns raw PNG bytes.
+func (c *Client) GenerateLatencyGraph(monitorID int64, weekStart time.Time, points []models.LatencyPoint) ([]byte, error) {
+	if points == nil {
+		points = []models.LatencyPoint{}
+	}
+	body, err := json.Marshal(latencyGraphRequest{
+		MonitorID: monitorID,
+		WeekStart: weekStart,
+		Points:    points,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+	return c.post("/generate-latency-graph", body)
+}
+
+// post sends a JSON body to a graph-service endpoint and returns the PNG it answers with.
+func (c *Client) post(path string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("http post: %w", err)
 	}
@@ -0,0 +1,119 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"no-lights-monitor/internal/models"
+	"no-lights-monitor/internal/mq"
+	"no-lights-monitor/internal/photofit"
+)
+
+// Daily and monthly uptime graphs are opt-in companions of the weekly one:
+// the same bars for today or for the calendar month so far, each kept as its
+// own channel message that is edited in place until the period rolls over.
+// Periods follow Kyiv calendar days, unlike the UTC weeks of the weekly graph.
+
+// periodStart returns the Kyiv midnight the period containing t starts at.
+func periodStart(p mq.GraphPeriod, t time.Time) time.Time {
+	kyiv, _ := time.LoadLocation("Europe/Kyiv")
+	t = t.In(kyiv)
+	if p == mq.GraphMonth {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, kyiv)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, kyiv)
+}
+
+// periodDays returns how many day rows the period starting at start has.
+func periodDays(p mq.GraphPeriod, start time.Time) int {
+	if p == mq.GraphMonth {
+		return time.Date(start.Year(), start.Month()+1, 0, 0, 0, 0, 0, start.Location()).Day()
+	}
+	return 1
+}
+
+// wantsPeriodGraph reports whether m should get the graph of period p in its channel.
+func wantsPeriodGraph(m *models.Monitor, p mq.GraphPeriod) bool {
+	if m.ChannelID == 0 {
+		return false
+	}
+	switch p {
+	case mq.GraphDay:
+		return m.DailyGraphEnabled
+	case mq.GraphMonth:
+		return m.MonthlyGraphEnabled
+	}
+	return false
+}
+
+// UpdatePeriodSingle generates and publishes the daily or monthly graph of a
+// single monitor, if it has that graph enabled.
+func (u *Updater) UpdatePeriodSingle(ctx context.Context, monitorID int64, p mq.GraphPeriod) error {
+	m, err := u.db.GetMonitorByID(ctx, monitorID)
+	if err != nil {
+		return err
+	}
+	if !wantsPeriodGraph(m, p) {
+		return nil
+	}
+	return u.updatePeriod(ctx, m, p, time.Now())
+}
+
+// updatePeriod renders the monitor's uptime for the current day or month and
+// publishes it. Like the latency graph it is re-rendered on every pass.
+func (u *Updater) updatePeriod(ctx context.Context, m *models.Monitor, p mq.GraphPeriod, now time.Time) error {
+	start := periodStart(p, now)
+	anchor, events, err := u.db.GetCorrectedStatusHistory(ctx, m.ID, start, now)
+	if err != nil {
+		return fmt.Errorf("fetch events: %w", err)
+	}
+	if anchor != nil {
+		events = append([]*models.StatusEvent{anchor}, events...)
+	}
+
+	png, err := u.client.GeneratePeriodGraph(m.ID, start, periodDays(p, start), events)
+	if err != nil {
+		return fmt.Errorf("generate %s graph: %w", p, err)
+	}
+	if png, err = photofit.Fit(string(p)+"_graph", png); err != nil {
+		return fmt.Errorf("fit %s graph: %w", p, err)
+	}
+
+	oldMsgID, oldStart := m.DailyGraphMessageID, m.DailyGraphStart
+	if p == mq.GraphMonth {
+		oldMsgID, oldStart = m.MonthlyGraphMessageID, m.MonthlyGraphStart
+	}
+	needsNewMessage := oldMsgID == 0 || oldStart == nil || !oldStart.Equal(start)
+	msg := mq.GraphReadyMsg{
+		Period:         p,
+		MonitorID:      m.ID,
+		ChannelID:      m.ChannelID,
+		MonitorName:    m.Name,
+		MonitorAddress: m.Address,
+		NotifyAddress:  m.NotifyAddress,
+		WeekStart:      start,
+		OldMsgID:       oldMsgID,
+		NeedsNewMsg:    needsNewMessage,
+		ImagePNG:       png,
+		Caption:        periodCaption(m, p, start),
+	}
+	if err := u.pub.Publish(ctx, mq.RoutingGraphReady, msg); err != nil {
+		return fmt.Errorf("publish %s graph: %w", p, err)
+	}
+	log.Printf("[graph] monitor %d: published %s graph for %s (new=%v)", m.ID, p, start.Format("2006-01-02"), needsNewMessage)
+	return nil
+}
+
+// periodCaption heads the daily or monthly graph.
+func periodCaption(m *models.Monitor, p mq.GraphPeriod, start time.Time) string {
+	caption := fmt.Sprintf("📊 Графік за день (%s)", start.Format("02.01.2006"))
+	if p == mq.GraphMonth {
+		caption = fmt.Sprintf("📊 Графік за місяць (%s)", start.Format("01.2006"))
+	}
+	if m.NotifyAddress && m.Address != "" {
+		caption += fmt.Sprintf("\n📍 %s", m.Address)
+	}
+	return caption
+}
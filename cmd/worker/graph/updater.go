@@ -2,16 +2,40 @@ package graph
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"log"
+	"sort"
+	"sync"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 
 	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/metrics"
 	"no-lights-monitor/internal/models"
 	"no-lights-monitor/internal/mq"
+	"no-lights-monitor/internal/photofit"
+	"no-lights-monitor/internal/safego"
+)
+
+const (
+	// staggerWindow is the part of the hour over which periodic graph updates are spread.
+	staggerWindow = 50 * time.Minute
+	// maxConcurrentGraphs bounds parallel graph-service calls during a periodic pass.
+	maxConcurrentGraphs = 4
+	// graphMaxStale is how long an unchanged graph may go without re-rendering.
+	graphMaxStale = 6 * time.Hour
+	// sponsorTTL is how long the active sponsor message is cached between DB lookups.
+	sponsorTTL = 5 * time.Minute
+	// latencyRetention is how long ping latency samples are kept: the current
+	// week's graph plus a spare week.
+	latencyRetention = 14 * 24 * time.Hour
 )
 
 // Updater is a background service that generates weekly graph images
@@ -20,6 +44,10 @@ type Updater struct {
 	db     *database.DB
 	client *Client
 	pub    *mq.Publisher
+
+	sponsorMu sync.Mutex
+	sponsor   *models.SponsorMessage
+	sponsorAt time.Time
 }
 
 // NewUpdater creates a graph updater.
@@ -27,39 +55,16 @@ func NewUpdater(db *database.DB, client *Client, pub *mq.Publisher) *Updater {
 	return &Updater{db: db, client: client, pub: pub}
 }
 
-// Start runs the hourly update loop and listens for on-demand graph requests.
-func (u *Updater) Start(ctx context.Context, consumer *mq.Consumer) {
-	log.Println("[graph] updater started, waiting 30s for graph-service")
+// ListenRequests consumes graph request messages from the bot and generates graphs on-demand.
+// The periodic pass over all monitors is run by the scheduler via RunAll.
+func (u *Updater) ListenRequests(ctx context.Context, consumer *mq.Consumer) {
+	log.Println("[graph] waiting 30s for graph-service before serving requests")
 	select {
 	case <-ctx.Done():
 		return
 	case <-time.After(30 * time.Second):
 	}
 
-	// Listen for on-demand graph requests from the bot service.
-	if consumer != nil {
-		go u.listenRequests(ctx, consumer)
-	}
-
-	log.Println("[graph] running initial pass")
-	u.runAll(ctx)
-
-	ticker := time.NewTicker(1 * time.Hour)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("[graph] updater stopped")
-			return
-		case <-ticker.C:
-			u.runAll(ctx)
-		}
-	}
-}
-
-// listenRequests consumes graph request messages from the bot and generates graphs on-demand.
-func (u *Updater) listenRequests(ctx context.Context, consumer *mq.Consumer) {
 	deliveries, err := consumer.Consume(mq.QueueGraphRequest)
 	if err != nil {
 		log.Printf("[graph] failed to consume graph requests: %v", err)
@@ -73,7 +78,9 @@ func (u *Updater) listenRequests(ctx context.Context, consumer *mq.Consumer) {
 			if !ok {
 				return
 			}
-			u.handleRequest(ctx, d)
+			if err := safego.Run("graph_requests", func() { u.handleRequest(ctx, d) }); err != nil {
+				d.Nack(false, false)
+			}
 		}
 	}
 }
@@ -85,8 +92,17 @@ func (u *Updater) handleRequest(ctx context.Context, d amqp.Delivery) {
 		d.Nack(false, false)
 		return
 	}
-	if err := u.UpdateSingle(ctx, msg.MonitorID, msg.ChannelID); err != nil {
-		log.Printf("[graph] on-demand graph for monitor %d failed: %v", msg.MonitorID, err)
+	var err error
+	switch {
+	case msg.Kind == mq.GraphLatency:
+		err = u.UpdateLatencySingle(ctx, msg.MonitorID)
+	case msg.Period != mq.GraphWeek:
+		err = u.UpdatePeriodSingle(ctx, msg.MonitorID, msg.Period)
+	default:
+		err = u.UpdateSingle(ctx, msg.MonitorID, msg.ChannelID)
+	}
+	if err != nil {
+		log.Printf("[graph] on-demand %s graph for monitor %d failed: %v", graphName(msg.Kind, msg.Period), msg.MonitorID, err)
 	}
 	d.Ack(false)
 }
@@ -116,62 +132,136 @@ func (u *Updater) UpdateSingle(ctx context.Context, monitorID, channelID int64)
 			if !m.GraphEnabled {
 				return nil
 			}
-			return u.updateOne(ctx, m.ID, m.ChannelID, m.Name, m.Address, m.NotifyAddress, m.GraphMessageID, m.GraphWeekStart, weekStart, now)
+			return u.updateOne(ctx, m, weekStart, now, true)
 		}
 	}
 	// Monitor just created — graph_enabled defaults to true, so post.
-	return u.updateOne(ctx, monitorID, channelID, "", "", false, 0, nil, weekStart, now)
+	return u.updateOne(ctx, &models.Monitor{ID: monitorID, ChannelID: channelID}, weekStart, now, true)
 }
 
-// runAll iterates over every monitor with a channel and updates its graph.
-func (u *Updater) runAll(ctx context.Context) {
-	monitors, err := u.db.GetMonitorsWithChannels(ctx)
+// Recompute re-renders and republishes every graph the monitor has enabled
+// (weekly, latency, daily and monthly) even if nothing they are drawn from
+// changed, e.g. after a rendering bug fix.
+func (u *Updater) Recompute(ctx context.Context, m *models.Monitor) error {
+	if m.ChannelID == 0 {
+		return nil
+	}
+	return u.updateMonitor(ctx, m, time.Now().UTC(), true)
+}
+
+// UpdateLatencySingle generates and publishes the latency graph of a single
+// ping monitor, if it has one enabled.
+func (u *Updater) UpdateLatencySingle(ctx context.Context, monitorID int64) error {
+	m, err := u.db.GetMonitorByID(ctx, monitorID)
 	if err != nil {
-		log.Printf("[graph] failed to list monitors: %v", err)
-		return
+		return err
+	}
+	if !wantsLatencyGraph(m) {
+		return nil
 	}
 	now := time.Now().UTC()
-	weekStart := currentWeekStart(now)
+	return u.updateLatency(ctx, m, currentWeekStart(now), now)
+}
 
-	enabled := 0
+// RunAll updates the graphs of every monitor with a channel. Instead of bursting
+// all of them at the top of the hour, each monitor gets a stable offset within
+// staggerWindow (hashed from its ID), and at most maxConcurrentGraphs run at once.
+func (u *Updater) RunAll(ctx context.Context) error {
+	monitors, err := u.db.GetMonitorsWithChannels(ctx)
+	if err != nil {
+		return fmt.Errorf("list monitors: %w", err)
+	}
+
+	var enabled []*models.Monitor
 	for _, m := range monitors {
-		if m.GraphEnabled {
-			enabled++
+		if m.GraphEnabled || wantsLatencyGraph(m) || wantsPeriodGraph(m, mq.GraphDay) || wantsPeriodGraph(m, mq.GraphMonth) {
+			enabled = append(enabled, m)
 		}
 	}
-	log.Printf("[graph] updating graphs for %d monitors (%d with graph enabled)", len(monitors), enabled)
+	log.Printf("[graph] updating graphs for %d monitors (%d with graph enabled) over %s", len(monitors), len(enabled), staggerWindow)
 
-	for _, m := range monitors {
-		if !m.GraphEnabled {
-			continue
+	sort.Slice(enabled, func(i, j int) bool {
+		return staggerOffset(enabled[i].ID) < staggerOffset(enabled[j].ID)
+	})
+
+	start := time.Now()
+	sem := make(chan struct{}, maxConcurrentGraphs)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for _, m := range enabled {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Until(start.Add(staggerOffset(m.ID)))):
 		}
-		if err := u.updateOne(ctx, m.ID, m.ChannelID, m.Name, m.Address, m.NotifyAddress, m.GraphMessageID, m.GraphWeekStart, weekStart, now); err != nil {
-			log.Printf("[graph] monitor %d: %v", m.ID, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case sem <- struct{}{}:
 		}
+
+		wg.Add(1)
+		safego.Go("graph_update", func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := u.updateMonitor(ctx, m, time.Now().UTC(), false); err != nil {
+				log.Printf("[graph] monitor %d: %v", m.ID, err)
+			}
+		})
 	}
+	return nil
 }
 
-// updateOne generates a graph PNG and publishes a message for the bot service.
-func (u *Updater) updateOne(ctx context.Context, monitorID, channelID int64, monitorName, monitorAddress string, notifyAddress bool, oldMsgID int, oldWeekStart *time.Time, weekStart, now time.Time) error {
-	needsNewMessage := oldMsgID == 0 || oldWeekStart == nil || !oldWeekStart.Equal(weekStart)
-
-	caption := fmt.Sprintf("📊 Тижневий графік (від %s)", weekStart.Format("02.01.2006"))
-	if notifyAddress && monitorAddress != "" {
-		caption += fmt.Sprintf("\n📍 %s", monitorAddress)
+// updateMonitor refreshes every graph m has enabled. Unless force is set, the
+// weekly graph is skipped when unchanged; the others are always re-rendered.
+// A failing graph doesn't stop the rest; their errors are returned joined.
+func (u *Updater) updateMonitor(ctx context.Context, m *models.Monitor, now time.Time, force bool) error {
+	var errs []error
+	if m.GraphEnabled {
+		if err := u.updateOne(ctx, m, currentWeekStart(now), now, force); err != nil {
+			errs = append(errs, err)
+		}
 	}
-
-	// Fetch week events.
-	events, err := u.db.GetStatusHistory(ctx, monitorID, weekStart, now)
-	if err != nil {
-		return fmt.Errorf("fetch events: %w", err)
+	if wantsLatencyGraph(m) {
+		if err := u.updateLatency(ctx, m, currentWeekStart(now), now); err != nil {
+			errs = append(errs, fmt.Errorf("latency: %w", err))
+		}
+	}
+	for _, p := range []mq.GraphPeriod{mq.GraphDay, mq.GraphMonth} {
+		if wantsPeriodGraph(m, p) {
+			if err := u.updatePeriod(ctx, m, p, now); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", p, err))
+			}
+		}
 	}
+	return errors.Join(errs...)
+}
+
+// staggerOffset returns the monitor's stable delay within staggerWindow.
+func staggerOffset(monitorID int64) time.Duration {
+	h := fnv.New32a()
+	_ = binary.Write(h, binary.LittleEndian, monitorID)
+	return time.Duration(h.Sum32()%uint32(staggerWindow/time.Second)) * time.Second
+}
+
+// updateOne generates a graph PNG and publishes a message for the bot service.
+// Unless force is set, it skips the graph service entirely when the events
+// (and caption) match what the channel graph was last rendered from.
+func (u *Updater) updateOne(ctx context.Context, m *models.Monitor, weekStart, now time.Time, force bool) error {
+	monitorID := m.ID
+	needsNewMessage := m.GraphMessageID == 0 || m.GraphWeekStart == nil || !m.GraphWeekStart.Equal(weekStart)
 
-	anchor, err := u.db.GetLastEventBefore(ctx, monitorID, weekStart)
+	caption, events, err := u.weekInput(ctx, m, weekStart, now)
 	if err != nil {
-		return fmt.Errorf("fetch anchor event: %w", err)
+		return err
 	}
-	if anchor != nil {
-		events = append([]*models.StatusEvent{anchor}, events...)
+
+	hash := eventsHash(weekStart, now, caption, events)
+	if !force && !needsNewMessage && hash == m.GraphEventsHash {
+		metrics.GraphSkippedTotal.Inc()
+		return nil
 	}
 
 	// Call graph service.
@@ -179,19 +269,23 @@ func (u *Updater) updateOne(ctx context.Context, monitorID, channelID int64, mon
 	if err != nil {
 		return fmt.Errorf("generate graph: %w", err)
 	}
+	if png, err = photofit.Fit("graph", png); err != nil {
+		return fmt.Errorf("fit graph: %w", err)
+	}
 
 	// Publish to RabbitMQ for the bot service to send to Telegram.
 	msg := mq.GraphReadyMsg{
 		MonitorID:      monitorID,
-		ChannelID:      channelID,
-		MonitorName:    monitorName,
-		MonitorAddress: monitorAddress,
-		NotifyAddress:  notifyAddress,
+		ChannelID:      m.ChannelID,
+		MonitorName:    m.Name,
+		MonitorAddress: m.Address,
+		NotifyAddress:  m.NotifyAddress,
 		WeekStart:      weekStart,
-		OldMsgID:       oldMsgID,
+		OldMsgID:       m.GraphMessageID,
 		NeedsNewMsg:    needsNewMessage,
 		ImagePNG:       png,
 		Caption:        caption,
+		EventsHash:     hash,
 	}
 	if err := u.pub.Publish(ctx, mq.RoutingGraphReady, msg); err != nil {
 		return fmt.Errorf("publish graph: %w", err)
@@ -200,3 +294,199 @@ func (u *Updater) updateOne(ctx context.Context, monitorID, channelID int64, mon
 	log.Printf("[graph] monitor %d: published graph for week %s (new=%v)", monitorID, weekStart.Format("2006-01-02"), needsNewMessage)
 	return nil
 }
+
+// updateLatency renders the week's hourly RTT and packet loss of a ping monitor
+// and publishes it as the channel's second graph. Latency changes every hour,
+// so unlike the uptime graph it is re-rendered on every pass.
+func (u *Updater) updateLatency(ctx context.Context, m *models.Monitor, weekStart, now time.Time) error {
+	points, err := u.db.GetLatencyHourly(ctx, m.ID, weekStart, now)
+	if err != nil {
+		return fmt.Errorf("fetch latency: %w", err)
+	}
+	png, err := u.client.GenerateLatencyGraph(m.ID, weekStart, points)
+	if err != nil {
+		return fmt.Errorf("generate latency graph: %w", err)
+	}
+	if png, err = photofit.Fit("latency_graph", png); err != nil {
+		return fmt.Errorf("fit latency graph: %w", err)
+	}
+
+	needsNewMessage := m.LatencyGraphMessageID == 0 || m.LatencyGraphWeekStart == nil || !m.LatencyGraphWeekStart.Equal(weekStart)
+	msg := mq.GraphReadyMsg{
+		Kind:           mq.GraphLatency,
+		MonitorID:      m.ID,
+		ChannelID:      m.ChannelID,
+		MonitorName:    m.Name,
+		MonitorAddress: m.Address,
+		NotifyAddress:  m.NotifyAddress,
+		WeekStart:      weekStart,
+		OldMsgID:       m.LatencyGraphMessageID,
+		NeedsNewMsg:    needsNewMessage,
+		ImagePNG:       png,
+		Caption:        latencyCaption(m, weekStart, points),
+	}
+	if err := u.pub.Publish(ctx, mq.RoutingGraphReady, msg); err != nil {
+		return fmt.Errorf("publish latency graph: %w", err)
+	}
+	log.Printf("[graph] monitor %d: published latency graph for week %s (new=%v)", m.ID, weekStart.Format("2006-01-02"), needsNewMessage)
+	return nil
+}
+
+// PruneLatencySamples drops ping latency samples older than latencyRetention.
+func (u *Updater) PruneLatencySamples(ctx context.Context) error {
+	n, err := u.db.DeletePingSamplesBefore(ctx, time.Now().Add(-latencyRetention))
+	if err != nil {
+		return fmt.Errorf("delete ping samples: %w", err)
+	}
+	log.Printf("[graph] pruned %d ping samples", n)
+	return nil
+}
+
+// wantsLatencyGraph reports whether m should get a latency graph in its channel.
+func wantsLatencyGraph(m *models.Monitor) bool {
+	return m.MonitorType == "ping" && m.LatencyGraphEnabled && m.ChannelID != 0
+}
+
+// graphName names a graph kind and period in log lines.
+func graphName(k mq.GraphKind, p mq.GraphPeriod) string {
+	switch {
+	case k != mq.GraphUptime:
+		return string(k)
+	case p != mq.GraphWeek:
+		return string(p) + " uptime"
+	}
+	return "uptime"
+}
+
+// latencyCaption summarizes the week's latency under the graph: the median of
+// the hourly medians and the average packet loss.
+func latencyCaption(m *models.Monitor, weekStart time.Time, points []models.LatencyPoint) string {
+	caption := fmt.Sprintf("📶 Затримка пінгу за тиждень (від %s)", weekStart.Format("02.01.2006"))
+	if m.NotifyAddress && m.Address != "" {
+		caption += fmt.Sprintf("\n📍 %s", m.Address)
+	}
+	var rtts []float64
+	var loss float64
+	var probes int
+	for _, p := range points {
+		if p.RTTMs != nil {
+			rtts = append(rtts, *p.RTTMs)
+		}
+		loss += p.Loss * float64(p.Probes)
+		probes += p.Probes
+	}
+	if probes == 0 {
+		return caption + "\nЩе немає вимірювань."
+	}
+	if len(rtts) > 0 {
+		sort.Float64s(rtts)
+		caption += fmt.Sprintf("\nМедіана: %.0f мс", rtts[len(rtts)/2])
+	}
+	return caption + fmt.Sprintf("\nВтрати пакетів: %.1f%%", loss/float64(probes)*100)
+}
+
+// Sandbox renders the monitor's current weekly graph and publishes it as a new
+// message to channelID, leaving the monitor's own graph bookkeeping untouched.
+func (u *Updater) Sandbox(ctx context.Context, m *models.Monitor, channelID int64) error {
+	now := time.Now().UTC()
+	weekStart := currentWeekStart(now)
+	caption, events, err := u.weekInput(ctx, m, weekStart, now)
+	if err != nil {
+		return err
+	}
+	png, err := u.client.GenerateWeekGraph(m.ID, weekStart, events)
+	if err != nil {
+		return fmt.Errorf("generate graph: %w", err)
+	}
+	if png, err = photofit.Fit("graph", png); err != nil {
+		return fmt.Errorf("fit graph: %w", err)
+	}
+	msg := mq.GraphReadyMsg{
+		MonitorID:   m.ID,
+		ChannelID:   channelID,
+		MonitorName: m.Name,
+		WeekStart:   weekStart,
+		NeedsNewMsg: true,
+		ImagePNG:    png,
+		Caption:     caption,
+		Sandbox:     true,
+	}
+	if err := u.pub.Publish(ctx, mq.RoutingGraphReady, msg); err != nil {
+		return fmt.Errorf("publish graph: %w", err)
+	}
+	return nil
+}
+
+// weekInput returns the caption and the events (with the anchor before weekStart)
+// a weekly graph is rendered from.
+func (u *Updater) weekInput(ctx context.Context, m *models.Monitor, weekStart, now time.Time) (string, []*models.StatusEvent, error) {
+	caption := fmt.Sprintf("📊 Тижневий графік (від %s)", weekStart.Format("02.01.2006"))
+	if m.NotifyAddress && m.Address != "" {
+		caption += fmt.Sprintf("\n📍 %s", m.Address)
+	}
+	if s := u.sponsorFor(ctx, m, weekStart, now); s != nil {
+		caption += sponsorCaption(s)
+	}
+
+	// Owner corrections are overlaid here; raw events stay untouched in the DB.
+	anchor, events, err := u.db.GetCorrectedStatusHistory(ctx, m.ID, weekStart, now)
+	if err != nil {
+		return "", nil, fmt.Errorf("fetch events: %w", err)
+	}
+	if anchor != nil {
+		events = append([]*models.StatusEvent{anchor}, events...)
+	}
+	return caption, events, nil
+}
+
+// sponsorFor returns the sponsor message to append to the monitor's caption
+// for the week, or nil. Only monitors whose owner opted in get one, and they
+// get it one week in EveryWeeks, spread by monitor ID so channels don't all carry
+// it in the same week. The choice is stable within a week, so hourly
+// re-renders keep the same caption.
+func (u *Updater) sponsorFor(ctx context.Context, m *models.Monitor, weekStart, now time.Time) *models.SponsorMessage {
+	if !m.SponsorEnabled {
+		return nil
+	}
+	u.sponsorMu.Lock()
+	if u.sponsorAt.IsZero() || now.Sub(u.sponsorAt) > sponsorTTL {
+		s, err := u.db.GetActiveSponsorMessage(ctx, now)
+		if err != nil {
+			log.Printf("[graph] load sponsor message: %v", err)
+		} else {
+			u.sponsor, u.sponsorAt = s, now
+		}
+	}
+	s := u.sponsor
+	u.sponsorMu.Unlock()
+
+	if s == nil || s.EveryWeeks < 1 {
+		return nil
+	}
+	week := weekStart.Unix() / int64(7*24*time.Hour/time.Second)
+	if (week+m.ID)%int64(s.EveryWeeks) != 0 {
+		return nil
+	}
+	return s
+}
+
+// sponsorCaption formats a sponsor message as a clearly separated caption footer.
+func sponsorCaption(s *models.SponsorMessage) string {
+	text := "\n\n💛 Підтримка проєкту: " + s.Message
+	if s.LinkURL != "" {
+		text += "\n" + s.LinkURL
+	}
+	return text
+}
+
+// eventsHash fingerprints everything a week graph is rendered from. Today's bar
+// is drawn up to "now", so the hash also rolls over every graphMaxStale to let
+// the current day keep filling in on quiet days.
+func eventsHash(weekStart, now time.Time, caption string, events []*models.StatusEvent) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d|%d|%s\n", weekStart.Unix(), now.Unix()/int64(graphMaxStale/time.Second), caption)
+	for _, e := range events {
+		fmt.Fprintf(h, "%d:%t\n", e.Timestamp.Unix(), e.IsOnline)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
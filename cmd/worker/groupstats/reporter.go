@@ -0,0 +1,149 @@
+// Package groupstats posts a weekly comparison of measured and scheduled
+// outage hours per outage group to the city aggregate channels.
+package groupstats
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"strings"
+	"time"
+
+	"no-lights-monitor/cmd/worker/incident"
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/mq"
+	"no-lights-monitor/internal/outage"
+)
+
+const (
+	// reportDays is the window of one report, ending at today's Kyiv midnight.
+	reportDays = 7
+	// A group is flagged when it was measured offline more than overShare
+	// times its scheduled hours, and at least overMinHours more.
+	overShare    = 1.2
+	overMinHours = 1.0
+)
+
+// Reporter builds and publishes the weekly report of every city. Scheduled weekly.
+type Reporter struct {
+	db           *database.DB
+	publisher    *mq.Publisher
+	outageClient *outage.Client
+	cities       []incident.City
+	minMonitors  int // groups measured by fewer monitors are left out
+	kyiv         *time.Location
+}
+
+func NewReporter(db *database.DB, publisher *mq.Publisher, oc *outage.Client, cities []incident.City, minMonitors int) *Reporter {
+	kyiv, _ := time.LoadLocation("Europe/Kyiv")
+	return &Reporter{db: db, publisher: publisher, outageClient: oc, cities: cities, minMonitors: minMonitors, kyiv: kyiv}
+}
+
+// Run posts the report of every configured city.
+func (r *Reporter) Run(ctx context.Context) error {
+	for _, c := range r.cities {
+		if err := r.report(ctx, c, time.Now()); err != nil {
+			log.Printf("[groupstats] %s: %v", c.Region, err)
+		}
+	}
+	return nil
+}
+
+// groupRow is one line of a report.
+type groupRow struct {
+	group     string
+	monitors  int
+	measured  float64
+	scheduled float64
+	known     bool // the schedule has the group
+}
+
+// over reports whether the group was off notably longer than scheduled.
+func (g groupRow) over() bool {
+	return g.known && g.measured > g.scheduled*overShare && g.measured-g.scheduled >= overMinHours
+}
+
+func (r *Reporter) report(ctx context.Context, c incident.City, now time.Time) error {
+	sched, err := r.outageClient.GetScheduledHours(c.Region, reportDays)
+	if err != nil {
+		return fmt.Errorf("scheduled hours: %w", err)
+	}
+	if len(sched.Days) == 0 {
+		log.Printf("[groupstats] %s: no schedule history yet, skipped", c.Region)
+		return nil
+	}
+
+	// Measure over the days the schedule covers, so both sides are comparable.
+	local := now.In(r.kyiv)
+	to := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, r.kyiv)
+	from, err := time.ParseInLocation("2006-01-02", sched.Days[0], r.kyiv)
+	if err != nil {
+		return fmt.Errorf("schedule day %q: %w", sched.Days[0], err)
+	}
+	measured, err := r.db.GetGroupOutageHours(ctx, c.Region, from, to)
+	if err != nil {
+		return fmt.Errorf("measured hours: %w", err)
+	}
+
+	var rows []groupRow
+	for _, m := range measured {
+		if m.Monitors < r.minMonitors {
+			continue
+		}
+		scheduled, known := sched.Groups[m.OutageGroup]
+		rows = append(rows, groupRow{group: m.OutageGroup, monitors: m.Monitors, measured: m.OfflineHours, scheduled: scheduled, known: known})
+	}
+	if len(rows) == 0 {
+		log.Printf("[groupstats] %s: no group with %d+ monitors, skipped", c.Region, r.minMonitors)
+		return nil
+	}
+
+	text := r.text(c, from, to, len(sched.Days), rows)
+	if err := r.publisher.Publish(ctx, mq.RoutingBroadcast, mq.BroadcastMsg{ChannelID: c.ChannelID, Text: text}); err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+	log.Printf("[groupstats] %s: posted %d group(s) to channel %d", c.Region, len(rows), c.ChannelID)
+	return nil
+}
+
+// text builds the HTML post of a city's report.
+//
+//	📊 <b>Відключення за тиждень: Київ</b>
+//	<i>09.06 – 15.06</i>
+//
+//	Черга 1.1: 18 год (за графіком 16 год)
+//	⚠️ Черга 3.2: 31 год (за графіком 20 год, +55%)
+func (r *Reporter) text(c incident.City, from, to time.Time, days int, rows []groupRow) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "📊 <b>Відключення за тиждень: %s</b>\n", html.EscapeString(c.Name))
+	fmt.Fprintf(&sb, "<i>%s – %s</i>\n\n", from.Format("02.01"), to.AddDate(0, 0, -1).Format("02.01"))
+
+	flagged := false
+	for _, g := range rows {
+		group := html.EscapeString(g.group)
+		switch {
+		case !g.known:
+			fmt.Fprintf(&sb, "Черга %s: %.0f год (графік невідомий)\n", group, g.measured)
+		case g.over():
+			flagged = true
+			extra := ""
+			if g.scheduled > 0 {
+				extra = fmt.Sprintf(", +%.0f%%", (g.measured/g.scheduled-1)*100)
+			}
+			fmt.Fprintf(&sb, "⚠️ <b>Черга %s: %.0f год</b> (за графіком %.0f год%s)\n", group, g.measured, g.scheduled, extra)
+		default:
+			fmt.Fprintf(&sb, "Черга %s: %.0f год (за графіком %.0f год)\n", group, g.measured, g.scheduled)
+		}
+	}
+
+	sb.WriteString("\n")
+	if flagged {
+		sb.WriteString("⚠️ — світла не було помітно довше, ніж за графіком.\n")
+	}
+	if days < reportDays {
+		fmt.Fprintf(&sb, "<i>Графік відомий лише за %d з %d днів.</i>\n", days, reportDays)
+	}
+	sb.WriteString("<i>Середнє за даними моніторів спільноти, без адрес.</i>")
+	return sb.String()
+}
@@ -0,0 +1,130 @@
+package heartbeat
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"no-lights-monitor/internal/metrics"
+	"no-lights-monitor/internal/models"
+)
+
+// Flap detection: a monitor changing status more than flapMax times within
+// flapWindow (a failing relay, a loose plug) gets one aggregated "unstable
+// power" notice instead of a channel message per change. Changes are still
+// recorded and still reach webhooks, SMS and push. Once the monitor keeps its
+// status for a whole window, its current status is sent through the regular
+// notifier and channel messages resume. Transition logs and the flapping state
+// live in Redis, so a restart or another worker replica picks them up.
+
+// FlapAlerter delivers the notices of flapping monitors.
+type FlapAlerter interface {
+	// NotifyFlapping announces that sc's monitor started flapping after
+	// changes status changes within window.
+	NotifyFlapping(sc models.StatusChange, changes int, window time.Duration)
+}
+
+// SetFlapDetection enables flap detection: more than maxChanges status
+// changes within window switch a monitor to a single notice sent through
+// alerter. maxChanges 0 disables it.
+func (s *Service) SetFlapDetection(maxChanges int, window time.Duration, alerter FlapAlerter) {
+	s.flapMax = maxChanges
+	s.flapWindow = window
+	s.flapAlerter = alerter
+}
+
+// loadFlapping restores the flapping state of loaded monitors from Redis.
+func (s *Service) loadFlapping(ctx context.Context) {
+	if s.flapMax <= 0 {
+		return
+	}
+	flapping, err := s.cache.GetFlapping(ctx)
+	if err != nil {
+		log.Printf("[heartbeat] load flapping monitors error: %v", err)
+		return
+	}
+	restored := 0
+	s.monitors.Range(func(_, value any) bool {
+		info := value.(*monitorInfo)
+		info.mu.Lock()
+		if since, ok := flapping[info.ID]; ok {
+			info.FlappingSince = since
+			restored++
+		}
+		info.mu.Unlock()
+		return true
+	})
+	metrics.FlappingMonitors.Set(float64(restored))
+	if restored > 0 {
+		log.Printf("[heartbeat] %d monitors are flapping", restored)
+	}
+}
+
+// trackFlap records a status change of info's monitor. It reports whether the
+// monitor is flapping, in which case the change gets no channel message, and
+// whether this change started it.
+func (s *Service) trackFlap(ctx context.Context, info *monitorInfo, monitorID int64, now time.Time) (flapping, started bool, changes int) {
+	if s.flapMax <= 0 {
+		return false, false, 0
+	}
+	changes, err := s.cache.RecordTransition(ctx, monitorID, now, s.flapWindow)
+	if err != nil {
+		// Without the log, fall back to the last known state.
+		log.Printf("[heartbeat] record transition of monitor %d: %v", monitorID, err)
+	}
+
+	info.mu.Lock()
+	if info.FlappingSince.IsZero() && err == nil && changes > s.flapMax {
+		info.FlappingSince = now
+		started = true
+	}
+	flapping = !info.FlappingSince.IsZero()
+	info.mu.Unlock()
+
+	if started {
+		metrics.FlappingMonitors.Inc()
+		log.Printf("[heartbeat] monitor %d is flapping: %d changes within %s", monitorID, changes, s.flapWindow)
+		if err := s.cache.SetFlapping(ctx, monitorID, now); err != nil {
+			log.Printf("[heartbeat] set flapping for monitor %d: %v", monitorID, err)
+		}
+	}
+	return flapping, started, changes
+}
+
+// settleFlap ends the flapping state of a monitor that kept its status for a
+// whole window and returns its last change to post to the channel; ok is
+// false if the monitor isn't flapping or hasn't settled yet. The change is
+// marked TelegramOnly: integrations already got it while the monitor flapped.
+func (s *Service) settleFlap(ctx context.Context, info *monitorInfo, monitorID int64, now time.Time) (sc models.StatusChange, ok bool) {
+	info.mu.Lock()
+	if info.FlappingSince.IsZero() || now.Sub(info.LastChange) < s.flapWindow || now.Sub(info.FlappingSince) < s.flapWindow {
+		info.mu.Unlock()
+		return sc, false
+	}
+	since := info.FlappingSince
+	info.FlappingSince = time.Time{}
+	sc = models.StatusChange{
+		MonitorID:     monitorID,
+		ChannelID:     info.ChannelID,
+		Name:          info.Name,
+		Address:       info.Address,
+		NotifyAddress: info.NotifyAddress,
+		IsOnline:      info.IsOnline,
+		Duration:      info.LastDuration,
+		When:          info.LastChange,
+		OutageRegion:  info.OutageRegion,
+		OutageGroup:   info.OutageGroup,
+		NotifyOutage:  info.NotifyOutage,
+		NotifyStyle:   info.NotifyStyle,
+		PlannedMode:   info.PlannedMode,
+		TelegramOnly:  true,
+	}
+	info.mu.Unlock()
+
+	metrics.FlappingMonitors.Dec()
+	log.Printf("[heartbeat] monitor %d stopped flapping (flapping since %s)", monitorID, since.Format(time.RFC3339))
+	if err := s.cache.ClearFlapping(ctx, monitorID); err != nil {
+		log.Printf("[heartbeat] clear flapping for monitor %d: %v", monitorID, err)
+	}
+	return sc, true
+}
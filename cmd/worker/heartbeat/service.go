@@ -4,41 +4,72 @@ import (
 	"context"
 	"errors"
 	"log"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/redis/go-redis/v9"
 
 	"no-lights-monitor/internal/cache"
 	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/maintenance"
 	"no-lights-monitor/internal/metrics"
 	"no-lights-monitor/internal/models"
 	"no-lights-monitor/internal/ping"
+	"no-lights-monitor/internal/safego"
+	"no-lights-monitor/internal/workpool"
+)
+
+const (
+	// fullRefreshInterval bounds how long a change that bypassed the
+	// monitor_changes log can go unnoticed by the checker.
+	fullRefreshInterval = 5 * time.Minute
+	// changeSyncBatch caps the events applied per checker tick.
+	changeSyncBatch = 1000
+	// changeLookback is how long a skipped monitor_changes ID is re-read in
+	// case its transaction commits after later IDs were applied.
+	changeLookback = time.Minute
 )
 
 // Notifier sends Telegram messages on status changes.
 type Notifier interface {
-	NotifyStatusChange(monitorID, channelID int64, name, address string, notifyAddress, isOnline bool, duration time.Duration, when time.Time, outageRegion, outageGroup string, notifyOutage bool)
+	NotifyStatusChange(sc models.StatusChange)
+}
+
+// PowerReturnNotifier is told about every offline → online transition,
+// whether or not its notifications are suppressed.
+type PowerReturnNotifier interface {
+	NotifyPowerReturn(sc models.StatusChange)
 }
 
 // monitorInfo is the in-memory representation used for fast ping lookups.
 type monitorInfo struct {
 	ID          int64
+	UserID      int64
 	ChannelID   int64
 	Name        string
 	Address     string
 	Latitude    float64
 	Longitude   float64
-	MonitorType string // "heartbeat" or "ping"
-	PingTarget  string // IP/hostname for ping monitors
+	MonitorType string // "heartbeat", "ping" or "http"
+	PingTarget  string // IP/hostname for ping monitors, URL for http monitors
 	IsOnline            bool
 	IsActive            bool // whether monitoring is enabled
 	NotifyAddress       bool
 	OutageRegion        string
 	OutageGroup         string
 	NotifyOutage        bool
+	NotifyStyle         string // notification wording preset
+	PlannedMode         string // delivery of schedule-predicted changes (outage.PlannedModes)
 	OfflineThresholdSec int
+	OnlineConfirmSec    int       // fresh heartbeats needed this long before going online
+	LatencyGraph        bool      // ping monitors: keep RTT samples for the latency graph
+	PendingOnlineSince  time.Time // first fresh check while waiting for confirmation
+	MutedUntil          time.Time // status notifications are skipped until then
+	FlappingSince       time.Time // set while changes are too frequent to notify one by one
 	LastChange          time.Time
+	LastDuration        time.Duration // time spent in the state before LastChange
 	mu                  sync.Mutex
 }
 
@@ -48,23 +79,75 @@ type Service struct {
 	db          *database.DB
 	cache       *cache.Cache
 	notifier    Notifier
+	powerReturn PowerReturnNotifier
 	threshold   time.Duration
 	startupTime time.Time // when the service started, used for grace period
 
 	devModeMu   sync.Mutex
 	lastDevMode bool
 	devModeOffAt time.Time // when dev mode was last disabled, used for grace period
+
+	vantage        string        // this worker's name when voting on ping monitors
+	probeFreshness time.Duration // how long a vantage's vote counts towards the quorum
+
+	changeCursor    int64               // last applied monitor_changes ID
+	changeGaps      map[int64]time.Time // skipped IDs below changeCursor → when first missed
+	lastFullRefresh time.Time           // when the whole monitor table was last re-read
+	syncNow         chan struct{}       // asks the heartbeat checker for an early change sync
+
+	maintenanceMu sync.RWMutex
+	maintenance   map[int64][]models.MaintenanceWindow // monitor ID → windows without notifications
+
+	bansMu sync.RWMutex
+	banned map[int64]struct{} // user IDs of banned owners, whose monitors are frozen
+
+	flapMax     int           // status changes within flapWindow that mean flapping (0 = off)
+	flapWindow  time.Duration
+	flapAlerter FlapAlerter
+
+	pingPool *workpool.Pool // ICMP pings
+	httpPool *workpool.Pool // HTTP(S) checks
+	dbPool   *workpool.Pool // status writes
+	mqPool   *workpool.Pool // status change notifications
 }
 
-func NewService(db *database.DB, c *cache.Cache, notifier Notifier, thresholdSec int) *Service {
+// Limits caps how much concurrent work the service fans out.
+type Limits struct {
+	Ping      int
+	HTTP      int
+	DBWrite   int
+	MQPublish int
+}
+
+func NewService(db *database.DB, c *cache.Cache, notifier Notifier, thresholdSec int, limits Limits) *Service {
 	return &Service{
 		db:        db,
 		cache:     c,
 		notifier:  notifier,
 		threshold: time.Duration(thresholdSec) * time.Second,
+		syncNow:   make(chan struct{}, 1),
+		pingPool:  workpool.New("ping", limits.Ping),
+		httpPool:  workpool.New("http", limits.HTTP),
+		dbPool:    workpool.New("db", limits.DBWrite),
+		mqPool:    workpool.New("mq", limits.MQPublish),
 	}
 }
 
+// SetVantage names this worker as a probe vantage point. Ping monitors are then
+// judged by quorum over the votes of every vantage (this worker and remote
+// probe agents) seen within freshness, so one network path failing can't flip
+// them all offline. With no remote votes the local verdict decides alone.
+func (s *Service) SetVantage(name string, freshness time.Duration) {
+	s.vantage = name
+	s.probeFreshness = freshness
+}
+
+// SetPowerReturn sets who is told about power returns, independently of the
+// notifier and of everything that suppresses notifications.
+func (s *Service) SetPowerReturn(p PowerReturnNotifier) {
+	s.powerReturn = p
+}
+
 // SetNotifier sets the notifier (used to break circular dependency at startup).
 func (s *Service) SetNotifier(n Notifier) {
 	s.notifier = n
@@ -73,7 +156,13 @@ func (s *Service) SetNotifier(n Notifier) {
 // LoadMonitors reads all monitors from the DB into the in-memory map.
 // It also records the startup time for grace period handling.
 func (s *Service) LoadMonitors(ctx context.Context) error {
-	monitors, err := s.db.GetAllMonitors(ctx)
+	// Take the change cursor first so edits racing the load are replayed.
+	cursor, err := s.db.LatestMonitorChangeID(ctx)
+	if err != nil {
+		return err
+	}
+	s.loadBans(ctx)
+	monitors, err := s.db.GetCheckedMonitors(ctx)
 	if err != nil {
 		return err
 	}
@@ -84,6 +173,7 @@ func (s *Service) LoadMonitors(ctx context.Context) error {
 	for _, m := range monitors {
 		s.monitors.Store(m.Token, &monitorInfo{
 			ID:                  m.ID,
+			UserID:              m.UserID,
 			ChannelID:           m.ChannelID,
 			Name:                m.Name,
 			Address:             m.Address,
@@ -97,10 +187,19 @@ func (s *Service) LoadMonitors(ctx context.Context) error {
 			OutageRegion:        m.OutageRegion,
 			OutageGroup:         m.OutageGroup,
 			NotifyOutage:        m.NotifyOutage,
+			NotifyStyle:         m.NotifyStyle,
+			PlannedMode:         m.PlannedOutageMode,
 			OfflineThresholdSec: m.OfflineThresholdSec,
+			OnlineConfirmSec:    m.OnlineConfirmSec,
+			LatencyGraph:        m.LatencyGraphEnabled,
+			MutedUntil:          mutedUntil(m),
 			LastChange:          m.LastStatusChangeAt,
 		})
 	}
+	s.loadMaintenance(ctx)
+	s.loadFlapping(ctx)
+	s.changeCursor = cursor
+	s.lastFullRefresh = time.Now()
 	metrics.ActiveMonitors.Set(float64(len(monitors)))
 	log.Printf("[heartbeat] loaded %d monitors into memory (grace period: %s)", len(monitors), s.threshold)
 	return nil
@@ -111,6 +210,7 @@ func (s *Service) RegisterMonitor(m *models.Monitor) {
 	metrics.ActiveMonitors.Inc()
 	s.monitors.Store(m.Token, &monitorInfo{
 		ID:                  m.ID,
+		UserID:              m.UserID,
 		ChannelID:           m.ChannelID,
 		Name:                m.Name,
 		Address:             m.Address,
@@ -124,7 +224,12 @@ func (s *Service) RegisterMonitor(m *models.Monitor) {
 		OutageRegion:        m.OutageRegion,
 		OutageGroup:         m.OutageGroup,
 		NotifyOutage:        m.NotifyOutage,
+		NotifyStyle:         m.NotifyStyle,
+		PlannedMode:         m.PlannedOutageMode,
 		OfflineThresholdSec: m.OfflineThresholdSec,
+		OnlineConfirmSec:    m.OnlineConfirmSec,
+		LatencyGraph:        m.LatencyGraphEnabled,
+		MutedUntil:          mutedUntil(m),
 		LastChange:          m.LastStatusChangeAt,
 	})
 }
@@ -207,7 +312,8 @@ func (s *Service) RemoveMonitor(token string) {
 // refreshMonitors re-reads all monitors from the DB and updates the in-memory map.
 // New monitors are added, deleted monitors are removed, and changed fields are synced.
 func (s *Service) refreshMonitors(ctx context.Context) {
-	monitors, err := s.db.GetAllMonitors(ctx)
+	s.loadBans(ctx)
+	monitors, err := s.db.GetCheckedMonitors(ctx)
 	if err != nil {
 		log.Printf("[heartbeat] refresh monitors error: %v", err)
 		return
@@ -218,63 +324,251 @@ func (s *Service) refreshMonitors(ctx context.Context) {
 
 	for _, m := range monitors {
 		dbTokens[m.Token] = struct{}{}
+		s.upsertMonitor(m)
+	}
 
-		val, ok := s.monitors.Load(m.Token)
-		if !ok {
-			// New monitor — add to map.
-			s.monitors.Store(m.Token, &monitorInfo{
-				ID:                  m.ID,
-				ChannelID:           m.ChannelID,
-				Name:                m.Name,
-				Address:             m.Address,
-				Latitude:            m.Latitude,
-				Longitude:           m.Longitude,
-				MonitorType:         m.MonitorType,
-				PingTarget:          m.PingTarget,
-				IsOnline:            m.IsOnline,
-				IsActive:            m.IsActive,
-				NotifyAddress:       m.NotifyAddress,
-				OutageRegion:        m.OutageRegion,
-				OutageGroup:         m.OutageGroup,
-				NotifyOutage:        m.NotifyOutage,
-				OfflineThresholdSec: m.OfflineThresholdSec,
-				LastChange:          m.LastStatusChangeAt,
-			})
-			continue
+	// Remove monitors that no longer exist in DB.
+	s.monitors.Range(func(key, value any) bool {
+		token := key.(string)
+		if _, exists := dbTokens[token]; !exists {
+			s.monitors.Delete(token)
 		}
+		return true
+	})
+	s.loadMaintenance(ctx)
+}
 
-		// Existing monitor — update mutable fields.
-		info := val.(*monitorInfo)
-		info.mu.Lock()
-		info.Name = m.Name
-		info.Address = m.Address
-		info.Latitude = m.Latitude
-		info.Longitude = m.Longitude
-		info.ChannelID = m.ChannelID
-		info.IsActive = m.IsActive
-		info.NotifyAddress = m.NotifyAddress
-		info.OutageRegion = m.OutageRegion
-		info.OutageGroup = m.OutageGroup
-		info.NotifyOutage = m.NotifyOutage
-		info.PingTarget = m.PingTarget
-		info.OfflineThresholdSec = m.OfflineThresholdSec
-		info.mu.Unlock()
+// loadMaintenance re-reads all maintenance windows. On error the previous
+// windows stay in effect.
+func (s *Service) loadMaintenance(ctx context.Context) {
+	windows, err := s.db.GetMaintenanceWindows(ctx)
+	if err != nil {
+		log.Printf("[heartbeat] load maintenance windows error: %v", err)
+		return
 	}
+	s.maintenanceMu.Lock()
+	s.maintenance = windows
+	s.maintenanceMu.Unlock()
+}
 
-	// Remove monitors that no longer exist in DB.
+// loadBans re-reads the banned owners. On error the previous set stays in effect.
+func (s *Service) loadBans(ctx context.Context) {
+	ids, err := s.db.GetBannedUserIDs(ctx)
+	if err != nil {
+		log.Printf("[heartbeat] load banned users error: %v", err)
+		return
+	}
+	banned := make(map[int64]struct{}, len(ids))
+	for _, id := range ids {
+		banned[id] = struct{}{}
+	}
+	s.bansMu.Lock()
+	s.banned = banned
+	s.bansMu.Unlock()
+}
+
+// ownerBanned reports whether userID is a banned owner.
+func (s *Service) ownerBanned(userID int64) bool {
+	s.bansMu.RLock()
+	defer s.bansMu.RUnlock()
+	_, ok := s.banned[userID]
+	return ok
+}
+
+// inMaintenance reports whether monitorID is inside one of its maintenance windows at t.
+func (s *Service) inMaintenance(monitorID int64, t time.Time) bool {
+	s.maintenanceMu.RLock()
+	defer s.maintenanceMu.RUnlock()
+	return maintenance.Active(s.maintenance[monitorID], t)
+}
+
+// upsertMonitor adds m to the in-memory map, or syncs the mutable fields of
+// an existing entry. Online state and last change are owned by the checker
+// and left alone for existing entries.
+func (s *Service) upsertMonitor(m *models.Monitor) {
+	val, ok := s.monitors.Load(m.Token)
+	if !ok {
+		s.monitors.Store(m.Token, &monitorInfo{
+			ID:                  m.ID,
+			UserID:              m.UserID,
+			ChannelID:           m.ChannelID,
+			Name:                m.Name,
+			Address:             m.Address,
+			Latitude:            m.Latitude,
+			Longitude:           m.Longitude,
+			MonitorType:         m.MonitorType,
+			PingTarget:          m.PingTarget,
+			IsOnline:            m.IsOnline,
+			IsActive:            m.IsActive,
+			NotifyAddress:       m.NotifyAddress,
+			OutageRegion:        m.OutageRegion,
+			OutageGroup:         m.OutageGroup,
+			NotifyOutage:        m.NotifyOutage,
+			NotifyStyle:         m.NotifyStyle,
+			PlannedMode:         m.PlannedOutageMode,
+			OfflineThresholdSec: m.OfflineThresholdSec,
+			OnlineConfirmSec:    m.OnlineConfirmSec,
+			LatencyGraph:        m.LatencyGraphEnabled,
+			MutedUntil:          mutedUntil(m),
+			LastChange:          m.LastStatusChangeAt,
+		})
+		return
+	}
+
+	info := val.(*monitorInfo)
+	info.mu.Lock()
+	info.UserID = m.UserID
+	info.Name = m.Name
+	info.Address = m.Address
+	info.Latitude = m.Latitude
+	info.Longitude = m.Longitude
+	info.ChannelID = m.ChannelID
+	info.IsActive = m.IsActive
+	info.NotifyAddress = m.NotifyAddress
+	info.OutageRegion = m.OutageRegion
+	info.OutageGroup = m.OutageGroup
+	info.NotifyOutage = m.NotifyOutage
+	info.NotifyStyle = m.NotifyStyle
+	info.PlannedMode = m.PlannedOutageMode
+	info.PingTarget = m.PingTarget
+	info.OfflineThresholdSec = m.OfflineThresholdSec
+	info.OnlineConfirmSec = m.OnlineConfirmSec
+	info.LatencyGraph = m.LatencyGraphEnabled
+	info.MutedUntil = mutedUntil(m)
+	info.mu.Unlock()
+}
+
+// mutedUntil returns the end of the monitor's mute, or zero if it isn't muted.
+func mutedUntil(m *models.Monitor) time.Time {
+	if m.MutedUntil == nil {
+		return time.Time{}
+	}
+	return *m.MutedUntil
+}
+
+// dropMonitorByID removes every in-memory entry of monitor id whose token is
+// not keep. Used when a monitor is deleted (keep "") or its token regenerated.
+func (s *Service) dropMonitorByID(id int64, keep string) {
 	s.monitors.Range(func(key, value any) bool {
 		token := key.(string)
-		if _, exists := dbTokens[token]; !exists {
+		if token != keep && value.(*monitorInfo).ID == id {
 			s.monitors.Delete(token)
 		}
 		return true
 	})
 }
 
+// syncMonitors applies the monitor_changes events recorded since the last
+// sync, reloading only the monitors they touch. Change IDs can commit out of
+// order under concurrent writers, so IDs skipped over are re-read for
+// changeLookback. Some writes bypass the event log (manual SQL, restores), so
+// a full refresh still runs every fullRefreshInterval as a safety net.
+func (s *Service) syncMonitors(ctx context.Context) {
+	if time.Since(s.lastFullRefresh) >= fullRefreshInterval {
+		latest, err := s.db.LatestMonitorChangeID(ctx)
+		if err != nil {
+			log.Printf("[heartbeat] latest change id error: %v", err)
+			return
+		}
+		s.refreshMonitors(ctx)
+		s.changeCursor = latest
+		s.changeGaps = nil
+		s.lastFullRefresh = time.Now()
+		return
+	}
+
+	// Re-read from the oldest gap still within changeLookback; older gaps
+	// were rolled back or are left to the full refresh.
+	now := time.Now()
+	after := s.changeCursor
+	for id, missed := range s.changeGaps {
+		if now.Sub(missed) >= changeLookback {
+			delete(s.changeGaps, id)
+		} else if id <= after {
+			after = id - 1
+		}
+	}
+	changes, err := s.db.GetMonitorChangesAfter(ctx, after, changeSyncBatch)
+	if err != nil {
+		log.Printf("[heartbeat] monitor changes error: %v", err)
+		return
+	}
+
+	touched := make(map[int64]struct{})
+	moved, maintenanceChanged, bansChanged := false, false, false
+	for _, ch := range changes {
+		if ch.ID <= s.changeCursor {
+			if _, ok := s.changeGaps[ch.ID]; !ok {
+				continue // already applied
+			}
+			delete(s.changeGaps, ch.ID)
+		} else {
+			if s.changeGaps == nil {
+				s.changeGaps = make(map[int64]time.Time)
+			}
+			for id := max(s.changeCursor+1, ch.ID-changeSyncBatch); id < ch.ID; id++ {
+				s.changeGaps[id] = now
+			}
+			s.changeCursor = ch.ID
+		}
+		touched[ch.MonitorID] = struct{}{}
+		if ch.Field == "address" || ch.Field == "coordinates" {
+			moved = true
+		}
+		if ch.Field == database.ChangeFieldMaintenance {
+			maintenanceChanged = true
+		}
+		if ch.Field == database.ChangeFieldOwnerBanned {
+			bansChanged = true
+		}
+	}
+	if maintenanceChanged {
+		s.loadMaintenance(ctx)
+	}
+	if bansChanged {
+		s.loadBans(ctx)
+	}
+	// The bot has no Redis of its own; relocations it records reach the
+	// public map through this announcement.
+	if moved {
+		if err := s.cache.PublishMonitorsChanged(ctx, 0); err != nil {
+			log.Printf("[heartbeat] announce relocated monitors: %v", err)
+		}
+	}
+
+	for id := range touched {
+		if err := s.reloadMonitor(ctx, id); err != nil {
+			log.Printf("[heartbeat] reload monitor %d error: %v", id, err)
+			s.lastFullRefresh = time.Time{} // catch up with a full refresh next tick
+		}
+	}
+}
+
+// reloadMonitor re-reads one monitor into memory, dropping it when it was
+// deleted or its owner is banned.
+func (s *Service) reloadMonitor(ctx context.Context, id int64) error {
+	m, err := s.db.GetMonitorByID(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		s.dropMonitorByID(id, "")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if s.ownerBanned(m.UserID) {
+		s.dropMonitorByID(id, "")
+		return nil
+	}
+	s.dropMonitorByID(id, m.Token)
+	s.upsertMonitor(m)
+	return nil
+}
+
 // StartHeartbeatChecker runs a background loop that checks heartbeat monitors
 // (devices that send pings to the API) for stale heartbeats.
 func (s *Service) StartHeartbeatChecker(ctx context.Context, intervalSec int) {
-	ticker := time.NewTicker(time.Duration(intervalSec) * time.Second)
+	interval := time.Duration(intervalSec) * time.Second
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	log.Printf("[heartbeat] heartbeat checker started (interval=%ds, threshold=%s)", intervalSec, s.threshold)
@@ -285,15 +579,39 @@ func (s *Service) StartHeartbeatChecker(ctx context.Context, intervalSec int) {
 			log.Println("[heartbeat] heartbeat checker stopped")
 			return
 		case <-ticker.C:
-			s.checkHeartbeatMonitors(ctx)
+			_ = safego.Run("heartbeat_checker", func() { s.checkHeartbeatMonitors(ctx, interval) })
+		case <-s.syncNow:
+			_ = safego.Run("monitor_sync", func() { s.syncMonitors(ctx) })
 		}
 	}
 }
 
+// WatchSettings makes settings saved through the settings page and the API
+// take effect right away: the saved monitor is reloaded as soon as its
+// notification arrives. The change sync on the heartbeat checker still applies
+// the save's audit records (relocations, maintenance) at its next tick; after
+// a lost connection, when saves may have been missed, it is asked to sync early.
+func (s *Service) WatchSettings(ctx context.Context) {
+	s.db.ListenMonitorSettings(ctx, func(monitorID int64) {
+		if monitorID != 0 {
+			err := s.reloadMonitor(ctx, monitorID)
+			if err == nil {
+				return
+			}
+			log.Printf("[heartbeat] reload saved monitor %d error: %v", monitorID, err)
+		}
+		select {
+		case s.syncNow <- struct{}{}:
+		default: // a sync is already pending
+		}
+	})
+}
+
 // StartPingChecker runs a background loop that actively ICMP-pings targets
 // and checks ping monitors for status changes.
 func (s *Service) StartPingChecker(ctx context.Context, intervalSec int) {
-	ticker := time.NewTicker(time.Duration(intervalSec) * time.Second)
+	interval := time.Duration(intervalSec) * time.Second
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	log.Printf("[heartbeat] ping checker started (interval=%ds, threshold=%s)", intervalSec, s.threshold)
@@ -304,7 +622,27 @@ func (s *Service) StartPingChecker(ctx context.Context, intervalSec int) {
 			log.Println("[heartbeat] ping checker stopped")
 			return
 		case <-ticker.C:
-			s.checkPingMonitors(ctx)
+			_ = safego.Run("ping_checker", func() { s.checkPingMonitors(ctx, interval) })
+		}
+	}
+}
+
+// StartHTTPChecker runs a background loop that GETs the URLs of http
+// monitors and checks them for status changes.
+func (s *Service) StartHTTPChecker(ctx context.Context, intervalSec int) {
+	interval := time.Duration(intervalSec) * time.Second
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Printf("[heartbeat] http checker started (interval=%ds, threshold=%s)", intervalSec, s.threshold)
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("[heartbeat] http checker stopped")
+			return
+		case <-ticker.C:
+			_ = safego.Run("http_checker", func() { s.checkHTTPMonitors(ctx, interval) })
 		}
 	}
 }
@@ -335,13 +673,13 @@ func (s *Service) inDevModeGracePeriod(now time.Time) bool {
 
 // checkHeartbeatMonitors checks all heartbeat-type monitors for stale heartbeats
 // and triggers status change notifications when needed.
-func (s *Service) checkHeartbeatMonitors(ctx context.Context) {
+func (s *Service) checkHeartbeatMonitors(ctx context.Context, interval time.Duration) {
 	if s.checkDevMode(ctx) {
 		log.Println("[heartbeat] dev mode enabled — skipping heartbeat checks")
 		return
 	}
 
-	s.refreshMonitors(ctx)
+	s.syncMonitors(ctx)
 
 	now := time.Now()
 	inGracePeriod := now.Sub(s.startupTime) < s.threshold || s.inDevModeGracePeriod(now)
@@ -357,16 +695,17 @@ func (s *Service) checkHeartbeatMonitors(ctx context.Context) {
 		monitorID := info.ID
 		info.mu.Unlock()
 
-		s.checkAndTransition(ctx, info, monitorID, now, inGracePeriod)
+		s.checkAndTransition(ctx, info, monitorID, now, inGracePeriod, interval)
 		return true
 	})
 
 	metrics.WorkerLastCheckUnix.SetToCurrentTime()
 }
 
-// checkPingMonitors first executes all ICMP pings concurrently, then checks
+// checkPingMonitors first executes all ICMP pings concurrently, storing each
+// round's RTT and packet loss for monitors with a latency graph, then checks
 // ping monitors for status changes.
-func (s *Service) checkPingMonitors(ctx context.Context) {
+func (s *Service) checkPingMonitors(ctx context.Context, interval time.Duration) {
 	if s.checkDevMode(ctx) {
 		log.Println("[heartbeat] dev mode enabled — skipping ping checks")
 		return
@@ -375,8 +714,8 @@ func (s *Service) checkPingMonitors(ctx context.Context) {
 	now := time.Now()
 	inGracePeriod := now.Sub(s.startupTime) < s.threshold || s.inDevModeGracePeriod(now)
 
-	// Phase 1: Execute all ICMP pings concurrently.
-	// This ensures even 100 ping monitors complete within ~5 seconds (ping timeout).
+	// Phase 1: Execute ICMP pings concurrently, bounded by the ping pool.
+	// With the default pool size a few hundred targets still finish well within the interval.
 	var wg sync.WaitGroup
 	s.monitors.Range(func(key, value any) bool {
 		info := value.(*monitorInfo)
@@ -387,12 +726,23 @@ func (s *Service) checkPingMonitors(ctx context.Context) {
 		}
 		monitorID := info.ID
 		pingTarget := info.PingTarget
+		latencyGraph := info.LatencyGraph
 		info.mu.Unlock()
 
 		wg.Add(1)
-		go func() {
+		s.pingPool.Go(func() {
 			defer wg.Done()
-			if ping.PingHost(pingTarget) {
+			stats := ping.Probe(pingTarget)
+			if latencyGraph {
+				// Samples only feed the latency graph; writing them must not
+				// hold a ping worker.
+				s.dbPool.Go(func() {
+					if err := s.db.InsertPingSample(ctx, monitorID, now, stats.MedianRTT, stats.Loss()); err != nil {
+						log.Printf("[heartbeat] db ping sample error for ping monitor %d: %v", monitorID, err)
+					}
+				})
+			}
+			if s.reachable(ctx, monitorID, stats.Recv > 0, now) {
 				if err := s.cache.SetHeartbeat(ctx, monitorID, now); err != nil {
 					log.Printf("[heartbeat] redis set error for ping monitor %d: %v", monitorID, err)
 				}
@@ -400,7 +750,7 @@ func (s *Service) checkPingMonitors(ctx context.Context) {
 					log.Printf("[heartbeat] db heartbeat update error for ping monitor %d: %v", monitorID, err)
 				}
 			}
-		}()
+		})
 		return true
 	})
 	wg.Wait()
@@ -417,16 +767,106 @@ func (s *Service) checkPingMonitors(ctx context.Context) {
 		monitorID := info.ID
 		info.mu.Unlock()
 
-		s.checkAndTransition(ctx, info, monitorID, now, inGracePeriod)
+		s.checkAndTransition(ctx, info, monitorID, now, inGracePeriod, interval)
 		return true
 	})
 }
 
+// checkHTTPMonitors first GETs all monitored URLs concurrently, then checks
+// http monitors for status changes. A 2xx answer within ping.HTTPTimeout
+// counts as a heartbeat. Remote probe agents only ping, so there is no quorum.
+func (s *Service) checkHTTPMonitors(ctx context.Context, interval time.Duration) {
+	if s.checkDevMode(ctx) {
+		log.Println("[heartbeat] dev mode enabled — skipping http checks")
+		return
+	}
+
+	now := time.Now()
+	inGracePeriod := now.Sub(s.startupTime) < s.threshold || s.inDevModeGracePeriod(now)
+
+	// Phase 1: Execute HTTP checks concurrently, bounded by the http pool.
+	var wg sync.WaitGroup
+	s.monitors.Range(func(key, value any) bool {
+		info := value.(*monitorInfo)
+		info.mu.Lock()
+		if !info.IsActive || info.MonitorType != "http" || info.PingTarget == "" {
+			info.mu.Unlock()
+			return true
+		}
+		monitorID := info.ID
+		target := info.PingTarget
+		info.mu.Unlock()
+
+		wg.Add(1)
+		s.httpPool.Go(func() {
+			defer wg.Done()
+			if ping.CheckURL(ctx, target) {
+				if err := s.cache.SetHeartbeat(ctx, monitorID, now); err != nil {
+					log.Printf("[heartbeat] redis set error for http monitor %d: %v", monitorID, err)
+				}
+				if err := s.db.UpdateMonitorHeartbeat(ctx, monitorID, now); err != nil {
+					log.Printf("[heartbeat] db heartbeat update error for http monitor %d: %v", monitorID, err)
+				}
+			}
+		})
+		return true
+	})
+	wg.Wait()
+
+	// Phase 2: Check all http monitors for status changes.
+	s.monitors.Range(func(key, value any) bool {
+		info := value.(*monitorInfo)
+
+		info.mu.Lock()
+		if !info.IsActive || info.MonitorType != "http" {
+			info.mu.Unlock()
+			return true
+		}
+		monitorID := info.ID
+		info.mu.Unlock()
+
+		s.checkAndTransition(ctx, info, monitorID, now, inGracePeriod, interval)
+		return true
+	})
+}
+
+// reachable combines this worker's ping result with the fresh votes of the
+// other vantages. A target counts as reachable when at least half of the votes
+// say so: a tie gives it the benefit of the doubt rather than a false alert.
+func (s *Service) reachable(ctx context.Context, monitorID int64, localOK bool, now time.Time) bool {
+	if s.vantage == "" {
+		return localOK
+	}
+	if err := s.cache.RecordProbe(ctx, monitorID, cache.WorkerVantage(s.vantage), localOK, now); err != nil {
+		log.Printf("[heartbeat] redis probe vote error for ping monitor %d: %v", monitorID, err)
+		return localOK
+	}
+	ok, total, err := s.cache.ProbeVotes(ctx, monitorID, now.Add(-s.probeFreshness))
+	if err != nil || total == 0 {
+		return localOK
+	}
+	quorum := ok*2 >= total
+	if quorum != localOK {
+		metrics.ProbeDisagreements.WithLabelValues(strconv.FormatBool(quorum)).Inc()
+		log.Printf("[heartbeat] ping monitor %d: local probe says %v, quorum says %v (%d/%d reachable)", monitorID, localOK, quorum, ok, total)
+	}
+	return quorum
+}
+
 // checkAndTransition reads the heartbeat from Redis and updates the monitor's
-// online/offline state, firing notifications on transitions.
-func (s *Service) checkAndTransition(ctx context.Context, info *monitorInfo, monitorID int64, now time.Time, inGracePeriod bool) {
+// online/offline state, firing notifications on transitions. A monitor with
+// extra devices counts as fresh while any of them pings.
+func (s *Service) checkAndTransition(ctx context.Context, info *monitorInfo, monitorID int64, now time.Time, inGracePeriod bool, interval time.Duration) {
+	// A ban freezes the status until the next sync drops the monitor.
+	info.mu.Lock()
+	userID := info.UserID
+	info.mu.Unlock()
+	if s.ownerBanned(userID) {
+		return
+	}
+
 	// Check heartbeat in cache (outside lock - this is an I/O operation).
-	lastHB, err := s.cache.GetHeartbeat(ctx, monitorID)
+	lastHB, err := s.cache.GetLatestHeartbeat(ctx, monitorID)
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			// Redis key doesn't exist (new monitor, Redis restarted and lost data).
@@ -456,6 +896,7 @@ func (s *Service) checkAndTransition(ctx context.Context, info *monitorInfo, mon
 	if info.IsOnline && !isFresh && !inGracePeriod {
 		// Online → Offline transition.
 		duration = now.Sub(info.LastChange)
+		info.LastDuration = duration
 		info.IsOnline = false
 		offlineAt := lastHB
 		if offlineAt.IsZero() {
@@ -465,37 +906,105 @@ func (s *Service) checkAndTransition(ctx context.Context, info *monitorInfo, mon
 		statusChanged = true
 		isNowOnline = false
 	} else if !info.IsOnline && isFresh {
-		// Offline → Online transition.
-		duration = now.Sub(info.LastChange)
-		info.IsOnline = true
-		info.LastChange = now
-		statusChanged = true
-		isNowOnline = true
+		// Offline → Online transition, optionally held back until the power has
+		// been stable for OnlineConfirmSec so generator blips don't flap.
+		// Freshness alone spans the whole offline threshold, so confirmation
+		// also needs a heartbeat from the end of the window (give or take one
+		// check interval), not just the one that started it.
+		backAt := now
+		confirm := time.Duration(info.OnlineConfirmSec) * time.Second
+		if info.OnlineConfirmSec > 0 {
+			if info.PendingOnlineSince.IsZero() {
+				info.PendingOnlineSince = now
+			}
+			backAt = info.PendingOnlineSince
+		}
+		if now.Sub(backAt) >= confirm && (confirm == 0 || !lastHB.Before(backAt.Add(confirm-interval))) {
+			duration = backAt.Sub(info.LastChange)
+			info.LastDuration = duration
+			info.IsOnline = true
+			info.LastChange = backAt
+			info.PendingOnlineSince = time.Time{}
+			statusChanged = true
+			isNowOnline = true
+		}
+	} else if !isFresh {
+		info.PendingOnlineSince = time.Time{}
 	}
 
 	// Capture values for async operations.
 	monitorName := info.Name
-	monitorAddress := info.Address
-	notifyAddress := info.NotifyAddress
-	outageRegion := info.OutageRegion
-	outageGroup := info.OutageGroup
-	notifyOutage := info.NotifyOutage
-	channelID := info.ChannelID
+	mutedTill := info.MutedUntil
+	change := models.StatusChange{
+		MonitorID:     monitorID,
+		ChannelID:     info.ChannelID,
+		Name:          info.Name,
+		Address:       info.Address,
+		NotifyAddress: info.NotifyAddress,
+		IsOnline:      isNowOnline,
+		Duration:      duration,
+		When:          info.LastChange,
+		OutageRegion:  info.OutageRegion,
+		OutageGroup:   info.OutageGroup,
+		NotifyOutage:  info.NotifyOutage,
+		NotifyStyle:   info.NotifyStyle,
+		PlannedMode:   info.PlannedMode,
+	}
 	info.mu.Unlock()
 
+	if !statusChanged {
+		if settled, ok := s.settleFlap(ctx, info, monitorID, now); ok && s.notifier != nil && settled.ChannelID != 0 &&
+			!now.Before(mutedTill) && !s.inMaintenance(monitorID, now) {
+			s.mqPool.Go(func() {
+				s.notifier.NotifyStatusChange(settled)
+			})
+		}
+	}
+
 	if statusChanged {
-		go func() {
-			if err := s.db.UpdateMonitorStatus(context.Background(), monitorID, isNowOnline); err != nil {
+		s.dbPool.Go(func() {
+			recorded, err := s.db.UpdateMonitorStatus(context.Background(), monitorID, isNowOnline)
+			if err != nil {
 				log.Printf("[heartbeat] failed to update status for monitor %d: %v", monitorID, err)
+			} else if !recorded {
+				log.Printf("[heartbeat] monitor %d already recorded as online=%v, skipped duplicate event", monitorID, isNowOnline)
+			} else if err := s.cache.PublishMonitorsChanged(context.Background(), monitorID); err != nil {
+				log.Printf("[heartbeat] announce status change of monitor %d: %v", monitorID, err)
 			}
-		}()
+		})
+
+		if isNowOnline && s.powerReturn != nil {
+			s.mqPool.Go(func() {
+				s.powerReturn.NotifyPowerReturn(change)
+			})
+		}
 
-		if s.notifier != nil && channelID != 0 {
-			when := now
-			if !isNowOnline {
-				when = info.LastChange
+		flapping, flapStarted, flapChanges := s.trackFlap(ctx, info, monitorID, now)
+		muted := now.Before(mutedTill)
+		if s.inMaintenance(monitorID, now) {
+			log.Printf("[heartbeat] monitor %d is in a maintenance window, notification skipped", monitorID)
+		} else {
+			if muted {
+				// A mute silences the channel only; webhooks, SMS and push
+				// still get every change.
+				change.SkipTelegram = true
+				log.Printf("[heartbeat] monitor %d is muted until %s, channel message skipped", monitorID, mutedTill.Format(time.RFC3339))
+			}
+			if flapping {
+				// The flap notice stands in for the channel messages; webhooks,
+				// SMS and push still get every change.
+				change.SkipTelegram = true
+				if flapStarted && !muted && s.flapAlerter != nil && change.ChannelID != 0 {
+					s.mqPool.Go(func() {
+						s.flapAlerter.NotifyFlapping(change, flapChanges, s.flapWindow)
+					})
+				}
+			}
+			if s.notifier != nil && change.ChannelID != 0 {
+				s.mqPool.Go(func() {
+					s.notifier.NotifyStatusChange(change)
+				})
 			}
-			go s.notifier.NotifyStatusChange(monitorID, channelID, monitorName, monitorAddress, notifyAddress, isNowOnline, duration, when, outageRegion, outageGroup, notifyOutage)
 		}
 
 		if isNowOnline {
@@ -0,0 +1,53 @@
+// Package heatmap periodically aggregates the public monitors into heat
+// layer tiles, so the map can show offline density without fetching points.
+package heatmap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"no-lights-monitor/internal/cache"
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/tiles"
+)
+
+// tileTTL outlives a few missed runs; after that the layer goes empty rather than stale.
+const tileTTL = 10 * time.Minute
+
+// Builder renders the heat layer tiles into Redis for the API. Scheduled every minute.
+type Builder struct {
+	db    *database.DB
+	cache *cache.Cache
+}
+
+func NewBuilder(db *database.DB, c *cache.Cache) *Builder {
+	return &Builder{db: db, cache: c}
+}
+
+// Run rebuilds every tile from the current public monitor statuses.
+func (b *Builder) Run(ctx context.Context) error {
+	monitors, err := b.db.GetPublicMonitors(ctx)
+	if err != nil {
+		return fmt.Errorf("query public monitors: %w", err)
+	}
+	points := make([]tiles.Point, 0, len(monitors))
+	for _, m := range monitors {
+		points = append(points, tiles.Point{Lat: m.Latitude, Lng: m.Longitude, Online: m.IsOnline})
+	}
+
+	built := tiles.Build(points)
+	rendered := make(map[string][]byte, len(built))
+	for key, t := range built {
+		data, err := json.Marshal(t)
+		if err != nil {
+			return fmt.Errorf("marshal tile %s: %w", key, err)
+		}
+		rendered[key] = data
+	}
+	if err := b.cache.StoreHeatmap(ctx, rendered, tileTTL); err != nil {
+		return fmt.Errorf("store tiles: %w", err)
+	}
+	return nil
+}
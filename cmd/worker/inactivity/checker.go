@@ -2,15 +2,15 @@ package inactivity
 
 import (
 	"context"
+	"fmt"
 	"log"
-	"time"
 
 	"no-lights-monitor/internal/database"
 	"no-lights-monitor/internal/mq"
 )
 
 // Checker auto-pauses monitors that have never been active since creation
-// (last_status_change_at == created_at). Runs daily at 13:00 Kyiv time.
+// (last_status_change_at == created_at). Scheduled daily at 13:00 Kyiv time.
 type Checker struct {
 	db        *database.DB
 	publisher *mq.Publisher
@@ -20,29 +20,11 @@ func NewChecker(db *database.DB, publisher *mq.Publisher) *Checker {
 	return &Checker{db: db, publisher: publisher}
 }
 
-// Start runs the checker loop, firing daily at 13:00 Kyiv time.
-func (c *Checker) Start(ctx context.Context) {
-	kyiv, _ := time.LoadLocation("Europe/Kyiv")
-	log.Println("[inactivity] checker started, will run daily at 13:00 Kyiv")
-
-	for {
-		delay := timeUntilNext(13, 0, kyiv)
-		log.Printf("[inactivity] next check in %s", delay.Round(time.Second))
-		select {
-		case <-ctx.Done():
-			log.Println("[inactivity] checker stopped")
-			return
-		case <-time.After(delay):
-			c.run(ctx)
-		}
-	}
-}
-
-func (c *Checker) run(ctx context.Context) {
+// Run pauses every never-active monitor and notifies its owner.
+func (c *Checker) Run(ctx context.Context) error {
 	monitors, err := c.db.GetNeverActiveMonitors(ctx)
 	if err != nil {
-		log.Printf("[inactivity] failed to query monitors: %v", err)
-		return
+		return fmt.Errorf("query monitors: %w", err)
 	}
 	log.Printf("[inactivity] found %d never-active monitors to pause", len(monitors))
 
@@ -51,11 +33,18 @@ func (c *Checker) run(ctx context.Context) {
 			log.Printf("[inactivity] monitor %d: failed to pause: %v", m.ID, err)
 			continue
 		}
+		if err := c.db.RecordMonitorChange(ctx, m.ID, database.ChangeSourceSystem, "is_active", "true", "false"); err != nil {
+			log.Printf("[inactivity] monitor %d: record pause: %v", m.ID, err)
+		}
 
 		ownerID, err := c.db.GetOwnerTelegramIDByMonitorID(ctx, m.ID)
 		if err != nil {
 			log.Printf("[inactivity] monitor %d: failed to get owner: %v", m.ID, err)
 		}
+		// Owners of many monitors get it in the daily digest instead of a DM.
+		if digest, err := c.db.UsesOwnerDigest(ctx, ownerID); err == nil && digest {
+			ownerID = 0
+		}
 
 		msg := mq.InactivePauseMsg{
 			MonitorID:       m.ID,
@@ -68,14 +57,5 @@ func (c *Checker) run(ctx context.Context) {
 		}
 		log.Printf("[inactivity] monitor %d (%s): paused due to no activity", m.ID, m.Name)
 	}
-}
-
-// timeUntilNext returns the duration until the next occurrence of hour:minute in loc.
-func timeUntilNext(hour, minute int, loc *time.Location) time.Duration {
-	now := time.Now().In(loc)
-	next := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, loc)
-	if !now.Before(next) {
-		next = next.Add(24 * time.Hour)
-	}
-	return next.Sub(time.Now())
+	return nil
 }
@@ -0,0 +1,200 @@
+package incident
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"no-lights-monitor/internal/cache"
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/models"
+	"no-lights-monitor/internal/mq"
+)
+
+// City is an aggregate channel that gets the mass-outage updates of one outage region.
+type City struct {
+	Region    string
+	ChannelID int64
+	Name      string // shown in posts; the region ID if empty
+}
+
+// ParseCities turns "region:channel_id[:name]" entries (CITY_CHANNELS) into cities.
+// Malformed entries are skipped with a log line.
+func ParseCities(entries []string) []City {
+	var cities []City
+	for _, e := range entries {
+		parts := strings.SplitN(e, ":", 3)
+		if len(parts) < 2 {
+			log.Printf("[incident] ignoring malformed city entry %q", e)
+			continue
+		}
+		region := strings.TrimSpace(parts[0])
+		channelID, err := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64)
+		if region == "" || err != nil || channelID == 0 {
+			log.Printf("[incident] ignoring malformed city entry %q", e)
+			continue
+		}
+		c := City{Region: region, ChannelID: channelID, Name: region}
+		if len(parts) == 3 && strings.TrimSpace(parts[2]) != "" {
+			c.Name = strings.TrimSpace(parts[2])
+		}
+		cities = append(cities, c)
+	}
+	return cities
+}
+
+// Detector opens an incident when most monitors of an outage group go offline
+// together and closes it when they come back. Starts and ends are collected
+// into one anonymized post per city channel, at most once per postEvery; an
+// incident that ends before its start was posted is never posted at all.
+// Scheduled every minute.
+type Detector struct {
+	db          *database.DB
+	cache       *cache.Cache
+	publisher   *mq.Publisher
+	cities      []City
+	minMonitors int
+	postEvery   time.Duration
+	kyiv        *time.Location
+}
+
+func NewDetector(db *database.DB, c *cache.Cache, publisher *mq.Publisher, cities []City, minMonitors int, postEvery time.Duration) *Detector {
+	kyiv, _ := time.LoadLocation("Europe/Kyiv")
+	return &Detector{db: db, cache: c, publisher: publisher, cities: cities, minMonitors: minMonitors, postEvery: postEvery, kyiv: kyiv}
+}
+
+// Run updates the incidents of all configured cities and posts pending updates.
+func (d *Detector) Run(ctx context.Context) error {
+	if err := d.detect(ctx, time.Now()); err != nil {
+		return err
+	}
+	for _, c := range d.cities {
+		if err := d.post(ctx, c); err != nil {
+			log.Printf("[incident] post to %s: %v", c.Region, err)
+		}
+	}
+	return nil
+}
+
+// detect opens, updates and closes incidents from the current group stats.
+func (d *Detector) detect(ctx context.Context, now time.Time) error {
+	regions := make([]string, len(d.cities))
+	for i, c := range d.cities {
+		regions[i] = c.Region
+	}
+	stats, err := d.db.GetOutageGroupStats(ctx, regions)
+	if err != nil {
+		return fmt.Errorf("group stats: %w", err)
+	}
+	open, err := d.db.GetOpenIncidents(ctx)
+	if err != nil {
+		return fmt.Errorf("open incidents: %w", err)
+	}
+
+	ongoing := make(map[string]*models.Incident, len(open))
+	for _, inc := range open {
+		ongoing[inc.Region+"/"+inc.OutageGroup] = inc
+	}
+
+	for _, s := range stats {
+		key := s.Region + "/" + s.OutageGroup
+		inc := ongoing[key]
+		delete(ongoing, key)
+		switch {
+		case d.massOutage(s) && inc == nil:
+			opened, err := d.db.OpenIncident(ctx, s.Region, s.OutageGroup, s.Offline, s.Total, now)
+			if err != nil {
+				return fmt.Errorf("open incident %s: %w", key, err)
+			}
+			log.Printf("[incident] %d opened: %s, %d of %d monitors offline", opened.ID, key, s.Offline, s.Total)
+		case d.massOutage(s):
+			if err := d.db.UpdateIncidentCounts(ctx, inc.ID, s.Offline, s.Total); err != nil {
+				return fmt.Errorf("update incident %d: %w", inc.ID, err)
+			}
+		case inc != nil:
+			if err := d.close(ctx, inc, now); err != nil {
+				return err
+			}
+		}
+	}
+	// Groups without active monitors left (or cities no longer configured).
+	for _, inc := range ongoing {
+		if err := d.close(ctx, inc, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// massOutage reports whether enough monitors of a group are offline: at least
+// minMonitors, and at least half of the group.
+func (d *Detector) massOutage(s database.GroupStat) bool {
+	return s.Offline >= d.minMonitors && s.Offline*2 >= s.Total
+}
+
+func (d *Detector) close(ctx context.Context, inc *models.Incident, now time.Time) error {
+	if err := d.db.CloseIncident(ctx, inc.ID, now); err != nil {
+		return fmt.Errorf("close incident %d: %w", inc.ID, err)
+	}
+	log.Printf("[incident] %d closed: %s/%s after %s, peak %d monitors", inc.ID, inc.Region, inc.OutageGroup, database.FormatDuration(now.Sub(inc.StartedAt)), inc.Peak)
+	return nil
+}
+
+// post publishes the pending starts and ends of a city as one channel message,
+// unless the city's channel was posted to within postEvery.
+func (d *Detector) post(ctx context.Context, c City) error {
+	pending, err := d.db.GetUnpostedIncidents(ctx, c.Region)
+	if err != nil {
+		return fmt.Errorf("unposted incidents: %w", err)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	ok, err := d.cache.TakeIncidentPost(ctx, c.Region, d.postEvery)
+	if err != nil {
+		return fmt.Errorf("rate limit: %w", err)
+	}
+	if !ok {
+		return nil
+	}
+
+	if err := d.publisher.Publish(ctx, mq.RoutingBroadcast, mq.BroadcastMsg{ChannelID: c.ChannelID, Text: d.text(c, pending)}); err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+	ids := make([]int64, len(pending))
+	for i, inc := range pending {
+		ids[i] = inc.ID
+	}
+	if err := d.db.MarkIncidentsPosted(ctx, ids); err != nil {
+		return fmt.Errorf("mark posted: %w", err)
+	}
+	log.Printf("[incident] %s: posted %d update(s) to channel %d", c.Region, len(pending), c.ChannelID)
+	return nil
+}
+
+// text builds the HTML post of a city's pending incident updates.
+//
+//	⚡ <b>Масові відключення: Київ</b>
+//
+//	🔴 Черга 3.1: немає світла з 14:05 (12 з 20 моніторів)
+//	🟢 Черга 2.2: світло повернулося о 15:20, без світла 1 год 15 хв
+func (d *Detector) text(c City, incidents []*models.Incident) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "⚡ <b>Масові відключення: %s</b>\n\n", html.EscapeString(c.Name))
+	for _, inc := range incidents {
+		group := html.EscapeString(inc.OutageGroup)
+		if inc.EndedAt == nil {
+			fmt.Fprintf(&sb, "🔴 Черга %s: немає світла з %s (%d з %d моніторів)\n",
+				group, inc.StartedAt.In(d.kyiv).Format("15:04"), inc.Affected, inc.Total)
+			continue
+		}
+		fmt.Fprintf(&sb, "🟢 Черга %s: світло повернулося о %s, без світла %s\n",
+			group, inc.EndedAt.In(d.kyiv).Format("15:04"), database.FormatDuration(inc.EndedAt.Sub(inc.StartedAt)))
+	}
+	sb.WriteString("\n<i>За даними моніторів спільноти, без адрес.</i>")
+	return sb.String()
+}
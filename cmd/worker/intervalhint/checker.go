@@ -0,0 +1,125 @@
+package intervalhint
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"no-lights-monitor/internal/cache"
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/models"
+	"no-lights-monitor/internal/mq"
+)
+
+const (
+	// minSamples is how many observed intervals are needed before judging a device.
+	minSamples = 6
+	// riskRatio: a cadence above this share of the threshold is one late ping
+	// away from a false offline alert.
+	riskRatio = 0.8
+	// headroom is the margin a suggested threshold keeps over the cadence.
+	headroom = 1.5
+	// hintCooldown keeps the owner from being reminded more than once a week.
+	hintCooldown = 7 * 24 * time.Hour
+)
+
+// allowedThresholds mirrors the offline thresholds owners can pick in the bot and settings page.
+var allowedThresholds = []int{150, 300}
+
+// Checker compares each heartbeat device's observed ping cadence with its
+// offline threshold and DMs the owner when the two guarantee false alerts.
+// Scheduled hourly.
+type Checker struct {
+	db               *database.DB
+	cache            *cache.Cache
+	publisher        *mq.Publisher
+	defaultThreshold int
+}
+
+func NewChecker(db *database.DB, c *cache.Cache, publisher *mq.Publisher, defaultThresholdSec int) *Checker {
+	return &Checker{db: db, cache: c, publisher: publisher, defaultThreshold: defaultThresholdSec}
+}
+
+// Run checks every active heartbeat monitor once.
+func (c *Checker) Run(ctx context.Context) error {
+	monitors, err := c.db.GetAllMonitors(ctx)
+	if err != nil {
+		return fmt.Errorf("query monitors: %w", err)
+	}
+
+	now := time.Now()
+	hinted := 0
+	for _, m := range monitors {
+		if m.MonitorType != "heartbeat" || !m.IsActive || m.IsCanary {
+			continue
+		}
+		if m.IntervalHintAt != nil && now.Sub(*m.IntervalHintAt) < hintCooldown {
+			continue
+		}
+		interval, samples, err := c.cache.ObservedPingInterval(ctx, m.ID)
+		if err != nil {
+			log.Printf("[intervalhint] monitor %d: read intervals: %v", m.ID, err)
+			continue
+		}
+		if samples < minSamples {
+			continue
+		}
+
+		threshold := c.threshold(m)
+		ivSec := int(interval.Seconds())
+		if float64(ivSec) < riskRatio*float64(threshold) {
+			continue
+		}
+
+		ownerID, err := c.db.GetOwnerTelegramIDByMonitorID(ctx, m.ID)
+		if err != nil || ownerID == 0 {
+			continue
+		}
+		// Owners of many monitors read about it in the daily digest
+		// (cmd/worker/ownerdigest), which picks up interval_hint_at.
+		if digest, err := c.db.UsesOwnerDigest(ctx, ownerID); err == nil && digest {
+			if err := c.db.MarkIntervalHintSent(ctx, m.ID, now); err != nil {
+				log.Printf("[intervalhint] monitor %d: mark sent: %v", m.ID, err)
+			}
+			continue
+		}
+		msg := mq.IntervalHintMsg{
+			MonitorID:             m.ID,
+			OwnerTelegramID:       ownerID,
+			MonitorName:           m.Name,
+			IntervalSec:           ivSec,
+			ThresholdSec:          threshold,
+			SuggestedThresholdSec: suggestThreshold(ivSec),
+		}
+		if err := c.publisher.Publish(ctx, mq.RoutingIntervalHint, msg); err != nil {
+			log.Printf("[intervalhint] monitor %d: publish: %v", m.ID, err)
+			continue
+		}
+		if err := c.db.MarkIntervalHintSent(ctx, m.ID, now); err != nil {
+			log.Printf("[intervalhint] monitor %d: mark sent: %v", m.ID, err)
+		}
+		hinted++
+		log.Printf("[intervalhint] monitor %d: pings every ~%ds, threshold %ds — owner warned", m.ID, ivSec, threshold)
+	}
+	log.Printf("[intervalhint] checked %d monitors, %d hints sent", len(monitors), hinted)
+	return nil
+}
+
+func (c *Checker) threshold(m *models.Monitor) int {
+	if m.OfflineThresholdSec > 0 {
+		return m.OfflineThresholdSec
+	}
+	return c.defaultThreshold
+}
+
+// suggestThreshold returns the smallest allowed threshold with enough headroom
+// over the observed cadence, or 0 when even the largest one is too tight.
+func suggestThreshold(intervalSec int) int {
+	for _, t := range allowedThresholds {
+		if float64(t) >= headroom*float64(intervalSec) {
+			return t
+		}
+	}
+	return 0
+}
@@ -1,122 +1,18 @@
 package main
 
 import (
-	"context"
-	"log"
-	"os"
-	"os/signal"
-	"syscall"
+	"time"
 
-	"github.com/joho/godotenv"
-
-	"no-lights-monitor/internal/cache"
-	"no-lights-monitor/internal/config"
-	"no-lights-monitor/internal/database"
-	"no-lights-monitor/internal/health"
-	"no-lights-monitor/cmd/worker/dtek"
-	"no-lights-monitor/cmd/worker/graph"
-	"no-lights-monitor/cmd/worker/heartbeat"
-	"no-lights-monitor/cmd/worker/inactivity"
-	"no-lights-monitor/internal/mq"
-	"no-lights-monitor/internal/outage"
-	"no-lights-monitor/cmd/worker/outagephoto"
-)
-
-const (
-	// HeartbeatCheckIntervalSec is how often we check for stale heartbeats.
-	HeartbeatCheckIntervalSec = 15
-	// PingCheckIntervalSec is how often we ICMP-ping targets for ping monitors.
-	PingCheckIntervalSec = 60
+	"no-lights-monitor/cmd/worker/app"
+	"no-lights-monitor/internal/bootstrap"
+	"no-lights-monitor/internal/errsink"
 )
 
 func main() {
-	// Load .env if present.
-	_ = godotenv.Load()
-
-	cfg := config.Load()
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	// --- Database ---
-	db, err := database.New(ctx, cfg.DatabaseURL)
-	if err != nil {
-		log.Fatalf("database: %v", err)
-	}
-	defer db.Close()
-
-	if err := db.Migrate(ctx); err != nil {
-		log.Fatalf("migrate: %v", err)
-	}
-	log.Println("database connected and migrated")
-
-	// --- Redis ---
-	redisCache, err := cache.New(cfg.RedisURL)
-	if err != nil {
-		log.Fatalf("redis: %v", err)
-	}
-	defer redisCache.Close()
-	log.Println("redis connected")
-
-	// --- RabbitMQ ---
-	publisher, err := mq.NewPublisher(cfg.RabbitMQURL)
-	if err != nil {
-		log.Fatalf("rabbitmq publisher: %v", err)
-	}
-	defer publisher.Close()
-
-	consumer, err := mq.NewConsumer(cfg.RabbitMQURL)
-	if err != nil {
-		log.Fatalf("rabbitmq consumer: %v", err)
-	}
-	defer consumer.Close()
-	log.Println("rabbitmq connected")
-
-	// --- Health server ---
-	health.ServeAsync(func() error {
-		return db.Pool.Ping(ctx)
-	})
-
-	// --- Heartbeat Service ---
-	notifier := mq.NewStatusNotifier(publisher)
-	hbService := heartbeat.NewService(db, redisCache, notifier, cfg.OfflineThreshold)
-
-	if err := hbService.LoadMonitors(ctx); err != nil {
-		log.Fatalf("load monitors: %v", err)
-	}
-
-	// --- Start heartbeat and ping checkers ---
-	go hbService.StartHeartbeatChecker(ctx, HeartbeatCheckIntervalSec)
-	go hbService.StartPingChecker(ctx, PingCheckIntervalSec)
-
-	// --- Uptime Graph updater (hourly) ---
-	graphClient := graph.NewClient(cfg.GraphServiceURL)
-	graphUpdater := graph.NewUpdater(db, graphClient, publisher)
-	go graphUpdater.Start(ctx, consumer)
-	log.Println("graph updater started")
-
-	// --- Outage photo updater (hourly) ---
-	outageClient := outage.NewClient(cfg.OutageServiceURL)
-	photoUpdater := outagephoto.NewUpdater(db, publisher, outageClient)
-	go photoUpdater.Start(ctx)
-	log.Println("outage photo updater started")
-
-	// --- Inactivity checker (daily at 13:00 Kyiv) ---
-	inactivityChecker := inactivity.NewChecker(db, publisher)
-	go inactivityChecker.Start(ctx)
-	log.Println("inactivity checker started")
-
-	// --- DTEK unplanned outage poller ---
-	if cfg.DtekServiceURL != "" {
-		dtekPoller := dtek.NewPoller(db, publisher, cfg.DtekServiceURL)
-		go dtekPoller.Start(ctx, cfg.DtekPollInterval)
-		log.Printf("dtek outage poller started (interval: %ds)", cfg.DtekPollInterval)
-	}
+	cfg := bootstrap.Init("worker")
+	defer errsink.Flush(2 * time.Second)
 
-	// --- Graceful shutdown ---
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	log.Println("shutting down worker...")
-	cancel()
+	ctx, stop := bootstrap.SignalContext()
+	defer stop()
+	app.Run(ctx, cfg)
 }
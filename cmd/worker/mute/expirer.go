@@ -0,0 +1,56 @@
+// Package mute ends expired monitor mutes (see /mute in the bot) and tells
+// each channel that status notifications are back.
+package mute
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/mq"
+)
+
+const msgUnmuted = "🔔 Сповіщення про світло знову увімкнено."
+
+// Expirer clears mutes whose time is up. The heartbeat service already
+// ignores an expired mute, so this only tidies the row and posts the note.
+type Expirer struct {
+	db        *database.DB
+	publisher *mq.Publisher
+}
+
+func NewExpirer(db *database.DB, publisher *mq.Publisher) *Expirer {
+	return &Expirer{db: db, publisher: publisher}
+}
+
+// Run unmutes every monitor whose mute has expired.
+func (e *Expirer) Run(ctx context.Context) error {
+	monitors, err := e.db.GetExpiredMutes(ctx, time.Now())
+	if err != nil {
+		return fmt.Errorf("query expired mutes: %w", err)
+	}
+	for _, m := range monitors {
+		until := *m.MutedUntil
+		cleared, err := e.db.ClearMonitorMute(ctx, m.ID, until)
+		if err != nil {
+			log.Printf("[mute] monitor %d: failed to unmute: %v", m.ID, err)
+			continue
+		}
+		if !cleared {
+			continue
+		}
+		if err := e.db.RecordMonitorChange(ctx, m.ID, database.ChangeSourceSystem, "muted_until", until.Format(time.RFC3339), ""); err != nil {
+			log.Printf("[mute] monitor %d: record unmute: %v", m.ID, err)
+		}
+		log.Printf("[mute] monitor %d (%s): mute expired", m.ID, m.Name)
+		if m.ChannelID == 0 {
+			continue
+		}
+		if err := e.publisher.Publish(ctx, mq.RoutingBroadcast, mq.BroadcastMsg{ChannelID: m.ChannelID, Text: msgUnmuted}); err != nil {
+			log.Printf("[mute] monitor %d: publish unmute note: %v", m.ID, err)
+		}
+	}
+	return nil
+}
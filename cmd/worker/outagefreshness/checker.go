@@ -0,0 +1,92 @@
+// Package outagefreshness watches how old the upstream outage schedule data
+// of each region is and tells operators when a region goes stale or recovers.
+// Notifications built from stale data are flagged by the outage service itself
+// (GroupHourlyFact.Stale); this job only covers the operator side.
+package outagefreshness
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"sync"
+	"time"
+
+	"no-lights-monitor/internal/errsink"
+	"no-lights-monitor/internal/metrics"
+	"no-lights-monitor/internal/mq"
+	"no-lights-monitor/internal/outage"
+)
+
+const (
+	msgStale     = "⚠️ <b>Outage data</b>: region <code>%s</code> has not been updated upstream since %s (%s ago). Schedule lines are marked as possibly outdated."
+	msgRecovered = "✅ <b>Outage data</b>: region <code>%s</code> is fresh again (updated %s)."
+)
+
+// Checker compares each region's data age with the outage service's verdict
+// and alerts the ops channel on every change of it.
+type Checker struct {
+	outage       *outage.Client
+	publisher    *mq.Publisher
+	opsChannelID int64 // 0 = log and error sink only
+	kyiv         *time.Location
+
+	mu    sync.Mutex
+	stale map[string]bool // region -> stale at the last run
+}
+
+// NewChecker creates a checker alerting opsChannelID.
+func NewChecker(oc *outage.Client, publisher *mq.Publisher, opsChannelID int64) *Checker {
+	kyiv, _ := time.LoadLocation("Europe/Kyiv")
+	return &Checker{outage: oc, publisher: publisher, opsChannelID: opsChannelID, kyiv: kyiv, stale: make(map[string]bool)}
+}
+
+// Run exports the age of every region and alerts on stale/fresh transitions.
+// The first run after a start only alerts about regions that are stale.
+func (c *Checker) Run(ctx context.Context) error {
+	regions, err := c.outage.GetRegions()
+	if err != nil {
+		return fmt.Errorf("get regions: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, r := range regions {
+		if r.AgeSec >= 0 {
+			metrics.OutageDataAgeSeconds.WithLabelValues(r.RegionID).Set(float64(r.AgeSec))
+		} else {
+			log.Printf("[outagefreshness] %s: can't parse lastUpdated %q", r.RegionID, r.LastUpdated)
+		}
+
+		was := c.stale[r.RegionID]
+		c.stale[r.RegionID] = r.Stale
+		if r.Stale == was {
+			continue
+		}
+
+		updated := r.LastUpdated
+		if t, ok := outage.ParseLastUpdated(r.LastUpdated); ok {
+			updated = t.In(c.kyiv).Format("02.01 15:04")
+		}
+		var text string
+		if r.Stale {
+			age := (time.Duration(r.AgeSec) * time.Second).Truncate(time.Minute)
+			text = fmt.Sprintf(msgStale, html.EscapeString(r.RegionID), html.EscapeString(updated), age)
+			errsink.CaptureMessage("outage data stale: "+r.RegionID, errsink.Fields{"component": "outagefreshness", "region": r.RegionID, "last_updated": r.LastUpdated})
+		} else {
+			text = fmt.Sprintf(msgRecovered, html.EscapeString(r.RegionID), html.EscapeString(updated))
+		}
+		log.Printf("[outagefreshness] %s: stale=%v (last updated %s)", r.RegionID, r.Stale, r.LastUpdated)
+		c.alert(ctx, text)
+	}
+	return nil
+}
+
+func (c *Checker) alert(ctx context.Context, text string) {
+	if c.opsChannelID == 0 {
+		return
+	}
+	if err := c.publisher.Publish(ctx, mq.RoutingBroadcast, mq.BroadcastMsg{ChannelID: c.opsChannelID, Text: text}); err != nil {
+		log.Printf("[outagefreshness] alert: %v", err)
+	}
+}
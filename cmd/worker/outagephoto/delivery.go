@@ -0,0 +1,30 @@
+package outagephoto
+
+import (
+	"context"
+
+	"no-lights-monitor/internal/mq"
+)
+
+// Delivery carries outage photo actions to Telegram. The worker has no bot
+// session, so its only Delivery publishes to RabbitMQ and the bot service's
+// listener performs the action (and records the posted message). A nil error
+// means the action was accepted for delivery; on error nothing was handed off
+// and the updater leaves the stored photo state as it was.
+type Delivery interface {
+	Deliver(ctx context.Context, msg mq.OutagePhotoMsg) error
+}
+
+// mqDelivery publishes photo actions for the bot service's listener.
+type mqDelivery struct {
+	pub *mq.Publisher
+}
+
+// NewMQDelivery returns a Delivery that publishes to mq.RoutingOutagePhoto.
+func NewMQDelivery(pub *mq.Publisher) Delivery {
+	return &mqDelivery{pub: pub}
+}
+
+func (d *mqDelivery) Deliver(ctx context.Context, msg mq.OutagePhotoMsg) error {
+	return d.pub.Publish(ctx, mq.RoutingOutagePhoto, msg)
+}
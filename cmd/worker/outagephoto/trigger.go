@@ -0,0 +1,36 @@
+package outagephoto
+
+import (
+	"context"
+	"log"
+
+	"no-lights-monitor/internal/models"
+)
+
+// statusNotifier mirrors heartbeat.Notifier.
+type statusNotifier interface {
+	NotifyStatusChange(sc models.StatusChange)
+}
+
+// OutageStartNotifier forwards status changes to the wrapped notifier and, on
+// online → offline transitions posted to the channel, delivers the schedule
+// photo for monitors whose policy is outage.PhotoModeOutageStart.
+type OutageStartNotifier struct {
+	next    statusNotifier
+	updater *Updater
+}
+
+// WrapNotifier returns a notifier that also triggers outage-start photo delivery.
+func (u *Updater) WrapNotifier(next statusNotifier) *OutageStartNotifier {
+	return &OutageStartNotifier{next: next, updater: u}
+}
+
+func (n *OutageStartNotifier) NotifyStatusChange(sc models.StatusChange) {
+	n.next.NotifyStatusChange(sc)
+	if sc.IsOnline || sc.SkipTelegram || sc.TelegramOnly {
+		return
+	}
+	if err := n.updater.DeliverOnOutageStart(context.Background(), sc.MonitorID); err != nil {
+		log.Printf("[outage-photo] monitor %d: outage start delivery: %v", sc.MonitorID, err)
+	}
+}
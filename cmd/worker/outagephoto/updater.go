@@ -10,101 +10,145 @@ import (
 	"no-lights-monitor/internal/models"
 	"no-lights-monitor/internal/mq"
 	"no-lights-monitor/internal/outage"
+	"no-lights-monitor/internal/photofit"
 )
 
+// photoStore is the part of the database the updater reads and clears photo
+// state through (*database.DB).
+type photoStore interface {
+	GetMonitorsWithChannels(ctx context.Context) ([]*models.Monitor, error)
+	GetMonitorByID(ctx context.Context, id int64) (*models.Monitor, error)
+	ClearOutagePhoto(ctx context.Context, monitorID int64) error
+}
+
+// scheduleSource serves group schedule photos and hours (*outage.Client).
+type scheduleSource interface {
+	GetGroupPhoto(region, group, storedETag string) (data []byte, etag string, notModified bool, err error)
+	GetGroupFact(region, group string) (*outage.GroupHourlyFact, error)
+}
+
 // Updater is a background service that fetches outage schedule images
-// and publishes them to RabbitMQ for the bot service to post to Telegram.
+// and hands them to a Delivery for posting to Telegram.
 type Updater struct {
-	db     *database.DB
-	pub    *mq.Publisher
-	outage *outage.Client
+	db       photoStore
+	delivery Delivery
+	outage   scheduleSource
 }
 
 // NewUpdater creates a new outage photo updater.
-func NewUpdater(db *database.DB, pub *mq.Publisher, outageClient *outage.Client) *Updater {
+func NewUpdater(db *database.DB, delivery Delivery, outageClient *outage.Client) *Updater {
 	return &Updater{
-		db:     db,
-		pub:    pub,
-		outage: outageClient,
-	}
-}
-
-// Start runs the periodic update loop. Fires once after a delay, then every hour.
-func (u *Updater) Start(ctx context.Context) {
-	log.Println("[outage-photo] updater started, waiting 60s")
-	select {
-	case <-ctx.Done():
-		return
-	case <-time.After(60 * time.Second):
-	}
-	log.Println("[outage-photo] running initial pass")
-	u.runAll(ctx)
-
-	ticker := time.NewTicker(1 * time.Hour)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("[outage-photo] updater stopped")
-			return
-		case <-ticker.C:
-			u.runAll(ctx)
-		}
+		db:       db,
+		delivery: delivery,
+		outage:   outageClient,
 	}
 }
 
-func (u *Updater) runAll(ctx context.Context) {
+// RunAll refreshes the outage schedule photo of every monitor with a channel,
+// as far as each monitor's delivery policy allows. Scheduled hourly by the worker,
+// so a "daily" photo goes out on the first pass at or after its configured time.
+func (u *Updater) RunAll(ctx context.Context) error {
 	monitors, err := u.db.GetMonitorsWithChannels(ctx)
 	if err != nil {
-		log.Printf("[outage-photo] failed to list monitors: %v", err)
-		return
+		return fmt.Errorf("list monitors: %w", err)
 	}
 
 	for _, m := range monitors {
-		if m.OutageRegion == "" || m.OutageGroup == "" {
+		policy := outage.PolicyFor(m)
+		if !policy.Enabled {
 			if m.OutagePhotoMessageID != 0 {
-				// Publish delete action for the bot service.
-				msg := mq.OutagePhotoMsg{
-					MonitorID:   m.ID,
-					ChannelID:   m.ChannelID,
-					MonitorName: m.Name,
-					Action:      mq.OutagePhotoDelete,
-					OldMsgID:    m.OutagePhotoMessageID,
-				}
-				if err := u.pub.Publish(ctx, mq.RoutingOutagePhoto, msg); err != nil {
-					log.Printf("[outage-photo] monitor %d: failed to publish delete: %v", m.ID, err)
-				}
-				if err := u.db.ClearOutagePhoto(ctx, m.ID); err != nil {
-					log.Printf("[outage-photo] monitor %d: failed to clear photo: %v", m.ID, err)
+				if err := u.deletePhoto(ctx, m); err != nil {
+					log.Printf("[outage-photo] monitor %d: %v", m.ID, err)
 				}
 			}
 			continue
 		}
 
-		if !m.OutagePhotoEnabled {
-			if m.OutagePhotoMessageID != 0 {
-				msg := mq.OutagePhotoMsg{
-					MonitorID:   m.ID,
-					ChannelID:   m.ChannelID,
-					MonitorName: m.Name,
-					Action:      mq.OutagePhotoDelete,
-					OldMsgID:    m.OutagePhotoMessageID,
-				}
-				if err := u.pub.Publish(ctx, mq.RoutingOutagePhoto, msg); err != nil {
-					log.Printf("[outage-photo] monitor %d: failed to publish delete: %v", m.ID, err)
-				}
-				if err := u.db.ClearOutagePhoto(ctx, m.ID); err != nil {
-					log.Printf("[outage-photo] monitor %d: failed to clear photo: %v", m.ID, err)
-				}
-			}
-			continue
-		}
-
-		if err := u.updateOne(ctx, m); err != nil {
+		if err := u.updateOne(ctx, m, policy, false); err != nil {
 			log.Printf("[outage-photo] monitor %d: %v", m.ID, err)
 		}
 	}
+	return nil
+}
+
+// DeliverOnOutageStart posts a fresh schedule photo when a monitor goes offline,
+// if its policy asks for that.
+func (u *Updater) DeliverOnOutageStart(ctx context.Context, monitorID int64) error {
+	m, err := u.db.GetMonitorByID(ctx, monitorID)
+	if err != nil {
+		return fmt.Errorf("load monitor: %w", err)
+	}
+	if m.ChannelID == 0 || !m.IsActive {
+		return nil
+	}
+	policy := outage.PolicyFor(m)
+	if !policy.OnOutageStart() {
+		return nil
+	}
+	return u.updateOne(ctx, m, policy, true)
+}
+
+// Sandbox posts the monitor's current schedule photo and caption as a new message
+// to channelID, ignoring the delivery policy and leaving stored photo state untouched.
+func (u *Updater) Sandbox(ctx context.Context, m *models.Monitor, channelID int64) error {
+	if m.OutageRegion == "" || m.OutageGroup == "" {
+		return nil
+	}
+	data, _, _, err := u.outage.GetGroupPhoto(m.OutageRegion, m.OutageGroup, "")
+	if err != nil {
+		return fmt.Errorf("fetch photo: %w", err)
+	}
+	if data, err = photofit.Fit("outage_photo", data); err != nil {
+		return fmt.Errorf("fit photo: %w", err)
+	}
+	caption := ""
+	if fact, err := u.outage.GetGroupFact(m.OutageRegion, m.OutageGroup); err == nil {
+		caption = outage.BuildPhotoCaption(m.OutageGroup, fact, time.Now())
+	}
+	msg := mq.OutagePhotoMsg{
+		MonitorID:   m.ID,
+		ChannelID:   channelID,
+		MonitorName: m.Name,
+		Action:      mq.OutagePhotoSend,
+		ImageData:   data,
+		Filename:    outage.GroupToFilename(m.OutageGroup),
+		Caption:     caption,
+		Sandbox:     true,
+	}
+	if err := u.delivery.Deliver(ctx, msg); err != nil {
+		return fmt.Errorf("deliver outage photo: %w", err)
+	}
+	return nil
+}
+
+// deletePhoto removes the monitor's current photo from the channel and forgets it.
+func (u *Updater) deletePhoto(ctx context.Context, m *models.Monitor) error {
+	msg := mq.OutagePhotoMsg{
+		MonitorID:   m.ID,
+		ChannelID:   m.ChannelID,
+		MonitorName: m.Name,
+		Action:      mq.OutagePhotoDelete,
+		OldMsgID:    m.OutagePhotoMessageID,
+	}
+	if err := u.delivery.Deliver(ctx, msg); err != nil {
+		return fmt.Errorf("deliver delete: %w", err)
+	}
+	if err := u.db.ClearOutagePhoto(ctx, m.ID); err != nil {
+		return fmt.Errorf("clear photo: %w", err)
+	}
+	m.OutagePhotoMessageID = 0
+	return nil
+}
+
+// photoIsStale reports whether a photo last refreshed at updatedAt belongs to
+// an earlier Kyiv day than now. The ETag alone can't tell: an unchanged image
+// still shows yesterday's caption. Unknown refresh times are not stale.
+func photoIsStale(updatedAt *time.Time, now time.Time) bool {
+	if updatedAt == nil {
+		return false
+	}
+	seenAt := updatedAt.In(now.Location())
+	return seenAt.Year() != now.Year() || seenAt.YearDay() != now.YearDay()
 }
 
 // allLightsOn reports whether every hour in the schedule has power (no outages).
@@ -117,36 +161,42 @@ func allLightsOn(hours map[string]string) bool {
 	return true
 }
 
-func (u *Updater) updateOne(ctx context.Context, m *models.Monitor) error {
+// updateOne delivers the latest schedule photo for m. onOutageStart replaces the
+// current photo with a new post so it shows up as a fresh channel message.
+func (u *Updater) updateOne(ctx context.Context, m *models.Monitor, policy outage.PhotoPolicy, onOutageStart bool) error {
+	kyiv, _ := time.LoadLocation("Europe/Kyiv")
+	now := time.Now().In(kyiv)
+
 	// If the existing photo is from a previous day, delete it and force a fresh fetch.
 	storedETag := m.OutagePhotoETag
-	if m.OutagePhotoMessageID != 0 && m.OutagePhotoUpdatedAt != nil {
-		kyiv, _ := time.LoadLocation("Europe/Kyiv")
-		now := time.Now().In(kyiv)
-		seenAt := m.OutagePhotoUpdatedAt.In(kyiv)
-		if seenAt.Year() != now.Year() || seenAt.YearDay() != now.YearDay() {
-			// Publish delete for the old photo.
-			msg := mq.OutagePhotoMsg{
-				MonitorID:   m.ID,
-				ChannelID:   m.ChannelID,
-				MonitorName: m.Name,
-				Action:      mq.OutagePhotoDelete,
-				OldMsgID:    m.OutagePhotoMessageID,
-			}
-			if err := u.pub.Publish(ctx, mq.RoutingOutagePhoto, msg); err != nil {
-				return fmt.Errorf("publish delete stale photo: %w", err)
+	if m.OutagePhotoMessageID != 0 && photoIsStale(m.OutagePhotoUpdatedAt, now) {
+		if err := u.deletePhoto(ctx, m); err != nil {
+			return fmt.Errorf("stale photo: %w", err)
+		}
+		log.Printf("[outage-photo] monitor %d: deleted stale photo", m.ID)
+		storedETag = ""
+	}
+
+	if onOutageStart {
+		if m.OutagePhotoMessageID != 0 {
+			if err := u.deletePhoto(ctx, m); err != nil {
+				return fmt.Errorf("replace photo: %w", err)
 			}
-			if err := u.db.ClearOutagePhoto(ctx, m.ID); err != nil {
-				return fmt.Errorf("clear stale photo: %w", err)
+		}
+		storedETag = ""
+	} else {
+		switch policy.Periodic(now, m.OutagePhotoMessageID != 0) {
+		case outage.PhotoSkip:
+			return nil
+		case outage.PhotoEditOnly:
+			if m.OutagePhotoMessageID == 0 {
+				return nil
 			}
-			log.Printf("[outage-photo] monitor %d: deleted stale photo, fetching new", m.ID)
-			m.OutagePhotoMessageID = 0
-			storedETag = ""
 		}
 	}
 
 	// If the toggle is enabled and there are no outages scheduled today, skip posting a new photo.
-	if m.OutagePhotoMessageID == 0 && m.SkipOutagePhotoIfNoOutages {
+	if m.OutagePhotoMessageID == 0 && policy.SkipIfNoOutages {
 		if fact, err := u.outage.GetGroupFact(m.OutageRegion, m.OutageGroup); err == nil {
 			if allLightsOn(fact.Hours) {
 				log.Printf("[outage-photo] monitor %d: no outages today, skipping photo", m.ID)
@@ -155,6 +205,22 @@ func (u *Updater) updateOne(ctx context.Context, m *models.Monitor) error {
 		}
 	}
 
+	return u.deliver(ctx, m, storedETag)
+}
+
+// Recompute re-renders the photo currently posted in the monitor's channel,
+// bypassing the ETag check, e.g. after a caption or rendering fix. Monitors
+// without a posted photo are left alone; the periodic pass handles those.
+func (u *Updater) Recompute(ctx context.Context, m *models.Monitor) error {
+	if m.OutagePhotoMessageID == 0 || !outage.PolicyFor(m).Enabled {
+		return nil
+	}
+	return u.deliver(ctx, m, "")
+}
+
+// deliver fetches the group photo and sends it, or edits the existing message.
+// Nothing is sent if the photo still matches storedETag.
+func (u *Updater) deliver(ctx context.Context, m *models.Monitor, storedETag string) error {
 	data, etag, notModified, err := u.outage.GetGroupPhoto(m.OutageRegion, m.OutageGroup, storedETag)
 	if err != nil {
 		return fmt.Errorf("fetch photo: %w", err)
@@ -163,6 +229,9 @@ func (u *Updater) updateOne(ctx context.Context, m *models.Monitor) error {
 	if notModified {
 		return nil
 	}
+	if data, err = photofit.Fit("outage_photo", data); err != nil {
+		return fmt.Errorf("fit photo: %w", err)
+	}
 
 	filename := outage.GroupToFilename(m.OutageGroup)
 
@@ -191,10 +260,10 @@ func (u *Updater) updateOne(ctx context.Context, m *models.Monitor) error {
 		ETag:        etag,
 		Caption:     caption,
 	}
-	if err := u.pub.Publish(ctx, mq.RoutingOutagePhoto, msg); err != nil {
-		return fmt.Errorf("publish outage photo: %w", err)
+	if err := u.delivery.Deliver(ctx, msg); err != nil {
+		return fmt.Errorf("deliver outage photo: %w", err)
 	}
 
-	log.Printf("[outage-photo] monitor %d: published %s action", m.ID, action)
+	log.Printf("[outage-photo] monitor %d: delivered %s action", m.ID, action)
 	return nil
 }
@@ -0,0 +1,199 @@
+package outagephoto
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"testing"
+	"time"
+
+	"no-lights-monitor/internal/models"
+	"no-lights-monitor/internal/mq"
+	"no-lights-monitor/internal/outage"
+)
+
+func TestPhotoIsStale(t *testing.T) {
+	kyiv, err := time.LoadLocation("Europe/Kyiv")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, kyiv)
+	at := func(ts time.Time) *time.Time { return &ts }
+
+	tests := []struct {
+		name      string
+		updatedAt *time.Time
+		want      bool
+	}{
+		{"missing timestamp", nil, false},
+		{"fresh, earlier today", at(time.Date(2026, 10, 16, 0, 5, 0, 0, kyiv)), false},
+		{"fresh, stored in UTC on the previous UTC day", at(time.Date(2026, 10, 15, 22, 30, 0, 0, time.UTC)), false},
+		{"stale, yesterday evening", at(time.Date(2026, 10, 15, 23, 55, 0, 0, kyiv)), true},
+		{"stale, same day a year ago", at(time.Date(2025, 10, 16, 9, 0, 0, 0, kyiv)), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := photoIsStale(tt.updatedAt, now); got != tt.want {
+				t.Errorf("photoIsStale(%v, %v) = %v, want %v", tt.updatedAt, now, got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeDelivery records the actions handed to it and fails them with err.
+type fakeDelivery struct {
+	err  error
+	msgs []mq.OutagePhotoMsg
+}
+
+func (d *fakeDelivery) Deliver(_ context.Context, msg mq.OutagePhotoMsg) error {
+	if d.err != nil {
+		return d.err
+	}
+	d.msgs = append(d.msgs, msg)
+	return nil
+}
+
+// fakeStore records which monitors had their photo state cleared.
+type fakeStore struct {
+	cleared []int64
+}
+
+func (s *fakeStore) GetMonitorsWithChannels(context.Context) ([]*models.Monitor, error) {
+	return nil, nil
+}
+
+func (s *fakeStore) GetMonitorByID(context.Context, int64) (*models.Monitor, error) {
+	return nil, errors.New("not found")
+}
+
+func (s *fakeStore) ClearOutagePhoto(_ context.Context, monitorID int64) error {
+	s.cleared = append(s.cleared, monitorID)
+	return nil
+}
+
+// fakeSchedules serves one photo and records the ETags it was asked with.
+type fakeSchedules struct {
+	photo []byte
+	etags []string
+}
+
+func (f *fakeSchedules) GetGroupPhoto(_, _, storedETag string) ([]byte, string, bool, error) {
+	f.etags = append(f.etags, storedETag)
+	if storedETag == "current" {
+		return nil, "", true, nil
+	}
+	return f.photo, "current", false, nil
+}
+
+func (f *fakeSchedules) GetGroupFact(_, _ string) (*outage.GroupHourlyFact, error) {
+	return nil, errors.New("no schedule")
+}
+
+func testPhoto(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDeletePhoto(t *testing.T) {
+	t.Run("acked", func(t *testing.T) {
+		d, st := &fakeDelivery{}, &fakeStore{}
+		u := &Updater{db: st, delivery: d}
+		m := &models.Monitor{ID: 7, ChannelID: -100, OutagePhotoMessageID: 42}
+
+		if err := u.deletePhoto(context.Background(), m); err != nil {
+			t.Fatalf("deletePhoto: %v", err)
+		}
+		if len(d.msgs) != 1 || d.msgs[0].Action != mq.OutagePhotoDelete || d.msgs[0].OldMsgID != 42 {
+			t.Fatalf("delivered %+v, want one delete of message 42", d.msgs)
+		}
+		if len(st.cleared) != 1 || st.cleared[0] != 7 {
+			t.Errorf("cleared %v, want [7]", st.cleared)
+		}
+		if m.OutagePhotoMessageID != 0 {
+			t.Errorf("message ID = %d, want 0", m.OutagePhotoMessageID)
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		d, st := &fakeDelivery{err: errors.New("broker down")}, &fakeStore{}
+		u := &Updater{db: st, delivery: d}
+		m := &models.Monitor{ID: 7, ChannelID: -100, OutagePhotoMessageID: 42}
+
+		if err := u.deletePhoto(context.Background(), m); err == nil {
+			t.Fatal("deletePhoto succeeded, want the delivery error")
+		}
+		if len(st.cleared) != 0 {
+			t.Errorf("cleared %v after a failed delivery, want nothing", st.cleared)
+		}
+		if m.OutagePhotoMessageID != 42 {
+			t.Errorf("message ID = %d, want 42 kept", m.OutagePhotoMessageID)
+		}
+	})
+}
+
+func TestUpdateOneStalePhoto(t *testing.T) {
+	yesterday := time.Now().AddDate(0, 0, -1)
+	monitor := func() *models.Monitor {
+		return &models.Monitor{
+			ID: 7, ChannelID: -100, OutageRegion: "kyiv", OutageGroup: "1.1", OutagePhotoEnabled: true,
+			OutagePhotoMessageID: 42, OutagePhotoETag: "current", OutagePhotoUpdatedAt: &yesterday,
+		}
+	}
+
+	t.Run("acked", func(t *testing.T) {
+		d, st, src := &fakeDelivery{}, &fakeStore{}, &fakeSchedules{photo: testPhoto(t)}
+		u := &Updater{db: st, delivery: d, outage: src}
+		m := monitor()
+
+		if err := u.updateOne(context.Background(), m, outage.PolicyFor(m), false); err != nil {
+			t.Fatalf("updateOne: %v", err)
+		}
+		// The stale photo goes and a new one is posted, even though the image
+		// itself (its ETag) didn't change.
+		if len(d.msgs) != 2 || d.msgs[0].Action != mq.OutagePhotoDelete || d.msgs[1].Action != mq.OutagePhotoSend {
+			t.Fatalf("delivered %+v, want a delete then a send", d.msgs)
+		}
+		if len(src.etags) != 1 || src.etags[0] != "" {
+			t.Errorf("photo fetched with ETags %q, want one unconditional fetch", src.etags)
+		}
+		if d.msgs[1].ETag != "current" {
+			t.Errorf("sent ETag = %q, want %q", d.msgs[1].ETag, "current")
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		d, st, src := &fakeDelivery{err: errors.New("broker down")}, &fakeStore{}, &fakeSchedules{photo: testPhoto(t)}
+		u := &Updater{db: st, delivery: d, outage: src}
+		m := monitor()
+
+		if err := u.updateOne(context.Background(), m, outage.PolicyFor(m), false); err == nil {
+			t.Fatal("updateOne succeeded, want the delivery error")
+		}
+		if len(st.cleared) != 0 || len(src.etags) != 0 {
+			t.Errorf("cleared %v, fetched %q after a failed delete, want neither", st.cleared, src.etags)
+		}
+		if m.OutagePhotoMessageID != 42 {
+			t.Errorf("message ID = %d, want 42 kept for the next pass", m.OutagePhotoMessageID)
+		}
+	})
+}
+
+func TestDeliverNotModified(t *testing.T) {
+	d, src := &fakeDelivery{}, &fakeSchedules{photo: testPhoto(t)}
+	u := &Updater{db: &fakeStore{}, delivery: d, outage: src}
+	m := &models.Monitor{ID: 7, ChannelID: -100, OutageGroup: "1.1", OutagePhotoMessageID: 42}
+
+	if err := u.deliver(context.Background(), m, "current"); err != nil {
+		t.Fatalf("deliver: %v", err)
+	}
+	if len(d.msgs) != 0 {
+		t.Errorf("delivered %+v for an unchanged photo, want nothing", d.msgs)
+	}
+}
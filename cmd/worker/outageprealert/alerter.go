@@ -0,0 +1,90 @@
+package outageprealert
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/models"
+	"no-lights-monitor/internal/mq"
+	"no-lights-monitor/internal/outage"
+)
+
+// Lead is how long before a scheduled outage window the heads-up is posted.
+const Lead = 15 * time.Minute
+
+// Alerter posts "light goes off at HH:MM per schedule" heads-ups for monitors
+// that opted in. Run is scheduled every minute; each outage window is announced
+// at most once per monitor (tracked by outage_prealert_sent_for).
+type Alerter struct {
+	db        *database.DB
+	publisher *mq.Publisher
+	outage    *outage.Client
+}
+
+func NewAlerter(db *database.DB, publisher *mq.Publisher, outageClient *outage.Client) *Alerter {
+	return &Alerter{db: db, publisher: publisher, outage: outageClient}
+}
+
+// Run publishes pre-alerts for all monitors with an outage window starting within Lead.
+func (a *Alerter) Run(ctx context.Context) error {
+	monitors, err := a.db.GetMonitorsWithChannels(ctx)
+	if err != nil {
+		return fmt.Errorf("query monitors: %w", err)
+	}
+
+	now := time.Now()
+	// Many monitors share a group: fetch each schedule once per run.
+	facts := make(map[string]*outage.GroupHourlyFact)
+	for _, m := range monitors {
+		if !m.IsActive || !m.OutagePreAlertEnabled || m.OutageRegion == "" || m.OutageGroup == "" {
+			continue
+		}
+		key := m.OutageRegion + "/" + m.OutageGroup
+		fact, ok := facts[key]
+		if !ok {
+			fact, err = a.outage.GetGroupFact(m.OutageRegion, m.OutageGroup)
+			if err != nil {
+				log.Printf("[outageprealert] %s: get group fact: %v", key, err)
+			}
+			facts[key] = fact
+		}
+		if fact == nil {
+			continue
+		}
+		if err := a.check(ctx, m, fact, now); err != nil {
+			log.Printf("[outageprealert] monitor %d: %v", m.ID, err)
+		}
+	}
+	return nil
+}
+
+func (a *Alerter) check(ctx context.Context, m *models.Monitor, fact *outage.GroupHourlyFact, now time.Time) error {
+	start, end, ok := outage.NextOutageStart(fact, now, Lead)
+	if !ok {
+		return nil
+	}
+	if m.OutagePreAlertSentFor != nil && m.OutagePreAlertSentFor.Equal(start) {
+		return nil
+	}
+
+	msg := mq.OutageSummaryMsg{
+		MonitorID:   m.ID,
+		ChannelID:   m.ChannelID,
+		MonitorName: m.Name,
+		Text:        outage.BuildPreAlert(m.OutageGroup, start, end, now),
+	}
+	if fact.Stale {
+		msg.Text += outage.OutdatedNote
+	}
+	if err := a.publisher.Publish(ctx, mq.RoutingOutagePreAlert, msg); err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+	if err := a.db.MarkOutagePreAlertSent(ctx, m.ID, start); err != nil {
+		return fmt.Errorf("mark sent: %w", err)
+	}
+	log.Printf("[outageprealert] monitor %d (%s): pre-alert for %s published", m.ID, m.Name, start.Format("15:04"))
+	return nil
+}
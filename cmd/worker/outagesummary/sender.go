@@ -0,0 +1,118 @@
+package outagesummary
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/models"
+	"no-lights-monitor/internal/mq"
+	"no-lights-monitor/internal/outage"
+)
+
+// unpublishedWait is how long after its time a summary waits for today's
+// schedule to be published before it goes out saying it isn't.
+const unpublishedWait = 2 * time.Hour
+
+// Sender publishes the optional daily text summary of today's outage windows.
+// Run is scheduled every few minutes; each monitor is sent at most once per Kyiv
+// day, as soon as its configured outage_summary_at time has passed and today's
+// schedule is out (or unpublishedWait later without it).
+type Sender struct {
+	db        *database.DB
+	publisher *mq.Publisher
+	outage    *outage.Client
+	kyiv      *time.Location
+}
+
+func NewSender(db *database.DB, publisher *mq.Publisher, outageClient *outage.Client) *Sender {
+	kyiv, _ := time.LoadLocation("Europe/Kyiv")
+	return &Sender{db: db, publisher: publisher, outage: outageClient, kyiv: kyiv}
+}
+
+// Run publishes summaries for all monitors whose daily time has come.
+func (s *Sender) Run(ctx context.Context) error {
+	monitors, err := s.db.GetMonitorsWithChannels(ctx)
+	if err != nil {
+		return fmt.Errorf("query monitors: %w", err)
+	}
+
+	now := time.Now().In(s.kyiv)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	for _, m := range monitors {
+		if !s.due(m, now, today) {
+			continue
+		}
+		if err := s.send(ctx, m, now, today); err != nil {
+			log.Printf("[outagesummary] monitor %d: %v", m.ID, err)
+		}
+	}
+	return nil
+}
+
+// due reports whether the monitor's summary should be sent now.
+func (s *Sender) due(m *models.Monitor, now, today time.Time) bool {
+	if !m.OutageSummaryEnabled || m.OutageRegion == "" || m.OutageGroup == "" {
+		return false
+	}
+	if m.OutageSummarySentOn != nil && !m.OutageSummarySentOn.Before(today) {
+		return false
+	}
+	h, min, err := outage.ParseDailyAt(m.OutageSummaryAt)
+	if err != nil {
+		return false
+	}
+	return now.Hour()*60+now.Minute() >= h*60+min
+}
+
+// overdue reports whether unpublishedWait has passed since the monitor's summary time.
+func (s *Sender) overdue(m *models.Monitor, now time.Time) bool {
+	h, min, err := outage.ParseDailyAt(m.OutageSummaryAt)
+	if err != nil {
+		return true
+	}
+	return now.Sub(time.Date(now.Year(), now.Month(), now.Day(), h, min, 0, 0, now.Location())) >= unpublishedWait
+}
+
+func (s *Sender) send(ctx context.Context, m *models.Monitor, now, today time.Time) error {
+	fact, err := s.outage.GetGroupFact(m.OutageRegion, m.OutageGroup)
+	if err != nil {
+		return fmt.Errorf("get group fact: %w", err)
+	}
+
+	text, ok := outage.BuildDailySummary(m.OutageGroup, fact, now)
+	if !ok {
+		// The service still holds an older day: wait for today's schedule.
+		if !s.overdue(m, now) {
+			return nil
+		}
+		text = outage.BuildUnpublishedSummary(m.OutageGroup, now)
+	} else if fact.Stale {
+		text += outage.OutdatedNote
+	}
+	msg := mq.OutageSummaryMsg{
+		MonitorID:   m.ID,
+		ChannelID:   m.ChannelID,
+		MonitorName: m.Name,
+		Text:        text,
+	}
+	if m.OutageForecastEnabled {
+		// The forecast is a bonus; a missing one never holds the summary back.
+		f, err := s.outage.GetForecast(m.OutageRegion)
+		if err != nil {
+			log.Printf("[outagesummary] monitor %d: get forecast: %v", m.ID, err)
+		}
+		msg.Text += outage.ForecastAdvisory(f)
+	}
+	if err := s.publisher.Publish(ctx, mq.RoutingOutageSummary, msg); err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+	if err := s.db.MarkOutageSummarySent(ctx, m.ID, today); err != nil {
+		return fmt.Errorf("mark sent: %w", err)
+	}
+	log.Printf("[outagesummary] monitor %d (%s): summary published", m.ID, m.Name)
+	return nil
+}
@@ -0,0 +1,147 @@
+// Package ownerdigest sends owners of many monitors one daily DM listing what
+// needs their attention, instead of a DM per monitor and problem.
+package ownerdigest
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"strings"
+	"time"
+
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/models"
+	"no-lights-monitor/internal/mq"
+)
+
+const (
+	// staleAfter is how long a heartbeat device may stay silent before the
+	// digest lists it; shorter silences are usually just outages.
+	staleAfter = 48 * time.Hour
+	// hintWindow picks up cadence hints flagged since the previous digest.
+	hintWindow = 24 * time.Hour
+	// maxItems keeps the DM well under Telegram's message size limit.
+	maxItems = 40
+)
+
+const (
+	msgDigestHeader   = "📋 <b>Щоденний огляд моніторів</b>\n\nПотребують уваги (%d з %d):\n\n"
+	msgDigestItem     = "• <a href=\"%s/settings/%s\">%s</a> — %s\n"
+	msgDigestMore     = "\n…і ще %d"
+	msgDigestFooter   = "\n\n<i>Посилання ведуть на сторінку налаштувань; пароль — у /edit.</i>"
+	msgIssuePaused    = "призупинено системою %s (втрачено доступ до каналу або немає сигналу), відновіть через /resume"
+	msgIssueStale     = "пристрій не пінгує з %s"
+	msgIssueNeverSeen = "жодного пінгу з моменту створення"
+	msgIssueCadence   = "пристрій пінгує надто рідко для порогу офлайн"
+)
+
+// Digester builds and publishes the daily owner digests.
+type Digester struct {
+	db        *database.DB
+	publisher *mq.Publisher
+	baseURL   string
+	kyiv      *time.Location
+}
+
+func NewDigester(db *database.DB, publisher *mq.Publisher, baseURL string) *Digester {
+	kyiv, _ := time.LoadLocation("Europe/Kyiv")
+	return &Digester{db: db, publisher: publisher, baseURL: strings.TrimRight(baseURL, "/"), kyiv: kyiv}
+}
+
+// Run sends a digest to every owner with DigestMinMonitors or more monitors
+// that has something to fix. Owners with nothing to report get no DM.
+func (d *Digester) Run(ctx context.Context) error {
+	owners, err := d.db.GetDigestOwners(ctx)
+	if err != nil {
+		return fmt.Errorf("query digest owners: %w", err)
+	}
+	sent := 0
+	for _, ownerID := range owners {
+		text, err := d.digest(ctx, ownerID, time.Now())
+		if err != nil {
+			log.Printf("[digest] owner %d: %v", ownerID, err)
+			continue
+		}
+		if text == "" {
+			continue
+		}
+		if err := d.publisher.Publish(ctx, mq.RoutingOwnerDigest, mq.OwnerDigestMsg{OwnerTelegramID: ownerID, Text: text}); err != nil {
+			log.Printf("[digest] owner %d: publish: %v", ownerID, err)
+			continue
+		}
+		sent++
+	}
+	log.Printf("[digest] %d owners checked, %d digests sent", len(owners), sent)
+	return nil
+}
+
+// digest returns the owner's digest text, or "" when nothing needs attention.
+func (d *Digester) digest(ctx context.Context, ownerID int64, now time.Time) (string, error) {
+	monitors, err := d.db.GetMonitorsByTelegramID(ctx, ownerID)
+	if err != nil {
+		return "", fmt.Errorf("query monitors: %w", err)
+	}
+	ids := make([]int64, 0, len(monitors))
+	for _, m := range monitors {
+		ids = append(ids, m.ID)
+	}
+	pauses, err := d.db.GetSystemPauses(ctx, ids)
+	if err != nil {
+		return "", fmt.Errorf("query pauses: %w", err)
+	}
+
+	var items []string
+	total := 0
+	for _, m := range monitors {
+		if m.IsCanary {
+			continue
+		}
+		total++
+		issue := d.issue(m, pauses, now)
+		if issue == "" {
+			continue
+		}
+		items = append(items, fmt.Sprintf(msgDigestItem, d.baseURL, m.SettingsToken, html.EscapeString(m.Name), issue))
+	}
+	if len(items) == 0 {
+		return "", nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, msgDigestHeader, len(items), total)
+	for i, item := range items {
+		if i == maxItems {
+			fmt.Fprintf(&b, msgDigestMore, len(items)-maxItems)
+			break
+		}
+		b.WriteString(item)
+	}
+	b.WriteString(msgDigestFooter)
+	return b.String(), nil
+}
+
+// issue describes the most pressing problem of m, or "" if there is none.
+func (d *Digester) issue(m *models.Monitor, pauses map[int64]time.Time, now time.Time) string {
+	if !m.IsActive {
+		if at, ok := pauses[m.ID]; ok {
+			return fmt.Sprintf(msgIssuePaused, at.In(d.kyiv).Format("02.01"))
+		}
+		return "" // paused by the owner on purpose
+	}
+	if m.MonitorType != "heartbeat" {
+		return ""
+	}
+	switch {
+	case m.LastHeartbeatAt == nil:
+		if now.Sub(m.CreatedAt) >= staleAfter {
+			return msgIssueNeverSeen
+		}
+	case now.Sub(*m.LastHeartbeatAt) >= staleAfter:
+		return fmt.Sprintf(msgIssueStale, m.LastHeartbeatAt.In(d.kyiv).Format("02.01 15:04"))
+	}
+	if m.IntervalHintAt != nil && now.Sub(*m.IntervalHintAt) < hintWindow {
+		return msgIssueCadence
+	}
+	return ""
+}
@@ -0,0 +1,59 @@
+// Package plannedoutage downgrades the Telegram messages of status changes
+// that the outage schedule predicted, so channels only ring loudly for
+// unplanned events. Webhooks, SMS and push still get every change.
+package plannedoutage
+
+import (
+	"log"
+
+	"no-lights-monitor/internal/models"
+	"no-lights-monitor/internal/outage"
+)
+
+// statusNotifier mirrors heartbeat.Notifier.
+type statusNotifier interface {
+	NotifyStatusChange(sc models.StatusChange)
+}
+
+// Filter forwards status changes to the wrapped notifier, applying each
+// monitor's planned outage mode to changes that match its group's schedule.
+type Filter struct {
+	next         statusNotifier
+	outageClient *outage.Client
+}
+
+// NewFilter wraps next with schedule-aware suppression.
+func NewFilter(next statusNotifier, oc *outage.Client) *Filter {
+	return &Filter{next: next, outageClient: oc}
+}
+
+func (f *Filter) NotifyStatusChange(sc models.StatusChange) {
+	if sc.PlannedMode == outage.PlannedNotify || !f.scheduled(sc) {
+		f.next.NotifyStatusChange(sc)
+		return
+	}
+
+	switch sc.PlannedMode {
+	case outage.PlannedSkip:
+		log.Printf("[planned] monitor %d: online=%v matches the schedule, Telegram message skipped", sc.MonitorID, sc.IsOnline)
+		sc.SkipTelegram = true
+	case outage.PlannedSilent:
+		sc.Silent = true
+	}
+	f.next.NotifyStatusChange(sc)
+}
+
+// scheduled reports whether the monitor's group schedule predicted sc. Without
+// a group or schedule data the change counts as unplanned, so it is never
+// suppressed by mistake.
+func (f *Filter) scheduled(sc models.StatusChange) bool {
+	if sc.OutageRegion == "" || sc.OutageGroup == "" {
+		return false
+	}
+	fact, err := f.outageClient.GetGroupFact(sc.OutageRegion, sc.OutageGroup)
+	if err != nil {
+		log.Printf("[planned] monitor %d: outage fetch error for %s/%s: %v", sc.MonitorID, sc.OutageRegion, sc.OutageGroup, err)
+		return false
+	}
+	return fact.Scheduled(sc.IsOnline, sc.When)
+}
@@ -0,0 +1,116 @@
+// Package pushnotify sends status changes to the companion app installations
+// subscribed to a monitor.
+package pushnotify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/models"
+	"no-lights-monitor/internal/push"
+)
+
+// queueSize bounds the changes waiting for the push services; when it is
+// full further pushes are dropped.
+const queueSize = 1000
+
+const (
+	msgOnline  = "Світло з'явилося о %s (не було %s)"
+	msgOffline = "Світла немає з %s (було %s)"
+)
+
+// statusNotifier mirrors heartbeat.Notifier.
+type statusNotifier interface {
+	NotifyStatusChange(sc models.StatusChange)
+}
+
+// Notifier forwards status changes to the wrapped notifier and queues a push
+// for each of them. Run delivers the queue.
+type Notifier struct {
+	next    statusNotifier
+	db      *database.DB
+	senders map[string]push.Sender // by platform
+	queue   chan models.StatusChange
+	kyiv    *time.Location
+}
+
+// New wraps next with push delivery through senders.
+func New(next statusNotifier, db *database.DB, senders map[string]push.Sender) *Notifier {
+	kyiv, _ := time.LoadLocation("Europe/Kyiv")
+	return &Notifier{
+		next:    next,
+		db:      db,
+		senders: senders,
+		queue:   make(chan models.StatusChange, queueSize),
+		kyiv:    kyiv,
+	}
+}
+
+func (n *Notifier) NotifyStatusChange(sc models.StatusChange) {
+	n.next.NotifyStatusChange(sc)
+	if sc.TelegramOnly {
+		return
+	}
+	select {
+	case n.queue <- sc:
+	default:
+		log.Printf("[push] monitor %d: queue full, push dropped", sc.MonitorID)
+	}
+}
+
+// Run sends queued pushes until ctx is done.
+func (n *Notifier) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sc := <-n.queue:
+			if err := n.deliver(ctx, sc); err != nil {
+				log.Printf("[push] monitor %d: %v", sc.MonitorID, err)
+			}
+		}
+	}
+}
+
+// deliver pushes one status change to every device subscribed to the
+// monitor, forgetting tokens the push service no longer knows.
+func (n *Notifier) deliver(ctx context.Context, sc models.StatusChange) error {
+	devices, err := n.db.GetPushDevices(ctx, sc.MonitorID)
+	if err != nil {
+		return fmt.Errorf("load devices: %w", err)
+	}
+	if len(devices) == 0 {
+		return nil
+	}
+
+	format := msgOffline
+	if sc.IsOnline {
+		format = msgOnline
+	}
+	msg := push.Notification{
+		Title:     sc.Name,
+		Body:      fmt.Sprintf(format, sc.When.In(n.kyiv).Format("15:04"), database.FormatDuration(sc.Duration)),
+		MonitorID: sc.MonitorID,
+		IsOnline:  sc.IsOnline,
+	}
+	for _, d := range devices {
+		sender, ok := n.senders[d.Platform]
+		if !ok {
+			continue
+		}
+		err := sender.Send(ctx, d.Token, msg)
+		switch {
+		case errors.Is(err, push.ErrUnregistered):
+			if err := n.db.DeletePushToken(ctx, d.Platform, d.Token); err != nil {
+				log.Printf("[push] forget %s token: %v", d.Platform, err)
+			}
+		case err != nil:
+			log.Printf("[push] monitor %d: send via %s: %v", sc.MonitorID, d.Platform, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package recompute
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/models"
+	"no-lights-monitor/internal/mq"
+	"no-lights-monitor/internal/safego"
+)
+
+// throttle is the pause after each request, so a batch of thousands trickles
+// through the graph service and Telegram instead of bursting.
+const throttle = 2 * time.Second
+
+// Recomputer force-regenerates one kind of channel content for a monitor.
+// Implemented by the graph and outage photo updaters.
+type Recomputer interface {
+	Recompute(ctx context.Context, m *models.Monitor) error
+}
+
+// Runner works through recompute requests queued by the admin panel or the
+// nlm recompute tool, one at a time.
+type Runner struct {
+	db     *database.DB
+	graphs Recomputer
+	photos Recomputer
+}
+
+func NewRunner(db *database.DB, graphs, photos Recomputer) *Runner {
+	return &Runner{db: db, graphs: graphs, photos: photos}
+}
+
+// Listen consumes recompute requests until ctx is cancelled.
+func (r *Runner) Listen(ctx context.Context, consumer *mq.Consumer) {
+	deliveries, err := consumer.Consume(mq.QueueRecompute)
+	if err != nil {
+		log.Printf("[recompute] failed to consume %s: %v", mq.QueueRecompute, err)
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d, ok := <-deliveries:
+			if !ok {
+				return
+			}
+			_ = safego.Run("recompute", func() { r.handle(ctx, d) })
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(throttle):
+			}
+		}
+	}
+}
+
+// handle settles d exactly once, in its defer: acked once the request was
+// handled, even if regenerating failed, and dead-lettered when the body is
+// malformed or handling panicked.
+func (r *Runner) handle(ctx context.Context, d amqp.Delivery) {
+	handled := false
+	defer func() {
+		if handled {
+			d.Ack(false)
+		} else {
+			d.Nack(false, false)
+		}
+	}()
+
+	var msg mq.RecomputeMsg
+	if err := json.Unmarshal(d.Body, &msg); err != nil {
+		log.Printf("[recompute] bad request: %v", err)
+		return
+	}
+	r.recompute(ctx, msg)
+	handled = true
+}
+
+func (r *Runner) recompute(ctx context.Context, msg mq.RecomputeMsg) {
+	var target Recomputer
+	switch msg.Kind {
+	case mq.RecomputeGraph:
+		target = r.graphs
+	case mq.RecomputePhoto:
+		target = r.photos
+	default:
+		log.Printf("[recompute] monitor %d: unknown kind %q", msg.MonitorID, msg.Kind)
+		return
+	}
+
+	// Reload: the monitor may have changed or lost its message since enqueueing.
+	m, err := r.db.GetMonitorByID(ctx, msg.MonitorID)
+	if err != nil {
+		log.Printf("[recompute] monitor %d: load: %v", msg.MonitorID, err)
+		return
+	}
+	if err := target.Recompute(ctx, m); err != nil {
+		log.Printf("[recompute] monitor %d: %s: %v", m.ID, msg.Kind, err)
+		return
+	}
+	log.Printf("[recompute] monitor %d: %s regenerated", m.ID, msg.Kind)
+}
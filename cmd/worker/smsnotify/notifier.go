@@ -0,0 +1,121 @@
+// Package smsnotify texts status changes to the phone numbers an owner set up
+// for a monitor, for family members without Telegram. Texts count against a
+// per-monitor and a global monthly quota, so a flapping device or a
+// misconfiguration can't run up the gateway bill.
+package smsnotify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+	"unicode/utf8"
+
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/models"
+	"no-lights-monitor/internal/sms"
+)
+
+// queueSize bounds the changes waiting for the gateway; when it is full
+// (gateway down during an outage wave) further texts are dropped.
+const queueSize = 1000
+
+const (
+	msgOnline  = "Світло є: %s (%s)"
+	msgOffline = "Світла немає: %s (%s)"
+)
+
+// statusNotifier mirrors heartbeat.Notifier.
+type statusNotifier interface {
+	NotifyStatusChange(sc models.StatusChange)
+}
+
+// Notifier forwards status changes to the wrapped notifier and queues an SMS
+// for monitors with SMS enabled. Run delivers the queue.
+type Notifier struct {
+	next       statusNotifier
+	db         *database.DB
+	provider   sms.Provider
+	perMonitor int
+	budget     int
+	queue      chan models.StatusChange
+	kyiv       *time.Location
+}
+
+// New wraps next with SMS delivery through provider. perMonitor and budget
+// are the monthly SMS quotas of one monitor and of all monitors together.
+func New(next statusNotifier, db *database.DB, provider sms.Provider, perMonitor, budget int) *Notifier {
+	kyiv, _ := time.LoadLocation("Europe/Kyiv")
+	return &Notifier{
+		next:       next,
+		db:         db,
+		provider:   provider,
+		perMonitor: perMonitor,
+		budget:     budget,
+		queue:      make(chan models.StatusChange, queueSize),
+		kyiv:       kyiv,
+	}
+}
+
+func (n *Notifier) NotifyStatusChange(sc models.StatusChange) {
+	n.next.NotifyStatusChange(sc)
+	if sc.TelegramOnly {
+		return
+	}
+	select {
+	case n.queue <- sc:
+	default:
+		log.Printf("[sms] monitor %d: queue full, text dropped", sc.MonitorID)
+	}
+}
+
+// Run sends queued texts until ctx is done.
+func (n *Notifier) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sc := <-n.queue:
+			if err := n.deliver(ctx, sc); err != nil {
+				log.Printf("[sms] monitor %d: %v", sc.MonitorID, err)
+			}
+		}
+	}
+}
+
+// deliver texts one status change to the monitor's phones if it has SMS
+// enabled and the quotas allow it.
+func (n *Notifier) deliver(ctx context.Context, sc models.StatusChange) error {
+	m, err := n.db.GetMonitorByID(ctx, sc.MonitorID)
+	if err != nil {
+		return fmt.Errorf("load monitor: %w", err)
+	}
+	phones := sms.ParsePhones(m.SMSPhones)
+	if !m.SMSEnabled || len(phones) == 0 {
+		return nil
+	}
+
+	ok, err := n.db.ReserveSMS(ctx, m.ID, sms.Month(sc.When), len(phones), n.perMonitor, n.budget)
+	if err != nil {
+		return fmt.Errorf("reserve quota: %w", err)
+	}
+	if !ok {
+		log.Printf("[sms] monitor %d: monthly quota reached, %d texts skipped", m.ID, len(phones))
+		return nil
+	}
+
+	format := msgOffline
+	if sc.IsOnline {
+		format = msgOnline
+	}
+	at := sc.When.In(n.kyiv).Format("15:04")
+	// Shorten the name rather than the time, keeping the text to one SMS.
+	room := sms.MaxLen - utf8.RuneCountInString(fmt.Sprintf(format, "", at))
+	text := fmt.Sprintf(format, sms.Truncate(m.Name, room), at)
+	for _, phone := range phones {
+		if err := n.provider.Send(ctx, phone, text); err != nil {
+			log.Printf("[sms] monitor %d: send via %s: %v", m.ID, n.provider.Name(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+// Package surge detects outage waves from the rate of status transitions and
+// switches the pipeline to degraded behavior until the wave subsides:
+//   - status notifications are batched per window, and a monitor that flaps
+//     back to its previous state within one window is not posted at all;
+//   - the periodic graph and outage photo passes are postponed;
+//   - the API keeps serving the public monitor list from cache for longer.
+//
+// The state is shared through a Redis flag, so other services and worker
+// replicas follow the detecting instance.
+package surge
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"no-lights-monitor/internal/cache"
+	"no-lights-monitor/internal/metrics"
+	"no-lights-monitor/internal/models"
+	"no-lights-monitor/internal/scheduler"
+)
+
+const (
+	// batchWindow is how long status changes are held during a surge; the
+	// surge state is re-evaluated at the same pace.
+	batchWindow = 30 * time.Second
+	// cooldown is how long the rate must stay below half the threshold
+	// before normal behavior returns, so a wave's tail doesn't flip it back and forth.
+	cooldown = 10 * time.Minute
+	// flagTTL bounds the Redis flag, so a surge ends even if this worker dies.
+	flagTTL = 5 * time.Minute
+)
+
+// statusNotifier mirrors heartbeat.Notifier.
+type statusNotifier interface {
+	NotifyStatusChange(sc models.StatusChange)
+}
+
+// pending collects one monitor's status changes within a batch window.
+type pending struct {
+	first, last models.StatusChange
+}
+
+// Mode forwards status changes to the wrapped notifier, batching them while
+// transitions per minute stay above the threshold.
+type Mode struct {
+	next      statusNotifier
+	cache     *cache.Cache
+	threshold int
+
+	mu        sync.Mutex
+	recent    []time.Time // transitions of the last minute
+	active    bool
+	calmSince time.Time
+	order     []int64
+	held      map[int64]*pending
+}
+
+// New wraps next with surge detection. threshold is the number of transitions
+// per minute that starts a surge.
+func New(next statusNotifier, c *cache.Cache, threshold int) *Mode {
+	return &Mode{next: next, cache: c, threshold: threshold, held: make(map[int64]*pending)}
+}
+
+func (m *Mode) NotifyStatusChange(sc models.StatusChange) {
+	if sc.TelegramOnly {
+		// A settled flap repeats an earlier change: not a new transition, and
+		// never merged into a held one.
+		m.next.NotifyStatusChange(sc)
+		return
+	}
+	m.mu.Lock()
+	now := time.Now()
+	m.recent = append(m.recent, now)
+	m.prune(now)
+	if !m.active {
+		m.mu.Unlock()
+		m.next.NotifyStatusChange(sc)
+		return
+	}
+	if p, ok := m.held[sc.MonitorID]; ok {
+		p.last = sc
+	} else {
+		m.held[sc.MonitorID] = &pending{first: sc, last: sc}
+		m.order = append(m.order, sc.MonitorID)
+	}
+	m.mu.Unlock()
+}
+
+// prune drops transitions older than a minute. Callers hold m.mu.
+func (m *Mode) prune(now time.Time) {
+	cut := 0
+	for cut < len(m.recent) && now.Sub(m.recent[cut]) > time.Minute {
+		cut++
+	}
+	m.recent = m.recent[cut:]
+}
+
+// Run evaluates the surge state and flushes held notifications every
+// batchWindow until ctx is cancelled.
+func (m *Mode) Run(ctx context.Context) {
+	t := time.NewTicker(batchWindow)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			m.flush()
+			return
+		case <-t.C:
+			m.evaluate(ctx, time.Now())
+			m.flush()
+		}
+	}
+}
+
+// evaluate switches the surge state based on the last minute's transitions.
+func (m *Mode) evaluate(ctx context.Context, now time.Time) {
+	m.mu.Lock()
+	m.prune(now)
+	rate := len(m.recent)
+	wasActive := m.active
+	switch {
+	case !m.active && rate >= m.threshold:
+		m.active = true
+		m.calmSince = time.Time{}
+	case m.active && rate*2 < m.threshold:
+		if m.calmSince.IsZero() {
+			m.calmSince = now
+		} else if now.Sub(m.calmSince) >= cooldown {
+			m.active = false
+		}
+	case m.active:
+		m.calmSince = time.Time{}
+	}
+	active := m.active
+	m.mu.Unlock()
+
+	if active && !wasActive {
+		log.Printf("[surge] STARTED: %d transitions in the last minute (threshold %d)", rate, m.threshold)
+	} else if !active && wasActive {
+		log.Printf("[surge] ended: %d transitions in the last minute", rate)
+	}
+	if active {
+		metrics.SurgeActive.Set(1)
+		if err := m.cache.SetSurge(ctx, flagTTL); err != nil {
+			log.Printf("[surge] set flag: %v", err)
+		}
+	} else {
+		metrics.SurgeActive.Set(0)
+		if wasActive {
+			if err := m.cache.ClearSurge(ctx); err != nil {
+				log.Printf("[surge] clear flag: %v", err)
+			}
+		}
+	}
+}
+
+// flush forwards one change per monitor held in the window: the first one, so
+// the message carries when the monitor actually changed state and for how long
+// it had been in the previous one. Monitors that ended the window back in the
+// state they started it in are skipped.
+func (m *Mode) flush() {
+	m.mu.Lock()
+	order, held := m.order, m.held
+	m.order, m.held = nil, make(map[int64]*pending)
+	m.mu.Unlock()
+
+	var sent, dropped int
+	for _, id := range order {
+		p := held[id]
+		if p.first.IsOnline != p.last.IsOnline {
+			// Flapped back to the state before the window: nothing to report.
+			dropped++
+			continue
+		}
+		m.next.NotifyStatusChange(p.first)
+		sent++
+	}
+	if sent+dropped > 0 {
+		metrics.SurgeFlappedDropped.Add(float64(dropped))
+		log.Printf("[surge] batch: %d notifications sent, %d flaps dropped", sent, dropped)
+	}
+}
+
+// Postpone wraps a periodic job so it is skipped while a surge is flagged.
+// A skipped run returns scheduler.ErrSkipped, so it isn't recorded as a
+// success; the job runs again on its next scheduled pass.
+func Postpone(c *cache.Cache, name string, run func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if active, err := c.SurgeActive(ctx); err == nil && active {
+			return fmt.Errorf("%s postponed until the outage wave subsides: %w", name, scheduler.ErrSkipped)
+		}
+		return run(ctx)
+	}
+}
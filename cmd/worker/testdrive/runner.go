@@ -0,0 +1,131 @@
+package testdrive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/models"
+	"no-lights-monitor/internal/mq"
+	"no-lights-monitor/internal/safego"
+)
+
+// stepDelay spaces out the simulated messages so they arrive in order.
+const stepDelay = 2 * time.Second
+
+// simulatedOutage is the outage length shown in the simulated status messages.
+const simulatedOutage = 90 * time.Minute
+
+// Sandboxer renders a monitor's graph or outage photo into an arbitrary channel.
+// Implemented by the graph and outage photo updaters.
+type Sandboxer interface {
+	Sandbox(ctx context.Context, m *models.Monitor, channelID int64) error
+}
+
+// Runner replays a monitor's full notification cycle into a sandbox channel
+// on request from the admin panel. Every published message carries the sandbox
+// flag, so the bot neither updates the monitor's stored message IDs nor pauses
+// it on delivery errors.
+type Runner struct {
+	db     *database.DB
+	pub    *mq.Publisher
+	graphs Sandboxer
+	photos Sandboxer
+}
+
+func NewRunner(db *database.DB, pub *mq.Publisher, graphs, photos Sandboxer) *Runner {
+	return &Runner{db: db, pub: pub, graphs: graphs, photos: photos}
+}
+
+// Listen consumes test-drive requests until ctx is cancelled.
+func (r *Runner) Listen(ctx context.Context, consumer *mq.Consumer) {
+	deliveries, err := consumer.Consume(mq.QueueTestDrive)
+	if err != nil {
+		log.Printf("[testdrive] failed to consume %s: %v", mq.QueueTestDrive, err)
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d, ok := <-deliveries:
+			if !ok {
+				return
+			}
+			if err := safego.Run("testdrive", func() { r.handle(ctx, d) }); err != nil {
+				d.Nack(false, false)
+			}
+		}
+	}
+}
+
+func (r *Runner) handle(ctx context.Context, d amqp.Delivery) {
+	var msg mq.TestDriveMsg
+	if err := json.Unmarshal(d.Body, &msg); err != nil {
+		log.Printf("[testdrive] bad request: %v", err)
+		d.Nack(false, false)
+		return
+	}
+	if err := r.Run(ctx, msg.MonitorID, msg.ChannelID); err != nil {
+		log.Printf("[testdrive] monitor %d: %v", msg.MonitorID, err)
+	}
+	d.Ack(false)
+}
+
+// Run publishes offline → online status messages, the weekly graph and the
+// outage schedule photo for the monitor's exact configuration to channelID.
+func (r *Runner) Run(ctx context.Context, monitorID, channelID int64) error {
+	m, err := r.db.GetMonitorByID(ctx, monitorID)
+	if err != nil {
+		return fmt.Errorf("load monitor: %w", err)
+	}
+	log.Printf("[testdrive] monitor %d (%s): replaying into channel %d", m.ID, m.Name, channelID)
+
+	now := time.Now()
+	steps := []func() error{
+		func() error { return r.publishStatus(ctx, m, channelID, false, 3*time.Hour, now.Add(-simulatedOutage)) },
+		func() error { return r.publishStatus(ctx, m, channelID, true, simulatedOutage, now) },
+		func() error { return r.graphs.Sandbox(ctx, m, channelID) },
+		func() error { return r.photos.Sandbox(ctx, m, channelID) },
+	}
+	for i, step := range steps {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(stepDelay):
+			}
+		}
+		if err := step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Runner) publishStatus(ctx context.Context, m *models.Monitor, channelID int64, isOnline bool, duration time.Duration, when time.Time) error {
+	msg := mq.NewStatusChangeMsg(models.StatusChange{
+		MonitorID:     m.ID,
+		ChannelID:     channelID,
+		Name:          m.Name,
+		Address:       m.Address,
+		NotifyAddress: m.NotifyAddress,
+		IsOnline:      isOnline,
+		Duration:      duration,
+		When:          when,
+		OutageRegion:  m.OutageRegion,
+		OutageGroup:   m.OutageGroup,
+		NotifyOutage:  m.NotifyOutage,
+		NotifyStyle:   m.NotifyStyle,
+	})
+	msg.Sandbox = true
+	if err := r.pub.Publish(ctx, mq.RoutingStatusChange, msg); err != nil {
+		return fmt.Errorf("publish status: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package webhooknotify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"no-lights-monitor/internal/cache"
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/webhook"
+	"no-lights-monitor/internal/workpool"
+)
+
+const (
+	// heartbeatGroup is the Redis consumer group of the relay; worker
+	// replicas share it, so each event is delivered once.
+	heartbeatGroup = "webhook"
+	// heartbeatBatch is how many stream events are read at once.
+	heartbeatBatch = 100
+	// heartbeatBlock is how long one read waits for new events.
+	heartbeatBlock = 5 * time.Second
+	// heartbeatRetry is the pause after a failed stream read.
+	heartbeatRetry = 5 * time.Second
+)
+
+// HeartbeatRelay delivers the heartbeat firehose: the ping arrivals the API
+// appends to the Redis heartbeat stream are POSTed to the monitors' webhooks.
+// Deliveries are best effort; a failed one is not retried.
+type HeartbeatRelay struct {
+	db     *database.DB
+	cache  *cache.Cache
+	client *webhook.Client
+}
+
+// NewHeartbeatRelay creates a relay reading the heartbeat stream of c.
+func NewHeartbeatRelay(db *database.DB, c *cache.Cache) *HeartbeatRelay {
+	return &HeartbeatRelay{db: db, cache: c, client: webhook.NewHeartbeatClient()}
+}
+
+// SetSandbox logs the deliveries instead of making them (SANDBOX=1).
+func (r *HeartbeatRelay) SetSandbox(on bool) {
+	r.client.SetSandbox(on)
+}
+
+// Run relays heartbeat events until ctx is done.
+func (r *HeartbeatRelay) Run(ctx context.Context) {
+	consumer, _ := os.Hostname()
+	if consumer == "" {
+		consumer = "worker"
+	}
+	pool := workpool.New("webhook_heartbeat", concurrency)
+	for ctx.Err() == nil {
+		if err := r.cache.EnsureHeartbeatGroup(ctx, heartbeatGroup); err != nil {
+			log.Printf("[webhook] heartbeat stream group: %v", err)
+			sleep(ctx, heartbeatRetry)
+			continue
+		}
+		events, err := r.cache.ReadHeartbeatEvents(ctx, heartbeatGroup, consumer, heartbeatBatch, heartbeatBlock)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("[webhook] read heartbeat stream: %v", err)
+				sleep(ctx, heartbeatRetry)
+			}
+			continue
+		}
+		ids := make([]string, 0, len(events))
+		for _, ev := range events {
+			ids = append(ids, ev.StreamID)
+			pool.Go(func() {
+				if err := r.deliver(ctx, ev); err != nil {
+					log.Printf("[webhook] monitor %d heartbeat: %v", ev.MonitorID, err)
+				}
+			})
+		}
+		if err := r.cache.AckHeartbeatEvents(ctx, heartbeatGroup, ids...); err != nil {
+			log.Printf("[webhook] ack heartbeat events: %v", err)
+		}
+	}
+}
+
+// deliver POSTs one ping arrival, if the monitor still wants it.
+func (r *HeartbeatRelay) deliver(ctx context.Context, ev cache.HeartbeatEvent) error {
+	m, err := r.db.GetMonitorByID(ctx, ev.MonitorID)
+	if err != nil {
+		return fmt.Errorf("load monitor: %w", err)
+	}
+	if !m.HeartbeatWebhookEnabled || m.WebhookURL == "" {
+		return nil
+	}
+
+	p := webhook.Payload{
+		ID:        fmt.Sprintf("%d-hb-%d", ev.MonitorID, ev.At.UnixNano()),
+		Event:     webhook.EventHeartbeat,
+		MonitorID: ev.MonitorID,
+		Name:      m.Name,
+		Status:    "online",
+		IsOnline:  true,
+		Timestamp: ev.At.UTC(),
+	}
+	if err := r.client.Send(ctx, m.WebhookURL, m.WebhookSecret, p); err != nil {
+		return fmt.Errorf("deliver: %w", err)
+	}
+	return nil
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
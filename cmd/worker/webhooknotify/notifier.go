@@ -0,0 +1,111 @@
+// Package webhooknotify POSTs status changes to the webhook URLs owners set
+// up for their monitors, and power returns to their trigger URLs (see
+// PowerReturn), for home automation and custom integrations.
+package webhooknotify
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/models"
+	"no-lights-monitor/internal/webhook"
+	"no-lights-monitor/internal/workpool"
+)
+
+const (
+	// queueSize bounds the changes waiting for delivery; when it is full
+	// further webhooks are dropped.
+	queueSize = 1000
+	// concurrency bounds parallel deliveries, so a few slow or retrying
+	// endpoints don't hold up everyone else's.
+	concurrency = 8
+)
+
+// statusNotifier mirrors heartbeat.Notifier.
+type statusNotifier interface {
+	NotifyStatusChange(sc models.StatusChange)
+}
+
+// Notifier forwards status changes to the wrapped notifier and queues a
+// webhook delivery for each of them. Run delivers the queue.
+type Notifier struct {
+	next   statusNotifier
+	db     *database.DB
+	client *webhook.Client
+	queue  chan models.StatusChange
+}
+
+// New wraps next with webhook delivery.
+func New(next statusNotifier, db *database.DB) *Notifier {
+	return &Notifier{
+		next:   next,
+		db:     db,
+		client: webhook.NewClient(),
+		queue:  make(chan models.StatusChange, queueSize),
+	}
+}
+
+// SetSandbox logs the deliveries instead of making them (SANDBOX=1).
+func (n *Notifier) SetSandbox(on bool) {
+	n.client.SetSandbox(on)
+}
+
+func (n *Notifier) NotifyStatusChange(sc models.StatusChange) {
+	n.next.NotifyStatusChange(sc)
+	if sc.TelegramOnly {
+		return
+	}
+	select {
+	case n.queue <- sc:
+	default:
+		log.Printf("[webhook] monitor %d: queue full, delivery dropped", sc.MonitorID)
+	}
+}
+
+// Run delivers queued webhooks until ctx is done.
+func (n *Notifier) Run(ctx context.Context) {
+	pool := workpool.New("webhook", concurrency)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sc := <-n.queue:
+			pool.Go(func() {
+				if err := n.deliver(ctx, sc); err != nil {
+					log.Printf("[webhook] monitor %d: %v", sc.MonitorID, err)
+				}
+			})
+		}
+	}
+}
+
+// deliver POSTs one status change to the monitor's webhook, if it has one.
+func (n *Notifier) deliver(ctx context.Context, sc models.StatusChange) error {
+	m, err := n.db.GetMonitorByID(ctx, sc.MonitorID)
+	if err != nil {
+		return fmt.Errorf("load monitor: %w", err)
+	}
+	if m.WebhookURL == "" {
+		return nil
+	}
+	status := "offline"
+	if sc.IsOnline {
+		status = "online"
+	}
+	p := webhook.Payload{
+		ID:          fmt.Sprintf("%d-%d", sc.MonitorID, sc.When.UnixNano()),
+		Event:       webhook.EventStatusChange,
+		MonitorID:   sc.MonitorID,
+		Name:        m.Name,
+		Status:      status,
+		IsOnline:    sc.IsOnline,
+		DurationSec: int64(sc.Duration.Seconds()),
+		Timestamp:   sc.When.UTC(),
+	}
+	if err := n.client.Send(ctx, m.WebhookURL, m.WebhookSecret, p); err != nil {
+		return fmt.Errorf("deliver: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package webhooknotify
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/models"
+	"no-lights-monitor/internal/webhook"
+	"no-lights-monitor/internal/workpool"
+)
+
+// PowerReturn calls the power return trigger URLs of monitors on every
+// offline → online transition. Unlike the status webhooks it is not part of
+// the notifier chain, so mute, maintenance windows, planned outage modes,
+// surge batching and flap suppression never hold a trigger back: they only
+// decide about messages, while the trigger switches things on at home.
+type PowerReturn struct {
+	db     *database.DB
+	client *webhook.Client
+	queue  chan models.StatusChange
+}
+
+// NewPowerReturn creates the power return trigger. Run delivers its queue.
+func NewPowerReturn(db *database.DB) *PowerReturn {
+	return &PowerReturn{
+		db:     db,
+		client: webhook.NewClient(),
+		queue:  make(chan models.StatusChange, queueSize),
+	}
+}
+
+// SetSandbox logs the deliveries instead of making them (SANDBOX=1).
+func (p *PowerReturn) SetSandbox(on bool) {
+	p.client.SetSandbox(on)
+}
+
+// NotifyPowerReturn queues the trigger call of a monitor that just came back online.
+func (p *PowerReturn) NotifyPowerReturn(sc models.StatusChange) {
+	select {
+	case p.queue <- sc:
+	default:
+		log.Printf("[webhook] monitor %d: queue full, power return trigger dropped", sc.MonitorID)
+	}
+}
+
+// Run calls queued triggers until ctx is done.
+func (p *PowerReturn) Run(ctx context.Context) {
+	pool := workpool.New("power_return", concurrency)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sc := <-p.queue:
+			pool.Go(func() {
+				if err := p.deliver(ctx, sc); err != nil {
+					log.Printf("[webhook] monitor %d: power return trigger: %v", sc.MonitorID, err)
+				}
+			})
+		}
+	}
+}
+
+// deliver calls the monitor's power return trigger, if it has one.
+func (p *PowerReturn) deliver(ctx context.Context, sc models.StatusChange) error {
+	m, err := p.db.GetMonitorByID(ctx, sc.MonitorID)
+	if err != nil {
+		return fmt.Errorf("load monitor: %w", err)
+	}
+	if m.PowerReturnURL == "" {
+		return nil
+	}
+	payload := webhook.Payload{
+		ID:          fmt.Sprintf("%d-pr-%d", sc.MonitorID, sc.When.UnixNano()),
+		Event:       webhook.EventPowerReturned,
+		MonitorID:   sc.MonitorID,
+		Name:        m.Name,
+		Status:      "online",
+		IsOnline:    true,
+		DurationSec: int64(sc.Duration.Seconds()),
+		Timestamp:   sc.When.UTC(),
+	}
+	return p.client.SendTrigger(ctx, m.PowerReturnURL, m.PowerReturnSecret, payload)
+}
@@ -57,10 +57,11 @@ def draw_chart(days: list) -> bytes:
         "\u0432\u0456\u0434\u043a\u043b\u044e\u0447\u0435\u043d\u044c "
         "\u0441\u0432\u0456\u0442\u043b\u0430"
     )
+    span   = d_from if d_from == d_to else f'{d_from} \u2013 {d_to}'
     o.append(
         f'<text x="{W // 2}" y="36" text-anchor="middle" '
         f'font-size="22" font-weight="bold" fill="{C_TEXT}">'
-        f'{title}  {d_from} \u2013 {d_to}</text>'
+        f'{title}  {span}</text>'
     )
 
     # ── day rows ───────────────────────────────────────────────────────────────
@@ -176,4 +177,174 @@ def draw_chart(days: list) -> bytes:
     svg_bytes = ''.join(o).encode('utf-8')
 
     import cairosvg
-    return cairosvg.svg2png(bytestring=svg_bytes, scale=2)
\ No newline at end of file
+    return cairosvg.svg2png(bytestring=svg_bytes, scale=2)
+
+
+# ── Ping latency chart ─────────────────────────────────────────────────────────
+
+LAT_PAD_L  = 90
+LAT_PAD_R  = 40
+LAT_PLOT_W = W - LAT_PAD_L - LAT_PAD_R
+LAT_RTT_H  = 300
+LAT_GAP    = 40
+LAT_LOSS_H = 90
+LAT_HOURS  = 7 * 24
+C_RTT      = '#1A73E8'
+C_GRID     = '#EEEEEE'
+
+
+def _lx(hour: float) -> float:
+    return LAT_PAD_L + hour / LAT_HOURS * LAT_PLOT_W
+
+
+def _nice_max(value: float) -> float:
+    """Rounds the RTT axis up to 1, 2 or 5 × 10^n ms."""
+    step = 1.0
+    while True:
+        for m in (1, 2, 5):
+            if value <= m * step:
+                return m * step
+        step *= 10
+
+
+def draw_latency_chart(day_labels: list, hours: list, now_hour: float,
+                       median_ms, loss_pct) -> bytes:
+    """
+    day_labels: 7 labels like 'ПН (16.02)'.
+    hours:      [{'hour': 0..167, 'rtt_ms': float | None, 'loss': 0..1}] for
+                hours with samples; the others are drawn as gaps.
+    now_hour:   hours since the week start; later hours are shaded as future.
+    """
+    rtt_y  = PAD_T
+    loss_y = rtt_y + LAT_RTT_H + LAT_GAP
+    SVG_H  = loss_y + LAT_LOSS_H + PAD_B + 24
+
+    rtts   = sorted(h['rtt_ms'] for h in hours if h['rtt_ms'] is not None)
+    # The 95th percentile keeps one spike from flattening the rest of the week.
+    p95    = rtts[max(int(len(rtts) * 0.95) - 1, 0)] if rtts else 0
+    top    = _nice_max(max(p95 * 1.2, 10))
+
+    o = []
+    o.append(
+        f'<svg xmlns="http://www.w3.org/2000/svg" width="{W}" height="{SVG_H}" '
+        f'style="font-family:DejaVu Sans,Arial,sans-serif;">'
+    )
+    o.append(f'<rect width="{W}" height="{SVG_H}" fill="#ffffff"/>')
+
+    # ── title ──────────────────────────────────────────────────────────────────
+    title = "Затримка пінгу"
+    o.append(
+        f'<text x="{W // 2}" y="36" text-anchor="middle" '
+        f'font-size="22" font-weight="bold" fill="{C_TEXT}">'
+        f'{title}  {_date(day_labels[0])} – {_date(day_labels[-1])}</text>'
+    )
+
+    # ── future shading ─────────────────────────────────────────────────────────
+    if now_hour < LAT_HOURS:
+        fx = _lx(max(now_hour, 0))
+        for y, h in ((rtt_y, LAT_RTT_H), (loss_y, LAT_LOSS_H)):
+            o.append(
+                f'<rect x="{fx:.1f}" y="{y}" width="{LAT_PAD_L + LAT_PLOT_W - fx:.1f}" '
+                f'height="{h}" fill="{C_GRAY}" opacity="0.5"/>'
+            )
+
+    # ── RTT grid and axis ──────────────────────────────────────────────────────
+    for i in range(5):
+        v  = top * i / 4
+        gy = rtt_y + LAT_RTT_H - LAT_RTT_H * i / 4
+        o.append(
+            f'<line x1="{LAT_PAD_L}" y1="{gy:.1f}" x2="{LAT_PAD_L + LAT_PLOT_W}" y2="{gy:.1f}" '
+            f'stroke="{C_GRID}" stroke-width="1"/>'
+        )
+        o.append(
+            f'<text x="{LAT_PAD_L - 8}" y="{gy + 5:.1f}" text-anchor="end" '
+            f'font-size="13" fill="{C_AXIS}">{v:g} мс</text>'
+        )
+
+    # ── loss axis ──────────────────────────────────────────────────────────────
+    for pct in (0, 50, 100):
+        gy = loss_y + LAT_LOSS_H - LAT_LOSS_H * pct / 100
+        o.append(
+            f'<line x1="{LAT_PAD_L}" y1="{gy:.1f}" x2="{LAT_PAD_L + LAT_PLOT_W}" y2="{gy:.1f}" '
+            f'stroke="{C_GRID}" stroke-width="1"/>'
+        )
+        o.append(
+            f'<text x="{LAT_PAD_L - 8}" y="{gy + 5:.1f}" text-anchor="end" '
+            f'font-size="13" fill="{C_AXIS}">{pct}%</text>'
+        )
+    o.append(
+        f'<text x="{LAT_PAD_L}" y="{loss_y - 10}" font-size="14" fill="{C_SUB}">'
+        f'Втрати пакетів</text>'
+    )
+
+    # ── day separators and labels ──────────────────────────────────────────────
+    for d in range(8):
+        dx = _lx(d * 24)
+        o.append(
+            f'<line x1="{dx:.1f}" y1="{rtt_y}" x2="{dx:.1f}" y2="{loss_y + LAT_LOSS_H}" '
+            f'stroke="rgba(0,0,0,0.18)" stroke-width="1"/>'
+        )
+    for d, label in enumerate(day_labels):
+        cx = _lx(d * 24 + 12)
+        name, date = label.split(' ', 1)
+        o.append(
+            f'<text x="{cx:.1f}" y="{loss_y + LAT_LOSS_H + 22}" text-anchor="middle" '
+            f'font-size="15" font-weight="bold" fill="{C_TEXT}">{name}</text>'
+        )
+        o.append(
+            f'<text x="{cx:.1f}" y="{loss_y + LAT_LOSS_H + 40}" text-anchor="middle" '
+            f'font-size="12" fill="{C_SUB}">{date.strip("()")}</text>'
+        )
+
+    # ── loss bars ──────────────────────────────────────────────────────────────
+    bar_w = LAT_PLOT_W / LAT_HOURS
+    for h in hours:
+        if h['loss'] <= 0:
+            continue
+        bh = LAT_LOSS_H * min(h['loss'], 1)
+        o.append(
+            f'<rect x="{_lx(h["hour"]):.1f}" y="{loss_y + LAT_LOSS_H - bh:.1f}" '
+            f'width="{bar_w:.1f}" height="{bh:.1f}" fill="{C_OFF}"/>'
+        )
+
+    # ── RTT line, broken where an hour has no reply ───────────────────────────
+    run, prev = [], None
+    for h in sorted(hours, key=lambda x: x['hour']):
+        if h['rtt_ms'] is None or (prev is not None and h['hour'] != prev + 1):
+            if len(run) > 1:
+                o.append(f'<polyline points="{" ".join(run)}" fill="none" stroke="{C_RTT}" stroke-width="2"/>')
+            elif run:
+                cx, cy = run[0].split(',')
+                o.append(f'<circle cx="{cx}" cy="{cy}" r="2" fill="{C_RTT}"/>')
+            run = []
+        if h['rtt_ms'] is not None:
+            ry = rtt_y + LAT_RTT_H - LAT_RTT_H * min(h['rtt_ms'], top) / top
+            run.append(f'{_lx(h["hour"] + 0.5):.1f},{ry:.1f}')
+        prev = h['hour']
+    if len(run) > 1:
+        o.append(f'<polyline points="{" ".join(run)}" fill="none" stroke="{C_RTT}" stroke-width="2"/>')
+    elif run:
+        cx, cy = run[0].split(',')
+        o.append(f'<circle cx="{cx}" cy="{cy}" r="2" fill="{C_RTT}"/>')
+
+    # ── footer ─────────────────────────────────────────────────────────────────
+    fy = SVG_H - 16
+    median_txt = f'{median_ms:.0f} мс' if median_ms is not None else '—'
+    loss_txt   = f'{loss_pct:.1f}%' if loss_pct is not None else '—'
+    o.append(
+        f'<text x="{LAT_PAD_L}" y="{fy}" font-size="15" fill="{C_TEXT}">'
+        f'Медіана '
+        f'<tspan font-weight="bold" fill="{C_RTT}">{median_txt}</tspan></text>'
+    )
+    o.append(
+        f'<text x="{LAT_PAD_L + LAT_PLOT_W // 2}" y="{fy}" font-size="15" fill="{C_TEXT}">'
+        f'Втрати '
+        f'<tspan font-weight="bold" fill="{C_OFF}">{loss_txt}</tspan></text>'
+    )
+
+    o.append('</svg>')
+
+    svg_bytes = ''.join(o).encode('utf-8')
+
+    import cairosvg
+    return cairosvg.svg2png(bytestring=svg_bytes, scale=2)
@@ -1,11 +1,15 @@
-from fastapi import FastAPI, HTTPException
+from fastapi import Depends, FastAPI, HTTPException, Request
 from fastapi.responses import Response
 from pydantic import BaseModel, Field
 from typing import List, Optional
 from datetime import datetime, timedelta, timezone
 from zoneinfo import ZoneInfo
-from draw_chart_svg import draw_chart
+from draw_chart_svg import draw_chart, draw_latency_chart
+import hashlib
+import hmac
 import logging
+import os
+import time
 
 logging.getLogger("uvicorn.access").addFilter(
     type("", (logging.Filter,), {"filter": staticmethod(lambda r: "/health" not in r.getMessage())})()
@@ -14,6 +18,10 @@ logging.getLogger("uvicorn.access").addFilter(
 KYIV_TZ      = ZoneInfo('Europe/Kyiv')
 DAY_NAMES_UA = ['ПН', 'ВТ', 'СР', 'ЧТ', 'ПТ', 'СБ', 'НД']
 
+# Shared secret of internal/svcauth; empty disables signature checks.
+INTERNAL_AUTH_SECRET = os.getenv("INTERNAL_AUTH_SECRET", "")
+SIGNATURE_MAX_SKEW   = 300  # seconds
+
 app = FastAPI(
     title="Light Status Graph Service",
     description="Generates weekly light status graphs from monitoring data",
@@ -56,6 +64,37 @@ class WeekFromEventsRequest(BaseModel):
 
 
 
+class PeriodFromEventsRequest(BaseModel):
+    monitor_id: int            = Field(..., description="Monitor ID")
+    start:      str            = Field(..., description="Kyiv midnight the graph starts at, e.g. '2026-10-01T00:00:00+03:00'")
+    days:       int            = Field(..., ge=1, le=31, description="Number of day rows: 1 for a daily graph, the month's length for a monthly one")
+    events:     List[RawEvent] = Field(..., description=(
+        "Events of the period, plus the last one before start so the "
+        "initial status is known (see /generate-week-graph)."
+    ))
+
+
+
+# ── Models: ping latency ──────────────────────────────────────────────────────
+
+class LatencyPoint(BaseModel):
+    hour:   str
+    rtt_ms: Optional[float] = None
+    loss:   float
+    probes: int
+
+
+class LatencyGraphRequest(BaseModel):
+    monitor_id: int                = Field(..., description="Monitor ID")
+    week_start: str                = Field(..., description="Monday 00:00 UTC e.g. '2026-02-09T00:00:00Z'")
+    points:     List[LatencyPoint] = Field(..., description=(
+        "Hourly aggregates of the week so far: median RTT (null when no "
+        "reply came back) and the share of lost pings. Hours without "
+        "samples are left out and drawn as gaps."
+    ))
+
+
+
 # ── Helpers ────────────────────────────────────────────────────────────────────
 
 def parse_ts(ts: str) -> datetime:
@@ -123,11 +162,15 @@ def build_day_segments(
 # ── Core logic: /generate-week-graph ─────────────────────────────────────────
 
 def build_week_from_events(req: WeekFromEventsRequest) -> bytes:
-    week_start_dt = parse_ts(req.week_start)
-    week_end_dt   = week_start_dt + timedelta(days=7)
+    return draw_chart(build_days(parse_ts(req.week_start), 7, req.events))
+
+
+def build_days(week_start_dt: datetime, n_days: int, events: List[RawEvent]) -> list:
+    """Day rows of n_days days from week_start_dt, ready for draw_chart."""
+    week_end_dt   = week_start_dt + timedelta(days=n_days)
     now_kyiv      = datetime.now(KYIV_TZ)
 
-    all_sorted = sorted(req.events, key=lambda e: parse_ts(e.timestamp))
+    all_sorted = sorted(events, key=lambda e: parse_ts(e.timestamp))
 
     # Initial status: last event BEFORE week_start.
     # None = no history (new monitor) → gray until first real event.
@@ -149,7 +192,7 @@ def build_week_from_events(req: WeekFromEventsRequest) -> bytes:
     days_draw: list[dict] = []
     carry = initial_status   # None for new monitor; real bool once first event fires
 
-    for offset in range(7):
+    for offset in range(n_days):
         day_dt    = week_start_dt + timedelta(days=offset)
         day_key   = day_dt.strftime('%Y-%m-%d')
         label     = f"{DAY_NAMES_UA[day_dt.weekday()]} ({day_dt.strftime('%d.%m')})"
@@ -183,7 +226,61 @@ def build_week_from_events(req: WeekFromEventsRequest) -> bytes:
             })
             carry = last_status
 
-    return draw_chart(days_draw)
+    return days_draw
+
+
+
+# ── Core logic: /generate-period-graph ────────────────────────────────────────
+
+def build_period_from_events(req: PeriodFromEventsRequest) -> bytes:
+    return draw_chart(build_days(parse_ts(req.start), req.days, req.events))
+
+
+
+# ── Core logic: /generate-latency-graph ───────────────────────────────────────
+
+def build_latency_graph(req: LatencyGraphRequest) -> bytes:
+    week_start_dt = parse_ts(req.week_start)
+    now_kyiv      = datetime.now(KYIV_TZ)
+
+    day_labels = []
+    for offset in range(7):
+        day_dt = week_start_dt + timedelta(days=offset)
+        day_labels.append(f"{DAY_NAMES_UA[day_dt.weekday()]} ({day_dt.strftime('%d.%m')})")
+
+    hours = []
+    for p in req.points:
+        h = int((parse_ts(p.hour) - week_start_dt).total_seconds() // 3600)
+        if 0 <= h < 7 * 24:
+            hours.append({'hour': h, 'rtt_ms': p.rtt_ms, 'loss': p.loss})
+
+    rtts   = sorted(p.rtt_ms for p in req.points if p.rtt_ms is not None)
+    probes = sum(p.probes for p in req.points)
+    median = rtts[len(rtts) // 2] if rtts else None
+    loss   = sum(p.loss * p.probes for p in req.points) / probes * 100 if probes else None
+    now_h  = (now_kyiv - week_start_dt).total_seconds() / 3600
+
+    return draw_latency_chart(day_labels, hours, now_h, median, loss)
+
+
+
+# ── Auth ───────────────────────────────────────────────────────────────────────
+
+async def verify_signature(request: Request):
+    """Checks the X-NLM-Timestamp / X-NLM-Signature headers set by the worker."""
+    if not INTERNAL_AUTH_SECRET:
+        return
+    ts  = request.headers.get("X-NLM-Timestamp", "")
+    sig = request.headers.get("X-NLM-Signature", "")
+    if not ts or not sig or not ts.isdigit() or abs(time.time() - int(ts)) > SIGNATURE_MAX_SKEW:
+        raise HTTPException(status_code=401, detail="unauthorized")
+
+    uri = request.url.path + ("?" + request.url.query if request.url.query else "")
+    body_hash = hashlib.sha256(await request.body()).hexdigest()
+    msg  = f"{ts}\n{request.method}\n{uri}\n{body_hash}".encode()
+    want = hmac.new(INTERNAL_AUTH_SECRET.encode(), msg, hashlib.sha256).hexdigest()
+    if not hmac.compare_digest(want, sig):
+        raise HTTPException(status_code=401, detail="unauthorized")
 
 
 
@@ -203,7 +300,8 @@ async def health_check():
 @app.post("/generate-week-graph", response_class=Response,
           responses={200: {"content": {"image/png": {}},
                            "description": "Full Mon-Sun PNG. Past=green/red. Unknown/future=gray."}},
-          summary="Generate weekly graph from raw events (recommended)")
+          summary="Generate weekly graph from raw events (recommended)",
+          dependencies=[Depends(verify_signature)])
 async def generate_week_graph(request: WeekFromEventsRequest):
     """
     Pass week_start + raw events (include at least one event before week_start
@@ -217,6 +315,36 @@ async def generate_week_graph(request: WeekFromEventsRequest):
 
 
 
+@app.post("/generate-period-graph", response_class=Response,
+          responses={200: {"content": {"image/png": {}},
+                           "description": "PNG with one row per day of the period."}},
+          summary="Generate a daily or monthly graph from raw events",
+          dependencies=[Depends(verify_signature)])
+async def generate_period_graph(request: PeriodFromEventsRequest):
+    """
+    Same rendering as /generate-week-graph for any number of days from a Kyiv
+    midnight: one row for a daily graph, a row per day for a monthly one.
+    """
+    try:
+        return Response(content=build_period_from_events(request), media_type="image/png")
+    except Exception as e:
+        raise HTTPException(status_code=500, detail=str(e))
+
+
+
+@app.post("/generate-latency-graph", response_class=Response,
+          responses={200: {"content": {"image/png": {}},
+                           "description": "Mon-Sun PNG of hourly median RTT and packet loss."}},
+          summary="Generate weekly ping latency graph from hourly aggregates",
+          dependencies=[Depends(verify_signature)])
+async def generate_latency_graph(request: LatencyGraphRequest):
+    try:
+        return Response(content=build_latency_graph(request), media_type="image/png")
+    except Exception as e:
+        raise HTTPException(status_code=500, detail=str(e))
+
+
+
 @app.get("/test-graph", response_class=Response,
          responses={200: {"content": {"image/png": {}}}},
          summary="Returns a sample graph with realistic fixture data")
@@ -257,6 +385,7 @@ async def root():
         "version": "2.0.0",
         "endpoints": {
             "POST /generate-week-graph": "raw events -> full Mon-Sun graph",
+            "POST /generate-latency-graph": "hourly RTT/loss -> Mon-Sun latency graph",
             "GET  /health":              "health check",
             "GET  /docs":                "Swagger UI",
         },
@@ -0,0 +1,127 @@
+// Package backfill restores outages the heartbeat checker missed, e.g. while
+// the worker was down or redeploying. The API logs every heartbeat gap longer
+// than the monitor's offline threshold in Redis; a gap with no status event
+// inside it means nobody noticed, so the weekly graph shows it as online.
+package backfill
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"no-lights-monitor/internal/cache"
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/models"
+)
+
+// Options select what a backfill run looks at.
+type Options struct {
+	Since            time.Time     // only gaps that ended after this
+	MonitorID        int64         // 0 means all heartbeat monitors
+	DefaultThreshold time.Duration // offline threshold for monitors without their own
+	DryRun           bool          // report periods without inserting events
+}
+
+// Period is an inferred outage: offline at From (last heartbeat), online at To.
+type Period struct {
+	MonitorID int64     `json:"monitor_id"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+}
+
+// Report summarizes a backfill run.
+type Report struct {
+	Monitors int      `json:"monitors"`
+	Gaps     int      `json:"gaps"`
+	Periods  []Period `json:"periods"`
+	DryRun   bool     `json:"dry_run"`
+}
+
+// Run scans heartbeat gaps and inserts an inferred offline/online event pair
+// for every gap the checker did not record. A gap is left alone when:
+//   - it is not longer than the monitor's offline threshold;
+//   - any status event already falls inside it;
+//   - the monitor was not online before it (or has no history yet);
+//   - monitoring was paused or resumed during it.
+func Run(ctx context.Context, db *database.DB, c *cache.Cache, opts Options) (*Report, error) {
+	var monitors []*models.Monitor
+	if opts.MonitorID != 0 {
+		m, err := db.GetMonitorByID(ctx, opts.MonitorID)
+		if err != nil {
+			return nil, fmt.Errorf("get monitor %d: %w", opts.MonitorID, err)
+		}
+		monitors = []*models.Monitor{m}
+	} else {
+		all, err := db.GetAllMonitors(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get monitors: %w", err)
+		}
+		monitors = all
+	}
+
+	report := &Report{Periods: []Period{}, DryRun: opts.DryRun}
+	for _, m := range monitors {
+		if m.MonitorType != "heartbeat" || m.IsCanary {
+			continue
+		}
+		report.Monitors++
+
+		gaps, err := c.HeartbeatGaps(ctx, m.ID, opts.Since)
+		if err != nil {
+			return report, fmt.Errorf("gaps of monitor %d: %w", m.ID, err)
+		}
+		threshold := opts.DefaultThreshold
+		if m.OfflineThresholdSec > 0 {
+			threshold = time.Duration(m.OfflineThresholdSec) * time.Second
+		}
+
+		for _, g := range gaps {
+			report.Gaps++
+			missed, err := missedOutage(ctx, db, m.ID, g, threshold)
+			if err != nil {
+				return report, fmt.Errorf("check gap of monitor %d: %w", m.ID, err)
+			}
+			if !missed {
+				continue
+			}
+			if !opts.DryRun {
+				if err := db.InsertInferredStatusEvents(ctx, m.ID, g.From, g.To); err != nil {
+					return report, fmt.Errorf("insert events for monitor %d: %w", m.ID, err)
+				}
+				log.Printf("[backfill] monitor %d: inferred outage %s – %s", m.ID, g.From.Format(time.RFC3339), g.To.Format(time.RFC3339))
+			}
+			report.Periods = append(report.Periods, Period{MonitorID: m.ID, From: g.From, To: g.To})
+		}
+	}
+	return report, nil
+}
+
+// missedOutage reports whether gap g is an outage with no trace in status_events.
+func missedOutage(ctx context.Context, db *database.DB, monitorID int64, g cache.HeartbeatGap, threshold time.Duration) (bool, error) {
+	if g.To.Sub(g.From) <= threshold {
+		return false, nil
+	}
+
+	events, err := db.GetStatusHistory(ctx, monitorID, g.From, g.To)
+	if err != nil {
+		return false, err
+	}
+	if len(events) > 0 {
+		return false, nil
+	}
+
+	prev, err := db.GetLastEventBefore(ctx, monitorID, g.From)
+	if err != nil {
+		return false, err
+	}
+	if prev == nil || !prev.IsOnline {
+		return false, nil
+	}
+
+	paused, err := db.HasMonitorChangeBetween(ctx, monitorID, "is_active", g.From, g.To)
+	if err != nil {
+		return false, err
+	}
+	return !paused, nil
+}
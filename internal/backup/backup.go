@@ -0,0 +1,157 @@
+// Package backup writes logical backups of the monitor data (users, monitors
+// and recent status events) to an S3-compatible bucket and restores them.
+//
+// A backup is a gzipped JSON-lines file: a header line, then one
+// {"table": ..., "row": ...} line per row in database.BackupTables order.
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"no-lights-monitor/internal/database"
+)
+
+// Format identifies backup files in their header line.
+const Format = "nlm-backup/1"
+
+// keyLayout names backup objects; keys sort by creation time.
+const keyLayout = "20060102T150405Z"
+
+// Header is the first line of a backup.
+type Header struct {
+	Format      string    `json:"format"`
+	CreatedAt   time.Time `json:"created_at"`
+	EventsSince time.Time `json:"events_since"`
+}
+
+// Write dumps the backup to w, including status events from eventsSince on.
+func Write(ctx context.Context, db *database.DB, w io.Writer, eventsSince time.Time) error {
+	gz := gzip.NewWriter(w)
+	enc := json.NewEncoder(gz)
+	if err := enc.Encode(Header{Format: Format, CreatedAt: time.Now().UTC(), EventsSince: eventsSince}); err != nil {
+		return err
+	}
+	err := db.DumpTables(ctx, eventsSince, func(table string, row json.RawMessage) error {
+		return enc.Encode(database.BackupRow{Table: table, Row: row})
+	})
+	if err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Restore loads a backup written by Write. Existing rows are kept, so it is
+// meant for an empty database of the same release. Returns rows inserted per table.
+func Restore(ctx context.Context, db *database.DB, r io.Reader) (map[string]int64, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a backup: %w", err)
+	}
+	defer gz.Close()
+
+	lines := bufio.NewScanner(gz)
+	lines.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	if !lines.Scan() {
+		return nil, fmt.Errorf("empty backup")
+	}
+	var h Header
+	if err := json.Unmarshal(lines.Bytes(), &h); err != nil || h.Format != Format {
+		return nil, fmt.Errorf("not a %s file", Format)
+	}
+	log.Printf("[backup] restoring backup of %s (events since %s)", h.CreatedAt.Format(time.RFC3339), h.EventsSince.Format(time.RFC3339))
+
+	return db.RestoreRows(ctx, func() (*database.BackupRow, error) {
+		if !lines.Scan() {
+			return nil, lines.Err()
+		}
+		var row database.BackupRow
+		if err := json.Unmarshal(lines.Bytes(), &row); err != nil {
+			return nil, fmt.Errorf("bad backup line: %w", err)
+		}
+		return &row, nil
+	})
+}
+
+// Store keeps backups in a bucket under a key prefix.
+type Store struct {
+	Bucket *Bucket
+	Prefix string // e.g. "backups/"
+}
+
+// Save writes a new backup to the bucket and returns its key. The backup is
+// streamed to the bucket as it is dumped, a part at a time.
+func (s *Store) Save(ctx context.Context, db *database.DB, eventsSince time.Time) (string, error) {
+	key := s.Prefix + "nlm-" + time.Now().UTC().Format(keyLayout) + ".jsonl.gz"
+	pr, pw := io.Pipe()
+	written := make(chan error, 1)
+	go func() {
+		err := Write(ctx, db, pw, eventsSince)
+		pw.CloseWithError(err)
+		written <- err
+	}()
+
+	size, err := s.Bucket.Upload(ctx, key, pr)
+	pr.CloseWithError(err) // unblocks Write if the upload gave up early
+	werr := <-written
+	if err != nil {
+		return "", fmt.Errorf("upload: %w", err)
+	}
+	if werr != nil {
+		return "", werr
+	}
+	log.Printf("[backup] saved %s (%d bytes)", key, size)
+	return key, nil
+}
+
+// List returns the stored backups, oldest first.
+func (s *Store) List(ctx context.Context) ([]Object, error) {
+	objects, err := s.Bucket.List(ctx, s.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	out := objects[:0]
+	for _, o := range objects {
+		if strings.HasSuffix(o.Key, ".jsonl.gz") {
+			out = append(out, o)
+		}
+	}
+	return out, nil
+}
+
+// Prune deletes backups older than keep, but never the newest one.
+func (s *Store) Prune(ctx context.Context, keep time.Duration) (int, error) {
+	objects, err := s.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	cutoff := time.Now().Add(-keep)
+	deleted := 0
+	for i, o := range objects {
+		if i == len(objects)-1 || !o.LastModified.Before(cutoff) {
+			continue
+		}
+		if err := s.Bucket.Delete(ctx, o.Key); err != nil {
+			return deleted, fmt.Errorf("delete %s: %w", o.Key, err)
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// Load restores the backup stored as key.
+func (s *Store) Load(ctx context.Context, db *database.DB, key string) (map[string]int64, error) {
+	data, err := s.Bucket.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("download: %w", err)
+	}
+	return Restore(ctx, db, bytes.NewReader(data))
+}
@@ -0,0 +1,338 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bucket is a minimal client for an S3-compatible bucket (AWS, MinIO, R2,
+// Backblaze...), signing requests with AWS Signature Version 4 and addressing
+// objects path-style.
+type Bucket struct {
+	Endpoint  string // e.g. https://s3.eu-central-1.amazonaws.com
+	Region    string
+	Name      string
+	AccessKey string
+	SecretKey string
+
+	client *http.Client
+}
+
+// NewBucket returns a client for the named bucket.
+func NewBucket(endpoint, region, name, accessKey, secretKey string) *Bucket {
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &Bucket{
+		Endpoint:  strings.TrimRight(endpoint, "/"),
+		Region:    region,
+		Name:      name,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		client:    &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+// Object is an entry of a bucket listing.
+type Object struct {
+	Key          string    `xml:"Key"`
+	LastModified time.Time `xml:"LastModified"`
+	Size         int64     `xml:"Size"`
+}
+
+// Put uploads data as key.
+func (b *Bucket) Put(ctx context.Context, key string, data []byte) error {
+	resp, err := b.do(ctx, http.MethodPut, key, nil, data)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// partSize is the part size of multipart uploads. S3 requires at least 5 MiB
+// for every part but the last.
+const partSize = 8 << 20
+
+// Upload streams r to key and returns the number of bytes stored. Data that
+// fits in one part goes up in a single PUT, anything larger as a multipart
+// upload, so only one part is held in memory at a time.
+func (b *Bucket) Upload(ctx context.Context, key string, r io.Reader) (int64, error) {
+	buf := make([]byte, partSize)
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return int64(n), b.Put(ctx, key, buf[:n])
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	uploadID, err := b.createMultipart(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	var (
+		parts []completedPart
+		total int64
+	)
+	for num := 1; ; num++ {
+		etag, err := b.uploadPart(ctx, key, uploadID, num, buf[:n])
+		if err != nil {
+			b.abortMultipart(ctx, key, uploadID)
+			return total, err
+		}
+		parts = append(parts, completedPart{PartNumber: num, ETag: etag})
+		total += int64(n)
+
+		n, err = io.ReadFull(r, buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			b.abortMultipart(ctx, key, uploadID)
+			return total, err
+		}
+	}
+	if err := b.completeMultipart(ctx, key, uploadID, parts); err != nil {
+		b.abortMultipart(ctx, key, uploadID)
+		return total, err
+	}
+	return total, nil
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// createMultipart starts a multipart upload of key and returns its ID.
+func (b *Bucket) createMultipart(ctx context.Context, key string) (string, error) {
+	resp, err := b.do(ctx, http.MethodPost, key, map[string]string{"uploads": ""}, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil || result.UploadID == "" {
+		return "", fmt.Errorf("start multipart upload of %s: no upload ID (%v)", key, err)
+	}
+	return result.UploadID, nil
+}
+
+// uploadPart uploads one part and returns its ETag.
+func (b *Bucket) uploadPart(ctx context.Context, key, uploadID string, num int, data []byte) (string, error) {
+	query := map[string]string{"partNumber": strconv.Itoa(num), "uploadId": uploadID}
+	resp, err := b.do(ctx, http.MethodPut, key, query, data)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return resp.Header.Get("ETag"), nil
+}
+
+// completeMultipart assembles the uploaded parts into key.
+func (b *Bucket) completeMultipart(ctx context.Context, key, uploadID string, parts []completedPart) error {
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return err
+	}
+	resp, err := b.do(ctx, http.MethodPost, key, map[string]string{"uploadId": uploadID}, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// S3 may answer 200 and still report a failure in the body.
+	var result struct {
+		XMLName xml.Name
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("complete multipart upload of %s: %w", key, err)
+	}
+	if result.XMLName.Local == "Error" {
+		return fmt.Errorf("complete multipart upload of %s: %s: %s", key, result.Code, result.Message)
+	}
+	return nil
+}
+
+// abortMultipart discards the parts of a failed upload. It runs even when
+// ctx was cancelled, so the bucket isn't left holding them.
+func (b *Bucket) abortMultipart(ctx context.Context, key, uploadID string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Minute)
+	defer cancel()
+	resp, err := b.do(ctx, http.MethodDelete, key, map[string]string{"uploadId": uploadID}, nil)
+	if err != nil {
+		log.Printf("[backup] abort multipart upload of %s: %v", key, err)
+		return
+	}
+	resp.Body.Close()
+}
+
+// Get downloads key.
+func (b *Bucket) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := b.do(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// Delete removes key.
+func (b *Bucket) Delete(ctx context.Context, key string) error {
+	resp, err := b.do(ctx, http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// List returns the objects whose keys start with prefix, in key order.
+func (b *Bucket) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	token := ""
+	for {
+		query := map[string]string{"list-type": "2", "prefix": prefix}
+		if token != "" {
+			query["continuation-token"] = token
+		}
+		resp, err := b.do(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Contents              []Object `xml:"Contents"`
+			IsTruncated           bool     `xml:"IsTruncated"`
+			NextContinuationToken string   `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decode listing: %w", err)
+		}
+		objects = append(objects, page.Contents...)
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// do sends a signed request and turns non-2xx responses into errors.
+func (b *Bucket) do(ctx context.Context, method, key string, query map[string]string, body []byte) (*http.Response, error) {
+	path := "/" + b.Name
+	if key != "" {
+		path += "/" + key
+	}
+	canonicalURI := uriEncode(path, false)
+
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, uriEncode(k, true)+"="+uriEncode(query[k], true))
+	}
+	canonicalQuery := strings.Join(pairs, "&")
+
+	target := b.Endpoint + canonicalURI
+	if canonicalQuery != "" {
+		target += "?" + canonicalQuery
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	b.sign(req, canonicalURI, canonicalQuery, body, time.Now().UTC())
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, fmt.Errorf("s3 %s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+// sign adds AWS Signature Version 4 headers to req.
+func (b *Bucket) sign(req *http.Request, canonicalURI, canonicalQuery string, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method, canonicalURI, canonicalQuery, canonicalHeaders, signedHeaders, payloadHash,
+	}, "\n")
+
+	scope := day + "/" + b.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+b.SecretKey), day)
+	key = hmacSHA256(key, b.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+b.AccessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// uriEncode percent-encodes s as SigV4 expects: everything but unreserved
+// characters, and "/" too when encodeSlash is set.
+func uriEncode(s string, encodeSlash bool) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			sb.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			sb.WriteByte(c)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
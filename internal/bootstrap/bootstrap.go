@@ -0,0 +1,99 @@
+// Package bootstrap holds the start-up steps shared by every service: loading
+// configuration and connecting to Postgres, Redis and RabbitMQ. Failures are
+// fatal, as a service cannot run without its dependencies.
+package bootstrap
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/joho/godotenv"
+
+	"no-lights-monitor/internal/backup"
+	"no-lights-monitor/internal/cache"
+	"no-lights-monitor/internal/config"
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/errsink"
+	"no-lights-monitor/internal/mq"
+)
+
+// Init loads .env (if present) and the configuration, and starts error
+// reporting tagged with service. Callers defer errsink.Flush.
+func Init(service string) *config.Config {
+	_ = godotenv.Load()
+
+	cfg := config.Load()
+	if err := errsink.Init(cfg.SentryDSN, service); err != nil {
+		log.Printf("errsink: %v", err)
+	}
+	if cfg.Sandbox {
+		log.Println("SANDBOX mode: Telegram, SMS, push and webhook writes are not delivered, MQ messages are tagged")
+	}
+	return cfg
+}
+
+// SignalContext returns a context cancelled on SIGINT or SIGTERM.
+func SignalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// Database connects to Postgres and applies migrations.
+func Database(ctx context.Context, cfg *config.Config) *database.DB {
+	db, err := database.New(ctx, cfg.DatabaseURL, cfg.SlowQueryThreshold())
+	if err != nil {
+		log.Fatalf("database: %v", err)
+	}
+	if err := db.Migrate(ctx); err != nil {
+		db.Close()
+		log.Fatalf("migrate: %v", err)
+	}
+	log.Println("database connected and migrated")
+	return db
+}
+
+// Redis connects to Redis.
+func Redis(cfg *config.Config) *cache.Cache {
+	c, err := cache.New(cfg.RedisURL)
+	if err != nil {
+		log.Fatalf("redis: %v", err)
+	}
+	log.Println("redis connected")
+	return c
+}
+
+// Publisher opens a RabbitMQ publisher.
+func Publisher(cfg *config.Config) *mq.Publisher {
+	pub, err := mq.NewPublisher(cfg.RabbitMQURL)
+	if err != nil {
+		log.Fatalf("rabbitmq publisher: %v", err)
+	}
+	pub.SetSandbox(cfg.Sandbox)
+	return pub
+}
+
+// BackupStore returns the configured backup bucket, or nil if backups are off.
+func BackupStore(cfg *config.Config) *backup.Store {
+	if cfg.BackupS3Bucket == "" {
+		return nil
+	}
+	if cfg.BackupS3Endpoint == "" {
+		log.Fatal("BACKUP_S3_ENDPOINT is required with BACKUP_S3_BUCKET")
+	}
+	return &backup.Store{
+		Bucket: backup.NewBucket(cfg.BackupS3Endpoint, cfg.BackupS3Region, cfg.BackupS3Bucket, cfg.BackupS3AccessKey, cfg.BackupS3SecretKey),
+		Prefix: cfg.BackupPrefix,
+	}
+}
+
+// Consumer opens a RabbitMQ consumer.
+func Consumer(cfg *config.Config) *mq.Consumer {
+	c, err := mq.NewConsumer(cfg.RabbitMQURL)
+	if err != nil {
+		log.Fatalf("rabbitmq consumer: %v", err)
+	}
+	c.SetSandbox(cfg.Sandbox)
+	return c
+}
@@ -2,8 +2,13 @@ package cache
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -12,6 +17,54 @@ import (
 const (
 	heartbeatPrefix = "hb:"
 	devModeKey      = "app:dev_mode"
+
+	settingsFailPrefix = "settings_fail:"
+	settingsLockPrefix = "settings_lock:"
+
+	webSessionPrefix = "web_sess:"
+
+	incidentPostPrefix = "incident_post:"
+
+	pingIntervalPrefix = "ping_iv:"
+	// pingIntervalSamples is how many recent ping intervals are kept per monitor.
+	pingIntervalSamples = 30
+	// maxPingInterval drops gaps longer than this: they are outages, not the device's cadence.
+	maxPingInterval = time.Hour
+
+	// monitorsChangedChannel is the pub/sub channel announcing that the public
+	// monitor list changed (a status transition, a ban). Payload: monitor ID or "".
+	monitorsChangedChannel = "events:monitors_changed"
+
+	// Rendered API responses shared by all API replicas. Keys embed the current
+	// version; bumping it orphans every cached response at once.
+	responsePrefix     = "resp:"
+	responseVersionKey = "resp:version"
+
+	heartbeatGapPrefix = "hb_gaps:"
+
+	// Last heartbeat of each extra device of a monitor: a hash of device ID →
+	// unix seconds, read together with the monitor's own heartbeat.
+	deviceHeartbeatPrefix = "hb_dev:"
+
+	// Geocoding results keyed by the normalized query.
+	geocodePrefix = "geocode:"
+	// Next free Nominatim request slot (unix ms), shared by every service so
+	// together they keep to Nominatim's one request per second.
+	geocodeSlotKey = "geocode_slot"
+
+	// Last successful run of each scheduled job (unix seconds), for the watchdog.
+	jobHeartbeatPrefix = "job_hb:"
+
+	// Set by the worker while an outage wave (surge mode) is in progress.
+	surgeKey = "surge:active"
+
+	// Recent status transitions of each monitor (sorted set scored by unix
+	// time) and the monitors currently flapping (hash of ID → unix since).
+	flapPrefix  = "flap:"
+	flappingKey = "flapping"
+
+	// heartbeatGapRetention is how long heartbeat gaps are kept for backfilling.
+	heartbeatGapRetention = 30 * 24 * time.Hour
 )
 
 type Cache struct {
@@ -54,6 +107,104 @@ func (c *Cache) SetHeartbeat(ctx context.Context, monitorID int64, t time.Time)
 	return c.Client.Set(ctx, key, t.Unix(), 0).Err()
 }
 
+// SwapHeartbeat records a heartbeat like SetHeartbeat and returns the previous
+// one (zero if there was none).
+func (c *Cache) SwapHeartbeat(ctx context.Context, monitorID int64, t time.Time) (time.Time, error) {
+	key := fmt.Sprintf("%s%d", heartbeatPrefix, monitorID)
+	val, err := c.Client.SetArgs(ctx, key, t.Unix(), redis.SetArgs{Get: true}).Result()
+	if err == redis.Nil {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	unix, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return time.Time{}, nil
+	}
+	return time.Unix(unix, 0), nil
+}
+
+// RecordPingInterval stores the gap between two consecutive heartbeats.
+// Gaps over an hour are outages rather than the device's cadence and are skipped.
+func (c *Cache) RecordPingInterval(ctx context.Context, monitorID int64, d time.Duration) error {
+	if d <= 0 || d > maxPingInterval {
+		return nil
+	}
+	key := fmt.Sprintf("%s%d", pingIntervalPrefix, monitorID)
+	pipe := c.Client.Pipeline()
+	pipe.LPush(ctx, key, int64(d.Seconds()))
+	pipe.LTrim(ctx, key, 0, pingIntervalSamples-1)
+	pipe.Expire(ctx, key, 48*time.Hour)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// HeartbeatGap is a period between two consecutive heartbeats of a monitor.
+type HeartbeatGap struct {
+	From time.Time // last heartbeat before the gap
+	To   time.Time // first heartbeat after it
+}
+
+// RecordHeartbeatGap logs a gap between heartbeats that exceeded the monitor's
+// offline threshold, so outages missed by the checker can be backfilled later.
+func (c *Cache) RecordHeartbeatGap(ctx context.Context, monitorID int64, from, to time.Time) error {
+	key := fmt.Sprintf("%s%d", heartbeatGapPrefix, monitorID)
+	pipe := c.Client.Pipeline()
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(to.Unix()), Member: fmt.Sprintf("%d:%d", from.Unix(), to.Unix())})
+	pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(to.Add(-heartbeatGapRetention).Unix(), 10))
+	pipe.Expire(ctx, key, heartbeatGapRetention)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// HeartbeatGaps returns the recorded gaps of a monitor that ended after since, oldest first.
+func (c *Cache) HeartbeatGaps(ctx context.Context, monitorID int64, since time.Time) ([]HeartbeatGap, error) {
+	key := fmt.Sprintf("%s%d", heartbeatGapPrefix, monitorID)
+	vals, err := c.Client.ZRangeByScore(ctx, key, &redis.ZRangeBy{
+		Min: strconv.FormatInt(since.Unix(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	gaps := make([]HeartbeatGap, 0, len(vals))
+	for _, v := range vals {
+		fromStr, toStr, ok := strings.Cut(v, ":")
+		if !ok {
+			continue
+		}
+		from, err1 := strconv.ParseInt(fromStr, 10, 64)
+		to, err2 := strconv.ParseInt(toStr, 10, 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		gaps = append(gaps, HeartbeatGap{From: time.Unix(from, 0), To: time.Unix(to, 0)})
+	}
+	return gaps, nil
+}
+
+// ObservedPingInterval returns the median of the recent ping intervals of a
+// monitor and how many samples it is based on.
+func (c *Cache) ObservedPingInterval(ctx context.Context, monitorID int64) (time.Duration, int, error) {
+	key := fmt.Sprintf("%s%d", pingIntervalPrefix, monitorID)
+	vals, err := c.Client.LRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		return 0, 0, err
+	}
+	secs := make([]int64, 0, len(vals))
+	for _, v := range vals {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			secs = append(secs, n)
+		}
+	}
+	if len(secs) == 0 {
+		return 0, 0, nil
+	}
+	slices.Sort(secs)
+	return time.Duration(secs[len(secs)/2]) * time.Second, len(secs), nil
+}
+
 // GetHeartbeat returns the last heartbeat time for a monitor.
 func (c *Cache) GetHeartbeat(ctx context.Context, monitorID int64) (time.Time, error) {
 	key := fmt.Sprintf("%s%d", heartbeatPrefix, monitorID)
@@ -68,6 +219,48 @@ func (c *Cache) GetHeartbeat(ctx context.Context, monitorID int64) (time.Time, e
 	return time.Unix(unix, 0), nil
 }
 
+// SetDeviceHeartbeat records the last heartbeat of one extra device of a monitor.
+func (c *Cache) SetDeviceHeartbeat(ctx context.Context, monitorID, deviceID int64, t time.Time) error {
+	key := fmt.Sprintf("%s%d", deviceHeartbeatPrefix, monitorID)
+	return c.Client.HSet(ctx, key, strconv.FormatInt(deviceID, 10), t.Unix()).Err()
+}
+
+// DeleteDeviceHeartbeat forgets a removed device so it no longer keeps the
+// monitor online.
+func (c *Cache) DeleteDeviceHeartbeat(ctx context.Context, monitorID, deviceID int64) error {
+	key := fmt.Sprintf("%s%d", deviceHeartbeatPrefix, monitorID)
+	return c.Client.HDel(ctx, key, strconv.FormatInt(deviceID, 10)).Err()
+}
+
+// GetLatestHeartbeat returns the newest heartbeat of a monitor across its own
+// token and all its extra devices, in one round trip. Like GetHeartbeat it
+// returns redis.Nil when nothing has pinged yet.
+func (c *Cache) GetLatestHeartbeat(ctx context.Context, monitorID int64) (time.Time, error) {
+	pipe := c.Client.Pipeline()
+	own := pipe.Get(ctx, fmt.Sprintf("%s%d", heartbeatPrefix, monitorID))
+	devices := pipe.HVals(ctx, fmt.Sprintf("%s%d", deviceHeartbeatPrefix, monitorID))
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return time.Time{}, err
+	}
+
+	var latest int64
+	found := false
+	if val, err := own.Result(); err == nil {
+		if unix, err := strconv.ParseInt(val, 10, 64); err == nil {
+			latest, found = unix, true
+		}
+	}
+	for _, val := range devices.Val() {
+		if unix, err := strconv.ParseInt(val, 10, 64); err == nil && (!found || unix > latest) {
+			latest, found = unix, true
+		}
+	}
+	if !found {
+		return time.Time{}, redis.Nil
+	}
+	return time.Unix(latest, 0), nil
+}
+
 // GetAllHeartbeats returns heartbeat timestamps for all monitors.
 func (c *Cache) GetAllHeartbeats(ctx context.Context) (map[int64]time.Time, error) {
 	pattern := heartbeatPrefix + "*"
@@ -93,3 +286,262 @@ func (c *Cache) GetAllHeartbeats(ctx context.Context) (map[int64]time.Time, erro
 	}
 	return result, iter.Err()
 }
+
+// RecordSettingsFailure counts a failed settings access from ip within window
+// and returns the number of failures seen so far.
+func (c *Cache) RecordSettingsFailure(ctx context.Context, ip string, window time.Duration) (int64, error) {
+	key := settingsFailPrefix + ip
+	n, err := c.Client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if n == 1 {
+		c.Client.Expire(ctx, key, window)
+	}
+	return n, nil
+}
+
+// LockSettings blocks settings access from ip for d and resets its failure counter.
+func (c *Cache) LockSettings(ctx context.Context, ip string, d time.Duration) error {
+	pipe := c.Client.TxPipeline()
+	pipe.Set(ctx, settingsLockPrefix+ip, "1", d)
+	pipe.Del(ctx, settingsFailPrefix+ip)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// IsSettingsLocked returns true if ip is currently locked out of the settings API.
+func (c *Cache) IsSettingsLocked(ctx context.Context, ip string) bool {
+	n, err := c.Client.Exists(ctx, settingsLockPrefix+ip).Result()
+	return err == nil && n > 0
+}
+
+// TakeIncidentPost reserves the next aggregate channel post for region and
+// reports false if one was already made within every.
+func (c *Cache) TakeIncidentPost(ctx context.Context, region string, every time.Duration) (bool, error) {
+	return c.Client.SetNX(ctx, incidentPostPrefix+region, "1", every).Result()
+}
+
+// WebSession is a Telegram user signed in on the website.
+type WebSession struct {
+	TelegramID int64  `json:"telegram_id"`
+	Username   string `json:"username"`
+	FirstName  string `json:"first_name"`
+}
+
+// CreateWebSession stores s under a new random token that expires after ttl.
+func (c *Cache) CreateWebSession(ctx context.Context, s WebSession, ttl time.Duration) (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+	data, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	if err := c.Client.Set(ctx, webSessionPrefix+token, data, ttl).Err(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// GetWebSession returns the session for token, or nil if it is unknown or expired.
+func (c *Cache) GetWebSession(ctx context.Context, token string) (*WebSession, error) {
+	data, err := c.Client.Get(ctx, webSessionPrefix+token).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s WebSession
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// SetJobHeartbeat records that the named scheduled job completed a run at at.
+func (c *Cache) SetJobHeartbeat(ctx context.Context, name string, at time.Time) error {
+	return c.Client.Set(ctx, jobHeartbeatPrefix+name, at.Unix(), 0).Err()
+}
+
+// JobHeartbeat returns the last recorded run of the named job, or the zero time.
+func (c *Cache) JobHeartbeat(ctx context.Context, name string) (time.Time, error) {
+	sec, err := c.Client.Get(ctx, jobHeartbeatPrefix+name).Int64()
+	if err == redis.Nil {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(sec, 0), nil
+}
+
+// SetSurge flags surge mode for ttl; the worker refreshes it while the wave lasts.
+func (c *Cache) SetSurge(ctx context.Context, ttl time.Duration) error {
+	return c.Client.Set(ctx, surgeKey, "1", ttl).Err()
+}
+
+// ClearSurge ends surge mode.
+func (c *Cache) ClearSurge(ctx context.Context) error {
+	return c.Client.Del(ctx, surgeKey).Err()
+}
+
+// SurgeActive reports whether surge mode is flagged.
+func (c *Cache) SurgeActive(ctx context.Context) (bool, error) {
+	n, err := c.Client.Exists(ctx, surgeKey).Result()
+	return n > 0, err
+}
+
+// RecordTransition logs a status transition of a monitor at at and returns
+// how many transitions it had within window up to then.
+func (c *Cache) RecordTransition(ctx context.Context, monitorID int64, at time.Time, window time.Duration) (int, error) {
+	key := fmt.Sprintf("%s%d", flapPrefix, monitorID)
+	pipe := c.Client.TxPipeline()
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(at.UnixMilli()), Member: at.UnixNano()})
+	pipe.ZRemRangeByScore(ctx, key, "-inf", "("+strconv.FormatInt(at.Add(-window).UnixMilli(), 10))
+	count := pipe.ZCard(ctx, key)
+	pipe.Expire(ctx, key, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return int(count.Val()), nil
+}
+
+// SetFlapping marks a monitor as flapping since since.
+func (c *Cache) SetFlapping(ctx context.Context, monitorID int64, since time.Time) error {
+	return c.Client.HSet(ctx, flappingKey, strconv.FormatInt(monitorID, 10), since.Unix()).Err()
+}
+
+// ClearFlapping ends a monitor's flapping state.
+func (c *Cache) ClearFlapping(ctx context.Context, monitorID int64) error {
+	return c.Client.HDel(ctx, flappingKey, strconv.FormatInt(monitorID, 10)).Err()
+}
+
+// GetFlapping returns the monitors currently flapping and since when.
+func (c *Cache) GetFlapping(ctx context.Context) (map[int64]time.Time, error) {
+	vals, err := c.Client.HGetAll(ctx, flappingKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[int64]time.Time, len(vals))
+	for k, v := range vals {
+		id, err := strconv.ParseInt(k, 10, 64)
+		if err != nil {
+			continue
+		}
+		unix, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			continue
+		}
+		out[id] = time.Unix(unix, 0)
+	}
+	return out, nil
+}
+
+// GetGeocode returns the cached geocoding result of a normalized query, or nil.
+func (c *Cache) GetGeocode(ctx context.Context, query string) ([]byte, error) {
+	data, err := c.Client.Get(ctx, geocodePrefix+query).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return data, err
+}
+
+// SetGeocode caches the geocoding result of a normalized query for ttl.
+func (c *Cache) SetGeocode(ctx context.Context, query string, data []byte, ttl time.Duration) error {
+	return c.Client.Set(ctx, geocodePrefix+query, data, ttl).Err()
+}
+
+// geocodeSlotScript books the next free slot: max(stored slot, now) unless it
+// is more than ARGV[2] ms away (-1), then moves the stored slot ARGV[1] ms on.
+// Redis' clock is used so every service agrees on "now".
+var geocodeSlotScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local slot = tonumber(redis.call('GET', KEYS[1]) or '0')
+if slot < now then slot = now end
+if slot - now > tonumber(ARGV[2]) then return -1 end
+local next = slot + tonumber(ARGV[1])
+redis.call('SET', KEYS[1], next, 'PX', next - now + 1000)
+return slot - now
+`)
+
+// ReserveGeocodeSlot books the next Nominatim request slot, interval after the
+// previous one of any service, and returns how long to wait for it. ok is
+// false, and nothing is booked, when the slot is more than maxWait away.
+func (c *Cache) ReserveGeocodeSlot(ctx context.Context, interval, maxWait time.Duration) (wait time.Duration, ok bool, err error) {
+	ms, err := geocodeSlotScript.Run(ctx, c.Client, []string{geocodeSlotKey}, interval.Milliseconds(), maxWait.Milliseconds()).Int64()
+	if err != nil {
+		return 0, false, err
+	}
+	if ms < 0 {
+		return 0, false, nil
+	}
+	return time.Duration(ms) * time.Millisecond, true, nil
+}
+
+// PublishMonitorsChanged tells every API replica that the public monitor list
+// changed: it bumps the shared response version and announces the change so
+// replicas also drop their in-process copies. monitorID is informational; 0
+// means "many monitors".
+func (c *Cache) PublishMonitorsChanged(ctx context.Context, monitorID int64) error {
+	payload := ""
+	if monitorID != 0 {
+		payload = strconv.FormatInt(monitorID, 10)
+	}
+	pipe := c.Client.Pipeline()
+	pipe.Incr(ctx, responseVersionKey)
+	pipe.Publish(ctx, monitorsChangedChannel, payload)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// ResponseVersion returns the current version of the shared response cache.
+// Read it before loading data and store the response under it, so a change
+// announced in between is never overwritten with stale data.
+func (c *Cache) ResponseVersion(ctx context.Context) (int64, error) {
+	v, err := c.Client.Get(ctx, responseVersionKey).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return v, err
+}
+
+// GetResponse returns the cached rendering of the named response at version,
+// or nil if there is none.
+func (c *Cache) GetResponse(ctx context.Context, name string, version int64) ([]byte, error) {
+	data, err := c.Client.Get(ctx, fmt.Sprintf("%s%s:%d", responsePrefix, name, version)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return data, err
+}
+
+// SetResponse caches the rendering of the named response at version for ttl.
+func (c *Cache) SetResponse(ctx context.Context, name string, version int64, data []byte, ttl time.Duration) error {
+	return c.Client.Set(ctx, fmt.Sprintf("%s%s:%d", responsePrefix, name, version), data, ttl).Err()
+}
+
+// SubscribeMonitorsChanged calls fn for every PublishMonitorsChanged until ctx
+// is done. go-redis re-subscribes by itself after a connection loss; fn is
+// also called then, since announcements may have been missed.
+func (c *Cache) SubscribeMonitorsChanged(ctx context.Context, fn func()) {
+	sub := c.Client.Subscribe(ctx, monitorsChangedChannel)
+	defer sub.Close()
+	ch := sub.ChannelWithSubscriptions()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-ch:
+			// A *redis.Subscription arrives on every (re)subscribe, a *redis.Message per announcement.
+			if !ok {
+				return
+			}
+			fn()
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Monitors with the heartbeat firehose enabled get their ping arrivals
+// appended to the Redis stream "events:heartbeats" (fields monitor_id and
+// at, unix milliseconds), at most one per monitor per rate-limit window. The
+// worker relays the stream to the owners' webhooks through a consumer group;
+// self-hosters can read it directly for their own automation.
+
+const (
+	heartbeatEventsStream = "events:heartbeats"
+	heartbeatEventPrefix  = "hb_evt:"
+	// heartbeatEventsMaxLen caps the stream; XADD trims it approximately.
+	heartbeatEventsMaxLen = 10000
+)
+
+// HeartbeatEvent is one ping arrival read from the stream.
+type HeartbeatEvent struct {
+	StreamID  string
+	MonitorID int64
+	At        time.Time
+}
+
+// TakeHeartbeatEvent reserves the monitor's next heartbeat event and reports
+// false if one was already emitted within every.
+func (c *Cache) TakeHeartbeatEvent(ctx context.Context, monitorID int64, every time.Duration) (bool, error) {
+	return c.Client.SetNX(ctx, fmt.Sprintf("%s%d", heartbeatEventPrefix, monitorID), "1", every).Result()
+}
+
+// AddHeartbeatEvent appends a ping arrival to the heartbeat stream.
+func (c *Cache) AddHeartbeatEvent(ctx context.Context, monitorID int64, at time.Time) error {
+	return c.Client.XAdd(ctx, &redis.XAddArgs{
+		Stream: heartbeatEventsStream,
+		MaxLen: heartbeatEventsMaxLen,
+		Approx: true,
+		Values: map[string]any{"monitor_id": monitorID, "at": at.UnixMilli()},
+	}).Err()
+}
+
+// EnsureHeartbeatGroup creates the consumer group if it doesn't exist yet.
+// A new group starts at the end of the stream: older events are not relayed.
+func (c *Cache) EnsureHeartbeatGroup(ctx context.Context, group string) error {
+	err := c.Client.XGroupCreateMkStream(ctx, heartbeatEventsStream, group, "$").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	return nil
+}
+
+// ReadHeartbeatEvents waits up to block for new events for consumer of group
+// and returns at most count of them. It returns nil on timeout.
+func (c *Cache) ReadHeartbeatEvents(ctx context.Context, group, consumer string, count int64, block time.Duration) ([]HeartbeatEvent, error) {
+	streams, err := c.Client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    group,
+		Consumer: consumer,
+		Streams:  []string{heartbeatEventsStream, ">"},
+		Count:    count,
+		Block:    block,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var events []HeartbeatEvent
+	for _, s := range streams {
+		for _, msg := range s.Messages {
+			ev := HeartbeatEvent{StreamID: msg.ID}
+			if v, ok := msg.Values["monitor_id"].(string); ok {
+				ev.MonitorID, _ = strconv.ParseInt(v, 10, 64)
+			}
+			if v, ok := msg.Values["at"].(string); ok {
+				ms, _ := strconv.ParseInt(v, 10, 64)
+				ev.At = time.UnixMilli(ms)
+			}
+			events = append(events, ev)
+		}
+	}
+	return events, nil
+}
+
+// AckHeartbeatEvents marks events as handled by group.
+func (c *Cache) AckHeartbeatEvents(ctx context.Context, group string, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return c.Client.XAck(ctx, heartbeatEventsStream, group, ids...).Err()
+}
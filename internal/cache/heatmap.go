@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// The public heat layer is stored as one hash "heatmap" of tile key
+// ("z/x/y") -> rendered tile JSON. The worker writes each build to a staging
+// key and renames it over the live one, so readers never see a half-written set.
+
+const (
+	heatmapKey        = "heatmap"
+	heatmapStagingKey = "heatmap:staging"
+)
+
+// StoreHeatmap replaces the stored heat layer with tiles. The set expires
+// after ttl, so a stopped worker doesn't leave a stale layer behind.
+func (c *Cache) StoreHeatmap(ctx context.Context, tiles map[string][]byte, ttl time.Duration) error {
+	if len(tiles) == 0 {
+		return c.Client.Del(ctx, heatmapKey).Err()
+	}
+	values := make(map[string]any, len(tiles))
+	for k, v := range tiles {
+		values[k] = v
+	}
+	pipe := c.Client.TxPipeline()
+	pipe.Del(ctx, heatmapStagingKey)
+	pipe.HSet(ctx, heatmapStagingKey, values)
+	pipe.Expire(ctx, heatmapStagingKey, ttl)
+	pipe.Rename(ctx, heatmapStagingKey, heatmapKey)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// GetHeatmapTile returns the stored JSON of one tile, or nil if the tile is empty.
+func (c *Cache) GetHeatmapTile(ctx context.Context, key string) ([]byte, error) {
+	data, err := c.Client.HGet(ctx, heatmapKey, key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return data, err
+}
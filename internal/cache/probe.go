@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ping monitors can be probed from several vantage points (the worker itself
+// plus remote probe agents). Each vantage's latest verdict is kept in a hash
+// "probe:<monitorID>" as vantage -> "<unix>:<1|0>" and combined by quorum.
+// Vantage names are namespaced by kind (see WorkerVantage and AgentVantage),
+// so an agent configured under the worker's name can't overwrite its vote.
+
+const (
+	probePrefix = "probe:"
+	// probeTTL drops the votes of monitors that are no longer probed.
+	probeTTL = time.Hour
+)
+
+// WorkerVantage is the vote key of a worker probing under PROBE_VANTAGE name.
+func WorkerVantage(name string) string {
+	return "worker:" + name
+}
+
+// AgentVantage is the vote key of the remote probe agent called name.
+func AgentVantage(name string) string {
+	return "agent:" + name
+}
+
+// RecordProbe stores vantage's latest verdict on whether a ping monitor's
+// target answered. vantage is a WorkerVantage or AgentVantage key.
+func (c *Cache) RecordProbe(ctx context.Context, monitorID int64, vantage string, ok bool, at time.Time) error {
+	key := fmt.Sprintf("%s%d", probePrefix, monitorID)
+	v := "0"
+	if ok {
+		v = "1"
+	}
+	pipe := c.Client.Pipeline()
+	pipe.HSet(ctx, key, vantage, strconv.FormatInt(at.Unix(), 10)+":"+v)
+	pipe.Expire(ctx, key, probeTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// ProbeVotes counts the verdicts on a ping monitor recorded since the given
+// time: how many vantages reached the target and how many voted in total.
+func (c *Cache) ProbeVotes(ctx context.Context, monitorID int64, since time.Time) (reachable, total int, err error) {
+	key := fmt.Sprintf("%s%d", probePrefix, monitorID)
+	vals, err := c.Client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, v := range vals {
+		tsStr, okStr, found := strings.Cut(v, ":")
+		if !found {
+			continue
+		}
+		ts, err := strconv.ParseInt(tsStr, 10, 64)
+		if err != nil || time.Unix(ts, 0).Before(since) {
+			continue
+		}
+		total++
+		if okStr == "1" {
+			reachable++
+		}
+	}
+	return reachable, total, nil
+}
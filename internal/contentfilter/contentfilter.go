@@ -0,0 +1,165 @@
+// Package contentfilter checks user-supplied monitor names and addresses
+// before they reach channels and the public map: length and emoji limits plus
+// an admin-managed list of banned words.
+package contentfilter
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"no-lights-monitor/internal/database"
+)
+
+const (
+	// MaxNameRunes is the longest monitor name, in characters.
+	MaxNameRunes = 64
+	// MaxAddressRunes is the longest monitor address, in characters.
+	MaxAddressRunes = 200
+	// MaxEmoji is how many emoji a name or address may contain.
+	MaxEmoji = 3
+
+	// refreshEvery is how long a loaded banned-word list is used before reloading.
+	refreshEvery = time.Minute
+)
+
+var (
+	// ErrTooLong is returned when the text exceeds the field's length limit.
+	ErrTooLong = errors.New("contentfilter: text is too long")
+	// ErrTooManyEmoji is returned when the text has more than MaxEmoji emoji.
+	ErrTooManyEmoji = errors.New("contentfilter: too many emoji")
+	// ErrBanned is returned when the text contains a banned word.
+	ErrBanned = errors.New("contentfilter: banned word")
+)
+
+// Field selects the limits a text is checked against.
+type Field int
+
+const (
+	Name Field = iota
+	Address
+)
+
+// Filter checks texts against the limits and the banned words stored in the
+// database. The word list is cached and reloaded once a minute, so admin
+// changes apply to every service without a restart.
+type Filter struct {
+	db *database.DB
+
+	mu       sync.Mutex
+	words    []string // normalized; replaced on reload, never modified
+	loadedAt time.Time
+	loading  bool // a reload is in flight
+}
+
+func New(db *database.DB) *Filter {
+	return &Filter{db: db}
+}
+
+// Check returns nil if text is acceptable for field, or one of ErrTooLong,
+// ErrTooManyEmoji and ErrBanned. If the word list cannot be loaded, the last
+// known list is used.
+func (f *Filter) Check(ctx context.Context, field Field, text string) error {
+	limit := MaxNameRunes
+	if field == Address {
+		limit = MaxAddressRunes
+	}
+	if len([]rune(text)) > limit {
+		return ErrTooLong
+	}
+	if CountEmoji(text) > MaxEmoji {
+		return ErrTooManyEmoji
+	}
+	norm := Normalize(text)
+	for _, w := range f.bannedWords(ctx) {
+		if strings.Contains(norm, w) {
+			return ErrBanned
+		}
+	}
+	return nil
+}
+
+// Invalidate drops the cached word list so the next Check reloads it.
+func (f *Filter) Invalidate() {
+	f.mu.Lock()
+	f.loadedAt = time.Time{}
+	f.mu.Unlock()
+}
+
+// bannedWords returns the cached word list, reloading it when it is due. The
+// database is queried without holding the lock; meanwhile other callers keep
+// using the previous list.
+func (f *Filter) bannedWords(ctx context.Context) []string {
+	f.mu.Lock()
+	current := f.words
+	if f.loading || time.Since(f.loadedAt) < refreshEvery {
+		f.mu.Unlock()
+		return current
+	}
+	f.loading = true
+	f.mu.Unlock()
+
+	loaded, err := f.db.GetBannedWords(ctx)
+	var words []string
+	for _, w := range loaded {
+		if n := Normalize(w); n != "" {
+			words = append(words, n)
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.loading = false
+	if err != nil {
+		log.Printf("[contentfilter] load banned words: %v", err)
+		return current
+	}
+	f.words = words
+	f.loadedAt = time.Now()
+	return words
+}
+
+// homoglyphs maps Latin letters that look like Cyrillic ones, so "xyй"
+// typed with Latin letters still matches a Cyrillic banned word.
+var homoglyphs = map[rune]rune{
+	'a': 'а', 'b': 'в', 'c': 'с', 'e': 'е', 'h': 'н', 'i': 'і', 'k': 'к',
+	'm': 'м', 'o': 'о', 'p': 'р', 't': 'т', 'x': 'х', 'y': 'у',
+	'ё': 'е', 'ї': 'і', 'ы': 'и', '0': 'о', '3': 'з', '@': 'а',
+}
+
+// Normalize lowercases text, folds look-alike characters into Cyrillic and
+// drops everything that is not a letter, so separators like "х.у.й" or
+// "х у й" do not hide a word. Banned words are matched as substrings of the
+// result, which also catches inflected forms of a stem.
+func Normalize(text string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(text) {
+		if h, ok := homoglyphs[r]; ok {
+			r = h
+		}
+		if unicode.IsLetter(r) {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// CountEmoji counts pictographic symbols in text. Modifiers, joiners and
+// variation selectors are not counted, so one composed emoji counts once.
+func CountEmoji(text string) int {
+	n, flagHalves := 0, 0
+	for _, r := range text {
+		switch {
+		case r >= 0x1F1E6 && r <= 0x1F1FF: // regional indicators: a flag is a pair
+			flagHalves++
+		case r >= 0x1F3FB && r <= 0x1F3FF: // skin tone modifiers
+		case unicode.Is(unicode.So, r):
+			n++
+		}
+	}
+	return n + (flagHalves+1)/2
+}
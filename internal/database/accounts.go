@@ -0,0 +1,189 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"no-lights-monitor/internal/models"
+)
+
+// ── Linked accounts ──────────────────────────────────────────────────
+
+// ErrLinkNotAllowed is returned by LinkAccount when the accounts can't be
+// linked: the same account, a secondary account as primary, or a secondary
+// that already has linked accounts of its own.
+var ErrLinkNotAllowed = errors.New("accounts can't be linked")
+
+// CreateLinkCode stores a one-time code a secondary account hands to its
+// primary account. Earlier codes of the same account are dropped.
+func (db *DB) CreateLinkCode(ctx context.Context, code string, telegramID int64, username, firstName string, ttl time.Duration) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM link_codes WHERE telegram_id = $1 OR expires_at < NOW()`, telegramID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO link_codes (code, telegram_id, username, first_name, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, code, telegramID, username, firstName, time.Now().Add(ttl)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// GetLinkCode returns an unexpired link code, or nil if there is none.
+func (db *DB) GetLinkCode(ctx context.Context, code string) (*models.LinkCode, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+linkCodeColumns+` FROM link_codes WHERE code = $1 AND expires_at > NOW()
+	`, code)
+	if err != nil {
+		return nil, err
+	}
+	lc, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.LinkCode])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return lc, err
+}
+
+// DeleteLinkCode removes a link code once it has been approved or declined.
+func (db *DB) DeleteLinkCode(ctx context.Context, code string) error {
+	_, err := db.Pool.Exec(ctx, `DELETE FROM link_codes WHERE code = $1`, code)
+	return err
+}
+
+// LinkAccount gives the secondary Telegram account access to the primary
+// user's monitors. Monitors the secondary account created itself move to the
+// primary user, so both accounts see the same list; UnlinkAccount moves them back.
+func (db *DB) LinkAccount(ctx context.Context, primaryTelegramID, secondaryTelegramID int64, username, firstName string) error {
+	if primaryTelegramID == secondaryTelegramID {
+		return ErrLinkNotAllowed
+	}
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var primaryID int64
+	if err := tx.QueryRow(ctx, `SELECT id FROM users WHERE telegram_id = $1`, primaryTelegramID).Scan(&primaryID); err != nil {
+		return err
+	}
+	var blocked bool
+	if err := tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM account_links WHERE telegram_id = $1)
+		    OR EXISTS (SELECT 1 FROM account_links l JOIN users u ON u.id = l.user_id WHERE u.telegram_id = $2)
+	`, primaryTelegramID, secondaryTelegramID).Scan(&blocked); err != nil {
+		return err
+	}
+	if blocked {
+		return ErrLinkNotAllowed
+	}
+
+	var secondaryID int64
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO users (telegram_id, username, first_name)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (telegram_id) DO UPDATE SET username = $2, first_name = $3
+		RETURNING id
+	`, secondaryTelegramID, username, firstName).Scan(&secondaryID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE monitors SET user_id = $1, linked_from_user_id = $2 WHERE user_id = $2
+	`, primaryID, secondaryID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO account_links (telegram_id, user_id) VALUES ($1, $2)
+		ON CONFLICT (telegram_id) DO UPDATE SET user_id = $2, created_at = NOW()
+	`, secondaryTelegramID, primaryID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// UnlinkAccount removes the link of a secondary account. When primaryTelegramID
+// is non-zero, only a link to that primary account is removed. It reports
+// false if there was no such link. Monitors LinkAccount moved from the
+// secondary account go back to it; monitors created while linked stay with
+// the primary user.
+func (db *DB) UnlinkAccount(ctx context.Context, primaryTelegramID, secondaryTelegramID int64) (bool, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var primaryID int64
+	err = tx.QueryRow(ctx, `
+		DELETE FROM account_links
+		WHERE telegram_id = $2
+		  AND ($1 = 0 OR user_id = (SELECT id FROM users WHERE telegram_id = $1))
+		RETURNING user_id
+	`, primaryTelegramID, secondaryTelegramID).Scan(&primaryID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE monitors SET user_id = linked_from_user_id, linked_from_user_id = NULL
+		WHERE user_id = $1 AND linked_from_user_id = (SELECT id FROM users WHERE telegram_id = $2)
+	`, primaryID, secondaryTelegramID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// GetPrimaryAccount returns the primary user a Telegram account is linked to,
+// or nil if it is not a secondary account.
+func (db *DB) GetPrimaryAccount(ctx context.Context, telegramID int64) (*models.User, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+userColumnsAliased+` FROM users u
+		JOIN account_links l ON l.user_id = u.id
+		WHERE l.telegram_id = $1
+	`, telegramID)
+	if err != nil {
+		return nil, err
+	}
+	u, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.User])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return u, err
+}
+
+// GetLinkedAccounts returns the secondary accounts linked to a primary Telegram account.
+func (db *DB) GetLinkedAccounts(ctx context.Context, primaryTelegramID int64) ([]*models.User, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+userColumnsAliased+` FROM account_links l
+		JOIN users p ON p.id = l.user_id
+		JOIN users u ON u.telegram_id = l.telegram_id
+		WHERE p.telegram_id = $1
+		ORDER BY l.created_at
+	`, primaryTelegramID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[models.User])
+}
+
+// OwnerUserID returns the user ID new monitors of user belong to: the primary
+// user for linked accounts, user.ID otherwise.
+func (db *DB) OwnerUserID(ctx context.Context, user *models.User) (int64, error) {
+	var primaryID int64
+	err := db.Pool.QueryRow(ctx, `SELECT user_id FROM account_links WHERE telegram_id = $1`, user.TelegramID).Scan(&primaryID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return user.ID, nil
+	}
+	return primaryID, err
+}
@@ -0,0 +1,58 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"no-lights-monitor/internal/models"
+)
+
+// ── Announcements ────────────────────────────────────────────────────
+
+const announcementColumns = `id, message, link_url, level, pages, starts_at, ends_at, created_at`
+
+// GetActiveAnnouncements returns the announcements shown at the given time, newest first.
+func (db *DB) GetActiveAnnouncements(ctx context.Context, now time.Time) ([]models.Announcement, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+announcementColumns+` FROM announcements
+		WHERE starts_at <= $1 AND (ends_at IS NULL OR ends_at > $1)
+		ORDER BY starts_at DESC, id DESC
+	`, now)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[models.Announcement])
+}
+
+// GetAnnouncements returns every announcement, including scheduled and expired ones.
+func (db *DB) GetAnnouncements(ctx context.Context) ([]models.Announcement, error) {
+	rows, err := db.Pool.Query(ctx, `SELECT `+announcementColumns+` FROM announcements ORDER BY id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[models.Announcement])
+}
+
+// CreateAnnouncement stores a new announcement and returns it.
+func (db *DB) CreateAnnouncement(ctx context.Context, a models.Announcement) (models.Announcement, error) {
+	rows, err := db.Pool.Query(ctx, `
+		INSERT INTO announcements (message, link_url, level, pages, starts_at, ends_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+announcementColumns,
+		a.Message, a.LinkURL, a.Level, a.Pages, a.StartsAt, a.EndsAt)
+	if err != nil {
+		return models.Announcement{}, err
+	}
+	return pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[models.Announcement])
+}
+
+// DeleteAnnouncement removes an announcement. Returns false if it didn't exist.
+func (db *DB) DeleteAnnouncement(ctx context.Context, id int64) (bool, error) {
+	tag, err := db.Pool.Exec(ctx, `DELETE FROM announcements WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"no-lights-monitor/internal/models"
+)
+
+// ── API keys ─────────────────────────────────────────────────────────
+
+// APIKeyPrefix starts every API key, so a leaked one is easy to recognize.
+const APIKeyPrefix = "nlm_"
+
+// CreateAPIKey issues a new API key for a user, replacing the previous one,
+// and returns it. Only its hash is stored, so it can't be shown again.
+func (db *DB) CreateAPIKey(ctx context.Context, userID int64) (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	key := APIKeyPrefix + hex.EncodeToString(buf)
+	_, err := db.Pool.Exec(ctx, `
+		INSERT INTO api_keys (user_id, key_hash) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET key_hash = $2, created_at = NOW(), last_used_at = NULL
+	`, userID, hashAPIKey(key))
+	if err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// RevokeAPIKey deletes the API key of a user. Returns false if there was none.
+func (db *DB) RevokeAPIKey(ctx context.Context, userID int64) (bool, error) {
+	tag, err := db.Pool.Exec(ctx, `DELETE FROM api_keys WHERE user_id = $1`, userID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetUserByAPIKey returns the user an API key belongs to and records its use,
+// or nil if the key is unknown.
+func (db *DB) GetUserByAPIKey(ctx context.Context, key string) (*models.User, error) {
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return nil, nil
+	}
+	rows, err := db.Pool.Query(ctx, `
+		WITH k AS (
+			UPDATE api_keys SET last_used_at = NOW() WHERE key_hash = $1 RETURNING user_id
+		)
+		SELECT `+userColumnsAliased+` FROM users u JOIN k ON k.user_id = u.id
+	`, hashAPIKey(key))
+	if err != nil {
+		return nil, err
+	}
+	u, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.User])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return u, err
+}
+
+// hashAPIKey returns the stored form of an API key: hex sha256.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
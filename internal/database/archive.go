@@ -0,0 +1,34 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"no-lights-monitor/internal/models"
+)
+
+// ── Archival ─────────────────────────────────────────────────────────
+
+// GetMonitorsToArchive returns up to limit monitors soft-deleted before the
+// given time, oldest deletion first. Canaries are never purged.
+func (db *DB) GetMonitorsToArchive(ctx context.Context, deletedBefore time.Time, limit int) ([]*models.Monitor, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+monitorColumns+` FROM monitors
+		WHERE deleted_at IS NOT NULL AND deleted_at < $1 AND NOT is_canary
+		ORDER BY deleted_at
+		LIMIT $2
+	`, deletedBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[models.Monitor])
+}
+
+// PurgeMonitor permanently deletes a soft-deleted monitor; its status events,
+// changes, corrections and other per-monitor rows go with it (ON DELETE CASCADE).
+func (db *DB) PurgeMonitor(ctx context.Context, id int64) error {
+	_, err := db.Pool.Exec(ctx, `DELETE FROM monitors WHERE id = $1 AND deleted_at IS NOT NULL`, id)
+	return err
+}
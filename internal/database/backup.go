@@ -0,0 +1,183 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// BackupTables are the tables covered by logical backups, in restore order
+// (parents before children).
+var BackupTables = []string{"users", "monitors", "status_events"}
+
+// backupQueries select every row of a backed-up table as JSON. Status events
+// are limited to $1 onwards.
+var backupQueries = map[string]string{
+	"users":         `SELECT row_to_json(t) FROM users t ORDER BY id`,
+	"monitors":      `SELECT row_to_json(t) FROM monitors t ORDER BY id`,
+	"status_events": `SELECT row_to_json(t) FROM status_events t WHERE timestamp >= $1 ORDER BY id`,
+}
+
+// restoreBatchSize is how many rows are inserted per round trip on restore.
+const restoreBatchSize = 500
+
+// DumpTables calls fn with every row of the BackupTables, in their order, as
+// JSON objects. For status_events only events at or after since are included.
+// All tables are read from one repeatable-read snapshot, so every child row's
+// parent is in the dump too.
+func (db *DB) DumpTables(ctx context.Context, since time.Time, fn func(table string, row json.RawMessage) error) error {
+	tx, err := db.Pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, table := range BackupTables {
+		if err := dumpTable(ctx, tx, table, since, fn); err != nil {
+			return fmt.Errorf("dump %s: %w", table, err)
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func dumpTable(ctx context.Context, tx pgx.Tx, table string, since time.Time, fn func(table string, row json.RawMessage) error) error {
+	var args []any
+	if table == "status_events" {
+		args = append(args, since)
+	}
+	rows, err := tx.Query(ctx, backupQueries[table], args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var row json.RawMessage
+		if err := rows.Scan(&row); err != nil {
+			return err
+		}
+		if err := fn(table, row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// BackupRow is one row of a backup, as produced by DumpTables.
+type BackupRow struct {
+	Table string          `json:"table"`
+	Row   json.RawMessage `json:"row"`
+}
+
+// RestoreRows inserts backed-up rows in a single transaction, keeping their IDs.
+// Rows that clash with existing ones are skipped, so restoring twice is
+// harmless; columns missing from the backup get their defaults. next returns
+// the rows in BackupTables order and (nil, nil) at the end. Returns the number
+// of rows inserted per table.
+func (db *DB) RestoreRows(ctx context.Context, next func() (*BackupRow, error)) (map[string]int64, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	inserted := map[string]int64{}
+	var (
+		table  string
+		insert string
+		batch  = &pgx.Batch{}
+	)
+	flush := func() error {
+		if batch.Len() == 0 {
+			return nil
+		}
+		results := tx.SendBatch(ctx, batch)
+		for range batch.Len() {
+			tag, err := results.Exec()
+			if err != nil {
+				results.Close()
+				return fmt.Errorf("restore %s: %w", table, err)
+			}
+			inserted[table] += tag.RowsAffected()
+		}
+		batch = &pgx.Batch{}
+		return results.Close()
+	}
+
+	for {
+		row, err := next()
+		if err != nil {
+			return nil, err
+		}
+		if row == nil {
+			break
+		}
+		if row.Table != table {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			if _, ok := backupQueries[row.Table]; !ok {
+				return nil, fmt.Errorf("table %q is not backed up", row.Table)
+			}
+			table = row.Table
+			if insert, err = restoreInsert(ctx, tx, table, row.Row); err != nil {
+				return nil, err
+			}
+		}
+		batch.Queue(insert, row.Row)
+		if batch.Len() >= restoreBatchSize {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	// Keep new rows from colliding with the restored IDs.
+	for _, t := range BackupTables {
+		if _, err := tx.Exec(ctx, `SELECT setval(pg_get_serial_sequence('`+t+`', 'id'), GREATEST((SELECT COALESCE(MAX(id), 0) FROM `+t+`), 1))`); err != nil {
+			return nil, fmt.Errorf("reset %s sequence: %w", t, err)
+		}
+	}
+	return inserted, tx.Commit(ctx)
+}
+
+// restoreInsert builds the INSERT for rows of table shaped like sample: only
+// the columns present in the backup that can be written (not generated).
+func restoreInsert(ctx context.Context, tx pgx.Tx, table string, sample json.RawMessage) (string, error) {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(sample, &keys); err != nil {
+		return "", fmt.Errorf("restore %s: %w", table, err)
+	}
+	rows, err := tx.Query(ctx, `
+		SELECT column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1 AND is_generated = 'NEVER'
+		ORDER BY ordinal_position
+	`, table)
+	if err != nil {
+		return "", err
+	}
+	columns, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return "", err
+	}
+	var list string
+	for _, c := range columns {
+		if _, ok := keys[c]; !ok {
+			continue
+		}
+		if list != "" {
+			list += ", "
+		}
+		list += pgx.Identifier{c}.Sanitize()
+	}
+	if list == "" {
+		return "", fmt.Errorf("restore %s: no known columns in backup", table)
+	}
+	return `INSERT INTO ` + table + ` (` + list + `) SELECT ` + list +
+		` FROM json_populate_record(NULL::` + table + `, $1) ON CONFLICT DO NOTHING`, nil
+}
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ── Banned words (content filter) ────────────────────────────────────
+
+// GetBannedWords returns the content filter's banned words, alphabetically.
+func (db *DB) GetBannedWords(ctx context.Context) ([]string, error) {
+	rows, err := db.Pool.Query(ctx, `SELECT word FROM banned_words ORDER BY word`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// SetBannedWords replaces the whole banned word list.
+func (db *DB) SetBannedWords(ctx context.Context, words []string) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM banned_words`); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO banned_words (word) SELECT DISTINCT unnest($1::text[])
+	`, words); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+
+	"no-lights-monitor/internal/models"
+)
+
+// ── User bans (shadow-ban) ───────────────────────────────────────────
+
+// BanUser bans a Telegram user, creating the user record if they never talked
+// to the bot. It returns the IDs of the user's monitors so callers can audit them.
+func (db *DB) BanUser(ctx context.Context, telegramID int64, reason string) ([]int64, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var userID int64
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO users (telegram_id, banned_at, ban_reason)
+		VALUES ($1, NOW(), $2)
+		ON CONFLICT (telegram_id) DO UPDATE SET banned_at = COALESCE(users.banned_at, NOW()), ban_reason = $2
+		RETURNING id
+	`, telegramID, reason).Scan(&userID); err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(ctx, `SELECT id FROM monitors WHERE user_id = $1 AND deleted_at IS NULL ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return nil, err
+	}
+	return ids, tx.Commit(ctx)
+}
+
+// UnbanUser lifts a ban. It returns the IDs of the user's monitors, or false
+// if the user was not banned.
+func (db *DB) UnbanUser(ctx context.Context, telegramID int64) ([]int64, bool, error) {
+	tag, err := db.Pool.Exec(ctx, `
+		UPDATE users SET banned_at = NULL, ban_reason = '' WHERE telegram_id = $1 AND banned_at IS NOT NULL
+	`, telegramID)
+	if err != nil || tag.RowsAffected() == 0 {
+		return nil, false, err
+	}
+	rows, err := db.Pool.Query(ctx, `
+		SELECT m.id FROM monitors m JOIN users u ON u.id = m.user_id
+		WHERE u.telegram_id = $1 AND m.deleted_at IS NULL ORDER BY m.id
+	`, telegramID)
+	if err != nil {
+		return nil, true, err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	return ids, true, err
+}
+
+// IsTelegramUserBanned reports whether the Telegram user is banned.
+func (db *DB) IsTelegramUserBanned(ctx context.Context, telegramID int64) (bool, error) {
+	var banned bool
+	err := db.Pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM users WHERE telegram_id = $1 AND banned_at IS NOT NULL)
+	`, telegramID).Scan(&banned)
+	return banned, err
+}
+
+// IsUserBanned reports whether the user with the given internal ID is banned.
+func (db *DB) IsUserBanned(ctx context.Context, userID int64) (bool, error) {
+	var banned bool
+	err := db.Pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND banned_at IS NOT NULL)
+	`, userID).Scan(&banned)
+	return banned, err
+}
+
+// GetBannedUserIDs returns the internal IDs of all banned users.
+func (db *DB) GetBannedUserIDs(ctx context.Context) ([]int64, error) {
+	rows, err := db.Pool.Query(ctx, `SELECT id FROM users WHERE banned_at IS NOT NULL`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[int64])
+}
+
+// GetBannedUsers returns every banned user, most recently banned first.
+func (db *DB) GetBannedUsers(ctx context.Context) ([]*models.User, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+userColumns+` FROM users WHERE banned_at IS NOT NULL ORDER BY banned_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[models.User])
+}
@@ -0,0 +1,143 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"no-lights-monitor/internal/models"
+)
+
+// ── Canary monitors ──────────────────────────────────────────────────
+
+// canaryTelegramID is the telegram_id of the system user owning canary monitors.
+// Real Telegram IDs are always positive.
+const canaryTelegramID = 0
+
+// canaryLockKey is the advisory lock that serializes canary creation.
+const canaryLockKey = "canary_monitor"
+
+// canaryMonitorSQL selects the canary monitor.
+const canaryMonitorSQL = `
+	SELECT ` + monitorColumns + ` FROM monitors
+	WHERE is_canary = TRUE AND deleted_at IS NULL
+	ORDER BY id LIMIT 1`
+
+// EnsureCanaryMonitor returns the canary monitor, creating it (and its system
+// owner) on first use. The canary is private, has no graph and uses the
+// shortest offline threshold so each pipeline cycle completes quickly.
+// Creation holds an advisory lock, so workers starting together create one
+// canary between them.
+func (db *DB) EnsureCanaryMonitor(ctx context.Context, channelID int64) (*models.Monitor, error) {
+	m, err := db.getCanaryMonitor(ctx)
+	if errors.Is(err, pgx.ErrNoRows) {
+		m, err = db.createCanaryMonitor(ctx, channelID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if m.ChannelID != channelID {
+		if _, err := db.Pool.Exec(ctx, `UPDATE monitors SET channel_id = $2 WHERE id = $1`, m.ID, channelID); err != nil {
+			return nil, err
+		}
+		m.ChannelID = channelID
+	}
+	return m, nil
+}
+
+// createCanaryMonitor creates the canary unless another worker just did,
+// returning whichever exists.
+func (db *DB) createCanaryMonitor(ctx context.Context, channelID int64) (*models.Monitor, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, canaryLockKey); err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(ctx, canaryMonitorSQL)
+	if err != nil {
+		return nil, err
+	}
+	m, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.Monitor])
+	if err == nil {
+		return m, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	var userID int64
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO users (telegram_id, username, first_name)
+		VALUES ($1, 'canary', 'System canary')
+		ON CONFLICT (telegram_id) DO UPDATE SET username = 'canary', first_name = 'System canary'
+		RETURNING id
+	`, canaryTelegramID).Scan(&userID); err != nil {
+		return nil, err
+	}
+	rows, err = tx.Query(ctx, `
+		INSERT INTO monitors (user_id, name, address, latitude, longitude, channel_id,
+			monitor_type, is_canary, is_public, graph_enabled, offline_threshold_sec)
+		VALUES ($1, 'Canary', '', 0, 0, $2, 'heartbeat', TRUE, FALSE, FALSE, 150)
+		RETURNING `+monitorColumns, userID, channelID)
+	if err != nil {
+		return nil, err
+	}
+	m, err = pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.Monitor])
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `INSERT INTO canaries (monitor_id) VALUES ($1) ON CONFLICT DO NOTHING`, m.ID); err != nil {
+		return nil, err
+	}
+	return m, tx.Commit(ctx)
+}
+
+func (db *DB) getCanaryMonitor(ctx context.Context) (*models.Monitor, error) {
+	rows, err := db.Pool.Query(ctx, canaryMonitorSQL)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.Monitor])
+}
+
+// IsCanaryMonitor reports whether the monitor is a canary.
+func (db *DB) IsCanaryMonitor(ctx context.Context, monitorID int64) (bool, error) {
+	var isCanary bool
+	err := db.Pool.QueryRow(ctx, `SELECT is_canary FROM monitors WHERE id = $1`, monitorID).Scan(&isCanary)
+	return isCanary, err
+}
+
+// RecordCanaryDelivery is called by the bot once a canary status message reached Telegram.
+func (db *DB) RecordCanaryDelivery(ctx context.Context, monitorID int64, isOnline bool, at time.Time) error {
+	_, err := db.Pool.Exec(ctx, `
+		INSERT INTO canaries (monitor_id, delivered_online, delivered_at) VALUES ($1, $2, $3)
+		ON CONFLICT (monitor_id) DO UPDATE SET delivered_online = $2, delivered_at = $3
+	`, monitorID, isOnline, at)
+	return err
+}
+
+// SetCanaryHealth stores the latest verdict of the canary check.
+func (db *DB) SetCanaryHealth(ctx context.Context, monitorID int64, healthy bool, problem string) error {
+	_, err := db.Pool.Exec(ctx, `
+		UPDATE canaries SET healthy = $2, problem = $3, checked_at = NOW() WHERE monitor_id = $1
+	`, monitorID, healthy, problem)
+	return err
+}
+
+// GetCanary returns the pipeline state of the canary monitor.
+func (db *DB) GetCanary(ctx context.Context, monitorID int64) (*models.Canary, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT monitor_id, delivered_online, delivered_at, healthy, problem, checked_at
+		FROM canaries WHERE monitor_id = $1
+	`, monitorID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.Canary])
+}
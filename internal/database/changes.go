@@ -0,0 +1,283 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"no-lights-monitor/internal/models"
+)
+
+// Sources recorded in monitor_changes.source.
+const (
+	ChangeSourceBot    = "bot"
+	ChangeSourceWeb    = "web"
+	ChangeSourceAdmin  = "admin"
+	ChangeSourceAPI    = "api"    // /api/v1 with a user's API key
+	ChangeSourceSystem = "system" // automatic: inactivity pause, lost channel, group migration
+)
+
+// Lifecycle fields recorded next to settings changes, so monitor_changes is a
+// complete event stream of monitor configuration.
+const (
+	ChangeFieldCreated = "created" // new_value: monitor name
+	ChangeFieldDeleted = "deleted" // old_value: monitor name
+	ChangeFieldToken   = "token"   // ping token regenerated; values left empty
+
+	// ChangeFieldMaintenance records a new set of maintenance windows
+	// (values: maintenance.Format of the windows).
+	ChangeFieldMaintenance = "maintenance_windows"
+
+	// ChangeFieldOwnerBanned records a ban (new_value: reason) or its lift
+	// (old_value: "banned") on each of the owner's monitors.
+	ChangeFieldOwnerBanned = "owner_banned"
+)
+
+// ── Settings audit ───────────────────────────────────────────────────
+
+// RecordMonitorChange appends an audit record for a single settings field change.
+func (db *DB) RecordMonitorChange(ctx context.Context, monitorID int64, source, field, oldValue, newValue string) error {
+	_, err := db.Pool.Exec(ctx, `
+		INSERT INTO monitor_changes (monitor_id, source, field, old_value, new_value)
+		VALUES ($1, $2, $3, $4, $5)
+	`, monitorID, source, field, oldValue, newValue)
+	return err
+}
+
+// GetMonitorChanges returns the most recent settings changes of a monitor, newest first.
+func (db *DB) GetMonitorChanges(ctx context.Context, monitorID int64, limit int) ([]*models.MonitorChange, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+monitorChangeColumns+` FROM monitor_changes
+		WHERE monitor_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2
+	`, monitorID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[models.MonitorChange])
+}
+
+// GetMonitorChangesAfter returns change records with an ID above afterID, oldest
+// first: the configuration event stream, read from a cursor.
+func (db *DB) GetMonitorChangesAfter(ctx context.Context, afterID int64, limit int) ([]*models.MonitorChange, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+monitorChangeColumns+` FROM monitor_changes
+		WHERE id > $1
+		ORDER BY id ASC
+		LIMIT $2
+	`, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[models.MonitorChange])
+}
+
+// LatestMonitorChangeID returns the ID of the newest change record, or 0.
+func (db *DB) LatestMonitorChangeID(ctx context.Context) (int64, error) {
+	var id int64
+	err := db.Pool.QueryRow(ctx, `SELECT COALESCE(MAX(id), 0) FROM monitor_changes`).Scan(&id)
+	return id, err
+}
+
+// GetMonitorChange returns a single change record belonging to the given monitor.
+func (db *DB) GetMonitorChange(ctx context.Context, monitorID, changeID int64) (*models.MonitorChange, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+monitorChangeColumns+` FROM monitor_changes
+		WHERE id = $1 AND monitor_id = $2
+	`, changeID, monitorID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.MonitorChange])
+}
+
+// MarkMonitorChangeReverted flags a change record as reverted.
+func (db *DB) MarkMonitorChangeReverted(ctx context.Context, changeID int64) error {
+	_, err := db.Pool.Exec(ctx, `UPDATE monitor_changes SET reverted_at = NOW() WHERE id = $1`, changeID)
+	return err
+}
+
+// revertableFields are the settings that can be restored from a single audit value.
+// Composite settings (address with coordinates, outage group, DTEK address) are
+// recorded for visibility but have to be re-entered by hand.
+var revertableFields = map[string]bool{
+	"name":                            true,
+	"is_active":                       true,
+	"is_public":                       true,
+	"map_fuzz_location":               true,
+	"map_hide_name":                   true,
+	"map_hide_channel":                true,
+	"notify_address":                  true,
+	"notify_outage":                   true,
+	"notify_style":                    true,
+	"planned_outage_mode":             true,
+	"outage_prealert_enabled":         true,
+	"outage_forecast_enabled":         true,
+	"heartbeat_webhook_enabled":       true,
+	"outage_photo_enabled":            true,
+	"skip_outage_photo_if_no_outages": true,
+	"graph_enabled":                   true,
+	"latency_graph_enabled":           true,
+	"daily_graph_enabled":             true,
+	"monthly_graph_enabled":           true,
+	"channel_stats_enabled":           true,
+	"sms_enabled":                     true,
+	"sponsor_enabled":                 true,
+	"dtek_enabled":                    true,
+	"offline_threshold_sec":           true,
+	"online_confirm_sec":              true,
+}
+
+// IsRevertableField reports whether changes to field can be reverted.
+func IsRevertableField(field string) bool {
+	return revertableFields[field]
+}
+
+// MonitorFieldValue returns the current value of a revertable field in audit format.
+func MonitorFieldValue(m *models.Monitor, field string) (string, bool) {
+	switch field {
+	case "name":
+		return m.Name, true
+	case "is_active":
+		return strconv.FormatBool(m.IsActive), true
+	case "is_public":
+		return strconv.FormatBool(m.IsPublic), true
+	case "map_fuzz_location":
+		return strconv.FormatBool(m.MapFuzzLocation), true
+	case "map_hide_name":
+		return strconv.FormatBool(m.MapHideName), true
+	case "map_hide_channel":
+		return strconv.FormatBool(m.MapHideChannel), true
+	case "notify_address":
+		return strconv.FormatBool(m.NotifyAddress), true
+	case "notify_outage":
+		return strconv.FormatBool(m.NotifyOutage), true
+	case "notify_style":
+		return m.NotifyStyle, true
+	case "planned_outage_mode":
+		return m.PlannedOutageMode, true
+	case "outage_photo_enabled":
+		return strconv.FormatBool(m.OutagePhotoEnabled), true
+	case "outage_prealert_enabled":
+		return strconv.FormatBool(m.OutagePreAlertEnabled), true
+	case "outage_forecast_enabled":
+		return strconv.FormatBool(m.OutageForecastEnabled), true
+	case "heartbeat_webhook_enabled":
+		return strconv.FormatBool(m.HeartbeatWebhookEnabled), true
+	case "skip_outage_photo_if_no_outages":
+		return strconv.FormatBool(m.SkipOutagePhotoIfNoOutages), true
+	case "graph_enabled":
+		return strconv.FormatBool(m.GraphEnabled), true
+	case "latency_graph_enabled":
+		return strconv.FormatBool(m.LatencyGraphEnabled), true
+	case "daily_graph_enabled":
+		return strconv.FormatBool(m.DailyGraphEnabled), true
+	case "monthly_graph_enabled":
+		return strconv.FormatBool(m.MonthlyGraphEnabled), true
+	case "channel_stats_enabled":
+		return strconv.FormatBool(m.ChannelStatsEnabled), true
+	case "sms_enabled":
+		return strconv.FormatBool(m.SMSEnabled), true
+	case "sponsor_enabled":
+		return strconv.FormatBool(m.SponsorEnabled), true
+	case "dtek_enabled":
+		return strconv.FormatBool(m.DtekEnabled), true
+	case "offline_threshold_sec":
+		return strconv.Itoa(m.OfflineThresholdSec), true
+	case "online_confirm_sec":
+		return strconv.Itoa(m.OnlineConfirmSec), true
+	}
+	return "", false
+}
+
+// ApplyMonitorField sets a revertable field from its audit-format value.
+func (db *DB) ApplyMonitorField(ctx context.Context, id int64, field, value string) error {
+	if field == "name" {
+		return db.UpdateMonitorName(ctx, id, value)
+	}
+	if field == "notify_style" {
+		// Only values that were valid presets ever reach the audit log.
+		return db.SetMonitorNotifyStyle(ctx, id, value)
+	}
+	if field == "planned_outage_mode" {
+		return db.SetMonitorPlannedOutageMode(ctx, id, value)
+	}
+	if field == "offline_threshold_sec" {
+		sec, err := strconv.Atoi(value)
+		if err != nil || (sec != 150 && sec != 300) {
+			return fmt.Errorf("invalid %s value %q", field, value)
+		}
+		return db.SetMonitorThreshold(ctx, id, sec)
+	}
+	if field == "online_confirm_sec" {
+		sec, err := strconv.Atoi(value)
+		if err != nil || !ValidOnlineConfirmSec(sec) {
+			return fmt.Errorf("invalid %s value %q", field, value)
+		}
+		return db.SetMonitorOnlineConfirm(ctx, id, sec)
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s value %q", field, value)
+	}
+	switch field {
+	case "is_active":
+		return db.SetMonitorActive(ctx, id, b)
+	case "is_public":
+		return db.SetMonitorPublic(ctx, id, b)
+	case "map_fuzz_location":
+		return db.SetMonitorMapFuzzLocation(ctx, id, b)
+	case "map_hide_name":
+		return db.SetMonitorMapHideName(ctx, id, b)
+	case "map_hide_channel":
+		return db.SetMonitorMapHideChannel(ctx, id, b)
+	case "notify_address":
+		return db.SetMonitorNotifyAddress(ctx, id, b)
+	case "notify_outage":
+		return db.SetMonitorNotifyOutage(ctx, id, b)
+	case "outage_photo_enabled":
+		return db.SetMonitorOutagePhotoEnabled(ctx, id, b)
+	case "outage_prealert_enabled":
+		return db.SetMonitorOutagePreAlert(ctx, id, b)
+	case "outage_forecast_enabled":
+		return db.SetMonitorOutageForecast(ctx, id, b)
+	case "heartbeat_webhook_enabled":
+		return db.SetMonitorHeartbeatWebhook(ctx, id, b)
+	case "skip_outage_photo_if_no_outages":
+		return db.SetMonitorSkipOutagePhotoIfNoOutages(ctx, id, b)
+	case "graph_enabled":
+		return db.SetMonitorGraphEnabled(ctx, id, b)
+	case "latency_graph_enabled":
+		return db.SetMonitorLatencyGraphEnabled(ctx, id, b)
+	case "daily_graph_enabled":
+		return db.SetMonitorDailyGraphEnabled(ctx, id, b)
+	case "monthly_graph_enabled":
+		return db.SetMonitorMonthlyGraphEnabled(ctx, id, b)
+	case "channel_stats_enabled":
+		return db.SetMonitorChannelStats(ctx, id, b)
+	case "sms_enabled":
+		return db.SetMonitorSMSEnabled(ctx, id, b)
+	case "sponsor_enabled":
+		return db.SetMonitorSponsorEnabled(ctx, id, b)
+	case "dtek_enabled":
+		return db.SetMonitorDtekEnabled(ctx, id, b)
+	}
+	return fmt.Errorf("field %s is not revertable", field)
+}
+
+// HasMonitorChangeBetween reports whether field of a monitor was changed within [from, to].
+func (db *DB) HasMonitorChangeBetween(ctx context.Context, monitorID int64, field string, from, to time.Time) (bool, error) {
+	var exists bool
+	err := db.Pool.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM monitor_changes
+			WHERE monitor_id = $1 AND field = $2 AND created_at BETWEEN $3 AND $4
+		)
+	`, monitorID, field, from, to).Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"no-lights-monitor/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ── Channel stats ────────────────────────────────────────────────────
+
+// GetChannelStatsMonitors returns active monitors with a linked channel and
+// channel stats enabled, ordered by owner so the DMs can be grouped.
+func (db *DB) GetChannelStatsMonitors(ctx context.Context) ([]*models.Monitor, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+monitorColumnsAliased+` FROM monitors m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.channel_stats_enabled AND m.channel_id IS NOT NULL AND m.channel_id <> 0
+		  AND m.is_active AND m.deleted_at IS NULL AND u.banned_at IS NULL
+		ORDER BY m.user_id, m.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[models.Monitor])
+}
+
+// GetLastChannelSubscribers returns the latest recorded subscriber count of
+// the monitor's channel. ok is false when nothing has been recorded yet.
+func (db *DB) GetLastChannelSubscribers(ctx context.Context, monitorID int64) (count int, ok bool, err error) {
+	err = db.Pool.QueryRow(ctx, `
+		SELECT subscribers FROM channel_stats
+		WHERE monitor_id = $1
+		ORDER BY taken_at DESC
+		LIMIT 1
+	`, monitorID).Scan(&count)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	return count, err == nil, err
+}
+
+// SaveChannelSubscribers records the current subscriber count of the monitor's channel.
+func (db *DB) SaveChannelSubscribers(ctx context.Context, monitorID int64, count int) error {
+	_, err := db.Pool.Exec(ctx, `
+		INSERT INTO channel_stats (monitor_id, subscribers) VALUES ($1, $2)
+	`, monitorID, count)
+	return err
+}
+
+// RecordChannelPost counts a status message posted to the monitor's channel,
+// if the monitor has channel stats enabled.
+func (db *DB) RecordChannelPost(ctx context.Context, monitorID int64) error {
+	_, err := db.Pool.Exec(ctx, `
+		INSERT INTO channel_posts (monitor_id)
+		SELECT id FROM monitors WHERE id = $1 AND channel_stats_enabled
+	`, monitorID)
+	return err
+}
+
+// CountChannelPosts returns how many status messages were posted to the
+// monitor's channel since the given time.
+func (db *DB) CountChannelPosts(ctx context.Context, monitorID int64, since time.Time) (int, error) {
+	var n int
+	err := db.Pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM channel_posts WHERE monitor_id = $1 AND posted_at >= $2
+	`, monitorID, since).Scan(&n)
+	return n, err
+}
+
+// DeleteChannelPostsBefore drops post records older than before.
+func (db *DB) DeleteChannelPostsBefore(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := db.Pool.Exec(ctx, `DELETE FROM channel_posts WHERE posted_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,149 @@
+package database
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"no-lights-monitor/internal/models"
+)
+
+// ── Owner status corrections ─────────────────────────────────────────
+
+// AddStatusCorrection stores an owner's override of the status for [start, end).
+func (db *DB) AddStatusCorrection(ctx context.Context, monitorID int64, isOnline bool, start, end time.Time, note string) (*models.StatusCorrection, error) {
+	rows, err := db.Pool.Query(ctx, `
+		INSERT INTO status_corrections (monitor_id, is_online, start_at, end_at, note)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+statusCorrectionColumns+`
+	`, monitorID, isOnline, start, end, note)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.StatusCorrection])
+}
+
+// GetStatusCorrections returns the corrections of a monitor overlapping [from, to],
+// oldest first, so later corrections win when they are applied in order.
+func (db *DB) GetStatusCorrections(ctx context.Context, monitorID int64, from, to time.Time) ([]*models.StatusCorrection, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+statusCorrectionColumns+` FROM status_corrections
+		WHERE monitor_id = $1 AND end_at > $2 AND start_at < $3
+		ORDER BY created_at ASC, id ASC
+	`, monitorID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[models.StatusCorrection])
+}
+
+// GetRecentStatusCorrections returns the latest corrections of a monitor, newest first.
+func (db *DB) GetRecentStatusCorrections(ctx context.Context, monitorID int64, limit int) ([]*models.StatusCorrection, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+statusCorrectionColumns+` FROM status_corrections
+		WHERE monitor_id = $1
+		ORDER BY start_at DESC, id DESC
+		LIMIT $2
+	`, monitorID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[models.StatusCorrection])
+}
+
+// DeleteStatusCorrection removes a correction of the given monitor.
+// Returns false if there was no such correction.
+func (db *DB) DeleteStatusCorrection(ctx context.Context, monitorID, id int64) (bool, error) {
+	tag, err := db.Pool.Exec(ctx, `
+		DELETE FROM status_corrections WHERE id = $1 AND monitor_id = $2
+	`, id, monitorID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// GetCorrectedStatusHistory returns the anchor (last event before from, may be
+// nil) and the events within [from, to] of a monitor with its corrections applied.
+func (db *DB) GetCorrectedStatusHistory(ctx context.Context, monitorID int64, from, to time.Time) (*models.StatusEvent, []*models.StatusEvent, error) {
+	events, err := db.GetStatusHistory(ctx, monitorID, from, to)
+	if err != nil {
+		return nil, nil, err
+	}
+	anchor, err := db.GetLastEventBefore(ctx, monitorID, from)
+	if err != nil {
+		return nil, nil, err
+	}
+	corrections, err := db.GetStatusCorrections(ctx, monitorID, from, to)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(corrections) == 0 {
+		return anchor, events, nil
+	}
+
+	timeline := events
+	if anchor != nil {
+		timeline = append([]*models.StatusEvent{anchor}, events...)
+	}
+	timeline = ApplyStatusCorrections(timeline, corrections)
+
+	anchor = nil
+	out := make([]*models.StatusEvent, 0, len(timeline))
+	for _, e := range timeline {
+		switch {
+		case e.Timestamp.Before(from):
+			anchor = e
+		case !e.Timestamp.After(to):
+			out = append(out, e)
+		}
+	}
+	return anchor, out, nil
+}
+
+// ApplyStatusCorrections overlays corrections on an ascending event timeline.
+// Each correction replaces the events inside [StartAt, EndAt) with a single
+// event at StartAt, then restores at EndAt whatever status the raw timeline
+// had there. Corrections are applied in the given order, so later ones win.
+// Consecutive events with the same status are collapsed afterwards.
+func ApplyStatusCorrections(events []*models.StatusEvent, corrections []*models.StatusCorrection) []*models.StatusEvent {
+	timeline := slices.Clone(events)
+	for _, c := range corrections {
+		// Status the timeline has at EndAt, before this correction is applied.
+		var after *bool
+		for _, e := range timeline {
+			if e.Timestamp.After(c.EndAt) {
+				break
+			}
+			v := e.IsOnline
+			after = &v
+		}
+
+		next := make([]*models.StatusEvent, 0, len(timeline)+2)
+		for _, e := range timeline {
+			if !e.Timestamp.Before(c.StartAt) && e.Timestamp.Before(c.EndAt) {
+				continue
+			}
+			next = append(next, e)
+		}
+		next = append(next, &models.StatusEvent{MonitorID: c.MonitorID, IsOnline: c.IsOnline, Timestamp: c.StartAt, Corrected: true})
+		if after != nil {
+			next = append(next, &models.StatusEvent{MonitorID: c.MonitorID, IsOnline: *after, Timestamp: c.EndAt, Corrected: true})
+		}
+		slices.SortStableFunc(next, func(a, b *models.StatusEvent) int {
+			return a.Timestamp.Compare(b.Timestamp)
+		})
+		timeline = next
+	}
+
+	collapsed := make([]*models.StatusEvent, 0, len(timeline))
+	for _, e := range timeline {
+		if n := len(collapsed); n > 0 && collapsed[n-1].IsOnline == e.IsOnline {
+			continue
+		}
+		collapsed = append(collapsed, e)
+	}
+	return collapsed
+}
@@ -2,7 +2,11 @@ package database
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -22,6 +26,44 @@ const monitorColumns = `id, user_id, token, name, address, latitude, longitude,
 	dtek_outage_recheck_at, dtek_outage_message_id,
 	offline_threshold_sec, settings_password,
 	skip_outage_photo_if_no_outages,
+	graph_events_hash,
+	outage_photo_mode,
+	outage_photo_daily_at,
+	outage_summary_enabled,
+	outage_summary_at,
+	outage_summary_sent_on,
+	is_canary,
+	ping_base_url,
+	ping_ip,
+	interval_hint_at,
+	notify_style,
+	outage_prealert_enabled,
+	outage_prealert_sent_for,
+	map_fuzz_location,
+	map_hide_name,
+	map_hide_channel,
+	planned_outage_mode,
+	online_confirm_sec,
+	muted_until,
+	channel_stats_enabled,
+	sms_enabled,
+	sms_phones,
+	sponsor_enabled,
+	webhook_url,
+	webhook_secret,
+	latency_graph_enabled,
+	latency_graph_message_id,
+	latency_graph_week_start,
+	outage_forecast_enabled,
+	heartbeat_webhook_enabled,
+	power_return_url,
+	power_return_secret,
+	daily_graph_enabled,
+	daily_graph_message_id,
+	daily_graph_start,
+	monthly_graph_enabled,
+	monthly_graph_message_id,
+	monthly_graph_start,
 	created_at, deleted_at`
 
 // monitorColumnsAliased is the same as monitorColumns but with table alias prefix for JOINs.
@@ -35,18 +77,79 @@ const monitorColumnsAliased = `m.id, m.user_id, m.token, m.name, m.address, m.la
 	m.dtek_outage_recheck_at, m.dtek_outage_message_id,
 	m.offline_threshold_sec, m.settings_password,
 	m.skip_outage_photo_if_no_outages,
+	m.graph_events_hash,
+	m.outage_photo_mode,
+	m.outage_photo_daily_at,
+	m.outage_summary_enabled,
+	m.outage_summary_at,
+	m.outage_summary_sent_on,
+	m.is_canary,
+	m.ping_base_url,
+	m.ping_ip,
+	m.interval_hint_at,
+	m.notify_style,
+	m.outage_prealert_enabled,
+	m.outage_prealert_sent_for,
+	m.map_fuzz_location,
+	m.map_hide_name,
+	m.map_hide_channel,
+	m.planned_outage_mode,
+	m.online_confirm_sec,
+	m.muted_until,
+	m.channel_stats_enabled,
+	m.sms_enabled,
+	m.sms_phones,
+	m.sponsor_enabled,
+	m.webhook_url,
+	m.webhook_secret,
+	m.latency_graph_enabled,
+	m.latency_graph_message_id,
+	m.latency_graph_week_start,
+	m.outage_forecast_enabled,
+	m.heartbeat_webhook_enabled,
+	m.power_return_url,
+	m.power_return_secret,
+	m.daily_graph_enabled,
+	m.daily_graph_message_id,
+	m.daily_graph_start,
+	m.monthly_graph_enabled,
+	m.monthly_graph_message_id,
+	m.monthly_graph_start,
 	m.created_at, m.deleted_at`
 
-const userColumns = `id, telegram_id, username, first_name, created_at`
+const userColumns = `id, telegram_id, username, first_name, banned_at, ban_reason, created_at`
 
-const statusEventColumns = `id, monitor_id, is_online, timestamp`
+// userColumnsAliased is userColumns with the "u" table alias.
+const userColumnsAliased = `u.id, u.telegram_id, u.username, u.first_name, u.banned_at, u.ban_reason, u.created_at`
+
+const linkCodeColumns = `code, telegram_id, username, first_name, expires_at`
+
+const statusEventColumns = `id, monitor_id, is_online, timestamp, inferred`
+
+const statusCorrectionColumns = `id, monitor_id, is_online, start_at, end_at, note, created_at`
+
+const followerColumns = `id, monitor_id, telegram_id, username, first_name, approved, declined_at, created_at`
+
+const incidentColumns = `id, region, outage_group, started_at, ended_at, affected, total, peak, start_posted, end_posted`
+
+const monitorChangeColumns = `id, monitor_id, source, field, old_value, new_value, reverted_at, created_at`
+
+const scheduledJobColumns = `name, schedule, locked_by, locked_until, last_started_at, last_finished_at,
+	last_duration_ms, last_error, run_count, next_run_at`
 
 type DB struct {
 	Pool *pgxpool.Pool
 }
 
-func New(ctx context.Context, databaseURL string) (*DB, error) {
-	pool, err := pgxpool.New(ctx, databaseURL)
+// New connects to the database. Queries taking longer than slowQuery are
+// logged with their parameters redacted; 0 disables the slow-query log.
+func New(ctx context.Context, databaseURL string, slowQuery time.Duration) (*DB, error) {
+	poolCfg, err := pgxpool.ParseConfig(databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse database url: %w", err)
+	}
+	poolCfg.ConnConfig.Tracer = queryTracer{slow: slowQuery}
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
 	if err != nil {
 		return nil, fmt.Errorf("connect to database: %w", err)
 	}
@@ -71,6 +174,9 @@ func (db *DB) Migrate(ctx context.Context) error {
 		created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
 	);
 
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS banned_at TIMESTAMPTZ;
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS ban_reason TEXT NOT NULL DEFAULT '';
+
 	CREATE TABLE IF NOT EXISTS monitors (
 		id                   BIGSERIAL PRIMARY KEY,
 		user_id              BIGINT NOT NULL REFERENCES users(id),
@@ -120,10 +226,65 @@ func (db *DB) Migrate(ctx context.Context) error {
 	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS settings_password TEXT NOT NULL DEFAULT left(replace(gen_random_uuid()::text, '-', ''), 8);
 	UPDATE monitors SET settings_password = left(replace(gen_random_uuid()::text, '-', ''), 8) WHERE settings_password = '';
 	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS skip_outage_photo_if_no_outages BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS settings_token_hash TEXT
+		GENERATED ALWAYS AS (encode(sha256(decode(replace(settings_token::text, '-', ''), 'hex')), 'hex')) STORED;
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS graph_events_hash TEXT NOT NULL DEFAULT '';
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS outage_photo_mode TEXT NOT NULL DEFAULT 'change';
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS outage_photo_daily_at TEXT NOT NULL DEFAULT '08:00';
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS outage_summary_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS outage_summary_at TEXT NOT NULL DEFAULT '07:00';
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS outage_summary_sent_on DATE;
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS is_canary BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS ping_base_url TEXT NOT NULL DEFAULT '';
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS ping_ip TEXT NOT NULL DEFAULT '';
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS interval_hint_at TIMESTAMPTZ;
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS notify_style TEXT NOT NULL DEFAULT '';
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS outage_prealert_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS outage_prealert_sent_for TIMESTAMPTZ;
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS map_fuzz_location BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS map_hide_name BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS map_hide_channel BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS planned_outage_mode TEXT NOT NULL DEFAULT '';
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS online_confirm_sec INT NOT NULL DEFAULT 0;
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS muted_until TIMESTAMPTZ;
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS channel_stats_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS sms_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS sms_phones TEXT NOT NULL DEFAULT '';
+	-- Sponsor notes are opt-in.
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS sponsor_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE monitors ALTER COLUMN sponsor_enabled SET DEFAULT FALSE;
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS webhook_url TEXT NOT NULL DEFAULT '';
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS webhook_secret TEXT NOT NULL DEFAULT '';
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS latency_graph_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS latency_graph_message_id INT NOT NULL DEFAULT 0;
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS latency_graph_week_start TIMESTAMPTZ;
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS outage_forecast_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS heartbeat_webhook_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS power_return_url TEXT NOT NULL DEFAULT '';
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS power_return_secret TEXT NOT NULL DEFAULT '';
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS daily_graph_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS daily_graph_message_id INT NOT NULL DEFAULT 0;
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS daily_graph_start TIMESTAMPTZ;
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS monthly_graph_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS monthly_graph_message_id INT NOT NULL DEFAULT 0;
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS monthly_graph_start TIMESTAMPTZ;
+	-- Secret of the follow invite link; the monitor ID alone is guessable.
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS follow_token TEXT UNIQUE DEFAULT replace(gen_random_uuid()::text, '-', '');
+	UPDATE monitors SET follow_token = replace(gen_random_uuid()::text, '-', '') WHERE follow_token IS NULL;
 
 	CREATE INDEX IF NOT EXISTS idx_monitors_token   ON monitors(token);
 	CREATE INDEX IF NOT EXISTS idx_monitors_settings_token ON monitors(settings_token);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_monitors_settings_token_hash ON monitors(settings_token_hash);
 	CREATE INDEX IF NOT EXISTS idx_monitors_user_id ON monitors(user_id);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_monitors_ping_ip ON monitors(ping_ip) WHERE ping_ip <> '' AND deleted_at IS NULL;
+	-- Partial indexes behind the hot listings (see queries.go); their predicates
+	-- must stay in sync with the WHERE clauses there.
+	CREATE INDEX IF NOT EXISTS idx_monitors_with_channels ON monitors(id)
+		WHERE channel_id IS NOT NULL AND channel_id <> 0 AND is_active AND deleted_at IS NULL;
+	CREATE INDEX IF NOT EXISTS idx_monitors_public ON monitors(id)
+		WHERE is_public AND is_active AND deleted_at IS NULL;
+	CREATE INDEX IF NOT EXISTS idx_monitors_public_changed ON monitors(last_status_change_at)
+		WHERE is_public AND is_active AND deleted_at IS NULL;
 
 	CREATE TABLE IF NOT EXISTS status_events (
 		id          BIGSERIAL PRIMARY KEY,
@@ -134,9 +295,259 @@ func (db *DB) Migrate(ctx context.Context) error {
 
 	CREATE INDEX IF NOT EXISTS idx_status_events_monitor_time
 		ON status_events (monitor_id, timestamp DESC);
+	ALTER TABLE status_events ADD COLUMN IF NOT EXISTS inferred BOOLEAN NOT NULL DEFAULT FALSE;
+
+	CREATE TABLE IF NOT EXISTS monitor_changes (
+		id          BIGSERIAL PRIMARY KEY,
+		monitor_id  BIGINT NOT NULL REFERENCES monitors(id) ON DELETE CASCADE,
+		source      TEXT NOT NULL,
+		field       TEXT NOT NULL,
+		old_value   TEXT NOT NULL DEFAULT '',
+		new_value   TEXT NOT NULL DEFAULT '',
+		reverted_at TIMESTAMPTZ,
+		created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_monitor_changes_monitor_time
+		ON monitor_changes (monitor_id, created_at DESC);
+	-- Behind monitorsHiddenSinceSQL (see queries.go); keep the fields in sync.
+	CREATE INDEX IF NOT EXISTS idx_monitor_changes_visibility
+		ON monitor_changes (created_at)
+		WHERE field IN ('is_public', 'is_active', 'deleted', 'owner_banned');
+
+	CREATE TABLE IF NOT EXISTS status_corrections (
+		id          BIGSERIAL PRIMARY KEY,
+		monitor_id  BIGINT NOT NULL REFERENCES monitors(id) ON DELETE CASCADE,
+		is_online   BOOLEAN NOT NULL,
+		start_at    TIMESTAMPTZ NOT NULL,
+		end_at      TIMESTAMPTZ NOT NULL,
+		note        TEXT NOT NULL DEFAULT '',
+		created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_status_corrections_monitor_time
+		ON status_corrections (monitor_id, start_at);
+
+	CREATE TABLE IF NOT EXISTS monitor_followers (
+		id          BIGSERIAL PRIMARY KEY,
+		monitor_id  BIGINT NOT NULL REFERENCES monitors(id) ON DELETE CASCADE,
+		telegram_id BIGINT NOT NULL,
+		username    TEXT NOT NULL DEFAULT '',
+		first_name  TEXT NOT NULL DEFAULT '',
+		approved    BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		UNIQUE (monitor_id, telegram_id)
+	);
+	-- Declined requests are kept so the same user can't ask again right away.
+	ALTER TABLE monitor_followers ADD COLUMN IF NOT EXISTS declined_at TIMESTAMPTZ;
+
+	CREATE TABLE IF NOT EXISTS account_links (
+		telegram_id BIGINT PRIMARY KEY,
+		user_id     BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_account_links_user ON account_links(user_id);
+	-- Owner a monitor had before LinkAccount moved it to the primary user;
+	-- UnlinkAccount gives it back.
+	ALTER TABLE monitors ADD COLUMN IF NOT EXISTS linked_from_user_id BIGINT REFERENCES users(id) ON DELETE SET NULL;
+
+	CREATE TABLE IF NOT EXISTS link_codes (
+		code        TEXT PRIMARY KEY,
+		telegram_id BIGINT NOT NULL,
+		username    TEXT NOT NULL DEFAULT '',
+		first_name  TEXT NOT NULL DEFAULT '',
+		expires_at  TIMESTAMPTZ NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS incidents (
+		id           BIGSERIAL PRIMARY KEY,
+		region       TEXT NOT NULL,
+		outage_group TEXT NOT NULL,
+		started_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		ended_at     TIMESTAMPTZ,
+		affected     INT NOT NULL DEFAULT 0,
+		total        INT NOT NULL DEFAULT 0,
+		peak         INT NOT NULL DEFAULT 0,
+		start_posted BOOLEAN NOT NULL DEFAULT FALSE,
+		end_posted   BOOLEAN NOT NULL DEFAULT FALSE
+	);
+
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_incidents_open
+		ON incidents (region, outage_group) WHERE ended_at IS NULL;
+
+	CREATE TABLE IF NOT EXISTS scheduled_jobs (
+		name             TEXT PRIMARY KEY,
+		schedule         TEXT NOT NULL DEFAULT '',
+		locked_by        TEXT NOT NULL DEFAULT '',
+		locked_until     TIMESTAMPTZ,
+		last_started_at  TIMESTAMPTZ,
+		last_finished_at TIMESTAMPTZ,
+		last_duration_ms BIGINT NOT NULL DEFAULT 0,
+		last_error       TEXT NOT NULL DEFAULT '',
+		run_count        BIGINT NOT NULL DEFAULT 0,
+		next_run_at      TIMESTAMPTZ
+	);
+
+	CREATE TABLE IF NOT EXISTS banned_words (
+		word       TEXT PRIMARY KEY,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE TABLE IF NOT EXISTS canaries (
+		monitor_id       BIGINT PRIMARY KEY REFERENCES monitors(id),
+		delivered_online BOOLEAN NOT NULL DEFAULT FALSE,
+		delivered_at     TIMESTAMPTZ,
+		healthy          BOOLEAN NOT NULL DEFAULT TRUE,
+		problem          TEXT NOT NULL DEFAULT '',
+		checked_at       TIMESTAMPTZ
+	);
+
+	CREATE TABLE IF NOT EXISTS channel_stats (
+		monitor_id  BIGINT NOT NULL REFERENCES monitors(id) ON DELETE CASCADE,
+		taken_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		subscribers INT NOT NULL,
+		PRIMARY KEY (monitor_id, taken_at)
+	);
+
+	-- Status messages posted to channels with stats enabled, counted by the
+	-- weekly channel stats DM and pruned by it.
+	CREATE TABLE IF NOT EXISTS channel_posts (
+		monitor_id BIGINT NOT NULL REFERENCES monitors(id) ON DELETE CASCADE,
+		posted_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS idx_channel_posts_monitor ON channel_posts (monitor_id, posted_at);
+
+	CREATE TABLE IF NOT EXISTS sms_usage (
+		monitor_id BIGINT NOT NULL REFERENCES monitors(id) ON DELETE CASCADE,
+		month      DATE NOT NULL,
+		sent       INT NOT NULL DEFAULT 0,
+		PRIMARY KEY (monitor_id, month)
+	);
+
+	CREATE TABLE IF NOT EXISTS push_devices (
+		monitor_id   BIGINT NOT NULL REFERENCES monitors(id) ON DELETE CASCADE,
+		platform     TEXT NOT NULL,
+		token        TEXT NOT NULL,
+		created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (monitor_id, platform, token)
+	);
+	CREATE INDEX IF NOT EXISTS idx_push_devices_token ON push_devices(platform, token);
+
+	CREATE TABLE IF NOT EXISTS announcements (
+		id         BIGSERIAL PRIMARY KEY,
+		message    TEXT NOT NULL,
+		link_url   TEXT NOT NULL DEFAULT '',
+		level      TEXT NOT NULL DEFAULT 'info',
+		pages      TEXT NOT NULL DEFAULT '',
+		starts_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		ends_at    TIMESTAMPTZ,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE TABLE IF NOT EXISTS sponsor_messages (
+		id          BIGSERIAL PRIMARY KEY,
+		message     TEXT NOT NULL,
+		link_url    TEXT NOT NULL DEFAULT '',
+		every_weeks INT NOT NULL DEFAULT 4,
+		starts_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		ends_at     TIMESTAMPTZ,
+		created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE TABLE IF NOT EXISTS monitor_devices (
+		id           BIGSERIAL PRIMARY KEY,
+		monitor_id   BIGINT NOT NULL REFERENCES monitors(id) ON DELETE CASCADE,
+		token        UUID UNIQUE NOT NULL DEFAULT gen_random_uuid(),
+		name         TEXT NOT NULL DEFAULT '',
+		created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		last_ping_at TIMESTAMPTZ
+	);
+	CREATE INDEX IF NOT EXISTS idx_monitor_devices_monitor ON monitor_devices(monitor_id);
+
+	CREATE TABLE IF NOT EXISTS maintenance_windows (
+		id         BIGSERIAL PRIMARY KEY,
+		monitor_id BIGINT NOT NULL REFERENCES monitors(id) ON DELETE CASCADE,
+		start_min  INT NOT NULL CHECK (start_min BETWEEN 0 AND 1439),
+		end_min    INT NOT NULL CHECK (end_min BETWEEN 0 AND 1439),
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS idx_maintenance_windows_monitor ON maintenance_windows(monitor_id);
+
+	CREATE TABLE IF NOT EXISTS api_keys (
+		id           BIGSERIAL PRIMARY KEY,
+		user_id      BIGINT UNIQUE NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		key_hash     TEXT UNIQUE NOT NULL,
+		created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		last_used_at TIMESTAMPTZ
+	);
+
+	CREATE TABLE IF NOT EXISTS ping_samples (
+		monitor_id BIGINT NOT NULL REFERENCES monitors(id) ON DELETE CASCADE,
+		sampled_at TIMESTAMPTZ NOT NULL,
+		rtt_ms     REAL, -- median round trip; NULL when no reply came back
+		loss       REAL NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_ping_samples_monitor_time ON ping_samples(monitor_id, sampled_at);
+	CREATE INDEX IF NOT EXISTS idx_ping_samples_time ON ping_samples(sampled_at);
+
+	CREATE TABLE IF NOT EXISTS data_migrations (
+		name       TEXT PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
 	`
-	_, err := db.Pool.Exec(ctx, sql)
-	return err
+	if _, err := db.Pool.Exec(ctx, sql); err != nil {
+		return err
+	}
+	for _, m := range dataMigrations {
+		if err := db.migrateDataOnce(ctx, m.name, m.sql); err != nil {
+			return fmt.Errorf("data migration %s: %w", m.name, err)
+		}
+	}
+	return nil
+}
+
+// dataMigrations are one-time data fixes, too expensive to repeat on every
+// start. Each runs once per database, recorded by name in data_migrations.
+var dataMigrations = []struct {
+	name string
+	sql  string
+}{
+	// Drop events that repeat the previous state of the same monitor (left by
+	// racing checkers before UpdateMonitorStatus became idempotent).
+	{"dedup_status_events", `
+		DELETE FROM status_events WHERE id IN (
+			SELECT id FROM (
+				SELECT id, is_online,
+					LAG(is_online) OVER (PARTITION BY monitor_id ORDER BY timestamp, id) AS prev_online
+				FROM status_events
+			) e
+			WHERE e.is_online = e.prev_online
+		)`},
+}
+
+// migrateDataOnce runs sql and records name in one transaction, unless name
+// is already recorded. A service migrating at the same time waits for the
+// marker and then skips it.
+func (db *DB) migrateDataOnce(ctx context.Context, name, sql string) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `INSERT INTO data_migrations (name) VALUES ($1) ON CONFLICT DO NOTHING`, name)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+	if _, err := tx.Exec(ctx, sql); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
 // ── User queries ─────────────────────────────────────────────────────
@@ -173,12 +584,26 @@ func (db *DB) GetAllUsers(ctx context.Context) ([]*models.User, error) {
 // ── Monitor queries ──────────────────────────────────────────────────
 
 // CreateMonitor inserts a new monitor and returns it (with generated token).
-func (db *DB) CreateMonitor(ctx context.Context, userID int64, name, address string, lat, lng float64, channelID int64, channelName, monitorType, pingTarget string) (*models.Monitor, error) {
+func (db *DB) CreateMonitor(ctx context.Context, userID int64, name, address string, lat, lng float64, channelID int64, channelName, monitorType, pingTarget, pingBaseURL string) (*models.Monitor, error) {
 	rows, err := db.Pool.Query(ctx, `
-		INSERT INTO monitors (user_id, name, address, latitude, longitude, channel_id, channel_name, monitor_type, ping_target)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO monitors (user_id, name, address, latitude, longitude, channel_id, channel_name, monitor_type, ping_target, ping_base_url)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		RETURNING `+monitorColumns+`
-	`, userID, name, address, lat, lng, channelID, channelName, monitorType, pingTarget)
+	`, userID, name, address, lat, lng, channelID, channelName, monitorType, pingTarget, pingBaseURL)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.Monitor])
+}
+
+// CreateSyntheticMonitor creates a private heartbeat monitor without a
+// channel for load tests, so it never shows up on the public map.
+func (db *DB) CreateSyntheticMonitor(ctx context.Context, userID int64, name string, lat, lng float64, pingBaseURL string) (*models.Monitor, error) {
+	rows, err := db.Pool.Query(ctx, `
+		INSERT INTO monitors (user_id, name, latitude, longitude, monitor_type, ping_base_url, is_public)
+		VALUES ($1, $2, $3, $4, 'heartbeat', $5, FALSE)
+		RETURNING `+monitorColumns+`
+	`, userID, name, lat, lng, pingBaseURL)
 	if err != nil {
 		return nil, err
 	}
@@ -196,23 +621,59 @@ func (db *DB) GetMonitorByToken(ctx context.Context, token string) (*models.Moni
 	return pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.Monitor])
 }
 
+// GetMonitorByPingIP returns the monitor bound to a source IP for /api/ping-ip.
+func (db *DB) GetMonitorByPingIP(ctx context.Context, ip string) (*models.Monitor, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+monitorColumns+` FROM monitors WHERE ping_ip = $1 AND ping_ip <> '' AND deleted_at IS NULL
+	`, ip)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.Monitor])
+}
+
+// GetMonitorByID returns a monitor by its ID.
+func (db *DB) GetMonitorByID(ctx context.Context, id int64) (*models.Monitor, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+monitorColumns+` FROM monitors WHERE id = $1 AND deleted_at IS NULL
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.Monitor])
+}
+
 // GetMonitorBySettingsToken returns a monitor by its unique settings token.
+// The lookup goes through settings_token_hash so the raw token is never compared directly.
 func (db *DB) GetMonitorBySettingsToken(ctx context.Context, settingsToken string) (*models.Monitor, error) {
 	rows, err := db.Pool.Query(ctx, `
-		SELECT `+monitorColumns+` FROM monitors WHERE settings_token = $1 AND deleted_at IS NULL
-	`, settingsToken)
+		SELECT `+monitorColumns+` FROM monitors WHERE settings_token_hash = $1 AND deleted_at IS NULL
+	`, hashSettingsToken(settingsToken))
 	if err != nil {
 		return nil, err
 	}
 	return pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.Monitor])
 }
 
-// GetMonitorsByTelegramID returns all monitors for the user with the given Telegram ID.
+// hashSettingsToken mirrors the settings_token_hash generated column: sha256 over the
+// 16 raw UUID bytes, hex-encoded. Malformed tokens hash to "" and never match.
+func hashSettingsToken(token string) string {
+	raw, err := hex.DecodeString(strings.ReplaceAll(token, "-", ""))
+	if err != nil || len(raw) != 16 {
+		return ""
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// GetMonitorsByTelegramID returns all monitors for the user with the given Telegram ID,
+// including the primary user's monitors when the account is linked to one.
 func (db *DB) GetMonitorsByTelegramID(ctx context.Context, telegramID int64) ([]*models.Monitor, error) {
 	rows, err := db.Pool.Query(ctx, `
 		SELECT `+monitorColumnsAliased+` FROM monitors m
 		JOIN users u ON u.id = m.user_id
-		WHERE u.telegram_id = $1 AND m.deleted_at IS NULL
+		WHERE (u.telegram_id = $1 OR u.id IN (SELECT user_id FROM account_links WHERE telegram_id = $1))
+		  AND m.deleted_at IS NULL
 		ORDER BY m.created_at DESC
 	`, telegramID)
 	if err != nil {
@@ -222,17 +683,44 @@ func (db *DB) GetMonitorsByTelegramID(ctx context.Context, telegramID int64) ([]
 }
 
 // GetPublicMonitors returns monitors that are visible on the public map.
+// Monitors of banned users are left out here and in the other public listings.
 func (db *DB) GetPublicMonitors(ctx context.Context) ([]*models.Monitor, error) {
-	rows, err := db.Pool.Query(ctx, `
-		SELECT `+monitorColumns+` FROM monitors
-		WHERE is_public = TRUE AND is_active = TRUE AND deleted_at IS NULL ORDER BY id
-	`)
+	rows, err := db.Pool.Query(ctx, publicMonitorsSQL)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[models.Monitor])
+}
+
+// GetPublicMonitorsByIDs returns the public, active monitors among ids.
+func (db *DB) GetPublicMonitorsByIDs(ctx context.Context, ids []int64) ([]*models.Monitor, error) {
+	rows, err := db.Pool.Query(ctx, publicMonitorsByIDsSQL, ids)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[models.Monitor])
+}
+
+// GetPublicMonitorsChangedSince returns the public, active monitors whose
+// status changed at or after since.
+func (db *DB) GetPublicMonitorsChangedSince(ctx context.Context, since time.Time) ([]*models.Monitor, error) {
+	rows, err := db.Pool.Query(ctx, publicMonitorsChangedSinceSQL, since)
 	if err != nil {
 		return nil, err
 	}
 	return pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[models.Monitor])
 }
 
+// GetMonitorsHiddenSince returns the IDs of monitors that left the public map
+// at or after since: made private, paused, deleted or owned by a banned user.
+func (db *DB) GetMonitorsHiddenSince(ctx context.Context, since time.Time) ([]int64, error) {
+	rows, err := db.Pool.Query(ctx, monitorsHiddenSinceSQL, since)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[int64])
+}
+
 // GetAllMonitors returns every monitor in the database.
 func (db *DB) GetAllMonitors(ctx context.Context) ([]*models.Monitor, error) {
 	rows, err := db.Pool.Query(ctx, `
@@ -244,38 +732,103 @@ func (db *DB) GetAllMonitors(ctx context.Context) ([]*models.Monitor, error) {
 	return pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[models.Monitor])
 }
 
+// GetCheckedMonitors returns the monitors the worker checks: every monitor
+// except those of banned owners, whose status is frozen.
+func (db *DB) GetCheckedMonitors(ctx context.Context) ([]*models.Monitor, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+monitorColumns+` FROM monitors WHERE deleted_at IS NULL AND `+notBannedOwner+` ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[models.Monitor])
+}
+
 // GetMonitorsWithChannels returns all monitors that have a Telegram channel linked.
 func (db *DB) GetMonitorsWithChannels(ctx context.Context) ([]*models.Monitor, error) {
+	rows, err := db.Pool.Query(ctx, monitorsWithChannelsSQL)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[models.Monitor])
+}
+
+// GetMonitorsByChannelID returns the active monitors posting to a Telegram channel.
+func (db *DB) GetMonitorsByChannelID(ctx context.Context, channelID int64) ([]*models.Monitor, error) {
 	rows, err := db.Pool.Query(ctx, `
 		SELECT `+monitorColumns+` FROM monitors
-		WHERE channel_id IS NOT NULL AND channel_id != 0 AND is_active = TRUE AND deleted_at IS NULL
+		WHERE channel_id = $1 AND is_active AND deleted_at IS NULL
 		ORDER BY id
-	`)
+	`, channelID)
 	if err != nil {
 		return nil, err
 	}
 	return pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[models.Monitor])
 }
 
+// ChannelShared reports whether any monitor other than exceptID, paused or
+// not, still posts to the channel.
+func (db *DB) ChannelShared(ctx context.Context, channelID, exceptID int64) (bool, error) {
+	var shared bool
+	err := db.Pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM monitors WHERE channel_id = $1 AND id <> $2 AND deleted_at IS NULL)
+	`, channelID, exceptID).Scan(&shared)
+	return shared, err
+}
+
 // ── Monitor updates ──────────────────────────────────────────────────
 
 // UpdateMonitorStatus sets online/offline, updates the status change timestamp,
-// and logs a status event for historical graphs.
-func (db *DB) UpdateMonitorStatus(ctx context.Context, id int64, isOnline bool) error {
-	_, err := db.Pool.Exec(ctx, `
+// and logs a status event for historical graphs. It is idempotent: the monitor
+// row is locked and nothing is logged if the latest event already has isOnline,
+// so racing checkers can't record the same transition twice. Returns false
+// when the call was a duplicate.
+func (db *DB) UpdateMonitorStatus(ctx context.Context, id int64, isOnline bool) (bool, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM monitors WHERE id = $1 FOR UPDATE`, id); err != nil {
+		return false, err
+	}
+
+	var lastOnline bool
+	err = tx.QueryRow(ctx, `
+		SELECT is_online FROM status_events
+		WHERE monitor_id = $1
+		ORDER BY timestamp DESC, id DESC
+		LIMIT 1
+	`, id).Scan(&lastOnline)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return false, err
+	}
+	if err == nil && lastOnline == isOnline {
+		// Already recorded; only heal the monitor row if it drifted.
+		if _, err := tx.Exec(ctx, `
+			UPDATE monitors SET is_online = $2 WHERE id = $1 AND is_online <> $2
+		`, id, isOnline); err != nil {
+			return false, err
+		}
+		return false, tx.Commit(ctx)
+	}
+
+	if _, err := tx.Exec(ctx, `
 		UPDATE monitors
 		SET is_online = $2, last_status_change_at = NOW()
 		WHERE id = $1
-	`, id, isOnline)
-	if err != nil {
-		return err
+	`, id, isOnline); err != nil {
+		return false, err
 	}
 
 	// Log the status change event.
-	_, err = db.Pool.Exec(ctx, `
+	if _, err := tx.Exec(ctx, `
 		INSERT INTO status_events (monitor_id, is_online) VALUES ($1, $2)
-	`, id, isOnline)
-	return err
+	`, id, isOnline); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
 }
 
 // UpdateMonitorHeartbeat sets the last heartbeat timestamp.
@@ -302,6 +855,24 @@ func (db *DB) SetMonitorPublic(ctx context.Context, id int64, isPublic bool) err
 	return err
 }
 
+// SetMonitorMapFuzzLocation toggles coordinate rounding on the public map.
+func (db *DB) SetMonitorMapFuzzLocation(ctx context.Context, id int64, v bool) error {
+	_, err := db.Pool.Exec(ctx, `UPDATE monitors SET map_fuzz_location = $2 WHERE id = $1`, id, v)
+	return err
+}
+
+// SetMonitorMapHideName toggles hiding the name and address on the public map.
+func (db *DB) SetMonitorMapHideName(ctx context.Context, id int64, v bool) error {
+	_, err := db.Pool.Exec(ctx, `UPDATE monitors SET map_hide_name = $2 WHERE id = $1`, id, v)
+	return err
+}
+
+// SetMonitorMapHideChannel toggles hiding the channel link on the public map.
+func (db *DB) SetMonitorMapHideChannel(ctx context.Context, id int64, v bool) error {
+	_, err := db.Pool.Exec(ctx, `UPDATE monitors SET map_hide_channel = $2 WHERE id = $1`, id, v)
+	return err
+}
+
 // SetMonitorOutageGroup saves the outage region and group for a monitor.
 func (db *DB) SetMonitorOutageGroup(ctx context.Context, id int64, region, group string) error {
 	_, err := db.Pool.Exec(ctx, `
@@ -310,6 +881,23 @@ func (db *DB) SetMonitorOutageGroup(ctx context.Context, id int64, region, group
 	return err
 }
 
+// SetMonitorNotifyStyle sets the notification wording preset of a monitor.
+func (db *DB) SetMonitorNotifyStyle(ctx context.Context, id int64, style string) error {
+	_, err := db.Pool.Exec(ctx, `
+		UPDATE monitors SET notify_style = $2 WHERE id = $1
+	`, id, style)
+	return err
+}
+
+// SetMonitorPlannedOutageMode sets how status changes matching the outage
+// schedule are delivered (see outage.PlannedModes).
+func (db *DB) SetMonitorPlannedOutageMode(ctx context.Context, id int64, mode string) error {
+	_, err := db.Pool.Exec(ctx, `
+		UPDATE monitors SET planned_outage_mode = $2 WHERE id = $1
+	`, id, mode)
+	return err
+}
+
 // SetMonitorNotifyOutage toggles whether the outage schedule is shown in notifications.
 func (db *DB) SetMonitorNotifyOutage(ctx context.Context, id int64, notifyOutage bool) error {
 	_, err := db.Pool.Exec(ctx, `
@@ -326,6 +914,62 @@ func (db *DB) SetMonitorOutagePhotoEnabled(ctx context.Context, id int64, enable
 	return err
 }
 
+// MarkOutageSummarySent records the (Kyiv) date the daily summary was published for.
+func (db *DB) MarkOutageSummarySent(ctx context.Context, id int64, day time.Time) error {
+	_, err := db.Pool.Exec(ctx, `UPDATE monitors SET outage_summary_sent_on = $2 WHERE id = $1`, id, day)
+	return err
+}
+
+// SetMonitorOutageForecast toggles the outage risk advisory in the daily summary.
+func (db *DB) SetMonitorOutageForecast(ctx context.Context, id int64, enabled bool) error {
+	_, err := db.Pool.Exec(ctx, `UPDATE monitors SET outage_forecast_enabled = $2 WHERE id = $1`, id, enabled)
+	return err
+}
+
+// SetMonitorOutagePreAlert toggles the heads-up posted before scheduled outages.
+func (db *DB) SetMonitorOutagePreAlert(ctx context.Context, id int64, enabled bool) error {
+	_, err := db.Pool.Exec(ctx, `UPDATE monitors SET outage_prealert_enabled = $2 WHERE id = $1`, id, enabled)
+	return err
+}
+
+// MarkOutagePreAlertSent records the start of the outage window a pre-alert was published for.
+func (db *DB) MarkOutagePreAlertSent(ctx context.Context, id int64, start time.Time) error {
+	_, err := db.Pool.Exec(ctx, `UPDATE monitors SET outage_prealert_sent_for = $2 WHERE id = $1`, id, start)
+	return err
+}
+
+// MarkIntervalHintSent records when the owner was last warned about the device's ping cadence.
+func (db *DB) MarkIntervalHintSent(ctx context.Context, id int64, at time.Time) error {
+	_, err := db.Pool.Exec(ctx, `UPDATE monitors SET interval_hint_at = $2 WHERE id = $1`, id, at)
+	return err
+}
+
+// SetMonitorChannelStats toggles the weekly channel stats DM to the owner.
+func (db *DB) SetMonitorChannelStats(ctx context.Context, id int64, enabled bool) error {
+	_, err := db.Pool.Exec(ctx, `UPDATE monitors SET channel_stats_enabled = $2 WHERE id = $1`, id, enabled)
+	return err
+}
+
+// SetMonitorSponsorEnabled sets whether sponsor messages may be appended to the weekly graph caption.
+func (db *DB) SetMonitorSponsorEnabled(ctx context.Context, id int64, enabled bool) error {
+	_, err := db.Pool.Exec(ctx, `UPDATE monitors SET sponsor_enabled = $2 WHERE id = $1`, id, enabled)
+	return err
+}
+
+// SetMonitorWebhook sets the URL status changes are POSTed to ('' disables
+// the webhook) and the secret they are signed with.
+func (db *DB) SetMonitorWebhook(ctx context.Context, id int64, url, secret string) error {
+	_, err := db.Pool.Exec(ctx, `UPDATE monitors SET webhook_url = $2, webhook_secret = $3 WHERE id = $1`, id, url, secret)
+	return err
+}
+
+// SetMonitorHeartbeatWebhook toggles relaying every (rate-limited) ping
+// arrival to the monitor's webhook.
+func (db *DB) SetMonitorHeartbeatWebhook(ctx context.Context, id int64, enabled bool) error {
+	_, err := db.Pool.Exec(ctx, `UPDATE monitors SET heartbeat_webhook_enabled = $2 WHERE id = $1`, id, enabled)
+	return err
+}
+
 // SetMonitorGraphEnabled toggles whether the uptime graph is posted to the channel.
 func (db *DB) SetMonitorGraphEnabled(ctx context.Context, id int64, enabled bool) error {
 	_, err := db.Pool.Exec(ctx, `
@@ -358,6 +1002,55 @@ func (db *DB) SetMonitorThreshold(ctx context.Context, id int64, thresholdSec in
 	return err
 }
 
+// ValidOnlineConfirmSec reports whether sec is a selectable online
+// confirmation delay (0 disables it).
+func ValidOnlineConfirmSec(sec int) bool {
+	return sec == 0 || sec == 120 || sec == 300 || sec == 600
+}
+
+// SetMonitorOnlineConfirm sets how long power must stay back before the
+// monitor is reported online.
+func (db *DB) SetMonitorOnlineConfirm(ctx context.Context, id int64, sec int) error {
+	_, err := db.Pool.Exec(ctx, `
+		UPDATE monitors SET online_confirm_sec = $2 WHERE id = $1
+	`, id, sec)
+	return err
+}
+
+// SetMonitorMutedUntil silences a monitor's status notifications until the
+// given time; nil unmutes it. Status is still recorded while muted.
+func (db *DB) SetMonitorMutedUntil(ctx context.Context, id int64, until *time.Time) error {
+	_, err := db.Pool.Exec(ctx, `
+		UPDATE monitors SET muted_until = $2 WHERE id = $1
+	`, id, until)
+	return err
+}
+
+// GetExpiredMutes returns monitors whose mute ended by now.
+func (db *DB) GetExpiredMutes(ctx context.Context, now time.Time) ([]*models.Monitor, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+monitorColumns+` FROM monitors
+		WHERE muted_until <= $1 AND deleted_at IS NULL
+		ORDER BY id
+	`, now)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[models.Monitor])
+}
+
+// ClearMonitorMute unmutes a monitor if it is still muted until the given
+// time. It reports false when the owner changed the mute in the meantime.
+func (db *DB) ClearMonitorMute(ctx context.Context, id int64, until time.Time) (bool, error) {
+	tag, err := db.Pool.Exec(ctx, `
+		UPDATE monitors SET muted_until = NULL WHERE id = $1 AND muted_until = $2
+	`, id, until)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
 // UpdateMonitorName updates the display name of a monitor.
 func (db *DB) UpdateMonitorName(ctx context.Context, id int64, name string) error {
 	_, err := db.Pool.Exec(ctx, `
@@ -366,6 +1059,33 @@ func (db *DB) UpdateMonitorName(ctx context.Context, id int64, name string) erro
 	return err
 }
 
+// RegenerateMonitorToken issues a fresh heartbeat token for a monitor and returns it.
+// The old ping URL stops working immediately; history and channel wiring are kept.
+// The device has to be reconfigured anyway, so the monitor moves to pingBaseURL.
+func (db *DB) RegenerateMonitorToken(ctx context.Context, id int64, pingBaseURL string) (string, error) {
+	var token string
+	err := db.Pool.QueryRow(ctx, `
+		UPDATE monitors SET token = gen_random_uuid(), ping_base_url = $2 WHERE id = $1 AND deleted_at IS NULL
+		RETURNING token::text
+	`, id, pingBaseURL).Scan(&token)
+	return token, err
+}
+
+// SetMonitorPingIP binds (or with "" unbinds) a monitor to a source IP.
+// Returns false if another monitor already holds the IP.
+func (db *DB) SetMonitorPingIP(ctx context.Context, id int64, ip string) (bool, error) {
+	tag, err := db.Pool.Exec(ctx, `
+		UPDATE monitors SET ping_ip = $2
+		WHERE id = $1 AND deleted_at IS NULL
+		  AND ($2 = '' OR NOT EXISTS (
+		      SELECT 1 FROM monitors o WHERE o.ping_ip = $2 AND o.id <> $1 AND o.deleted_at IS NULL))
+	`, id, ip)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
 // UpdateMonitorChannelName updates the stored Telegram channel username for a monitor.
 func (db *DB) UpdateMonitorChannelName(ctx context.Context, id int64, channelName string) error {
 	_, err := db.Pool.Exec(ctx, `
@@ -374,6 +1094,49 @@ func (db *DB) UpdateMonitorChannelName(ctx context.Context, id int64, channelNam
 	return err
 }
 
+// RelinkMonitorChannel attaches a monitor to a different Telegram channel.
+// Message IDs pinned to the old channel are reset so the graph, outage photo
+// and DTEK notice are posted fresh in the new one.
+func (db *DB) RelinkMonitorChannel(ctx context.Context, id, channelID int64, channelName string) error {
+	_, err := db.Pool.Exec(ctx, `
+		UPDATE monitors SET channel_id = $2, channel_name = $3,
+			graph_message_id = 0, graph_week_start = NULL, graph_events_hash = '',
+			latency_graph_message_id = 0, latency_graph_week_start = NULL,
+			daily_graph_message_id = 0, daily_graph_start = NULL,
+			monthly_graph_message_id = 0, monthly_graph_start = NULL,
+			outage_photo_message_id = 0, outage_photo_etag = '', outage_photo_updated_at = NULL,
+			dtek_outage_message_id = 0
+		WHERE id = $1
+	`, id, channelID, channelName)
+	return err
+}
+
+// MigrateMonitorChannel moves every monitor posting to the same channel as the
+// given monitor to newChannelID (Telegram "group migrated to supergroup").
+// Each move is recorded as a "channel" change. Returns the number of monitors updated.
+func (db *DB) MigrateMonitorChannel(ctx context.Context, monitorID, newChannelID int64) (int64, error) {
+	tag, err := db.Pool.Exec(ctx, `
+		WITH old AS (SELECT channel_id FROM monitors WHERE id = $1),
+		moved AS (
+			UPDATE monitors SET channel_id = $2,
+				graph_message_id = 0, graph_week_start = NULL, graph_events_hash = '',
+				latency_graph_message_id = 0, latency_graph_week_start = NULL,
+				daily_graph_message_id = 0, daily_graph_start = NULL,
+				monthly_graph_message_id = 0, monthly_graph_start = NULL,
+				outage_photo_message_id = 0, outage_photo_etag = '', outage_photo_updated_at = NULL,
+				dtek_outage_message_id = 0
+			WHERE deleted_at IS NULL AND channel_id = (SELECT channel_id FROM old)
+			RETURNING id
+		)
+		INSERT INTO monitor_changes (monitor_id, source, field, old_value, new_value)
+		SELECT id, '`+ChangeSourceSystem+`', 'channel', (SELECT channel_id FROM old)::text, $2::text FROM moved
+	`, monitorID, newChannelID)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
 // UpdateMonitorAddress updates the address and coordinates of a monitor.
 func (db *DB) UpdateMonitorAddress(ctx context.Context, id int64, address string, lat, lng float64) error {
 	_, err := db.Pool.Exec(ctx, `
@@ -390,6 +1153,12 @@ func (db *DB) UpdateGraphMessage(ctx context.Context, monitorID int64, messageID
 	return err
 }
 
+// SetGraphEventsHash stores the event checksum of the graph that was last delivered to the channel.
+func (db *DB) SetGraphEventsHash(ctx context.Context, monitorID int64, hash string) error {
+	_, err := db.Pool.Exec(ctx, `UPDATE monitors SET graph_events_hash = $2 WHERE id = $1`, monitorID, hash)
+	return err
+}
+
 // UpdateOutagePhoto stores the Telegram message ID, ETag, and fetch time for the outage schedule photo.
 func (db *DB) UpdateOutagePhoto(ctx context.Context, monitorID int64, messageID int, etag string, updatedAt time.Time) error {
 	_, err := db.Pool.Exec(ctx, `
@@ -417,10 +1186,15 @@ func (db *DB) GetAllDeletedMonitors(ctx context.Context) ([]*models.Monitor, err
 	return pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[models.Monitor])
 }
 
-// DeleteMonitor soft-deletes a monitor by setting deleted_at.
+// DeleteMonitor soft-deletes a monitor by setting deleted_at. Its channel
+// messages are removed by the bot (see mq.MonitorCleanupMsg), so their IDs
+// are cleared too.
 func (db *DB) DeleteMonitor(ctx context.Context, id int64) error {
 	_, err := db.Pool.Exec(ctx, `
-		UPDATE monitors SET deleted_at = NOW() WHERE id = $1
+		UPDATE monitors SET deleted_at = NOW(),
+			graph_message_id = 0, outage_photo_message_id = 0, dtek_outage_message_id = 0,
+			latency_graph_message_id = 0, daily_graph_message_id = 0, monthly_graph_message_id = 0
+		WHERE id = $1
 	`, id)
 	return err
 }
@@ -449,6 +1223,16 @@ func (db *DB) GetLastEventBefore(ctx context.Context, monitorID int64, before ti
 	return events[0], nil
 }
 
+// InsertInferredStatusEvents records an offline→online pair for a period the
+// checker missed (e.g. while the worker was down). Both events are marked inferred.
+func (db *DB) InsertInferredStatusEvents(ctx context.Context, monitorID int64, offlineAt, onlineAt time.Time) error {
+	_, err := db.Pool.Exec(ctx, `
+		INSERT INTO status_events (monitor_id, is_online, timestamp, inferred)
+		VALUES ($1, FALSE, $2, TRUE), ($1, TRUE, $3, TRUE)
+	`, monitorID, offlineAt, onlineAt)
+	return err
+}
+
 // GetStatusHistory returns status events for a monitor within a time range.
 func (db *DB) GetStatusHistory(ctx context.Context, monitorID int64, from, to time.Time) ([]*models.StatusEvent, error) {
 	rows, err := db.Pool.Query(ctx, `
@@ -462,14 +1246,18 @@ func (db *DB) GetStatusHistory(ctx context.Context, monitorID int64, from, to ti
 	return pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[models.StatusEvent])
 }
 
-// SetMonitorDtekConfig saves the DTEK unplanned outage config for a monitor.
-func (db *DB) SetMonitorDtekConfig(ctx context.Context, id int64, enabled bool, region, city, street, house string) error {
-	_, err := db.Pool.Exec(ctx, `
-		UPDATE monitors
-		SET dtek_enabled = $2, dtek_region = $3, dtek_city = $4, dtek_street = $5, dtek_house = $6
-		WHERE id = $1
-	`, id, enabled, region, city, street, house)
-	return err
+// GetStatusEventsBetween returns the status events of all monitors within a
+// time range, oldest first.
+func (db *DB) GetStatusEventsBetween(ctx context.Context, from, to time.Time) ([]*models.StatusEvent, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+statusEventColumns+` FROM status_events
+		WHERE timestamp >= $1 AND timestamp <= $2
+		ORDER BY timestamp ASC
+	`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[models.StatusEvent])
 }
 
 // SetMonitorDtekEnabled toggles the DTEK unplanned outage monitoring.
@@ -514,6 +1302,7 @@ func (db *DB) GetNeverActiveMonitors(ctx context.Context) ([]*models.Monitor, er
 		SELECT `+monitorColumns+` FROM monitors
 		WHERE is_active = TRUE
 		  AND deleted_at IS NULL
+		  AND is_canary = FALSE
 		  AND last_status_change_at = created_at
 		ORDER BY id
 	`)
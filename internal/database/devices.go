@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"no-lights-monitor/internal/models"
+)
+
+// ── Monitor devices ──────────────────────────────────────────────────
+
+const monitorDeviceColumns = `id, monitor_id, token::text AS token, name, created_at, last_ping_at`
+
+// CreateMonitorDevice adds an extra heartbeat device with a fresh ping token
+// to a monitor. Returns nil once the monitor already has maxDevices devices.
+func (db *DB) CreateMonitorDevice(ctx context.Context, monitorID int64, name string, maxDevices int) (*models.MonitorDevice, error) {
+	rows, err := db.Pool.Query(ctx, `
+		INSERT INTO monitor_devices (monitor_id, name)
+		SELECT $1, $2
+		WHERE (SELECT COUNT(*) FROM monitor_devices WHERE monitor_id = $1) < $3
+		RETURNING `+monitorDeviceColumns+`
+	`, monitorID, name, maxDevices)
+	if err != nil {
+		return nil, err
+	}
+	d, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.MonitorDevice])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return d, err
+}
+
+// GetMonitorDevices returns the extra heartbeat devices of a monitor, oldest first.
+func (db *DB) GetMonitorDevices(ctx context.Context, monitorID int64) ([]models.MonitorDevice, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+monitorDeviceColumns+` FROM monitor_devices WHERE monitor_id = $1 ORDER BY id
+	`, monitorID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[models.MonitorDevice])
+}
+
+// DeleteMonitorDevice removes a device of a monitor. Returns false if the
+// monitor has no such device.
+func (db *DB) DeleteMonitorDevice(ctx context.Context, monitorID, id int64) (bool, error) {
+	tag, err := db.Pool.Exec(ctx, `DELETE FROM monitor_devices WHERE id = $1 AND monitor_id = $2`, id, monitorID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetMonitorByDeviceToken returns the monitor a device ping token belongs to,
+// along with the device.
+func (db *DB) GetMonitorByDeviceToken(ctx context.Context, token string) (*models.Monitor, *models.MonitorDevice, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+monitorDeviceColumns+` FROM monitor_devices WHERE token = $1
+	`, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	d, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.MonitorDevice])
+	if err != nil {
+		return nil, nil, err
+	}
+	m, err := db.GetMonitorByID(ctx, d.MonitorID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, d, nil
+}
+
+// UpdateMonitorDevicePing records the last ping time of a device.
+func (db *DB) UpdateMonitorDevicePing(ctx context.Context, id int64, t time.Time) error {
+	_, err := db.Pool.Exec(ctx, `UPDATE monitor_devices SET last_ping_at = $2 WHERE id = $1`, id, t)
+	return err
+}
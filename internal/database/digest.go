@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ── Owner digest ─────────────────────────────────────────────────────
+
+// DigestMinMonitors is how many monitors an owner needs before per-monitor
+// problem DMs (lost channel, inactivity pause, ping cadence) are folded into
+// one daily digest instead.
+const DigestMinMonitors = 10
+
+// GetDigestOwners returns the Telegram IDs of unbanned owners with at least
+// DigestMinMonitors monitors. Canaries don't count.
+func (db *DB) GetDigestOwners(ctx context.Context) ([]int64, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT u.telegram_id FROM users u
+		JOIN monitors m ON m.user_id = u.id
+		WHERE m.deleted_at IS NULL AND m.is_canary = FALSE AND u.banned_at IS NULL
+		GROUP BY u.telegram_id
+		HAVING COUNT(*) >= $1
+		ORDER BY u.telegram_id
+	`, DigestMinMonitors)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[int64])
+}
+
+// UsesOwnerDigest reports whether the owner with telegramID gets the daily
+// digest instead of per-monitor problem DMs.
+func (db *DB) UsesOwnerDigest(ctx context.Context, telegramID int64) (bool, error) {
+	var n int
+	err := db.Pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM monitors m
+		JOIN users u ON u.id = m.user_id
+		WHERE u.telegram_id = $1 AND m.deleted_at IS NULL AND m.is_canary = FALSE
+	`, telegramID).Scan(&n)
+	return n >= DigestMinMonitors, err
+}
+
+// GetSystemPauses returns when each of the given monitors was last paused by
+// the system (lost channel, inactivity), for monitors whose latest is_active
+// change is such a pause. Owner pauses are intentional and left out.
+func (db *DB) GetSystemPauses(ctx context.Context, monitorIDs []int64) (map[int64]time.Time, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT DISTINCT ON (monitor_id) monitor_id, source, new_value, created_at
+		FROM monitor_changes
+		WHERE monitor_id = ANY($1) AND field = 'is_active' AND reverted_at IS NULL
+		ORDER BY monitor_id, id DESC
+	`, monitorIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	paused := make(map[int64]time.Time)
+	for rows.Next() {
+		var (
+			id       int64
+			source   string
+			newValue string
+			at       time.Time
+		)
+		if err := rows.Scan(&id, &source, &newValue, &at); err != nil {
+			return nil, err
+		}
+		if source == ChangeSourceSystem && newValue == "false" {
+			paused[id] = at
+		}
+	}
+	return paused, rows.Err()
+}
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"no-lights-monitor/internal/models"
+)
+
+// ── Monitor followers ────────────────────────────────────────────────
+
+// GetMonitorIDByFollowToken returns the monitor whose follow invite link
+// carries token.
+func (db *DB) GetMonitorIDByFollowToken(ctx context.Context, token string) (int64, error) {
+	var id int64
+	err := db.Pool.QueryRow(ctx, `
+		SELECT id FROM monitors WHERE follow_token = $1 AND deleted_at IS NULL
+	`, token).Scan(&id)
+	return id, err
+}
+
+// GetFollowToken returns the secret of a monitor's follow invite link.
+func (db *DB) GetFollowToken(ctx context.Context, monitorID int64) (string, error) {
+	var token string
+	err := db.Pool.QueryRow(ctx, `SELECT follow_token FROM monitors WHERE id = $1`, monitorID).Scan(&token)
+	return token, err
+}
+
+// AddFollowRequest records a pending follow request. It returns the existing
+// record instead when the user already asked, was approved, or was declined
+// less than cooldown ago; a request declined earlier is renewed.
+func (db *DB) AddFollowRequest(ctx context.Context, monitorID, telegramID int64, username, firstName string, cooldown time.Duration) (f *models.Follower, created bool, err error) {
+	rows, err := db.Pool.Query(ctx, `
+		INSERT INTO monitor_followers (monitor_id, telegram_id, username, first_name)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (monitor_id, telegram_id) DO UPDATE
+			SET username = $3, first_name = $4, declined_at = NULL, created_at = NOW()
+			WHERE monitor_followers.declined_at < NOW() - $5::interval
+		RETURNING `+followerColumns+`
+	`, monitorID, telegramID, username, firstName, cooldown)
+	if err != nil {
+		return nil, false, err
+	}
+	f, err = pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.Follower])
+	if err == nil {
+		return f, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, err
+	}
+	f, err = db.GetFollower(ctx, monitorID, telegramID)
+	return f, false, err
+}
+
+// GetFollower returns a user's follow record for a monitor, or nil if there is none.
+func (db *DB) GetFollower(ctx context.Context, monitorID, telegramID int64) (*models.Follower, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+followerColumns+` FROM monitor_followers WHERE monitor_id = $1 AND telegram_id = $2
+	`, monitorID, telegramID)
+	if err != nil {
+		return nil, err
+	}
+	f, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.Follower])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return f, err
+}
+
+// GetFollowers lists a monitor's followers and pending requests, oldest first.
+func (db *DB) GetFollowers(ctx context.Context, monitorID int64) ([]*models.Follower, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+followerColumns+` FROM monitor_followers
+		WHERE monitor_id = $1 AND declined_at IS NULL
+		ORDER BY created_at, id
+	`, monitorID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[models.Follower])
+}
+
+// GetApprovedFollowerIDs returns the Telegram IDs that get a monitor's status changes.
+func (db *DB) GetApprovedFollowerIDs(ctx context.Context, monitorID int64) ([]int64, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT telegram_id FROM monitor_followers WHERE monitor_id = $1 AND approved ORDER BY id
+	`, monitorID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[int64])
+}
+
+// ApproveFollower approves a pending request. It reports false if there was none.
+func (db *DB) ApproveFollower(ctx context.Context, monitorID, telegramID int64) (bool, error) {
+	tag, err := db.Pool.Exec(ctx, `
+		UPDATE monitor_followers SET approved = TRUE
+		WHERE monitor_id = $1 AND telegram_id = $2 AND declined_at IS NULL
+	`, monitorID, telegramID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// DeclineFollower declines a pending request or revokes a follower. The record
+// is kept, so AddFollowRequest can hold off repeated requests. It reports
+// false if there was nothing to decline.
+func (db *DB) DeclineFollower(ctx context.Context, monitorID, telegramID int64) (bool, error) {
+	tag, err := db.Pool.Exec(ctx, `
+		UPDATE monitor_followers SET approved = FALSE, declined_at = NOW()
+		WHERE monitor_id = $1 AND telegram_id = $2 AND declined_at IS NULL
+	`, monitorID, telegramID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// RemoveFollower deletes a follower or pending request (unfollow, unreachable
+// follower). Declined records stay until their cooldown is over.
+func (db *DB) RemoveFollower(ctx context.Context, monitorID, telegramID int64) (bool, error) {
+	tag, err := db.Pool.Exec(ctx, `
+		DELETE FROM monitor_followers WHERE monitor_id = $1 AND telegram_id = $2 AND declined_at IS NULL
+	`, monitorID, telegramID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// CountFollowers returns how many followers (approved or pending) a monitor has.
+func (db *DB) CountFollowers(ctx context.Context, monitorID int64) (int, error) {
+	var n int
+	err := db.Pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM monitor_followers WHERE monitor_id = $1 AND declined_at IS NULL
+	`, monitorID).Scan(&n)
+	return n, err
+}
@@ -0,0 +1,36 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// ── Daily and monthly uptime graphs ──────────────────────────────────
+
+// SetMonitorDailyGraphEnabled toggles whether today's uptime graph is posted to the channel.
+func (db *DB) SetMonitorDailyGraphEnabled(ctx context.Context, id int64, enabled bool) error {
+	_, err := db.Pool.Exec(ctx, `UPDATE monitors SET daily_graph_enabled = $2 WHERE id = $1`, id, enabled)
+	return err
+}
+
+// SetMonitorMonthlyGraphEnabled toggles whether the month's uptime graph is posted to the channel.
+func (db *DB) SetMonitorMonthlyGraphEnabled(ctx context.Context, id int64, enabled bool) error {
+	_, err := db.Pool.Exec(ctx, `UPDATE monitors SET monthly_graph_enabled = $2 WHERE id = $1`, id, enabled)
+	return err
+}
+
+// UpdateDailyGraphMessage stores the Telegram message ID and day start for the current daily graph.
+func (db *DB) UpdateDailyGraphMessage(ctx context.Context, monitorID int64, messageID int, dayStart time.Time) error {
+	_, err := db.Pool.Exec(ctx, `
+		UPDATE monitors SET daily_graph_message_id = $2, daily_graph_start = $3 WHERE id = $1
+	`, monitorID, messageID, dayStart)
+	return err
+}
+
+// UpdateMonthlyGraphMessage stores the Telegram message ID and month start for the current monthly graph.
+func (db *DB) UpdateMonthlyGraphMessage(ctx context.Context, monitorID int64, messageID int, monthStart time.Time) error {
+	_, err := db.Pool.Exec(ctx, `
+		UPDATE monitors SET monthly_graph_message_id = $2, monthly_graph_start = $3 WHERE id = $1
+	`, monitorID, messageID, monthStart)
+	return err
+}
@@ -0,0 +1,228 @@
+package database
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"no-lights-monitor/internal/models"
+)
+
+// ── Incidents ────────────────────────────────────────────────────────
+
+// GroupStat is how many monitors of one outage group are offline right now.
+type GroupStat struct {
+	Region      string `db:"region"`
+	OutageGroup string `db:"outage_group"`
+	Offline     int    `db:"offline"`
+	Total       int    `db:"total"`
+}
+
+// GetOutageGroupStats counts active monitors and offline ones per outage group
+// of the given regions. Canaries, monitors without a group and those of banned
+// owners are left out.
+func (db *DB) GetOutageGroupStats(ctx context.Context, regions []string) ([]GroupStat, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT outage_region AS region, outage_group,
+		       COUNT(*) FILTER (WHERE NOT is_online) AS offline,
+		       COUNT(*) AS total
+		FROM monitors
+		WHERE is_active = TRUE AND deleted_at IS NULL AND is_canary = FALSE
+		  AND `+notBannedOwner+`
+		  AND outage_group != '' AND outage_region = ANY($1)
+		GROUP BY outage_region, outage_group
+	`, regions)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[GroupStat])
+}
+
+// GroupOutageHours is the measured offline time of one outage group.
+type GroupOutageHours struct {
+	OutageGroup  string  `db:"outage_group"`
+	Monitors     int     `db:"monitors"`
+	OfflineHours float64 `db:"offline_hours"` // average per monitor
+}
+
+// GetGroupOutageHours measures how long the monitors of each outage group of
+// region were offline in [from, to), averaged per monitor. Like the public
+// map, it counts only public, active monitors of owners who aren't banned. A
+// monitor's state at from is its last event before it (online if none).
+// Canaries and monitors without a group are left out.
+func (db *DB) GetGroupOutageHours(ctx context.Context, region string, from, to time.Time) ([]GroupOutageHours, error) {
+	rows, err := db.Pool.Query(ctx, `
+		WITH m AS (
+			SELECT id, outage_group FROM monitors
+			WHERE `+publicFilter+` AND `+notBannedOwner+` AND is_canary = FALSE
+			  AND outage_group != '' AND outage_region = $1
+		), ev AS (
+			SELECT m.id, $2::timestamptz AS ts,
+			       COALESCE((SELECT e.is_online FROM status_events e
+			                 WHERE e.monitor_id = m.id AND e.timestamp < $2
+			                 ORDER BY e.timestamp DESC LIMIT 1), TRUE) AS is_online
+			FROM m
+			UNION ALL
+			SELECT e.monitor_id, e.timestamp, e.is_online
+			FROM status_events e JOIN m ON m.id = e.monitor_id
+			WHERE e.timestamp >= $2 AND e.timestamp < $3
+		), spans AS (
+			SELECT id, is_online, LEAD(ts, 1, $3::timestamptz) OVER (PARTITION BY id ORDER BY ts) - ts AS dur
+			FROM ev
+		)
+		SELECT m.outage_group,
+		       COUNT(DISTINCT m.id)::int AS monitors,
+		       (COALESCE(EXTRACT(EPOCH FROM SUM(s.dur) FILTER (WHERE NOT s.is_online)), 0)
+		        / 3600 / COUNT(DISTINCT m.id))::float8 AS offline_hours
+		FROM m JOIN spans s ON s.id = m.id
+		GROUP BY m.outage_group
+		ORDER BY m.outage_group
+	`, region, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[GroupOutageHours])
+}
+
+// GetOpenIncidents returns the ongoing incidents.
+func (db *DB) GetOpenIncidents(ctx context.Context) ([]*models.Incident, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+incidentColumns+` FROM incidents WHERE ended_at IS NULL ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[models.Incident])
+}
+
+// OpenIncident starts an incident for an outage group.
+func (db *DB) OpenIncident(ctx context.Context, region, group string, affected, total int, at time.Time) (*models.Incident, error) {
+	rows, err := db.Pool.Query(ctx, `
+		INSERT INTO incidents (region, outage_group, started_at, affected, total, peak)
+		VALUES ($1, $2, $3, $4, $5, $4)
+		RETURNING `+incidentColumns+`
+	`, region, group, at, affected, total)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.Incident])
+}
+
+// UpdateIncidentCounts stores the latest counts of an ongoing incident and raises its peak.
+func (db *DB) UpdateIncidentCounts(ctx context.Context, id int64, affected, total int) error {
+	_, err := db.Pool.Exec(ctx, `
+		UPDATE incidents SET affected = $2, total = $3, peak = GREATEST(peak, $2) WHERE id = $1
+	`, id, affected, total)
+	return err
+}
+
+// CloseIncident ends an incident. An incident whose start was never posted is
+// closed as posted on both ends, so a short blip never reaches the channel.
+func (db *DB) CloseIncident(ctx context.Context, id int64, at time.Time) error {
+	_, err := db.Pool.Exec(ctx, `
+		UPDATE incidents
+		SET ended_at = $2, end_posted = NOT start_posted, start_posted = TRUE
+		WHERE id = $1 AND ended_at IS NULL
+	`, id, at)
+	return err
+}
+
+// GetUnpostedIncidents returns incidents of a region with a start or end not yet posted.
+func (db *DB) GetUnpostedIncidents(ctx context.Context, region string) ([]*models.Incident, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+incidentColumns+` FROM incidents
+		WHERE region = $1 AND (NOT start_posted OR (ended_at IS NOT NULL AND NOT end_posted))
+		ORDER BY started_at
+	`, region)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[models.Incident])
+}
+
+// MarkIncidentsPosted flags the given incidents' current state as posted.
+func (db *DB) MarkIncidentsPosted(ctx context.Context, ids []int64) error {
+	_, err := db.Pool.Exec(ctx, `
+		UPDATE incidents SET start_posted = TRUE, end_posted = (ended_at IS NOT NULL) WHERE id = ANY($1)
+	`, ids)
+	return err
+}
+
+// GroupVote is how many monitors near a location use one outage group.
+type GroupVote struct {
+	Region      string `db:"region"`
+	OutageGroup string `db:"outage_group"`
+	Monitors    int    `db:"monitors"`
+}
+
+// GetNearbyOutageGroups counts the outage groups of monitors within roughly
+// radiusM metres of a location (a lat/lng box, not a circle), most used
+// first. Canaries, monitors without a group and excludeID are left out.
+func (db *DB) GetNearbyOutageGroups(ctx context.Context, lat, lng, radiusM float64, excludeID int64) ([]GroupVote, error) {
+	dLat := radiusM / 111320
+	dLng := dLat / math.Max(math.Cos(lat*math.Pi/180), 0.01)
+	rows, err := db.Pool.Query(ctx, `
+		SELECT outage_region AS region, outage_group, COUNT(*) AS monitors
+		FROM monitors
+		WHERE deleted_at IS NULL AND is_canary = FALSE AND id != $5
+		  AND outage_group != ''
+		  AND latitude BETWEEN $1::float8 - $3 AND $1::float8 + $3
+		  AND longitude BETWEEN $2::float8 - $4 AND $2::float8 + $4
+		GROUP BY outage_region, outage_group
+		ORDER BY monitors DESC, outage_region, outage_group
+	`, lat, lng, dLat, dLng, excludeID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[GroupVote])
+}
+
+// GetIncidents returns the incidents that overlap [from, to), ongoing ones
+// included, newest first and at most limit of them.
+func (db *DB) GetIncidents(ctx context.Context, from, to time.Time, limit int) ([]*models.Incident, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+incidentColumns+` FROM incidents
+		WHERE started_at < $2 AND (ended_at IS NULL OR ended_at >= $1)
+		ORDER BY started_at DESC
+		LIMIT $3
+	`, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[models.Incident])
+}
+
+// GroupBounds is the bounding box of the public monitors of one outage group.
+type GroupBounds struct {
+	Region      string  `db:"region"`
+	OutageGroup string  `db:"outage_group"`
+	Monitors    int     `db:"monitors"`
+	MinLat      float64 `db:"min_lat"`
+	MinLng      float64 `db:"min_lng"`
+	MaxLat      float64 `db:"max_lat"`
+	MaxLng      float64 `db:"max_lng"`
+}
+
+// GetOutageGroupBounds returns the bounding box of each outage group's
+// current public monitors, for groups with at least minMonitors of them so a
+// box never outlines a single home. Like the public map, only public, active
+// monitors of owners who aren't banned count; canaries and monitors without a
+// location are left out.
+func (db *DB) GetOutageGroupBounds(ctx context.Context, minMonitors int) ([]GroupBounds, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT outage_region AS region, outage_group, COUNT(*)::int AS monitors,
+		       MIN(latitude) AS min_lat, MIN(longitude) AS min_lng,
+		       MAX(latitude) AS max_lat, MAX(longitude) AS max_lng
+		FROM monitors
+		WHERE `+publicFilter+` AND `+notBannedOwner+` AND is_canary = FALSE
+		  AND outage_group != '' AND (latitude != 0 OR longitude != 0)
+		GROUP BY outage_region, outage_group
+		HAVING COUNT(*) >= $1
+	`, minMonitors)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[GroupBounds])
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"no-lights-monitor/internal/models"
+)
+
+// ── Scheduled jobs ───────────────────────────────────────────────────
+
+// RegisterScheduledJob creates the bookkeeping row for a job (or updates its schedule)
+// and returns the stored state.
+func (db *DB) RegisterScheduledJob(ctx context.Context, name, schedule string) (*models.ScheduledJob, error) {
+	rows, err := db.Pool.Query(ctx, `
+		INSERT INTO scheduled_jobs (name, schedule)
+		VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET schedule = $2
+		RETURNING `+scheduledJobColumns+`
+	`, name, schedule)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.ScheduledJob])
+}
+
+// AcquireJobLock takes the job's lease for owner if nobody else holds an unexpired one.
+// Returns false when another instance is already running the job.
+func (db *DB) AcquireJobLock(ctx context.Context, name, owner string, lease time.Duration) (bool, error) {
+	tag, err := db.Pool.Exec(ctx, `
+		UPDATE scheduled_jobs
+		SET locked_by = $2, locked_until = NOW() + $3::interval, last_started_at = NOW()
+		WHERE name = $1 AND (locked_until IS NULL OR locked_until < NOW() OR locked_by = $2)
+	`, name, owner, lease)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// FinishJobRun releases the job's lease and records the outcome of the run.
+func (db *DB) FinishJobRun(ctx context.Context, name, owner string, duration time.Duration, runErr string, nextRun time.Time) error {
+	_, err := db.Pool.Exec(ctx, `
+		UPDATE scheduled_jobs
+		SET locked_by = '', locked_until = NULL, last_finished_at = NOW(),
+		    last_duration_ms = $3, last_error = $4, run_count = run_count + 1, next_run_at = $5
+		WHERE name = $1 AND locked_by = $2
+	`, name, owner, duration.Milliseconds(), runErr, nextRun)
+	return err
+}
+
+// ReleaseJobLock releases the job's lease without recording a run, for runs
+// that were skipped.
+func (db *DB) ReleaseJobLock(ctx context.Context, name, owner string, nextRun time.Time) error {
+	_, err := db.Pool.Exec(ctx, `
+		UPDATE scheduled_jobs
+		SET locked_by = '', locked_until = NULL, next_run_at = $3
+		WHERE name = $1 AND locked_by = $2
+	`, name, owner, nextRun)
+	return err
+}
+
+// SetJobNextRun records when the job is next due (for admin visibility).
+func (db *DB) SetJobNextRun(ctx context.Context, name string, nextRun time.Time) error {
+	_, err := db.Pool.Exec(ctx, `UPDATE scheduled_jobs SET next_run_at = $2 WHERE name = $1`, name, nextRun)
+	return err
+}
+
+// GetScheduledJobs returns the bookkeeping rows of all known jobs.
+func (db *DB) GetScheduledJobs(ctx context.Context) ([]*models.ScheduledJob, error) {
+	rows, err := db.Pool.Query(ctx, `SELECT `+scheduledJobColumns+` FROM scheduled_jobs ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[models.ScheduledJob])
+}
@@ -0,0 +1,68 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"no-lights-monitor/internal/models"
+)
+
+// ── Ping latency ─────────────────────────────────────────────────────
+
+// InsertPingSample stores the outcome of one ping round of a ping monitor.
+// rtt is the median round trip, 0 when no reply came back.
+func (db *DB) InsertPingSample(ctx context.Context, monitorID int64, at time.Time, rtt time.Duration, loss float64) error {
+	var rttMs *float64
+	if rtt > 0 {
+		ms := float64(rtt) / float64(time.Millisecond)
+		rttMs = &ms
+	}
+	_, err := db.Pool.Exec(ctx, `
+		INSERT INTO ping_samples (monitor_id, sampled_at, rtt_ms, loss) VALUES ($1, $2, $3, $4)
+	`, monitorID, at, rttMs, loss)
+	return err
+}
+
+// GetLatencyHourly returns a monitor's ping samples in [from, to) aggregated
+// per hour, oldest first. Hours without samples are left out.
+func (db *DB) GetLatencyHourly(ctx context.Context, monitorID int64, from, to time.Time) ([]models.LatencyPoint, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT date_trunc('hour', sampled_at) AS hour,
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY rtt_ms) AS rtt_ms,
+			avg(loss)::float8 AS loss,
+			count(*)::int AS probes
+		FROM ping_samples
+		WHERE monitor_id = $1 AND sampled_at >= $2 AND sampled_at < $3
+		GROUP BY 1 ORDER BY 1
+	`, monitorID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[models.LatencyPoint])
+}
+
+// DeletePingSamplesBefore drops ping samples older than before and returns
+// how many were removed.
+func (db *DB) DeletePingSamplesBefore(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := db.Pool.Exec(ctx, `DELETE FROM ping_samples WHERE sampled_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// SetMonitorLatencyGraphEnabled toggles whether a ping monitor's latency graph is posted to the channel.
+func (db *DB) SetMonitorLatencyGraphEnabled(ctx context.Context, id int64, enabled bool) error {
+	_, err := db.Pool.Exec(ctx, `UPDATE monitors SET latency_graph_enabled = $2 WHERE id = $1`, id, enabled)
+	return err
+}
+
+// UpdateLatencyGraphMessage stores the Telegram message ID and week start for the current latency graph.
+func (db *DB) UpdateLatencyGraphMessage(ctx context.Context, monitorID int64, messageID int, weekStart time.Time) error {
+	_, err := db.Pool.Exec(ctx, `
+		UPDATE monitors SET latency_graph_message_id = $2, latency_graph_week_start = $3 WHERE id = $1
+	`, monitorID, messageID, weekStart)
+	return err
+}
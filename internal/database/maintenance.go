@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+
+	"no-lights-monitor/internal/models"
+)
+
+// ── Maintenance windows ──────────────────────────────────────────────
+
+const maintenanceWindowColumns = `id, monitor_id, start_min, end_min, created_at`
+
+// GetMaintenanceWindows returns the maintenance windows of all live monitors,
+// grouped by monitor ID.
+func (db *DB) GetMaintenanceWindows(ctx context.Context) (map[int64][]models.MaintenanceWindow, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT w.id, w.monitor_id, w.start_min, w.end_min, w.created_at
+		FROM maintenance_windows w JOIN monitors m ON m.id = w.monitor_id
+		WHERE m.deleted_at IS NULL
+		ORDER BY w.monitor_id, w.start_min
+	`)
+	if err != nil {
+		return nil, err
+	}
+	list, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.MaintenanceWindow])
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[int64][]models.MaintenanceWindow)
+	for _, w := range list {
+		out[w.MonitorID] = append(out[w.MonitorID], w)
+	}
+	return out, nil
+}
+
+// GetMonitorMaintenanceWindows returns the maintenance windows of a monitor.
+func (db *DB) GetMonitorMaintenanceWindows(ctx context.Context, monitorID int64) ([]models.MaintenanceWindow, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+maintenanceWindowColumns+` FROM maintenance_windows
+		WHERE monitor_id = $1 ORDER BY start_min
+	`, monitorID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[models.MaintenanceWindow])
+}
+
+// SetMonitorMaintenanceWindows replaces the maintenance windows of a monitor
+// (none removes them all).
+func (db *DB) SetMonitorMaintenanceWindows(ctx context.Context, monitorID int64, windows []models.MaintenanceWindow) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM maintenance_windows WHERE monitor_id = $1`, monitorID); err != nil {
+		return err
+	}
+	for _, w := range windows {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO maintenance_windows (monitor_id, start_min, end_min) VALUES ($1, $2, $3)
+		`, monitorID, w.StartMin, w.EndMin); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
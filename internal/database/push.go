@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+
+	"no-lights-monitor/internal/models"
+)
+
+// ── Push devices ─────────────────────────────────────────────────────
+
+// RegisterPushDevice subscribes a companion app token to a monitor, or
+// refreshes last_seen_at if it is already subscribed. A new token is refused
+// (false) once the monitor has maxDevices tokens.
+func (db *DB) RegisterPushDevice(ctx context.Context, monitorID int64, platform, token string, maxDevices int) (bool, error) {
+	tag, err := db.Pool.Exec(ctx, `
+		UPDATE push_devices SET last_seen_at = NOW()
+		WHERE monitor_id = $1 AND platform = $2 AND token = $3
+	`, monitorID, platform, token)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() > 0 {
+		return true, nil
+	}
+	tag, err = db.Pool.Exec(ctx, `
+		INSERT INTO push_devices (monitor_id, platform, token)
+		SELECT $1, $2, $3
+		WHERE (SELECT COUNT(*) FROM push_devices WHERE monitor_id = $1) < $4
+		ON CONFLICT DO NOTHING
+	`, monitorID, platform, token, maxDevices)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// DeletePushDevice unsubscribes a token from one monitor.
+func (db *DB) DeletePushDevice(ctx context.Context, monitorID int64, platform, token string) error {
+	_, err := db.Pool.Exec(ctx, `
+		DELETE FROM push_devices WHERE monitor_id = $1 AND platform = $2 AND token = $3
+	`, monitorID, platform, token)
+	return err
+}
+
+// DeletePushToken unsubscribes a token from all monitors, e.g. after the push
+// service reported the app was uninstalled.
+func (db *DB) DeletePushToken(ctx context.Context, platform, token string) error {
+	_, err := db.Pool.Exec(ctx, `DELETE FROM push_devices WHERE platform = $1 AND token = $2`, platform, token)
+	return err
+}
+
+// GetPushDevices returns the tokens subscribed to a monitor.
+func (db *DB) GetPushDevices(ctx context.Context, monitorID int64) ([]models.PushDevice, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT monitor_id, platform, token, created_at, last_seen_at
+		FROM push_devices WHERE monitor_id = $1
+	`, monitorID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[models.PushDevice])
+}
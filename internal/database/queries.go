@@ -0,0 +1,127 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ── Hot listings ─────────────────────────────────────────────────────
+
+// The queries below run on every map refresh and every worker cycle. Each is
+// served by a partial index created in Migrate (predicates written the same
+// way so the planner can match them); CheckQueryPlans guards against a change
+// that silently turns one of them into a table scan.
+
+// notBannedOwner filters monitor queries down to monitors whose owner is not banned.
+// The anti-join only touches users by primary key.
+const notBannedOwner = `NOT EXISTS (SELECT 1 FROM users b WHERE b.id = monitors.user_id AND b.banned_at IS NOT NULL)`
+
+// publicFilter matches idx_monitors_public and idx_monitors_public_changed.
+const publicFilter = `is_public AND is_active AND deleted_at IS NULL`
+
+const publicMonitorsSQL = `
+	SELECT ` + monitorColumns + ` FROM monitors
+	WHERE ` + publicFilter + ` AND ` + notBannedOwner + `
+	ORDER BY id`
+
+const publicMonitorsByIDsSQL = `
+	SELECT ` + monitorColumns + ` FROM monitors
+	WHERE id = ANY($1) AND ` + publicFilter + ` AND ` + notBannedOwner + `
+	ORDER BY id`
+
+const publicMonitorsChangedSinceSQL = `
+	SELECT ` + monitorColumns + ` FROM monitors
+	WHERE last_status_change_at >= $1 AND ` + publicFilter + ` AND ` + notBannedOwner + `
+	ORDER BY id`
+
+// monitorsHiddenSinceSQL matches idx_monitor_changes_visibility. The changes
+// only name candidates; a monitor counts as hidden if it isn't public now.
+const monitorsHiddenSinceSQL = `
+	SELECT DISTINCT c.monitor_id FROM monitor_changes c
+	WHERE c.created_at >= $1
+	  AND c.field IN ('is_public', 'is_active', 'deleted', 'owner_banned')
+	  AND NOT EXISTS (
+		SELECT 1 FROM monitors
+		WHERE monitors.id = c.monitor_id AND ` + publicFilter + ` AND ` + notBannedOwner + `
+	  )
+	ORDER BY c.monitor_id`
+
+// monitorsWithChannelsSQL matches idx_monitors_with_channels.
+const monitorsWithChannelsSQL = `
+	SELECT ` + monitorColumns + ` FROM monitors
+	WHERE channel_id IS NOT NULL AND channel_id <> 0 AND is_active AND deleted_at IS NULL
+	ORDER BY id`
+
+// hotQuery is a statement checked by CheckQueryPlans, with sample arguments.
+type hotQuery struct {
+	name string
+	sql  string
+	args []any
+}
+
+// hotQueries are the statements checked by CheckQueryPlans.
+var hotQueries = []hotQuery{
+	{"GetPublicMonitors", publicMonitorsSQL, nil},
+	{"GetPublicMonitorsByIDs", publicMonitorsByIDsSQL, []any{[]int64{1, 2, 3}}},
+	{"GetPublicMonitorsChangedSince", publicMonitorsChangedSinceSQL, []any{time.Unix(0, 0)}},
+	{"GetMonitorsHiddenSince", monitorsHiddenSinceSQL, []any{time.Unix(0, 0)}},
+	{"GetMonitorsWithChannels", monitorsWithChannelsSQL, nil},
+}
+
+// QueryPlan is the EXPLAIN result of one hot query.
+type QueryPlan struct {
+	Name     string `json:"name"`
+	Plan     string `json:"plan"`
+	SeqScans bool   `json:"seq_scans"` // the plan reads monitors without an index
+}
+
+// CheckQueryPlans EXPLAINs the hot listings with sequential scans disabled, so
+// the planner only falls back to one when no index can serve the query — on
+// any table size, including an empty dev database. A plan with "Seq Scan on
+// monitors" is a regression: a changed WHERE clause no longer matches its
+// partial index.
+func (db *DB) CheckQueryPlans(ctx context.Context) ([]QueryPlan, error) {
+	return db.explainQueries(ctx, hotQueries)
+}
+
+// explainQueries EXPLAINs queries the way CheckQueryPlans describes.
+func (db *DB) explainQueries(ctx context.Context, queries []hotQuery) ([]QueryPlan, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SET LOCAL enable_seqscan = off`); err != nil {
+		return nil, err
+	}
+	plans := make([]QueryPlan, 0, len(queries))
+	for _, q := range queries {
+		rows, err := tx.Query(ctx, "EXPLAIN "+q.sql, q.args...)
+		if err != nil {
+			return nil, fmt.Errorf("explain %s: %w", q.name, err)
+		}
+		var lines []string
+		for rows.Next() {
+			var line string
+			if err := rows.Scan(&line); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("explain %s: %w", q.name, err)
+			}
+			lines = append(lines, line)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("explain %s: %w", q.name, err)
+		}
+		plan := strings.Join(lines, "\n")
+		plans = append(plans, QueryPlan{
+			Name:     q.name,
+			Plan:     plan,
+			SeqScans: strings.Contains(plan, "Seq Scan on monitors"),
+		})
+	}
+	return plans, nil
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+// testDB connects to TEST_DATABASE_URL and migrates it; the test is skipped
+// when the variable is unset. Use a throwaway database: Migrate creates the
+// full schema in it.
+func testDB(t *testing.T) *DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	db, err := New(ctx, url, 0)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(db.Close)
+	if err := db.Migrate(ctx); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func TestCheckQueryPlansUseIndexes(t *testing.T) {
+	db := testDB(t)
+	plans, err := db.CheckQueryPlans(context.Background())
+	if err != nil {
+		t.Fatalf("CheckQueryPlans: %v", err)
+	}
+	if len(plans) != len(hotQueries) {
+		t.Fatalf("got %d plans, want %d", len(plans), len(hotQueries))
+	}
+	for _, p := range plans {
+		if p.SeqScans {
+			t.Errorf("%s falls back to a sequential scan of monitors:\n%s", p.Name, p.Plan)
+		}
+	}
+}
+
+func TestCheckQueryPlansFlagsSeqScan(t *testing.T) {
+	db := testDB(t)
+	// No index covers channel_name, so the planner has to scan the table.
+	unindexed := hotQuery{"unindexed", `SELECT id FROM monitors WHERE channel_name = $1`, []any{"x"}}
+	plans, err := db.explainQueries(context.Background(), []hotQuery{unindexed})
+	if err != nil {
+		t.Fatalf("explainQueries: %v", err)
+	}
+	if len(plans) != 1 || !plans[0].SeqScans {
+		t.Errorf("sequential scan not flagged:\n%+v", plans)
+	}
+}
@@ -0,0 +1,144 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ── Batched settings updates ─────────────────────────────────────────
+
+// monitorSettingsChannel is the Postgres NOTIFY channel of saved settings;
+// the payload is the monitor ID.
+const monitorSettingsChannel = "monitor_settings"
+
+// listenRetry is the pause before re-listening after a lost connection.
+const listenRetry = 5 * time.Second
+
+// MonitorSettings collects the changed settings of one monitor, so that a
+// save of the settings page is applied by UpdateMonitorSettings at once.
+type MonitorSettings struct {
+	columns []string
+	values  []any
+	changes []settingChange
+}
+
+// settingChange is one audit record of a MonitorSettings.
+type settingChange struct {
+	field, oldValue, newValue string
+}
+
+// Set assigns value to a monitors column. Columns are names from code, never
+// user input.
+func (s *MonitorSettings) Set(column string, value any) {
+	s.columns = append(s.columns, column)
+	s.values = append(s.values, value)
+}
+
+// Record adds the audit record of a changed field, like RecordMonitorChange.
+func (s *MonitorSettings) Record(field string, oldValue, newValue any) {
+	s.changes = append(s.changes, settingChange{field, fmt.Sprint(oldValue), fmt.Sprint(newValue)})
+}
+
+// Empty reports whether no column is set.
+func (s *MonitorSettings) Empty() bool {
+	return len(s.columns) == 0
+}
+
+// UpdateMonitorSettings applies s to the monitor in one transaction: a single
+// UPDATE of all set columns and a single INSERT of their audit records with
+// source. On commit
+// one monitor_settings notification tells the workers to reload the monitor
+// right away instead of at their next change sync.
+func (db *DB) UpdateMonitorSettings(ctx context.Context, monitorID int64, source string, s *MonitorSettings) error {
+	if s.Empty() {
+		return nil
+	}
+
+	assignments := make([]string, len(s.columns))
+	for i, col := range s.columns {
+		assignments[i] = fmt.Sprintf("%s = $%d", col, i+2)
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `UPDATE monitors SET `+strings.Join(assignments, ", ")+` WHERE id = $1`,
+		append([]any{monitorID}, s.values...)...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	if len(s.changes) > 0 {
+		fields := make([]string, len(s.changes))
+		oldValues := make([]string, len(s.changes))
+		newValues := make([]string, len(s.changes))
+		for i, ch := range s.changes {
+			fields[i], oldValues[i], newValues[i] = ch.field, ch.oldValue, ch.newValue
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO monitor_changes (monitor_id, source, field, old_value, new_value)
+			SELECT $1, $2, c.field, c.old_value, c.new_value
+			FROM unnest($3::text[], $4::text[], $5::text[]) WITH ORDINALITY AS c(field, old_value, new_value, n)
+			ORDER BY c.n
+		`, monitorID, source, fields, oldValues, newValues); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(ctx, `SELECT pg_notify($1, $2)`, monitorSettingsChannel, strconv.FormatInt(monitorID, 10)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ListenMonitorSettings calls fn with the monitor ID of every committed
+// UpdateMonitorSettings until ctx is done. It holds one pool connection and
+// re-listens after losing it; fn gets 0 then, since saves may have been missed.
+func (db *DB) ListenMonitorSettings(ctx context.Context, fn func(monitorID int64)) {
+	for ctx.Err() == nil {
+		err := db.listenMonitorSettings(ctx, fn)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("[db] listen %s: %v", monitorSettingsChannel, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetry):
+		}
+		fn(0)
+	}
+}
+
+// listenMonitorSettings serves one LISTEN connection until it fails.
+func (db *DB) listenMonitorSettings(ctx context.Context, fn func(monitorID int64)) error {
+	pooled, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// A connection left in LISTEN mode must not go back to the pool.
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+monitorSettingsChannel); err != nil {
+		return err
+	}
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		id, _ := strconv.ParseInt(n.Payload, 10, 64)
+		fn(id)
+	}
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ── SMS ──────────────────────────────────────────────────────────────
+
+// SetMonitorSMSEnabled toggles SMS copies of status changes.
+func (db *DB) SetMonitorSMSEnabled(ctx context.Context, id int64, enabled bool) error {
+	_, err := db.Pool.Exec(ctx, `UPDATE monitors SET sms_enabled = $2 WHERE id = $1`, id, enabled)
+	return err
+}
+
+// ReserveSMS counts n texts of a monitor against the month's quotas: at most
+// perMonitor for the monitor and budget for all monitors together. Nothing is
+// counted and false is returned when either would be exceeded. The budget
+// check is not serialized, so concurrent reservations may overshoot it by a
+// few texts.
+func (db *DB) ReserveSMS(ctx context.Context, monitorID int64, month time.Time, n, perMonitor, budget int) (bool, error) {
+	var sent int
+	err := db.Pool.QueryRow(ctx, `
+		INSERT INTO sms_usage AS u (monitor_id, month, sent)
+		SELECT $1, $2, $3
+		WHERE $3 <= $4 AND (SELECT COALESCE(SUM(sent), 0) FROM sms_usage WHERE month = $2) + $3 <= $5
+		ON CONFLICT (monitor_id, month) DO UPDATE SET sent = u.sent + $3
+		WHERE u.sent + $3 <= $4 AND (SELECT COALESCE(SUM(sent), 0) FROM sms_usage WHERE month = $2) + $3 <= $5
+		RETURNING sent
+	`, monitorID, month, n, perMonitor, budget).Scan(&sent)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// GetSMSUsage returns how many texts a monitor sent in the given month.
+func (db *DB) GetSMSUsage(ctx context.Context, monitorID int64, month time.Time) (int, error) {
+	var sent int
+	err := db.Pool.QueryRow(ctx, `
+		SELECT sent FROM sms_usage WHERE monitor_id = $1 AND month = $2
+	`, monitorID, month).Scan(&sent)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	return sent, err
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"no-lights-monitor/internal/models"
+)
+
+// ── Sponsor messages ─────────────────────────────────────────────────
+
+const sponsorMessageColumns = `id, message, link_url, every_weeks, starts_at, ends_at, created_at`
+
+// GetActiveSponsorMessage returns the newest sponsor message scheduled at the
+// given time, or nil when there is none.
+func (db *DB) GetActiveSponsorMessage(ctx context.Context, now time.Time) (*models.SponsorMessage, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+sponsorMessageColumns+` FROM sponsor_messages
+		WHERE starts_at <= $1 AND (ends_at IS NULL OR ends_at > $1)
+		ORDER BY starts_at DESC, id DESC
+		LIMIT 1
+	`, now)
+	if err != nil {
+		return nil, err
+	}
+	s, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[models.SponsorMessage])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return s, err
+}
+
+// GetSponsorMessages returns every sponsor message, including scheduled and expired ones.
+func (db *DB) GetSponsorMessages(ctx context.Context) ([]models.SponsorMessage, error) {
+	rows, err := db.Pool.Query(ctx, `SELECT `+sponsorMessageColumns+` FROM sponsor_messages ORDER BY id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[models.SponsorMessage])
+}
+
+// CreateSponsorMessage stores a new sponsor message and returns it.
+func (db *DB) CreateSponsorMessage(ctx context.Context, s models.SponsorMessage) (models.SponsorMessage, error) {
+	rows, err := db.Pool.Query(ctx, `
+		INSERT INTO sponsor_messages (message, link_url, every_weeks, starts_at, ends_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+sponsorMessageColumns,
+		s.Message, s.LinkURL, s.EveryWeeks, s.StartsAt, s.EndsAt)
+	if err != nil {
+		return models.SponsorMessage{}, err
+	}
+	return pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[models.SponsorMessage])
+}
+
+// DeleteSponsorMessage removes a sponsor message. Returns false if it didn't exist.
+func (db *DB) DeleteSponsorMessage(ctx context.Context, id int64) (bool, error) {
+	tag, err := db.Pool.Exec(ctx, `DELETE FROM sponsor_messages WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"no-lights-monitor/internal/errsink"
+	"no-lights-monitor/internal/metrics"
+)
+
+// maxTracedSQL caps the query text attached to error reports and slow-query logs.
+const maxTracedSQL = 200
+
+type traceKey struct{}
+
+type traceData struct {
+	sql   string
+	args  []any
+	start time.Time
+}
+
+// queryTracer times every query into the DB query histogram, logs queries
+// slower than slow (0 disables the log) and reports failed queries to the
+// error sink. Expected outcomes (no rows, cancelled context) are not errors
+// and are not reported. Query parameters are never logged, only their types.
+type queryTracer struct {
+	slow time.Duration
+}
+
+func (queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, traceKey{}, &traceData{sql: data.SQL, args: data.Args, start: time.Now()})
+}
+
+func (t queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	td, _ := ctx.Value(traceKey{}).(*traceData)
+	if td == nil {
+		return
+	}
+	elapsed := time.Since(td.start)
+	name := queryName(td.sql)
+	metrics.DBQueryDuration.WithLabelValues(name).Observe(elapsed.Seconds())
+
+	if t.slow > 0 && elapsed >= t.slow {
+		metrics.DBSlowQueries.WithLabelValues(name).Inc()
+		log.Printf("[db] slow query (%s, %d ms): %s args=%s", name, elapsed.Milliseconds(), compactSQL(td.sql), redactArgs(td.args))
+	}
+
+	err := data.Err
+	if err == nil || errors.Is(err, pgx.ErrNoRows) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+	errsink.Capture(err, errsink.Fields{"component": "database", "sql": compactSQL(td.sql)})
+}
+
+var (
+	sqlSpace = regexp.MustCompile(`\s+`)
+	// sqlTable finds the first table a statement reads or writes.
+	sqlTable = regexp.MustCompile(`(?i)\b(?:from|into|update)\s+([a-z_][a-z0-9_]*)`)
+)
+
+// queryName is the low-cardinality metric label of a statement, e.g.
+// "select monitors" or "update users".
+func queryName(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "unknown"
+	}
+	op := strings.ToLower(fields[0])
+	switch op {
+	case "select", "insert", "update", "delete", "with":
+	default:
+		return op
+	}
+	if m := sqlTable.FindStringSubmatch(sql); m != nil {
+		return op + " " + strings.ToLower(m[1])
+	}
+	return op
+}
+
+// compactSQL collapses whitespace and truncates sql to maxTracedSQL bytes.
+func compactSQL(sql string) string {
+	sql = strings.TrimSpace(sqlSpace.ReplaceAllString(sql, " "))
+	if len(sql) > maxTracedSQL {
+		sql = sql[:maxTracedSQL] + "…"
+	}
+	return sql
+}
+
+// redactArgs renders query parameters as their Go types only, e.g. "[int64 string]".
+func redactArgs(args []any) string {
+	types := make([]string, len(args))
+	for i, a := range args {
+		types[i] = fmt.Sprintf("%T", a)
+	}
+	return "[" + strings.Join(types, " ") + "]"
+}
@@ -0,0 +1,202 @@
+// Package errsink forwards errors and panics to an external aggregator.
+// It speaks the Sentry store protocol (configure with a standard Sentry DSN,
+// or any service accepting it, e.g. GlitchTip) using only the standard library.
+// Without a DSN every call is a no-op, so callers never need to check.
+//
+// Events are queued and sent from a single background goroutine; when the
+// queue is full new events are dropped rather than slowing the caller down.
+package errsink
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// queueSize bounds the number of events waiting to be sent.
+const queueSize = 256
+
+// Fields attach context to an event (monitor_id, user_id, queue, ...).
+// They are sent as Sentry tags, so keep values short.
+type Fields map[string]any
+
+type event struct {
+	EventID    string            `json:"event_id"`
+	Timestamp  string            `json:"timestamp"`
+	Level      string            `json:"level"`
+	Logger     string            `json:"logger"`
+	Platform   string            `json:"platform"`
+	ServerName string            `json:"server_name,omitempty"`
+	Release    string            `json:"release,omitempty"`
+	Message    string            `json:"message,omitempty"`
+	Tags       map[string]string `json:"tags,omitempty"`
+	Extra      map[string]any    `json:"extra,omitempty"`
+	Exception  []exception       `json:"exception,omitempty"`
+}
+
+type exception struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sink struct {
+	endpoint string
+	auth     string
+	service  string
+	host     string
+	client   *http.Client
+	queue    chan *event
+}
+
+var (
+	mu      sync.RWMutex
+	current *sink
+)
+
+// Init configures the sink from a Sentry DSN (https://<key>@<host>/<project>).
+// service names the binary (api, bot, worker) and is attached to every event.
+// An empty DSN leaves the sink disabled.
+func Init(dsn, service string) error {
+	if dsn == "" {
+		return nil
+	}
+	u, err := url.Parse(dsn)
+	if err != nil || u.User == nil || u.Host == "" {
+		return fmt.Errorf("invalid error sink DSN")
+	}
+	project := strings.Trim(u.Path, "/")
+	if project == "" {
+		return fmt.Errorf("invalid error sink DSN: missing project id")
+	}
+	host, _ := os.Hostname()
+	s := &sink{
+		endpoint: fmt.Sprintf("%s://%s/api/%s/store/", u.Scheme, u.Host, project),
+		auth:     fmt.Sprintf("Sentry sentry_version=7, sentry_client=nlm-errsink/1.0, sentry_key=%s", u.User.Username()),
+		service:  service,
+		host:     host,
+		client:   &http.Client{Timeout: 5 * time.Second},
+		queue:    make(chan *event, queueSize),
+	}
+	go s.run()
+
+	mu.Lock()
+	current = s
+	mu.Unlock()
+	log.Printf("[errsink] enabled for %s", service)
+	return nil
+}
+
+// Capture reports err with optional context. Nil errors are ignored.
+func Capture(err error, fields Fields) {
+	if err == nil {
+		return
+	}
+	enqueue("error", fmt.Sprintf("%T", err), err.Error(), fields, nil)
+}
+
+// CaptureMessage reports a condition that is not a Go error.
+func CaptureMessage(msg string, fields Fields) {
+	enqueue("warning", "", msg, fields, nil)
+}
+
+// CapturePanic reports a recovered panic value together with the current stack.
+// Call it from a deferred recover.
+func CapturePanic(recovered any, fields Fields) {
+	enqueue("fatal", "panic", fmt.Sprint(recovered), fields, debug.Stack())
+}
+
+// Flush waits up to timeout for queued events to be sent. Call before exit.
+func Flush(timeout time.Duration) {
+	mu.RLock()
+	s := current
+	mu.RUnlock()
+	if s == nil {
+		return
+	}
+	deadline := time.Now().Add(timeout)
+	for len(s.queue) > 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func enqueue(level, typ, value string, fields Fields, stack []byte) {
+	mu.RLock()
+	s := current
+	mu.RUnlock()
+	if s == nil {
+		return
+	}
+
+	ev := &event{
+		EventID:    newEventID(),
+		Timestamp:  time.Now().UTC().Format(time.RFC3339),
+		Level:      level,
+		Logger:     s.service,
+		Platform:   "go",
+		ServerName: s.host,
+		Tags:       map[string]string{"service": s.service},
+	}
+	for k, v := range fields {
+		ev.Tags[k] = fmt.Sprint(v)
+	}
+	if typ != "" {
+		ev.Exception = []exception{{Type: typ, Value: value}}
+	} else {
+		ev.Message = value
+	}
+	if stack != nil {
+		ev.Extra = map[string]any{"stack": string(stack)}
+	}
+
+	select {
+	case s.queue <- ev:
+	default:
+		// Queue full: drop rather than block the caller.
+	}
+}
+
+func (s *sink) run() {
+	for ev := range s.queue {
+		if err := s.send(ev); err != nil {
+			log.Printf("[errsink] send failed: %v", err)
+		}
+	}
+}
+
+func (s *sink) send(ev *event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
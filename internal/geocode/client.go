@@ -0,0 +1,127 @@
+package geocode
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"no-lights-monitor/internal/metrics"
+)
+
+const (
+	// Found addresses rarely move; misses are retried sooner in case the
+	// map data gets fixed.
+	hitTTL  = 7 * 24 * time.Hour
+	missTTL = 24 * time.Hour
+
+	// candidates is how many addresses are fetched (and cached) per query;
+	// the best one is used when a single address is needed.
+	candidates = 5
+)
+
+// Cache is the Redis state shared by every service that geocodes: cached
+// results and the Nominatim request throttle. Implemented by *cache.Cache.
+type Cache interface {
+	GetGeocode(ctx context.Context, query string) ([]byte, error)
+	SetGeocode(ctx context.Context, query string, data []byte, ttl time.Duration) error
+	ReserveGeocodeSlot(ctx context.Context, interval, maxWait time.Duration) (time.Duration, bool, error)
+}
+
+// Client resolves addresses through the shared cache, falling back to
+// Nominatim. Its requests are spaced minInterval apart across all services.
+type Client struct {
+	cache Cache
+}
+
+// NewClient returns a Client backed by the shared cache.
+func NewClient(c Cache) *Client {
+	return &Client{cache: c}
+}
+
+// key normalizes a query for caching: case and spacing don't matter.
+func key(q string) string {
+	return strings.Join(strings.Fields(strings.ToLower(q)), " ")
+}
+
+// Candidates resolves q to up to a few addresses, best match first.
+// Returns an empty slice (no error) if nothing was found.
+func (c *Client) Candidates(ctx context.Context, q string) ([]Result, error) {
+	k := key(q)
+	if data, err := c.cache.GetGeocode(ctx, k); err != nil {
+		log.Printf("[geocode] cache read %q: %v", k, err)
+	} else if data != nil {
+		var results []Result
+		if err := json.Unmarshal(data, &results); err == nil {
+			metrics.GeocodeRequests.WithLabelValues("hit").Inc()
+			return results, nil
+		}
+	}
+
+	if err := c.wait(ctx); err != nil {
+		metrics.GeocodeRequests.WithLabelValues("error").Inc()
+		return nil, err
+	}
+	results, err := search(ctx, q, candidates)
+	if err != nil {
+		metrics.GeocodeRequests.WithLabelValues("error").Inc()
+		return nil, err
+	}
+	metrics.GeocodeRequests.WithLabelValues("miss").Inc()
+
+	ttl := hitTTL
+	if len(results) == 0 {
+		results = []Result{}
+		ttl = missTTL
+	}
+	if data, err := json.Marshal(results); err == nil {
+		if err := c.cache.SetGeocode(ctx, k, data, ttl); err != nil {
+			log.Printf("[geocode] cache write %q: %v", k, err)
+		}
+	}
+	return results, nil
+}
+
+// Lookup resolves q to its best matching address.
+// Returns nil (no error) if nothing was found.
+func (c *Client) Lookup(ctx context.Context, q string) (*Result, error) {
+	results, err := c.Candidates(ctx, q)
+	if err != nil || len(results) == 0 {
+		return nil, err
+	}
+	return &results[0], nil
+}
+
+// wait blocks until the shared throttle grants a Nominatim slot. It returns
+// ErrBusy without booking when the next free slot is more than maxQueueWait
+// away, and ctx's error when ctx expires before it. If Redis is unreachable
+// it falls back to this process' own throttle.
+func (c *Client) wait(ctx context.Context) error {
+	limit := maxQueueWait
+	byDeadline := false
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < limit {
+		limit = time.Until(deadline)
+		byDeadline = true
+	}
+	delay, ok, err := c.cache.ReserveGeocodeSlot(ctx, minInterval, limit)
+	if err != nil {
+		log.Printf("[geocode] shared throttle: %v, using the local one", err)
+		return waitLocal(ctx)
+	}
+	if !ok {
+		if byDeadline {
+			return context.DeadlineExceeded
+		}
+		return ErrBusy
+	}
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -3,11 +3,13 @@ package geocode
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -37,12 +39,62 @@ type nominatimAddr struct {
 	Country      string `json:"country"`
 }
 
-// Search queries Nominatim for the given address string.
-// Returns nil (no error) if nothing was found.
-func Search(ctx context.Context, query string) (*Result, error) {
+const (
+	// minInterval is the gap Nominatim's usage policy requires between
+	// requests (at most one per second per application).
+	minInterval = time.Second
+	// maxQueueWait bounds how long a request may be queued for its slot, which
+	// caps the queue at about maxQueueWait/minInterval requests.
+	maxQueueWait = 10 * time.Second
+)
+
+// ErrBusy is returned when too many requests are already queued.
+var ErrBusy = errors.New("geocode: too many queued requests")
+
+var (
+	throttleMu sync.Mutex
+	nextSlot   time.Time
+)
+
+// waitLocal blocks until this process may send the next Nominatim request.
+// It is the fallback when the shared throttle is unreachable. It returns
+// ErrBusy without queueing when the next free slot is more than
+// maxQueueWait away, and ctx's error when ctx expires before it.
+func waitLocal(ctx context.Context) error {
+	throttleMu.Lock()
+	now := time.Now()
+	slot := nextSlot
+	if slot.Before(now) {
+		slot = now
+	}
+	if slot.Sub(now) > maxQueueWait {
+		throttleMu.Unlock()
+		return ErrBusy
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(slot) {
+		throttleMu.Unlock()
+		return context.DeadlineExceeded
+	}
+	nextSlot = slot.Add(minInterval)
+	throttleMu.Unlock()
+
+	t := time.NewTimer(slot.Sub(now))
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// search queries Nominatim for up to limit candidates, best match first.
+// Candidates that format to the same address are merged. Callers take a
+// throttle slot first (see Client.wait).
+func search(ctx context.Context, query string, limit int) ([]Result, error) {
 	u := fmt.Sprintf(
-		"https://nominatim.openstreetmap.org/search?q=%s&format=json&limit=1&addressdetails=1&accept-language=uk",
-		url.QueryEscape(query),
+		"https://nominatim.openstreetmap.org/search?q=%s&format=json&limit=%d&addressdetails=1&accept-language=uk",
+		url.QueryEscape(query), limit,
 	)
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
@@ -67,26 +119,29 @@ func Search(ctx context.Context, query string) (*Result, error) {
 		return nil, fmt.Errorf("decode nominatim response: %w", err)
 	}
 
-	if len(results) == 0 {
-		return nil, nil
-	}
-
-	r := results[0]
-
-	lat, err := strconv.ParseFloat(r.Lat, 64)
-	if err != nil {
-		return nil, fmt.Errorf("parse lat: %w", err)
-	}
-	lon, err := strconv.ParseFloat(r.Lon, 64)
-	if err != nil {
-		return nil, fmt.Errorf("parse lon: %w", err)
+	var out []Result
+	seen := make(map[string]bool)
+	for _, r := range results {
+		lat, err := strconv.ParseFloat(r.Lat, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse lat: %w", err)
+		}
+		lon, err := strconv.ParseFloat(r.Lon, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse lon: %w", err)
+		}
+		name := formatAddress(r.Address)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		out = append(out, Result{
+			DisplayName: name,
+			Latitude:    lat,
+			Longitude:   lon,
+		})
 	}
-
-	return &Result{
-		DisplayName: formatAddress(r.Address),
-		Latitude:    lat,
-		Longitude:   lon,
-	}, nil
+	return out, nil
 }
 
 // formatAddress builds a clean human-readable address from structured fields.
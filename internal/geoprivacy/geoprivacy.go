@@ -0,0 +1,55 @@
+// Package geoprivacy coarsens monitor coordinates for the public map. The
+// precise coordinates stay in the database for neighbour analysis and
+// geocoding; only the public output is moved.
+package geoprivacy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"strconv"
+)
+
+// CellDeg is the grid fuzzed coordinates snap to: 0.005° ≈ 550 m of latitude,
+// ≈ 350 m of longitude in Ukraine.
+const CellDeg = 0.005
+
+// Fuzz snaps a location to the CellDeg grid and then moves it to a fixed
+// pseudo-random point inside its cell. The offset is derived from the monitor
+// ID and secret, so a marker doesn't jump between map loads and monitors in
+// the same cell don't stack, yet without the secret the offset can't be
+// recomputed. Within a cell the precise location is not recoverable.
+func Fuzz(lat, lng float64, monitorID int64, secret string) (float64, float64) {
+	dLat, dLng := offset(monitorID, secret)
+	return round6(snap(lat) + dLat*CellDeg), round6(snap(lng) + dLng*CellDeg)
+}
+
+// Box widens a bounding box to whole CellDeg cells, so its edges don't sit on
+// the outermost monitors.
+func Box(minLat, minLng, maxLat, maxLng float64) (float64, float64, float64, float64) {
+	floor := func(v float64) float64 { return round6(math.Floor(v/CellDeg) * CellDeg) }
+	ceil := func(v float64) float64 { return round6(math.Ceil(v/CellDeg) * CellDeg) }
+	return floor(minLat), floor(minLng), ceil(maxLat), ceil(maxLng)
+}
+
+// snap moves v to the center of its grid cell.
+func snap(v float64) float64 {
+	return math.Round(v/CellDeg) * CellDeg
+}
+
+// offset returns a deterministic offset in [-0.5, 0.5) cells per axis.
+func offset(monitorID int64, secret string) (float64, float64) {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(monitorID, 10)))
+	sum := mac.Sum(nil)
+	unit := func(b []byte) float64 {
+		return float64(binary.BigEndian.Uint64(b)>>11)/(1<<53) - 0.5
+	}
+	return unit(sum[:8]), unit(sum[8:16])
+}
+
+// round6 trims floating-point noise; 6 decimals is ~10 cm.
+func round6(v float64) float64 {
+	return math.Round(v*1e6) / 1e6
+}
@@ -3,30 +3,63 @@ package health
 import (
 	"log"
 	"net/http"
+	"sync"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"no-lights-monitor/internal/safego"
+)
+
+var (
+	mu      sync.Mutex
+	checks  []func() error
+	started bool
 )
 
 // ServeAsync starts a health + metrics server on :8081 in the background.
 // /healthz → 200 always          (liveness)
 // /readyz  → calls check()       (readiness)
 // /metrics → Prometheus scrape   (not exposed through ingress)
+//
+// When several services share a process, later calls only add their check:
+// /readyz then requires all of them to pass.
 func ServeAsync(check func() error) {
+	mu.Lock()
+	defer mu.Unlock()
+	checks = append(checks, check)
+	if started {
+		return
+	}
+	started = true
+
 	mux := http.NewServeMux()
 	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
 	mux.HandleFunc("/readyz", func(w http.ResponseWriter, _ *http.Request) {
-		if err := check(); err != nil {
+		if err := ready(); err != nil {
 			http.Error(w, err.Error(), http.StatusServiceUnavailable)
 			return
 		}
 		w.WriteHeader(http.StatusOK)
 	})
 	mux.Handle("/metrics", promhttp.Handler())
-	go func() {
+	safego.Go("health_server", func() {
 		if err := http.ListenAndServe(":8081", mux); err != nil {
 			log.Printf("[health] server stopped: %v", err)
 		}
-	}()
+	})
+}
+
+// ready runs every registered check and returns the first failure.
+func ready() error {
+	mu.Lock()
+	cs := append([]func() error(nil), checks...)
+	mu.Unlock()
+	for _, check := range cs {
+		if err := check(); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,129 @@
+// Package httpx retries idempotent calls to the internal HTTP services (outage,
+// graph) with jittered exponential backoff and a timeout per attempt, so one
+// dropped connection or restarting pod doesn't cost a notification or a graph.
+package httpx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"no-lights-monitor/internal/metrics"
+)
+
+// Policy controls how a request is retried.
+type Policy struct {
+	Attempts       int           // total attempts, at least 1
+	AttemptTimeout time.Duration // deadline of a single attempt (0 = none)
+	BaseDelay      time.Duration // backoff before the 2nd attempt, doubled after each
+	MaxDelay       time.Duration // backoff cap
+}
+
+// DefaultPolicy is three attempts, 200ms → 400ms backoff, 10s per attempt.
+var DefaultPolicy = Policy{Attempts: 3, AttemptTimeout: 10 * time.Second, BaseDelay: 200 * time.Millisecond, MaxDelay: 2 * time.Second}
+
+// Client sends requests with a retry policy. Name labels the retry metrics and logs.
+type Client struct {
+	Name   string
+	HTTP   *http.Client
+	Policy Policy
+}
+
+// Do sends req, retrying retryable failures. Request bodies are replayed
+// through req.GetBody, which http.NewRequest sets for in-memory bodies.
+// The last response is returned as is, whatever its status; only transport
+// errors come back as err.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	attempts := max(c.Policy.Attempts, 1)
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		attempts = 1 // the body can't be replayed
+	}
+
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		resp, err := c.attempt(ctx, req, attempt)
+		if attempt >= attempts || !Retryable(resp, err) || ctx.Err() != nil {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		metrics.HTTPRetries.WithLabelValues(c.Name).Inc()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(c.backoff(attempt)):
+		}
+	}
+}
+
+// attempt sends one try of req under the per-attempt timeout.
+func (c *Client) attempt(ctx context.Context, req *http.Request, n int) (*http.Response, error) {
+	cancel := context.CancelFunc(func() {})
+	if c.Policy.AttemptTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.Policy.AttemptTimeout)
+	}
+	r := req.Clone(ctx)
+	if n > 1 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("replay body: %w", err)
+		}
+		r.Body = body
+	}
+	resp, err := c.HTTP.Do(r)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// The attempt's deadline also covers reading the body; release it on Close.
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// backoff returns the delay after the given attempt: exponential with full jitter.
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.Policy.BaseDelay << (attempt - 1)
+	if c.Policy.MaxDelay > 0 && (d > c.Policy.MaxDelay || d <= 0) {
+		d = c.Policy.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// Retryable reports whether a failed attempt is worth repeating: transport
+// errors and timeouts, 408, 429 and 5xx other than 501. Cancellation by the
+// caller and other client errors are final.
+func Retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		// Refused or reset connections, DNS hiccups and attempt timeouts.
+		return !errors.Is(err, context.Canceled)
+	}
+	switch resp.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
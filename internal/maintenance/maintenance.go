@@ -0,0 +1,108 @@
+// Package maintenance handles owner-defined recurring maintenance windows:
+// daily Kyiv-time periods (e.g. 02:00–03:00 every night, when a router
+// reboots) during which a monitor's status changes are recorded but not
+// notified.
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"no-lights-monitor/internal/models"
+)
+
+// MaxPerMonitor caps the windows of one monitor.
+const MaxPerMonitor = 3
+
+// MaxLength is the longest window; anything longer is a pause, not maintenance.
+const MaxLength = 12 * time.Hour
+
+var (
+	ErrFormat   = errors.New("expected HH:MM-HH:MM")
+	ErrTooLong  = errors.New("a window may last at most 12 hours")
+	ErrTooMany  = fmt.Errorf("at most %d windows", MaxPerMonitor)
+	ErrTooShort = errors.New("a window must last at least a minute")
+)
+
+var kyiv, _ = time.LoadLocation("Europe/Kyiv")
+
+// Parse reads a comma-separated list of windows such as "02:00-03:00,
+// 13:30-13:45". A window may cross midnight ("23:30-00:30"); en and em dashes
+// are accepted. The returned windows only have StartMin and EndMin set.
+func Parse(s string) ([]models.MaintenanceWindow, error) {
+	s = strings.NewReplacer("–", "-", "—", "-", " ", "").Replace(s)
+	var out []models.MaintenanceWindow
+	for _, part := range strings.Split(s, ",") {
+		if part == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(part, "-")
+		if !ok {
+			return nil, ErrFormat
+		}
+		start, err := parseClock(from)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseClock(to)
+		if err != nil {
+			return nil, err
+		}
+		w := models.MaintenanceWindow{StartMin: start, EndMin: end}
+		if length(w) == 0 {
+			return nil, ErrTooShort
+		}
+		if length(w) > int(MaxLength/time.Minute) {
+			return nil, ErrTooLong
+		}
+		out = append(out, w)
+	}
+	if len(out) == 0 {
+		return nil, ErrFormat
+	}
+	if len(out) > MaxPerMonitor {
+		return nil, ErrTooMany
+	}
+	return out, nil
+}
+
+// parseClock reads "H:MM" or "HH:MM" as minutes after midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, ErrFormat
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// length returns the minutes a window lasts.
+func length(w models.MaintenanceWindow) int {
+	return (w.EndMin - w.StartMin + 24*60) % (24 * 60)
+}
+
+// Format renders windows the way Parse reads them, e.g. "02:00–03:00, 23:30–00:30".
+func Format(windows []models.MaintenanceWindow) string {
+	parts := make([]string, 0, len(windows))
+	for _, w := range windows {
+		parts = append(parts, fmt.Sprintf("%02d:%02d–%02d:%02d", w.StartMin/60, w.StartMin%60, w.EndMin/60, w.EndMin%60))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Active reports whether t falls inside one of windows (Kyiv time).
+func Active(windows []models.MaintenanceWindow, t time.Time) bool {
+	k := t.In(kyiv)
+	m := k.Hour()*60 + k.Minute()
+	for _, w := range windows {
+		if w.StartMin < w.EndMin {
+			if m >= w.StartMin && m < w.EndMin {
+				return true
+			}
+		} else if m >= w.StartMin || m < w.EndMin {
+			return true
+		}
+	}
+	return false
+}
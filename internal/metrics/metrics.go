@@ -9,7 +9,7 @@ var (
 	// ── API ──────────────────────────────────────────────────────────────
 
 	// PingTotal counts incoming heartbeat pings.
-	// status: ok | paused | not_found
+	// status: ok | paused | banned | not_found
 	PingTotal = promauto.NewCounterVec(prometheus.CounterOpts{
 		Namespace: "nlm", Name: "ping_total",
 		Help: "Total heartbeat pings received by the API.",
@@ -23,6 +23,19 @@ var (
 		Buckets: prometheus.DefBuckets,
 	}, []string{"route", "status"})
 
+	// GeocodeRequests counts address lookups served by the API.
+	// result: hit | miss | error
+	GeocodeRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "nlm", Name: "geocode_requests_total",
+		Help: "Total address lookups, by cache result.",
+	}, []string{"result"})
+
+	// StreamClients is the number of open /api/stream connections.
+	StreamClients = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "nlm", Name: "stream_clients",
+		Help: "Open live status stream connections of this API replica.",
+	})
+
 	// ── Worker ────────────────────────────────────────────────────────────
 
 	// StatusChangeTotal counts monitor online/offline transitions.
@@ -39,6 +52,13 @@ var (
 		Help: "Unix timestamp of the last completed heartbeat check cycle.",
 	})
 
+	// ProbeDisagreements counts ping checks where the vantage quorum overruled this worker's own ping.
+	// quorum: true (reachable) | false (unreachable)
+	ProbeDisagreements = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "nlm", Name: "probe_disagreements_total",
+		Help: "Total ping checks where the vantage quorum overruled the worker's local result.",
+	}, []string{"quorum"})
+
 	// ActiveMonitors is the number of monitors currently loaded in worker memory.
 	ActiveMonitors = promauto.NewGauge(prometheus.GaugeOpts{
 		Namespace: "nlm", Name: "active_monitors",
@@ -52,6 +72,118 @@ var (
 		Help: "Total failed RabbitMQ publish attempts.",
 	}, []string{"routing_key"})
 
+	// HTTPRetries counts repeated attempts of calls to internal services.
+	// client: outage | graph
+	HTTPRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "nlm", Name: "http_retries_total",
+		Help: "Total retried HTTP attempts to internal services.",
+	}, []string{"client"})
+
+	// GraphSkippedTotal counts periodic graph renders skipped because the events were unchanged.
+	GraphSkippedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "nlm", Name: "graph_skipped_total",
+		Help: "Total periodic graph updates skipped due to an unchanged event checksum.",
+	})
+
+	// FlappingMonitors is the number of monitors whose notifications are
+	// aggregated because their status changes too often.
+	FlappingMonitors = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "nlm", Name: "flapping_monitors",
+		Help: "Monitors currently flapping (status change notifications aggregated).",
+	})
+
+	// PhotosFitted counts images downscaled, recompressed or padded to fit
+	// Telegram's photo limits before publishing. source: graph | outage_photo
+	PhotosFitted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "nlm", Name: "photos_fitted_total",
+		Help: "Total images adjusted to fit Telegram photo limits.",
+	}, []string{"source"})
+
+	// WorkPoolInFlight is the number of tasks currently running in a worker pool.
+	// pool: ping | db | mq
+	WorkPoolInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "nlm", Name: "workpool_in_flight",
+		Help: "Tasks currently running in a worker pool.",
+	}, []string{"pool"})
+
+	// WorkPoolQueueDepth is the number of submitters blocked waiting for a free slot.
+	WorkPoolQueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "nlm", Name: "workpool_queue_depth",
+		Help: "Submitters waiting for a free worker pool slot.",
+	}, []string{"pool"})
+
+	// WorkPoolWaitSeconds records how long submitters waited when a pool was saturated.
+	WorkPoolWaitSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "nlm", Name: "workpool_wait_seconds",
+		Help:    "Time spent waiting for a worker pool slot.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"pool"})
+
+	// CanaryHealthy is 1 while the canary monitor completes its ping → check →
+	// Telegram delivery cycle on time, 0 when a stage silently broke.
+	CanaryHealthy = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "nlm", Name: "canary_healthy",
+		Help: "Whether the synthetic canary monitor pipeline is healthy (1) or not (0).",
+	})
+
+	// CanaryFailuresTotal counts failed canary checks.
+	// stage: ping | check | delivery
+	CanaryFailuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "nlm", Name: "canary_failures_total",
+		Help: "Total failed canary checks by pipeline stage.",
+	}, []string{"stage"})
+
+	// OutageDataAgeSeconds is how long ago the upstream outage schedule data
+	// of a region was last updated.
+	OutageDataAgeSeconds = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "nlm", Name: "outage_data_age_seconds",
+		Help: "Age of the upstream outage schedule data by region.",
+	}, []string{"region"})
+
+	// SurgeActive is 1 while the worker is in outage-wave surge mode.
+	SurgeActive = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "nlm", Name: "surge_active",
+		Help: "Whether surge mode (outage wave) is active (1) or not (0).",
+	})
+
+	// SurgeFlappedDropped counts notifications not sent during a surge because
+	// the monitor flapped back to its previous state within one batch window.
+	SurgeFlappedDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "nlm", Name: "surge_flapped_dropped_total",
+		Help: "Total status notifications dropped as flaps during surge mode.",
+	})
+
+	// JobStalled is 1 while the watchdog finds a scheduled job behind its
+	// schedule by the configured number of runs, 0 otherwise.
+	JobStalled = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "nlm", Name: "job_stalled",
+		Help: "Whether a scheduled job missed too many runs (1) or not (0).",
+	}, []string{"job"})
+
+	// GoroutinePanics counts panics recovered in background goroutines and loops.
+	// name: the safego.Go / safego.Run name (e.g. heartbeat_checker, listener:status_change)
+	GoroutinePanics = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "nlm", Name: "goroutine_panics_total",
+		Help: "Total panics recovered in background goroutines.",
+	}, []string{"name"})
+
+	// ── Database ──────────────────────────────────────────────────────────
+
+	// DBQueryDuration records database query latency in every service.
+	// query: statement kind and first table (e.g. "select monitors", "update users")
+	DBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "nlm", Name: "db_query_duration_seconds",
+		Help:    "Database query duration.",
+		Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"query"})
+
+	// DBSlowQueries counts queries slower than DB_SLOW_QUERY_MS.
+	// query: same label values as DBQueryDuration
+	DBSlowQueries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "nlm", Name: "db_slow_queries_total",
+		Help: "Total database queries slower than the slow-query threshold.",
+	}, []string{"query"})
+
 	// ── Bot ───────────────────────────────────────────────────────────────
 
 	// BotMessagesProcessed counts messages consumed from RabbitMQ by the bot listener.
@@ -67,4 +199,41 @@ var (
 		Namespace: "nlm", Name: "bot_notification_errors_total",
 		Help: "Total Telegram notification errors in the bot listener.",
 	}, []string{"msg_type"})
+
+	// BotSendGateWait records how long Telegram calls of the bot service
+	// waited for a send slot. class: priority | bulk
+	BotSendGateWait = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "nlm", Name: "bot_send_gate_wait_seconds",
+		Help:    "Time Telegram calls of the bot service waited for a send slot.",
+		Buckets: []float64{0.05, 0.1, 0.5, 1, 5, 15, 60, 300},
+	}, []string{"class"})
+
+	// BotUpdates counts Telegram updates handled by the bot's router.
+	// route: /start | /create | ... | callback | text | location | media | channel_post
+	// result: ok | error | panic | limited (dropped by the per-user rate limit)
+	BotUpdates = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "nlm", Name: "bot_updates_total",
+		Help: "Total Telegram updates handled by the bot, by route and result.",
+	}, []string{"route", "result"})
+
+	// BotUpdateDuration records how long bot handlers took per route.
+	BotUpdateDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "nlm", Name: "bot_update_duration_seconds",
+		Help:    "Time spent handling Telegram updates, by route.",
+		Buckets: []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"route"})
+
+	// BotFloodWaits counts Telegram FloodWait (429) answers to calls for one chat.
+	// result: retried (the chat's queue waited retry_after) | gave_up
+	BotFloodWaits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "nlm", Name: "bot_flood_waits_total",
+		Help: "Total Telegram FloodWait answers to per-chat calls, by outcome.",
+	}, []string{"result"})
+
+	// BotMessageTimeouts counts deliveries whose handler outlived its time budget.
+	// queue: status_change | graph_ready | outage_photo | ... (listener handler names)
+	BotMessageTimeouts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "nlm", Name: "bot_message_timeouts_total",
+		Help: "Total listener messages not handled within their time budget.",
+	}, []string{"queue"})
 )
@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"no-lights-monitor/internal/models"
+)
+
+// MonitorCollector exports per-monitor gauges for /metrics/monitors. It is
+// registered in its own registry so the operational /metrics endpoint keeps a
+// bounded cardinality. Monitors are loaded from the database on every scrape.
+type MonitorCollector struct {
+	load func(ctx context.Context) ([]*models.Monitor, error)
+
+	up               *prometheus.Desc
+	secondsSince     *prometheus.Desc
+	lastHeartbeatAge *prometheus.Desc
+}
+
+// NewMonitorCollector returns a collector over the monitors returned by load.
+func NewMonitorCollector(load func(ctx context.Context) ([]*models.Monitor, error)) *MonitorCollector {
+	labels := []string{"id", "name", "type"}
+	return &MonitorCollector{
+		load: load,
+		up: prometheus.NewDesc("nlm_monitor_up",
+			"Whether the monitor sees power (1) or not (0).", labels, nil),
+		secondsSince: prometheus.NewDesc("nlm_monitor_seconds_since_change",
+			"Seconds since the monitor's last status change.", labels, nil),
+		lastHeartbeatAge: prometheus.NewDesc("nlm_monitor_seconds_since_heartbeat",
+			"Seconds since the last heartbeat ping (heartbeat monitors that pinged at least once).", labels, nil),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *MonitorCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.up
+	ch <- c.secondsSince
+	ch <- c.lastHeartbeatAge
+}
+
+// Collect implements prometheus.Collector. Paused and canary monitors are skipped.
+func (c *MonitorCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	monitors, err := c.load(ctx)
+	if err != nil {
+		log.Printf("[metrics] load monitors: %v", err)
+		return
+	}
+	now := time.Now()
+	for _, m := range monitors {
+		if !m.IsActive || m.IsCanary {
+			continue
+		}
+		labels := []string{strconv.FormatInt(m.ID, 10), m.Name, m.MonitorType}
+		up := 0.0
+		if m.IsOnline {
+			up = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, up, labels...)
+		ch <- prometheus.MustNewConstMetric(c.secondsSince, prometheus.GaugeValue, now.Sub(m.LastStatusChangeAt).Seconds(), labels...)
+		if m.LastHeartbeatAt != nil {
+			ch <- prometheus.MustNewConstMetric(c.lastHeartbeatAge, prometheus.GaugeValue, now.Sub(*m.LastHeartbeatAt).Seconds(), labels...)
+		}
+	}
+}
@@ -3,11 +3,13 @@ package models
 import "time"
 
 type User struct {
-	ID         int64     `json:"id" db:"id"`
-	TelegramID int64     `json:"telegram_id" db:"telegram_id"`
-	Username   string    `json:"username" db:"username"`
-	FirstName  string    `json:"first_name" db:"first_name"`
-	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	ID         int64      `json:"id" db:"id"`
+	TelegramID int64      `json:"telegram_id" db:"telegram_id"`
+	Username   string     `json:"username" db:"username"`
+	FirstName  string     `json:"first_name" db:"first_name"`
+	BannedAt   *time.Time `json:"banned_at,omitempty" db:"banned_at"` // set while the user is shadow-banned
+	BanReason  string     `json:"ban_reason,omitempty" db:"ban_reason"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
 }
 
 type Monitor struct {
@@ -20,7 +22,7 @@ type Monitor struct {
 	Longitude          float64    `json:"longitude" db:"longitude"`
 	ChannelID          int64      `json:"channel_id,omitempty" db:"channel_id"`
 	ChannelName        string     `json:"channel_name,omitempty" db:"channel_name"`
-	MonitorType        string     `json:"monitor_type" db:"monitor_type"`   // "heartbeat" or "ping"
+	MonitorType        string     `json:"monitor_type" db:"monitor_type"`   // "heartbeat", "ping" or "http"
 	PingTarget         string     `json:"ping_target" db:"ping_target"`     // IP/hostname for ping monitors
 	IsOnline           bool       `json:"is_online" db:"is_online"`
 	IsActive           bool       `json:"is_active" db:"is_active"`         // whether monitoring is enabled
@@ -50,6 +52,44 @@ type Monitor struct {
 	DtekOutageMessageID  int        `json:"dtek_outage_message_id" db:"dtek_outage_message_id"`
 	OfflineThresholdSec  int        `json:"offline_threshold_sec" db:"offline_threshold_sec"` // 150 (2.5 min) or 300 (5 min)
 	SettingsPassword     string     `json:"settings_password" db:"settings_password"`
+	GraphEventsHash      string     `json:"graph_events_hash" db:"graph_events_hash"` // hash of the events behind the last delivered graph
+	OutagePhotoMode      string     `json:"outage_photo_mode" db:"outage_photo_mode"` // "change", "daily" or "outage_start", see outage.PhotoPolicy
+	OutagePhotoDailyAt   string     `json:"outage_photo_daily_at" db:"outage_photo_daily_at"` // HH:MM Kyiv time for the "daily" photo mode
+	OutageSummaryEnabled bool       `json:"outage_summary_enabled" db:"outage_summary_enabled"` // post a daily text summary of today's outage windows
+	OutageSummaryAt      string     `json:"outage_summary_at" db:"outage_summary_at"` // HH:MM Kyiv time of the daily summary
+	OutageSummarySentOn  *time.Time `json:"outage_summary_sent_on" db:"outage_summary_sent_on"` // Kyiv date the last summary was published
+	IsCanary             bool       `json:"is_canary" db:"is_canary"` // system-maintained synthetic monitor (see cmd/worker/canary)
+	PingBaseURL          string     `json:"ping_base_url" db:"ping_base_url"` // public base URL the device was given ('' = legacy host)
+	PingIP               string     `json:"ping_ip" db:"ping_ip"` // source IP bound for /api/ping-ip ('' = disabled)
+	IntervalHintAt       *time.Time `json:"interval_hint_at" db:"interval_hint_at"` // last stale-device cadence hint DM
+	NotifyStyle          string     `json:"notify_style" db:"notify_style"` // notification wording preset (see notify.Styles); "" is the classic style
+	OutagePreAlertEnabled bool       `json:"outage_prealert_enabled" db:"outage_prealert_enabled"` // post a heads-up before scheduled outage windows
+	OutagePreAlertSentFor *time.Time `json:"outage_prealert_sent_for" db:"outage_prealert_sent_for"` // start of the outage window last pre-alerted
+	MapFuzzLocation      bool       `json:"map_fuzz_location" db:"map_fuzz_location"` // public map: show a fixed point of the ~500 m cell (see geoprivacy)
+	MapHideName          bool       `json:"map_hide_name" db:"map_hide_name"` // public map: show status without name and address
+	MapHideChannel       bool       `json:"map_hide_channel" db:"map_hide_channel"` // public map: don't link the channel
+	PlannedOutageMode    string     `json:"planned_outage_mode" db:"planned_outage_mode"` // delivery of status changes the schedule predicted: "", "silent" or "skip"
+	OnlineConfirmSec     int        `json:"online_confirm_sec" db:"online_confirm_sec"` // power must be back this long before going online (0 = immediately)
+	MutedUntil           *time.Time `json:"muted_until,omitempty" db:"muted_until"` // status notifications silenced until then (nil = not muted)
+	ChannelStatsEnabled  bool       `json:"channel_stats_enabled" db:"channel_stats_enabled"` // weekly DM to the owner with channel subscriber and post counts
+	SMSEnabled           bool       `json:"sms_enabled" db:"sms_enabled"` // text status changes to SMSPhones
+	SMSPhones            string     `json:"sms_phones" db:"sms_phones"` // comma-separated +380 numbers (see sms.MaxPhones)
+	SponsorEnabled       bool       `json:"sponsor_enabled" db:"sponsor_enabled"` // weekly graph caption may carry the operator's sponsor message (owner opt-in)
+	WebhookURL           string     `json:"webhook_url" db:"webhook_url"` // status changes are POSTed here ('' = no webhook)
+	WebhookSecret        string     `json:"-" db:"webhook_secret"` // HMAC key of the webhook signature (see internal/webhook)
+	LatencyGraphEnabled  bool       `json:"latency_graph_enabled" db:"latency_graph_enabled"` // ping monitors: also post a weekly RTT/packet loss graph
+	LatencyGraphMessageID int       `json:"latency_graph_message_id" db:"latency_graph_message_id"`
+	LatencyGraphWeekStart *time.Time `json:"latency_graph_week_start,omitempty" db:"latency_graph_week_start"`
+	OutageForecastEnabled bool       `json:"outage_forecast_enabled" db:"outage_forecast_enabled"` // add a high-outage-risk advisory to the daily summary
+	HeartbeatWebhookEnabled bool     `json:"heartbeat_webhook_enabled" db:"heartbeat_webhook_enabled"` // also send ping arrivals (rate limited) to the webhook
+	PowerReturnURL       string     `json:"power_return_url" db:"power_return_url"` // called only when power comes back ('' = no trigger)
+	PowerReturnSecret    string     `json:"-" db:"power_return_secret"` // shared secret of the power return trigger (see internal/webhook)
+	DailyGraphEnabled    bool       `json:"daily_graph_enabled" db:"daily_graph_enabled"` // also post today's uptime graph
+	DailyGraphMessageID  int        `json:"daily_graph_message_id" db:"daily_graph_message_id"`
+	DailyGraphStart      *time.Time `json:"daily_graph_start,omitempty" db:"daily_graph_start"` // Kyiv midnight of the day the message shows
+	MonthlyGraphEnabled  bool       `json:"monthly_graph_enabled" db:"monthly_graph_enabled"` // also post the month's uptime graph
+	MonthlyGraphMessageID int       `json:"monthly_graph_message_id" db:"monthly_graph_message_id"`
+	MonthlyGraphStart    *time.Time `json:"monthly_graph_start,omitempty" db:"monthly_graph_start"` // Kyiv midnight of the 1st of the month the message shows
 	CreatedAt            time.Time  `json:"created_at" db:"created_at"`
 	DeletedAt            *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
 }
@@ -72,4 +112,182 @@ type StatusEvent struct {
 	MonitorID int64     `json:"monitor_id" db:"monitor_id"`
 	IsOnline  bool      `json:"is_online" db:"is_online"`
 	Timestamp time.Time `json:"timestamp" db:"timestamp"`
+	Inferred  bool      `json:"inferred" db:"inferred"` // backfilled for a period the checker missed
+	Corrected bool      `json:"corrected,omitempty" db:"-"` // synthesized from an owner's status correction
+}
+
+// StatusChange is a monitor's status transition as handed to notifiers. All
+// deployment modes (in-process, over RabbitMQ) deliver this one value, so new
+// fields reach every notifier without changing their signatures.
+type StatusChange struct {
+	MonitorID     int64
+	ChannelID     int64
+	Name          string
+	Address       string
+	NotifyAddress bool // include Address in the message
+	IsOnline      bool
+	Duration      time.Duration // time spent in the previous state
+	When          time.Time
+	OutageRegion  string
+	OutageGroup   string
+	NotifyOutage  bool   // append the outage schedule line
+	NotifyStyle   string // notification wording preset
+	PlannedMode   string // outage.PlannedModes: how to deliver changes the schedule predicted
+	Silent        bool   // post the Telegram message without sound
+	SkipTelegram  bool   // no channel or follower message; webhooks, SMS and push still get it
+	TelegramOnly  bool   // channel message only: webhooks, SMS and push already got the change
+}
+
+// StatusCorrection is an owner's override of the recorded status for a period
+// (e.g. the sensor was unplugged while the power was on). Raw events are kept;
+// corrections are overlaid when graphs and history are built.
+type StatusCorrection struct {
+	ID        int64     `json:"id" db:"id"`
+	MonitorID int64     `json:"monitor_id" db:"monitor_id"`
+	IsOnline  bool      `json:"is_online" db:"is_online"`
+	StartAt   time.Time `json:"start_at" db:"start_at"`
+	EndAt     time.Time `json:"end_at" db:"end_at"`
+	Note      string    `json:"note" db:"note"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Follower is a Telegram user who asked to get a monitor's status changes by DM.
+// Requests stay pending until the owner approves them.
+type Follower struct {
+	ID         int64      `json:"id" db:"id"`
+	MonitorID  int64      `json:"monitor_id" db:"monitor_id"`
+	TelegramID int64      `json:"telegram_id" db:"telegram_id"`
+	Username   string     `json:"username" db:"username"`
+	FirstName  string     `json:"first_name" db:"first_name"`
+	Approved   bool       `json:"approved" db:"approved"`
+	DeclinedAt *time.Time `json:"declined_at,omitempty" db:"declined_at"` // declined or revoked; blocks new requests for a while
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// LinkCode is a one-time code a secondary Telegram account generates to get
+// access to the monitors of a primary account, which has to approve it.
+type LinkCode struct {
+	Code       string    `json:"code" db:"code"`
+	TelegramID int64     `json:"telegram_id" db:"telegram_id"` // the secondary account
+	Username   string    `json:"username" db:"username"`
+	FirstName  string    `json:"first_name" db:"first_name"`
+	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`
+}
+
+// Incident is a mass outage: many monitors of one outage group in a region
+// went offline together. Only counts are kept, never which monitors.
+type Incident struct {
+	ID          int64      `json:"id" db:"id"`
+	Region      string     `json:"region" db:"region"`
+	OutageGroup string     `json:"outage_group" db:"outage_group"`
+	StartedAt   time.Time  `json:"started_at" db:"started_at"`
+	EndedAt     *time.Time `json:"ended_at" db:"ended_at"` // nil while ongoing
+	Affected    int        `json:"affected" db:"affected"` // offline monitors at the last check
+	Total       int        `json:"total" db:"total"`       // monitors of the group at the last check
+	Peak        int        `json:"peak" db:"peak"`         // most monitors offline at once
+	StartPosted bool       `json:"start_posted" db:"start_posted"`
+	EndPosted   bool       `json:"end_posted" db:"end_posted"`
+}
+
+// MonitorChange is an audit record of a single settings field change made via bot or web.
+type MonitorChange struct {
+	ID         int64      `json:"id" db:"id"`
+	MonitorID  int64      `json:"monitor_id" db:"monitor_id"`
+	Source     string     `json:"source" db:"source"` // "bot" or "web"
+	Field      string     `json:"field" db:"field"`
+	OldValue   string     `json:"old_value" db:"old_value"`
+	NewValue   string     `json:"new_value" db:"new_value"`
+	RevertedAt *time.Time `json:"reverted_at,omitempty" db:"reverted_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Canary is the pipeline health state of a synthetic canary monitor.
+type Canary struct {
+	MonitorID       int64      `json:"monitor_id" db:"monitor_id"`
+	DeliveredOnline bool       `json:"delivered_online" db:"delivered_online"` // status of the last message the bot delivered
+	DeliveredAt     *time.Time `json:"delivered_at,omitempty" db:"delivered_at"`
+	Healthy         bool       `json:"healthy" db:"healthy"`
+	Problem         string     `json:"problem" db:"problem"` // failing pipeline stage, empty when healthy
+	CheckedAt       *time.Time `json:"checked_at,omitempty" db:"checked_at"`
+}
+
+// ScheduledJob is the bookkeeping row of a periodic job run by the scheduler.
+type ScheduledJob struct {
+	Name           string     `json:"name" db:"name"`
+	Schedule       string     `json:"schedule" db:"schedule"`
+	LockedBy       string     `json:"locked_by" db:"locked_by"` // instance currently running the job, if any
+	LockedUntil    *time.Time `json:"locked_until,omitempty" db:"locked_until"`
+	LastStartedAt  *time.Time `json:"last_started_at,omitempty" db:"last_started_at"`
+	LastFinishedAt *time.Time `json:"last_finished_at,omitempty" db:"last_finished_at"`
+	LastDurationMs int64      `json:"last_duration_ms" db:"last_duration_ms"`
+	LastError      string     `json:"last_error" db:"last_error"`
+	RunCount       int64      `json:"run_count" db:"run_count"`
+	NextRunAt      *time.Time `json:"next_run_at,omitempty" db:"next_run_at"`
+}
+
+// PushDevice is a companion app installation (APNs or FCM token) subscribed
+// to a monitor's status changes.
+type PushDevice struct {
+	MonitorID  int64     `json:"monitor_id" db:"monitor_id"`
+	Platform   string    `json:"platform" db:"platform"` // push.PlatformAPNs or push.PlatformFCM
+	Token      string    `json:"token" db:"token"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at" db:"last_seen_at"` // last (re-)registration by the app
+}
+
+// MonitorDevice is an extra heartbeat device of a monitor with its own ping
+// token. The monitor stays online while any of its devices (or its own token)
+// keeps pinging.
+type MonitorDevice struct {
+	ID         int64      `json:"id" db:"id"`
+	MonitorID  int64      `json:"monitor_id" db:"monitor_id"`
+	Token      string     `json:"-" db:"token"`
+	Name       string     `json:"name" db:"name"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	LastPingAt *time.Time `json:"last_ping_at" db:"last_ping_at"`
+}
+
+// MaintenanceWindow is a daily period (Kyiv time) during which a monitor's
+// status changes are recorded but not notified. A window with EndMin before
+// StartMin crosses midnight.
+type MaintenanceWindow struct {
+	ID        int64     `json:"id" db:"id"`
+	MonitorID int64     `json:"monitor_id" db:"monitor_id"`
+	StartMin  int       `json:"start_min" db:"start_min"` // minutes after midnight
+	EndMin    int       `json:"end_min" db:"end_min"`     // minutes after midnight, exclusive
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// LatencyPoint is one hour of a ping monitor's latency samples.
+type LatencyPoint struct {
+	Hour   time.Time `json:"hour" db:"hour"`
+	RTTMs  *float64  `json:"rtt_ms" db:"rtt_ms"` // median round trip; nil when no reply came back
+	Loss   float64   `json:"loss" db:"loss"`     // average share of lost pings, 0..1
+	Probes int       `json:"probes" db:"probes"`
+}
+
+// Announcement is an operator notice (maintenance, donations) shown as a
+// banner on the web pages while it is active.
+type Announcement struct {
+	ID        int64      `json:"id" db:"id"`
+	Message   string     `json:"message" db:"message"`
+	LinkURL   string     `json:"link_url" db:"link_url"`
+	Level     string     `json:"level" db:"level"` // info | warning
+	Pages     string     `json:"pages" db:"pages"` // comma-separated pages (map, settings); empty = all
+	StartsAt  time.Time  `json:"starts_at" db:"starts_at"`
+	EndsAt    *time.Time `json:"ends_at,omitempty" db:"ends_at"` // nil = until deleted
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// SponsorMessage is an operator support/donation note appended to the weekly
+// graph captions of monitors whose owners opted in, at most once every
+// EveryWeeks weeks per monitor.
+type SponsorMessage struct {
+	ID         int64      `json:"id" db:"id"`
+	Message    string     `json:"message" db:"message"`
+	LinkURL    string     `json:"link_url" db:"link_url"`
+	EveryWeeks int        `json:"every_weeks" db:"every_weeks"`
+	StartsAt   time.Time  `json:"starts_at" db:"starts_at"`
+	EndsAt     *time.Time `json:"ends_at,omitempty" db:"ends_at"` // nil = until deleted
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
 }
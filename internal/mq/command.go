@@ -0,0 +1,308 @@
+package mq
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"no-lights-monitor/internal/errsink"
+	"no-lights-monitor/internal/metrics"
+	"no-lights-monitor/internal/safego"
+)
+
+// The command bus lets services without a Telegram session (the API) ask the
+// bot to act on their behalf. Commands go through QueueBotCommand; callers that
+// need an answer wait for it on RabbitMQ's direct reply-to pseudo-queue, so no
+// per-instance reply queue has to be declared.
+
+// CommandType names an action the bot performs for another service.
+type CommandType string
+
+const (
+	// CommandSendTest posts the /test message to a monitor's channel.
+	CommandSendTest CommandType = "send_test"
+	// CommandCheckChannelRights checks the bot can post in Channel.
+	CommandCheckChannelRights CommandType = "check_channel_rights"
+	// CommandPostNotice posts Text (HTML) to a monitor's channel.
+	CommandPostNotice CommandType = "post_notice"
+)
+
+// Command failure reasons reported in CommandResult.Error.
+const (
+	CommandErrNoChannel   = "no_channel"
+	CommandErrNotFound    = "not_found"
+	CommandErrNotAdmin    = "not_admin"
+	CommandErrNoPostRight = "no_post_right"
+	CommandErrCheckFailed = "check_failed"
+	CommandErrSendFailed  = "send_failed"
+	CommandErrBadRequest  = "bad_request"
+	CommandErrUnknown     = "unknown_command"
+)
+
+const (
+	// directReplyTo is RabbitMQ's built-in pseudo-queue for RPC replies.
+	directReplyTo = "amq.rabbitmq.reply-to"
+	// defaultCommandTimeout bounds Call when the context has no deadline.
+	defaultCommandTimeout = 10 * time.Second
+)
+
+// ErrCommandTimeout is returned by Call when the bot does not answer in time.
+var ErrCommandTimeout = errors.New("bot did not answer in time")
+
+// BotCommand is published by the API to the bot.
+type BotCommand struct {
+	Type      CommandType `json:"type"`
+	MonitorID int64       `json:"monitor_id,omitempty"`
+	Channel   string      `json:"channel,omitempty"` // @username, for check_channel_rights
+	Text      string      `json:"text,omitempty"`    // HTML, for post_notice
+}
+
+// CommandResult is the bot's answer to a BotCommand.
+type CommandResult struct {
+	OK              bool   `json:"ok"`
+	Error           string `json:"error,omitempty"`  // one of the CommandErr* reasons
+	Detail          string `json:"detail,omitempty"` // Telegram's error text, for logs
+	ChannelID       int64  `json:"channel_id,omitempty"`
+	ChannelTitle    string `json:"channel_title,omitempty"`
+	ChannelUsername string `json:"channel_username,omitempty"`
+}
+
+// CommandBus publishes bot commands and collects their replies.
+type CommandBus struct {
+	url string // redialed when the publisher's connection is gone
+
+	chMu     sync.RWMutex
+	conn     *amqp.Connection
+	ownsConn bool // conn was dialed by the bus (not the publisher's)
+	ch       *amqp.Channel
+	closed   bool
+
+	mu      sync.Mutex
+	pending map[string]chan CommandResult
+}
+
+// NewCommandBus opens a dedicated channel on the publisher's connection and
+// starts listening for replies. When the channel or the connection drops, the
+// bus reconnects and subscribes to the reply queue again.
+func NewCommandBus(p *Publisher) (*CommandBus, error) {
+	b := &CommandBus{url: p.url, conn: p.conn, pending: make(map[string]chan CommandResult)}
+	replies, err := b.connect()
+	if err != nil {
+		return nil, err
+	}
+	safego.Go("mq_command_replies", func() { b.serve(replies) })
+	return b, nil
+}
+
+// connect opens a new command channel, dialing a connection of the bus's own
+// first if the current one is closed, and consumes replies on it: direct
+// reply-to answers arrive on the channel the command was published from.
+func (b *CommandBus) connect() (<-chan amqp.Delivery, error) {
+	b.chMu.RLock()
+	conn := b.conn
+	b.chMu.RUnlock()
+
+	owns := false
+	if conn == nil || conn.IsClosed() {
+		c, err := dialWithRetry(b.url)
+		if err != nil {
+			return nil, err
+		}
+		conn, owns = c, true
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		if owns {
+			conn.Close()
+		}
+		return nil, fmt.Errorf("open command channel: %w", err)
+	}
+	// Direct reply-to: consume in no-ack mode on the channel we publish from.
+	replies, err := ch.Consume(directReplyTo, "", true, false, false, false, nil)
+	if err != nil {
+		ch.Close()
+		if owns {
+			conn.Close()
+		}
+		return nil, fmt.Errorf("consume replies: %w", err)
+	}
+
+	b.chMu.Lock()
+	defer b.chMu.Unlock()
+	if b.closed {
+		ch.Close()
+		if owns {
+			conn.Close()
+		}
+		return nil, errors.New("command bus closed")
+	}
+	if owns {
+		if b.ownsConn && b.conn != nil {
+			b.conn.Close()
+		}
+		b.conn, b.ownsConn = conn, true
+	}
+	b.ch = ch
+	return replies, nil
+}
+
+// serve dispatches replies and reconnects whenever the reply consumer stops
+// because its channel closed, until the bus is closed. Calls waiting while the
+// channel was down time out: their replies went to the old channel.
+func (b *CommandBus) serve(replies <-chan amqp.Delivery) {
+	for {
+		b.dispatch(replies)
+		if b.isClosed() {
+			return
+		}
+		log.Printf("[mq] command reply channel closed, reconnecting")
+		wait := time.Second
+		for {
+			time.Sleep(wait)
+			if b.isClosed() {
+				return
+			}
+			var err error
+			if replies, err = b.connect(); err == nil {
+				break
+			}
+			log.Printf("[mq] command bus reconnect: %v", err)
+			wait = min(2*wait, 30*time.Second)
+		}
+		log.Printf("[mq] command bus reconnected")
+	}
+}
+
+func (b *CommandBus) isClosed() bool {
+	b.chMu.RLock()
+	defer b.chMu.RUnlock()
+	return b.closed
+}
+
+func (b *CommandBus) dispatch(replies <-chan amqp.Delivery) {
+	for d := range replies {
+		var res CommandResult
+		if err := json.Unmarshal(d.Body, &res); err != nil {
+			log.Printf("[mq] bad command reply: %v", err)
+			continue
+		}
+		b.mu.Lock()
+		waiter, ok := b.pending[d.CorrelationId]
+		delete(b.pending, d.CorrelationId)
+		b.mu.Unlock()
+		if ok {
+			waiter <- res
+		}
+	}
+}
+
+// Call publishes cmd and waits for the bot's reply until ctx is done (or
+// 10 seconds if ctx has no deadline). The command expires with the wait, so a
+// bot that comes back later does not act on a request nobody is waiting for.
+func (b *CommandBus) Call(ctx context.Context, cmd BotCommand) (*CommandResult, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultCommandTimeout)
+		defer cancel()
+	}
+	deadline, _ := ctx.Deadline()
+	ttl := time.Until(deadline).Milliseconds()
+	if ttl <= 0 {
+		return nil, ErrCommandTimeout
+	}
+
+	id := newCorrelationID()
+	waiter := make(chan CommandResult, 1)
+	b.mu.Lock()
+	b.pending[id] = waiter
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		delete(b.pending, id)
+		b.mu.Unlock()
+	}()
+
+	if err := b.publish(ctx, cmd, amqp.Publishing{
+		CorrelationId: id,
+		ReplyTo:       directReplyTo,
+		Expiration:    strconv.FormatInt(ttl, 10),
+	}); err != nil {
+		return nil, err
+	}
+
+	select {
+	case res := <-waiter:
+		return &res, nil
+	case <-ctx.Done():
+		return nil, ErrCommandTimeout
+	}
+}
+
+// Send publishes cmd without waiting for a reply.
+func (b *CommandBus) Send(ctx context.Context, cmd BotCommand) error {
+	return b.publish(ctx, cmd, amqp.Publishing{DeliveryMode: amqp.Persistent})
+}
+
+func (b *CommandBus) publish(ctx context.Context, cmd BotCommand, msg amqp.Publishing) error {
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return fmt.Errorf("marshal command: %w", err)
+	}
+	msg.ContentType = "application/json"
+	msg.Body = data
+	b.chMu.RLock()
+	ch := b.ch
+	b.chMu.RUnlock()
+	if err := ch.PublishWithContext(ctx, ExchangeName, RoutingBotCommand, false, false, msg); err != nil {
+		metrics.MQPublishErrors.WithLabelValues(RoutingBotCommand).Inc()
+		errsink.Capture(err, errsink.Fields{"component": "mq", "routing_key": RoutingBotCommand, "command": string(cmd.Type)})
+		return err
+	}
+	return nil
+}
+
+// Close closes the command channel (and the bus's own connection, if it
+// had to dial one) and stops reconnecting.
+func (b *CommandBus) Close() {
+	b.chMu.Lock()
+	defer b.chMu.Unlock()
+	b.closed = true
+	if b.ch != nil {
+		b.ch.Close()
+	}
+	if b.ownsConn && b.conn != nil {
+		b.conn.Close()
+	}
+}
+
+// Reply answers a command delivery. Deliveries without ReplyTo (sent with
+// CommandBus.Send) are ignored.
+func (c *Consumer) Reply(ctx context.Context, d amqp.Delivery, res CommandResult) error {
+	if d.ReplyTo == "" {
+		return nil
+	}
+	data, err := json.Marshal(res)
+	if err != nil {
+		return fmt.Errorf("marshal reply: %w", err)
+	}
+	return c.ch.PublishWithContext(ctx, "", d.ReplyTo, false, false, amqp.Publishing{
+		ContentType:   "application/json",
+		CorrelationId: d.CorrelationId,
+		Body:          data,
+	})
+}
+
+func newCorrelationID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -1,6 +1,10 @@
 package mq
 
-import "context"
+import (
+	"context"
+
+	"no-lights-monitor/internal/models"
+)
 
 // GraphRequester implements bot.GraphUpdater by publishing to RabbitMQ.
 type GraphRequester struct {
@@ -19,3 +23,22 @@ func (r *GraphRequester) UpdateSingle(ctx context.Context, monitorID, channelID
 		ChannelID: channelID,
 	})
 }
+
+// CleanupRequester implements bot.ChannelCleaner by publishing to RabbitMQ.
+type CleanupRequester struct {
+	pub *Publisher
+}
+
+// NewCleanupRequester creates a requester that publishes channel cleanups to RabbitMQ.
+func NewCleanupRequester(pub *Publisher) *CleanupRequester {
+	return &CleanupRequester{pub: pub}
+}
+
+// CleanupChannel publishes the removal of m's channel artifacts. Call it
+// after deleting m, with m as loaded before the deletion cleared its message IDs.
+func (r *CleanupRequester) CleanupChannel(ctx context.Context, m *models.Monitor) error {
+	if m.ChannelID == 0 {
+		return nil
+	}
+	return r.pub.Publish(ctx, RoutingMonitorCleanup, NewMonitorCleanupMsg(m))
+}
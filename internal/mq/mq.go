@@ -9,28 +9,47 @@ import (
 
 	amqp "github.com/rabbitmq/amqp091-go"
 
+	"no-lights-monitor/internal/errsink"
 	"no-lights-monitor/internal/metrics"
+	"no-lights-monitor/internal/models"
+	"no-lights-monitor/internal/safego"
 )
 
 // Exchange and queue/routing key constants.
 const (
 	ExchangeName = "nlm"
 
-	RoutingStatusChange  = "status.change"
-	RoutingGraphReady    = "graph.ready"
-	RoutingOutagePhoto   = "outage.photo"
-	RoutingGraphRequest  = "graph.request"
-	RoutingDtekOutage    = "dtek.outage"
-	RoutingInactivePause = "inactive.pause"
-	RoutingBroadcast     = "broadcast.message"
-
-	QueueStatusChange  = "nlm.status_change"
-	QueueGraphReady    = "nlm.graph_ready"
-	QueueOutagePhoto   = "nlm.outage_photo"
-	QueueGraphRequest  = "nlm.graph_request"
-	QueueDtekOutage    = "nlm.dtek_outage"
-	QueueInactivePause = "nlm.inactive_pause"
-	QueueBroadcast     = "nlm.broadcast"
+	RoutingStatusChange   = "status.change"
+	RoutingGraphReady     = "graph.ready"
+	RoutingOutagePhoto    = "outage.photo"
+	RoutingGraphRequest   = "graph.request"
+	RoutingDtekOutage     = "dtek.outage"
+	RoutingInactivePause  = "inactive.pause"
+	RoutingBroadcast      = "broadcast.message"
+	RoutingOutageSummary  = "outage.summary"
+	RoutingOutagePreAlert = "outage.prealert"
+	RoutingTestDrive      = "admin.test_drive"
+	RoutingRecompute      = "admin.recompute"
+	RoutingIntervalHint   = "owner.interval_hint"
+	RoutingBotCommand     = "bot.command"
+	RoutingMonitorCleanup = "monitor.cleanup"
+	RoutingOwnerDigest    = "owner.digest"
+
+	QueueStatusChange   = "nlm.status_change"
+	QueueGraphReady     = "nlm.graph_ready"
+	QueueOutagePhoto    = "nlm.outage_photo"
+	QueueGraphRequest   = "nlm.graph_request"
+	QueueDtekOutage     = "nlm.dtek_outage"
+	QueueInactivePause  = "nlm.inactive_pause"
+	QueueBroadcast      = "nlm.broadcast"
+	QueueOutageSummary  = "nlm.outage_summary"
+	QueueOutagePreAlert = "nlm.outage_prealert"
+	QueueTestDrive      = "nlm.test_drive"
+	QueueRecompute      = "nlm.recompute"
+	QueueIntervalHint   = "nlm.interval_hint"
+	QueueBotCommand     = "nlm.bot_command"
+	QueueMonitorCleanup = "nlm.monitor_cleanup"
+	QueueOwnerDigest    = "nlm.owner_digest"
 )
 
 // ── Message types ────────────────────────────────────────────────────
@@ -48,20 +67,83 @@ type StatusChangeMsg struct {
 	OutageRegion  string    `json:"outage_region"`
 	OutageGroup   string    `json:"outage_group"`
 	NotifyOutage  bool      `json:"notify_outage"`
+	NotifyStyle   string    `json:"notify_style,omitempty"`
+	Silent        bool      `json:"silent,omitempty"`
+	Sandbox       bool      `json:"sandbox,omitempty"` // admin test-drive: deliver as-is, never touch monitor state
 }
 
+// NewStatusChangeMsg builds the wire form of sc.
+func NewStatusChangeMsg(sc models.StatusChange) StatusChangeMsg {
+	return StatusChangeMsg{
+		MonitorID:     sc.MonitorID,
+		ChannelID:     sc.ChannelID,
+		Name:          sc.Name,
+		Address:       sc.Address,
+		NotifyAddress: sc.NotifyAddress,
+		IsOnline:      sc.IsOnline,
+		DurationSec:   sc.Duration.Seconds(),
+		When:          sc.When,
+		OutageRegion:  sc.OutageRegion,
+		OutageGroup:   sc.OutageGroup,
+		NotifyOutage:  sc.NotifyOutage,
+		NotifyStyle:   sc.NotifyStyle,
+		Silent:        sc.Silent,
+	}
+}
+
+// StatusChange returns the status change carried by the message.
+func (m StatusChangeMsg) StatusChange() models.StatusChange {
+	return models.StatusChange{
+		MonitorID:     m.MonitorID,
+		ChannelID:     m.ChannelID,
+		Name:          m.Name,
+		Address:       m.Address,
+		NotifyAddress: m.NotifyAddress,
+		IsOnline:      m.IsOnline,
+		Duration:      time.Duration(m.DurationSec * float64(time.Second)),
+		When:          m.When,
+		OutageRegion:  m.OutageRegion,
+		OutageGroup:   m.OutageGroup,
+		NotifyOutage:  m.NotifyOutage,
+		NotifyStyle:   m.NotifyStyle,
+		Silent:        m.Silent,
+	}
+}
+
+// GraphKind selects which graph of a monitor a message is about.
+type GraphKind string
+
+const (
+	GraphUptime  GraphKind = ""        // light on/off bars, every monitor
+	GraphLatency GraphKind = "latency" // median RTT and packet loss, ping monitors (weekly only)
+)
+
+// GraphPeriod selects the time span of an uptime graph. Each period is a
+// separate channel message.
+type GraphPeriod string
+
+const (
+	GraphWeek  GraphPeriod = ""      // Monday to Sunday, the default graph
+	GraphDay   GraphPeriod = "day"   // today, Kyiv time
+	GraphMonth GraphPeriod = "month" // the calendar month so far, Kyiv time
+)
+
 // GraphReadyMsg is published by the worker when a graph image is generated.
 type GraphReadyMsg struct {
-	MonitorID      int64     `json:"monitor_id"`
-	ChannelID      int64     `json:"channel_id"`
-	MonitorName    string    `json:"monitor_name"`
-	MonitorAddress string    `json:"monitor_address"`
-	NotifyAddress  bool      `json:"notify_address"`
-	WeekStart      time.Time `json:"week_start"`
-	OldMsgID       int       `json:"old_msg_id"`
-	NeedsNewMsg    bool      `json:"needs_new_msg"`
-	ImagePNG       []byte    `json:"image_png"`
-	Caption        string    `json:"caption"`
+	Kind           GraphKind   `json:"kind,omitempty"`
+	Period         GraphPeriod `json:"period,omitempty"`
+	MonitorID      int64       `json:"monitor_id"`
+	ChannelID      int64       `json:"channel_id"`
+	MonitorName    string      `json:"monitor_name"`
+	MonitorAddress string      `json:"monitor_address"`
+	NotifyAddress  bool        `json:"notify_address"`
+	WeekStart      time.Time   `json:"week_start"` // start of the graph's period (Monday for weekly graphs)
+	OldMsgID       int         `json:"old_msg_id"`
+	NeedsNewMsg    bool        `json:"needs_new_msg"`
+	ImagePNG       []byte      `json:"image_png"`
+	Caption        string      `json:"caption"`
+	EventsHash     string      `json:"events_hash"` // stored by the bot once the graph is delivered
+	Sandbox        bool        `json:"sandbox,omitempty"`
 }
 
 // OutagePhotoAction specifies what the bot should do with an outage photo.
@@ -84,12 +166,15 @@ type OutagePhotoMsg struct {
 	Filename    string            `json:"filename,omitempty"`
 	ETag        string            `json:"etag,omitempty"`
 	Caption     string            `json:"caption,omitempty"`
+	Sandbox     bool              `json:"sandbox,omitempty"`
 }
 
 // GraphRequestMsg is published by the bot to request immediate graph generation.
 type GraphRequestMsg struct {
-	MonitorID int64 `json:"monitor_id"`
-	ChannelID int64 `json:"channel_id"`
+	Kind      GraphKind   `json:"kind,omitempty"`
+	Period    GraphPeriod `json:"period,omitempty"`
+	MonitorID int64       `json:"monitor_id"`
+	ChannelID int64       `json:"channel_id"`
 }
 
 // DtekOutageAction specifies what the bot should do with a DTEK outage message.
@@ -129,17 +214,104 @@ type BroadcastMsg struct {
 	Text      string `json:"text"`
 }
 
+// OutageSummaryMsg is published by the worker with a monitor's daily schedule
+// summary, or (on RoutingOutagePreAlert) a heads-up before a scheduled outage.
+type OutageSummaryMsg struct {
+	MonitorID   int64  `json:"monitor_id"`
+	ChannelID   int64  `json:"channel_id"`
+	MonitorName string `json:"monitor_name"`
+	Text        string `json:"text"` // HTML
+}
+
+// TestDriveMsg is published by the admin API to replay a monitor's full
+// notification cycle (offline → online, graph, outage photo) into a sandbox channel.
+type TestDriveMsg struct {
+	MonitorID int64 `json:"monitor_id"`
+	ChannelID int64 `json:"channel_id"` // sandbox channel
+}
+
+// RecomputeKind selects what a recompute request regenerates.
+type RecomputeKind string
+
+const (
+	RecomputeGraph RecomputeKind = "graph"
+	RecomputePhoto RecomputeKind = "photo"
+)
+
+// RecomputeMsg asks the worker to force-regenerate one monitor's channel graph
+// or outage photo. Published by the admin API and the nlm recompute tool.
+type RecomputeMsg struct {
+	MonitorID int64         `json:"monitor_id"`
+	Kind      RecomputeKind `json:"kind"`
+}
+
+// IntervalHintMsg is published by the worker when a heartbeat device pings too
+// rarely for its offline threshold, so the owner can fix it before false alerts.
+type IntervalHintMsg struct {
+	MonitorID             int64  `json:"monitor_id"`
+	OwnerTelegramID       int64  `json:"owner_telegram_id"`
+	MonitorName           string `json:"monitor_name"`
+	IntervalSec           int    `json:"interval_sec"`            // median observed gap between pings
+	ThresholdSec          int    `json:"threshold_sec"`           // current offline threshold
+	SuggestedThresholdSec int    `json:"suggested_threshold_sec"` // 0 = no threshold fits, reconfigure the device
+}
+
+// OwnerDigestMsg is published by the worker with the daily digest of problems
+// across the monitors of an owner with many of them.
+type OwnerDigestMsg struct {
+	OwnerTelegramID int64  `json:"owner_telegram_id"`
+	Text            string `json:"text"` // HTML
+}
+
+// MonitorCleanupMsg is published once a monitor is deleted so the bot removes
+// what it left in the channel and posts a final notice. Message IDs are 0 when
+// there is nothing to remove.
+type MonitorCleanupMsg struct {
+	MonitorID         int64  `json:"monitor_id"`
+	ChannelID         int64  `json:"channel_id"`
+	MonitorName       string `json:"monitor_name"`
+	GraphMsgID        int    `json:"graph_msg_id"`
+	LatencyGraphMsgID int    `json:"latency_graph_msg_id,omitempty"`
+	DailyGraphMsgID   int    `json:"daily_graph_msg_id,omitempty"`
+	MonthlyGraphMsgID int    `json:"monthly_graph_msg_id,omitempty"`
+	OutagePhotoMsgID  int    `json:"outage_photo_msg_id"`
+	DtekMsgID         int    `json:"dtek_msg_id"`
+}
+
+// NewMonitorCleanupMsg captures m's channel artifacts, as loaded before it was deleted.
+func NewMonitorCleanupMsg(m *models.Monitor) MonitorCleanupMsg {
+	return MonitorCleanupMsg{
+		MonitorID:         m.ID,
+		ChannelID:         m.ChannelID,
+		MonitorName:       m.Name,
+		GraphMsgID:        m.GraphMessageID,
+		LatencyGraphMsgID: m.LatencyGraphMessageID,
+		DailyGraphMsgID:   m.DailyGraphMessageID,
+		MonthlyGraphMsgID: m.MonthlyGraphMessageID,
+		OutagePhotoMsgID:  m.OutagePhotoMessageID,
+		DtekMsgID:         m.DtekOutageMessageID,
+	}
+}
+
 // ── Topology setup ───────────────────────────────────────────────────
 
 // queues maps queue names to their routing keys.
 var queues = map[string]string{
-	QueueStatusChange:  RoutingStatusChange,
-	QueueGraphReady:    RoutingGraphReady,
-	QueueOutagePhoto:   RoutingOutagePhoto,
-	QueueGraphRequest:  RoutingGraphRequest,
-	QueueDtekOutage:    RoutingDtekOutage,
-	QueueInactivePause: RoutingInactivePause,
-	QueueBroadcast:     RoutingBroadcast,
+	QueueStatusChange:   RoutingStatusChange,
+	QueueGraphReady:     RoutingGraphReady,
+	QueueOutagePhoto:    RoutingOutagePhoto,
+	QueueGraphRequest:   RoutingGraphRequest,
+	QueueOutageSummary:  RoutingOutageSummary,
+	QueueOutagePreAlert: RoutingOutagePreAlert,
+	QueueTestDrive:      RoutingTestDrive,
+	QueueRecompute:      RoutingRecompute,
+	QueueIntervalHint:   RoutingIntervalHint,
+	QueueBotCommand:     RoutingBotCommand,
+	QueueMonitorCleanup: RoutingMonitorCleanup,
+	QueueOwnerDigest:    RoutingOwnerDigest,
+	QueueDtekOutage:     RoutingDtekOutage,
+	QueueInactivePause:  RoutingInactivePause,
+	QueueBroadcast:      RoutingBroadcast,
 }
 
 // SetupTopology declares the exchange, all queues, and bindings.
@@ -159,12 +331,19 @@ func SetupTopology(ch *amqp.Channel) error {
 	return nil
 }
 
+// HeaderSandbox marks messages published by a service running in sandbox mode.
+// Consumers outside sandbox mode drop them, so a sandbox pointed at the wrong
+// broker can never post to real channels.
+const HeaderSandbox = "x-nlm-sandbox"
+
 // ── Publisher ────────────────────────────────────────────────────────
 
 // Publisher publishes messages to the RabbitMQ exchange.
 type Publisher struct {
-	conn *amqp.Connection
-	ch   *amqp.Channel
+	url     string
+	conn    *amqp.Connection
+	ch      *amqp.Channel
+	sandbox bool
 }
 
 // NewPublisher connects to RabbitMQ, sets up topology, and returns a Publisher.
@@ -183,7 +362,7 @@ func NewPublisher(url string) (*Publisher, error) {
 		conn.Close()
 		return nil, err
 	}
-	return &Publisher{conn: conn, ch: ch}, nil
+	return &Publisher{url: url, conn: conn, ch: ch}, nil
 }
 
 // Publish serializes msg to JSON and publishes it with the given routing key.
@@ -192,17 +371,28 @@ func (p *Publisher) Publish(ctx context.Context, routingKey string, msg any) err
 	if err != nil {
 		return fmt.Errorf("marshal message: %w", err)
 	}
+	var headers amqp.Table
+	if p.sandbox {
+		headers = amqp.Table{HeaderSandbox: true}
+	}
 	if err := p.ch.PublishWithContext(ctx, ExchangeName, routingKey, false, false, amqp.Publishing{
+		Headers:      headers,
 		ContentType:  "application/json",
 		DeliveryMode: amqp.Persistent,
 		Body:         data,
 	}); err != nil {
 		metrics.MQPublishErrors.WithLabelValues(routingKey).Inc()
+		errsink.Capture(err, errsink.Fields{"component": "mq", "routing_key": routingKey})
 		return err
 	}
 	return nil
 }
 
+// SetSandbox makes the publisher tag every message with HeaderSandbox.
+func (p *Publisher) SetSandbox(on bool) {
+	p.sandbox = on
+}
+
 // Close closes the channel and connection.
 func (p *Publisher) Close() {
 	if p.ch != nil {
@@ -217,8 +407,9 @@ func (p *Publisher) Close() {
 
 // Consumer consumes messages from RabbitMQ queues.
 type Consumer struct {
-	conn *amqp.Connection
-	ch   *amqp.Channel
+	conn    *amqp.Connection
+	ch      *amqp.Channel
+	sandbox bool
 }
 
 // NewConsumer connects to RabbitMQ, sets up topology, and returns a Consumer.
@@ -246,9 +437,44 @@ func NewConsumer(url string) (*Consumer, error) {
 	return &Consumer{conn: conn, ch: ch}, nil
 }
 
+// SetSandbox lets the consumer accept messages tagged with HeaderSandbox.
+func (c *Consumer) SetSandbox(on bool) {
+	c.sandbox = on
+}
+
+// SetPrefetch sets how many unacked deliveries each consumer started after
+// this call may hold (1 by default), for callers that process several at once.
+func (c *Consumer) SetPrefetch(n int) error {
+	if err := c.ch.Qos(n, 0, false); err != nil {
+		return fmt.Errorf("set qos: %w", err)
+	}
+	return nil
+}
+
 // Consume starts consuming from the given queue and returns a delivery channel.
+// Outside sandbox mode, sandbox-tagged messages are dropped before delivery.
 func (c *Consumer) Consume(queue string) (<-chan amqp.Delivery, error) {
-	return c.ch.Consume(queue, "", false, false, false, false, nil)
+	deliveries, err := c.ch.Consume(queue, "", false, false, false, false, nil)
+	if err != nil {
+		errsink.Capture(err, errsink.Fields{"component": "mq", "queue": queue})
+		return deliveries, err
+	}
+	if c.sandbox {
+		return deliveries, nil
+	}
+	out := make(chan amqp.Delivery)
+	safego.Go("mq_sandbox_filter", func() {
+		defer close(out)
+		for d := range deliveries {
+			if tagged, _ := d.Headers[HeaderSandbox].(bool); tagged {
+				log.Printf("[mq] dropped sandbox message on %s", queue)
+				_ = d.Nack(false, false)
+				continue
+			}
+			out <- d
+		}
+	})
+	return out, nil
 }
 
 // Close closes the channel and connection.
@@ -3,7 +3,8 @@ package mq
 import (
 	"context"
 	"log"
-	"time"
+
+	"no-lights-monitor/internal/models"
 )
 
 // StatusNotifier implements heartbeat.Notifier by publishing to RabbitMQ.
@@ -17,21 +18,12 @@ func NewStatusNotifier(pub *Publisher) *StatusNotifier {
 }
 
 // NotifyStatusChange publishes a status change message to the queue.
-func (n *StatusNotifier) NotifyStatusChange(monitorID, channelID int64, name, address string, notifyAddress, isOnline bool, duration time.Duration, when time.Time, outageRegion, outageGroup string, notifyOutage bool) {
-	msg := StatusChangeMsg{
-		MonitorID:     monitorID,
-		ChannelID:     channelID,
-		Name:          name,
-		Address:       address,
-		NotifyAddress: notifyAddress,
-		IsOnline:      isOnline,
-		DurationSec:   duration.Seconds(),
-		When:          when,
-		OutageRegion:  outageRegion,
-		OutageGroup:   outageGroup,
-		NotifyOutage:  notifyOutage,
+// Changes marked SkipTelegram are not published, as the bot is the only consumer.
+func (n *StatusNotifier) NotifyStatusChange(sc models.StatusChange) {
+	if sc.SkipTelegram {
+		return
 	}
-	if err := n.pub.Publish(context.Background(), RoutingStatusChange, msg); err != nil {
-		log.Printf("[mq] failed to publish status change for monitor %d: %v", monitorID, err)
+	if err := n.pub.Publish(context.Background(), RoutingStatusChange, NewStatusChangeMsg(sc)); err != nil {
+		log.Printf("[mq] failed to publish status change for monitor %d: %v", sc.MonitorID, err)
 	}
 }
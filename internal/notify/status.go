@@ -0,0 +1,188 @@
+// Package notify renders channel notifications. It is shared by the bot, which
+// posts them, and the API, which previews them on the settings page, so both
+// always show the same text.
+package notify
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"strconv"
+	"time"
+
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/models"
+	"no-lights-monitor/internal/outage"
+)
+
+// StatusText builds the HTML body of a status change notification exactly as
+// it is posted to the channel, worded in sc's style preset. oc may be nil,
+// which omits the schedule line and the planned/unplanned label. An outage is
+// only labelled unplanned when a fresh schedule of its day says the group
+// should have power; without one it gets no label.
+func StatusText(oc *outage.Client, sc models.StatusChange) string {
+	t := templatesFor(sc.NotifyStyle)
+	var msg string
+	dur := database.FormatDuration(sc.Duration)
+	kyiv, _ := time.LoadLocation("Europe/Kyiv")
+	timeStr := sc.When.In(kyiv).Format("15:04")
+
+	if sc.IsOnline {
+		msg = fmt.Sprintf(t.online, timeStr, dur)
+	} else {
+		msg = fmt.Sprintf(t.offline, timeStr, dur)
+	}
+
+	if sc.NotifyAddress && sc.Address != "" {
+		msg += fmt.Sprintf(t.addressLine, html.EscapeString(sc.Address))
+	}
+
+	// Label outages and append outage schedule info if enabled.
+	if sc.NotifyOutage && sc.OutageRegion != "" && sc.OutageGroup != "" && oc != nil {
+		fact, err := oc.GetGroupFact(sc.OutageRegion, sc.OutageGroup)
+		if err != nil {
+			log.Printf("[notify] outage fetch error for %s/%s: %v", sc.OutageRegion, sc.OutageGroup, err)
+			return msg
+		}
+		scheduled := fact.Scheduled(sc.IsOnline, sc.When)
+		unplanned := fact.AgainstSchedule(sc.IsOnline, sc.When)
+		if !sc.IsOnline {
+			if scheduled {
+				msg = t.planned + msg
+			} else if unplanned {
+				msg = t.unplanned + msg
+			}
+		}
+		// An unplanned event's end can't be predicted by the schedule, so
+		// only scheduled changes get the outage line.
+		if scheduled {
+			msg += outageLine(t, fact, sc.OutageRegion, sc.OutageGroup, sc.IsOnline, sc.When)
+		} else if unplanned {
+			log.Printf("[notify] outage skip: lights online=%v against the schedule — unplanned", sc.IsOnline)
+		} else {
+			log.Printf("[notify] outage skip: no current schedule for %s/%s", sc.OutageRegion, sc.OutageGroup)
+		}
+		// The planned label and the line are only as good as the data.
+		if fact.Stale && scheduled {
+			msg += t.outdated
+		}
+	}
+	return msg
+}
+
+// outageLine builds the schedule line of a notification from the group fact.
+// For lights ON: shows next planned outage window.
+// For lights OFF: shows expected restoration time.
+func outageLine(t templates, fact *outage.GroupHourlyFact, region, group string, isOnline bool, when time.Time) string {
+	kyiv, _ := time.LoadLocation("Europe/Kyiv")
+	nowKyiv := when.In(kyiv)
+	currentHour := nowKyiv.Hour() // 0-23
+
+	log.Printf("[notify] outage data for %s/%s: factUpdate=%s, date=%s, currentHour=%d, isOnline=%v, hours=%v",
+		region, group, fact.FactUpdate, fact.Date, currentHour, isOnline, fact.Hours)
+
+	if isOnline {
+		// Find next contiguous outage block, only within today (no wrap-around).
+		startH, startM, endH, endM, ok := findNextOutageBlock(fact.Hours, currentHour)
+		if !ok {
+			log.Printf("[notify] outage: lights ON, no next outage block found today")
+			return ""
+		}
+		startStr := fmt.Sprintf("%02d:%02d", startH, startM)
+		endStr := fmt.Sprintf("%02d:%02d", endH, endM)
+		if endH == 24 {
+			endStr = "24:00"
+		}
+		log.Printf("[notify] outage: lights ON, next outage block %s-%s", startStr, endStr)
+		return fmt.Sprintf(t.nextPlanned, fmt.Sprintf("%s - %s", startStr, endStr))
+	}
+
+	// Lights OFF: find next restoration (full "yes" hour or "first" at :30).
+	restoreH, restoreM, ok := findNextRestoration(fact.Hours, currentHour)
+	if !ok {
+		log.Printf("[notify] outage: lights OFF, no restoration found today")
+		return ""
+	}
+	restoreTime := time.Date(nowKyiv.Year(), nowKyiv.Month(), nowKyiv.Day(), restoreH, restoreM, 0, 0, nowKyiv.Location())
+	if restoreTime.Before(nowKyiv) {
+		restoreTime = restoreTime.Add(24 * time.Hour)
+	}
+	durationUntil := restoreTime.Sub(nowKyiv)
+	durStr := database.FormatDuration(durationUntil)
+	restoreStr := fmt.Sprintf("%02d:%02d", restoreH, restoreM)
+	log.Printf("[notify] outage: lights OFF, next ON at %s (in %s)", restoreStr, durStr)
+	return fmt.Sprintf(t.expected, durStr, restoreStr)
+}
+
+// findNextOutageBlock finds the next contiguous block of outage hours
+// (status "no", "first", or "second") starting from the given hour.
+// Handles transitional hours: "first" (off 00-30) ends block at :30,
+// "second" (off 30-60) starts block at :30.
+// Returns (startH, startM, endH, endM, ok). endH may be 24 for midnight.
+func findNextOutageBlock(hours map[string]string, currentHour int) (startH, startM, endH, endM int, ok bool) {
+	h := currentHour + 1
+
+	// If we just got lights ON early during a scheduled outage block,
+	// we should skip the remaining hours of this current block
+	// so we don't report them as the "next" outage block.
+	curKey := strconv.Itoa(currentHour + 1)
+	curStatus := hours[curKey]
+	if curStatus == "no" || curStatus == "first" || curStatus == "second" {
+		for ; h < 24; h++ {
+			st := hours[strconv.Itoa(h+1)]
+			if st == "yes" {
+				break
+			}
+		}
+	}
+
+	for ; h < 24; h++ {
+		hourKey := strconv.Itoa(h + 1) // hours in data are 1-24
+		status := hours[hourKey]
+		if status == "no" || status == "first" || status == "second" {
+			// Block start: "second" => :30, else :00
+			if status == "second" {
+				startH, startM = h, 30
+			} else {
+				startH, startM = h, 0
+			}
+			// Block end for first hour
+			if status == "first" {
+				endH, endM = h, 30
+				return startH, startM, endH, endM, true
+			}
+			// "no" or "second": block continues
+			endH, endM = h+1, 0
+			for nextH := h + 1; nextH < 24; nextH++ {
+				nextKey := strconv.Itoa(nextH + 1)
+				nextStatus := hours[nextKey]
+				if nextStatus == "no" || nextStatus == "second" {
+					endH, endM = nextH+1, 0
+				} else if nextStatus == "first" {
+					endH, endM = nextH, 30
+					return startH, startM, endH, endM, true
+				} else {
+					break
+				}
+			}
+			return startH, startM, endH, endM, true
+		}
+	}
+	return 0, 0, 0, 0, false
+}
+
+// findNextRestoration finds the next time power returns: "yes" (full hour) or "first" (at :30).
+// Returns (hour, minute, ok). Hour is 0-23, minute is 0 or 30.
+func findNextRestoration(hours map[string]string, currentHour int) (hour, minute int, ok bool) {
+	for h := currentHour + 1; h < 24; h++ {
+		hourKey := strconv.Itoa(h + 1)
+		status := hours[hourKey]
+		if status == "yes" {
+			return h, 0, true
+		}
+		if status == "first" {
+			return h, 30, true
+		}
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,96 @@
+package notify
+
+// Notification style presets. Each monitor picks one (monitors.notify_style);
+// the empty string is the original wording and stays the default.
+const (
+	StyleClassic = ""
+	StyleFormal  = "formal"
+	StylePlayful = "playful"
+	StyleMinimal = "minimal"
+	StylePlain   = "plain" // classic wording without emoji
+)
+
+// templates is the wording of one style. Every string keeps the placeholders
+// of its classic counterpart.
+type templates struct {
+	online      string // time, how long it was off
+	offline     string // time, how long it was on
+	addressLine string // address
+	nextPlanned string // "HH:MM - HH:MM"
+	expected    string // duration, "HH:MM"
+	planned     string // prefix of an offline message the schedule predicted
+	unplanned   string // prefix of an offline message against the schedule
+	outdated    string // appended when the schedule data is stale
+}
+
+var catalog = map[string]templates{
+	StyleClassic: {
+		online:      "🟢 <b>%s Світло з'явилося</b> \n<i>(не було %s)</i>",
+		offline:     "🔴 <b>%s Світла немає</b>\n<i>(воно було %s)</i>",
+		addressLine: "\n📍 <i>%s</i>",
+		nextPlanned: "\n⏱ <i>Наступне планове: %s</i>",
+		expected:    "\n⏱ <i>Очікуємо за ~%s, о %s</i>",
+		planned:     "🗓 <b>Планове відключення</b>\n",
+		unplanned:   "⚡️ <b>Позапланове відключення</b>\n",
+		outdated:    "\n⚠️ <i>Графік може бути застарілим</i>",
+	},
+	StyleFormal: {
+		online:      "🟢 <b>%s Електропостачання відновлено</b>\n<i>(перерва тривала %s)</i>",
+		offline:     "🔴 <b>%s Електропостачання припинено</b>\n<i>(тривалість подачі: %s)</i>",
+		addressLine: "\n📍 <i>Адреса: %s</i>",
+		nextPlanned: "\n⏱ <i>Наступне планове відключення: %s</i>",
+		expected:    "\n⏱ <i>Орієнтовне відновлення через ~%s, о %s</i>",
+		planned:     "🗓 <b>Планове відключення згідно з графіком</b>\n",
+		unplanned:   "⚡️ <b>Позапланове відключення</b>\n",
+		outdated:    "\n⚠️ <i>Дані графіка можуть бути неактуальними</i>",
+	},
+	StylePlayful: {
+		online:      "💡 <b>%s Ура, світло повернулося!</b> 🎉\n<i>(сиділи без нього %s)</i>",
+		offline:     "🕯 <b>%s Ой, світло зникло</b>\n<i>(протрималося %s)</i>",
+		addressLine: "\n🏠 <i>%s</i>",
+		nextPlanned: "\n🗓 <i>Наступного разу вимкнуть: %s</i>",
+		expected:    "\n🤞 <i>Чекаємо за ~%s, о %s</i>",
+		planned:     "🗓 <b>Все за графіком</b>\n",
+		unplanned:   "⚡️ <b>Позапланове відключення!</b>\n",
+		outdated:    "\n🤷 <i>Графік давно не оновлювався, тож може брехати</i>",
+	},
+	StyleMinimal: {
+		online:      "🟢 %s світло є <i>(%s без)</i>",
+		offline:     "🔴 %s світла немає <i>(%s було)</i>",
+		addressLine: "\n<i>%s</i>",
+		nextPlanned: "\n<i>Далі за графіком: %s</i>",
+		expected:    "\n<i>Очікуємо ~%s, о %s</i>",
+		planned:     "🗓 за графіком\n",
+		unplanned:   "⚡️ позапланове\n",
+		outdated:    "\n<i>графік може бути застарілим</i>",
+	},
+	StylePlain: {
+		online:      "<b>%s Світло з'явилося</b>\n<i>(не було %s)</i>",
+		offline:     "<b>%s Світла немає</b>\n<i>(воно було %s)</i>",
+		addressLine: "\n<i>Адреса: %s</i>",
+		nextPlanned: "\n<i>Наступне планове: %s</i>",
+		expected:    "\n<i>Очікуємо за ~%s, о %s</i>",
+		planned:     "<b>Планове відключення</b>\n",
+		unplanned:   "<b>Позапланове відключення</b>\n",
+		outdated:    "\n<i>Графік може бути застарілим</i>",
+	},
+}
+
+// Styles lists the selectable styles in display order.
+func Styles() []string {
+	return []string{StyleClassic, StyleFormal, StylePlayful, StyleMinimal, StylePlain}
+}
+
+// ValidStyle reports whether style is a known preset.
+func ValidStyle(style string) bool {
+	_, ok := catalog[style]
+	return ok
+}
+
+// templatesFor returns the wording of style, falling back to classic.
+func templatesFor(style string) templates {
+	if t, ok := catalog[style]; ok {
+		return t
+	}
+	return catalog[StyleClassic]
+}
@@ -0,0 +1,157 @@
+package outage
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// The outage service sits on the notification path (schedule lines, photos),
+// so a slow or dead instance must not hold every notification for the full
+// HTTP timeout. Successful responses are cached briefly, and after a run of
+// failures the circuit opens: calls fail fast (or serve stale data) until a
+// single trial request gets through again.
+
+const (
+	// cacheTTL is how long a successful response is served without asking again.
+	cacheTTL = time.Minute
+	// staleTTL is how long an old response may stand in while the service fails.
+	staleTTL = 15 * time.Minute
+
+	// breakerThreshold consecutive failures open the circuit.
+	breakerThreshold = 3
+	// breakerCooldown is how long the circuit stays open before a trial request.
+	breakerCooldown = 30 * time.Second
+)
+
+// ErrUnavailable is returned without a request while the circuit is open.
+var ErrUnavailable = errors.New("outage service unavailable (circuit open)")
+
+// StatusError is a non-OK response from the outage service.
+type StatusError struct {
+	Code int
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("outage service returned %d: %s", e.Code, e.Body)
+}
+
+// serviceFailure reports whether err says the service is unhealthy, as opposed
+// to a valid answer like "group not found".
+func serviceFailure(err error) bool {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Code >= 500
+	}
+	return err != nil
+}
+
+// breaker is a consecutive-failure circuit breaker. Once open, it lets one
+// trial request through per cooldown; its outcome closes or reopens the circuit.
+type breaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	trial     bool // a trial request is in flight
+}
+
+// allow reports whether a request may be sent now.
+func (b *breaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < breakerThreshold {
+		return true
+	}
+	if now.Before(b.openUntil) || b.trial {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+// record feeds the outcome of a request back into the breaker.
+func (b *breaker) record(err error, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !serviceFailure(err) {
+		if b.failures >= breakerThreshold {
+			log.Printf("[outage] circuit closed, outage service is back")
+		}
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= breakerThreshold {
+		if b.failures == breakerThreshold {
+			log.Printf("[outage] circuit open after %d failures: %v", b.failures, err)
+		}
+		b.openUntil = now.Add(breakerCooldown)
+	}
+}
+
+// endTrial marks the trial request, if any, as no longer in flight.
+func (b *breaker) endTrial() {
+	b.mu.Lock()
+	b.trial = false
+	b.mu.Unlock()
+}
+
+// send runs a request the breaker allowed and records its outcome. The trial
+// flag is cleared in a defer, so a panicking trial request can't keep the
+// circuit from ever trying again.
+func send[T any](b *breaker, fetch func() (T, error)) (T, error) {
+	defer b.endTrial()
+	v, err := fetch()
+	b.record(err, time.Now())
+	return v, err
+}
+
+type cacheEntry struct {
+	value any
+	at    time.Time
+}
+
+// cached serves key from the response cache or fetches it through the breaker.
+// When the service fails or the circuit is open, a stale entry younger than
+// staleTTL is returned instead of the error.
+func cached[T any](c *Client, key string, fetch func() (T, error)) (T, error) {
+	now := time.Now()
+	c.mu.Lock()
+	entry, hit := c.cache[key]
+	c.mu.Unlock()
+	if hit && now.Sub(entry.at) < cacheTTL {
+		return entry.value.(T), nil
+	}
+
+	var err error
+	if c.breaker.allow(now) {
+		var v T
+		v, err = send(&c.breaker, fetch)
+		if err == nil {
+			c.mu.Lock()
+			c.cache[key] = cacheEntry{value: v, at: time.Now()}
+			c.mu.Unlock()
+			return v, nil
+		}
+	} else {
+		err = ErrUnavailable
+	}
+
+	if hit && serviceFailure(err) && now.Sub(entry.at) < staleTTL {
+		return entry.value.(T), nil
+	}
+	var zero T
+	return zero, err
+}
+
+// guarded runs an uncached request through the breaker.
+func guarded[T any](c *Client, fetch func() (T, error)) (T, error) {
+	if !c.breaker.allow(time.Now()) {
+		var zero T
+		return zero, ErrUnavailable
+	}
+	return send(&c.breaker, fetch)
+}
@@ -5,29 +5,52 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"time"
+	"sync"
+
+	"no-lights-monitor/internal/httpx"
+	"no-lights-monitor/internal/svcauth"
 )
 
-// Client talks to the outage data service.
+// Client talks to the outage data service. Schedule and list lookups are
+// cached briefly, and all calls go through a circuit breaker (see breaker.go).
 type Client struct {
 	baseURL    string
-	httpClient *http.Client
+	httpClient *httpx.Client
+
+	breaker breaker
+	mu      sync.Mutex
+	cache   map[string]cacheEntry
 }
 
-// NewClient creates a new outage service client.
-func NewClient(baseURL string) *Client {
+// NewClient creates a new outage service client. Requests are signed with
+// secret (see svcauth) when it is not empty and retried according to policy.
+func NewClient(baseURL, secret string, policy httpx.Policy) *Client {
 	return &Client{
-		baseURL: baseURL,
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		baseURL:    baseURL,
+		httpClient: &httpx.Client{Name: "outage", HTTP: svcauth.NewClient(secret, 0), Policy: policy},
+		cache:      make(map[string]cacheEntry),
+	}
+}
+
+// get sends a GET request to the outage service.
+func (c *Client) get(url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
 	}
+	return c.httpClient.Do(req)
 }
 
 // GetGroupFact fetches the hourly fact status for a group in a region.
 func (c *Client) GetGroupFact(region, group string) (*GroupHourlyFact, error) {
+	return cached(c, "fact:"+region+"/"+group, func() (*GroupHourlyFact, error) {
+		return c.getGroupFact(region, group)
+	})
+}
+
+func (c *Client) getGroupFact(region, group string) (*GroupHourlyFact, error) {
 	url := fmt.Sprintf("%s/api/outage/%s/%s", c.baseURL, region, group)
-	resp, err := c.httpClient.Get(url)
+	resp, err := c.get(url)
 	if err != nil {
 		return nil, fmt.Errorf("GET %s: %w", url, err)
 	}
@@ -35,7 +58,7 @@ func (c *Client) GetGroupFact(region, group string) (*GroupHourlyFact, error) {
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("outage service returned %d: %s", resp.StatusCode, string(body))
+		return nil, &StatusError{Code: resp.StatusCode, Body: string(body)}
 	}
 
 	var result GroupHourlyFact
@@ -45,6 +68,65 @@ func (c *Client) GetGroupFact(region, group string) (*GroupHourlyFact, error) {
 	return &result, nil
 }
 
+// GetScheduledHours fetches the scheduled outage hours per group of a region
+// over the last days complete days.
+func (c *Client) GetScheduledHours(region string, days int) (*ScheduledHours, error) {
+	return cached(c, fmt.Sprintf("scheduled:%s/%d", region, days), func() (*ScheduledHours, error) {
+		return c.getScheduledHours(region, days)
+	})
+}
+
+func (c *Client) getScheduledHours(region string, days int) (*ScheduledHours, error) {
+	url := fmt.Sprintf("%s/api/outage/%s/scheduled?days=%d", c.baseURL, region, days)
+	resp, err := c.get(url)
+	if err != nil {
+		return nil, fmt.Errorf("GET %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &StatusError{Code: resp.StatusCode, Body: string(body)}
+	}
+
+	var result ScheduledHours
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return &result, nil
+}
+
+// GetForecast fetches today's outage forecast for a region. It returns nil
+// without an error when the service has no forecast for the region today.
+func (c *Client) GetForecast(region string) (*Forecast, error) {
+	return cached(c, "forecast:"+region, func() (*Forecast, error) {
+		return c.getForecast(region)
+	})
+}
+
+func (c *Client) getForecast(region string) (*Forecast, error) {
+	url := fmt.Sprintf("%s/api/outage/%s/forecast", c.baseURL, region)
+	resp, err := c.get(url)
+	if err != nil {
+		return nil, fmt.Errorf("GET %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &StatusError{Code: resp.StatusCode, Body: string(body)}
+	}
+
+	var result Forecast
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return &result, nil
+}
+
 // GroupsResponse is the response from the /groups endpoint.
 type GroupsResponse struct {
 	Region string      `json:"region"`
@@ -53,8 +135,14 @@ type GroupsResponse struct {
 
 // GetGroups fetches the list of available groups for a region.
 func (c *Client) GetGroups(region string) ([]GroupInfo, error) {
+	return cached(c, "groups:"+region, func() ([]GroupInfo, error) {
+		return c.getGroups(region)
+	})
+}
+
+func (c *Client) getGroups(region string) ([]GroupInfo, error) {
 	url := fmt.Sprintf("%s/api/outage/%s/groups", c.baseURL, region)
-	resp, err := c.httpClient.Get(url)
+	resp, err := c.get(url)
 	if err != nil {
 		return nil, fmt.Errorf("GET %s: %w", url, err)
 	}
@@ -62,7 +150,7 @@ func (c *Client) GetGroups(region string) ([]GroupInfo, error) {
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("outage service returned %d: %s", resp.StatusCode, string(body))
+		return nil, &StatusError{Code: resp.StatusCode, Body: string(body)}
 	}
 
 	var result GroupsResponse
@@ -75,7 +163,21 @@ func (c *Client) GetGroups(region string) ([]GroupInfo, error) {
 // GetGroupPhoto fetches the outage schedule photo for a group via the outage service.
 // Pass storedETag (may be empty) to enable 304 Not Modified responses.
 // Returns (nil, "", true, nil) when the image is unchanged.
+// Photos are not cached (the ETag already saves the transfer) but share the breaker.
 func (c *Client) GetGroupPhoto(region, group, storedETag string) (data []byte, etag string, notModified bool, err error) {
+	type photo struct {
+		data        []byte
+		etag        string
+		notModified bool
+	}
+	p, err := guarded(c, func() (photo, error) {
+		data, etag, notModified, err := c.getGroupPhoto(region, group, storedETag)
+		return photo{data, etag, notModified}, err
+	})
+	return p.data, p.etag, p.notModified, err
+}
+
+func (c *Client) getGroupPhoto(region, group, storedETag string) (data []byte, etag string, notModified bool, err error) {
 	url := fmt.Sprintf("%s/api/outage/%s/%s/photo", c.baseURL, region, group)
 
 	req, err := http.NewRequest(http.MethodGet, url, nil)
@@ -98,7 +200,7 @@ func (c *Client) GetGroupPhoto(region, group, storedETag string) (data []byte, e
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, "", false, fmt.Errorf("outage service returned %d: %s", resp.StatusCode, string(body))
+		return nil, "", false, &StatusError{Code: resp.StatusCode, Body: string(body)}
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -114,8 +216,12 @@ type RegionsResponse []RegionInfo
 
 // GetRegions fetches the list of available regions.
 func (c *Client) GetRegions() ([]RegionInfo, error) {
+	return cached(c, "regions", c.getRegions)
+}
+
+func (c *Client) getRegions() ([]RegionInfo, error) {
 	url := fmt.Sprintf("%s/api/outage/regions", c.baseURL)
-	resp, err := c.httpClient.Get(url)
+	resp, err := c.get(url)
 	if err != nil {
 		return nil, fmt.Errorf("GET %s: %w", url, err)
 	}
@@ -123,7 +229,7 @@ func (c *Client) GetRegions() ([]RegionInfo, error) {
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("outage service returned %d: %s", resp.StatusCode, string(body))
+		return nil, &StatusError{Code: resp.StatusCode, Body: string(body)}
 	}
 
 	var result []RegionInfo
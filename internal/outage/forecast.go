@@ -0,0 +1,121 @@
+package outage
+
+import (
+	"fmt"
+	"time"
+)
+
+// Forecast risk levels, lowest to highest.
+const (
+	RiskLow    = "low"
+	RiskMedium = "medium"
+	RiskHigh   = "high"
+)
+
+// ForecastAllRegions is the region key of forecasts that cover the whole
+// country, like the grid operator's national consumption outlook.
+const ForecastAllRegions = "*"
+
+// ForecastFeed is the document served by OUTAGE_FORECAST_URL. Example:
+//
+//	{
+//	  "updated": "2026-10-16T09:00:00+03:00",
+//	  "forecasts": [
+//	    {"region": "*", "date": "2026-10-16", "from": "17:00", "to": "22:00", "risk": "high"},
+//	    {"region": "odesa", "date": "2026-10-16", "from": "18:00", "to": "21:00", "risk": "medium"}
+//	  ]
+//	}
+type ForecastFeed struct {
+	Updated   string     `json:"updated"`
+	Forecasts []Forecast `json:"forecasts"`
+}
+
+// Forecast is the outlook for one region and Kyiv day: how likely outages
+// are in the [From, To) window.
+type Forecast struct {
+	Region string `json:"region"` // region ID or ForecastAllRegions
+	Date   string `json:"date"`   // Kyiv date, YYYY-MM-DD
+	From   string `json:"from"`   // HH:MM, optional
+	To     string `json:"to"`     // HH:MM (24:00 for midnight), optional
+	Risk   string `json:"risk"`   // RiskLow, RiskMedium or RiskHigh
+}
+
+// ValidRisk reports whether s is a known risk level.
+func ValidRisk(s string) bool {
+	switch s {
+	case RiskLow, RiskMedium, RiskHigh:
+		return true
+	}
+	return false
+}
+
+// PickForecast returns the forecast for region on the Kyiv day of now.
+// A region's own entry wins over a country-wide one; nil if neither exists.
+func PickForecast(forecasts []Forecast, region string, now time.Time) *Forecast {
+	kyiv, _ := time.LoadLocation("Europe/Kyiv")
+	day := now.In(kyiv).Format("2006-01-02")
+
+	var national *Forecast
+	for i := range forecasts {
+		f := &forecasts[i]
+		if f.Date != day || !ValidRisk(f.Risk) {
+			continue
+		}
+		switch f.Region {
+		case region:
+			return f
+		case ForecastAllRegions:
+			national = f
+		}
+	}
+	return national
+}
+
+// forecastClock parses a forecast time of day into minutes since midnight,
+// accepting 24:00 as the end of the day.
+func forecastClock(s string) (int, bool) {
+	if s == "24:00" {
+		return 24 * 60, true
+	}
+	h, m, err := ParseDailyAt(s)
+	if err != nil {
+		return 0, false
+	}
+	return h*60 + m, true
+}
+
+// dayPart names the part of the day the [from, to) window (minutes since
+// midnight) lies in, or "" when it spans several parts.
+func dayPart(from, to int) string {
+	parts := []struct {
+		end  int
+		name string
+	}{{6 * 60, "вночі"}, {12 * 60, "вранці"}, {17 * 60, "вдень"}, {24 * 60, "ввечері"}}
+	start := 0
+	for _, p := range parts {
+		if from >= start && to <= p.end {
+			return p.name
+		}
+		start = p.end
+	}
+	return ""
+}
+
+// ForecastAdvisory renders the daily summary note for a high-risk forecast,
+// or "" when the risk isn't high. The note names the forecast window and,
+// when the window lies within one, the part of the day.
+func ForecastAdvisory(f *Forecast) string {
+	if f == nil || f.Risk != RiskHigh {
+		return ""
+	}
+	from, okFrom := forecastClock(f.From)
+	to, okTo := forecastClock(f.To)
+	if !okFrom || !okTo || to <= from {
+		return "\n\n⚡️ <b>Високий ризик відключень сьогодні</b> за прогнозом"
+	}
+	when := "сьогодні"
+	if part := dayPart(from, to); part != "" {
+		when += " " + part
+	}
+	return fmt.Sprintf("\n\n⚡️ <b>Високий ризик відключень %s</b> (%s–%s) за прогнозом", when, f.From, f.To)
+}
@@ -3,6 +3,7 @@ package outage
 import (
 	"regexp"
 	"strings"
+	"time"
 )
 
 // reLetterDigit matches the boundary between letters and digits.
@@ -46,6 +47,7 @@ type GroupHourlyFact struct {
 	LastUpdated string            `json:"last_updated"`
 	FactUpdate  string            `json:"fact_update"`
 	Hours       map[string]string `json:"hours"`
+	Stale       bool              `json:"stale,omitempty"` // upstream data is older than the service's limit
 }
 
 // RegionFactSummary is the API response for all groups' current status in a region.
@@ -54,12 +56,50 @@ type RegionFactSummary struct {
 	LastUpdated string                    `json:"last_updated"`
 	FactUpdate  string                    `json:"fact_update"`
 	Groups      map[string]map[string]string `json:"groups"`
+	Stale       bool                      `json:"stale,omitempty"`
+}
+
+// ScheduledHours is the API response for the scheduled outage hours of every
+// group of a region over the last complete days the service has data for.
+type ScheduledHours struct {
+	Region string             `json:"region"`
+	Days   []string           `json:"days"`   // covered Kyiv dates, YYYY-MM-DD, oldest first
+	Groups map[string]float64 `json:"groups"` // group ID -> scheduled outage hours over Days
+}
+
+// OffHours sums the scheduled outage time of one day of hourly statuses:
+// "no" counts a full hour, "first" and "second" half an hour each.
+func OffHours(hours map[string]string) float64 {
+	var total float64
+	for _, s := range hours {
+		switch s {
+		case "no":
+			total++
+		case "first", "second":
+			total += 0.5
+		}
+	}
+	return total
 }
 
 // RegionInfo is a short summary of a region for the regions list endpoint.
 type RegionInfo struct {
 	RegionID    string `json:"region_id"`
 	LastUpdated string `json:"last_updated"`
+	AgeSec      int64  `json:"age_sec"` // since LastUpdated; -1 if it can't be parsed
+	Stale       bool   `json:"stale"`
+}
+
+// OutdatedNote is appended to schedule-derived messages built from stale data.
+const OutdatedNote = "\n⚠️ <i>Графік може бути застарілим</i>"
+
+// ParseLastUpdated parses the upstream lastUpdated timestamp (RFC 3339).
+func ParseLastUpdated(s string) (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
 }
 
 // GroupInfo is an entry in the groups list with ID and human-readable name.
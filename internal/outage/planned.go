@@ -0,0 +1,114 @@
+package outage
+
+import (
+	"strconv"
+	"time"
+)
+
+// Planned outage notification modes (monitors.planned_outage_mode). They apply
+// to the Telegram messages of status changes the group's schedule predicted;
+// unplanned changes, webhooks, SMS and push are always delivered normally.
+const (
+	// PlannedNotify delivers scheduled changes like any other.
+	PlannedNotify = ""
+	// PlannedSilent posts scheduled changes to Telegram without sound.
+	PlannedSilent = "silent"
+	// PlannedSkip doesn't post scheduled changes to Telegram at all.
+	PlannedSkip = "skip"
+)
+
+// PlannedModes lists the valid planned outage notification modes.
+func PlannedModes() []string {
+	return []string{PlannedNotify, PlannedSilent, PlannedSkip}
+}
+
+// ValidPlannedMode reports whether mode is a known planned outage mode.
+func ValidPlannedMode(mode string) bool {
+	return mode == PlannedNotify || mode == PlannedSilent || mode == PlannedSkip
+}
+
+// Scheduled reports whether the hourly schedule predicts a monitor of this
+// group being online (or offline) at when. Both the hour slot of when and the
+// next one are checked to absorb threshold drift (an outage scheduled at
+// 15:00 that cuts at 14:55); only slots of the fact's own day count, so
+// yesterday's or a stale schedule never matches. Transitional hours
+// ("first", "second") match either state, since the status changes mid-hour.
+func (f *GroupHourlyFact) Scheduled(isOnline bool, when time.Time) bool {
+	day, ok := f.Day()
+	if !ok {
+		return false
+	}
+	matches := func(t time.Time) bool {
+		h, ok := hourSlot(day, t)
+		if !ok {
+			return false
+		}
+		switch f.Hours[strconv.Itoa(h)] {
+		case "first", "second":
+			return true
+		case "yes":
+			return isOnline
+		case "no":
+			return !isOnline
+		}
+		return false
+	}
+	return matches(when) || matches(when.Add(time.Hour))
+}
+
+// AgainstSchedule reports whether a fresh schedule of when's day has the group
+// in the other state at when, which makes the change unplanned. A missing,
+// stale or other day's schedule can't tell, so it reports false, as it does
+// for changes the schedule predicted.
+func (f *GroupHourlyFact) AgainstSchedule(isOnline bool, when time.Time) bool {
+	if f.Stale || f.Scheduled(isOnline, when) {
+		return false
+	}
+	day, ok := f.Day()
+	if !ok {
+		return false
+	}
+	h, ok := hourSlot(day, when)
+	if !ok {
+		return false
+	}
+	switch f.Hours[strconv.Itoa(h)] {
+	case "yes", "no":
+		return true
+	}
+	return false
+}
+
+// Day returns the Kyiv midnight the fact's hours belong to; ok is false if
+// the date is missing or malformed.
+func (f *GroupHourlyFact) Day() (day time.Time, ok bool) {
+	sec, err := strconv.ParseInt(f.Date, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	kyiv, _ := time.LoadLocation("Europe/Kyiv")
+	return time.Unix(sec, 0).In(kyiv), true
+}
+
+// IsFor reports whether the fact is the schedule of t's Kyiv date.
+func (f *GroupHourlyFact) IsFor(t time.Time) bool {
+	day, ok := f.Day()
+	if !ok {
+		return false
+	}
+	y, m, d := day.Date()
+	ty, tm, td := t.In(day.Location()).Date()
+	return y == ty && m == tm && d == td
+}
+
+// hourSlot returns the schedule hour (1-24) of day that t falls in; ok is
+// false if t is on another day. DST days have 23 or 25 hours, so the slot is
+// taken from t's wall clock once its date matches.
+func hourSlot(day, t time.Time) (int, bool) {
+	local := t.In(day.Location())
+	y, m, d := day.Date()
+	if ly, lm, ld := local.Date(); ly != y || lm != m || ld != d {
+		return 0, false
+	}
+	return local.Hour() + 1, true
+}
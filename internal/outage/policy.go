@@ -0,0 +1,100 @@
+package outage
+
+import (
+	"fmt"
+	"time"
+
+	"no-lights-monitor/internal/models"
+)
+
+// Outage photo delivery modes (monitors.outage_photo_mode).
+const (
+	// PhotoModeChange keeps the channel photo in sync with every schedule change.
+	PhotoModeChange = "change"
+	// PhotoModeDaily posts one fresh photo a day at the configured time and leaves it alone.
+	PhotoModeDaily = "daily"
+	// PhotoModeOutageStart posts the photo only when the monitor goes offline.
+	PhotoModeOutageStart = "outage_start"
+)
+
+// PhotoAction is what a delivery path should do with a monitor's photo right now.
+type PhotoAction int
+
+const (
+	PhotoSkip     PhotoAction = iota // leave the channel alone
+	PhotoRefresh                     // send a new photo or edit the existing one
+	PhotoEditOnly                    // edit an existing photo, never post a new one
+)
+
+// PhotoPolicy is the single place that decides when an outage schedule photo
+// is delivered to a monitor's channel. Both the periodic updater and the
+// outage-start trigger go through it.
+type PhotoPolicy struct {
+	Enabled         bool
+	Mode            string
+	DailyHour       int
+	DailyMinute     int
+	SkipIfNoOutages bool
+}
+
+// PolicyFor builds the delivery policy from a monitor's preferences.
+// Unknown modes fall back to PhotoModeChange.
+func PolicyFor(m *models.Monitor) PhotoPolicy {
+	p := PhotoPolicy{
+		Enabled:         m.OutagePhotoEnabled && m.OutageRegion != "" && m.OutageGroup != "",
+		Mode:            m.OutagePhotoMode,
+		SkipIfNoOutages: m.SkipOutagePhotoIfNoOutages,
+	}
+	if !ValidPhotoMode(p.Mode) {
+		p.Mode = PhotoModeChange
+	}
+	if h, min, err := ParseDailyAt(m.OutagePhotoDailyAt); err == nil {
+		p.DailyHour, p.DailyMinute = h, min
+	} else {
+		p.DailyHour = 8
+	}
+	return p
+}
+
+// Periodic decides what the periodic pass should do. postedToday reports whether
+// the current channel photo was posted/refreshed on today's (Kyiv) date.
+func (p PhotoPolicy) Periodic(now time.Time, postedToday bool) PhotoAction {
+	if !p.Enabled {
+		return PhotoSkip
+	}
+	switch p.Mode {
+	case PhotoModeDaily:
+		if postedToday {
+			return PhotoSkip
+		}
+		due := time.Date(now.Year(), now.Month(), now.Day(), p.DailyHour, p.DailyMinute, 0, 0, now.Location())
+		if now.Before(due) {
+			return PhotoSkip
+		}
+		return PhotoRefresh
+	case PhotoModeOutageStart:
+		// Keep an already posted photo current, but only outages post new ones.
+		return PhotoEditOnly
+	default:
+		return PhotoRefresh
+	}
+}
+
+// OnOutageStart reports whether the photo should be (re)posted when the monitor goes offline.
+func (p PhotoPolicy) OnOutageStart() bool {
+	return p.Enabled && p.Mode == PhotoModeOutageStart
+}
+
+// ValidPhotoMode reports whether mode is a known delivery mode.
+func ValidPhotoMode(mode string) bool {
+	return mode == PhotoModeChange || mode == PhotoModeDaily || mode == PhotoModeOutageStart
+}
+
+// ParseDailyAt parses an "HH:MM" time of day.
+func ParseDailyAt(s string) (hour, minute int, err error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return t.Hour(), t.Minute(), nil
+}
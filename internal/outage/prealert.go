@@ -0,0 +1,51 @@
+package outage
+
+import (
+	"fmt"
+	"html"
+	"time"
+)
+
+// NextOutageStart returns the next outage window of the schedule that starts
+// after now and no later than now+within. The windows are placed on the
+// schedule's own date, so yesterday's schedule never announces outages for
+// today. Times are in Europe/Kyiv.
+func NextOutageStart(fact *GroupHourlyFact, now time.Time, within time.Duration) (start, end time.Time, ok bool) {
+	date, ok := fact.Day()
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+
+	for _, b := range allOutageBlocks(fact.Hours) {
+		start = day.Add(time.Duration(b.startH)*time.Hour + time.Duration(b.startM)*time.Minute)
+		if !start.After(now) {
+			continue
+		}
+		if start.Sub(now) > within {
+			return time.Time{}, time.Time{}, false
+		}
+		end = day.Add(time.Duration(b.endH)*time.Hour + time.Duration(b.endM)*time.Minute)
+		return start, end, true
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+// BuildPreAlert renders the heads-up posted shortly before a scheduled outage.
+// Example output:
+//
+//	⚠️ За графіком світло вимкнуть о 16:00 (через ~15 хв)
+//	Планово до 19:30, черга 5.1
+func BuildPreAlert(group string, start, end, now time.Time) string {
+	kyiv, _ := time.LoadLocation("Europe/Kyiv")
+	minutes := int(start.Sub(now).Round(time.Minute).Minutes())
+	if minutes < 1 {
+		minutes = 1
+	}
+	endStr := end.In(kyiv).Format("15:04")
+	if end.Sub(start) > 0 && end.In(kyiv).Hour() == 0 && end.In(kyiv).Minute() == 0 {
+		endStr = "24:00"
+	}
+	return fmt.Sprintf("⚠️ <b>За графіком світло вимкнуть о %s</b> (через ~%d хв)\n<i>Планово до %s, черга %s</i>",
+		start.In(kyiv).Format("15:04"), minutes, endStr, html.EscapeString(group))
+}
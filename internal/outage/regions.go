@@ -0,0 +1,29 @@
+package outage
+
+// regionBox is a coarse latitude/longitude rectangle around a supported
+// region. Neighbouring oblasts overlap at the edges, so a detected region is
+// only ever offered as a suggestion, never applied on its own.
+type regionBox struct {
+	region         string
+	minLat, maxLat float64
+	minLng, maxLng float64
+}
+
+// regionBoxes is checked in order; Kyiv city comes before the oblast around it.
+var regionBoxes = []regionBox{
+	{"kyiv", 50.213, 50.591, 30.239, 30.826},
+	{"kyiv-region", 49.179, 51.554, 29.266, 32.161},
+	{"odesa", 45.180, 48.080, 28.190, 31.230},
+	{"dnipro", 47.450, 49.200, 33.200, 36.950},
+}
+
+// DetectRegion returns the outage region a location most likely belongs to,
+// or "" when it lies outside every supported region.
+func DetectRegion(lat, lng float64) string {
+	for _, b := range regionBoxes {
+		if lat >= b.minLat && lat <= b.maxLat && lng >= b.minLng && lng <= b.maxLng {
+			return b.region
+		}
+	}
+	return ""
+}
@@ -0,0 +1,80 @@
+package outage
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// hourEmoji maps an hourly fact value to its timeline symbol.
+func hourEmoji(v string) string {
+	switch v {
+	case "no":
+		return "🔴"
+	case "first", "second":
+		return "🟡"
+	case "yes":
+		return "🟢"
+	}
+	return "⚪"
+}
+
+// BuildDailySummary renders a group's schedule as an HTML text message: the
+// outage windows followed by an emoji timeline in rows of six hours. ok is
+// false unless fact is the schedule of now's Kyiv date, so yesterday's
+// schedule is never passed off as today's.
+// Example output:
+//
+//	🗓 Графік на сьогодні, 06.03 (П'ятниця), черга 5.1
+//
+//	🔴 09:00 - 12:00 (≈3 год.)
+//	🔴 19:00 - 22:30 (≈3.5 год.)
+//
+//	00–06 🟢🟢🟢🟢🟢🟢
+//	06–12 🟢🟢🟢🔴🔴🔴
+//	...
+//	🟢 є світло · 🔴 немає · 🟡 частково
+func BuildDailySummary(group string, fact *GroupHourlyFact, now time.Time) (text string, ok bool) {
+	if !fact.IsFor(now) {
+		return "", false
+	}
+	day, _ := fact.Day()
+
+	var sb strings.Builder
+	sb.WriteString(summaryHeader(group, day))
+
+	blocks := allOutageBlocks(fact.Hours)
+	if len(blocks) == 0 {
+		sb.WriteString("✅ Відключень не заплановано\n")
+	}
+	for _, b := range blocks {
+		fmt.Fprintf(&sb, "🔴 %02d:%02d - %02d:%02d (%s)\n", b.startH, b.startM, b.endH, b.endM,
+			formatBlockDuration(b.startH, b.startM, b.endH, b.endM))
+	}
+
+	sb.WriteString("\n")
+	for row := 0; row < 4; row++ {
+		fmt.Fprintf(&sb, "<code>%02d–%02d</code> ", row*6, row*6+6)
+		for h := row * 6; h < row*6+6; h++ {
+			sb.WriteString(hourEmoji(fact.Hours[strconv.Itoa(h+1)]))
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("🟢 є світло · 🔴 немає · 🟡 частково")
+	return sb.String(), true
+}
+
+// BuildUnpublishedSummary is the daily summary for a day whose schedule
+// hasn't been published.
+func BuildUnpublishedSummary(group string, now time.Time) string {
+	kyiv, _ := time.LoadLocation("Europe/Kyiv")
+	return summaryHeader(group, now.In(kyiv)) + "⏳ Графік на сьогодні ще не опубліковано"
+}
+
+// summaryHeader is the first line of the daily summary for day.
+func summaryHeader(group string, day time.Time) string {
+	return fmt.Sprintf("🗓 <b>Графік на сьогодні, %s (%s), черга %s</b>\n\n",
+		day.Format("02.01"), ukrainianWeekdays[day.Weekday()], html.EscapeString(group))
+}
@@ -0,0 +1,141 @@
+// Package photofit keeps images within Telegram's photo limits before they
+// are published for the bot: at most MaxBytes, no side over MaxSide and an
+// aspect ratio of at most MaxAspect. Oversized images are downscaled (area
+// averaging) and re-encoded as compressed PNG; extremely narrow ones are
+// padded with their corner colour rather than stretched.
+package photofit
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg" // decode JPEG sources too
+	"image/png"
+	"log"
+
+	"no-lights-monitor/internal/metrics"
+)
+
+const (
+	// MaxBytes is the upload limit kept below Telegram's 10 MB for photos.
+	MaxBytes = 8 << 20
+	// MaxSide is the longest side sent; Telegram scales larger photos down
+	// to 2560 px anyway, after a much bigger upload.
+	MaxSide = 2560
+	// MaxAspect is the widest ratio of the long side to the short one that
+	// Telegram accepts for photos.
+	MaxAspect = 20
+	// maxShrinks bounds the extra downscaling passes for images that stay
+	// over MaxBytes.
+	maxShrinks = 4
+)
+
+// ErrUnfit means an image could not be brought within the limits.
+var ErrUnfit = errors.New("image does not fit telegram photo limits")
+
+// Fit returns data unchanged when it is within the limits, or an adjusted
+// PNG. source ("graph", "outage_photo") labels logs and metrics.
+func Fit(source string, data []byte) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+	if len(data) <= MaxBytes && fits(cfg.Width, cfg.Height) {
+		return data, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+	img = pad(img)
+	w, h := target(img.Bounds().Dx(), img.Bounds().Dy(), MaxSide)
+
+	enc := png.Encoder{CompressionLevel: png.BestCompression}
+	for i := 0; i <= maxShrinks; i++ {
+		var buf bytes.Buffer
+		if err := enc.Encode(&buf, scale(img, w, h)); err != nil {
+			return nil, fmt.Errorf("encode image: %w", err)
+		}
+		if buf.Len() <= MaxBytes {
+			metrics.PhotosFitted.WithLabelValues(source).Inc()
+			log.Printf("[photofit] %s: %dx%d %d KB → %dx%d %d KB", source, cfg.Width, cfg.Height, len(data)>>10, w, h, buf.Len()>>10)
+			return buf.Bytes(), nil
+		}
+		w, h = w*3/4, h*3/4
+	}
+	return nil, ErrUnfit
+}
+
+// fits reports whether a w×h photo needs no resizing or padding.
+func fits(w, h int) bool {
+	long, short := max(w, h), min(w, h)
+	return long <= MaxSide && short > 0 && long <= short*MaxAspect
+}
+
+// target returns the size of a w×h image scaled to a long side of at most limit.
+func target(w, h, limit int) (int, int) {
+	long := max(w, h)
+	if long <= limit {
+		return w, h
+	}
+	return max(1, w*limit/long), max(1, h*limit/long)
+}
+
+// pad widens the short side of an image narrower than MaxAspect:1, centring
+// it on its top-left pixel's colour.
+func pad(img image.Image) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	nw, nh := w, h
+	if w > h*MaxAspect {
+		nh = (w + MaxAspect - 1) / MaxAspect
+	} else if h > w*MaxAspect {
+		nw = (h + MaxAspect - 1) / MaxAspect
+	} else {
+		return img
+	}
+	out := image.NewNRGBA(image.Rect(0, 0, nw, nh))
+	draw.Draw(out, out.Bounds(), &image.Uniform{C: img.At(b.Min.X, b.Min.Y)}, image.Point{}, draw.Src)
+	off := image.Pt((nw-w)/2, (nh-h)/2)
+	draw.Draw(out, image.Rectangle{Min: off, Max: off.Add(b.Size())}, img, b.Min, draw.Src)
+	return out
+}
+
+// scale resizes img to w×h by averaging the source pixels under each target
+// pixel, which keeps thin graph lines visible where nearest-neighbour would
+// drop them.
+func scale(img image.Image, w, h int) image.Image {
+	b := img.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	if sw == w && sh == h {
+		return img
+	}
+	src := image.NewNRGBA(image.Rect(0, 0, sw, sh))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0, y1 := y*sh/h, max((y+1)*sh/h, y*sh/h+1)
+		for x := 0; x < w; x++ {
+			x0, x1 := x*sw/w, max((x+1)*sw/w, x*sw/w+1)
+			var r, g, bl, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += uint32(p[0])
+					g += uint32(p[1])
+					bl += uint32(p[2])
+					a += uint32(p[3])
+					n++
+				}
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(bl / n), A: uint8(a / n)})
+		}
+	}
+	return dst
+}
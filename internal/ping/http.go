@@ -0,0 +1,120 @@
+package ping
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// HTTPTimeout bounds one HTTP check, redirects included.
+	HTTPTimeout = 10 * time.Second
+	// MaxURLLen is the longest URL accepted as an HTTP monitor target.
+	MaxURLLen = 500
+)
+
+var (
+	ErrInvalidURL  = errors.New("not an http(s) URL")
+	ErrHostUnknown = errors.New("host not found")
+	ErrPrivateHost = errors.New("private addresses cannot be monitored")
+)
+
+// ValidateURL checks an HTTP monitor target typed by a user and returns it
+// normalized. A missing scheme defaults to https. Hosts resolving to private,
+// loopback or link-local addresses are refused.
+func ValidateURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	if len(raw) > MaxURLLen {
+		return "", ErrInvalidURL
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || u.User != nil {
+		return "", ErrInvalidURL
+	}
+	ips, err := net.LookupIP(u.Hostname())
+	if err != nil || len(ips) == 0 {
+		return "", ErrHostUnknown
+	}
+	for _, ip := range ips {
+		if privateIP(ip) {
+			return "", ErrPrivateHost
+		}
+	}
+	u.Fragment = ""
+	return u.String(), nil
+}
+
+// httpClient is the client of the HTTP monitor checks.
+var httpClient = PublicHTTPClient(HTTPTimeout)
+
+// PublicHTTPClient returns a client for user-supplied URLs. It refuses
+// connections to private addresses at dial time, so a public hostname later
+// re-pointed (or redirecting) to an internal service can't be used to probe
+// the worker's network.
+func PublicHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy: nil,
+			DialContext: (&net.Dialer{
+				Timeout: timeout,
+				Control: func(network, address string, _ syscall.RawConn) error {
+					host, _, err := net.SplitHostPort(address)
+					if err != nil {
+						return err
+					}
+					if ip := net.ParseIP(host); ip == nil || privateIP(ip) {
+						return ErrPrivateHost
+					}
+					return nil
+				},
+			}).DialContext,
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConnsPerHost:   1,
+			IdleConnTimeout:       90 * time.Second,
+		},
+	}
+}
+
+// CheckURL GETs target and returns true if it answers 2xx within HTTPTimeout.
+func CheckURL(ctx context.Context, target string) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return false
+	}
+	req.Header.Set("User-Agent", "no-lights-monitor/1.0 (uptime check)")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	// Drain a little so the connection can be reused; the body itself is irrelevant.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
+
+// AnyPrivate reports whether any of a host's resolved addresses is private
+// (or not an IP at all). Checking only the first one would let a host with
+// one public and one internal address through.
+func AnyPrivate(addrs []string) bool {
+	for _, a := range addrs {
+		if ip := net.ParseIP(a); ip == nil || privateIP(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func privateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified()
+}
@@ -2,23 +2,59 @@ package ping
 
 import (
 	"log"
+	"slices"
 	"time"
 
 	probing "github.com/prometheus-community/pro-bing"
 )
 
+// Stats is the outcome of one round of ICMP pings to a target.
+type Stats struct {
+	Sent      int
+	Recv      int
+	MedianRTT time.Duration // 0 when no reply came back
+}
+
+// Loss returns the share of pings that got no reply, from 0 to 1.
+func (s Stats) Loss() float64 {
+	if s.Sent == 0 {
+		return 1
+	}
+	return float64(s.Sent-s.Recv) / float64(s.Sent)
+}
+
 // PingHost sends ICMP pings to the target and returns true if reachable.
 func PingHost(target string) bool {
+	return Probe(target).Recv > 0
+}
+
+// Probe sends ICMP pings to the target and returns the round's statistics.
+func Probe(target string) Stats {
 	pinger, err := probing.NewPinger(target)
 	if err != nil {
 		log.Printf("[ping] failed to create pinger for %s: %v", target, err)
-		return false
+		return Stats{}
 	}
 	pinger.Count = 3
 	pinger.Timeout = 5 * time.Second
 	pinger.SetPrivileged(true)
 	if err := pinger.Run(); err != nil {
-		return false
+		return Stats{Sent: pinger.Count}
+	}
+	st := pinger.Statistics()
+	return Stats{Sent: st.PacketsSent, Recv: st.PacketsRecv, MedianRTT: median(st.Rtts)}
+}
+
+// median returns the middle value of rtts, or 0 if there are none.
+func median(rtts []time.Duration) time.Duration {
+	if len(rtts) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(rtts)
+	slices.Sort(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
 	}
-	return pinger.Statistics().PacketsRecv > 0
+	return sorted[mid]
 }
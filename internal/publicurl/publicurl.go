@@ -0,0 +1,77 @@
+// Package publicurl picks which public hostname to show in ping links.
+//
+// The service can be reachable under several hostnames: the canonical one
+// (BASE_URL) that new monitors are given, and legacy ones (LEGACY_BASE_URLS)
+// that devices flashed before a domain move still call. Each monitor remembers
+// the base it was issued so its owner keeps seeing the URL their device uses;
+// monitors created before the column existed fall back to the first legacy URL.
+package publicurl
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Hosts holds the canonical and legacy public base URLs.
+type Hosts struct {
+	canonical string
+	legacy    []string
+}
+
+// New returns Hosts for the canonical base URL and optional legacy ones.
+// Trailing slashes are trimmed and empty entries ignored.
+func New(canonical string, legacy []string) *Hosts {
+	h := &Hosts{canonical: strings.TrimRight(canonical, "/")}
+	for _, l := range legacy {
+		l = strings.TrimRight(strings.TrimSpace(l), "/")
+		if l != "" && l != h.canonical {
+			h.legacy = append(h.legacy, l)
+		}
+	}
+	return h
+}
+
+// Canonical returns the base URL issued to new monitors and used for web links.
+func (h *Hosts) Canonical() string { return h.canonical }
+
+// For returns the base URL to show for a monitor's stored ping_base_url.
+// Empty means the monitor predates multi-host support and uses the primary
+// legacy host; a base that is no longer configured falls back to canonical.
+func (h *Hosts) For(stored string) string {
+	if stored == "" {
+		if len(h.legacy) > 0 {
+			return h.legacy[0]
+		}
+		return h.canonical
+	}
+	if stored == h.canonical {
+		return stored
+	}
+	for _, l := range h.legacy {
+		if l == stored {
+			return stored
+		}
+	}
+	return h.canonical
+}
+
+// PingURL returns the heartbeat URL for a token under the monitor's base.
+func (h *Hosts) PingURL(stored, token string) string {
+	return h.For(stored) + "/api/ping/" + token
+}
+
+// All returns the canonical base followed by the legacy ones.
+func (h *Hosts) All() []string {
+	return append([]string{h.canonical}, h.legacy...)
+}
+
+// IsLegacyHost reports whether host (as in the HTTP Host header) belongs to a
+// legacy base URL.
+func (h *Hosts) IsLegacyHost(host string) bool {
+	for _, l := range h.legacy {
+		if u, err := url.Parse(l); err == nil && strings.EqualFold(u.Host, host) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	apnsHost        = "https://api.push.apple.com"
+	apnsSandboxHost = "https://api.sandbox.push.apple.com"
+	// apnsTokenTTL renews the provider token well within Apple's 20-60 minute window.
+	apnsTokenTTL = 40 * time.Minute
+)
+
+// APNs sends notifications through Apple's HTTP/2 provider API with
+// token-based (.p8 key) authentication.
+type APNs struct {
+	key    *ecdsa.PrivateKey
+	keyID  string
+	teamID string
+	topic  string
+	host   string
+	client *http.Client
+
+	mu      sync.Mutex
+	token   string
+	tokenAt time.Time
+}
+
+// NewAPNs parses a .p8 signing key and returns an APNs sender for the app
+// with bundle ID topic.
+func NewAPNs(keyPEM []byte, keyID, teamID, topic string, sandbox bool) (*APNs, error) {
+	if keyID == "" || teamID == "" || topic == "" {
+		return nil, fmt.Errorf("apns needs a key ID, team ID and topic")
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("apns key: no PEM block")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("apns key: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("apns key: not an ECDSA key")
+	}
+	host := apnsHost
+	if sandbox {
+		host = apnsSandboxHost
+	}
+	return &APNs{
+		key:    key,
+		keyID:  keyID,
+		teamID: teamID,
+		topic:  topic,
+		host:   host,
+		client: &http.Client{Timeout: 15 * time.Second},
+	}, nil
+}
+
+// providerToken returns the cached ES256 provider token, renewing it when old.
+func (a *APNs) providerToken() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != "" && time.Since(a.tokenAt) < apnsTokenTTL {
+		return a.token, nil
+	}
+	now := time.Now()
+	token, err := signJWT("ES256", a.keyID, map[string]any{"iss": a.teamID, "iat": now.Unix()}, func(digest []byte) ([]byte, error) {
+		r, s, err := ecdsa.Sign(rand.Reader, a.key, digest)
+		if err != nil {
+			return nil, err
+		}
+		// JWS wants the fixed-size r||s form, not ASN.1.
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+		return sig, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	a.token, a.tokenAt = token, now
+	return token, nil
+}
+
+type apnsPayload struct {
+	APS struct {
+		Alert struct {
+			Title string `json:"title"`
+			Body  string `json:"body"`
+		} `json:"alert"`
+		Sound string `json:"sound,omitempty"`
+	} `json:"aps"`
+	MonitorID int64 `json:"monitor_id"`
+	IsOnline  bool  `json:"is_online"`
+}
+
+// Send delivers n to one device token.
+func (a *APNs) Send(ctx context.Context, token string, n Notification) error {
+	var p apnsPayload
+	p.APS.Alert.Title = n.Title
+	p.APS.Alert.Body = n.Body
+	if !n.Silent {
+		p.APS.Sound = "default"
+	}
+	p.MonitorID = n.MonitorID
+	p.IsOnline = n.IsOnline
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	bearer, err := a.providerToken()
+	if err != nil {
+		return fmt.Errorf("provider token: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.host+"/3/device/"+token, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "bearer "+bearer)
+	req.Header.Set("apns-topic", a.topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-collapse-id", fmt.Sprintf("monitor-%d", n.MonitorID))
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("http post: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var result struct {
+		Reason string `json:"reason"`
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+	if resp.StatusCode == http.StatusGone || result.Reason == "BadDeviceToken" || result.Reason == "Unregistered" {
+		return ErrUnregistered
+	}
+	return fmt.Errorf("apns returned HTTP %d %s", resp.StatusCode, result.Reason)
+}
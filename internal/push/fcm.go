@@ -0,0 +1,189 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	fcmScope    = "https://www.googleapis.com/auth/firebase.messaging"
+	fcmSendURL  = "https://fcm.googleapis.com/v1/projects/%s/messages:send"
+	fcmTokenURI = "https://oauth2.googleapis.com/token"
+)
+
+// FCM sends notifications through the Firebase Cloud Messaging HTTP v1 API,
+// authenticating as a service account.
+type FCM struct {
+	projectID string
+	email     string
+	tokenURI  string
+	key       *rsa.PrivateKey
+	client    *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewFCM parses a Firebase service account JSON file.
+func NewFCM(credentials []byte) (*FCM, error) {
+	var sa struct {
+		ProjectID   string `json:"project_id"`
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(credentials, &sa); err != nil {
+		return nil, fmt.Errorf("fcm credentials: %w", err)
+	}
+	if sa.ProjectID == "" || sa.ClientEmail == "" {
+		return nil, fmt.Errorf("fcm credentials: project_id and client_email are required")
+	}
+	block, _ := pem.Decode([]byte(sa.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("fcm credentials: no PEM private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("fcm credentials: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("fcm credentials: not an RSA key")
+	}
+	if sa.TokenURI == "" {
+		sa.TokenURI = fcmTokenURI
+	}
+	return &FCM{
+		projectID: sa.ProjectID,
+		email:     sa.ClientEmail,
+		tokenURI:  sa.TokenURI,
+		key:       key,
+		client:    &http.Client{Timeout: 15 * time.Second},
+	}, nil
+}
+
+// token returns a cached OAuth2 access token, exchanging a signed service
+// account assertion for a new one shortly before the old one expires.
+func (f *FCM) token(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.accessToken != "" && time.Until(f.expiresAt) > time.Minute {
+		return f.accessToken, nil
+	}
+
+	now := time.Now()
+	claims := map[string]any{
+		"iss":   f.email,
+		"scope": fcmScope,
+		"aud":   f.tokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}
+	assertion, err := signJWT("RS256", "", claims, func(digest []byte) ([]byte, error) {
+		return rsa.SignPKCS1v15(rand.Reader, f.key, crypto.SHA256, digest)
+	})
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	form.Set("assertion", assertion)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.tokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("http post: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint returned HTTP %d", resp.StatusCode)
+	}
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("decode token: %w", err)
+	}
+	f.accessToken = result.AccessToken
+	f.expiresAt = now.Add(time.Duration(result.ExpiresIn) * time.Second)
+	return f.accessToken, nil
+}
+
+type fcmMessage struct {
+	Message struct {
+		Token        string            `json:"token"`
+		Notification map[string]string `json:"notification"`
+		Data         map[string]string `json:"data"`
+		Android      struct {
+			CollapseKey  string `json:"collapse_key"`
+			Notification struct {
+				DefaultSound bool `json:"default_sound"`
+			} `json:"notification"`
+		} `json:"android"`
+	} `json:"message"`
+}
+
+// Send delivers n to one registration token.
+func (f *FCM) Send(ctx context.Context, token string, n Notification) error {
+	var m fcmMessage
+	m.Message.Token = token
+	m.Message.Notification = map[string]string{"title": n.Title, "body": n.Body}
+	m.Message.Data = map[string]string{
+		"monitor_id": strconv.FormatInt(n.MonitorID, 10),
+		"is_online":  strconv.FormatBool(n.IsOnline),
+	}
+	m.Message.Android.CollapseKey = fmt.Sprintf("monitor-%d", n.MonitorID)
+	m.Message.Android.Notification.DefaultSound = !n.Silent
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	access, err := f.token(ctx)
+	if err != nil {
+		return fmt.Errorf("access token: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf(fcmSendURL, f.projectID), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+access)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("http post: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var result struct {
+		Error struct {
+			Status string `json:"status"`
+		} `json:"error"`
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+	if resp.StatusCode == http.StatusNotFound || result.Error.Status == "UNREGISTERED" {
+		return ErrUnregistered
+	}
+	return fmt.Errorf("fcm returned HTTP %d %s", resp.StatusCode, result.Error.Status)
+}
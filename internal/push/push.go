@@ -0,0 +1,125 @@
+// Package push delivers status changes to the mobile companion app through
+// Apple (APNs) and Google (FCM) push services.
+package push
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Platforms a device token can belong to.
+const (
+	PlatformAPNs = "apns"
+	PlatformFCM  = "fcm"
+)
+
+// MaxDevicesPerMonitor bounds the app installations subscribed to one monitor.
+const MaxDevicesPerMonitor = 20
+
+// ErrUnregistered means the push service no longer knows the token (the app
+// was uninstalled or the token rotated); it should be forgotten.
+var ErrUnregistered = errors.New("device token is no longer registered")
+
+// Notification is one status change as shown by the app.
+type Notification struct {
+	Title     string
+	Body      string
+	MonitorID int64
+	IsOnline  bool
+	Silent    bool // show without sound
+}
+
+// Sender delivers notifications to device tokens of one platform.
+type Sender interface {
+	Send(ctx context.Context, token string, n Notification) error
+}
+
+// Config locates the push service credentials. A platform without
+// credentials is disabled.
+type Config struct {
+	FCMCredentialsFile string // Firebase service account JSON
+	APNsKeyFile        string // .p8 token signing key
+	APNsKeyID          string
+	APNsTeamID         string
+	APNsTopic          string // app bundle ID
+	APNsSandbox        bool   // use the development APNs environment
+}
+
+// NewSenders returns a Sender per configured platform; the map is empty when
+// push is not configured.
+func NewSenders(cfg Config) (map[string]Sender, error) {
+	senders := map[string]Sender{}
+	if cfg.FCMCredentialsFile != "" {
+		data, err := os.ReadFile(cfg.FCMCredentialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("read fcm credentials: %w", err)
+		}
+		fcm, err := NewFCM(data)
+		if err != nil {
+			return nil, err
+		}
+		senders[PlatformFCM] = fcm
+	}
+	if cfg.APNsKeyFile != "" {
+		data, err := os.ReadFile(cfg.APNsKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read apns key: %w", err)
+		}
+		apns, err := NewAPNs(data, cfg.APNsKeyID, cfg.APNsTeamID, cfg.APNsTopic, cfg.APNsSandbox)
+		if err != nil {
+			return nil, err
+		}
+		senders[PlatformAPNs] = apns
+	}
+	return senders, nil
+}
+
+// ValidToken reports whether token looks like a device token of platform:
+// 64+ hex digits for APNs, a URL-safe string for FCM.
+func ValidToken(platform, token string) bool {
+	switch platform {
+	case PlatformAPNs:
+		return len(token) >= 64 && len(token) <= 200 && strings.Trim(strings.ToLower(token), "0123456789abcdef") == ""
+	case PlatformFCM:
+		if len(token) < 32 || len(token) > 4096 {
+			return false
+		}
+		for _, r := range token {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_:.", r)) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// signJWT builds a compact JWS of claims with the given algorithm header;
+// sign returns the signature of the SHA-256 digest of the signing input.
+func signJWT(alg, keyID string, claims any, sign func(digest []byte) ([]byte, error)) (string, error) {
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if keyID != "" {
+		header["kid"] = keyID
+	}
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	input := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	digest := sha256.Sum256([]byte(input))
+	sig, err := sign(digest[:])
+	if err != nil {
+		return "", err
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
@@ -0,0 +1,25 @@
+package push
+
+import (
+	"context"
+	"log"
+)
+
+// Sandbox replaces every sender with one that only logs (SANDBOX=1): a copy
+// of production data must not reach real devices.
+func Sandbox(senders map[string]Sender) map[string]Sender {
+	out := make(map[string]Sender, len(senders))
+	for platform := range senders {
+		out[platform] = sandboxSender{platform: platform}
+	}
+	return out
+}
+
+type sandboxSender struct {
+	platform string
+}
+
+func (s sandboxSender) Send(ctx context.Context, token string, n Notification) error {
+	log.Printf("[sandbox] %s push for monitor %d not sent: %q", s.platform, n.MonitorID, n.Title)
+	return nil
+}
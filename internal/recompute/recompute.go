@@ -0,0 +1,95 @@
+// Package recompute force-regenerates channel graphs or outage photos for a
+// filtered set of monitors, e.g. after a rendering bug fix. Enqueue publishes
+// one request per monitor; the worker works through them at a throttled pace,
+// so a large batch doesn't flood the graph service or Telegram.
+package recompute
+
+import (
+	"context"
+	"fmt"
+
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/models"
+	"no-lights-monitor/internal/mq"
+)
+
+// publisher is the subset of *mq.Publisher Enqueue needs.
+type publisher interface {
+	Publish(ctx context.Context, routingKey string, msg any) error
+}
+
+// Options select the monitors a recompute run covers. Filters combine with AND.
+type Options struct {
+	Kind       mq.RecomputeKind
+	MonitorIDs []int64 // empty means every monitor with a channel
+	Region     string  // outage region, "" for any
+	Group      string  // outage group, "" for any
+	DryRun     bool    // report the selection without enqueueing
+}
+
+// Report summarizes a recompute run.
+type Report struct {
+	Kind       mq.RecomputeKind `json:"kind"`
+	MonitorIDs []int64          `json:"monitor_ids"`
+	DryRun     bool             `json:"dry_run"`
+}
+
+// ValidKind reports whether kind is a known recompute kind.
+func ValidKind(kind mq.RecomputeKind) bool {
+	return kind == mq.RecomputeGraph || kind == mq.RecomputePhoto
+}
+
+// Enqueue publishes a recompute request for every monitor matching opts.
+// Monitors without anything of the requested kind in their channel (every
+// graph disabled, no outage group) are skipped.
+func Enqueue(ctx context.Context, db *database.DB, pub publisher, opts Options) (*Report, error) {
+	if !ValidKind(opts.Kind) {
+		return nil, fmt.Errorf("unknown kind %q", opts.Kind)
+	}
+	monitors, err := db.GetMonitorsWithChannels(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get monitors: %w", err)
+	}
+
+	report := &Report{Kind: opts.Kind, MonitorIDs: []int64{}, DryRun: opts.DryRun}
+	for _, m := range monitors {
+		if !matches(m, opts) {
+			continue
+		}
+		if !opts.DryRun {
+			if err := pub.Publish(ctx, mq.RoutingRecompute, mq.RecomputeMsg{MonitorID: m.ID, Kind: opts.Kind}); err != nil {
+				return report, fmt.Errorf("publish monitor %d: %w", m.ID, err)
+			}
+		}
+		report.MonitorIDs = append(report.MonitorIDs, m.ID)
+	}
+	return report, nil
+}
+
+func matches(m *models.Monitor, opts Options) bool {
+	if len(opts.MonitorIDs) > 0 && !containsID(opts.MonitorIDs, m.ID) {
+		return false
+	}
+	if opts.Region != "" && m.OutageRegion != opts.Region {
+		return false
+	}
+	if opts.Group != "" && m.OutageGroup != opts.Group {
+		return false
+	}
+	switch opts.Kind {
+	case mq.RecomputeGraph:
+		return m.GraphEnabled || (m.MonitorType == "ping" && m.LatencyGraphEnabled) || m.DailyGraphEnabled || m.MonthlyGraphEnabled
+	case mq.RecomputePhoto:
+		return m.OutagePhotoMessageID != 0
+	}
+	return false
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
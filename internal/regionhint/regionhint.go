@@ -0,0 +1,69 @@
+// Package regionhint re-checks a monitor's outage group after its address or
+// coordinates change. The new location's region comes from outage.DetectRegion
+// and the group from the monitors around it; owners are offered the result,
+// it is never applied automatically.
+package regionhint
+
+import (
+	"context"
+
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/models"
+	"no-lights-monitor/internal/outage"
+)
+
+const (
+	// neighbourRadius is how far (in metres) neighbours count towards a group.
+	neighbourRadius = 300
+	// minVotes is how many neighbours must agree before a group is suggested.
+	minVotes = 2
+)
+
+// Hint is the outage group suggested for a monitor's new location. Group is
+// empty when the region is known but the neighbours don't agree on a group.
+type Hint struct {
+	Region string `json:"region"`
+	Group  string `json:"group"`
+}
+
+// Check returns a suggestion when m's outage group no longer fits its
+// (already updated) location, or nil when it fits or nothing better is known.
+func Check(ctx context.Context, db *database.DB, m *models.Monitor) (*Hint, error) {
+	region := outage.DetectRegion(m.Latitude, m.Longitude)
+	if region == "" {
+		return nil, nil
+	}
+	votes, err := db.GetNearbyOutageGroups(ctx, m.Latitude, m.Longitude, neighbourRadius, m.ID)
+	if err != nil {
+		return nil, err
+	}
+	hint := &Hint{Region: region, Group: majorityGroup(votes, region)}
+
+	switch {
+	case m.OutageRegion != region:
+		return hint, nil
+	case hint.Group != "" && hint.Group != m.OutageGroup:
+		return hint, nil
+	}
+	return nil, nil
+}
+
+// majorityGroup returns the group used by most neighbours in region, if at
+// least minVotes of them use it and they outnumber all other groups together.
+func majorityGroup(votes []database.GroupVote, region string) string {
+	var best database.GroupVote
+	total := 0
+	for _, v := range votes {
+		if v.Region != region {
+			continue
+		}
+		total += v.Monitors
+		if v.Monitors > best.Monitors {
+			best = v
+		}
+	}
+	if best.Monitors < minVotes || best.Monitors*2 <= total {
+		return ""
+	}
+	return best.OutageGroup
+}
@@ -0,0 +1,48 @@
+// Package safego runs background work so that a panic is contained instead of
+// taking the whole process (and with it every heartbeat check, graph update
+// and notification) down. Recovered panics are logged with their stack,
+// counted in nlm_goroutine_panics_total and reported to the error sink.
+package safego
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+
+	"no-lights-monitor/internal/errsink"
+	"no-lights-monitor/internal/metrics"
+)
+
+// PanicError is returned by Run when fn panicked.
+type PanicError struct {
+	Name  string
+	Value any
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic in %s: %v", e.Name, e.Value)
+}
+
+// Go runs fn in a new goroutine. A panic ends fn but not the process.
+// name identifies the goroutine in logs and metrics; keep it low-cardinality.
+func Go(name string, fn func()) {
+	go func() {
+		_ = Run(name, fn)
+	}()
+}
+
+// Run calls fn in the current goroutine and recovers a panic, returning it as
+// a *PanicError. Long-running loops call it once per iteration so that one bad
+// tick or message does not stop the loop.
+func Run(name string, fn func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[safego] %s: panic: %v\n%s", name, r, debug.Stack())
+			metrics.GoroutinePanics.WithLabelValues(name).Inc()
+			errsink.CapturePanic(r, errsink.Fields{"component": name})
+			err = &PanicError{Name: name, Value: r}
+		}
+	}()
+	fn()
+	return nil
+}
@@ -0,0 +1,173 @@
+// Package scheduler runs periodic jobs on cron schedules. Job state lives in
+// the scheduled_jobs table: a lease row makes sure only one instance runs a
+// job at a time, and last-run bookkeeping lets missed runs catch up on start.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"no-lights-monitor/internal/database"
+	"no-lights-monitor/internal/safego"
+)
+
+// DefaultLease is how long a job may run before its lock is considered stale.
+const DefaultLease = 1 * time.Hour
+
+// ErrSkipped is returned (possibly wrapped) by a job that chose not to run
+// this time. The run is neither a failure nor a success: nothing is recorded
+// and the job's heartbeat is left alone.
+var ErrSkipped = errors.New("run skipped")
+
+// Job is a unit of periodic work.
+type Job struct {
+	Name string
+	// Spec is a cron expression, see Parse.
+	Spec string
+	// Lease bounds how long the job holds its lock; DefaultLease if zero.
+	Lease time.Duration
+	// Timeout is the deadline of one run's context; Lease if zero, so a hung
+	// run is cancelled before another instance may take the job over.
+	Timeout time.Duration
+	// StartDelay postpones the catch-up run on startup (e.g. to let dependencies come up).
+	StartDelay time.Duration
+	Run        func(ctx context.Context) error
+}
+
+type entry struct {
+	job      Job
+	schedule Schedule
+}
+
+// Scheduler runs registered jobs until its context is cancelled.
+type Scheduler struct {
+	db         *database.DB
+	loc        *time.Location
+	owner      string
+	entries    []*entry
+	heartbeats Heartbeats
+	started    time.Time
+}
+
+// New creates a scheduler that evaluates cron expressions in loc.
+func New(db *database.DB, loc *time.Location) *Scheduler {
+	host, _ := os.Hostname()
+	return &Scheduler{
+		db:      db,
+		loc:     loc,
+		owner:   fmt.Sprintf("%s/%d", host, os.Getpid()),
+		started: time.Now(),
+	}
+}
+
+// Register adds a job. Must be called before Start.
+func (s *Scheduler) Register(job Job) error {
+	sched, err := Parse(job.Spec, s.loc)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", job.Name, err)
+	}
+	if job.Lease == 0 {
+		job.Lease = DefaultLease
+	}
+	if job.Timeout == 0 {
+		job.Timeout = job.Lease
+	}
+	s.entries = append(s.entries, &entry{job: job, schedule: sched})
+	return nil
+}
+
+// Start launches every registered job in its own goroutine.
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, e := range s.entries {
+		safego.Go("scheduler:"+e.job.Name, func() { s.loop(ctx, e) })
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, e *entry) {
+	name := e.job.Name
+	state, err := s.db.RegisterScheduledJob(ctx, name, e.job.Spec)
+	if err != nil {
+		log.Printf("[scheduler] %s: register failed: %v", name, err)
+	}
+
+	// Catch up if the job never ran or its last run is older than one period.
+	now := time.Now()
+	next := e.schedule.Next(now)
+	if state == nil || state.LastStartedAt == nil || !e.schedule.Next(*state.LastStartedAt).After(now) {
+		next = now.Add(e.job.StartDelay)
+	}
+
+	for {
+		if err := s.db.SetJobNextRun(ctx, name, next); err != nil && ctx.Err() == nil {
+			log.Printf("[scheduler] %s: record next run: %v", name, err)
+		}
+		log.Printf("[scheduler] %s: next run in %s", name, time.Until(next).Round(time.Second))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			log.Printf("[scheduler] %s: stopped", name)
+			return
+		case <-timer.C:
+		}
+
+		s.runOnce(ctx, e)
+		next = e.schedule.Next(time.Now())
+	}
+}
+
+func (s *Scheduler) runOnce(ctx context.Context, e *entry) {
+	name := e.job.Name
+	ok, err := s.db.AcquireJobLock(ctx, name, s.owner, e.job.Lease)
+	if err != nil {
+		log.Printf("[scheduler] %s: acquire lock: %v", name, err)
+		return
+	}
+	if !ok {
+		log.Printf("[scheduler] %s: already running elsewhere, skipping", name)
+		return
+	}
+
+	start := time.Now()
+	runCtx, cancelRun := context.WithTimeout(ctx, e.job.Timeout)
+	var runErr error
+	if perr := safego.Run("job:"+name, func() { runErr = e.job.Run(runCtx) }); perr != nil {
+		runErr = perr
+	}
+	cancelRun()
+	dur := time.Since(start)
+
+	// Use a fresh context so the lock is released even during shutdown.
+	finishCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if errors.Is(runErr, ErrSkipped) {
+		log.Printf("[scheduler] %s: skipped: %v", name, runErr)
+		if err := s.db.ReleaseJobLock(finishCtx, name, s.owner, e.schedule.Next(time.Now())); err != nil {
+			log.Printf("[scheduler] %s: release lock: %v", name, err)
+		}
+		return
+	}
+
+	errText := ""
+	if runErr != nil {
+		errText = runErr.Error()
+		log.Printf("[scheduler] %s: failed after %s: %v", name, dur.Round(time.Millisecond), runErr)
+	} else {
+		log.Printf("[scheduler] %s: done in %s", name, dur.Round(time.Millisecond))
+	}
+
+	if err := s.db.FinishJobRun(finishCtx, name, s.owner, dur, errText, e.schedule.Next(time.Now())); err != nil {
+		log.Printf("[scheduler] %s: record run: %v", name, err)
+	}
+	if runErr == nil && s.heartbeats != nil {
+		if err := s.heartbeats.SetJobHeartbeat(finishCtx, name, time.Now()); err != nil {
+			log.Printf("[scheduler] %s: record heartbeat: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"no-lights-monitor/internal/metrics"
+)
+
+// WatchdogName is the job name of the watchdog returned by Watchdog.
+const WatchdogName = "watchdog"
+
+// Heartbeats stores when each job last completed a run successfully. Backed
+// by Redis, so every worker replica sees the runs of the others.
+type Heartbeats interface {
+	SetJobHeartbeat(ctx context.Context, name string, at time.Time) error
+	JobHeartbeat(ctx context.Context, name string) (time.Time, error)
+}
+
+// SetHeartbeats makes the scheduler record a heartbeat after every successful
+// run. Must be called before Start.
+func (s *Scheduler) SetHeartbeats(hb Heartbeats) {
+	s.heartbeats = hb
+}
+
+// StallAlert is called once when a job is found stalled, with the time of its
+// last successful run (zero if it never completed one).
+type StallAlert func(ctx context.Context, job string, lastOK time.Time)
+
+// Watchdog returns a job that checks the heartbeat of every other registered
+// job. A job is stalled once missed of its scheduled runs have passed since
+// the last success, plus its timeout for the run in flight. That catches a
+// scheduler loop that hangs as well as a job that keeps failing or timing out.
+// alert fires once per stall; nlm_job_stalled tracks the current state.
+func (s *Scheduler) Watchdog(spec string, missed int, alert StallAlert) Job {
+	var mu sync.Mutex
+	alerted := make(map[string]bool)
+
+	return Job{
+		Name: WatchdogName,
+		Spec: spec,
+		Run: func(ctx context.Context) error {
+			if s.heartbeats == nil {
+				return fmt.Errorf("no heartbeat store configured")
+			}
+			mu.Lock()
+			defer mu.Unlock()
+
+			now := time.Now()
+			for _, e := range s.entries {
+				name := e.job.Name
+				if name == WatchdogName {
+					continue
+				}
+				last, err := s.heartbeats.JobHeartbeat(ctx, name)
+				if err != nil {
+					log.Printf("[watchdog] %s: read heartbeat: %v", name, err)
+					continue
+				}
+				since := last
+				if since.Before(s.started) {
+					// Runs before this process started can't be told from a
+					// long outage of the whole worker; count from our start.
+					since = s.started
+				}
+
+				deadline := since
+				for range missed {
+					deadline = e.schedule.Next(deadline)
+				}
+				deadline = deadline.Add(e.job.Timeout)
+
+				if now.Before(deadline) {
+					metrics.JobStalled.WithLabelValues(name).Set(0)
+					alerted[name] = false
+					continue
+				}
+				metrics.JobStalled.WithLabelValues(name).Set(1)
+				if alerted[name] {
+					continue
+				}
+				alerted[name] = true
+				log.Printf("[watchdog] %s: STALLED, missed %d runs (last success %s)", name, missed, formatBeat(last))
+				alert(ctx, name, last)
+			}
+			return nil
+		},
+	}
+}
+
+func formatBeat(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,23 @@
+package sms
+
+import (
+	"context"
+	"log"
+)
+
+// Sandbox wraps p so that texts are only logged (SANDBOX=1): a copy of
+// production data must not text real phone numbers.
+func Sandbox(p Provider) Provider {
+	return sandboxProvider{name: p.Name()}
+}
+
+type sandboxProvider struct {
+	name string
+}
+
+func (s sandboxProvider) Name() string { return s.name + " (sandbox)" }
+
+func (s sandboxProvider) Send(ctx context.Context, phone, text string) error {
+	log.Printf("[sandbox] sms to %s not sent: %q", phone, text)
+	return nil
+}
@@ -0,0 +1,96 @@
+// Package sms sends short status change texts through a pluggable SMS
+// gateway, for people who follow a monitor without Telegram.
+package sms
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// MaxPhones is how many phone numbers one monitor may text.
+	MaxPhones = 3
+	// MaxLen keeps a text within one SMS segment of Cyrillic (UCS-2) text, so
+	// a message always costs a single SMS per recipient.
+	MaxLen = 70
+)
+
+// Provider delivers one text to one phone number.
+type Provider interface {
+	Name() string
+	Send(ctx context.Context, phone, text string) error
+}
+
+// NewProvider returns the gateway named by the SMS_PROVIDER setting, or nil
+// when SMS is disabled (empty name).
+func NewProvider(name, token, sender string) (Provider, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "":
+		return nil, nil
+	case "turbosms":
+		if token == "" || sender == "" {
+			return nil, fmt.Errorf("turbosms needs SMS_TOKEN and SMS_SENDER")
+		}
+		return NewTurboSMS(token, sender), nil
+	}
+	return nil, fmt.Errorf("unknown SMS provider %q", name)
+}
+
+// NormalizePhone turns a Ukrainian mobile number in any common spelling
+// (+380 67 123 45 67, 067-123-45-67, 380671234567) into +380XXXXXXXXX.
+func NormalizePhone(s string) (string, bool) {
+	var digits strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' || r == ' ' || r == '-' || r == '(' || r == ')':
+		default:
+			return "", false
+		}
+	}
+	d := digits.String()
+	switch {
+	case len(d) == 10 && d[0] == '0':
+		d = "38" + d
+	case len(d) == 11 && strings.HasPrefix(d, "80"):
+		d = "3" + d
+	}
+	if len(d) != 12 || !strings.HasPrefix(d, "380") {
+		return "", false
+	}
+	return "+" + d, true
+}
+
+// ParsePhones splits a comma-separated list (as stored in monitors.sms_phones).
+func ParsePhones(list string) []string {
+	var phones []string
+	for _, p := range strings.Split(list, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			phones = append(phones, p)
+		}
+	}
+	return phones
+}
+
+// Truncate shortens text to at most n characters, marking the cut with "…".
+func Truncate(text string, n int) string {
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	if n < 1 {
+		return ""
+	}
+	r := []rune(text)
+	return string(r[:n-1]) + "…"
+}
+
+// Month returns the quota month of t: the first day of its Kyiv calendar month.
+func Month(t time.Time) time.Time {
+	kyiv, _ := time.LoadLocation("Europe/Kyiv")
+	k := t.In(kyiv)
+	return time.Date(k.Year(), k.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,78 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// turboSMSURL is the TurboSMS HTTP API send endpoint.
+const turboSMSURL = "https://api.turbosms.ua/message/send.json"
+
+// TurboSMS sends texts through the TurboSMS HTTP API (turbosms.ua).
+type TurboSMS struct {
+	token  string
+	sender string
+	client *http.Client
+}
+
+// NewTurboSMS returns a TurboSMS gateway using an API token and a registered sender name.
+func NewTurboSMS(token, sender string) *TurboSMS {
+	return &TurboSMS{token: token, sender: sender, client: &http.Client{Timeout: 15 * time.Second}}
+}
+
+func (t *TurboSMS) Name() string { return "turbosms" }
+
+type turboSMSRequest struct {
+	Recipients []string `json:"recipients"`
+	SMS        struct {
+		Sender string `json:"sender"`
+		Text   string `json:"text"`
+	} `json:"sms"`
+}
+
+type turboSMSResponse struct {
+	ResponseCode   int    `json:"response_code"`
+	ResponseStatus string `json:"response_status"`
+}
+
+// Send delivers text to phone. TurboSMS wants numbers without the leading "+".
+func (t *TurboSMS) Send(ctx context.Context, phone, text string) error {
+	var body turboSMSRequest
+	body.Recipients = []string{strings.TrimPrefix(phone, "+")}
+	body.SMS.Sender = t.sender
+	body.SMS.Text = text
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, turboSMSURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("http post: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("turbosms returned HTTP %d", resp.StatusCode)
+	}
+
+	var result turboSMSResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	// 0 is OK, 800-802 are "accepted" variants; everything else is a failure.
+	if result.ResponseCode != 0 && (result.ResponseCode < 800 || result.ResponseCode > 802) {
+		return fmt.Errorf("turbosms: %d %s", result.ResponseCode, result.ResponseStatus)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// Package tgauth verifies Telegram-signed user identities: the Login Widget
+// callback data and Mini App (Web App) initData. Both are signed with a key
+// derived from the bot token, so only the bot's backend can check them.
+package tgauth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrBadSignature is returned when the hash does not match the data.
+	ErrBadSignature = errors.New("tgauth: bad signature")
+	// ErrExpired is returned when auth_date is older than the allowed age.
+	ErrExpired = errors.New("tgauth: auth data expired")
+	// ErrMalformed is returned when required fields are missing or invalid.
+	ErrMalformed = errors.New("tgauth: malformed auth data")
+)
+
+// User is the Telegram account the auth data was issued for.
+type User struct {
+	ID        int64  `json:"id"`
+	Username  string `json:"username"`
+	FirstName string `json:"first_name"`
+}
+
+// VerifyLogin checks Login Widget data (id, first_name, username, photo_url,
+// auth_date, hash, ...). The secret key is SHA256(botToken).
+func VerifyLogin(fields map[string]string, botToken string, maxAge time.Duration) (*User, error) {
+	key := sha256.Sum256([]byte(botToken))
+	if err := verify(fields, key[:], maxAge); err != nil {
+		return nil, err
+	}
+	id, err := strconv.ParseInt(fields["id"], 10, 64)
+	if err != nil || id == 0 {
+		return nil, ErrMalformed
+	}
+	return &User{ID: id, Username: fields["username"], FirstName: fields["first_name"]}, nil
+}
+
+// VerifyWebApp checks Mini App initData (the raw query string from
+// Telegram.WebApp.initData). The secret key is HMAC_SHA256("WebAppData", botToken).
+func VerifyWebApp(initData, botToken string, maxAge time.Duration) (*User, error) {
+	values, err := url.ParseQuery(initData)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	fields := make(map[string]string, len(values))
+	for k := range values {
+		fields[k] = values.Get(k)
+	}
+
+	mac := hmac.New(sha256.New, []byte("WebAppData"))
+	mac.Write([]byte(botToken))
+	if err := verify(fields, mac.Sum(nil), maxAge); err != nil {
+		return nil, err
+	}
+
+	var u User
+	if err := json.Unmarshal([]byte(fields["user"]), &u); err != nil || u.ID == 0 {
+		return nil, ErrMalformed
+	}
+	return &u, nil
+}
+
+// verify checks the hash over the data-check-string (sorted key=value lines,
+// hash excluded) and the freshness of auth_date.
+func verify(fields map[string]string, key []byte, maxAge time.Duration) error {
+	hash := fields["hash"]
+	if hash == "" || fields["auth_date"] == "" {
+		return ErrMalformed
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		if k != "hash" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	lines := make([]string, len(keys))
+	for i, k := range keys {
+		lines[i] = k + "=" + fields[k]
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	want := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(want), []byte(strings.ToLower(hash))) {
+		return ErrBadSignature
+	}
+
+	authDate, err := strconv.ParseInt(fields["auth_date"], 10, 64)
+	if err != nil {
+		return ErrMalformed
+	}
+	if maxAge > 0 && time.Since(time.Unix(authDate, 0)) > maxAge {
+		return ErrExpired
+	}
+	return nil
+}
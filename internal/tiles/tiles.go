@@ -0,0 +1,125 @@
+// Package tiles pre-aggregates monitor statuses into Web Mercator (slippy
+// map) tiles for the public heat layer. Each tile holds a coarse grid of
+// cells with monitor counts, never individual monitors.
+package tiles
+
+import (
+	"math"
+	"strconv"
+)
+
+const (
+	// MinZoom and MaxZoom bound the zoom levels tiles are built for. At
+	// MaxZoom a cell is ~0.8 km wide in Ukraine, coarser than the map's
+	// privacy grid (see geoprivacy.CellDeg).
+	MinZoom = 5
+	MaxZoom = 10
+	// cellsPerSide is the heat grid resolution inside one tile.
+	cellsPerSide = 32
+	// maxLat is the Web Mercator latitude limit.
+	maxLat = 85.05112878
+)
+
+// Point is one monitor's location and status.
+type Point struct {
+	Lat, Lng float64
+	Online   bool
+}
+
+// Cell is one grid cell of a tile: its center and how many monitors in it
+// are offline.
+type Cell struct {
+	Lat     float64 `json:"lat"`
+	Lng     float64 `json:"lng"`
+	Total   int     `json:"total"`
+	Offline int     `json:"offline"`
+}
+
+// Tile is the heat data of one z/x/y tile.
+type Tile struct {
+	Z     int    `json:"z"`
+	X     int    `json:"x"`
+	Y     int    `json:"y"`
+	Cells []Cell `json:"cells"`
+}
+
+// Key identifies a tile in the stored set.
+func Key(z, x, y int) string {
+	return strconv.Itoa(z) + "/" + strconv.Itoa(x) + "/" + strconv.Itoa(y)
+}
+
+// Valid reports whether z/x/y addresses a tile that Build may produce.
+func Valid(z, x, y int) bool {
+	if z < MinZoom || z > MaxZoom {
+		return false
+	}
+	n := 1 << z
+	return x >= 0 && x < n && y >= 0 && y < n
+}
+
+// Build aggregates points into the non-empty tiles of every zoom level,
+// keyed by Key. Points at 0,0 (no location) are skipped.
+func Build(points []Point) map[string]*Tile {
+	type cellKey struct{ z, cx, cy int }
+	counts := make(map[cellKey]*Cell)
+	for _, p := range points {
+		if p.Lat == 0 && p.Lng == 0 {
+			continue
+		}
+		fx, fy := project(p.Lat, p.Lng)
+		for z := MinZoom; z <= MaxZoom; z++ {
+			side := float64(int(1)<<z) * cellsPerSide
+			k := cellKey{z, clamp(int(fx*side), int(side)), clamp(int(fy*side), int(side))}
+			c := counts[k]
+			if c == nil {
+				c = &Cell{}
+				c.Lat, c.Lng = unproject((float64(k.cx)+0.5)/side, (float64(k.cy)+0.5)/side)
+				counts[k] = c
+			}
+			c.Total++
+			if !p.Online {
+				c.Offline++
+			}
+		}
+	}
+
+	tiles := make(map[string]*Tile)
+	for k, c := range counts {
+		x, y := k.cx/cellsPerSide, k.cy/cellsPerSide
+		key := Key(k.z, x, y)
+		t := tiles[key]
+		if t == nil {
+			t = &Tile{Z: k.z, X: x, Y: y}
+			tiles[key] = t
+		}
+		t.Cells = append(t.Cells, *c)
+	}
+	return tiles
+}
+
+// project maps a location to Web Mercator coordinates in [0, 1).
+func project(lat, lng float64) (float64, float64) {
+	lat = math.Max(-maxLat, math.Min(maxLat, lat))
+	rad := lat * math.Pi / 180
+	x := (lng + 180) / 360
+	y := (1 - math.Log(math.Tan(rad)+1/math.Cos(rad))/math.Pi) / 2
+	return x, y
+}
+
+// unproject is the inverse of project, rounded to 6 decimals (~10 cm).
+func unproject(x, y float64) (float64, float64) {
+	lng := x*360 - 180
+	lat := math.Atan(math.Sinh(math.Pi*(1-2*y))) * 180 / math.Pi
+	return math.Round(lat*1e6) / 1e6, math.Round(lng*1e6) / 1e6
+}
+
+// clamp keeps a cell index inside [0, n).
+func clamp(i, n int) int {
+	if i < 0 {
+		return 0
+	}
+	if i >= n {
+		return n - 1
+	}
+	return i
+}
@@ -0,0 +1,157 @@
+// Package webhook POSTs monitor status changes to owner-configured URLs.
+//
+// Every request carries a JSON Payload and two headers: X-NLM-Timestamp (Unix
+// seconds) and X-NLM-Signature ("sha256=" + hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the monitor's webhook secret), so receivers
+// can check the sender and reject replays. Power return triggers are signed
+// with their own secret and also send it as X-NLM-Secret.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"no-lights-monitor/internal/httpx"
+	"no-lights-monitor/internal/ping"
+)
+
+const (
+	// HeaderTimestamp and HeaderSignature are set on every delivery.
+	HeaderTimestamp = "X-NLM-Timestamp"
+	HeaderSignature = "X-NLM-Signature"
+	// HeaderSecret carries the plain shared secret on power return triggers,
+	// for automation hubs that can compare a header but not check an HMAC.
+	HeaderSecret = "X-NLM-Secret"
+
+	// EventStatusChange is sent on every online/offline transition.
+	EventStatusChange = "status_change"
+	// EventPowerReturned is sent to the power return trigger URL on every
+	// offline → online transition.
+	EventPowerReturned = "power_returned"
+	// EventHeartbeat is sent for ping arrivals of monitors with the heartbeat
+	// firehose enabled, at most once per monitor per minute.
+	EventHeartbeat = "heartbeat"
+)
+
+// Policy retries failed deliveries for about half a minute: receivers that
+// are restarting get the change, dead endpoints don't hold the queue long.
+var Policy = httpx.Policy{Attempts: 4, AttemptTimeout: 10 * time.Second, BaseDelay: 2 * time.Second, MaxDelay: 15 * time.Second}
+
+// HeartbeatPolicy tries heartbeat deliveries once: the next one is due
+// within a minute anyway.
+var HeartbeatPolicy = httpx.Policy{Attempts: 1, AttemptTimeout: 5 * time.Second}
+
+// Payload is the JSON body of a delivery.
+type Payload struct {
+	ID          string    `json:"id"` // unique per change; retries repeat it
+	Event       string    `json:"event"`
+	MonitorID   int64     `json:"monitor_id"`
+	Name        string    `json:"name"`
+	Status      string    `json:"status"` // online | offline
+	IsOnline    bool      `json:"is_online"`
+	DurationSec int64     `json:"duration_sec"` // time spent in the previous status
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// ValidateURL checks a webhook URL typed by an owner and returns it
+// normalized. The rules are those of HTTP monitor targets: http(s) only and
+// no private addresses.
+func ValidateURL(raw string) (string, error) {
+	return ping.ValidateURL(raw)
+}
+
+// NewSecret returns a random signing secret for a monitor.
+func NewSecret() string {
+	buf := make([]byte, 24)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// Sign returns the X-NLM-Signature value of body sent at ts.
+func Sign(secret string, ts int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(ts, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Client delivers payloads.
+type Client struct {
+	http    *httpx.Client
+	sandbox bool // log payloads instead of delivering them
+}
+
+// NewClient creates a client that only connects to public addresses and
+// retries according to Policy.
+func NewClient() *Client {
+	return &Client{http: &httpx.Client{Name: "webhook", HTTP: ping.PublicHTTPClient(Policy.AttemptTimeout), Policy: Policy}}
+}
+
+// NewHeartbeatClient is NewClient for heartbeat events, retried according to
+// HeartbeatPolicy.
+func NewHeartbeatClient() *Client {
+	return &Client{http: &httpx.Client{Name: "webhook_heartbeat", HTTP: ping.PublicHTTPClient(HeartbeatPolicy.AttemptTimeout), Policy: HeartbeatPolicy}}
+}
+
+// SetSandbox makes the client log payloads instead of delivering them
+// (SANDBOX=1), so a copy of production data doesn't call real endpoints.
+func (c *Client) SetSandbox(on bool) {
+	c.sandbox = on
+}
+
+// Send POSTs p to url signed with secret. Any 2xx answer is success.
+func (c *Client) Send(ctx context.Context, url, secret string, p Payload) error {
+	return c.send(ctx, url, secret, p, false)
+}
+
+// SendTrigger is Send for the power return trigger: the request also
+// carries the secret itself in HeaderSecret.
+func (c *Client) SendTrigger(ctx context.Context, url, secret string, p Payload) error {
+	return c.send(ctx, url, secret, p, true)
+}
+
+func (c *Client) send(ctx context.Context, url, secret string, p Payload, withSecret bool) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+	if c.sandbox {
+		log.Printf("[sandbox] webhook to %s not sent: %s", url, body)
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	ts := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "no-lights-monitor/1.0 (webhook)")
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderSignature, Sign(secret, ts, body))
+	if withSecret {
+		req.Header.Set(HeaderSecret, secret)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("endpoint returned %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+// Package workpool provides bounded goroutine pools for the worker's fan-out
+// work (ICMP pings, DB writes, MQ publishes). A saturated pool blocks the
+// submitter instead of spawning more goroutines, which is the backpressure.
+package workpool
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"no-lights-monitor/internal/metrics"
+	"no-lights-monitor/internal/safego"
+)
+
+// saturationLogEvery throttles the "pool saturated" log line per pool.
+const saturationLogEvery = 30 * time.Second
+
+// Pool runs at most size functions concurrently.
+type Pool struct {
+	name    string
+	sem     chan struct{}
+	waiting atomic.Int64
+
+	logMu   sync.Mutex
+	lastLog time.Time
+}
+
+// New creates a pool. name is used as the metrics label and in logs.
+func New(name string, size int) *Pool {
+	if size < 1 {
+		size = 1
+	}
+	return &Pool{name: name, sem: make(chan struct{}, size)}
+}
+
+// Go runs fn in a new goroutine once a slot is free. If the pool is full the
+// caller blocks until one frees up.
+func (p *Pool) Go(fn func()) {
+	select {
+	case p.sem <- struct{}{}:
+	default:
+		p.wait()
+	}
+	metrics.WorkPoolInFlight.WithLabelValues(p.name).Inc()
+
+	safego.Go("workpool:"+p.name, func() {
+		defer func() {
+			metrics.WorkPoolInFlight.WithLabelValues(p.name).Dec()
+			<-p.sem
+		}()
+		fn()
+	})
+}
+
+// wait blocks for a slot, tracking queue depth and logging sustained saturation.
+func (p *Pool) wait() {
+	depth := p.waiting.Add(1)
+	metrics.WorkPoolQueueDepth.WithLabelValues(p.name).Set(float64(depth))
+	start := time.Now()
+
+	p.sem <- struct{}{}
+
+	depth = p.waiting.Add(-1)
+	metrics.WorkPoolQueueDepth.WithLabelValues(p.name).Set(float64(depth))
+	waited := time.Since(start)
+	metrics.WorkPoolWaitSeconds.WithLabelValues(p.name).Observe(waited.Seconds())
+
+	p.logMu.Lock()
+	defer p.logMu.Unlock()
+	if time.Since(p.lastLog) >= saturationLogEvery {
+		p.lastLog = time.Now()
+		log.Printf("[workpool] %s saturated (size %d): waited %s, %d still queued", p.name, cap(p.sem), waited.Round(time.Millisecond), depth)
+	}
+}
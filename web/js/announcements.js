@@ -0,0 +1,41 @@
+// Operator announcement banners (maintenance notices, donation links).
+// Dismissed banners stay hidden in this browser.
+
+function renderAnnouncements(container, list) {
+  const dismissed = JSON.parse(localStorage.getItem('dismissedAnnouncements') || '[]');
+  container.innerHTML = '';
+  (list || []).filter(a => !dismissed.includes(a.id)).forEach(a => {
+    const el = document.createElement('div');
+    el.className = 'flex items-start justify-between gap-3 px-4 py-2.5 text-sm border-b ' +
+      (a.level === 'warning' ? 'bg-amber-50 text-amber-800 border-amber-200' : 'bg-sky-50 text-sky-800 border-sky-200');
+    const text = document.createElement('span');
+    text.textContent = a.message;
+    if (a.link_url) {
+      const link = document.createElement('a');
+      link.href = a.link_url;
+      link.target = '_blank';
+      link.rel = 'noopener';
+      link.className = 'ml-1 font-medium underline';
+      link.textContent = 'Детальніше →';
+      text.appendChild(link);
+    }
+    const close = document.createElement('button');
+    close.textContent = '✕';
+    close.title = 'Сховати';
+    close.className = 'opacity-60 hover:opacity-100';
+    close.onclick = () => {
+      dismissed.push(a.id);
+      localStorage.setItem('dismissedAnnouncements', JSON.stringify(dismissed.slice(-50)));
+      el.remove();
+    };
+    el.append(text, close);
+    container.appendChild(el);
+  });
+}
+
+async function loadAnnouncements(container, page) {
+  try {
+    const res = await fetch('/api/announcements?page=' + page);
+    if (res.ok) renderAnnouncements(container, await res.json());
+  } catch (e) {}
+}
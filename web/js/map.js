@@ -117,8 +117,8 @@ function createMarker(monitor) {
 
     marker.bindPopup(`
       <div style="font-family:Inter,system-ui,sans-serif;min-width:170px;line-height:1.5;">
-        <div style="font-weight:600;font-size:0.95em;">${escapeHtml(monitor.name)}</div>
-        <div style="font-size:0.85em;color:#78716c;margin-bottom:8px;">${escapeHtml(monitor.address)}</div>
+        <div style="font-weight:600;font-size:0.95em;">${monitor.name ? escapeHtml(monitor.name) : 'Приватна локація'}</div>
+        ${monitor.address ? `<div style="font-size:0.85em;color:#78716c;margin-bottom:8px;">${escapeHtml(monitor.address)}</div>` : '<div style="margin-bottom:8px;"></div>'}
         <div style="font-weight:500;color:${statusColor};">${statusText}</div>
         ${durationText}
         ${channel}
@@ -161,29 +161,93 @@ function updateMarker(monitor) {
 }
 
 // --- Load monitors from API ---
+const monitorOnline = {}; // monitor id -> is_online, for the stats badge
+let lastSync = null;      // server "now" of the last changes poll
+
 async function loadMonitors() {
   try {
     const res = await fetch('/api/monitors');
     const data = await res.json();
 
-    let online = 0;
-    let offline = 0;
-
-    data.forEach(monitor => {
-      updateMarker(monitor);
-      if (monitor.is_online) online++;
-      else offline++;
-    });
-
-    updateStats(data.length, online, offline);
+    data.forEach(applyMonitor);
+    // The list may be cached for up to 15s: ask for changes from a bit before the server's Date.
+    const served = new Date(res.headers.get('Date') || Date.now());
+    lastSync = new Date(served.getTime() - 20000).toISOString().replace(/\.\d+Z$/, 'Z');
+    refreshStats();
+  } catch (e) {
+    console.error('Failed to load monitors:', e);
+  }
+}
 
-    const el = document.getElementById('last-updated');
-    if (el) {
-      const t = new Date();
-      el.textContent = 'Оновлено: ' + t.toLocaleTimeString('uk-UA', { hour: '2-digit', minute: '2-digit', second: '2-digit' });
+// Between full loads, fetch only the monitors whose status changed.
+async function loadMonitorChanges() {
+  if (!lastSync) return;
+  try {
+    const res = await fetch('/api/monitors/changes?since=' + encodeURIComponent(lastSync));
+    if (res.status === 400) {
+      // Too far behind (e.g. the tab slept): reload everything.
+      return loadMonitors();
     }
+    const data = await res.json();
+    data.monitors.forEach(applyMonitor);
+    (data.removed || []).forEach(removeMonitor);
+    lastSync = data.now;
+    refreshStats();
   } catch (e) {
-    console.error('Failed to load monitors:', e);
+    console.error('Failed to load monitor changes:', e);
+  }
+}
+
+// Live status changes over Server-Sent Events. While the stream is up the
+// 30-second changes poll is skipped; EventSource reconnects by itself and
+// each reconnect catches up through the poll.
+let streamLive = false;
+
+function connectStream() {
+  if (!window.EventSource) return;
+  const stream = new EventSource('/api/stream');
+  stream.onopen = () => {
+    streamLive = true;
+    loadMonitorChanges(); // pick up what happened while disconnected
+  };
+  stream.onerror = () => { streamLive = false; };
+  stream.addEventListener('monitors', (e) => {
+    const data = JSON.parse(e.data);
+    data.monitors.forEach(applyMonitor);
+    (data.removed || []).forEach(removeMonitor);
+    refreshStats();
+  });
+}
+
+function pollMonitorChanges() {
+  if (!streamLive) loadMonitorChanges();
+}
+
+function applyMonitor(monitor) {
+  updateMarker(monitor);
+  monitorOnline[monitor.id] = monitor.is_online;
+}
+
+// Drops monitors that were made private, paused, deleted or banned.
+function removeMonitor(id) {
+  const existing = markers[id];
+  if (existing) {
+    clusterGroup.removeLayer(existing);
+    nonClusterGroup.removeLayer(existing);
+    delete markers[id];
+  }
+  delete monitorOnline[id];
+}
+
+function refreshStats() {
+  const states = Object.values(monitorOnline);
+  const online = states.filter(Boolean).length;
+  updateStats(states.length, online, states.length - online);
+
+  const el = document.getElementById('last-updated');
+  if (el) {
+    const t = new Date();
+    el.textContent = 'Оновлено: ' + t.toLocaleTimeString('uk-UA', { hour: '2-digit', minute: '2-digit', second: '2-digit' });
   }
 }
 
@@ -504,8 +568,12 @@ document.getElementById('toggle-grouping').addEventListener('change', function (
 loadMonitors();
 loadSvitlobot();
 
-// Poll every 5mins seconds for own monitors.
+connectStream();
+
+// Full reload of own monitors every 5 minutes, status changes every 30 seconds
+// unless the live stream delivers them.
 setInterval(loadMonitors, 60000 * 5);
+setInterval(pollMonitorChanges, 30 * 1000);
 
 // Poll Svitlobot every 5 minutes.
 setInterval(loadSvitlobot, 5 * 60 * 1000);